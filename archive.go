@@ -0,0 +1,359 @@
+package redka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sqlDisableAutoCheckpoint = `pragma wal_autocheckpoint = 0`
+
+// defaultArchiveInterval is the default
+// time between the archived WAL segments.
+const defaultArchiveInterval = time.Minute
+
+// Archive file names.
+const (
+	archiveBase = "base"
+	archiveWAL  = "wal"
+)
+
+// ArchiveStore stores the WAL archive: base snapshots of the database
+// and the WAL segments written after each of them. Use [DirArchive]
+// to archive to a local directory, or implement the interface on top
+// of an object store (like S3).
+type ArchiveStore interface {
+	// Put stores the file with the given name.
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get returns the contents of the file with the given name.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names of the stored files.
+	List(ctx context.Context) ([]string, error)
+}
+
+// ArchiveOptions configures the WAL archiving.
+type ArchiveOptions struct {
+	// Store is where the archive is stored (required).
+	Store ArchiveStore
+	// Interval is the time between the archived WAL segments,
+	// which is also the recovery precision. If zero, uses 1 minute.
+	Interval time.Duration
+}
+
+// DirArchive returns an archive store that keeps
+// the files in the given local directory.
+func DirArchive(dir string) ArchiveStore {
+	return dirStore(dir)
+}
+
+// dirStore is a directory-based archive store.
+type dirStore string
+
+// Put writes the file atomically, so that a partially
+// written file never appears in the archive.
+func (d dirStore) Put(ctx context.Context, name string, r io.Reader) error {
+	if err := os.MkdirAll(string(d), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(string(d), "."+name+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(string(d), name))
+}
+
+// Get opens the file.
+func (d dirStore) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return os.Open(filepath.Join(string(d), name))
+}
+
+// List returns the names of the files, skipping the temporary ones.
+func (d dirStore) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(string(d))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// archiveEntry is a file in the WAL archive.
+type archiveEntry struct {
+	name string
+	kind string
+	time time.Time
+}
+
+// parseArchiveName parses the archive file name
+// (like "base-1700000000000000000.db"). Returns false
+// if the name is not an archive file.
+func parseArchiveName(name string) (archiveEntry, bool) {
+	stem, _, _ := strings.Cut(name, ".")
+	kind, ts, ok := strings.Cut(stem, "-")
+	if !ok || (kind != archiveBase && kind != archiveWAL) {
+		return archiveEntry{}, false
+	}
+	nsec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return archiveEntry{}, false
+	}
+	return archiveEntry{name: name, kind: kind, time: time.Unix(0, nsec)}, true
+}
+
+// archiver writes the base snapshots and the WAL segments.
+type archiver struct {
+	store  ArchiveStore
+	path   string // database file
+	mu     sync.Mutex
+	last   time.Time
+	ticker *time.Ticker
+}
+
+// name returns a unique file name of the given kind.
+// The names sort in the order they were created.
+func (a *archiver) name(kind, ext string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if !now.After(a.last) {
+		now = a.last.Add(time.Nanosecond)
+	}
+	a.last = now
+	return fmt.Sprintf("%s-%020d.%s", kind, now.UnixNano(), ext)
+}
+
+// put stores the file at path under the given name.
+// Does nothing if the file does not exist or is empty.
+func (a *archiver) put(ctx context.Context, path, name string) error {
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.Size() == 0 {
+		return err
+	}
+	if err := a.store.Put(ctx, name, f); err != nil {
+		return fmt.Errorf("archive %s: %w", name, err)
+	}
+	return nil
+}
+
+// saveWAL archives the WAL file as a new segment. The caller must
+// hold the connection, so that there are no writes in the meantime.
+func (a *archiver) saveWAL(ctx context.Context) error {
+	return a.put(ctx, a.path+"-wal", a.name(archiveWAL, "wal"))
+}
+
+// startArchive checkpoints the WAL (archiving the pending segment),
+// stores a new base snapshot and starts the goroutine that archives
+// the WAL segments in the background. Does nothing if the archiving
+// is disabled.
+func (db *DB) startArchive(opts *ArchiveOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.Store == nil {
+		return errors.New("archive: missing store")
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultArchiveInterval
+	}
+
+	ctx := context.Background()
+	conn, err := db.SQL.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	var path string
+	if err := conn.QueryRowContext(ctx, sqlDatabaseFile).Scan(&path); err != nil {
+		return err
+	}
+	if path == "" {
+		return errors.New("archive: not a file database")
+	}
+	// SQLite's automatic checkpoints would reuse the WAL file before
+	// it is archived, so only the archiver checkpoints the WAL.
+	if _, err := conn.ExecContext(ctx, sqlDisableAutoCheckpoint); err != nil {
+		return err
+	}
+
+	arc := &archiver{store: opts.Store, path: path}
+	db.archive = arc
+	res, err := db.checkpoint(ctx, conn, CheckpointTruncate)
+	if err != nil {
+		db.archive = nil
+		return fmt.Errorf("archive: %w", err)
+	}
+	if res.Busy {
+		db.archive = nil
+		return fmt.Errorf("archive: %w", ErrSnapshotBusy)
+	}
+	if err := arc.put(ctx, path, arc.name(archiveBase, "db")); err != nil {
+		db.archive = nil
+		return err
+	}
+	db.log.Info("archive base", "path", path)
+
+	arc.ticker = time.NewTicker(interval)
+	go func() {
+		for range arc.ticker.C {
+			res, err := db.Checkpoint(CheckpointTruncate)
+			if err != nil {
+				db.log.Error("archive", "error", err)
+				continue
+			}
+			db.log.Debug("archive", "log", res.Log, "busy", res.Busy)
+		}
+	}()
+	return nil
+}
+
+// RestoreArchive restores the database from the WAL archive into
+// a new file at path. Applies the latest base snapshot made before
+// the until time, and then the WAL segments archived after it up
+// to the until time. If until is zero, restores the latest state.
+// Returns the time of the last applied archive file. The database
+// state is as of that time, which precedes until by no more than
+// the archive interval (see [ArchiveOptions.Interval]).
+//
+// Uses the DriverName and EncryptionKey options to open the database.
+// The opts parameter is optional. If nil, uses default options.
+// Fails if the file at path already exists.
+func RestoreArchive(ctx context.Context, store ArchiveStore, path string,
+	until time.Time, opts *Options) (time.Time, error) {
+	opts = applyOptions(defaultOptions, opts)
+	if _, err := os.Stat(path); err == nil {
+		return time.Time{}, fmt.Errorf("restore %s: %w", path, fs.ErrExist)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, err
+	}
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	names, err := store.List(ctx)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var entries []archiveEntry
+	for _, name := range names {
+		if e, ok := parseArchiveName(name); ok && !e.time.After(until) {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortFunc(entries, func(a, b archiveEntry) int {
+		return a.time.Compare(b.time)
+	})
+	base := -1
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].kind == archiveBase {
+			base = i
+			break
+		}
+	}
+	if base == -1 {
+		return time.Time{}, fmt.Errorf("restore: no base snapshot before %s", until)
+	}
+
+	restored, err := restoreEntries(ctx, store, path, entries[base:], opts)
+	if err != nil {
+		_ = os.Remove(path)
+		_ = os.Remove(path + "-wal")
+		_ = os.Remove(path + "-shm")
+		return time.Time{}, fmt.Errorf("restore: %w", err)
+	}
+	return restored, nil
+}
+
+// restoreEntries writes the base snapshot (the first entry)
+// to path, and applies the WAL segments (the rest) one by one.
+// Returns the time of the last applied entry.
+func restoreEntries(ctx context.Context, store ArchiveStore, path string,
+	entries []archiveEntry, opts *Options) (time.Time, error) {
+	if err := getArchive(ctx, store, entries[0].name, path); err != nil {
+		return time.Time{}, err
+	}
+	var key *cipherKey
+	if opts.EncryptionKey != "" {
+		key = &cipherKey{key: opts.EncryptionKey}
+	}
+	for _, e := range entries[1:] {
+		_ = os.Remove(path + "-shm")
+		if err := getArchive(ctx, store, e.name, path+"-wal"); err != nil {
+			return time.Time{}, err
+		}
+		// SQLite recovers the WAL on open,
+		// and the checkpoint applies it.
+		db, err := openSQL(opts.DriverName, path, key, "")
+		if err != nil {
+			return time.Time{}, err
+		}
+		var busy, log, checkpointed int
+		query := "pragma wal_checkpoint(" + string(CheckpointTruncate) + ")"
+		err = db.QueryRowContext(ctx, query).Scan(&busy, &log, &checkpointed)
+		_ = db.Close()
+		if err != nil {
+			return time.Time{}, fmt.Errorf("apply %s: %w", e.name, err)
+		}
+		if busy != 0 {
+			return time.Time{}, fmt.Errorf("apply %s: %w", e.name, ErrSnapshotBusy)
+		}
+	}
+	_ = os.Remove(path + "-wal")
+	_ = os.Remove(path + "-shm")
+	return entries[len(entries)-1].time, nil
+}
+
+// getArchive copies the archive file to path.
+func getArchive(ctx context.Context, store ArchiveStore, name, path string) error {
+	r, err := store.Get(ctx, name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
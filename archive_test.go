@@ -0,0 +1,70 @@
+package redka_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestWALArchive(t *testing.T) {
+	dir := t.TempDir()
+	store := redka.DirArchive(filepath.Join(dir, "archive"))
+	start := time.Now()
+
+	db, err := redka.Open(filepath.Join(dir, "data.db"), &redka.Options{
+		WALArchive: &redka.ArchiveOptions{Store: store, Interval: time.Hour},
+	})
+	testx.AssertNoErr(t, err)
+	_ = db.Str().Set("name", "alice")
+	_, err = db.Checkpoint(redka.CheckpointTruncate)
+	testx.AssertNoErr(t, err)
+	mid := time.Now()
+	_ = db.Str().Set("name", "bob")
+	_ = db.Str().Set("age", 25)
+	// Close archives the last segment.
+	err = db.Close()
+	testx.AssertNoErr(t, err)
+
+	restore := func(t *testing.T, until time.Time) *redka.DB {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "restored.db")
+		at, err := redka.RestoreArchive(context.Background(), store, path, until, nil)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, at.After(start), true)
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		return db
+	}
+
+	t.Run("point in time", func(t *testing.T) {
+		db := restore(t, mid)
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+		exists, _ := db.Key().Exists("age")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("latest", func(t *testing.T) {
+		db := restore(t, time.Time{})
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "bob")
+		age, _ := db.Str().Get("age")
+		testx.AssertEqual(t, age.String(), "25")
+	})
+	t.Run("before base", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "restored.db")
+		_, err := redka.RestoreArchive(context.Background(), store, path, start.Add(-time.Hour), nil)
+		testx.AssertEqual(t, err != nil, true)
+	})
+	t.Run("in-memory", func(t *testing.T) {
+		_, err := redka.Open("", &redka.Options{
+			InMemory:   true,
+			WALArchive: &redka.ArchiveOptions{Store: store},
+		})
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
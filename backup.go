@@ -0,0 +1,325 @@
+package redka
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const sqlBackupSize = `
+select (page_count - freelist_count) * page_size
+from pragma_page_count(), pragma_freelist_count(), pragma_page_size()`
+
+const sqlBackup = `vacuum into ?`
+
+// backupPollInterval is how often the backup progress is reported.
+const backupPollInterval = 100 * time.Millisecond
+
+// backupPrefix and backupExt make the names of the rotated
+// backup files (see [DB.BackupRotate]).
+const (
+	backupPrefix = "backup-"
+	backupExt    = ".db"
+)
+
+// BackupOptions configures the scheduled backups.
+type BackupOptions struct {
+	// Dir is the directory for the backup files (required).
+	// The directory should only contain the backups of
+	// this database, since the old ones are deleted.
+	Dir string
+	// Interval is how often the backups are made.
+	// If zero, uses 1 hour.
+	Interval time.Duration
+	// Keep is the number of the latest backups to keep.
+	// If zero, keeps all of them.
+	Keep int
+}
+
+// BackupProgress describes the state of a running backup.
+type BackupProgress struct {
+	Written int64 // bytes written to the backup file so far
+	Total   int64 // estimated size of the backup file in bytes
+}
+
+// Backup creates a consistent snapshot of the database at the given path.
+// The snapshot is a regular SQLite database file that can be opened with [Open].
+//
+// The database stays writable while the backup is running: for file
+// databases, the snapshot is read through a separate connection,
+// so in WAL mode it does not block concurrent writers.
+//
+// Fails if the file at path already exists. If the backup fails
+// or ctx is canceled, the partially written file is removed.
+func (db *DB) Backup(ctx context.Context, path string) error {
+	return db.BackupWithProgress(ctx, path, nil)
+}
+
+// BackupWithProgress is like [DB.Backup], but periodically calls
+// the progress function while the backup is running. The last call
+// always reports the final size of the backup file as both
+// Written and Total. If progress is nil, it is ignored.
+func (db *DB) BackupWithProgress(ctx context.Context, path string, progress func(BackupProgress)) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup %s: %w", path, fs.ErrExist)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	conn, release, err := db.snapshotConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var total int64
+	err = conn.QueryRowContext(ctx, sqlBackupSize).Scan(&total)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if progress == nil {
+			return
+		}
+		ticker := time.NewTicker(backupPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				if fi, err := os.Stat(path); err == nil {
+					progress(BackupProgress{Written: min(fi.Size(), total), Total: total})
+				}
+			}
+		}
+	}()
+
+	_, err = conn.ExecContext(ctx, sqlBackup, path)
+	close(stop)
+	<-done
+	if err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	if progress != nil {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		progress(BackupProgress{Written: fi.Size(), Total: fi.Size()})
+	}
+	db.log.Info("backup", "path", path)
+	return nil
+}
+
+// BackupRotate creates a backup (see [DB.Backup]) in the dir directory,
+// named after the current time, like backup-1700000000000000000.db.
+// Then deletes the oldest backups in the directory, so that only
+// the keep latest ones are left (if keep = 0, keeps all of them).
+// Returns the path of the new backup.
+func (db *DB) BackupRotate(ctx context.Context, dir string, keep int) (string, error) {
+	name := backupPrefix + strconv.FormatInt(time.Now().UnixNano(), 10) + backupExt
+	path := filepath.Join(dir, name)
+	if err := db.Backup(ctx, path); err != nil {
+		return "", err
+	}
+	if keep <= 0 {
+		return path, nil
+	}
+	backups, err := listBackups(dir)
+	if err != nil {
+		return path, err
+	}
+	for _, name := range backups[:max(len(backups)-keep, 0)] {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
+
+// RestoreBackup restores the database from the backup file (made
+// with [DB.Backup] or [DB.BackupRotate]) into a new file at path.
+// Checks the integrity of the restored database, and removes it
+// if the check fails. To restore a database in place, close it,
+// move the database file (and its -wal and -shm files) away,
+// and restore into the original path.
+//
+// Uses the DriverName and EncryptionKey options to open the database.
+// The opts parameter is optional. If nil, uses default options.
+// Fails if the file at path already exists.
+func RestoreBackup(ctx context.Context, backup, path string, opts *Options) error {
+	opts = applyOptions(defaultOptions, opts)
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("restore %s: %w", path, fs.ErrExist)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := restoreBackup(ctx, backup, path, opts); err != nil {
+		_ = os.Remove(path)
+		return fmt.Errorf("restore: %w", err)
+	}
+	return nil
+}
+
+// restoreBackup copies the backup file to path
+// and checks the integrity of the copy.
+func restoreBackup(ctx context.Context, backup, path string, opts *Options) error {
+	src, err := os.Open(backup)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+
+	var key *cipherKey
+	if opts.EncryptionKey != "" {
+		key = &cipherKey{key: opts.EncryptionKey}
+	}
+	sdb, err := openSQL(opts.DriverName, path, key, "")
+	if err != nil {
+		return err
+	}
+	defer sdb.Close()
+	var check string
+	if err := sdb.QueryRowContext(ctx, "pragma quick_check").Scan(&check); err != nil {
+		return err
+	}
+	if check != "ok" {
+		return fmt.Errorf("integrity check: %s", check)
+	}
+	return nil
+}
+
+// listBackups returns the names of the rotated
+// backup files in the directory, oldest first.
+func listBackups(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		ts, ok := strings.CutPrefix(name, backupPrefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		ts, ok = strings.CutSuffix(ts, backupExt)
+		if _, err := strconv.ParseInt(ts, 10, 64); !ok || err != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+	// The timestamps have the same number of digits,
+	// so the names sort in time order.
+	slices.Sort(names)
+	return names, nil
+}
+
+// backupper makes the scheduled backups (see Options.AutoBackup).
+type backupper struct {
+	mu      sync.Mutex // held while making a backup
+	stopped bool
+	ticker  *time.Ticker
+}
+
+// stop stops the scheduled backups and waits
+// for the running one (if any) to complete.
+func (b *backupper) stop() {
+	b.ticker.Stop()
+	b.mu.Lock()
+	b.stopped = true
+	b.mu.Unlock()
+}
+
+// startBackups starts the goroutine that runs in the background
+// and makes the scheduled backups. Returns nil if the scheduled
+// backups are disabled.
+func (db *DB) startBackups(opts *BackupOptions) *backupper {
+	if opts == nil || opts.Dir == "" {
+		return nil
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	b := &backupper{ticker: time.NewTicker(interval)}
+	go func() {
+		for range b.ticker.C {
+			b.mu.Lock()
+			if b.stopped {
+				b.mu.Unlock()
+				return
+			}
+			if _, err := db.BackupRotate(context.Background(), opts.Dir, opts.Keep); err != nil {
+				db.log.Error("backup", "error", err)
+			}
+			b.mu.Unlock()
+		}
+	}()
+	return b
+}
+
+// snapshotConn returns a connection to read a consistent snapshot of
+// the database. For file databases, opens a separate connection so that
+// long reads do not occupy the main one. For in-memory databases,
+// uses a connection from the main pool. The caller must call release
+// when finished with the connection.
+func (db *DB) snapshotConn(ctx context.Context) (conn *sql.Conn, release func(), err error) {
+	if isMemoryPath(db.path) {
+		conn, err = db.SQL.Conn(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return conn, func() { _ = conn.Close() }, nil
+	}
+
+	sdb, err := openSQL(db.driver, db.path, db.key, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	sdb.SetMaxOpenConns(1)
+	conn, err = sdb.Conn(ctx)
+	if err != nil {
+		_ = sdb.Close()
+		return nil, nil, err
+	}
+	release = func() {
+		_ = conn.Close()
+		_ = sdb.Close()
+	}
+	return conn, release, nil
+}
+
+// isMemoryPath reports whether the path points to an in-memory database.
+func isMemoryPath(path string) bool {
+	return path == "" || strings.Contains(path, ":memory:") ||
+		strings.Contains(path, "mode=memory")
+}
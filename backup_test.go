@@ -0,0 +1,166 @@
+package redka_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestBackup(t *testing.T) {
+	t.Run("file", func(t *testing.T) {
+		dir := t.TempDir()
+		db, err := redka.Open(filepath.Join(dir, "data.db"), nil)
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().Set("name", "alice")
+		_, _ = db.Hash().Set("person", "age", 25)
+
+		var last redka.BackupProgress
+		path := filepath.Join(dir, "backup.db")
+		err = db.BackupWithProgress(context.Background(), path, func(p redka.BackupProgress) {
+			last = p
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, last.Written > 0, true)
+		testx.AssertEqual(t, last.Written, last.Total)
+
+		// The database stays writable after the backup.
+		err = db.Str().Set("name", "bob")
+		testx.AssertNoErr(t, err)
+
+		bak, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		defer bak.Close()
+		name, _ := bak.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+		age, _ := bak.Hash().Get("person", "age")
+		testx.AssertEqual(t, age.MustInt(), 25)
+	})
+	t.Run("memory", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		path := filepath.Join(t.TempDir(), "backup.db")
+		err := db.Backup(context.Background(), path)
+		testx.AssertNoErr(t, err)
+
+		bak, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		defer bak.Close()
+		name, _ := bak.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("file exists", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		path := filepath.Join(t.TempDir(), "backup.db")
+		err := db.Backup(context.Background(), path)
+		testx.AssertNoErr(t, err)
+		err = db.Backup(context.Background(), path)
+		testx.AssertEqual(t, errors.Is(err, fs.ErrExist), true)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		path := filepath.Join(t.TempDir(), "backup.db")
+		err := db.Backup(ctx, path)
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
+
+func TestBackupRotate(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+	dir := t.TempDir()
+
+	var paths []string
+	for i := range 4 {
+		_ = db.Str().Set("n", i)
+		path, err := db.BackupRotate(context.Background(), dir, 2)
+		testx.AssertNoErr(t, err)
+		paths = append(paths, path)
+	}
+
+	// Only the latest backups are kept.
+	entries, err := os.ReadDir(dir)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(entries), 2)
+	testx.AssertEqual(t, filepath.Join(dir, entries[0].Name()), paths[2])
+	testx.AssertEqual(t, filepath.Join(dir, entries[1].Name()), paths[3])
+
+	bak, err := redka.Open(paths[3], nil)
+	testx.AssertNoErr(t, err)
+	defer bak.Close()
+	n, _ := bak.Str().Get("n")
+	testx.AssertEqual(t, n.MustInt(), 3)
+}
+
+func TestAutoBackup(t *testing.T) {
+	dir := t.TempDir()
+	db, err := redka.Open(":memory:", &redka.Options{
+		AutoBackup: &redka.BackupOptions{Dir: dir, Interval: 10 * time.Millisecond, Keep: 1},
+	})
+	testx.AssertNoErr(t, err)
+	_ = db.Str().Set("name", "alice")
+	time.Sleep(50 * time.Millisecond)
+	_ = db.Close()
+
+	entries, err := os.ReadDir(dir)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(entries), 1)
+}
+
+func TestRestoreBackup(t *testing.T) {
+	t.Run("restore", func(t *testing.T) {
+		dir := t.TempDir()
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+		backup := filepath.Join(dir, "backup.db")
+		err := db.Backup(context.Background(), backup)
+		testx.AssertNoErr(t, err)
+
+		path := filepath.Join(dir, "data.db")
+		err = redka.RestoreBackup(context.Background(), backup, path, nil)
+		testx.AssertNoErr(t, err)
+
+		restored, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		defer restored.Close()
+		name, _ := restored.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("exists", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "data.db")
+		err := os.WriteFile(path, nil, 0o644)
+		testx.AssertNoErr(t, err)
+		err = redka.RestoreBackup(context.Background(), path, path, nil)
+		testx.AssertErr(t, err, fs.ErrExist)
+	})
+	t.Run("corrupted", func(t *testing.T) {
+		dir := t.TempDir()
+		backup := filepath.Join(dir, "backup.db")
+		err := os.WriteFile(backup, []byte("not a database"), 0o644)
+		testx.AssertNoErr(t, err)
+
+		path := filepath.Join(dir, "data.db")
+		err = redka.RestoreBackup(context.Background(), backup, path, nil)
+		testx.AssertEqual(t, err != nil, true)
+		_, err = os.Stat(path)
+		testx.AssertErr(t, err, fs.ErrNotExist)
+	})
+}
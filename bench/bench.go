@@ -0,0 +1,298 @@
+// Package bench measures the Redka performance with the workloads
+// of redis-benchmark: the same commands and key names, pipelining
+// and a number of concurrent clients.
+//
+// The benchmark runs against a Target: either the embedded database
+// (the commands run in-process, without the network) or a server
+// (Redka or Redis) over RESP, so the two can be compared:
+//
+//	db, _ := redka.Open("bench.db", nil)
+//	results, err := bench.Run(ctx, bench.Embedded(db), &bench.Options{
+//	    Tests:    []string{"set", "get"},
+//	    Clients:  []int{1, 10, 50},
+//	    Pipeline: 16,
+//	})
+//	for _, res := range results {
+//	    fmt.Println(res)
+//	}
+//
+// Every result reports the throughput (requests per second)
+// and the latency percentiles of the round trips.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Tests lists the supported tests in the default order.
+// Lists and sets are not supported by Redka, and the sorted set
+// commands are not available in the server yet, so the LPUSH, RPUSH,
+// LPOP, RPOP, SADD, SPOP, ZADD and ZPOPMIN tests are absent.
+var Tests = []string{"ping", "set", "get", "incr", "hset", "mset"}
+
+// Default benchmark settings (same as redis-benchmark).
+const (
+	defaultRequests = 100000
+	defaultClients  = 50
+	defaultDataSize = 3
+)
+
+// ErrUnknownTest is returned when running a test
+// that is not in the Tests list.
+var ErrUnknownTest = errors.New("bench: unknown test")
+
+// Options configures the benchmark.
+type Options struct {
+	// Tests are the names of the tests to run (see [Tests]).
+	// If empty, runs all tests.
+	Tests []string
+	// Requests is the total number of requests per test.
+	// If zero, uses 100000.
+	Requests int
+	// Clients are the numbers of concurrent clients. Each test runs
+	// once for each number, so a list makes a concurrency sweep.
+	// If empty, uses 50 clients.
+	Clients []int
+	// Pipeline is the number of requests sent in a single round trip.
+	// If zero, uses 1 (no pipelining).
+	Pipeline int
+	// KeySpace is the number of distinct keys (random keys are used
+	// instead of a single one, like -r in redis-benchmark).
+	// If zero, all requests use the same key.
+	KeySpace int
+	// DataSize is the size of the SET/HSET/MSET values in bytes.
+	// If zero, uses 3.
+	DataSize int
+	// Progress is called after each result.
+	// If nil, it is ignored.
+	Progress func(Result)
+}
+
+// Result describes a single test run.
+type Result struct {
+	Test     string        // test name
+	Clients  int           // number of concurrent clients
+	Pipeline int           // requests per round trip
+	Requests int           // completed requests
+	Duration time.Duration // total run time
+	P50      time.Duration // median round trip latency
+	P95      time.Duration // 95th percentile round trip latency
+	P99      time.Duration // 99th percentile round trip latency
+	Max      time.Duration // maximum round trip latency
+}
+
+// Throughput returns the number of requests per second.
+func (r Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Duration.Seconds()
+}
+
+// String returns the result in the redis-benchmark -q format,
+// followed by the latency percentiles.
+func (r Result) String() string {
+	return fmt.Sprintf("%s: %.2f requests per second, clients=%d, pipeline=%d, "+
+		"p50=%.3f msec, p95=%.3f msec, p99=%.3f msec, max=%.3f msec",
+		strings.ToUpper(r.Test), r.Throughput(), r.Clients, r.Pipeline,
+		msec(r.P50), msec(r.P95), msec(r.P99), msec(r.Max))
+}
+
+// Run runs the tests against the target and returns the results
+// (one for each test and number of clients). Stops at the first
+// failed request, or when ctx is canceled.
+//
+// The opts parameter is optional. If nil, uses default options.
+func Run(ctx context.Context, target Target, opts *Options) ([]Result, error) {
+	o := applyOptions(opts)
+	for _, test := range o.Tests {
+		if !slices.Contains(Tests, test) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownTest, test)
+		}
+	}
+
+	var results []Result
+	for _, test := range o.Tests {
+		for _, clients := range o.Clients {
+			res, err := run(ctx, target, test, clients, o)
+			if err != nil {
+				return results, fmt.Errorf("bench: %s: %w", test, err)
+			}
+			results = append(results, res)
+			if o.Progress != nil {
+				o.Progress(res)
+			}
+		}
+	}
+	return results, nil
+}
+
+// applyOptions returns a copy of the options with the defaults applied.
+func applyOptions(opts *Options) *Options {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if len(o.Tests) == 0 {
+		o.Tests = Tests
+	}
+	o.Tests = slices.Clone(o.Tests)
+	for i, test := range o.Tests {
+		o.Tests[i] = strings.ToLower(test)
+	}
+	if o.Requests <= 0 {
+		o.Requests = defaultRequests
+	}
+	if len(o.Clients) == 0 {
+		o.Clients = []int{defaultClients}
+	}
+	if o.Pipeline <= 0 {
+		o.Pipeline = 1
+	}
+	if o.DataSize <= 0 {
+		o.DataSize = defaultDataSize
+	}
+	return &o
+}
+
+// run runs a single test with the given number of clients.
+func run(ctx context.Context, target Target, test string, clients int, o *Options) (Result, error) {
+	clients = max(clients, 1)
+	conns := make([]Conn, clients)
+	for i := range conns {
+		conn, err := target.Connect()
+		if err != nil {
+			for _, c := range conns[:i] {
+				_ = c.Close()
+			}
+			return Result{}, err
+		}
+		conns[i] = conn
+	}
+
+	var (
+		claimed   atomic.Int64 // requests claimed by the clients
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		latencies []time.Duration
+		firstErr  error
+	)
+	value := strings.Repeat("x", o.DataSize)
+	start := time.Now()
+	for i, conn := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			rnd := rand.New(rand.NewPCG(uint64(i), uint64(start.UnixNano())))
+			gen := generator{test: test, keySpace: o.KeySpace, value: value, rnd: rnd}
+			var lats []time.Duration
+			var err error
+			for ctx.Err() == nil {
+				n := int(claimed.Add(int64(o.Pipeline)))
+				size := o.Pipeline - max(n-o.Requests, 0)
+				if size <= 0 {
+					break
+				}
+				cmds := make([][]string, size)
+				for j := range cmds {
+					cmds[j] = gen.next()
+				}
+				t := time.Now()
+				if err = conn.Do(cmds); err != nil {
+					break
+				}
+				lats = append(lats, time.Since(t))
+			}
+			mu.Lock()
+			latencies = append(latencies, lats...)
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	if firstErr != nil {
+		return Result{}, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+
+	slices.Sort(latencies)
+	return Result{
+		Test:     test,
+		Clients:  clients,
+		Pipeline: o.Pipeline,
+		Requests: o.Requests,
+		Duration: elapsed,
+		P50:      percentile(latencies, 50),
+		P95:      percentile(latencies, 95),
+		P99:      percentile(latencies, 99),
+		Max:      percentile(latencies, 100),
+	}, nil
+}
+
+// generator creates the test commands (same as redis-benchmark).
+type generator struct {
+	test     string
+	keySpace int
+	value    string
+	rnd      *rand.Rand
+}
+
+// next returns the next command.
+func (g *generator) next() []string {
+	switch g.test {
+	case "ping":
+		return []string{"ping"}
+	case "set":
+		return []string{"set", "key:" + g.randInt(), g.value}
+	case "get":
+		return []string{"get", "key:" + g.randInt()}
+	case "incr":
+		return []string{"incr", "counter:" + g.randInt()}
+	case "hset":
+		return []string{"hset", "myhash", "element:" + g.randInt(), g.value}
+	case "mset":
+		args := []string{"mset"}
+		for range 10 {
+			args = append(args, "key:"+g.randInt(), g.value)
+		}
+		return args
+	}
+	panic("unknown test: " + g.test)
+}
+
+// randInt returns the __rand_int__ part of the key.
+func (g *generator) randInt() string {
+	n := 0
+	if g.keySpace > 0 {
+		n = g.rnd.IntN(g.keySpace)
+	}
+	return fmt.Sprintf("%012d", n)
+}
+
+// percentile returns the p-th percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := (len(sorted)*p+99)/100 - 1
+	return sorted[max(idx, 0)]
+}
+
+// msec returns the duration in milliseconds.
+func msec(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
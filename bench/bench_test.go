@@ -0,0 +1,98 @@
+package bench_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/bench"
+	"github.com/nalgeon/redka/internal/server"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestEmbedded(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	var progress []bench.Result
+	results, err := bench.Run(context.Background(), bench.Embedded(db), &bench.Options{
+		Requests: 100,
+		Clients:  []int{1, 4},
+		Pipeline: 8,
+		KeySpace: 10,
+		Progress: func(res bench.Result) { progress = append(progress, res) },
+	})
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(results), len(bench.Tests)*2)
+	testx.AssertEqual(t, len(progress), len(results))
+	for _, res := range results {
+		testx.AssertEqual(t, res.Requests, 100)
+		testx.AssertEqual(t, res.Throughput() > 0, true)
+		testx.AssertEqual(t, res.P50 <= res.P99 && res.P99 <= res.Max, true)
+	}
+	testx.AssertEqual(t, results[0].Test, "ping")
+	testx.AssertEqual(t, results[1].Clients, 4)
+
+	// The keys are within the key space.
+	keys, err := db.Key().Keys("key:*")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(keys) > 0 && len(keys) <= 10, true)
+	total := 0
+	for i := range 10 {
+		val, _ := db.Str().Get("counter:00000000000" + string(rune('0'+i)))
+		if val.Exists() {
+			total += val.MustInt()
+		}
+	}
+	testx.AssertEqual(t, total, 200)
+}
+
+func TestServer(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testx.AssertNoErr(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+	srv := server.New(addr, db, nil)
+	srv.Start()
+	defer srv.Stop()
+
+	var results []bench.Result
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		results, err = bench.Run(context.Background(), bench.Server(addr), &bench.Options{
+			Tests:    []string{"SET", "get"},
+			Requests: 50,
+			Clients:  []int{2},
+			Pipeline: 4,
+		})
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(results), 2)
+	testx.AssertEqual(t, strings.HasPrefix(results[0].String(), "SET: "), true)
+	val, _ := db.Str().Get("key:000000000000")
+	testx.AssertEqual(t, val.String(), "xxx")
+}
+
+func TestUnknownTest(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+	_, err = bench.Run(context.Background(), bench.Embedded(db), &bench.Options{
+		Tests: []string{"lpush"},
+	})
+	testx.AssertEqual(t, errors.Is(err, bench.ErrUnknownTest), true)
+}
@@ -0,0 +1,137 @@
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+// Target is the system under test.
+type Target interface {
+	// Connect returns a new client connection.
+	// Each benchmark client uses its own connection.
+	Connect() (Conn, error)
+}
+
+// Conn is a client connection to the target.
+type Conn interface {
+	// Do runs the commands in a single round trip
+	// (as a pipeline) and returns the first error.
+	Do(cmds [][]string) error
+	// Close closes the connection.
+	Close() error
+}
+
+// Embedded returns a target that runs the commands
+// in-process against the database, without the network.
+func Embedded(db *redka.DB) Target {
+	return embedded{db: db}
+}
+
+// embedded runs the commands against the database.
+type embedded struct {
+	db *redka.DB
+}
+
+func (e embedded) Connect() (Conn, error) {
+	return &embeddedConn{red: command.RedkaDB(e.db)}, nil
+}
+
+// embeddedConn runs the commands one by one
+// and discards the replies.
+type embeddedConn struct {
+	red command.Redka
+	w   discard
+}
+
+func (c *embeddedConn) Do(cmds [][]string) error {
+	for _, args := range cmds {
+		bargs := make([][]byte, len(args))
+		for i, arg := range args {
+			bargs[i] = []byte(arg)
+		}
+		pcmd, err := command.Parse(bargs)
+		if err != nil {
+			return err
+		}
+		if _, err := pcmd.Run(&c.w, c.red); err != nil {
+			return err
+		}
+		if c.w.err != "" {
+			return errors.New(c.w.err)
+		}
+	}
+	return nil
+}
+
+func (c *embeddedConn) Close() error {
+	return nil
+}
+
+// Server returns a target that sends the commands to the server
+// (Redka or Redis) at addr (host:port) over RESP.
+func Server(addr string) Target {
+	return server{addr: addr}
+}
+
+// server sends the commands over the network.
+type server struct {
+	addr string
+}
+
+func (s server) Connect() (Conn, error) {
+	c, err := resp.Dial(context.Background(), s.addr)
+	if err != nil {
+		return nil, err
+	}
+	return &serverConn{c: c}, nil
+}
+
+// serverConn pipelines the commands over a RESP connection.
+type serverConn struct {
+	c *resp.Client
+}
+
+func (c *serverConn) Do(cmds [][]string) error {
+	for _, args := range cmds {
+		if err := c.c.Send(args...); err != nil {
+			return err
+		}
+	}
+	if err := c.c.Flush(); err != nil {
+		return err
+	}
+	var first error
+	for _, args := range cmds {
+		if _, err := c.c.Receive(); err != nil && first == nil {
+			first = fmt.Errorf("%s: %w", args[0], err)
+		}
+	}
+	return first
+}
+
+func (c *serverConn) Close() error {
+	return c.c.Close()
+}
+
+// discard is a command.Writer that discards the replies
+// but keeps the last error.
+type discard struct {
+	err string
+}
+
+func (w *discard) WriteError(msg string)       { w.err = msg }
+func (w *discard) WriteString(str string)      {}
+func (w *discard) WriteBulk(bulk []byte)       {}
+func (w *discard) WriteBulkString(bulk string) {}
+func (w *discard) WriteInt(num int)            {}
+func (w *discard) WriteInt64(num int64)        {}
+func (w *discard) WriteUint64(num uint64)      {}
+func (w *discard) WriteArray(count int)        {}
+func (w *discard) WriteNull()                  {}
+func (w *discard) WriteRaw(data []byte)        {}
+func (w *discard) WriteAny(v any)              {}
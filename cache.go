@@ -0,0 +1,192 @@
+package redka
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"time"
+)
+
+// cacheNotFound is the value cached for the "not found" results
+// (see CacheOptions.NegativeTTL). A computed value equal to it
+// is read back as "not found" too, so it is deliberately unlikely.
+var cacheNotFound = []byte("\x00redka:not-found\x00")
+
+// CacheOptions configures the cache.
+type CacheOptions struct {
+	// StaleWhileRevalidate is how long after the TTL a stale value
+	// can still be returned while it is recomputed in the background.
+	// Zero disables stale values.
+	StaleWhileRevalidate time.Duration
+	// NegativeTTL is how long a "not found" result (an error matching
+	// [ErrNotFound] returned by the compute function) is cached.
+	// Usually shorter than the value TTL, so that new data shows up
+	// soon. Zero disables caching such results.
+	NegativeTTL time.Duration
+	// OnError is called when a background recomputation fails.
+	// If nil, the errors are logged.
+	OnError func(key string, err error)
+}
+
+// Cache implements the cache-aside pattern on top of the string keys:
+// it returns the cached value, or computes and caches it on a miss.
+// Concurrent misses for the same key are coalesced, so the value
+// is computed once (within the process). Safe for concurrent use.
+type Cache struct {
+	db   *DB
+	opts CacheOptions
+
+	mu    sync.Mutex
+	calls map[string]*cacheCall // in-flight computations
+}
+
+// cacheCall is an in-flight computation.
+type cacheCall struct {
+	wg  sync.WaitGroup
+	val []byte
+	err error
+}
+
+// Cache returns a cache backed by the database.
+// The opts parameter is optional. If nil, uses default options.
+func (db *DB) Cache(opts *CacheOptions) *Cache {
+	c := &Cache{db: db, calls: map[string]*cacheCall{}}
+	if opts != nil {
+		c.opts = *opts
+	}
+	return c
+}
+
+// GetOrCompute returns the value of the key. If the key does not exist,
+// calls fn to compute the value, and sets it with the given ttl
+// (if ttl > 0, otherwise the value does not expire). If several
+// goroutines miss the same key, fn is called only once, and they all
+// get its result. The errors returned by fn are not cached.
+//
+// With CacheOptions.StaleWhileRevalidate, a value that is older
+// than ttl (but still within the stale window) is returned as is,
+// while fn recomputes it in the background.
+//
+// With CacheOptions.NegativeTTL, a "not found" error returned by fn
+// is cached for the negative TTL, and GetOrCompute returns
+// [ErrNotFound] without calling fn until it expires. Check for it
+// with errors.Is instead of caching a magic value.
+func (c *Cache) GetOrCompute(key string, ttl time.Duration, fn func() ([]byte, error)) ([]byte, error) {
+	return c.getOrCompute(key, ttl, c.opts.NegativeTTL, fn)
+}
+
+// getOrCompute implements GetOrCompute with the given negative TTL.
+func (c *Cache) getOrCompute(key string, ttl, negTTL time.Duration, fn func() ([]byte, error)) ([]byte, error) {
+	val, found, stale, err := c.get(key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		if stale {
+			c.refresh(key, ttl, negTTL, fn)
+		}
+		return val, nil
+	}
+	return c.do(key, ttl, negTTL, fn)
+}
+
+// get returns the cached value, whether it exists,
+// and whether it is stale. Returns ErrNotFound if
+// a "not found" result is cached.
+func (c *Cache) get(key string, ttl time.Duration) (val []byte, found, stale bool, err error) {
+	err = c.db.View(func(tx *Tx) error {
+		k, err := tx.Key().Get(key)
+		if err == ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		v, err := tx.Str().Get(key)
+		if err != nil || !v.Exists() {
+			return err
+		}
+		if bytes.Equal(v.Bytes(), cacheNotFound) {
+			return ErrNotFound
+		}
+		val, found = v.Bytes(), true
+		if c.opts.StaleWhileRevalidate > 0 && ttl > 0 && k.ETime != nil {
+			left := time.UnixMilli(*k.ETime).Sub(c.db.Now())
+			stale = left <= c.opts.StaleWhileRevalidate
+		}
+		return nil
+	})
+	return val, found, stale, err
+}
+
+// do computes and caches the value, coalescing
+// the concurrent calls for the same key.
+func (c *Cache) do(key string, ttl, negTTL time.Duration, fn func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		call.wg.Wait()
+		return call.val, call.err
+	}
+	call := c.start(key)
+	c.mu.Unlock()
+
+	c.compute(key, ttl, negTTL, fn, call)
+	return call.val, call.err
+}
+
+// refresh recomputes the value in the background,
+// unless it is already being computed.
+func (c *Cache) refresh(key string, ttl, negTTL time.Duration, fn func() ([]byte, error)) {
+	c.mu.Lock()
+	if _, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		return
+	}
+	call := c.start(key)
+	c.mu.Unlock()
+
+	go func() {
+		c.compute(key, ttl, negTTL, fn, call)
+		if call.err == nil || (negTTL > 0 && errors.Is(call.err, ErrNotFound)) {
+			return
+		}
+		if c.opts.OnError != nil {
+			c.opts.OnError(key, call.err)
+		} else {
+			c.db.log.Error("cache refresh", "key", key, "error", call.err)
+		}
+	}()
+}
+
+// start registers an in-flight computation.
+// Must be called with c.mu held.
+func (c *Cache) start(key string) *cacheCall {
+	call := &cacheCall{}
+	call.wg.Add(1)
+	c.calls[key] = call
+	return call
+}
+
+// compute calls fn, caches the result, and completes the call.
+// Caches a "not found" error for negTTL if it is positive.
+func (c *Cache) compute(key string, ttl, negTTL time.Duration, fn func() ([]byte, error), call *cacheCall) {
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		call.wg.Done()
+	}()
+	call.val, call.err = fn()
+	if call.err != nil {
+		call.val = nil
+		if negTTL > 0 && errors.Is(call.err, ErrNotFound) {
+			_ = c.db.Str().SetExpires(key, cacheNotFound, negTTL)
+		}
+		return
+	}
+	if ttl > 0 {
+		ttl += c.opts.StaleWhileRevalidate
+	}
+	call.err = c.db.Str().SetExpires(key, call.val, ttl)
+}
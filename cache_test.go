@@ -0,0 +1,174 @@
+package redka_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestCacheGetOrCompute(t *testing.T) {
+	t.Run("miss and hit", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := db.Cache(nil)
+
+		var calls int
+		fn := func() ([]byte, error) {
+			calls++
+			return []byte("alice"), nil
+		}
+		val, err := cache.GetOrCompute("name", time.Minute, fn)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, string(val), "alice")
+
+		val, err = cache.GetOrCompute("name", time.Minute, fn)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, string(val), "alice")
+		testx.AssertEqual(t, calls, 1)
+
+		key, _ := db.Key().Get("name")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("no ttl", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := db.Cache(nil)
+
+		_, err := cache.GetOrCompute("name", 0, func() ([]byte, error) {
+			return []byte("alice"), nil
+		})
+		testx.AssertNoErr(t, err)
+		key, _ := db.Key().Get("name")
+		testx.AssertEqual(t, key.ETime, (*int64)(nil))
+	})
+	t.Run("error", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := db.Cache(nil)
+
+		errFailed := errors.New("failed")
+		_, err := cache.GetOrCompute("name", time.Minute, func() ([]byte, error) {
+			return nil, errFailed
+		})
+		testx.AssertErr(t, err, errFailed)
+		count, _ := db.Key().Count("name")
+		testx.AssertEqual(t, count, 0)
+	})
+	t.Run("negative", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := db.Cache(&redka.CacheOptions{NegativeTTL: 50 * time.Millisecond})
+
+		var calls int
+		found := false
+		fn := func() ([]byte, error) {
+			calls++
+			if !found {
+				return nil, fmt.Errorf("user 42: %w", redka.ErrNotFound)
+			}
+			return []byte("alice"), nil
+		}
+		_, err := cache.GetOrCompute("user:42", time.Minute, fn)
+		testx.AssertErr(t, err, redka.ErrNotFound)
+		_, err = cache.GetOrCompute("user:42", time.Minute, fn)
+		testx.AssertErr(t, err, redka.ErrNotFound)
+		testx.AssertEqual(t, calls, 1)
+
+		// The negative TTL is separate from the value one.
+		key, _ := db.Key().Get("user:42")
+		left := time.UnixMilli(*key.ETime).Sub(time.Now())
+		testx.AssertEqual(t, left <= 50*time.Millisecond, true)
+
+		found = true
+		time.Sleep(60 * time.Millisecond)
+		val, err := cache.GetOrCompute("user:42", time.Minute, fn)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, string(val), "alice")
+		testx.AssertEqual(t, calls, 2)
+	})
+	t.Run("negative disabled", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := db.Cache(nil)
+
+		_, err := cache.GetOrCompute("user:42", time.Minute, func() ([]byte, error) {
+			return nil, redka.ErrNotFound
+		})
+		testx.AssertErr(t, err, redka.ErrNotFound)
+		count, _ := db.Key().Count("user:42")
+		testx.AssertEqual(t, count, 0)
+	})
+	t.Run("singleflight", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := db.Cache(nil)
+
+		var calls atomic.Int64
+		release := make(chan struct{})
+		fn := func() ([]byte, error) {
+			calls.Add(1)
+			<-release
+			return []byte("alice"), nil
+		}
+
+		const n = 10
+		var wg sync.WaitGroup
+		var started sync.WaitGroup
+		results := make([]string, n)
+		for i := range n {
+			wg.Add(1)
+			started.Add(1)
+			go func() {
+				defer wg.Done()
+				started.Done()
+				val, _ := cache.GetOrCompute("name", time.Minute, fn)
+				results[i] = string(val)
+			}()
+		}
+		started.Wait()
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		testx.AssertEqual(t, calls.Load(), int64(1))
+		for _, res := range results {
+			testx.AssertEqual(t, res, "alice")
+		}
+	})
+	t.Run("stale while revalidate", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := db.Cache(&redka.CacheOptions{StaleWhileRevalidate: time.Minute})
+
+		var calls atomic.Int64
+		fn := func() ([]byte, error) {
+			n := calls.Add(1)
+			return []byte{byte('0' + n)}, nil
+		}
+		_, _ = cache.GetOrCompute("num", 50*time.Millisecond, fn)
+		val, _ := cache.GetOrCompute("num", 50*time.Millisecond, fn)
+		testx.AssertEqual(t, string(val), "1")
+
+		time.Sleep(60 * time.Millisecond)
+		val, err := cache.GetOrCompute("num", 50*time.Millisecond, fn)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, string(val), "1")
+
+		// Wait for the background recomputation.
+		for range 100 {
+			if v, _ := db.Str().Get("num"); v.String() == "2" {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		val, _ = cache.GetOrCompute("num", 50*time.Millisecond, fn)
+		testx.AssertEqual(t, string(val), "2")
+		testx.AssertEqual(t, calls.Load(), int64(2))
+	})
+}
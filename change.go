@@ -0,0 +1,40 @@
+package redka
+
+import "github.com/nalgeon/redka/internal/core"
+
+// ChangeOp is the kind of change made to a key.
+type ChangeOp = core.ChangeOp
+
+// Kinds of changes.
+const (
+	ChangeSet     = core.ChangeSet     // value, hash field or sorted set member set
+	ChangeDelete  = core.ChangeDelete  // key, hash field or sorted set member deleted
+	ChangeExpire  = core.ChangeExpire  // expiration time changed
+	ChangeRename  = core.ChangeRename  // key renamed
+	ChangeExpired = core.ChangeExpired // key deleted after its expiration time
+)
+
+// Change describes a single change made to a key.
+// See [core.Change] for the meaning of the Before and After values.
+type Change = core.Change
+
+// ChangeEvent describes the changes made by a committed
+// transaction, in the order they were made.
+type ChangeEvent = core.ChangeEvent
+
+// OnChange registers a function to be called after each committed
+// write transaction with the changes it made (keys, hash fields and
+// sorted set members set or deleted, expiration times changed, keys
+// renamed or expired). Use it to maintain derived data or external
+// indexes. See [DB.Watch] for a simpler per-key event feed.
+//
+// The function is called synchronously by the goroutine that
+// committed the transaction, so it should return quickly.
+// It may read from or write to the database, but the writes
+// trigger OnChange too.
+//
+// Changes made with Key().DeleteAll (FLUSHDB) are not reported.
+// Keys overwritten by a rename are not reported as deleted.
+func (db *DB) OnChange(fn func(ChangeEvent)) {
+	db.changes.Subscribe(fn)
+}
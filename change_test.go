@@ -0,0 +1,107 @@
+package redka_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestOnChange(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+
+	var events []redka.ChangeEvent
+	db.OnChange(func(e redka.ChangeEvent) {
+		events = append(events, e)
+	})
+
+	t.Run("string", func(t *testing.T) {
+		events = nil
+		_ = db.Str().Set("name", "bob")
+		testx.AssertEqual(t, len(events), 1)
+		testx.AssertEqual(t, events[0].Changes, []redka.Change{
+			{Op: redka.ChangeSet, Key: "name", Type: 1, Before: []byte("alice"), After: []byte("bob")},
+		})
+	})
+	t.Run("transaction", func(t *testing.T) {
+		events = nil
+		err := db.Update(func(tx *redka.Tx) error {
+			_, _ = tx.Hash().Set("person", "age", 25)
+			_, _ = tx.SortedSet().Add("scores", "alice", 11)
+			_, _ = tx.Key().Expire("name", time.Minute)
+			return nil
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(events), 1)
+		changes := events[0].Changes
+		testx.AssertEqual(t, len(changes), 3)
+		testx.AssertEqual(t, changes[0], redka.Change{
+			Op: redka.ChangeSet, Key: "person", Type: 4, Field: "age", After: []byte("25"),
+		})
+		testx.AssertEqual(t, changes[1], redka.Change{
+			Op: redka.ChangeSet, Key: "scores", Type: 5, Field: "alice", After: []byte("11"),
+		})
+		testx.AssertEqual(t, changes[2].Op, redka.ChangeExpire)
+		testx.AssertEqual(t, changes[2].Key, "name")
+		testx.AssertEqual(t, changes[2].Before.Exists(), false)
+		testx.AssertEqual(t, changes[2].After.Exists(), true)
+	})
+	t.Run("rollback", func(t *testing.T) {
+		events = nil
+		err := db.Update(func(tx *redka.Tx) error {
+			_ = tx.Str().Set("name", "carol")
+			return errors.New("rollback")
+		})
+		testx.AssertEqual(t, err.Error(), "rollback")
+		testx.AssertEqual(t, len(events), 0)
+	})
+	t.Run("rename", func(t *testing.T) {
+		events = nil
+		_ = db.Key().Rename("name", "user")
+		testx.AssertEqual(t, len(events), 1)
+		testx.AssertEqual(t, events[0].Changes, []redka.Change{
+			{Op: redka.ChangeRename, Key: "name", Type: 1, Before: []byte("name"), After: []byte("user")},
+		})
+	})
+	t.Run("delete", func(t *testing.T) {
+		events = nil
+		_, _ = db.Hash().Set("person", "name", "alice")
+		events = nil
+		_, _ = db.Key().Delete("person")
+		testx.AssertEqual(t, len(events), 1)
+		changes := events[0].Changes
+		testx.AssertEqual(t, len(changes), 3)
+		testx.AssertEqual(t, changes[0], redka.Change{Op: redka.ChangeDelete, Key: "person", Type: 4})
+		for _, ch := range changes[1:] {
+			testx.AssertEqual(t, ch.Op, redka.ChangeDelete)
+			testx.AssertEqual(t, ch.Key, "person")
+			testx.AssertEqual(t, ch.Before.Exists(), true)
+		}
+	})
+	t.Run("expired", func(t *testing.T) {
+		_ = db.Str().SetExpires("tmp", "x", time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+		events = nil
+		_, _ = db.Key().DeleteExpired(0)
+		testx.AssertEqual(t, len(events), 1)
+		changes := events[0].Changes
+		testx.AssertEqual(t, len(changes), 2)
+		testx.AssertEqual(t, changes[0], redka.Change{Op: redka.ChangeExpired, Key: "tmp", Type: 1})
+		testx.AssertEqual(t, changes[1].Op, redka.ChangeDelete)
+		testx.AssertEqual(t, changes[1].Before.String(), "x")
+	})
+	t.Run("read", func(t *testing.T) {
+		events = nil
+		_, _ = db.Str().Get("user")
+		_ = db.View(func(tx *redka.Tx) error {
+			_, err := tx.Str().Get("user")
+			return err
+		})
+		testx.AssertEqual(t, len(events), 0)
+	})
+}
@@ -0,0 +1,217 @@
+package redka
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+const sqlDatabaseFile = `select file from pragma_database_list where name = 'main'`
+
+// walPollInterval is how often the background
+// checkpointer checks the WAL size.
+const walPollInterval = time.Second
+
+// CheckpointMode is the mode of a WAL checkpoint.
+// See https://sqlite.org/pragma.html#pragma_wal_checkpoint for details.
+type CheckpointMode string
+
+// WAL checkpoint modes.
+const (
+	// CheckpointPassive copies as many pages as possible
+	// without waiting for the readers and writers.
+	CheckpointPassive CheckpointMode = "passive"
+	// CheckpointFull waits for the writers and readers,
+	// and then copies all the pages.
+	CheckpointFull CheckpointMode = "full"
+	// CheckpointRestart is like CheckpointFull, but also waits
+	// for the readers, so that the next writer starts the WAL
+	// file from the beginning.
+	CheckpointRestart CheckpointMode = "restart"
+	// CheckpointTruncate is like CheckpointRestart,
+	// but also truncates the WAL file to zero bytes.
+	CheckpointTruncate CheckpointMode = "truncate"
+)
+
+// CheckpointResult describes the outcome of a checkpoint.
+type CheckpointResult struct {
+	Busy         bool // the checkpoint could not complete
+	Log          int  // number of pages in the WAL
+	Checkpointed int  // number of pages copied to the database
+}
+
+// CheckpointOptions configures the background checkpointer.
+// A checkpoint runs when any of the thresholds is reached.
+type CheckpointOptions struct {
+	// Mode is the checkpoint mode. If empty, uses CheckpointPassive.
+	// Use CheckpointTruncate to reclaim the disk space taken
+	// by the WAL file after write bursts.
+	Mode CheckpointMode
+	// MaxWALSize is the WAL file size in bytes that triggers
+	// a checkpoint. If zero, the size is not checked.
+	MaxWALSize int64
+	// Interval is the maximum time between checkpoints.
+	// If zero, the time is not checked.
+	Interval time.Duration
+}
+
+// WALStats describes the write-ahead log of the database.
+type WALStats struct {
+	Size           int64            // WAL file size in bytes
+	Checkpoints    int              // checkpoints made with redka
+	LastCheckpoint time.Time        // time of the last checkpoint
+	LastResult     CheckpointResult // result of the last checkpoint
+}
+
+// Checkpoint copies the pages from the write-ahead log (WAL)
+// into the database file. SQLite checkpoints automatically when
+// the WAL reaches 1000 pages, but it never shrinks the WAL file,
+// and long-running readers may prevent the checkpoint from
+// completing. Use CheckpointTruncate to shrink the WAL file.
+func (db *DB) Checkpoint(mode CheckpointMode) (CheckpointResult, error) {
+	switch mode {
+	case CheckpointPassive, CheckpointFull, CheckpointRestart, CheckpointTruncate:
+	default:
+		return CheckpointResult{}, fmt.Errorf("checkpoint: invalid mode %q", mode)
+	}
+	ctx := context.Background()
+	conn, err := db.SQL.Conn(ctx)
+	if err != nil {
+		return CheckpointResult{}, fmt.Errorf("checkpoint: %w", err)
+	}
+	defer conn.Close()
+	res, err := db.checkpoint(ctx, conn, mode)
+	if err != nil {
+		return res, fmt.Errorf("checkpoint: %w", err)
+	}
+	return res, nil
+}
+
+// checkpoint checkpoints the WAL using the given connection.
+// With the WAL archiving enabled, archives the WAL beforehand.
+func (db *DB) checkpoint(ctx context.Context, conn *sql.Conn, mode CheckpointMode) (CheckpointResult, error) {
+	var res CheckpointResult
+	if db.archive != nil {
+		if err := db.archive.saveWAL(ctx); err != nil {
+			return res, err
+		}
+	}
+	var busy int
+	query := "pragma wal_checkpoint(" + string(mode) + ")"
+	err := conn.QueryRowContext(ctx, query).Scan(&busy, &res.Log, &res.Checkpointed)
+	if err != nil {
+		return res, err
+	}
+	res.Busy = busy != 0
+	db.wal.record(res)
+	return res, nil
+}
+
+// WALStats returns the write-ahead log statistics.
+// The size is zero if the database is not in WAL mode.
+func (db *DB) WALStats() (WALStats, error) {
+	stats := db.wal.stats()
+	size, err := db.walSize()
+	if err != nil {
+		return stats, err
+	}
+	stats.Size = size
+	return stats, nil
+}
+
+// walSize returns the size of the WAL file.
+func (db *DB) walSize() (int64, error) {
+	var path string
+	if err := db.SQL.QueryRow(sqlDatabaseFile).Scan(&path); err != nil {
+		return 0, err
+	}
+	if path == "" {
+		// In-memory or temporary database.
+		return 0, nil
+	}
+	fi, err := os.Stat(path + "-wal")
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
+
+// startCheckpointer starts the goroutine that runs in the
+// background and checkpoints the WAL when the thresholds
+// are reached. Returns nil if the checkpointer is disabled.
+func (db *DB) startCheckpointer(opts *CheckpointOptions) *time.Ticker {
+	if opts == nil || (opts.MaxWALSize <= 0 && opts.Interval <= 0) {
+		return nil
+	}
+	mode := opts.Mode
+	if mode == "" {
+		mode = CheckpointPassive
+	}
+	poll := walPollInterval
+	if opts.Interval > 0 {
+		poll = min(poll, opts.Interval)
+	}
+
+	start := time.Now()
+	ticker := time.NewTicker(poll)
+	go func() {
+		for range ticker.C {
+			last := db.wal.stats().LastCheckpoint
+			if last.Before(start) {
+				last = start
+			}
+			due := opts.Interval > 0 && time.Since(last) >= opts.Interval
+			if !due && opts.MaxWALSize > 0 {
+				size, err := db.walSize()
+				if err != nil {
+					db.log.Error("wal size", "error", err)
+					continue
+				}
+				due = size >= opts.MaxWALSize
+			}
+			if !due {
+				continue
+			}
+			res, err := db.Checkpoint(mode)
+			if err != nil {
+				db.log.Error("checkpoint", "error", err)
+				continue
+			}
+			db.log.Info("checkpoint", "mode", mode, "busy", res.Busy,
+				"log", res.Log, "checkpointed", res.Checkpointed)
+		}
+	}()
+	return ticker
+}
+
+// walState tracks the checkpoints. Safe for concurrent use.
+type walState struct {
+	mu    sync.Mutex
+	count int
+	last  time.Time
+	res   CheckpointResult
+}
+
+// record records a completed checkpoint.
+func (w *walState) record(res CheckpointResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.count++
+	w.last = time.Now()
+	w.res = res
+}
+
+// stats returns the checkpoint statistics.
+func (w *walState) stats() WALStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return WALStats{Checkpoints: w.count, LastCheckpoint: w.last, LastResult: w.res}
+}
@@ -0,0 +1,59 @@
+package redka_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	db, err := redka.Open(path, nil)
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	t.Run("truncate", func(t *testing.T) {
+		_ = db.Str().Set("name", "alice")
+		stats, err := db.WALStats()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, stats.Size > 0, true)
+
+		res, err := db.Checkpoint(redka.CheckpointTruncate)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res.Busy, false)
+
+		stats, err = db.WALStats()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, stats.Size, int64(0))
+		testx.AssertEqual(t, stats.Checkpoints, 1)
+		testx.AssertEqual(t, stats.LastResult, res)
+
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("invalid mode", func(t *testing.T) {
+		_, err := db.Checkpoint("invalid")
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
+
+func TestAutoCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	db, err := redka.Open(path, &redka.Options{
+		AutoCheckpoint: &redka.CheckpointOptions{
+			Mode:     redka.CheckpointTruncate,
+			Interval: 10 * time.Millisecond,
+		},
+	})
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	waitFor(t, func() bool {
+		stats, _ := db.WALStats()
+		return stats.Checkpoints > 0 && stats.Size == 0
+	})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+// conn executes commands on a server or a database.
+// The server connection is a *resp.Client.
+type conn interface {
+	// Do executes the command and returns the reply
+	// (see resp.ReadReply for the reply types).
+	// Returns a resp.Error if the command failed.
+	Do(args ...string) (any, error)
+	Close() error
+}
+
+// localConn executes commands on a database opened
+// directly, without a server.
+type localConn struct {
+	db      *redka.DB
+	inMulti bool
+	queue   []command.Cmd
+}
+
+func newLocalConn(db *redka.DB) *localConn {
+	return &localConn{db: db}
+}
+
+// Do executes the command and returns the reply.
+// Queues the commands between MULTI and EXEC, and runs
+// them in a single transaction on EXEC.
+func (c *localConn) Do(args ...string) (any, error) {
+	if len(args) == 0 {
+		return nil, resp.Error(command.ErrInvalidArgNum.Error())
+	}
+	switch strings.ToLower(args[0]) {
+	case "multi":
+		if c.inMulti {
+			return nil, resp.Error(command.ErrNestedMulti.Error())
+		}
+		c.inMulti = true
+		return "OK", nil
+	case "exec":
+		if !c.inMulti {
+			return nil, resp.Error(command.ErrNotInMulti.Error())
+		}
+		return c.exec()
+	case "discard":
+		if !c.inMulti {
+			return nil, resp.Error(command.ErrNotInMulti.Error())
+		}
+		c.inMulti, c.queue = false, nil
+		return "OK", nil
+	}
+
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+	pcmd, err := command.Parse(bargs)
+	if err != nil {
+		return nil, resp.Error(pcmd.Error(err))
+	}
+	if c.inMulti {
+		c.queue = append(c.queue, pcmd)
+		return "QUEUED", nil
+	}
+
+	w := new(replyWriter)
+	_, _ = pcmd.Run(w, command.RedkaDB(c.db))
+	return w.result()
+}
+
+// exec runs the queued commands in a transaction.
+// Stops at the first failed command.
+func (c *localConn) exec() (any, error) {
+	cmds := c.queue
+	c.inMulti, c.queue = false, nil
+	w := new(replyWriter)
+	w.WriteArray(len(cmds))
+	_ = c.db.Update(func(tx *redka.Tx) error {
+		for _, pcmd := range cmds {
+			if _, err := pcmd.Run(w, command.RedkaTx(tx).WithProcs(c.db, tx)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return w.result()
+}
+
+// Close closes the database.
+func (c *localConn) Close() error {
+	return c.db.Close()
+}
+
+// replyWriter collects the reply written by a command
+// (see resp.ReadReply for the reply types).
+type replyWriter struct {
+	reply any
+	open  []*array // arrays waiting for the items
+}
+
+// array is an array reply with the expected number of items.
+type array struct {
+	items []any
+	size  int
+}
+
+func (w *replyWriter) WriteError(msg string) {
+	w.put(resp.Error(msg))
+}
+func (w *replyWriter) WriteString(str string) {
+	w.put(str)
+}
+func (w *replyWriter) WriteBulk(bulk []byte) {
+	w.put(bytes.Clone(bulk))
+}
+func (w *replyWriter) WriteBulkString(bulk string) {
+	w.put([]byte(bulk))
+}
+func (w *replyWriter) WriteInt(num int) {
+	w.put(int64(num))
+}
+func (w *replyWriter) WriteInt64(num int64) {
+	w.put(num)
+}
+func (w *replyWriter) WriteUint64(num uint64) {
+	w.put(int64(num))
+}
+func (w *replyWriter) WriteArray(count int) {
+	if count <= 0 {
+		w.put([]any{})
+		return
+	}
+	w.open = append(w.open, &array{items: make([]any, 0, count), size: count})
+}
+func (w *replyWriter) WriteNull() {
+	w.put(nil)
+}
+func (w *replyWriter) WriteRaw(data []byte) {
+	reply, err := resp.ReadReply(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		reply = string(data)
+	}
+	w.put(reply)
+}
+func (w *replyWriter) WriteAny(v any) {
+	switch v := v.(type) {
+	case nil:
+		w.WriteNull()
+	case string:
+		w.WriteBulkString(v)
+	case []byte:
+		w.WriteBulk(v)
+	case int:
+		w.WriteInt(v)
+	case int64:
+		w.WriteInt64(v)
+	case error:
+		w.WriteError(v.Error())
+	default:
+		w.WriteBulkString(fmt.Sprint(v))
+	}
+}
+
+// put adds the value to the innermost open array,
+// or sets it as the reply if there are none.
+func (w *replyWriter) put(v any) {
+	for len(w.open) > 0 {
+		top := w.open[len(w.open)-1]
+		top.items = append(top.items, v)
+		if len(top.items) < top.size {
+			return
+		}
+		w.open = w.open[:len(w.open)-1]
+		v = top.items
+	}
+	w.reply = v
+}
+
+// result returns the reply. Closes the arrays left
+// incomplete by a failed command.
+func (w *replyWriter) result() (any, error) {
+	for len(w.open) > 0 {
+		top := w.open[len(w.open)-1]
+		w.open = w.open[:len(w.open)-1]
+		w.put(top.items)
+	}
+	if err, ok := w.reply.(resp.Error); ok {
+		return nil, err
+	}
+	return w.reply, nil
+}
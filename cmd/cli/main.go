@@ -1,39 +1,137 @@
-// Redka CLI. Executes commands from a file.
+// Redka CLI. Runs commands interactively, in bulk
+// from the standard input, or from a file.
+// Example usage:
+//
+//	redka-cli                         # connect to localhost:6379
+//	redka-cli -h host -p 6380         # connect to a server
+//	redka-cli -db data.db             # open the database file directly
+//	redka-cli -scan -pattern 'user:*' # list the keys
+//	redka-cli -bigkeys                # find the biggest keys
+//	redka-cli -pipe < commands.txt    # bulk-load commands
+//	redka-cli commands.txt            # run a file on an in-memory database
 package main
 
 import (
 	"bytes"
+	"context"
 	"flag"
 	"fmt"
+	"net"
 	"os"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/nalgeon/redka"
 	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/resp"
 )
 
 const dbURI = ":memory:"
 
+// dialTimeout is the timeout for connecting to the server.
+const dialTimeout = 5 * time.Second
+
+// Config holds the CLI configuration.
+type Config struct {
+	Host    string
+	Port    string
+	Path    string
+	Raw     bool
+	Scan    bool
+	Pattern string
+	BigKeys bool
+	Pipe    bool
+}
+
+var config Config
+
 func init() {
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: redka-cli <filename>\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: redka-cli [options] [filename]\n")
 		flag.PrintDefaults()
 	}
+	flag.StringVar(&config.Host, "h", "localhost", "server host")
+	flag.StringVar(&config.Port, "p", "6379", "server port")
+	flag.StringVar(&config.Path, "db", "", "open the database file directly instead of connecting to a server")
+	flag.BoolVar(&config.Raw, "raw", false, "print the raw replies")
+	flag.BoolVar(&config.Scan, "scan", false, "list the keys matching the -pattern")
+	flag.StringVar(&config.Pattern, "pattern", "*", "key pattern for -scan")
+	flag.BoolVar(&config.BigKeys, "bigkeys", false, "find the biggest keys of each type")
+	flag.BoolVar(&config.Pipe, "pipe", false, "bulk-load commands (plain text or RESP) from the standard input")
 }
 
 func main() {
 	// Parse command line arguments.
 	flag.Parse()
-	if len(flag.Args()) != 1 {
+	if len(flag.Args()) > 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	// File with commands.
-	filename := flag.Arg(0)
+	// Execute the commands from the file.
+	if len(flag.Args()) == 1 {
+		runFile(flag.Arg(0))
+		return
+	}
+
+	// Connect to the server or open the database.
+	c, name, err := connect(config)
+	if err != nil {
+		fail("failed to connect: %v\n", err)
+	}
+	defer c.Close()
+
+	switch {
+	case config.Scan:
+		err = scanKeys(c, os.Stdout, config.Pattern)
+	case config.BigKeys:
+		err = bigKeys(c, os.Stdout)
+	case config.Pipe:
+		err = pipe(c, os.Stdin, os.Stdout)
+	default:
+		prompt := ""
+		if isTerminal(os.Stdin) {
+			prompt = name + "> "
+		}
+		err = repl(c, os.Stdin, os.Stdout, prompt, config.Raw)
+	}
+	if err != nil {
+		c.Close()
+		fail("%v\n", err)
+	}
+}
+
+// connect opens the database file (if the path is set),
+// or connects to the server. Returns the connection name
+// for the prompt.
+func connect(cfg Config) (conn, string, error) {
+	if cfg.Path != "" {
+		db, err := redka.Open(cfg.Path, nil)
+		if err != nil {
+			return nil, "", err
+		}
+		return newLocalConn(db), cfg.Path, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dialTimeout)
+	defer cancel()
+	addr := net.JoinHostPort(cfg.Host, cfg.Port)
+	client, err := resp.Dial(ctx, addr)
+	if err != nil {
+		return nil, "", err
+	}
+	return client, addr, nil
+}
+
+// runFile executes the commands from a file on the
+// database (in-memory unless the -db path is set).
+func runFile(filename string) {
+	uri := dbURI
+	if config.Path != "" {
+		uri = config.Path
+	}
 
 	// Open the database.
-	db, err := redka.Open(dbURI, nil)
+	db, err := redka.Open(uri, nil)
 	if err != nil {
 		fail("failed to open database: %v\n", err)
 	}
@@ -47,11 +145,18 @@ func main() {
 	// Execute the commands.
 	r := newRunner(db)
 	err = r.run(cmds)
+	_ = db.Close()
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
+// isTerminal reports whether the file is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
 // readCommands reads commands from a file.
 func readCommands(filename string) ([][]byte, error) {
 	if filename == "" {
@@ -85,7 +190,7 @@ var multiHandlers = map[string]func(*runner, []byte) error{
 	"exec": func(r *runner, cmd []byte) error {
 		fmt.Println(len(r.cmds))
 		err := r.db.Update(func(tx *redka.Tx) error {
-			return r.runBatch(r.cmds, command.RedkaTx(tx))
+			return r.runBatch(r.cmds, command.RedkaTx(tx).WithProcs(r.db, tx))
 		})
 		r.inMulti = false
 		r.clear()
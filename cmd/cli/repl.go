@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+// maxLineSize is the maximum size of an input line.
+const maxLineSize = 64 * 1024 * 1024
+
+// errUnbalanced is returned for the lines
+// with unbalanced quotes.
+var errUnbalanced = errors.New("unbalanced quotes")
+
+const replHelp = `Type a command and press Enter to run it.
+Type a command prefix, then Tab and Enter to list matching commands.
+Quote the arguments with spaces: set greeting "hello world".
+Type quit to exit.`
+
+// repl reads the commands from in, executes them
+// and prints the replies to out until the input ends
+// or the user quits. Prints the prompt before each command.
+func repl(c conn, in io.Reader, out io.Writer, prompt string, raw bool) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(nil, maxLineSize)
+	for {
+		fmt.Fprint(out, prompt)
+		if !sc.Scan() {
+			if prompt != "" {
+				fmt.Fprintln(out)
+			}
+			return sc.Err()
+		}
+
+		line := sc.Text()
+		if strings.HasSuffix(line, "\t") {
+			fmt.Fprintln(out, strings.Join(complete(line), " "))
+			continue
+		}
+		args, err := splitArgs(line)
+		if err != nil {
+			fmt.Fprintf(out, "Invalid argument(s): %v\n", err)
+			continue
+		}
+		if len(args) == 0 {
+			continue
+		}
+		switch strings.ToLower(args[0]) {
+		case "quit", "exit":
+			return nil
+		case "help":
+			fmt.Fprintln(out, replHelp)
+			continue
+		}
+
+		reply, err := c.Do(args...)
+		var rerr resp.Error
+		if errors.As(err, &rerr) {
+			reply = rerr
+		} else if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, format(reply, raw))
+	}
+}
+
+// complete returns the command names (in uppercase)
+// that start with the line. Returns nothing if the line
+// already has arguments, as only the names are completed.
+func complete(line string) []string {
+	prefix := strings.ToLower(strings.TrimSpace(line))
+	if strings.ContainsAny(prefix, " \t") {
+		return nil
+	}
+	var matches []string
+	for _, name := range command.Names() {
+		if strings.HasPrefix(name, prefix) {
+			matches = append(matches, strings.ToUpper(name))
+		}
+	}
+	return matches
+}
+
+// format returns the text representation of the reply.
+// The pretty format shows the reply types like redis-cli does,
+// while the raw format shows the values only.
+func format(reply any, raw bool) string {
+	if raw {
+		return formatRaw(reply)
+	}
+	return formatPretty(reply)
+}
+
+// formatPretty returns the reply with the types and
+// the numbered array items.
+func formatPretty(reply any) string {
+	switch v := reply.(type) {
+	case nil:
+		return "(nil)"
+	case string:
+		return v
+	case resp.Error:
+		return "(error) " + string(v)
+	case int64:
+		return "(integer) " + strconv.FormatInt(v, 10)
+	case []byte:
+		return strconv.Quote(string(v))
+	case []any:
+		if len(v) == 0 {
+			return "(empty array)"
+		}
+		var b strings.Builder
+		width := len(strconv.Itoa(len(v)))
+		for i, item := range v {
+			prefix := fmt.Sprintf("%*d) ", width, i+1)
+			indent := strings.Repeat(" ", len(prefix))
+			for j, line := range strings.Split(formatPretty(item), "\n") {
+				if i > 0 || j > 0 {
+					b.WriteByte('\n')
+				}
+				if j == 0 {
+					b.WriteString(prefix)
+				} else {
+					b.WriteString(indent)
+				}
+				b.WriteString(line)
+			}
+		}
+		return b.String()
+	}
+	return fmt.Sprint(reply)
+}
+
+// formatRaw returns the reply values as is,
+// with the array items on separate lines.
+func formatRaw(reply any) string {
+	switch v := reply.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case resp.Error:
+		return string(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case []byte:
+		return string(v)
+	case []any:
+		lines := make([]string, len(v))
+		for i, item := range v {
+			lines[i] = formatRaw(item)
+		}
+		return strings.Join(lines, "\n")
+	}
+	return fmt.Sprint(reply)
+}
+
+// splitArgs splits the line into the command arguments
+// separated by whitespace. Supports the same quoting as
+// redis-cli: double quotes with escape sequences (\n, \t,
+// \xHH and others), and single quotes with \' only.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	i := 0
+	for {
+		// Skip the whitespace between the arguments.
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var arg strings.Builder
+		switch line[i] {
+		case '"':
+			n, err := readQuoted(line[i+1:], '"', &arg)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+		case '\'':
+			n, err := readQuoted(line[i+1:], '\'', &arg)
+			if err != nil {
+				return nil, err
+			}
+			i += n + 1
+		default:
+			for i < len(line) && !isSpace(line[i]) {
+				arg.WriteByte(line[i])
+				i++
+			}
+		}
+		args = append(args, arg.String())
+	}
+}
+
+// readQuoted reads a quoted argument from s (after the opening
+// quote) into arg. Returns the number of bytes read including
+// the closing quote. The closing quote must be followed
+// by whitespace or the end of the line.
+func readQuoted(s string, quote byte, arg *strings.Builder) (int, error) {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			if i+1 < len(s) && !isSpace(s[i+1]) {
+				return 0, errUnbalanced
+			}
+			return i + 1, nil
+		case c == '\\' && quote == '\'':
+			if i+1 < len(s) && s[i+1] == '\'' {
+				i++
+				c = '\''
+			}
+			arg.WriteByte(c)
+		case c == '\\' && i+3 < len(s) && s[i+1] == 'x' && isHex(s[i+2]) && isHex(s[i+3]):
+			b, _ := strconv.ParseUint(s[i+2:i+4], 16, 8)
+			arg.WriteByte(byte(b))
+			i += 3
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'a':
+				c = '\a'
+			default:
+				c = s[i]
+			}
+			arg.WriteByte(c)
+		default:
+			arg.WriteByte(c)
+		}
+	}
+	return 0, errUnbalanced
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+// scanPageSize is the number of keys requested per SCAN call.
+const scanPageSize = 1000
+
+// pipeBatchSize is the number of commands sent
+// before waiting for the replies in the pipe mode.
+const pipeBatchSize = 1000
+
+// scanKeys prints the keys matching the pattern.
+func scanKeys(c conn, out io.Writer, pattern string) error {
+	return eachKey(c, pattern, func(key string) error {
+		_, err := fmt.Fprintln(out, key)
+		return err
+	})
+}
+
+// eachKey calls fn for each key matching the pattern
+// using the SCAN command.
+func eachKey(c conn, pattern string, fn func(key string) error) error {
+	cursor := "0"
+	for {
+		reply, err := c.Do("scan", cursor, "match", pattern, "count", strconv.Itoa(scanPageSize))
+		if err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+		next, keys, err := parseScan(reply)
+		if err != nil {
+			return fmt.Errorf("scan: %w", err)
+		}
+		for _, key := range keys {
+			if err := fn(key); err != nil {
+				return err
+			}
+		}
+		if next == "0" {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// parseScan returns the next cursor and the keys from the SCAN reply.
+// Accepts both the bulk string (Redis) and the integer (Redka) cursor.
+func parseScan(reply any) (string, []string, error) {
+	page, ok := reply.([]any)
+	if !ok || len(page) != 2 {
+		return "", nil, fmt.Errorf("unexpected reply: %v", reply)
+	}
+	var cursor string
+	switch v := page[0].(type) {
+	case []byte:
+		cursor = string(v)
+	case int64:
+		cursor = strconv.FormatInt(v, 10)
+	default:
+		return "", nil, fmt.Errorf("unexpected cursor: %v", page[0])
+	}
+	items, ok := page[1].([]any)
+	if !ok {
+		return "", nil, fmt.Errorf("unexpected keys: %v", page[1])
+	}
+	keys := make([]string, len(items))
+	for i, item := range items {
+		key, ok := item.([]byte)
+		if !ok {
+			return "", nil, fmt.Errorf("unexpected key: %v", item)
+		}
+		keys[i] = string(key)
+	}
+	return cursor, keys, nil
+}
+
+// keyTypes are the key types in the -bigkeys report order,
+// with the commands that return the key size and the size units.
+var keyTypes = []struct {
+	name   string
+	plural string
+	cmd    string
+	unit   string
+}{
+	{"string", "strings", "strlen", "bytes"},
+	{"list", "lists", "llen", "items"},
+	{"set", "sets", "scard", "members"},
+	{"hash", "hashes", "hlen", "fields"},
+	{"zset", "zsets", "zcard", "members"},
+}
+
+// typeStats are the -bigkeys statistics of a key type.
+type typeStats struct {
+	count   int    // number of keys
+	sized   int    // number of keys with a known size
+	total   int64  // total size
+	maxKey  string // the biggest key
+	maxSize int64  // the biggest key size
+}
+
+// bigKeys scans the keys and prints the biggest key of each type.
+// The key size is the length of a string, or the number of items in
+// a collection. If the server does not support the size command for
+// a type, only counts the keys of that type. Strings are measured with
+// GET if the server does not support STRLEN.
+func bigKeys(c conn, out io.Writer) error {
+	fmt.Fprintln(out, "# Scanning the entire keyspace to find the biggest keys")
+	stats := map[string]*typeStats{}
+	sampled := 0
+	err := eachKey(c, "*", func(key string) error {
+		reply, err := c.Do("type", key)
+		if err != nil {
+			return fmt.Errorf("type %s: %w", key, err)
+		}
+		typ, _ := reply.(string)
+		if typ == "none" {
+			// Deleted since the scan.
+			return nil
+		}
+		sampled++
+		st := stats[typ]
+		if st == nil {
+			st = &typeStats{}
+			stats[typ] = st
+		}
+		st.count++
+
+		size, ok, err := keySize(c, typ, key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		st.sized++
+		st.total += size
+		if st.maxKey == "" || size > st.maxSize {
+			st.maxKey, st.maxSize = key, size
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out)
+	for _, t := range keyTypes {
+		if st := stats[t.name]; st != nil && st.sized > 0 {
+			fmt.Fprintf(out, "Biggest %6s found %q has %d %s\n", t.name, st.maxKey, st.maxSize, t.unit)
+		}
+	}
+	fmt.Fprintln(out)
+	fmt.Fprintf(out, "%d keys sampled\n", sampled)
+	for _, t := range keyTypes {
+		st := stats[t.name]
+		if st == nil {
+			continue
+		}
+		if st.sized == 0 {
+			fmt.Fprintf(out, "%d %s (size unknown)\n", st.count, t.plural)
+			continue
+		}
+		avg := float64(st.total) / float64(st.sized)
+		fmt.Fprintf(out, "%d %s with %d %s (avg size %.2f)\n", st.count, t.plural, st.total, t.unit, avg)
+	}
+	return nil
+}
+
+// keySize returns the size of the key. Returns false
+// if the server can't tell the size of this key type.
+func keySize(c conn, typ, key string) (int64, bool, error) {
+	for _, t := range keyTypes {
+		if t.name != typ {
+			continue
+		}
+		reply, err := c.Do(t.cmd, key)
+		var rerr resp.Error
+		if errors.As(err, &rerr) && typ == "string" {
+			// Fall back to GET for the servers without STRLEN.
+			reply, err = c.Do("get", key)
+			if val, ok := reply.([]byte); ok {
+				reply = int64(len(val))
+			}
+		}
+		if errors.As(err, &rerr) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, fmt.Errorf("%s %s: %w", t.cmd, key, err)
+		}
+		size, ok := reply.(int64)
+		return size, ok, nil
+	}
+	return 0, false, nil
+}
+
+// pipeliner sends the commands without waiting
+// for the replies (implemented by *resp.Client).
+type pipeliner interface {
+	Send(args ...string) error
+	Flush() error
+	Receive() (any, error)
+}
+
+// pipe executes the commands from in and prints the summary to out.
+// The commands are either in plain text (one per line, like in the
+// interactive mode) or encoded with RESP. Sends the commands to
+// the server in batches without waiting for each reply.
+func pipe(c conn, in io.Reader, out io.Writer) error {
+	next := commandReader(in)
+	var replies, errs int
+
+	// handle counts the reply and reports the error.
+	handle := func(err error) error {
+		var rerr resp.Error
+		if errors.As(err, &rerr) {
+			fmt.Fprintln(os.Stderr, rerr)
+			errs++
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		replies++
+		return nil
+	}
+
+	p, isPipeliner := c.(pipeliner)
+	for done := false; !done; {
+		// Send the batch of commands.
+		sent := 0
+		for sent < pipeBatchSize {
+			args, err := next()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if !isPipeliner {
+				_, err := c.Do(args...)
+				if err := handle(err); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := p.Send(args...); err != nil {
+				return err
+			}
+			sent++
+		}
+		if !isPipeliner || sent == 0 {
+			continue
+		}
+
+		// Receive the replies.
+		if err := p.Flush(); err != nil {
+			return err
+		}
+		for range sent {
+			_, err := p.Receive()
+			if err := handle(err); err != nil {
+				return err
+			}
+		}
+	}
+
+	fmt.Fprintf(out, "All data transferred. errors: %d, replies: %d\n", errs, replies+errs)
+	if errs > 0 {
+		return fmt.Errorf("%d commands failed", errs)
+	}
+	return nil
+}
+
+// commandReader returns a function that reads the next command
+// from in, either encoded with RESP (if the input starts with '*')
+// or in plain text. Skips the empty lines and the comments (#)
+// in plain text. Returns io.EOF when there are no more commands.
+func commandReader(in io.Reader) func() ([]string, error) {
+	rd := bufio.NewReaderSize(in, 64*1024)
+	if b, err := rd.Peek(1); err == nil && b[0] == '*' {
+		return func() ([]string, error) {
+			bargs, _, err := resp.ReadCommand(rd)
+			if err != nil {
+				return nil, err
+			}
+			args := make([]string, len(bargs))
+			for i, arg := range bargs {
+				args[i] = string(arg)
+			}
+			return args, nil
+		}
+	}
+
+	sc := bufio.NewScanner(rd)
+	sc.Buffer(nil, maxLineSize)
+	return func() ([]string, error) {
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			args, err := splitArgs(line)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", err, line)
+			}
+			return args, nil
+		}
+		if err := sc.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
@@ -0,0 +1,122 @@
+// Redka dump tool. Writes the keys from a Redka database
+// to stdout (or a file) in a portable format: JSON Lines,
+// CSV, Redis RDB or Redis commands (RESP). Use redka-restore
+// to load the dump into another database.
+// Example usage:
+//
+//	./redka-dump redka.db > redka.jsonl
+//	./redka-dump -format csv -match "user:*" redka.db > users.csv
+//	./redka-dump redka.db | ssh backup ./redka-restore /data/redka.db
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+)
+
+// formatRDB is the Redis RDB dump format.
+const formatRDB = "rdb"
+
+// Config holds the dump configuration.
+type Config struct {
+	Path   string
+	Format string
+	Match  string
+	Types  string
+	Output string
+}
+
+var config Config
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: redka-dump [options] <data-source>\n")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&config.Format, "format", "jsonl", "dump format (jsonl, csv, resp or rdb)")
+	flag.StringVar(&config.Match, "match", "", "pattern of the keys to dump (all keys if empty)")
+	flag.StringVar(&config.Types, "types", "", "comma-separated key types to dump (all types if empty)")
+	flag.StringVar(&config.Output, "o", "", "output file (stdout if empty or -)")
+}
+
+func main() {
+	// The export subcommand is the same as no subcommand,
+	// and the import one is replaced by redka-restore.
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "export" {
+		args = args[1:]
+	}
+	if len(args) > 0 && args[0] == "import" {
+		fmt.Fprintln(os.Stderr, "redka-dump: use redka-restore to import the keys")
+		os.Exit(1)
+	}
+
+	// Parse command line arguments.
+	_ = flag.CommandLine.Parse(args)
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	config.Path = flag.Arg(0)
+
+	// Log to stderr, so that the dump can be written to stdout.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	if err := dump(config); err != nil {
+		slog.Error("dump", "error", err)
+		os.Exit(1)
+	}
+}
+
+// dump writes the keys from the database to the output.
+func dump(config Config) error {
+	if config.Format == formatRDB && (config.Match != "" || config.Types != "") {
+		return errors.New("rdb format does not support -match and -types")
+	}
+
+	db, err := redka.Open(config.Path, nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var w io.Writer = os.Stdout
+	if config.Output != "" && config.Output != "-" {
+		f, err := os.Create(config.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if config.Format == formatRDB {
+		if err := db.ExportRDB(w); err != nil {
+			return err
+		}
+		slog.Info("dump", "format", config.Format)
+		return nil
+	}
+
+	opts := &redka.ExportOptions{
+		Format: redka.DumpFormat(config.Format),
+		Match:  config.Match,
+	}
+	if config.Types != "" {
+		opts.Types = strings.Split(config.Types, ",")
+	}
+	count, err := db.Export(w, opts)
+	if err != nil {
+		return err
+	}
+	slog.Info("dump", "format", config.Format, "keys", count)
+	return nil
+}
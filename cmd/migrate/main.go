@@ -0,0 +1,106 @@
+// Redka migration tool. Copies the keys from a running
+// Redis server into a Redka database.
+// Example usage:
+//
+//	./redka-migrate -follow localhost:6379 redka.db
+//
+// With -follow, the tool keeps the database in sync with the
+// source server (using keyspace notifications) until interrupted.
+// Stop the writes to the source, wait a moment for the remaining
+// changes to arrive, then interrupt the tool and switch the
+// clients to Redka.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+)
+
+// Config holds the migration configuration.
+type Config struct {
+	Source   string
+	Path     string
+	User     string
+	Password string
+	DB       int
+	Match    string
+	Batch    int
+	Follow   bool
+	Verbose  bool
+}
+
+var config Config
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: redka-migrate [options] <redis-addr> <data-source>\n")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&config.User, "user", "", "username to authenticate with the source")
+	flag.StringVar(&config.Password, "pass", "", "password to authenticate with the source")
+	flag.IntVar(&config.DB, "db", 0, "source database number")
+	flag.StringVar(&config.Match, "match", "", "pattern of the keys to migrate (all keys if empty)")
+	flag.IntVar(&config.Batch, "batch", 100, "number of keys to copy at once")
+	flag.BoolVar(&config.Follow, "follow", false, "keep in sync with the source until interrupted")
+	flag.BoolVar(&config.Verbose, "v", false, "verbose logging")
+}
+
+func main() {
+	// Parse command line arguments.
+	flag.Parse()
+	if len(flag.Args()) != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	config.Source = flag.Arg(0)
+	config.Path = flag.Arg(1)
+
+	// Prepare a context to handle shutdown signals.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Set up logging.
+	logLevel := new(slog.LevelVar)
+	logHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})
+	logger := slog.New(logHandler)
+	slog.SetDefault(logger)
+	if config.Verbose {
+		logLevel.Set(slog.LevelDebug)
+	}
+
+	// Open the database.
+	db, err := redka.Open(config.Path, &redka.Options{Logger: logger})
+	if err != nil {
+		slog.Error("data source", "error", err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	// Copy the keys.
+	slog.Info("migrate", "source", config.Source, "path", config.Path, "follow", config.Follow)
+	opts := &redka.MigrateOptions{
+		User:      config.User,
+		Password:  config.Password,
+		DB:        config.DB,
+		Match:     config.Match,
+		BatchSize: config.Batch,
+		Follow:    config.Follow,
+		Progress: func(stats redka.MigrateStats) {
+			slog.Debug("progress", "scanned", stats.Scanned, "keys", stats.Keys,
+				"updated", stats.Updated, "deleted", stats.Deleted)
+		},
+	}
+	if _, err := db.Migrate(ctx, config.Source, opts); err != nil {
+		slog.Error("migrate", "error", err)
+		db.Close()
+		os.Exit(1)
+	}
+}
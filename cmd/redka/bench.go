@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/bench"
+)
+
+// runBench runs the benchmark (redka bench) and returns the exit code.
+// Runs against the server at -addr if set, or the embedded database
+// otherwise (in-memory if there is no data source).
+func runBench(args []string) int {
+	fs := flag.NewFlagSet("redka bench", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: redka bench [options] [data-source]\n")
+		fs.PrintDefaults()
+	}
+	addr := fs.String("addr", "", "benchmark the server at host:port instead of the embedded database")
+	tests := fs.String("t", strings.Join(bench.Tests, ","), "comma-separated tests to run")
+	requests := fs.Int("n", 100000, "total number of requests per test")
+	clients := fs.String("c", "50", "number of concurrent clients (comma-separated for a sweep, like 1,10,50)")
+	pipeline := fs.Int("P", 1, "pipeline requests per round trip")
+	keySpace := fs.Int("r", 0, "use random keys out of the key space of the size (single key if zero)")
+	dataSize := fs.Int("d", 3, "SET/HSET/MSET value size in bytes")
+	_ = fs.Parse(args)
+	if fs.NArg() > 1 || (*addr != "" && fs.NArg() > 0) {
+		fs.Usage()
+		return 1
+	}
+
+	opts := &bench.Options{
+		Tests:    strings.Split(*tests, ","),
+		Requests: *requests,
+		Pipeline: *pipeline,
+		KeySpace: *keySpace,
+		DataSize: *dataSize,
+		Progress: func(res bench.Result) { fmt.Println(res) },
+	}
+	for _, s := range strings.Split(*clients, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid number of clients: %q\n", s)
+			return 1
+		}
+		opts.Clients = append(opts.Clients, n)
+	}
+
+	var target bench.Target
+	if *addr != "" {
+		target = bench.Server(*addr)
+	} else {
+		path, inMemory := fs.Arg(0), fs.NArg() == 0
+		if inMemory {
+			path = memoryName
+		}
+		db, err := redka.Open(path, &redka.Options{InMemory: inMemory})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "data source: %v\n", err)
+			return 1
+		}
+		defer db.Close()
+		target = bench.Embedded(db)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if _, err := bench.Run(ctx, target, opts); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return 130
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/server"
+)
+
+// tokensDirective is the config file directive with the
+// HTTP API tokens. The tokens are not accepted as a flag,
+// so that they do not show in the process list.
+const tokensDirective = "http-tokens"
+
+// reloadable are the flags applied on reload.
+// Changing the others requires a restart.
+var reloadable = map[string]bool{
+	"v":               true,
+	"slowlog":         true,
+	"max-key-size":    true,
+	"max-value-size":  true,
+	"max-elements":    true,
+	"expire-interval": true,
+}
+
+// parseConfig parses the command line arguments and the config file
+// (if set with -config) into the configuration. The command line
+// flags take precedence over the config file directives.
+func parseConfig(fs *flag.FlagSet, config *Config, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if config.File != "" {
+		if err := readConfigFile(fs, config); err != nil {
+			return fmt.Errorf("config file %s: %w", config.File, err)
+		}
+	}
+	if config.HTTPTokens == nil {
+		config.HTTPTokens = httpTokens(os.Getenv("REDKA_HTTP_TOKENS"))
+	}
+	return nil
+}
+
+// readConfigFile applies the directives of the config file
+// to the flags that are not set on the command line.
+//
+// The file has one directive per line, like in redis.conf:
+// the flag name, a space and the value. Empty lines and lines
+// starting with # are skipped. The repeatable flags (like shard)
+// may have several lines.
+//
+//	p 6380
+//	slowlog 10ms
+//	max-value-size 1048576
+//	http-tokens secret1,secret2
+func readConfigFile(fs *flag.FlagSet, config *Config) error {
+	f, err := os.Open(config.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, _ := strings.Cut(line, " ")
+		value = strings.TrimSpace(value)
+		switch {
+		case name == tokensDirective:
+			config.HTTPTokens = httpTokens(value)
+		case name == "config" || fs.Lookup(name) == nil:
+			return fmt.Errorf("line %d: unknown directive %q", n, name)
+		case set[name]:
+			// The command line flag takes precedence.
+		default:
+			if err := fs.Set(name, value); err != nil {
+				return fmt.Errorf("line %d: %s: %w", n, name, err)
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// settings returns the database settings
+// that can be changed without a restart.
+func (c *Config) settings() redka.Settings {
+	return redka.Settings{
+		SlowThreshold:  c.SlowLog,
+		MaxKeySize:     c.MaxKeySize,
+		MaxValueSize:   c.MaxValueSize,
+		MaxElements:    c.MaxElements,
+		ExpireInterval: c.ExpireInterval,
+	}
+}
+
+// logLevel returns the log level for the configuration.
+func (c *Config) logLevel() slog.Level {
+	if c.Verbose {
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// reloader applies the configuration changes
+// without dropping the client connections.
+type reloader struct {
+	mu       sync.Mutex
+	logLevel *slog.LevelVar
+	dbs      []*redka.DB
+	srv      *server.Server
+}
+
+// reload reads the command line arguments and the config file
+// again, and applies the log level, the slowlog threshold,
+// the limits, the expiration interval and the HTTP tokens.
+// The other changes are logged and ignored until a restart.
+func (r *reloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if config.File == "" {
+		return errors.New("no config file to reload (see -config)")
+	}
+	var next Config
+	fs := flag.NewFlagSet("redka", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	defineFlags(fs, &next)
+	if err := parseConfig(fs, &next, os.Args[1:]); err != nil {
+		return err
+	}
+
+	var ignored []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if !reloadable[f.Name] && f.Value.String() != flag.Lookup(f.Name).Value.String() {
+			ignored = append(ignored, f.Name)
+		}
+	})
+	if len(ignored) > 0 {
+		slog.Warn("reload config: restart to apply", "options", strings.Join(ignored, ","))
+	}
+
+	r.logLevel.Set(next.logLevel())
+	for _, db := range r.dbs {
+		db.Reconfigure(next.settings())
+	}
+	r.srv.SetHTTPTokens(next.HTTPTokens)
+
+	config.Verbose = next.Verbose
+	config.SlowLog = next.SlowLog
+	config.MaxKeySize = next.MaxKeySize
+	config.MaxValueSize = next.MaxValueSize
+	config.MaxElements = next.MaxElements
+	config.ExpireInterval = next.ExpireInterval
+	config.HTTPTokens = next.HTTPTokens
+	slog.Info("reload config", "path", config.File)
+	return nil
+}
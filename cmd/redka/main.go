@@ -3,6 +3,10 @@
 //
 //	./redka -h localhost -p 6379 redka.db
 //
+// Example usage (benchmark):
+//
+//	./redka bench -t set,get -c 1,10,50 -P 16 bench.db
+//
 // Example usage (client):
 //
 //	docker run --rm -it redis redis-cli -h host.docker.internal -p 6379
@@ -10,16 +14,23 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/aof"
+	"github.com/nalgeon/redka/internal/repl"
 	"github.com/nalgeon/redka/internal/server"
 )
 
@@ -30,14 +41,38 @@ var (
 	date    = "unknown"
 )
 
-const memoryURI = "file:redka?mode=memory&cache=shared"
+// memoryName is the name of the in-memory database
+// used when no data source is given.
+const memoryName = "redka"
 
 // Config holds the server configuration.
 type Config struct {
-	Host    string
-	Port    string
-	Path    string
-	Verbose bool
+	File       string
+	Host       string
+	Port       string
+	Path       string
+	Verbose    bool
+	AOF        string
+	AOFSync    string
+	ReplicaOf  string
+	MasterUser string
+	MasterAuth string
+	OutboxNATS string
+	OutboxSubj string
+	Metrics    string
+	HTTP       string
+	HTTPCORS   string
+	WebSocket  string
+	Admin      string
+	SlowLog    time.Duration
+	Tenants    map[string]string
+	Shards     []string
+
+	MaxKeySize     int
+	MaxValueSize   int
+	MaxElements    int
+	ExpireInterval time.Duration
+	HTTPTokens     []string
 }
 
 func (c *Config) Addr() string {
@@ -49,24 +84,76 @@ var config Config
 func init() {
 	flag.Usage = func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "Usage: redka [options] <data-source>\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "       redka bench [options] [data-source]\n")
 		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "Environment:\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  REDKA_ENCRYPTION_KEY\n    \tdatabase encryption key (requires a SQLCipher build)\n")
+		fmt.Fprintf(flag.CommandLine.Output(), "  REDKA_HTTP_TOKENS\n    \tcomma-separated bearer tokens required by the HTTP API (no auth if empty),\n    \tunless set with the http-tokens directive of the config file\n")
 	}
-	flag.StringVar(&config.Host, "h", "localhost", "server host")
-	flag.StringVar(&config.Port, "p", "6379", "server port")
-	flag.BoolVar(&config.Verbose, "v", false, "verbose logging")
+	defineFlags(flag.CommandLine, &config)
+}
+
+// defineFlags defines the command line flags that set the configuration.
+// The flags (except -config) are also the directives of the config file.
+func defineFlags(fs *flag.FlagSet, config *Config) {
+	fs.StringVar(&config.File, "config", "", "read the options from the config file at `path` (reloaded on SIGHUP or RELOAD)")
+	fs.StringVar(&config.Host, "h", "localhost", "server host")
+	fs.StringVar(&config.Port, "p", "6379", "server port")
+	fs.BoolVar(&config.Verbose, "v", false, "verbose logging")
+	fs.StringVar(&config.AOF, "aof", "", "append-only journal file (disabled if empty)")
+	fs.StringVar(&config.AOFSync, "aof-sync", "everysec", "journal fsync policy: always, everysec or no")
+	fs.StringVar(&config.ReplicaOf, "replicaof", "", "replicate from the primary (Redka or Redis) at host:port")
+	fs.StringVar(&config.MasterUser, "masteruser", "", "username to authenticate with the primary")
+	fs.StringVar(&config.MasterAuth, "masterauth", "", "password to authenticate with the primary")
+	fs.StringVar(&config.OutboxNATS, "outbox-nats", "", "publish committed changes to the NATS server at host:port")
+	fs.StringVar(&config.OutboxSubj, "outbox-subject", "redka.changes", "NATS subject to publish the changes to")
+	fs.DurationVar(&config.SlowLog, "slowlog", 0, "log operations slower than the duration, like 10ms (disabled if zero)")
+	fs.IntVar(&config.MaxKeySize, "max-key-size", 0, "maximum key length in bytes (no limit if zero)")
+	fs.IntVar(&config.MaxValueSize, "max-value-size", 0, "maximum size of a value, hash field or set member in bytes (no limit if zero)")
+	fs.IntVar(&config.MaxElements, "max-elements", 0, "maximum number of hash fields or set members (no limit if zero)")
+	fs.DurationVar(&config.ExpireInterval, "expire-interval", 0, "how often to delete the expired keys (60s if zero)")
+	fs.StringVar(&config.Metrics, "metrics", "", "serve Prometheus metrics over HTTP at host:port/metrics (disabled if empty)")
+	fs.StringVar(&config.HTTP, "http", "", "serve the commands as a REST API at host:port (disabled if empty)")
+	fs.StringVar(&config.HTTPCORS, "http-cors", "", "origin allowed to call the REST API from a browser, * for any (disabled if empty)")
+	fs.StringVar(&config.WebSocket, "ws", "", "serve pub/sub and keyspace notifications over WebSocket at host:port/ws (disabled if empty)")
+	fs.StringVar(&config.Admin, "admin", "", "serve the web admin dashboard at host:port (disabled if empty)")
+	fs.Func("shard", "store the keys in the shard database at `path` (repeatable, the order defines the slots)", func(s string) error {
+		config.Shards = append(config.Shards, s)
+		return nil
+	})
+	fs.Func("tenant", "attach a tenant database as `name=path` (repeatable, switch with SELECT name; not with -aof or replication)", func(s string) error {
+		name, path, ok := strings.Cut(s, "=")
+		if !ok || name == "" || path == "" {
+			return errors.New("expected name=path")
+		}
+		if config.Tenants == nil {
+			config.Tenants = map[string]string{}
+		}
+		config.Tenants[name] = path
+		return nil
+	})
 }
 
 func main() {
-	// Parse command line arguments.
-	flag.Parse()
+	// Run the benchmark instead of the server.
+	if len(os.Args) > 1 && os.Args[1] == "bench" {
+		os.Exit(runBench(os.Args[2:]))
+	}
+
+	// Parse command line arguments and the config file.
+	if err := parseConfig(flag.CommandLine, &config, os.Args[1:]); err != nil {
+		fmt.Fprintln(flag.CommandLine.Output(), err)
+		os.Exit(1)
+	}
 	if len(flag.Args()) > 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
 
 	// Set the data source.
-	if len(flag.Args()) == 0 {
-		config.Path = memoryURI
+	inMemory := len(flag.Args()) == 0
+	if inMemory {
+		config.Path = memoryName
 	} else {
 		config.Path = flag.Arg(0)
 	}
@@ -80,27 +167,131 @@ func main() {
 	logHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel})
 	logger := slog.New(logHandler)
 	slog.SetDefault(logger)
-	if config.Verbose {
-		logLevel.Set(slog.LevelDebug)
-	}
+	logLevel.Set(config.logLevel())
 
 	// Print version information.
 	slog.Info("starting redka", "version", version, "commit", commit, "built_at", date)
 
 	// Open the database.
-	db, err := redka.Open(config.Path, &redka.Options{Logger: logger})
+	dbOpts := &redka.Options{
+		Logger:         logger,
+		SlowThreshold:  config.SlowLog,
+		MaxKeySize:     config.MaxKeySize,
+		MaxValueSize:   config.MaxValueSize,
+		MaxElements:    config.MaxElements,
+		ExpireInterval: config.ExpireInterval,
+		InMemory:       inMemory,
+		Outbox:         config.OutboxNATS != "",
+		// The key is not accepted as a flag,
+		// so that it does not show in the process list.
+		EncryptionKey: os.Getenv("REDKA_ENCRYPTION_KEY"),
+	}
+	var db *redka.DB
+	var shards *redka.Shards
+	var err error
+	if len(config.Shards) > 0 {
+		shards, err = openShards(dbOpts)
+		if err != nil {
+			slog.Error("shards", "error", err)
+			os.Exit(1)
+		}
+		db = shards.DB(0)
+	} else {
+		db, err = redka.Open(config.Path, dbOpts)
+		if err != nil {
+			slog.Error("data source", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("data source", "path", config.Path)
+	}
+
+	// Open the journal.
+	journal, err := openJournal(db)
+	if err != nil {
+		slog.Error("journal", "error", err)
+		os.Exit(1)
+	}
+
+	// Attach the tenants.
+	tenants, err := openTenants(logger)
 	if err != nil {
-		slog.Error("data source", "error", err)
+		slog.Error("tenants", "error", err)
 		os.Exit(1)
 	}
-	slog.Info("data source", "path", config.Path)
+
+	// Prepare to reload the configuration.
+	reload := &reloader{logLevel: logLevel, dbs: []*redka.DB{db}}
+	if shards != nil {
+		reload.dbs = make([]*redka.DB, shards.Len())
+		for i := range reload.dbs {
+			reload.dbs[i] = shards.DB(i)
+		}
+	}
+
+	// Set up replication.
+	opts := &server.Options{
+		Journal:        journal,
+		Tenants:        tenants,
+		Shards:         shards,
+		Logger:         logger,
+		MetricsAddr:    config.Metrics,
+		HTTPAddr:       config.HTTP,
+		HTTPTokens:     config.HTTPTokens,
+		HTTPCORSOrigin: config.HTTPCORS,
+		WebSocketAddr:  config.WebSocket,
+		AdminAddr:      config.Admin,
+		AdminSlowLog:   config.SlowLog,
+		Reload:         reload.reload,
+	}
+	if config.ReplicaOf != "" {
+		port, _ := strconv.Atoi(config.Port)
+		opts.Replica = repl.NewReplica(db, config.ReplicaOf, &repl.ReplicaOptions{
+			Port:     port,
+			User:     config.MasterUser,
+			Password: config.MasterAuth,
+		})
+		slog.Info("replicate", "primary", config.ReplicaOf)
+	} else if shards == nil {
+		// The replicas receive the snapshot of a single database,
+		// so the sharded mode does not support replication.
+		opts.Primary = repl.NewPrimary(db)
+	}
 
 	// Start the server.
-	srv := server.New(config.Addr(), db)
+	srv := server.New(config.Addr(), db, opts)
+	reload.srv = srv
 	srv.Start()
 
+	// Reload the configuration on SIGHUP.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reload.reload(); err != nil {
+				slog.Error("reload config", "error", err)
+			}
+		}
+	}()
+
+	// Publish the changes.
+	published := make(chan struct{})
+	if config.OutboxNATS != "" {
+		pub := redka.NewNATSPublisher(config.OutboxNATS, &redka.NATSOptions{
+			Subject: config.OutboxSubj,
+		})
+		slog.Info("publish outbox", "nats", config.OutboxNATS, "subject", config.OutboxSubj)
+		go func() {
+			defer close(published)
+			defer pub.Close()
+			_ = db.PublishOutbox(ctx, pub, nil)
+		}()
+	} else {
+		close(published)
+	}
+
 	// Wait for a shutdown signal.
 	<-ctx.Done()
+	<-published
 
 	// Stop the server.
 	if err := srv.Stop(); err != nil {
@@ -108,3 +299,102 @@ func main() {
 	}
 	slog.Info("stop server")
 }
+
+// httpTokens returns the HTTP API tokens from
+// the comma-separated list, skipping the empty ones.
+func httpTokens(list string) []string {
+	var tokens []string
+	for _, token := range strings.Split(list, ",") {
+		if token = strings.TrimSpace(token); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens
+}
+
+// openShards opens the shard databases. The sharded mode does not
+// support the features that work with a single database (journal,
+// replication, tenants and outbox), nor the data source argument.
+func openShards(opts *redka.Options) (*redka.Shards, error) {
+	switch {
+	case len(flag.Args()) > 0:
+		return nil, errors.New("use either -shard or the data source")
+	case config.AOF != "":
+		return nil, errors.New("-aof is not supported with -shard")
+	case config.ReplicaOf != "":
+		return nil, errors.New("-replicaof is not supported with -shard")
+	case len(config.Tenants) > 0:
+		return nil, errors.New("-tenant is not supported with -shard")
+	case config.OutboxNATS != "":
+		return nil, errors.New("-outbox-nats is not supported with -shard")
+	}
+	opts.InMemory = false
+	shards, err := redka.OpenShards(config.Shards, opts)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("shards", "paths", strings.Join(config.Shards, ","))
+	return shards, nil
+}
+
+// openTenants attaches the tenant databases.
+// Returns nil if there are no tenants.
+func openTenants(logger *slog.Logger) (*redka.Tenants, error) {
+	if len(config.Tenants) == 0 {
+		return nil, nil
+	}
+	tenants := redka.NewTenants(&redka.Options{Logger: logger})
+	for name, path := range config.Tenants {
+		if _, err := tenants.Attach(name, path); err != nil {
+			_ = tenants.Close()
+			return nil, err
+		}
+		slog.Info("attach tenant", "name", name, "path", path)
+	}
+	return tenants, nil
+}
+
+// openJournal replays the journal into the database (if the database
+// is empty) and opens it for appending. Returns nil if the journal
+// is disabled.
+func openJournal(db *redka.DB) (*aof.Journal, error) {
+	if config.AOF == "" {
+		return nil, nil
+	}
+	sync, err := aof.ParseSync(config.AOFSync)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(config.AOF)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		// Nothing to replay.
+	case err != nil:
+		return nil, err
+	default:
+		stats, err := aof.Replay(f, db)
+		f.Close()
+		switch {
+		case errors.Is(err, aof.ErrNotEmpty):
+			slog.Info("skip journal replay: database is not empty", "path", config.AOF)
+		case errors.Is(err, aof.ErrTruncated):
+			slog.Warn("replay journal: remove truncated tail", "path", config.AOF,
+				"commands", stats.Commands, "size", stats.Size)
+			if err := os.Truncate(config.AOF, stats.Size); err != nil {
+				return nil, err
+			}
+		case err != nil:
+			return nil, err
+		default:
+			slog.Info("replay journal", "path", config.AOF, "commands", stats.Commands)
+		}
+	}
+
+	journal, err := aof.Open(config.AOF, sync)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("journal", "path", config.AOF, "fsync", sync)
+	return journal, nil
+}
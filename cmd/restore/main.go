@@ -0,0 +1,104 @@
+// Redka restore tool. Loads the keys from a dump created
+// by redka-dump (or an RDB or AOF file created by Redis) into
+// a Redka database, reading from stdin (or a file).
+// Example usage:
+//
+//	./redka-restore redka.db < redka.jsonl
+//	./redka-restore -format csv -conflict skip redka.db < users.csv
+//	ssh primary ./redka-dump /data/redka.db | ./redka-restore redka.db
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+)
+
+// formatRDB is the Redis RDB dump format.
+const formatRDB = "rdb"
+
+// Config holds the restore configuration.
+type Config struct {
+	Path     string
+	Format   string
+	Conflict string
+	Batch    int
+	Input    string
+}
+
+var config Config
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: redka-restore [options] <data-source>\n")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&config.Format, "format", "jsonl", "dump format (jsonl, csv, resp or rdb)")
+	flag.StringVar(&config.Conflict, "conflict", "replace", "existing keys policy (replace, skip or merge)")
+	flag.IntVar(&config.Batch, "batch", 1000, "number of keys to restore in a transaction")
+	flag.StringVar(&config.Input, "i", "", "input file (stdin if empty or -)")
+}
+
+func main() {
+	// Parse command line arguments.
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	config.Path = flag.Arg(0)
+
+	// Log to stderr, same as redka-dump.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	if err := restore(config); err != nil {
+		slog.Error("restore", "error", err)
+		os.Exit(1)
+	}
+}
+
+// restore loads the keys from the input into the database.
+func restore(config Config) error {
+	if config.Format == formatRDB && config.Conflict != string(redka.ConflictReplace) {
+		return errors.New("rdb format only supports -conflict replace")
+	}
+
+	var r io.Reader = os.Stdin
+	if config.Input != "" && config.Input != "-" {
+		f, err := os.Open(config.Input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	db, err := redka.Open(config.Path, nil)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var stats redka.ImportStats
+	if config.Format == formatRDB {
+		stats, err = db.ImportRDB(r, &redka.ImportOptions{BatchSize: config.Batch})
+	} else {
+		stats, err = db.Import(r, &redka.DumpImportOptions{
+			Format:    redka.DumpFormat(config.Format),
+			Conflict:  redka.ConflictPolicy(config.Conflict),
+			BatchSize: config.Batch,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("restore", "format", config.Format, "keys", stats.Keys,
+		"skipped", stats.Skipped, "expired", stats.Expired)
+	return nil
+}
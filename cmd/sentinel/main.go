@@ -0,0 +1,97 @@
+// Redka sentinel. Monitors a Redka primary and its replicas,
+// and serves the Sentinel protocol to the Sentinel-aware clients
+// (SENTINEL get-master-addr-by-name, SUBSCRIBE +switch-master).
+// Example usage:
+//
+//	./redka-sentinel -p 26379 -name mymaster localhost:6379 localhost:6380
+//
+// Example usage (client):
+//
+//	redis-cli -p 26379 sentinel get-master-addr-by-name mymaster
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nalgeon/redka/internal/sentinel"
+)
+
+// Config holds the sentinel configuration.
+type Config struct {
+	Host      string
+	Port      string
+	Name      string
+	Nodes     []string
+	Interval  time.Duration
+	DownAfter time.Duration
+	Verbose   bool
+}
+
+func (c *Config) Addr() string {
+	return net.JoinHostPort(c.Host, c.Port)
+}
+
+var config Config
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: redka-sentinel [options] <node> [node ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.StringVar(&config.Host, "h", "localhost", "sentinel host")
+	flag.StringVar(&config.Port, "p", "26379", "sentinel port")
+	flag.StringVar(&config.Name, "name", "mymaster", "name of the monitored primary")
+	flag.DurationVar(&config.Interval, "interval", time.Second, "node polling interval")
+	flag.DurationVar(&config.DownAfter, "down-after", 5*time.Second, "consider a node down after it's unreachable for the duration")
+	flag.BoolVar(&config.Verbose, "v", false, "verbose logging")
+}
+
+func main() {
+	// Parse command line arguments.
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	config.Nodes = flag.Args()
+
+	// Prepare a context to handle shutdown signals.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Set up logging.
+	logLevel := new(slog.LevelVar)
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))
+	slog.SetDefault(logger)
+	if config.Verbose {
+		logLevel.Set(slog.LevelDebug)
+	}
+
+	// Start the sentinel.
+	s := sentinel.New(config.Addr(), &sentinel.Options{
+		Name:      config.Name,
+		Nodes:     config.Nodes,
+		Interval:  config.Interval,
+		DownAfter: config.DownAfter,
+		Logger:    logger,
+	})
+	s.Start()
+	slog.Info("monitor nodes", "nodes", config.Nodes)
+
+	// Wait for a shutdown signal.
+	<-ctx.Done()
+
+	// Stop the sentinel.
+	if err := s.Stop(); err != nil {
+		slog.Error("stop sentinel", "error", err)
+	}
+	slog.Info("stop sentinel")
+}
@@ -0,0 +1,163 @@
+package redka
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// NumDatabases is the number of logical databases
+// available with [DB.Select] (as in Redis).
+const NumDatabases = 16
+
+// ErrDBIndex is returned when selecting
+// a logical database that does not exist.
+var ErrDBIndex = errors.New("DB index is out of range")
+
+// databases is a set of logical databases stored
+// in the same SQLite file and sharing the connection pool.
+// Database 0 is the one opened with Open or OpenDB,
+// the others are opened lazily on the first Select.
+// The logical databases share the writer of database 0,
+// which also runs the background tasks for all of them.
+type databases struct {
+	mu   sync.Mutex
+	root *DB
+	dbs  map[int]*DB
+}
+
+// newDatabases creates a set of logical databases
+// with the given database as database 0.
+func newDatabases(root *DB) *databases {
+	return &databases{root: root, dbs: map[int]*DB{0: root}}
+}
+
+// get returns the logical database with the given index,
+// opening it if necessary.
+func (d *databases) get(index int) (*DB, error) {
+	if index < 0 || index >= NumDatabases {
+		return nil, ErrDBIndex
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if db, ok := d.dbs[index]; ok {
+		return db, nil
+	}
+
+	// Each logical database has its own set of tables,
+	// prefixed with the database index. Unlike a database
+	// column in rkey, the prefix (same as Options.TablePrefix)
+	// keeps the queries, views, triggers and the unique key
+	// index unchanged, so the keys are isolated for free.
+	opts := *d.root.opts
+	opts.TablePrefix += "db" + strconv.Itoa(index) + "_"
+	opts.WriterQueue = 0
+	db, err := openShared(d.root.SQL, &opts)
+	if err != nil {
+		return nil, fmt.Errorf("select db %d: %w", index, err)
+	}
+	db.setReader(d.root.DB.Reader)
+	db.setWriter(d.root.DB.Writer)
+	db.index, db.dbs, db.procs = index, d, d.root.procs
+	db.refreshQuotas()
+	d.dbs[index] = db
+	return db, nil
+}
+
+// all returns the opened logical databases, database 0 first.
+func (d *databases) all() []*DB {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dbs := make([]*DB, 0, len(d.dbs))
+	for _, index := range slices.Sorted(maps.Keys(d.dbs)) {
+		dbs = append(dbs, d.dbs[index])
+	}
+	return dbs
+}
+
+// close closes the logical databases except database 0.
+func (d *databases) close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for index, db := range d.dbs {
+		if index == 0 {
+			continue
+		}
+		_ = db.Close()
+		delete(d.dbs, index)
+	}
+}
+
+// Select returns the logical database with the given index
+// (0 to NumDatabases-1), like the SELECT command in Redis.
+// Database 0 is the one returned by [Open] or [OpenDB].
+//
+// Logical databases are stored in the same SQLite file and share
+// the connection pool, but each has its own tables, so the keys
+// in one database are invisible to the others. Keys, Scan and
+// DeleteAll only work with the keys of the selected database.
+// The tables are created on the first selection.
+//
+// The returned database is closed along with database 0,
+// so do not close it separately.
+func (db *DB) Select(index int) (*DB, error) {
+	return db.dbs.get(index)
+}
+
+// Index returns the index of the logical database (see [DB.Select]).
+func (db *DB) Index() int {
+	return db.index
+}
+
+// Move moves the key to the logical database with the given index,
+// along with its value and expiration time. Returns true if the key
+// was moved, false if it does not exist in the current database
+// or already exists in the destination database.
+func (db *DB) Move(key string, index int) (bool, error) {
+	dst, err := db.Select(index)
+	if err != nil {
+		return false, err
+	}
+	if dst == db {
+		return false, errors.New("source and destination objects are the same")
+	}
+
+	var moved bool
+	err = db.Update(func(tx *Tx) error {
+		k, err := tx.Key().Get(key)
+		if err != nil || !k.Exists() {
+			return err
+		}
+		if !isExportType(k.Type) {
+			return ErrKeyType
+		}
+
+		// Both databases use the same file,
+		// so the destination tables are updated
+		// within the source transaction.
+		dtx := newTx(dst.DB.Wrap(sqlx.Unwrap(tx.tx)))
+		exists, err := dtx.Key().Exists(key)
+		if err != nil || exists {
+			return err
+		}
+
+		e, err := exportEntry(tx, k)
+		if err != nil {
+			return err
+		}
+		if err := importEntry(dtx, e); err != nil {
+			return err
+		}
+		if _, err := tx.Key().Delete(key); err != nil {
+			return err
+		}
+		moved = true
+		return nil
+	})
+	return moved, err
+}
@@ -0,0 +1,184 @@
+package redka_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestSelect(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	t.Run("zero", func(t *testing.T) {
+		db0, err := db.Select(0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, db0, db)
+		testx.AssertEqual(t, db0.Index(), 0)
+	})
+	t.Run("isolated", func(t *testing.T) {
+		db1, err := db.Select(1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, db1.Index(), 1)
+
+		_ = db.Str().Set("name", "main")
+		_ = db1.Str().Set("name", "test")
+		_ = db1.Str().Set("age", 25)
+
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "main")
+		name, _ = db1.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "test")
+
+		keys, err := db1.Key().Keys("*")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(keys), 2)
+
+		err = db1.Key().DeleteAll()
+		testx.AssertNoErr(t, err)
+		count, _ := db1.Key().Count("name", "age")
+		testx.AssertEqual(t, count, 0)
+		count, _ = db.Key().Count("name")
+		testx.AssertEqual(t, count, 1)
+	})
+	t.Run("cached", func(t *testing.T) {
+		db2, err := db.Select(2)
+		testx.AssertNoErr(t, err)
+		other, err := db.Select(2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, other, db2)
+
+		// Selecting from a logical database works too.
+		db0, err := db2.Select(0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, db0, db)
+	})
+	t.Run("background", func(t *testing.T) {
+		// Database 0 deletes the expired keys
+		// in the logical databases too.
+		db, err := redka.Open(":memory:", &redka.Options{
+			ExpireInterval: 10 * time.Millisecond,
+			WriterQueue:    10,
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+		db1, err := db.Select(1)
+		testx.AssertNoErr(t, err)
+
+		err = db1.Str().SetExpires("name", "alice", time.Millisecond)
+		testx.AssertNoErr(t, err)
+		time.Sleep(50 * time.Millisecond)
+
+		info, _ := db1.Key().Expiry()
+		testx.AssertEqual(t, info.Expired, 0)
+		testx.AssertEqual(t, db1.Stats().ExpireSweeps > 0, true)
+	})
+	t.Run("out of range", func(t *testing.T) {
+		_, err := db.Select(-1)
+		testx.AssertErr(t, err, redka.ErrDBIndex)
+		_, err = db.Select(redka.NumDatabases)
+		testx.AssertErr(t, err, redka.ErrDBIndex)
+	})
+}
+
+func TestMove(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+	db1, err := db.Select(1)
+	testx.AssertNoErr(t, err)
+
+	t.Run("string", func(t *testing.T) {
+		_ = db.Str().SetExpires("name", "alice", time.Minute)
+
+		moved, err := db.Move("name", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, moved, true)
+
+		exists, _ := db.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+		name, _ := db1.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+		key, _ := db1.Key().Get("name")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("hash", func(t *testing.T) {
+		_, _ = db.Hash().SetMany("person", map[string]any{"name": "alice", "age": 25})
+		_, _ = db.Hash().FieldExpire("person", time.Minute, "age")
+
+		moved, err := db.Move("person", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, moved, true)
+
+		items, _ := db1.Hash().Items("person")
+		testx.AssertEqual(t, len(items), 2)
+		testx.AssertEqual(t, items["name"].String(), "alice")
+		etimes, _ := db1.Hash().FieldExpireTimes("person")
+		testx.AssertEqual(t, len(etimes), 1)
+		testx.AssertEqual(t, etimes["age"].IsZero(), false)
+	})
+	t.Run("hll", func(t *testing.T) {
+		_, _ = db.HLL().Add("visitors", "alice", "bob")
+
+		moved, err := db.Move("visitors", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, moved, true)
+
+		key, _ := db1.Key().Get("visitors")
+		testx.AssertEqual(t, key.Type, core.TypeHLL)
+		count, _ := db1.HLL().Count("visitors")
+		testx.AssertEqual(t, count, 2)
+	})
+	t.Run("stream", func(t *testing.T) {
+		id, _ := db.Stream().Add("events", "user", "alice")
+
+		moved, err := db.Move("events", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, moved, true)
+
+		exists, _ := db.Key().Exists("events")
+		testx.AssertEqual(t, exists, false)
+		last, _ := db1.Stream().Last("events")
+		testx.AssertEqual(t, last, id)
+		n, _ := db1.Stream().Len("events")
+		testx.AssertEqual(t, n, 1)
+	})
+	t.Run("back", func(t *testing.T) {
+		moved, err := db1.Move("person", 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, moved, true)
+
+		items, _ := db.Hash().Items("person")
+		testx.AssertEqual(t, len(items), 2)
+		exists, _ := db1.Key().Exists("person")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("dst exists", func(t *testing.T) {
+		_ = db.Str().Set("city", "paris")
+		_ = db1.Str().Set("city", "berlin")
+
+		moved, err := db.Move("city", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, moved, false)
+
+		city, _ := db.Str().Get("city")
+		testx.AssertEqual(t, city.String(), "paris")
+		city, _ = db1.Str().Get("city")
+		testx.AssertEqual(t, city.String(), "berlin")
+	})
+	t.Run("not found", func(t *testing.T) {
+		moved, err := db.Move("nope", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, moved, false)
+	})
+	t.Run("same db", func(t *testing.T) {
+		_, err := db.Move("city", 0)
+		testx.AssertEqual(t, err != nil, true)
+	})
+	t.Run("out of range", func(t *testing.T) {
+		_, err := db.Move("city", redka.NumDatabases)
+		testx.AssertErr(t, err, redka.ErrDBIndex)
+	})
+}
@@ -0,0 +1,1119 @@
+package redka
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rdb"
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+// DumpFormat is the format of a logical dump
+// created by [DB.Export] and loaded by [DB.Import].
+type DumpFormat string
+
+// Logical dump formats.
+const (
+	// FormatJSON is the JSON Lines format, one key per line:
+	//
+	//	{"key":"name","type":"string","value":"alice"}
+	//	{"key":"person","type":"hash","etime":1700000000000,"value":{"age":"25"}}
+	//	{"key":"scores","type":"zset","value":[{"elem":"alice","score":11}]}
+	//
+	// The etime is the expiration time in Unix milliseconds
+	// (omitted if the key does not expire). A hash with expiring
+	// fields also has "field_etimes" with their expiration times
+	// ({"age":1700000000000}). If any of the key's values,
+	// hash fields or set elements is not valid UTF-8, all of them are
+	// base64-encoded, and the key has "encoding":"base64".
+	FormatJSON DumpFormat = "jsonl"
+	// FormatCSV is the CSV format with a header,
+	// one row per string, hash field or set element:
+	//
+	//	key,type,etime,field,value,score
+	//	name,string,,,alice,
+	//	person,hash,1700000000000,age,25,
+	//	scores,zset,,,alice,11
+	//
+	// The CSV format does not keep the expiration times
+	// of the hash fields, so the fields do not expire
+	// after the import. Use FormatJSON or FormatRESP for that.
+	FormatCSV DumpFormat = "csv"
+	// FormatRESP is a stream of Redis commands in the RESP protocol,
+	// the same format as the Redis append-only file (AOF). Export
+	// writes DEL, SET, HSET, ZADD, HPEXPIREAT and PEXPIREAT commands,
+	// so the dump can be replayed into Redis (e.g. with redis-cli --pipe):
+	//
+	//	DEL name
+	//	SET name alice
+	//	DEL person
+	//	HSET person age 25
+	//	PEXPIREAT person 1700000000000
+	//
+	// Import also reads the AOF files rewritten by Redis
+	// (BGREWRITEAOF) without the RDB preamble. It supports the
+	// commands that create strings, hashes and sorted sets,
+	// and skips the keys of other types (lists, sets, streams).
+	// The commands of each key must be consecutive.
+	FormatRESP DumpFormat = "resp"
+)
+
+// dumpHeader is the header of the CSV dump.
+var dumpHeader = []string{"key", "type", "etime", "field", "value", "score"}
+
+// dumpEncodingBase64 marks the base64-encoded JSON records.
+const dumpEncodingBase64 = "base64"
+
+// ExportOptions configures the logical dump.
+type ExportOptions struct {
+	// Format is the dump format. If empty, uses FormatJSON.
+	Format DumpFormat
+	// Match is the pattern of the keys to export
+	// (see [rkey.DB.Keys]). If empty, exports all keys.
+	Match string
+	// Types are the names of the key types to export
+	// ("string", "hash" or "zset"). If empty, exports all types.
+	// The HyperLogLogs are exported as strings, so "string"
+	// includes them.
+	Types []string
+}
+
+// Export writes the keys with their values and TTLs to w
+// in a documented text format (see [DumpFormat]), suitable for
+// logical backups, audits and comparing databases.
+// Use [DB.Import] to load the dump back.
+// Returns the number of exported keys. Same as Redis, the HyperLogLogs
+// are exported as strings in the dense encoding, and imported back
+// as HyperLogLogs. Skips the streams, which are not supported
+// by the dump formats.
+//
+// Reads the data in a single read-only transaction, so the
+// dump is a consistent snapshot of the database.
+//
+// The opts parameter is optional. If nil, uses default options.
+func (db *DB) Export(w io.Writer, opts *ExportOptions) (int, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	write, flush, err := newDumpWriter(w, opts.Format)
+	if err != nil {
+		return 0, err
+	}
+	types, err := parseDumpTypes(opts.Types)
+	if err != nil {
+		return 0, err
+	}
+	match := opts.Match
+	if match == "" {
+		match = "*"
+	}
+
+	var count int
+	err = db.View(func(tx *Tx) error {
+		sc := tx.Key().Scanner(match, exportPageSize)
+		for sc.Scan() {
+			key := sc.Key()
+			if !isDumpType(key.Type) || len(types) > 0 && !slices.Contains(types, exportType(key.Type)) {
+				continue
+			}
+			e, err := exportEntry(tx, key)
+			if err != nil {
+				return err
+			}
+			if err := write(e); err != nil {
+				return err
+			}
+			count++
+		}
+		if err := sc.Err(); err != nil {
+			return err
+		}
+		return flush()
+	})
+	db.log.Info("export", "format", opts.Format, "keys", count, "error", err)
+	return count, err
+}
+
+// isDumpType reports whether keys of the type can be exported
+// in the dump formats, which do not support streams.
+func isDumpType(typ core.TypeID) bool {
+	return isExportType(typ) && typ != core.TypeStream
+}
+
+// newDumpWriter returns the functions to write
+// the entries in the given format and flush the output.
+func newDumpWriter(w io.Writer, format DumpFormat) (func(rdb.Entry) error, func() error, error) {
+	switch format {
+	case "", FormatJSON:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		write := func(e rdb.Entry) error {
+			return enc.Encode(newDumpRecord(e))
+		}
+		return write, bw.Flush, nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		wroteHeader := false
+		write := func(e rdb.Entry) error {
+			if !wroteHeader {
+				if err := cw.Write(dumpHeader); err != nil {
+					return err
+				}
+				wroteHeader = true
+			}
+			return cw.WriteAll(dumpRows(e))
+		}
+		flush := func() error {
+			if !wroteHeader {
+				if err := cw.Write(dumpHeader); err != nil {
+					return err
+				}
+			}
+			cw.Flush()
+			return cw.Error()
+		}
+		return write, flush, nil
+	case FormatRESP:
+		bw := bufio.NewWriter(w)
+		var buf []byte
+		write := func(e rdb.Entry) error {
+			buf = appendRESPEntry(buf[:0], e)
+			_, err := bw.Write(buf)
+			return err
+		}
+		return write, bw.Flush, nil
+	}
+	return nil, nil, fmt.Errorf("unknown dump format: %q", format)
+}
+
+// parseDumpTypes converts the type names to the type IDs.
+func parseDumpTypes(names []string) ([]core.TypeID, error) {
+	types := make([]core.TypeID, 0, len(names))
+	for _, name := range names {
+		typ, ok := dumpTypes[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported key type: %q", name)
+		}
+		types = append(types, typ)
+	}
+	return types, nil
+}
+
+// dumpTypes maps the type names to the type IDs.
+var dumpTypes = map[string]core.TypeID{
+	"string": core.TypeString,
+	"hash":   core.TypeHash,
+	"zset":   core.TypeSortedSet,
+}
+
+// dumpRecord is a key in the JSON Lines dump.
+type dumpRecord struct {
+	Key         string           `json:"key"`
+	KeyEncoding string           `json:"key_encoding,omitempty"`
+	Type        string           `json:"type"`
+	ETime       *int64           `json:"etime,omitempty"`
+	Encoding    string           `json:"encoding,omitempty"`
+	Value       json.RawMessage  `json:"value"`
+	FieldETimes map[string]int64 `json:"field_etimes,omitempty"`
+}
+
+// dumpItem is a sorted set element in the JSON Lines dump.
+type dumpItem struct {
+	Elem  string    `json:"elem"`
+	Score dumpScore `json:"score"`
+}
+
+// dumpScore is a sorted set score. JSON does not support
+// infinite numbers, so they are encoded as "inf" and "-inf".
+type dumpScore float64
+
+// MarshalJSON implements the json.Marshaler interface.
+func (s dumpScore) MarshalJSON() ([]byte, error) {
+	f := float64(s)
+	switch {
+	case math.IsInf(f, 1):
+		return []byte(`"inf"`), nil
+	case math.IsInf(f, -1):
+		return []byte(`"-inf"`), nil
+	}
+	return strconv.AppendFloat(nil, f, 'g', -1, 64), nil
+}
+
+// newDumpRecord creates a JSON record from the entry.
+func newDumpRecord(e rdb.Entry) dumpRecord {
+	rec := dumpRecord{Key: e.Key, ETime: e.ETime}
+	if !utf8.ValidString(e.Key) {
+		// JSON strings are UTF-8, so a binary key
+		// would not survive the round trip as is.
+		rec.Key = base64.StdEncoding.EncodeToString([]byte(e.Key))
+		rec.KeyEncoding = dumpEncodingBase64
+	}
+	rec.Type = (core.Key{Type: e.Type}).TypeName()
+	enc := func(s string) string { return s }
+	if !isEntryUTF8(e) {
+		rec.Encoding = dumpEncodingBase64
+		enc = func(s string) string {
+			return base64.StdEncoding.EncodeToString([]byte(s))
+		}
+	}
+
+	var val any
+	switch e.Type {
+	case core.TypeString:
+		val = enc(string(e.Str))
+	case core.TypeHash:
+		m := make(map[string]string, len(e.Hash))
+		for field, v := range e.Hash {
+			m[enc(field)] = enc(string(v))
+		}
+		val = m
+		if len(e.HashETimes) > 0 {
+			rec.FieldETimes = make(map[string]int64, len(e.HashETimes))
+			for field, etime := range e.HashETimes {
+				rec.FieldETimes[enc(field)] = etime
+			}
+		}
+	case core.TypeSortedSet:
+		items := sortedItems(e.ZSet)
+		for i := range items {
+			items[i].Elem = enc(items[i].Elem)
+		}
+		val = items
+	}
+	// Marshaling strings, maps and slices never fails.
+	rec.Value, _ = json.Marshal(val)
+	return rec
+}
+
+// dumpRows creates the CSV rows from the entry.
+func dumpRows(e rdb.Entry) [][]string {
+	typ := (core.Key{Type: e.Type}).TypeName()
+	var etime string
+	if e.ETime != nil {
+		etime = strconv.FormatInt(*e.ETime, 10)
+	}
+	var rows [][]string
+	switch e.Type {
+	case core.TypeString:
+		rows = append(rows, []string{e.Key, typ, etime, "", string(e.Str), ""})
+	case core.TypeHash:
+		fields := make([]string, 0, len(e.Hash))
+		for field := range e.Hash {
+			fields = append(fields, field)
+		}
+		slices.Sort(fields)
+		for _, field := range fields {
+			rows = append(rows, []string{e.Key, typ, etime, field, string(e.Hash[field]), ""})
+		}
+	case core.TypeSortedSet:
+		for _, it := range sortedItems(e.ZSet) {
+			score := strconv.FormatFloat(float64(it.Score), 'g', -1, 64)
+			rows = append(rows, []string{e.Key, typ, etime, "", it.Elem, score})
+		}
+	}
+	return rows
+}
+
+// sortedItems returns the set elements ordered by score and element.
+func sortedItems(zset map[string]float64) []dumpItem {
+	items := make([]dumpItem, 0, len(zset))
+	for elem, score := range zset {
+		items = append(items, dumpItem{Elem: elem, Score: dumpScore(score)})
+	}
+	slices.SortFunc(items, func(a, b dumpItem) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), cmp.Compare(a.Elem, b.Elem))
+	})
+	return items
+}
+
+// isEntryUTF8 reports whether all the entry values are valid UTF-8.
+func isEntryUTF8(e rdb.Entry) bool {
+	if !utf8.Valid(e.Str) {
+		return false
+	}
+	for field, val := range e.Hash {
+		if !utf8.ValidString(field) || !utf8.Valid(val) {
+			return false
+		}
+	}
+	for elem := range e.ZSet {
+		if !utf8.ValidString(elem) {
+			return false
+		}
+	}
+	return true
+}
+
+// ConflictPolicy defines how [DB.Import] handles
+// the keys that already exist in the database.
+type ConflictPolicy string
+
+// Conflict policies.
+const (
+	// ConflictReplace replaces the existing key.
+	ConflictReplace ConflictPolicy = "replace"
+	// ConflictSkip keeps the existing key and skips the imported one.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictMerge adds the imported hash fields or set elements
+	// to the existing key of the same type (updating the ones that
+	// exist in both). Strings and keys of other types are replaced.
+	ConflictMerge ConflictPolicy = "merge"
+)
+
+// DumpImportOptions configures the logical dump import.
+type DumpImportOptions struct {
+	// Format is the dump format. If empty, uses FormatJSON.
+	Format DumpFormat
+	// Conflict defines how to handle the existing keys.
+	// If empty, uses ConflictReplace.
+	Conflict ConflictPolicy
+	// BatchSize is the number of keys imported in a single transaction.
+	// If zero, imports 1000 keys per transaction.
+	BatchSize int
+	// Progress is called after each batch is committed.
+	// If nil, it is ignored.
+	Progress func(ImportStats)
+}
+
+// Import loads the keys from a logical dump created by [DB.Export]
+// or a Redis AOF file (see [DumpFormat] for the format description,
+// and [FormatRESP] for the supported commands). Validates the key
+// types, values and TTLs, and fails on the first invalid record.
+// Keys that are already expired are not imported.
+//
+// Keys are imported in batched transactions (see [DumpImportOptions]),
+// so if the import fails midway, the already committed
+// batches remain in the database.
+//
+// The opts parameter is optional. If nil, uses default options.
+func (db *DB) Import(r io.Reader, opts *DumpImportOptions) (ImportStats, error) {
+	if opts == nil {
+		opts = &DumpImportOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatch
+	}
+	conflict := opts.Conflict
+	switch conflict {
+	case "":
+		conflict = ConflictReplace
+	case ConflictReplace, ConflictSkip, ConflictMerge:
+	default:
+		return ImportStats{}, fmt.Errorf("unknown conflict policy: %q", conflict)
+	}
+	cr := &countingReader{r: r}
+	next, err := newDumpReader(cr, opts.Format)
+	if err != nil {
+		return ImportStats{}, err
+	}
+
+	var stats ImportStats
+	batch := make([]rdb.Entry, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		var imported, skipped int
+		err := db.Update(func(tx *Tx) error {
+			imported, skipped = 0, 0
+			for _, e := range batch {
+				ok, err := importDumpEntry(tx, e, conflict)
+				if err != nil {
+					return fmt.Errorf("import %s: %w", e.Key, err)
+				}
+				if ok {
+					imported++
+				} else {
+					skipped++
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		stats.Keys += imported
+		stats.Skipped += skipped
+		stats.Bytes = cr.n
+		batch = batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(stats)
+		}
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	for {
+		e, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+		if e.ETime != nil && *e.ETime <= now {
+			stats.Expired++
+			continue
+		}
+		batch = append(batch, e)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	err = flush()
+	stats.Bytes = cr.n
+	db.log.Info("import", "format", opts.Format, "keys", stats.Keys,
+		"skipped", stats.Skipped, "expired", stats.Expired, "error", err)
+	return stats, err
+}
+
+// importDumpEntry creates the key from the entry according to
+// the conflict policy. Returns false if the key is skipped.
+func importDumpEntry(tx *Tx, e rdb.Entry, conflict ConflictPolicy) (bool, error) {
+	switch conflict {
+	case ConflictSkip:
+		exists, err := tx.Key().Exists(e.Key)
+		if err != nil || exists {
+			return false, err
+		}
+	case ConflictMerge:
+		key, err := tx.Key().Get(e.Key)
+		if err != nil && !errors.Is(err, core.ErrNotFound) {
+			return false, err
+		}
+		if err == nil && key.Type == e.Type && e.Type != core.TypeString {
+			return true, mergeEntry(tx, e)
+		}
+	}
+	return true, importEntry(tx, e)
+}
+
+// mergeEntry adds the hash fields or set elements
+// from the entry to the existing key.
+func mergeEntry(tx *Tx, e rdb.Entry) error {
+	var err error
+	switch e.Type {
+	case core.TypeHash:
+		items := make(map[string]any, len(e.Hash))
+		for field, val := range e.Hash {
+			items[field] = val
+		}
+		_, err = tx.Hash().SetMany(e.Key, items)
+	case core.TypeSortedSet:
+		items := make(map[any]float64, len(e.ZSet))
+		for elem, score := range e.ZSet {
+			items[elem] = score
+		}
+		_, err = tx.SortedSet().AddMany(e.Key, items)
+	}
+	if err != nil {
+		return err
+	}
+	if err := importFieldETimes(tx, e); err != nil {
+		return err
+	}
+	if e.ETime != nil {
+		_, err = tx.Key().ExpireAt(e.Key, time.UnixMilli(*e.ETime))
+	}
+	return err
+}
+
+// newDumpReader returns the function that reads
+// the next entry in the given format.
+func newDumpReader(r io.Reader, format DumpFormat) (func() (rdb.Entry, error), error) {
+	switch format {
+	case "", FormatJSON:
+		return newJSONDumpReader(r), nil
+	case FormatCSV:
+		return newCSVDumpReader(r), nil
+	case FormatRESP:
+		return newRESPDumpReader(r), nil
+	}
+	return nil, fmt.Errorf("unknown dump format: %q", format)
+}
+
+// newJSONDumpReader reads the entries from the JSON Lines dump.
+func newJSONDumpReader(r io.Reader) func() (rdb.Entry, error) {
+	dec := json.NewDecoder(bufio.NewReader(r))
+	var n int
+	return func() (rdb.Entry, error) {
+		var rec dumpRecord
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				return rdb.Entry{}, io.EOF
+			}
+			return rdb.Entry{}, fmt.Errorf("import: record %d: %w", n+1, err)
+		}
+		n++
+		e, err := rec.entry()
+		if err != nil {
+			return e, fmt.Errorf("import: record %d: %w", n, err)
+		}
+		return e, nil
+	}
+}
+
+// entry creates an entry from the JSON record.
+func (rec dumpRecord) entry() (rdb.Entry, error) {
+	key := rec.Key
+	switch rec.KeyEncoding {
+	case "":
+	case dumpEncodingBase64:
+		b, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return rdb.Entry{}, fmt.Errorf("invalid key: %w", err)
+		}
+		key = string(b)
+	default:
+		return rdb.Entry{}, fmt.Errorf("unknown key encoding: %q", rec.KeyEncoding)
+	}
+	e, err := newDumpEntry(key, rec.Type, rec.ETime)
+	if err != nil {
+		return e, err
+	}
+	dec := func(s string) (string, error) { return s, nil }
+	switch rec.Encoding {
+	case "":
+	case dumpEncodingBase64:
+		dec = func(s string) (string, error) {
+			b, err := base64.StdEncoding.DecodeString(s)
+			return string(b), err
+		}
+	default:
+		return e, fmt.Errorf("unknown encoding: %q", rec.Encoding)
+	}
+
+	switch e.Type {
+	case core.TypeString:
+		var val string
+		if err := json.Unmarshal(rec.Value, &val); err != nil {
+			return e, fmt.Errorf("invalid string value: %w", err)
+		}
+		if val, err = dec(val); err != nil {
+			return e, err
+		}
+		e.Str = []byte(val)
+	case core.TypeHash:
+		var m map[string]string
+		if err := json.Unmarshal(rec.Value, &m); err != nil || m == nil {
+			return e, fmt.Errorf("invalid hash value: %v", err)
+		}
+		e.Hash = make(map[string][]byte, len(m))
+		for field, val := range m {
+			if field, err = dec(field); err != nil {
+				return e, err
+			}
+			if val, err = dec(val); err != nil {
+				return e, err
+			}
+			e.Hash[field] = []byte(val)
+		}
+		for field, etime := range rec.FieldETimes {
+			if field, err = dec(field); err != nil {
+				return e, err
+			}
+			if _, ok := e.Hash[field]; !ok {
+				return e, fmt.Errorf("field_etimes: unknown field: %q", field)
+			}
+			if etime <= 0 {
+				return e, fmt.Errorf("field_etimes: invalid etime: %d", etime)
+			}
+			if e.HashETimes == nil {
+				e.HashETimes = make(map[string]int64, len(rec.FieldETimes))
+			}
+			e.HashETimes[field] = etime
+		}
+	case core.TypeSortedSet:
+		var items []dumpItem
+		if err := json.Unmarshal(rec.Value, &items); err != nil || items == nil {
+			return e, fmt.Errorf("invalid zset value: %v", err)
+		}
+		e.ZSet = make(map[string]float64, len(items))
+		for _, it := range items {
+			elem, err := dec(it.Elem)
+			if err != nil {
+				return e, err
+			}
+			e.ZSet[elem] = float64(it.Score)
+		}
+	}
+	return e, nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (s *dumpScore) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err == nil {
+		f, err := parseDumpScore(str)
+		*s = dumpScore(f)
+		return err
+	}
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("invalid score: %s", data)
+	}
+	*s = dumpScore(f)
+	return nil
+}
+
+// newCSVDumpReader reads the entries from the CSV dump.
+// The rows of each key must be consecutive.
+func newCSVDumpReader(r io.Reader) func() (rdb.Entry, error) {
+	cr := csv.NewReader(bufio.NewReader(r))
+	cr.FieldsPerRecord = len(dumpHeader)
+	var header bool
+	var pending []string
+	return func() (rdb.Entry, error) {
+		if !header {
+			row, err := cr.Read()
+			if err != nil {
+				return rdb.Entry{}, csvErr(cr, err)
+			}
+			if !slices.Equal(row, dumpHeader) {
+				return rdb.Entry{}, fmt.Errorf("import: invalid csv header: %v", row)
+			}
+			header = true
+		}
+
+		row := pending
+		pending = nil
+		if row == nil {
+			var err error
+			if row, err = cr.Read(); err != nil {
+				return rdb.Entry{}, csvErr(cr, err)
+			}
+		}
+		line, _ := cr.FieldPos(0)
+		e, err := parseCSVKey(row)
+		if err != nil {
+			return e, fmt.Errorf("import: line %d: %w", line, err)
+		}
+		for {
+			if err := addCSVRow(&e, row); err != nil {
+				return e, fmt.Errorf("import: line %d: %w", line, err)
+			}
+			next, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return e, nil
+			}
+			if err != nil {
+				return e, csvErr(cr, err)
+			}
+			line, _ = cr.FieldPos(0)
+			if next[0] != row[0] {
+				pending = next
+				return e, nil
+			}
+			if next[1] != row[1] || next[2] != row[2] {
+				return e, fmt.Errorf("import: line %d: rows of key %s differ in type or etime",
+					line, e.Key)
+			}
+			if e.Type == core.TypeString {
+				return e, fmt.Errorf("import: line %d: duplicate string key %s", line, e.Key)
+			}
+			row = next
+		}
+	}
+}
+
+// parseCSVKey creates an entry from the key columns of the CSV row.
+func parseCSVKey(row []string) (rdb.Entry, error) {
+	var etime *int64
+	if row[2] != "" {
+		ms, err := strconv.ParseInt(row[2], 10, 64)
+		if err != nil {
+			return rdb.Entry{}, fmt.Errorf("invalid etime: %q", row[2])
+		}
+		etime = &ms
+	}
+	e, err := newDumpEntry(row[0], row[1], etime)
+	if err != nil {
+		return e, err
+	}
+	switch e.Type {
+	case core.TypeHash:
+		e.Hash = map[string][]byte{}
+	case core.TypeSortedSet:
+		e.ZSet = map[string]float64{}
+	}
+	return e, nil
+}
+
+// addCSVRow adds the value from the CSV row to the entry.
+func addCSVRow(e *rdb.Entry, row []string) error {
+	switch e.Type {
+	case core.TypeString:
+		e.Str = []byte(row[4])
+	case core.TypeHash:
+		e.Hash[row[3]] = []byte(row[4])
+	case core.TypeSortedSet:
+		score, err := parseDumpScore(row[5])
+		if err != nil {
+			return err
+		}
+		e.ZSet[row[4]] = score
+	}
+	return nil
+}
+
+// csvErr adds the line number to the CSV reader error.
+func csvErr(cr *csv.Reader, err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.EOF
+	}
+	line, _ := cr.FieldPos(0)
+	return fmt.Errorf("import: line %d: %w", line, err)
+}
+
+// appendRESPEntry appends the commands that create
+// the key from the entry to b.
+func appendRESPEntry(b []byte, e rdb.Entry) []byte {
+	key := []byte(e.Key)
+	b = resp.AppendCommand(b, []byte("DEL"), key)
+	switch e.Type {
+	case core.TypeString:
+		b = resp.AppendCommand(b, []byte("SET"), key, e.Str)
+	case core.TypeHash:
+		args := make([][]byte, 0, 2+2*len(e.Hash))
+		args = append(args, []byte("HSET"), key)
+		for _, field := range slices.Sorted(maps.Keys(e.Hash)) {
+			args = append(args, []byte(field), e.Hash[field])
+		}
+		b = resp.AppendCommand(b, args...)
+		for _, field := range slices.Sorted(maps.Keys(e.HashETimes)) {
+			b = resp.AppendCommand(b, []byte("HPEXPIREAT"), key,
+				strconv.AppendInt(nil, e.HashETimes[field], 10),
+				[]byte("FIELDS"), []byte("1"), []byte(field))
+		}
+	case core.TypeSortedSet:
+		args := make([][]byte, 0, 2+2*len(e.ZSet))
+		args = append(args, []byte("ZADD"), key)
+		for _, it := range sortedItems(e.ZSet) {
+			args = append(args, formatRESPScore(float64(it.Score)), []byte(it.Elem))
+		}
+		b = resp.AppendCommand(b, args...)
+	}
+	if e.ETime != nil {
+		b = resp.AppendCommand(b, []byte("PEXPIREAT"), key,
+			strconv.AppendInt(nil, *e.ETime, 10))
+	}
+	return b
+}
+
+// formatRESPScore formats the sorted set score
+// the way Redis parses it.
+func formatRESPScore(f float64) []byte {
+	switch {
+	case math.IsInf(f, 1):
+		return []byte("+inf")
+	case math.IsInf(f, -1):
+		return []byte("-inf")
+	}
+	return strconv.AppendFloat(nil, f, 'g', -1, 64)
+}
+
+// respSkipCmds are the commands that create the keys
+// of the types not supported by the dump.
+var respSkipCmds = map[string]bool{
+	"lpush": true, "rpush": true, "sadd": true, "xadd": true,
+	"xsetid": true, "xgroup": true, "xclaim": true,
+}
+
+// respIgnoreCmds are the commands that do not change the keys.
+var respIgnoreCmds = map[string]bool{
+	"multi": true, "exec": true, "ping": true,
+}
+
+// newRESPDumpReader reads the entries from the RESP commands.
+// The commands of each key must be consecutive.
+func newRESPDumpReader(r io.Reader) func() (rdb.Entry, error) {
+	rd := bufio.NewReader(r)
+	var n int
+	var pending [][]byte
+	var skipped string // the key of an unsupported type
+	return func() (rdb.Entry, error) {
+		var e *rdb.Entry
+		for {
+			args := pending
+			pending = nil
+			if args == nil {
+				var err error
+				args, _, err = resp.ReadCommand(rd)
+				if errors.Is(err, io.EOF) && e != nil {
+					return *e, nil
+				}
+				if err != nil {
+					if errors.Is(err, io.EOF) {
+						return rdb.Entry{}, io.EOF
+					}
+					return rdb.Entry{}, fmt.Errorf("import: command %d: %w", n+1, err)
+				}
+				n++
+			}
+
+			name := strings.ToLower(string(args[0]))
+			if respIgnoreCmds[name] {
+				continue
+			}
+			if name == "select" {
+				if len(args) != 2 || string(args[1]) != "0" {
+					return rdb.Entry{}, fmt.Errorf("import: command %d: only database 0 is supported", n)
+				}
+				continue
+			}
+			if len(args) < 2 {
+				return rdb.Entry{}, fmt.Errorf("import: command %d: %s without a key", n, name)
+			}
+			key := string(args[1])
+			if e != nil && key != e.Key {
+				// The next key starts, so the entry is complete.
+				pending = args
+				return *e, nil
+			}
+			if key == skipped {
+				continue
+			}
+			if respSkipCmds[name] {
+				e, skipped = nil, key
+				continue
+			}
+			next, err := applyRESPCommand(e, name, args[1:])
+			if err != nil {
+				return rdb.Entry{}, fmt.Errorf("import: command %d: %s: %w", n, name, err)
+			}
+			e, skipped = next, ""
+		}
+	}
+}
+
+// applyRESPCommand applies the command to the entry of its key
+// (nil if there is none yet) and returns the updated entry
+// (nil if the key is deleted).
+func applyRESPCommand(e *rdb.Entry, name string, args [][]byte) (*rdb.Entry, error) {
+	key := string(args[0])
+	switch name {
+	case "del", "unlink":
+		if len(args) != 1 {
+			return nil, errors.New("multiple keys are not supported")
+		}
+		return nil, nil
+
+	case "set":
+		if len(args) < 2 {
+			return nil, errors.New("wrong number of arguments")
+		}
+		etime, err := parseRESPSetTTL(args[2:])
+		if err != nil {
+			return nil, err
+		}
+		return &rdb.Entry{Key: key, Type: core.TypeString, Str: args[1], ETime: etime}, nil
+
+	case "hset", "hmset":
+		if len(args) < 3 || len(args)%2 != 1 {
+			return nil, errors.New("wrong number of arguments")
+		}
+		if e == nil {
+			e = &rdb.Entry{Key: key, Type: core.TypeHash, Hash: map[string][]byte{}}
+		}
+		if e.Type != core.TypeHash {
+			return nil, ErrKeyType
+		}
+		for i := 1; i < len(args); i += 2 {
+			e.Hash[string(args[i])] = args[i+1]
+			// Setting a field removes its expiration time.
+			delete(e.HashETimes, string(args[i]))
+		}
+		return e, nil
+
+	case "hpexpireat", "hexpireat", "hpexpire", "hexpire", "hpersist":
+		if e == nil || e.Type != core.TypeHash {
+			return nil, errors.New("hash does not exist")
+		}
+		fieldsAt := 1
+		var etime int64
+		if name != "hpersist" {
+			if len(args) < 2 {
+				return nil, errors.New("wrong number of arguments")
+			}
+			n, err := strconv.ParseInt(string(args[1]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time: %q", args[1])
+			}
+			etime = respETime(name[1:], n)
+			fieldsAt = 2
+		}
+		fields, err := parseRESPFields(args[fieldsAt:])
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range fields {
+			if _, ok := e.Hash[field]; !ok {
+				continue
+			}
+			if name == "hpersist" {
+				delete(e.HashETimes, field)
+				continue
+			}
+			if e.HashETimes == nil {
+				e.HashETimes = map[string]int64{}
+			}
+			e.HashETimes[field] = etime
+		}
+		return e, nil
+
+	case "zadd":
+		if len(args) < 3 || len(args)%2 != 1 {
+			return nil, errors.New("wrong number of arguments or unsupported options")
+		}
+		if e == nil {
+			e = &rdb.Entry{Key: key, Type: core.TypeSortedSet, ZSet: map[string]float64{}}
+		}
+		if e.Type != core.TypeSortedSet {
+			return nil, ErrKeyType
+		}
+		for i := 1; i < len(args); i += 2 {
+			score, err := parseDumpScore(string(args[i]))
+			if err != nil {
+				return nil, err
+			}
+			e.ZSet[string(args[i+1])] = score
+		}
+		return e, nil
+
+	case "pexpireat", "expireat", "pexpire", "expire":
+		if len(args) != 2 {
+			return nil, errors.New("wrong number of arguments")
+		}
+		if e == nil {
+			return nil, errors.New("key does not exist")
+		}
+		n, err := strconv.ParseInt(string(args[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time: %q", args[1])
+		}
+		etime := respETime(name, n)
+		e.ETime = &etime
+		return e, nil
+
+	case "persist":
+		if e != nil {
+			e.ETime = nil
+		}
+		return e, nil
+	}
+	return nil, errors.New("unsupported command")
+}
+
+// parseRESPFields parses the FIELDS numfields field [field ...]
+// part of the hash field expiration commands.
+func parseRESPFields(args [][]byte) ([]string, error) {
+	if len(args) < 2 || strings.ToLower(string(args[0])) != "fields" {
+		return nil, errors.New("unsupported options")
+	}
+	n, err := strconv.Atoi(string(args[1]))
+	if err != nil || n != len(args)-2 {
+		return nil, errors.New("invalid number of fields")
+	}
+	fields := make([]string, n)
+	for i, arg := range args[2:] {
+		fields[i] = string(arg)
+	}
+	return fields, nil
+}
+
+// parseRESPSetTTL parses the expiration options of the SET command.
+// Returns nil if the key does not expire.
+func parseRESPSetTTL(args [][]byte) (*int64, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	if len(args) != 2 {
+		return nil, errors.New("unsupported options")
+	}
+	n, err := strconv.ParseInt(string(args[1]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time: %q", args[1])
+	}
+	var etime int64
+	switch strings.ToLower(string(args[0])) {
+	case "ex":
+		etime = respETime("expire", n)
+	case "px":
+		etime = respETime("pexpire", n)
+	case "exat":
+		etime = respETime("expireat", n)
+	case "pxat":
+		etime = respETime("pexpireat", n)
+	default:
+		return nil, fmt.Errorf("unsupported option: %s", args[0])
+	}
+	return &etime, nil
+}
+
+// respETime converts the time argument of the expire command
+// to the expiration time in Unix milliseconds.
+func respETime(name string, n int64) int64 {
+	switch name {
+	case "expireat":
+		return n * 1000
+	case "pexpire":
+		return time.Now().UnixMilli() + n
+	case "expire":
+		return time.Now().UnixMilli() + n*1000
+	}
+	return n
+}
+
+// newDumpEntry creates an entry after validating the key properties.
+func newDumpEntry(key, typeName string, etime *int64) (rdb.Entry, error) {
+	if key == "" {
+		return rdb.Entry{}, errors.New("empty key")
+	}
+	typ, ok := dumpTypes[typeName]
+	if !ok {
+		return rdb.Entry{}, fmt.Errorf("unsupported key type: %q", typeName)
+	}
+	if etime != nil && *etime <= 0 {
+		return rdb.Entry{}, fmt.Errorf("invalid etime: %d", *etime)
+	}
+	return rdb.Entry{Key: key, Type: typ, ETime: etime}, nil
+}
+
+// parseDumpScore parses the sorted set score.
+func parseDumpScore(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) {
+		return 0, fmt.Errorf("invalid score: %q", s)
+	}
+	return f, nil
+}
+
+// countingReader counts the bytes read from the reader.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,295 @@
+package redka_test
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/resp"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestExport(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	_ = db.Str().SetExpires("bin", []byte{0xff, 0x00}, time.Hour)
+	_, _ = db.Key().ExpireAt("bin", time.UnixMilli(4102444800000))
+	_, _ = db.Hash().SetMany("person", map[string]any{"name": "alice", "age": 25})
+	_, _ = db.SortedSet().AddMany("scores", map[any]float64{"bob": 22, "alice": 11})
+	_, _ = db.SortedSet().Add("limits", "max", math.Inf(1))
+	// Streams are not exported.
+	_, _ = db.Stream().Add("events", "user", "alice")
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		count, err := db.Export(&buf, nil)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 5)
+		want := `{"key":"name","type":"string","value":"alice"}
+{"key":"bin","type":"string","etime":4102444800000,"encoding":"base64","value":"/wA="}
+{"key":"person","type":"hash","value":{"age":"25","name":"alice"}}
+{"key":"scores","type":"zset","value":[{"elem":"alice","score":11},{"elem":"bob","score":22}]}
+{"key":"limits","type":"zset","value":[{"elem":"max","score":"inf"}]}
+`
+		testx.AssertEqual(t, buf.String(), want)
+	})
+	t.Run("csv", func(t *testing.T) {
+		var buf bytes.Buffer
+		opts := &redka.ExportOptions{Format: redka.FormatCSV, Types: []string{"hash", "zset"}}
+		count, err := db.Export(&buf, opts)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 3)
+		want := `key,type,etime,field,value,score
+person,hash,,age,25,
+person,hash,,name,alice,
+scores,zset,,,alice,11
+scores,zset,,,bob,22
+limits,zset,,,max,+Inf
+`
+		testx.AssertEqual(t, buf.String(), want)
+	})
+	t.Run("resp", func(t *testing.T) {
+		var buf bytes.Buffer
+		opts := &redka.ExportOptions{Format: redka.FormatRESP, Match: "[nl]*"}
+		count, err := db.Export(&buf, opts)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 2)
+		want := "*2\r\n$3\r\nDEL\r\n$4\r\nname\r\n" +
+			"*3\r\n$3\r\nSET\r\n$4\r\nname\r\n$5\r\nalice\r\n" +
+			"*2\r\n$3\r\nDEL\r\n$6\r\nlimits\r\n" +
+			"*4\r\n$4\r\nZADD\r\n$6\r\nlimits\r\n$4\r\n+inf\r\n$3\r\nmax\r\n"
+		testx.AssertEqual(t, buf.String(), want)
+	})
+	t.Run("field etimes", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().SetMany("person", map[string]any{"name": "alice", "age": 25})
+		_, _ = db.Hash().FieldExpireAt("person", time.UnixMilli(4102444800000), "age")
+
+		var buf bytes.Buffer
+		_, err := db.Export(&buf, nil)
+		testx.AssertNoErr(t, err)
+		want := `{"key":"person","type":"hash","value":{"age":"25","name":"alice"},"field_etimes":{"age":4102444800000}}
+`
+		testx.AssertEqual(t, buf.String(), want)
+
+		buf.Reset()
+		_, err = db.Export(&buf, &redka.ExportOptions{Format: redka.FormatRESP})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, bytes.Contains(buf.Bytes(), []byte(respCommands(
+			[]string{"HPEXPIREAT", "person", "4102444800000", "FIELDS", "1", "age"},
+		))), true)
+	})
+	t.Run("match", func(t *testing.T) {
+		var buf bytes.Buffer
+		count, err := db.Export(&buf, &redka.ExportOptions{Match: "na*"})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+	})
+	t.Run("invalid options", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := db.Export(&buf, &redka.ExportOptions{Format: "xml"})
+		testx.AssertEqual(t, err != nil, true)
+		_, err = db.Export(&buf, &redka.ExportOptions{Types: []string{"list"}})
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
+
+func TestImport(t *testing.T) {
+	src := getDB(t)
+	defer src.Close()
+	_ = src.Str().Set("name", "alice")
+	_ = src.Str().SetExpires("bin", []byte{0xff, 0x00}, time.Hour)
+	_, _ = src.Hash().SetMany("person", map[string]any{"name": "alice", "age": 25})
+	_, _ = src.SortedSet().AddMany("scores", map[any]float64{"bob": 22, "alice": 11})
+	_, _ = src.SortedSet().Add("limits", "max", math.Inf(1))
+	_ = src.Str().Set("\xffkey\x00", "binary key")
+	_, _ = src.HLL().Add("visitors", "alice", "bob")
+
+	for _, format := range []redka.DumpFormat{redka.FormatJSON, redka.FormatCSV, redka.FormatRESP} {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			_, err := src.Export(&buf, &redka.ExportOptions{Format: format})
+			testx.AssertNoErr(t, err)
+
+			db := getDB(t)
+			defer db.Close()
+			stats, err := db.Import(&buf, &redka.DumpImportOptions{Format: format, BatchSize: 2})
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, stats.Keys, 7)
+
+			bin, _ := db.Str().Get("bin")
+			testx.AssertEqual(t, bin.Bytes(), []byte{0xff, 0x00})
+			key, _ := db.Key().Get("bin")
+			testx.AssertEqual(t, key.ETime != nil, true)
+			age, _ := db.Hash().Get("person", "age")
+			testx.AssertEqual(t, age.String(), "25")
+			score, _ := db.SortedSet().GetScore("scores", "bob")
+			testx.AssertEqual(t, score, 22.0)
+			score, _ = db.SortedSet().GetScore("limits", "max")
+			testx.AssertEqual(t, score, math.Inf(1))
+			val, _ := db.Str().Get("\xffkey\x00")
+			testx.AssertEqual(t, val.String(), "binary key")
+			key, _ = db.Key().Get("visitors")
+			testx.AssertEqual(t, key.Type, core.TypeHLL)
+			count, _ := db.HLL().Count("visitors")
+			testx.AssertEqual(t, count, 2)
+		})
+	}
+	t.Run("field ttl", func(t *testing.T) {
+		src := getDB(t)
+		defer src.Close()
+		at := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+		_, _ = src.Hash().SetMany("person", map[string]any{"name": "alice", "age": 25})
+		_, _ = src.Hash().FieldExpireAt("person", at, "age")
+
+		for _, format := range []redka.DumpFormat{redka.FormatJSON, redka.FormatRESP} {
+			var buf bytes.Buffer
+			_, err := src.Export(&buf, &redka.ExportOptions{Format: format})
+			testx.AssertNoErr(t, err)
+
+			db := getDB(t)
+			_, err = db.Import(&buf, &redka.DumpImportOptions{Format: format})
+			testx.AssertNoErr(t, err)
+			etimes, _ := db.Hash().FieldExpireTimes("person")
+			testx.AssertEqual(t, len(etimes), 1)
+			testx.AssertEqual(t, etimes["age"].UnixMilli(), at.UnixMilli())
+			_ = db.Close()
+		}
+	})
+	t.Run("conflict", func(t *testing.T) {
+		const dump = `{"key":"name","type":"string","value":"bob"}
+{"key":"person","type":"hash","value":{"city":"paris"}}
+`
+		tests := []struct {
+			conflict redka.ConflictPolicy
+			name     string
+			fields   int
+		}{
+			{redka.ConflictReplace, "bob", 1},
+			{redka.ConflictSkip, "alice", 2},
+			{redka.ConflictMerge, "bob", 3},
+		}
+		for _, test := range tests {
+			db := getDB(t)
+			_ = db.Str().Set("name", "alice")
+			_, _ = db.Hash().SetMany("person", map[string]any{"name": "alice", "age": 25})
+
+			opts := &redka.DumpImportOptions{Conflict: test.conflict}
+			_, err := db.Import(bytes.NewBufferString(dump), opts)
+			testx.AssertNoErr(t, err)
+
+			name, _ := db.Str().Get("name")
+			testx.AssertEqual(t, name.String(), test.name)
+			fields, _ := db.Hash().Len("person")
+			testx.AssertEqual(t, fields, test.fields)
+			_ = db.Close()
+		}
+	})
+	t.Run("aof", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		at := strconv.FormatInt(time.Now().Add(time.Hour).UnixMilli(), 10)
+		aof := respCommands(
+			[]string{"SELECT", "0"},
+			[]string{"SET", "name", "alice", "PX", "60000"},
+			[]string{"HMSET", "person", "name", "alice"},
+			[]string{"HMSET", "person", "age", "25"},
+			[]string{"PEXPIREAT", "person", at},
+			[]string{"HPEXPIREAT", "person", at, "FIELDS", "2", "name", "city"},
+			[]string{"HPEXPIRE", "person", "60", "FIELDS", "1", "age"},
+			[]string{"HPERSIST", "person", "FIELDS", "1", "age"},
+			[]string{"RPUSH", "list", "a", "b"},
+			[]string{"PEXPIREAT", "list", at},
+			[]string{"ZADD", "scores", "11", "alice", "22", "bob"},
+			[]string{"SADD", "set", "a"},
+			[]string{"MULTI"},
+			[]string{"SET", "city", "paris"},
+			[]string{"DEL", "city"},
+			[]string{"EXEC"},
+		)
+		opts := &redka.DumpImportOptions{Format: redka.FormatRESP}
+		stats, err := db.Import(bytes.NewBufferString(aof), opts)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, stats.Keys, 3)
+
+		key, _ := db.Key().Get("name")
+		testx.AssertEqual(t, key.ETime != nil, true)
+		items, _ := db.Hash().Items("person")
+		testx.AssertEqual(t, len(items), 2)
+		key, _ = db.Key().Get("person")
+		testx.AssertEqual(t, strconv.FormatInt(*key.ETime, 10), at)
+		etimes, _ := db.Hash().FieldExpireTimes("person")
+		testx.AssertEqual(t, len(etimes), 1)
+		testx.AssertEqual(t, strconv.FormatInt(etimes["name"].UnixMilli(), 10), at)
+		score, _ := db.SortedSet().GetScore("scores", "bob")
+		testx.AssertEqual(t, score, 22.0)
+		count, _ := db.Key().Count("list", "set", "city")
+		testx.AssertEqual(t, count, 0)
+	})
+	t.Run("expired", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		const dump = `{"key":"name","type":"string","etime":1000,"value":"alice"}`
+		stats, err := db.Import(bytes.NewBufferString(dump), nil)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, stats.Keys, 0)
+		testx.AssertEqual(t, stats.Expired, 1)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			format redka.DumpFormat
+			dump   string
+		}{
+			{redka.FormatJSON, `{"key":"name","type":"list","value":[]}`},
+			{redka.FormatJSON, `{"key":"name","type":"string","value":42}`},
+			{redka.FormatJSON, `{"key":"","type":"string","value":"alice"}`},
+			{redka.FormatJSON, `{"key":"name","type":"string","etime":-1,"value":"alice"}`},
+			{redka.FormatJSON, `{"key":"s","type":"zset","value":[{"elem":"a","score":"x"}]}`},
+			{redka.FormatJSON, `{"key":"name",`},
+			{redka.FormatJSON, `{"key":"h","type":"hash","value":{"a":"1"},"field_etimes":{"b":1000}}`},
+			{redka.FormatJSON, `{"key":"h","type":"hash","value":{"a":"1"},"field_etimes":{"a":-1}}`},
+			{redka.FormatCSV, "key,value\nname,alice\n"},
+			{redka.FormatCSV, "key,type,etime,field,value,score\nname,string,,,alice,\nname,string,,,bob,\n"},
+			{redka.FormatCSV, "key,type,etime,field,value,score\ns,zset,,,a,\n"},
+			{redka.FormatRESP, respCommands([]string{"SELECT", "1"})},
+			{redka.FormatRESP, respCommands([]string{"INCR", "count"})},
+			{redka.FormatRESP, respCommands([]string{"PEXPIREAT", "name", "1000"})},
+			{redka.FormatRESP, respCommands([]string{"SET", "name", "alice", "NX"})},
+			{redka.FormatRESP, respCommands([]string{"SET", "name", "alice"}, []string{"HSET", "name", "a", "b"})},
+			{redka.FormatRESP, respCommands([]string{"ZADD", "s", "x", "a"})},
+			{redka.FormatRESP, respCommands([]string{"HPEXPIREAT", "h", "1000", "FIELDS", "1", "a"})},
+			{redka.FormatRESP, respCommands([]string{"HSET", "h", "a", "1"}, []string{"HPEXPIREAT", "h", "1000", "NX", "FIELDS", "1", "a"})},
+			{redka.FormatRESP, respCommands([]string{"HSET", "h", "a", "1"}, []string{"HPEXPIREAT", "h", "1000", "FIELDS", "2", "a"})},
+			{redka.FormatRESP, "*2\r\n$3\r\nDEL\r\n"},
+		}
+		for _, test := range tests {
+			db := getDB(t)
+			opts := &redka.DumpImportOptions{Format: test.format}
+			_, err := db.Import(bytes.NewBufferString(test.dump), opts)
+			if err == nil {
+				t.Errorf("%s: expected error", test.dump)
+			}
+			_ = db.Close()
+		}
+	})
+}
+
+// respCommands encodes the commands in the RESP protocol.
+func respCommands(cmds ...[]string) string {
+	var b []byte
+	for _, cmd := range cmds {
+		args := make([][]byte, len(cmd))
+		for i, arg := range cmd {
+			args[i] = []byte(arg)
+		}
+		b = resp.AppendCommand(b, args...)
+	}
+	return string(b)
+}
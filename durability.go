@@ -0,0 +1,85 @@
+package redka
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// Durability defines when the committed writes reach the disk.
+// Set the default level with [Options.Durability], and override
+// it for specific writes with [WithDurability].
+type Durability = sqlx.Durability
+
+// Durability levels.
+const (
+	// DurabilityFull syncs the WAL on each commit, so committed
+	// transactions survive a power loss. Suits durable queues.
+	DurabilityFull = sqlx.DurabilityFull
+	// DurabilityNormal syncs the WAL on checkpoints only, so the
+	// latest transactions may roll back after a power loss
+	// (but not after an application crash). The default.
+	DurabilityNormal = sqlx.DurabilityNormal
+	// DurabilityOff never syncs, leaving it to the operating system
+	// (or to [Options.SyncInterval]). Suits caches. The database may
+	// get corrupted after a power loss.
+	DurabilityOff = sqlx.DurabilityOff
+)
+
+// WithDurability returns a context that overrides the durability
+// of the writes made with it (see [DB.UpdateContext]). Use it to
+// make specific writes more (or less) durable than the rest:
+//
+//	ctx := redka.WithDurability(ctx, redka.DurabilityFull)
+//	err := db.UpdateContext(ctx, func(tx *redka.Tx) error {
+//	    return tx.Str().Set("order:42", "paid")
+//	})
+func WithDurability(ctx context.Context, d Durability) context.Context {
+	return sqlx.WithDurability(ctx, d)
+}
+
+// startSync starts the goroutine that runs in the background
+// and syncs the database and WAL files to disk at the given
+// interval. Returns nil if the interval is zero.
+func (db *DB) startSync(interval time.Duration) (*time.Ticker, error) {
+	if interval <= 0 {
+		return nil, nil
+	}
+	var path string
+	if err := db.SQL.QueryRow(sqlDatabaseFile).Scan(&path); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		// Nothing to sync for in-memory databases.
+		return nil, nil
+	}
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			for _, name := range []string{path, path + "-wal"} {
+				if err := syncFile(name); err != nil {
+					db.log.Error("sync", "path", name, "error", err)
+				}
+			}
+		}
+	}()
+	return ticker, nil
+}
+
+// syncFile commits the file contents to disk.
+// Does nothing if the file does not exist.
+func syncFile(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
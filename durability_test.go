@@ -0,0 +1,54 @@
+package redka_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestDurability(t *testing.T) {
+	db, err := redka.Open(filepath.Join(t.TempDir(), "data.db"), &redka.Options{
+		Durability:   redka.DurabilityOff,
+		SyncInterval: 10 * time.Millisecond,
+	})
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	synchronous := func() int {
+		var val int
+		_ = db.SQL.QueryRow("pragma synchronous").Scan(&val)
+		return val
+	}
+	testx.AssertEqual(t, synchronous(), 0)
+
+	t.Run("override", func(t *testing.T) {
+		ctx := redka.WithDurability(context.Background(), redka.DurabilityFull)
+		err := db.UpdateContext(ctx, func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "alice")
+		})
+		testx.AssertNoErr(t, err)
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+		// The default level is restored after the write.
+		testx.AssertEqual(t, synchronous(), 0)
+	})
+	t.Run("invalid override", func(t *testing.T) {
+		ctx := redka.WithDurability(context.Background(), "invalid")
+		err := db.UpdateContext(ctx, func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "bob")
+		})
+		testx.AssertEqual(t, err != nil, true)
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("invalid default", func(t *testing.T) {
+		_, err := redka.Open(filepath.Join(t.TempDir(), "data.db"), &redka.Options{
+			Durability: "invalid",
+		})
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
@@ -0,0 +1,142 @@
+package redka
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const sqlCipherVersion = `pragma cipher_version`
+
+// ErrEncryption is returned when the database is opened with
+// an encryption key, but the driver does not support encryption.
+var ErrEncryption = errors.New("encryption is not supported by the driver (requires SQLCipher)")
+
+// cipherKey is the encryption key shared by all
+// the connections to the database. Safe for concurrent use.
+type cipherKey struct {
+	mu  sync.RWMutex
+	key string
+}
+
+func (k *cipherKey) get() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.key
+}
+
+func (k *cipherKey) set(key string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.key = key
+}
+
+// openSQL opens the database handle. If key is not nil, sets
+// the encryption key on each new connection before using it.
+// If settings are not empty, applies them to each new connection
+// (after the key).
+func openSQL(driverName, path string, key *cipherKey, settings string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, path)
+	if err != nil || (key == nil && settings == "") {
+		return db, err
+	}
+	drv := db.Driver()
+	_ = db.Close()
+	return sql.OpenDB(&connector{drv: drv, dsn: path, key: key, settings: settings}), nil
+}
+
+// checkEncryption makes sure that the driver supports encryption.
+// Without SQLCipher, the key pragma is silently ignored,
+// and the data would be stored unencrypted.
+func checkEncryption(db *sql.DB) error {
+	var version string
+	err := db.QueryRow(sqlCipherVersion).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) || version == "" {
+		return ErrEncryption
+	}
+	return err
+}
+
+// Rekey changes the encryption key of the database, re-encrypting
+// all the data with the new key. The database must have been opened
+// with the EncryptionKey option. Rekeying a large database takes
+// time, and blocks other writes to it.
+func (db *DB) Rekey(newKey string) error {
+	if db.key == nil {
+		return errors.New("rekey: database is not encrypted")
+	}
+	if newKey == "" {
+		return errors.New("rekey: empty key")
+	}
+	// SQLite uses a single connection (see sqlx.DB.init),
+	// so the new key applies to the whole database.
+	_, err := db.SQL.Exec(pragmaKey("rekey", newKey))
+	if err != nil {
+		return fmt.Errorf("rekey: %w", err)
+	}
+	db.key.set(newKey)
+	db.log.Info("rekey database")
+	return nil
+}
+
+// connector opens connections to an encrypted database,
+// or the connections that need specific settings.
+type connector struct {
+	drv      driver.Driver
+	dsn      string
+	key      *cipherKey
+	settings string
+}
+
+// Connect opens a new connection, sets the encryption key
+// (if any), and applies the settings (if any).
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.drv.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if c.key != nil {
+		if err := execConn(ctx, conn, pragmaKey("key", c.key.get())); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	if c.settings != "" {
+		if err := execConn(ctx, conn, c.settings); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// Driver returns the underlying driver.
+func (c *connector) Driver() driver.Driver {
+	return c.drv
+}
+
+// execConn executes a query without arguments on a driver connection.
+func execConn(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	_, err = stmt.Exec(nil) //nolint:staticcheck
+	return err
+}
+
+// pragmaKey returns the key or rekey pragma with the quoted key.
+func pragmaKey(name, key string) string {
+	return fmt.Sprintf("pragma %s = '%s'", name, strings.ReplaceAll(key, "'", "''"))
+}
@@ -0,0 +1,24 @@
+package redka_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestEncryption(t *testing.T) {
+	t.Run("unsupported driver", func(t *testing.T) {
+		// The test driver is built without SQLCipher.
+		path := filepath.Join(t.TempDir(), "data.db")
+		_, err := redka.Open(path, &redka.Options{EncryptionKey: "secret"})
+		testx.AssertErr(t, err, redka.ErrEncryption)
+	})
+	t.Run("rekey unencrypted", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		err := db.Rekey("secret")
+		testx.AssertEqual(t, err.Error(), "rekey: database is not encrypted")
+	})
+}
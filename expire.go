@@ -0,0 +1,126 @@
+package redka
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExpireStrategy defines how the expired keys are deleted
+// (see [Options.ExpireStrategy]). The expired keys are never
+// visible to reads, whatever the strategy, but they take up
+// space in the database file until deleted.
+type ExpireStrategy string
+
+// Expiration strategies.
+const (
+	// ExpireBackground deletes the expired keys and hash fields
+	// in the background every Options.ExpireInterval. The default.
+	ExpireBackground ExpireStrategy = "background"
+	// ExpireLazy does not delete the expired keys in the background.
+	// The expired keys are replaced when written to again, and the
+	// rest are deleted by [DB.SweepExpired]. Suits the databases
+	// where the keys rarely expire, or the application prefers
+	// to run the sweeps at a time of its choosing.
+	ExpireLazy ExpireStrategy = "lazy"
+)
+
+// expirer deletes the expired keys according to the options.
+// Safe for concurrent use.
+type expirer struct {
+	strategy    ExpireStrategy
+	batchSize   int           // keys per transaction
+	maxDuration time.Duration // max duration of a sweep, 0 for no limit
+	paused      atomic.Bool
+
+	mu       sync.RWMutex
+	onExpire []func(key Key)
+}
+
+// newExpirer creates an expirer with the given options.
+func newExpirer(opts *Options) *expirer {
+	return &expirer{
+		strategy:    opts.ExpireStrategy,
+		batchSize:   opts.ExpireBatchSize,
+		maxDuration: opts.ExpireMaxDuration,
+	}
+}
+
+// active reports whether the background sweeps should run.
+func (e *expirer) active() bool {
+	return e.strategy != ExpireLazy && !e.paused.Load()
+}
+
+// notify calls the OnExpire functions for the deleted key.
+func (e *expirer) notify(key Key) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, fn := range e.onExpire {
+		fn(key)
+	}
+}
+
+// OnExpire registers a function to be called for each expired key
+// deleted by the expiry sweeper (in the background or with
+// [DB.SweepExpired]), after the deletion is committed. The key
+// describes the deleted key as it was right before the deletion.
+// Use it to invalidate in-process caches of the expired keys.
+//
+// The function is called synchronously by the sweeper, so it should
+// return quickly. It may read from or write to the database.
+//
+// The expired keys replaced by writes before the sweeper gets
+// to them are not reported, and neither are the expired hash fields.
+// See [DB.OnChange] and [DB.Watch] for the other changes.
+func (db *DB) OnExpire(fn func(key Key)) {
+	db.expire.mu.Lock()
+	defer db.expire.mu.Unlock()
+	db.expire.onExpire = append(db.expire.onExpire, fn)
+}
+
+// SweepExpired deletes the expired keys and hash fields right away,
+// regardless of the expiration strategy (see [Options.ExpireStrategy])
+// and pause. Deletes the keys in batches of Options.ExpireBatchSize,
+// each in a separate transaction, and stops after the
+// Options.ExpireMaxDuration (if set), leaving the rest of the keys
+// to the next sweep. Returns the number of deleted keys.
+func (db *DB) SweepExpired() (int, error) {
+	keys, _, err := db.sweepExpired()
+	return keys, err
+}
+
+// sweepExpired deletes the expired keys and hash fields, and
+// updates the counters. Returns the number of deleted keys and fields.
+func (db *DB) sweepExpired() (keys int, fields int, err error) {
+	start := time.Now()
+	defer func() {
+		db.stats.expired.Add(int64(keys))
+		db.stats.sweeps.Add(1)
+		db.stats.sweepTime.Add(int64(time.Since(start)))
+	}()
+	var deadline time.Time
+	if db.expire.maxDuration > 0 {
+		deadline = start.Add(db.expire.maxDuration)
+	}
+	keys, err = db.keyDB.SweepExpired(db.expire.batchSize, deadline, db.expire.notify)
+	if err != nil {
+		return keys, 0, err
+	}
+	fields, err = db.hashDB.DeleteExpired()
+	return keys, fields, err
+}
+
+// PauseExpire pauses the background deletion of the expired keys
+// and hash fields until [DB.ResumeExpire] is called. The expired
+// keys are still invisible to reads while paused. Useful to keep
+// the background writes out of the way of a bulk load or a backup.
+func (db *DB) PauseExpire() {
+	db.expire.paused.Store(true)
+}
+
+// ResumeExpire resumes the background deletion of the expired keys
+// paused with [DB.PauseExpire]. The expired keys are deleted
+// on the next sweep.
+func (db *DB) ResumeExpire() {
+	db.expire.paused.Store(false)
+}
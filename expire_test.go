@@ -0,0 +1,153 @@
+package redka_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestSweepExpired(t *testing.T) {
+	t.Run("sweep", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+		_ = db.Str().SetExpires("age", 25, time.Millisecond)
+		_ = db.Str().Set("city", "paris")
+		time.Sleep(2 * time.Millisecond)
+
+		count, err := db.SweepExpired()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 2)
+
+		info, _ := db.Key().Expiry()
+		testx.AssertEqual(t, info.Expired, 0)
+		n, _ := db.Key().Count("name", "age", "city")
+		testx.AssertEqual(t, n, 1)
+
+		stats := db.Stats()
+		testx.AssertEqual(t, stats.ExpiredKeys, int64(2))
+		testx.AssertEqual(t, stats.ExpireSweeps, int64(1))
+		testx.AssertEqual(t, stats.ExpireSweepTime > 0, true)
+	})
+	t.Run("max duration", func(t *testing.T) {
+		db, err := redka.Open(":memory:", &redka.Options{
+			ExpireBatchSize:   1,
+			ExpireMaxDuration: time.Nanosecond,
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+		_ = db.Str().SetExpires("age", 25, time.Millisecond)
+		time.Sleep(2 * time.Millisecond)
+
+		// Each sweep deletes a single batch.
+		count, err := db.SweepExpired()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+		count, err = db.SweepExpired()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+		count, err = db.SweepExpired()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 0)
+	})
+}
+
+func TestExpireStrategy(t *testing.T) {
+	t.Run("background", func(t *testing.T) {
+		db, err := redka.Open(":memory:", redka.WithExpireInterval(10*time.Millisecond))
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+
+		info, _ := db.Key().Expiry()
+		testx.AssertEqual(t, info.Expired, 0)
+		testx.AssertEqual(t, db.Stats().ExpireSweeps > 0, true)
+	})
+	t.Run("lazy", func(t *testing.T) {
+		db, err := redka.Open(":memory:",
+			redka.WithExpireInterval(10*time.Millisecond),
+			redka.WithExpireStrategy(redka.ExpireLazy),
+		)
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+
+		// The expired key is not deleted, but not visible either.
+		info, _ := db.Key().Expiry()
+		testx.AssertEqual(t, info.Expired, 1)
+		exists, _ := db.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+		testx.AssertEqual(t, db.Stats().ExpireSweeps, int64(0))
+
+		count, err := db.SweepExpired()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+	})
+}
+
+func TestPauseExpire(t *testing.T) {
+	db, err := redka.Open(":memory:", redka.WithExpireInterval(10*time.Millisecond))
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	db.PauseExpire()
+	_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	info, _ := db.Key().Expiry()
+	testx.AssertEqual(t, info.Expired, 1)
+
+	db.ResumeExpire()
+	time.Sleep(50 * time.Millisecond)
+	info, _ = db.Key().Expiry()
+	testx.AssertEqual(t, info.Expired, 0)
+}
+
+func TestOnExpire(t *testing.T) {
+	t.Run("sweep", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		var keys []redka.Key
+		db.OnExpire(func(key redka.Key) {
+			keys = append(keys, key)
+		})
+
+		_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+		_ = db.Str().Set("city", "paris")
+		time.Sleep(2 * time.Millisecond)
+		_, err := db.SweepExpired()
+		testx.AssertNoErr(t, err)
+
+		testx.AssertEqual(t, len(keys), 1)
+		testx.AssertEqual(t, keys[0].Key, "name")
+		testx.AssertEqual(t, keys[0].TypeName(), "string")
+		testx.AssertEqual(t, keys[0].ETime != nil, true)
+	})
+	t.Run("background", func(t *testing.T) {
+		db, err := redka.Open(":memory:", redka.WithExpireInterval(10*time.Millisecond))
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		expired := make(chan string, 1)
+		db.OnExpire(func(key redka.Key) {
+			expired <- key.Key
+		})
+
+		_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+		select {
+		case key := <-expired:
+			testx.AssertEqual(t, key, "name")
+		case <-time.After(time.Second):
+			t.Fatal("want expired key, got none")
+		}
+	})
+}
@@ -0,0 +1,253 @@
+// Package fake provides in-memory fakes of the Redka repositories
+// for the unit tests that do not need a real database.
+//
+// The fakes implement the repository interfaces ([redka.Keys],
+// [redka.Strings], [redka.Hashes] and [redka.SortedSets]) on top of
+// Go maps, and follow the same rules as the database: the values are
+// converted to bytes the same way, writing a key of another type
+// returns ErrKeyType, and the keys expire according to the clock.
+// The fakes are checked against the database by a shared test suite.
+//
+// Depend on the interfaces in the application code:
+//
+//	type Users struct {
+//	    hash redka.Hashes
+//	}
+//
+// and pass the fake instead of db.Hash() in the tests:
+//
+//	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+//	db := fake.New(func() time.Time { return now })
+//	users := Users{hash: db.Hash()}
+//
+// Unlike the database, the fakes do not enforce the size limits
+// (see redka.Options), and forget the expired keys right away.
+// Lists and sets are not supported, same as in Redka itself.
+package fake
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// DB is an in-memory database with the fake repositories.
+// It is safe for concurrent use.
+type DB struct {
+	mu     sync.Mutex
+	clock  func() time.Time
+	keys   map[string]*entry
+	lastID int
+
+	keyDB    *Keys
+	stringDB *Strings
+	hashDB   *Hashes
+	zsetDB   *SortedSets
+}
+
+// New creates an empty in-memory database.
+// The clock is the source of the current time for the expiration
+// (use it to test the ttl without sleeping). Uses the wall clock
+// if the clock is nil.
+func New(clock func() time.Time) *DB {
+	if clock == nil {
+		clock = time.Now
+	}
+	db := &DB{clock: clock, keys: map[string]*entry{}}
+	db.keyDB = &Keys{db}
+	db.stringDB = &Strings{db}
+	db.hashDB = &Hashes{db}
+	db.zsetDB = &SortedSets{db}
+	return db
+}
+
+// Key returns the key repository.
+func (db *DB) Key() *Keys {
+	return db.keyDB
+}
+
+// Str returns the string repository.
+func (db *DB) Str() *Strings {
+	return db.stringDB
+}
+
+// Hash returns the hash repository.
+func (db *DB) Hash() *Hashes {
+	return db.hashDB
+}
+
+// SortedSet returns the sorted set repository.
+func (db *DB) SortedSet() *SortedSets {
+	return db.zsetDB
+}
+
+// entry is a key with its value.
+type entry struct {
+	key  core.Key
+	str  core.Value
+	hash map[string]core.Value
+	zset map[string]float64
+}
+
+// info returns a copy of the key details.
+func (e *entry) info() core.Key {
+	k := e.key
+	if k.ETime != nil {
+		etime := *k.ETime
+		k.ETime = &etime
+	}
+	return k
+}
+
+// now returns the current time in unix milliseconds.
+func (db *DB) now() int64 {
+	return db.clock().UnixMilli()
+}
+
+// get returns the key entry, or nil if the key
+// does not exist or has expired.
+func (db *DB) get(key string) *entry {
+	e := db.keys[key]
+	if e == nil {
+		return nil
+	}
+	if e.key.ETime != nil && *e.key.ETime <= db.now() {
+		delete(db.keys, key)
+		return nil
+	}
+	return e
+}
+
+// getType returns the key entry, or nil if the key
+// does not exist or is not of the specified type.
+func (db *DB) getType(key string, typ core.TypeID) *entry {
+	e := db.get(key)
+	if e == nil || e.key.Type != typ {
+		return nil
+	}
+	return e
+}
+
+// checkType returns ErrKeyType if the key exists
+// and is not of the specified type.
+func (db *DB) checkType(key string, typ core.TypeID) error {
+	e := db.get(key)
+	if e != nil && e.key.Type != typ {
+		return &core.KeyTypeError{Key: key, Expected: typ, Actual: e.key.Type}
+	}
+	return nil
+}
+
+// write returns the key entry for modification, creating
+// it if the key does not exist. Increments the version of
+// the existing key. Returns ErrKeyType if the key exists
+// and is not of the specified type.
+func (db *DB) write(key string, typ core.TypeID) (*entry, error) {
+	if err := db.checkType(key, typ); err != nil {
+		return nil, err
+	}
+	now := db.now()
+	if e := db.get(key); e != nil {
+		e.key.Version++
+		e.key.MTime = now
+		return e, nil
+	}
+
+	db.lastID++
+	e := &entry{key: core.Key{
+		ID:      db.lastID,
+		Key:     key,
+		Type:    typ,
+		Version: core.InitialVersion,
+		MTime:   now,
+	}}
+	switch typ {
+	case core.TypeHash:
+		e.hash = map[string]core.Value{}
+	case core.TypeSortedSet:
+		e.zset = map[string]float64{}
+	}
+	db.keys[key] = e
+	return e, nil
+}
+
+// toValue converts the value to bytes the same way
+// the database does. The value must be one of the
+// types accepted by core.IsValueType.
+func toValue(v any) core.Value {
+	switch v := v.(type) {
+	case string:
+		return core.Value(v)
+	case []byte:
+		return core.Value(bytes.Clone(v))
+	case int:
+		return core.Value(strconv.Itoa(v))
+	case float64:
+		return core.Value(strconv.FormatFloat(v, 'g', -1, 64))
+	case bool:
+		if v {
+			return core.Value("1")
+		}
+		return core.Value("0")
+	}
+	return nil
+}
+
+// match reports whether s matches the glob pattern.
+// Supports the same syntax as the SQLite GLOB operator:
+//
+//	key*  k?y  k[bce]y  k[^a-c][y-z]
+func match(pattern, s string) bool {
+	var re strings.Builder
+	re.WriteString("(?s)^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; r {
+		case '*':
+			re.WriteString(".*")
+		case '?':
+			re.WriteString(".")
+		case '[':
+			// Find the end of the character class.
+			// The closing bracket right after the opening
+			// one (or after ^) is a part of the class.
+			j := i + 1
+			if j < len(runes) && runes[j] == '^' {
+				j++
+			}
+			if j < len(runes) && runes[j] == ']' {
+				j++
+			}
+			for j < len(runes) && runes[j] != ']' {
+				j++
+			}
+			if j >= len(runes) {
+				// Unclosed class never matches.
+				return false
+			}
+			re.WriteString("[")
+			for k, c := range runes[i+1 : j] {
+				if k == 0 && c == '^' {
+					re.WriteRune(c)
+					continue
+				}
+				if c == '\\' || c == '[' || c == ']' {
+					re.WriteRune('\\')
+				}
+				re.WriteRune(c)
+			}
+			re.WriteString("]")
+			i = j
+		default:
+			re.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re.WriteString("$")
+	ok, err := regexp.MatchString(re.String(), s)
+	return ok && err == nil
+}
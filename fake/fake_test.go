@@ -0,0 +1,520 @@
+package fake_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/fake"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rzset"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+// The conformance suite runs the same checks against the database
+// and the fakes, so that the fakes stay compatible with the database.
+
+// repos is a set of repositories under test.
+type repos struct {
+	key  redka.Keys
+	str  redka.Strings
+	hash redka.Hashes
+	zset redka.SortedSets
+}
+
+// opener creates the repositories with the clock.
+type opener func(t *testing.T, clock func() time.Time) repos
+
+func openRedka(t *testing.T, clock func() time.Time) repos {
+	db, err := redka.Open(":memory:", &redka.Options{Clock: clock})
+	testx.AssertNoErr(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return repos{db.Key(), db.Str(), db.Hash(), db.SortedSet()}
+}
+
+func openFake(t *testing.T, clock func() time.Time) repos {
+	db := fake.New(clock)
+	return repos{db.Key(), db.Str(), db.Hash(), db.SortedSet()}
+}
+
+// clock is a manual clock.
+type clock struct {
+	now time.Time
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) Now() time.Time          { return c.now }
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestConformance(t *testing.T) {
+	openers := []struct {
+		name string
+		open opener
+	}{
+		{"redka", openRedka},
+		{"fake", openFake},
+	}
+	for _, o := range openers {
+		t.Run(o.name, func(t *testing.T) {
+			t.Run("keys", func(t *testing.T) { testKeys(t, o.open) })
+			t.Run("strings", func(t *testing.T) { testStrings(t, o.open) })
+			t.Run("hashes", func(t *testing.T) { testHashes(t, o.open) })
+			t.Run("sorted sets", func(t *testing.T) { testSortedSets(t, o.open) })
+		})
+	}
+}
+
+func testKeys(t *testing.T, open opener) {
+	clock := newClock()
+	r := open(t, clock.Now)
+	_ = r.str.Set("name", "alice")
+	_ = r.str.Set("age", 25)
+	_, _ = r.hash.Set("person", "name", "alice")
+
+	t.Run("exists", func(t *testing.T) {
+		ok, err := r.key.Exists("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		ok, _ = r.key.Exists("city")
+		testx.AssertEqual(t, ok, false)
+		count, err := r.key.Count("name", "age", "city", "name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 2)
+	})
+	t.Run("keys", func(t *testing.T) {
+		keys, err := r.key.Keys("*a*")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, keyNames(keys), []string{"name", "age"})
+		keys, _ = r.key.Keys("[^n]*")
+		testx.AssertEqual(t, keyNames(keys), []string{"age", "person"})
+		keys, _ = r.key.Keys("?ge")
+		testx.AssertEqual(t, keyNames(keys), []string{"age"})
+		keys, _ = r.key.Keys("city*")
+		testx.AssertEqual(t, len(keys), 0)
+	})
+	t.Run("get", func(t *testing.T) {
+		k, err := r.key.Get("person")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, k.Key, "person")
+		testx.AssertEqual(t, k.Type, core.TypeHash)
+		testx.AssertEqual(t, k.Version, core.InitialVersion)
+		testx.AssertEqual(t, k.MTime, clock.Now().UnixMilli())
+		k, _ = r.key.Get("city")
+		testx.AssertEqual(t, k.Exists(), false)
+	})
+	t.Run("expire", func(t *testing.T) {
+		ok, err := r.key.Expire("age", time.Minute)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		k, _ := r.key.Get("age")
+		testx.AssertEqual(t, *k.ETime, clock.Now().Add(time.Minute).UnixMilli())
+		ok, _ = r.key.Expire("city", time.Minute)
+		testx.AssertEqual(t, ok, false)
+
+		clock.Advance(2 * time.Minute)
+		ok, _ = r.key.Exists("age")
+		testx.AssertEqual(t, ok, false)
+		ok, _ = r.key.Persist("age")
+		testx.AssertEqual(t, ok, false)
+	})
+	t.Run("persist", func(t *testing.T) {
+		at := clock.Now().Add(time.Minute)
+		ok, _ := r.key.ExpireAt("name", at)
+		testx.AssertEqual(t, ok, true)
+		ok, err := r.key.Persist("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		clock.Advance(2 * time.Minute)
+		k, _ := r.key.Get("name")
+		testx.AssertEqual(t, k.Exists(), true)
+		testx.AssertEqual(t, k.ETime, (*int64)(nil))
+	})
+	t.Run("rename", func(t *testing.T) {
+		_ = r.str.Set("city", "paris")
+		err := r.key.Rename("name", "user")
+		testx.AssertNoErr(t, err)
+		k, _ := r.key.Get("user")
+		testx.AssertEqual(t, k.Version, 2)
+		val, _ := r.str.Get("user")
+		testx.AssertEqual(t, val.String(), "alice")
+
+		err = r.key.Rename("name", "user")
+		testx.AssertErr(t, err, core.ErrNotFound)
+		ok, err := r.key.RenameNotExists("user", "city")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+		ok, _ = r.key.RenameNotExists("user", "name")
+		testx.AssertEqual(t, ok, true)
+
+		err = r.key.Rename("name", "city")
+		testx.AssertNoErr(t, err)
+		val, _ = r.str.Get("city")
+		testx.AssertEqual(t, val.String(), "alice")
+	})
+	t.Run("copy", func(t *testing.T) {
+		ok, err := r.key.Copy("city", "town", false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		src, _ := r.key.Get("city")
+		dst, _ := r.key.Get("town")
+		testx.AssertEqual(t, dst.Type, src.Type)
+		testx.AssertEqual(t, dst.Version, src.Version)
+		val, _ := r.str.Get("town")
+		testx.AssertEqual(t, val.String(), "alice")
+
+		ok, err = r.key.Copy("person", "town", false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+		ok, err = r.key.Copy("person", "town", true)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		name, _ := r.hash.Get("town", "name")
+		testx.AssertEqual(t, name.String(), "alice")
+
+		// The copy is independent of the source.
+		_, _ = r.hash.Set("town", "name", "bob")
+		name, _ = r.hash.Get("person", "name")
+		testx.AssertEqual(t, name.String(), "alice")
+
+		_, err = r.key.Copy("nope", "town", true)
+		testx.AssertErr(t, err, core.ErrNotFound)
+		_, _ = r.key.Delete("town")
+	})
+	t.Run("delete", func(t *testing.T) {
+		count, err := r.key.Delete("city", "age", "nope")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+		k, err := r.key.Random()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, k.Key, "person")
+		err = r.key.DeleteAll()
+		testx.AssertNoErr(t, err)
+		k, _ = r.key.Random()
+		testx.AssertEqual(t, k.Exists(), false)
+	})
+}
+
+func testStrings(t *testing.T, open opener) {
+	clock := newClock()
+	r := open(t, clock.Now)
+
+	t.Run("set", func(t *testing.T) {
+		tests := []struct {
+			value any
+			want  string
+		}{
+			{"alice", "alice"},
+			{25, "25"},
+			{4.5, "4.5"},
+			{1e20, "1e+20"},
+			{true, "1"},
+			{false, "0"},
+			{[]byte("bytes"), "bytes"},
+		}
+		for _, test := range tests {
+			err := r.str.Set("key", test.value)
+			testx.AssertNoErr(t, err)
+			val, _ := r.str.Get("key")
+			testx.AssertEqual(t, val.String(), test.want)
+		}
+		err := r.str.Set("key", struct{}{})
+		testx.AssertErr(t, err, core.ErrValueType)
+		val, _ := r.str.Get("nope")
+		testx.AssertEqual(t, val, core.Value(nil))
+	})
+	t.Run("ttl", func(t *testing.T) {
+		_ = r.str.SetExpires("session", "abc", time.Minute)
+		_ = r.str.SetExpires("counter", 1, time.Minute)
+		n, err := r.str.Incr("counter", 2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 3)
+		_ = r.str.SetExpires("token", "xyz", time.Minute)
+		_ = r.str.Set("token", "uvw")
+
+		clock.Advance(2 * time.Minute)
+		val, _ := r.str.Get("session")
+		testx.AssertEqual(t, val, core.Value(nil))
+		val, _ = r.str.Get("counter")
+		testx.AssertEqual(t, val, core.Value(nil))
+		val, _ = r.str.Get("token")
+		testx.AssertEqual(t, val.String(), "uvw")
+	})
+	t.Run("conditional", func(t *testing.T) {
+		ok, err := r.str.SetNotExists("name", "alice", 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		ok, _ = r.str.SetNotExists("name", "bob", 0)
+		testx.AssertEqual(t, ok, false)
+		ok, _ = r.str.SetExists("city", "paris", 0)
+		testx.AssertEqual(t, ok, false)
+		ok, _ = r.str.SetExists("name", "bob", 0)
+		testx.AssertEqual(t, ok, true)
+
+		prev, err := r.str.GetSet("name", "carol", 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, prev.String(), "bob")
+		prev, _ = r.str.GetSet("city", "paris", 0)
+		testx.AssertEqual(t, prev, core.Value(nil))
+	})
+	t.Run("many", func(t *testing.T) {
+		err := r.str.SetMany(map[string]any{"a": 1, "b": "two"})
+		testx.AssertNoErr(t, err)
+		vals, err := r.str.GetMany("a", "b", "c")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(vals), 3)
+		testx.AssertEqual(t, vals["a"].String(), "1")
+		testx.AssertEqual(t, vals["b"].String(), "two")
+		testx.AssertEqual(t, vals["c"], core.Value(nil))
+
+		ok, err := r.str.SetManyNX(map[string]any{"b": 2, "c": 3})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+		ok, _ = r.str.SetManyNX(map[string]any{"c": 3, "d": 4})
+		testx.AssertEqual(t, ok, true)
+	})
+	t.Run("incr", func(t *testing.T) {
+		n, err := r.str.Incr("visits", 5)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 5)
+		_, err = r.str.Incr("name", 1)
+		testx.AssertErr(t, err, core.ErrValueType)
+		f, err := r.str.IncrFloat("visits", 0.5)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, f, 5.5)
+		val, _ := r.str.Get("visits")
+		testx.AssertEqual(t, val.String(), "5.5")
+	})
+	t.Run("key type", func(t *testing.T) {
+		_, _ = r.hash.Set("person", "name", "alice")
+		err := r.str.Set("person", "alice")
+		testx.AssertErr(t, err, core.ErrKeyType)
+		_, err = r.str.Incr("person", 1)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		err = r.str.SetMany(map[string]any{"person": 1})
+		testx.AssertErr(t, err, core.ErrKeyType)
+		val, _ := r.str.Get("person")
+		testx.AssertEqual(t, val, core.Value(nil))
+	})
+}
+
+func testHashes(t *testing.T, open opener) {
+	clock := newClock()
+	r := open(t, clock.Now)
+
+	t.Run("set", func(t *testing.T) {
+		created, err := r.hash.Set("person", "name", "alice")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, created, true)
+		created, _ = r.hash.Set("person", "name", "bob")
+		testx.AssertEqual(t, created, false)
+		n, err := r.hash.SetMany("person", map[string]any{"name": "alice", "age": 25})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 1)
+		ok, err := r.hash.SetNotExists("person", "age", 30)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+		ok, _ = r.hash.SetNotExists("person", "city", "paris")
+		testx.AssertEqual(t, ok, true)
+		_, err = r.hash.Set("person", "name", struct{}{})
+		testx.AssertErr(t, err, core.ErrValueType)
+	})
+	t.Run("get", func(t *testing.T) {
+		val, err := r.hash.Get("person", "age")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val.String(), "25")
+		_, err = r.hash.Get("person", "email")
+		testx.AssertErr(t, err, core.ErrNotFound)
+		_, err = r.hash.Get("nope", "name")
+		testx.AssertErr(t, err, core.ErrNotFound)
+
+		ok, _ := r.hash.Exists("person", "city")
+		testx.AssertEqual(t, ok, true)
+		vals, err := r.hash.GetMany("person", "name", "email")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(vals), 1)
+		testx.AssertEqual(t, vals["name"].String(), "alice")
+	})
+	t.Run("all", func(t *testing.T) {
+		fields, err := r.hash.Fields("person")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, fields, []string{"age", "city", "name"})
+		vals, err := r.hash.Values("person")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, valueStrings(vals), []string{"25", "paris", "alice"})
+		items, err := r.hash.Items("person")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 3)
+		testx.AssertEqual(t, items["city"].String(), "paris")
+		n, err := r.hash.Len("person")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 3)
+
+		fields, _ = r.hash.Fields("nope")
+		testx.AssertEqual(t, fields, []string{})
+		vals, _ = r.hash.Values("nope")
+		testx.AssertEqual(t, vals, []core.Value{})
+	})
+	t.Run("incr", func(t *testing.T) {
+		n, err := r.hash.Incr("person", "age", 5)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 30)
+		n, _ = r.hash.Incr("person", "visits", 1)
+		testx.AssertEqual(t, n, 1)
+		_, err = r.hash.Incr("person", "name", 1)
+		testx.AssertErr(t, err, core.ErrValueType)
+		f, err := r.hash.IncrFloat("person", "age", 0.5)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, f, 30.5)
+	})
+	t.Run("delete", func(t *testing.T) {
+		n, err := r.hash.Delete("person", "name", "email", "name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 1)
+		_, _ = r.hash.Delete("person", "age", "city", "visits")
+		n, _ = r.hash.Len("person")
+		testx.AssertEqual(t, n, 0)
+		ok, _ := r.key.Exists("person")
+		testx.AssertEqual(t, ok, true)
+	})
+	t.Run("ttl", func(t *testing.T) {
+		_, _ = r.hash.Set("cart", "apple", 3)
+		_, _ = r.key.Expire("cart", time.Minute)
+		_, _ = r.hash.Set("cart", "pear", 1)
+		clock.Advance(2 * time.Minute)
+		n, _ := r.hash.Len("cart")
+		testx.AssertEqual(t, n, 0)
+	})
+	t.Run("key type", func(t *testing.T) {
+		_ = r.str.Set("name", "alice")
+		_, err := r.hash.Set("name", "first", "alice")
+		testx.AssertErr(t, err, core.ErrKeyType)
+		_, err = r.hash.Incr("name", "count", 1)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		_, err = r.hash.Get("name", "first")
+		testx.AssertErr(t, err, core.ErrNotFound)
+		n, _ := r.hash.Len("name")
+		testx.AssertEqual(t, n, 0)
+	})
+}
+
+func testSortedSets(t *testing.T, open opener) {
+	clock := newClock()
+	r := open(t, clock.Now)
+
+	t.Run("add", func(t *testing.T) {
+		created, err := r.zset.Add("race", "alice", 11)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, created, true)
+		created, _ = r.zset.Add("race", "alice", 12)
+		testx.AssertEqual(t, created, false)
+		n, err := r.zset.AddMany("race", map[any]float64{"bob": 22, "carol": 22, "alice": 11})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 2)
+		_, err = r.zset.Add("race", struct{}{}, 1)
+		testx.AssertErr(t, err, core.ErrValueType)
+	})
+	t.Run("score", func(t *testing.T) {
+		score, err := r.zset.GetScore("race", "bob")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, score, 22.0)
+		_, err = r.zset.GetScore("race", "dave")
+		testx.AssertErr(t, err, core.ErrNotFound)
+		n, err := r.zset.Count("race", 11, 22)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 3)
+		n, _ = r.zset.Count("race", 12, 100)
+		testx.AssertEqual(t, n, 2)
+	})
+	t.Run("rank", func(t *testing.T) {
+		rank, score, err := r.zset.GetRank("race", "carol")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, rank, 2)
+		testx.AssertEqual(t, score, 22.0)
+		rank, _, err = r.zset.GetRankRev("race", "carol")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, rank, 0)
+		_, _, err = r.zset.GetRank("race", "dave")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("range", func(t *testing.T) {
+		items, err := r.zset.Range("race", 0, 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, itemStrings(items), []string{"alice=11", "bob=22"})
+		items, _ = r.zset.Range("race", 1, 10)
+		testx.AssertEqual(t, itemStrings(items), []string{"bob=22", "carol=22"})
+		items, _ = r.zset.Range("race", 5, 10)
+		testx.AssertEqual(t, len(items), 0)
+		items, _ = r.zset.Range("race", 0, -1)
+		testx.AssertEqual(t, len(items), 0)
+		items, _ = r.zset.Range("nope", 0, 1)
+		testx.AssertEqual(t, len(items), 0)
+	})
+	t.Run("incr", func(t *testing.T) {
+		score, err := r.zset.Incr("race", "alice", 20)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, score, 31.0)
+		score, _ = r.zset.Incr("race", "dave", 5)
+		testx.AssertEqual(t, score, 5.0)
+		n, err := r.zset.Len("race")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 4)
+	})
+	t.Run("delete", func(t *testing.T) {
+		n, err := r.zset.Delete("race", "dave", "eve")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 1)
+		n, _ = r.zset.Len("race")
+		testx.AssertEqual(t, n, 3)
+	})
+	t.Run("ttl", func(t *testing.T) {
+		_, _ = r.key.Expire("race", time.Minute)
+		clock.Advance(2 * time.Minute)
+		n, _ := r.zset.Len("race")
+		testx.AssertEqual(t, n, 0)
+	})
+	t.Run("key type", func(t *testing.T) {
+		_ = r.str.Set("name", "alice")
+		_, err := r.zset.Add("name", "alice", 1)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		_, err = r.zset.Incr("name", "alice", 1)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		_, err = r.zset.GetScore("name", "alice")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+}
+
+func TestNew(t *testing.T) {
+	db := fake.New(nil)
+	_ = db.Str().SetExpires("name", "alice", time.Minute)
+	k, err := db.Key().Get("name")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, *k.ETime > time.Now().UnixMilli(), true)
+}
+
+func keyNames(keys []core.Key) []string {
+	names := make([]string, len(keys))
+	for i, k := range keys {
+		names[i] = k.Key
+	}
+	return names
+}
+
+func valueStrings(vals []core.Value) []string {
+	strs := make([]string, len(vals))
+	for i, v := range vals {
+		strs[i] = v.String()
+	}
+	return strs
+}
+
+func itemStrings(items []rzset.SetItem) []string {
+	strs := make([]string, len(items))
+	for i, it := range items {
+		strs[i] = it.Elem.String() + "=" + strconv.FormatFloat(it.Score, 'f', -1, 64)
+	}
+	return strs
+}
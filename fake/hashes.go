@@ -0,0 +1,251 @@
+package fake
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+)
+
+var _ redka.Hashes = (*Hashes)(nil)
+
+// Hashes is a fake hash repository.
+type Hashes struct {
+	db *DB
+}
+
+// Delete deletes one or more items from a hash.
+// Returns the number of fields deleted.
+// Does not delete the key if the hash becomes empty.
+func (r *Hashes) Delete(key string, fields ...string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.getType(key, core.TypeHash)
+	if e == nil {
+		return 0, nil
+	}
+	count := 0
+	for _, name := range fields {
+		if _, ok := e.hash[name]; ok {
+			delete(e.hash, name)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Exists checks if a field exists in a hash.
+// If the key does not exist or is not a hash, returns false.
+func (r *Hashes) Exists(key, field string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.getType(key, core.TypeHash)
+	if e == nil {
+		return false, nil
+	}
+	_, ok := e.hash[field]
+	return ok, nil
+}
+
+// Fields returns all fields in a hash.
+// If the key does not exist or is not a hash, returns an empty slice.
+func (r *Hashes) Fields(key string) ([]string, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return append([]string{}, r.names(key)...), nil
+}
+
+// Get returns the value of a field in a hash.
+// If the field or the key does not exist, returns ErrNotFound.
+func (r *Hashes) Get(key, field string) (core.Value, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return r.get(key, field)
+}
+
+// GetMany returns a map of values for given fields.
+// Ignores fields that do not exist and do not return them in the map.
+// If the key does not exist or is not a hash, returns an empty map.
+func (r *Hashes) GetMany(key string, fields ...string) (map[string]core.Value, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	items := map[string]core.Value{}
+	for _, name := range fields {
+		if val, err := r.get(key, name); err == nil {
+			items[name] = val
+		}
+	}
+	return items, nil
+}
+
+// Incr increments the integer value of a field in a hash.
+// If the field does not exist, sets it to 0 before the increment.
+// If the field value is not an integer, returns ErrValueType.
+// If the key exists but is not a hash, returns ErrKeyType.
+func (r *Hashes) Incr(key, field string, delta int) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	val, _ := r.get(key, field)
+	valInt, err := val.Int()
+	if err != nil {
+		return 0, core.ErrValueType
+	}
+	newVal := valInt + delta
+	if err := r.set(key, field, newVal); err != nil {
+		return 0, err
+	}
+	return newVal, nil
+}
+
+// IncrFloat increments the float value of a field in a hash.
+// If the field does not exist, sets it to 0 before the increment.
+// If the field value is not a float, returns ErrValueType.
+// If the key exists but is not a hash, returns ErrKeyType.
+func (r *Hashes) IncrFloat(key, field string, delta float64) (float64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	val, _ := r.get(key, field)
+	valFloat, err := val.Float()
+	if err != nil {
+		return 0, core.ErrValueType
+	}
+	newVal := valFloat + delta
+	if err := r.set(key, field, newVal); err != nil {
+		return 0, err
+	}
+	return newVal, nil
+}
+
+// Items returns a map of all fields and values in a hash.
+// If the key does not exist or is not a hash, returns an empty map.
+func (r *Hashes) Items(key string) (map[string]core.Value, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	items := map[string]core.Value{}
+	if e := r.db.getType(key, core.TypeHash); e != nil {
+		for name, val := range e.hash {
+			items[name] = bytes.Clone(val)
+		}
+	}
+	return items, nil
+}
+
+// Len returns the number of fields in a hash.
+// If the key does not exist or is not a hash, returns 0.
+func (r *Hashes) Len(key string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.getType(key, core.TypeHash)
+	if e == nil {
+		return 0, nil
+	}
+	return len(e.hash), nil
+}
+
+// Set creates or updates the value of a field in a hash.
+// Returns true if the field was created, false if it was updated.
+// If the key exists but is not a hash, returns ErrKeyType.
+func (r *Hashes) Set(key, field string, value any) (bool, error) {
+	if !core.IsValueType(value) {
+		return false, core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	_, err := r.get(key, field)
+	created := err != nil
+	if err := r.set(key, field, value); err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// SetMany creates or updates the values of multiple fields in a hash.
+// Returns the number of fields created (as opposed to updated).
+// If the key exists but is not a hash, returns ErrKeyType.
+func (r *Hashes) SetMany(key string, items map[string]any) (int, error) {
+	for _, val := range items {
+		if !core.IsValueType(val) {
+			return 0, core.ErrValueType
+		}
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	created := 0
+	for name, val := range items {
+		if _, err := r.get(key, name); err != nil {
+			created++
+		}
+		if err := r.set(key, name, val); err != nil {
+			return 0, err
+		}
+	}
+	return created, nil
+}
+
+// SetNotExists creates the value of a field in a hash if it does not exist.
+// Returns true if the field was created, false if it already exists.
+// If the key exists but is not a hash, returns ErrKeyType.
+func (r *Hashes) SetNotExists(key, field string, value any) (bool, error) {
+	if !core.IsValueType(value) {
+		return false, core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	if _, err := r.get(key, field); err == nil {
+		return false, nil
+	}
+	if err := r.set(key, field, value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Values returns all values in a hash.
+// If the key does not exist or is not a hash, returns an empty slice.
+func (r *Hashes) Values(key string) ([]core.Value, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	vals := []core.Value{}
+	if e := r.db.getType(key, core.TypeHash); e != nil {
+		for _, name := range r.names(key) {
+			vals = append(vals, bytes.Clone(e.hash[name]))
+		}
+	}
+	return vals, nil
+}
+
+// get returns the value of a field in a hash.
+// If the field or the key does not exist, returns ErrNotFound.
+func (r *Hashes) get(key, field string) (core.Value, error) {
+	e := r.db.getType(key, core.TypeHash)
+	if e == nil {
+		return nil, core.ErrNotFound
+	}
+	val, ok := e.hash[field]
+	if !ok {
+		return nil, core.ErrNotFound
+	}
+	return bytes.Clone(val), nil
+}
+
+// set creates or updates the value of a field in a hash.
+func (r *Hashes) set(key, field string, value any) error {
+	e, err := r.db.write(key, core.TypeHash)
+	if err != nil {
+		return err
+	}
+	e.hash[field] = toValue(value)
+	return nil
+}
+
+// names returns the fields of a hash ordered by name
+// (same as the database does).
+func (r *Hashes) names(key string) []string {
+	e := r.db.getType(key, core.TypeHash)
+	if e == nil {
+		return nil
+	}
+	return slices.Sorted(maps.Keys(e.hash))
+}
@@ -0,0 +1,235 @@
+package fake
+
+import (
+	"bytes"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+)
+
+var _ redka.Keys = (*Keys)(nil)
+
+// Keys is a fake key repository.
+type Keys struct {
+	db *DB
+}
+
+// Exists reports whether the key exists.
+func (r *Keys) Exists(key string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return r.db.get(key) != nil, nil
+}
+
+// Count returns the number of existing keys among specified.
+func (r *Keys) Count(keys ...string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	count := 0
+	for i, key := range keys {
+		if slices.Contains(keys[:i], key) {
+			continue
+		}
+		if r.db.get(key) != nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Keys returns all keys matching pattern.
+// Supports the same glob-style patterns as the database.
+func (r *Keys) Keys(pattern string) ([]core.Key, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	var keys []core.Key
+	for _, e := range r.db.live() {
+		if match(pattern, e.key.Key) {
+			keys = append(keys, e.info())
+		}
+	}
+	return keys, nil
+}
+
+// Random returns a random key.
+// Returns an empty key if there are no keys.
+func (r *Keys) Random() (core.Key, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	entries := r.db.live()
+	if len(entries) == 0 {
+		return core.Key{}, nil
+	}
+	return entries[rand.IntN(len(entries))].info(), nil
+}
+
+// Get returns a specific key with all associated details.
+// Returns an empty key if the key does not exist.
+func (r *Keys) Get(key string) (core.Key, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.get(key)
+	if e == nil {
+		return core.Key{}, nil
+	}
+	return e.info(), nil
+}
+
+// Expire sets a time-to-live (ttl) for the key using a relative duration.
+// Returns false is the key does not exist.
+func (r *Keys) Expire(key string, ttl time.Duration) (bool, error) {
+	return r.ExpireAt(key, r.db.clock().Add(ttl))
+}
+
+// ExpireAt sets an expiration time for the key.
+// Returns false is the key does not exist.
+func (r *Keys) ExpireAt(key string, at time.Time) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.get(key)
+	if e == nil {
+		return false, nil
+	}
+	etime := at.UnixMilli()
+	e.key.ETime = &etime
+	return true, nil
+}
+
+// Persist removes the expiration time for the key.
+// Returns false is the key does not exist.
+func (r *Keys) Persist(key string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.get(key)
+	if e == nil {
+		return false, nil
+	}
+	e.key.ETime = nil
+	return true, nil
+}
+
+// Rename changes the key name.
+// If there is an existing key with the new name, it is replaced.
+// Returns ErrNotFound if the key does not exist.
+func (r *Keys) Rename(key, newKey string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.get(key)
+	if e == nil {
+		return core.ErrNotFound
+	}
+	if key == newKey {
+		return nil
+	}
+	r.db.rename(e, newKey)
+	return nil
+}
+
+// RenameNotExists changes the key name.
+// If there is an existing key with the new name, does nothing.
+// Returns true if the key was renamed, false otherwise.
+// Returns ErrNotFound if the key does not exist.
+func (r *Keys) RenameNotExists(key, newKey string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.get(key)
+	if e == nil {
+		return false, core.ErrNotFound
+	}
+	if key == newKey || r.db.get(newKey) != nil {
+		return false, nil
+	}
+	r.db.rename(e, newKey)
+	return true, nil
+}
+
+// Copy copies the key with its value to the new key, regardless
+// of the type. The copy has the same type, version and expiration
+// time as the source key. If there is an existing key with the new
+// name, replaces it if replace is true, or does nothing otherwise.
+// Returns true if the key was copied, false otherwise.
+// Returns ErrNotFound if the source key does not exist.
+func (r *Keys) Copy(key, newKey string, replace bool) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.get(key)
+	if e == nil {
+		return false, core.ErrNotFound
+	}
+	if key == newKey || !replace && r.db.get(newKey) != nil {
+		return false, nil
+	}
+	r.db.copy(e, newKey)
+	return true, nil
+}
+
+// Delete deletes keys and their values, regardless of the type.
+// Returns the number of deleted keys. Non-existing keys are ignored.
+func (r *Keys) Delete(keys ...string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	count := 0
+	for _, key := range keys {
+		if r.db.get(key) != nil {
+			delete(r.db.keys, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteAll deletes all keys and their values.
+func (r *Keys) DeleteAll() error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	clear(r.db.keys)
+	return nil
+}
+
+// live returns the existing keys ordered by ID.
+func (db *DB) live() []*entry {
+	entries := make([]*entry, 0, len(db.keys))
+	for key := range db.keys {
+		if e := db.get(key); e != nil {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortFunc(entries, func(a, b *entry) int {
+		return a.key.ID - b.key.ID
+	})
+	return entries
+}
+
+// copy copies the key entry with its value to the new
+// key, replacing the existing key with the new name.
+func (db *DB) copy(e *entry, newKey string) {
+	db.lastID++
+	c := &entry{key: e.info(), str: bytes.Clone(e.str)}
+	c.key.ID = db.lastID
+	c.key.Key = newKey
+	c.key.MTime = db.now()
+	if e.hash != nil {
+		c.hash = make(map[string]core.Value, len(e.hash))
+		for field, val := range e.hash {
+			c.hash[field] = bytes.Clone(val)
+		}
+	}
+	if e.zset != nil {
+		c.zset = maps.Clone(e.zset)
+	}
+	db.keys[newKey] = c
+}
+
+// rename changes the key name, replacing
+// the existing key with the new name.
+func (db *DB) rename(e *entry, newKey string) {
+	delete(db.keys, e.key.Key)
+	e.key.Key = newKey
+	e.key.Version++
+	e.key.MTime = db.now()
+	db.keys[newKey] = e
+}
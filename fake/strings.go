@@ -0,0 +1,216 @@
+package fake
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+)
+
+var _ redka.Strings = (*Strings)(nil)
+
+// Strings is a fake string repository.
+type Strings struct {
+	db *DB
+}
+
+// Get returns the value of the key.
+// Returns nil if the key does not exist.
+func (r *Strings) Get(key string) (core.Value, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return r.get(key), nil
+}
+
+// GetMany returns a map of values for given keys.
+// Returns nil for keys that do not exist.
+func (r *Strings) GetMany(keys ...string) (map[string]core.Value, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	items := make(map[string]core.Value, len(keys))
+	for _, key := range keys {
+		items[key] = r.get(key)
+	}
+	return items, nil
+}
+
+// Set sets the key value that will not expire.
+// Overwrites the value if the key already exists.
+func (r *Strings) Set(key string, value any) error {
+	return r.SetExpires(key, value, 0)
+}
+
+// SetExpires sets the key value with an optional expiration time (if ttl > 0).
+// Overwrites the value and ttl if the key already exists.
+func (r *Strings) SetExpires(key string, value any, ttl time.Duration) error {
+	if !core.IsValueType(value) {
+		return core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	return r.set(key, value, ttl)
+}
+
+// SetNotExists sets the key value if the key does not exist.
+// Optionally sets the expiration time (if ttl > 0).
+// Returns true if the key was set, false if the key already exists.
+func (r *Strings) SetNotExists(key string, value any, ttl time.Duration) (bool, error) {
+	if !core.IsValueType(value) {
+		return false, core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	if r.db.get(key) != nil {
+		return false, nil
+	}
+	err := r.set(key, value, ttl)
+	return err == nil, err
+}
+
+// SetExists sets the key value if the key exists.
+// Optionally sets the expiration time (if ttl > 0).
+// Returns true if the key was set, false if the key does not exist.
+func (r *Strings) SetExists(key string, value any, ttl time.Duration) (bool, error) {
+	if !core.IsValueType(value) {
+		return false, core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	if r.db.get(key) == nil {
+		return false, nil
+	}
+	err := r.set(key, value, ttl)
+	return err == nil, err
+}
+
+// GetSet returns the previous value of a key after setting it to a new value.
+// Optionally sets the expiration time (if ttl > 0).
+// Returns nil if the key did not exist.
+func (r *Strings) GetSet(key string, value any, ttl time.Duration) (core.Value, error) {
+	if !core.IsValueType(value) {
+		return nil, core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	prev := r.get(key)
+	err := r.set(key, value, ttl)
+	return prev, err
+}
+
+// SetMany sets the values of multiple keys.
+// Removes the TTL for existing keys.
+// Does not change any keys if any of them is not a string.
+func (r *Strings) SetMany(items map[string]any) error {
+	for _, val := range items {
+		if !core.IsValueType(val) {
+			return core.ErrValueType
+		}
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for key := range items {
+		if err := r.db.checkType(key, core.TypeString); err != nil {
+			return err
+		}
+	}
+	for key, val := range items {
+		if err := r.set(key, val, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetManyNX sets the values of multiple keys, but only if none
+// of them yet exist. Returns true if the keys were set, false if any
+// of them already exist.
+func (r *Strings) SetManyNX(items map[string]any) (bool, error) {
+	for _, val := range items {
+		if !core.IsValueType(val) {
+			return false, core.ErrValueType
+		}
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	for key := range items {
+		if r.db.get(key) != nil {
+			return false, nil
+		}
+	}
+	for key, val := range items {
+		if err := r.set(key, val, 0); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Incr increments the key value by the specified amount.
+// If the key does not exist, sets it to 0 before the increment.
+// Returns an error if the key value is not an integer.
+func (r *Strings) Incr(key string, delta int) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	valInt, err := r.get(key).Int()
+	if err != nil {
+		return 0, core.ErrValueType
+	}
+	newVal := valInt + delta
+	if err := r.update(key, newVal); err != nil {
+		return 0, err
+	}
+	return newVal, nil
+}
+
+// IncrFloat increments the key value by the specified amount.
+// If the key does not exist, sets it to 0 before the increment.
+// Returns an error if the key value is not a float.
+func (r *Strings) IncrFloat(key string, delta float64) (float64, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	valFloat, err := r.get(key).Float()
+	if err != nil {
+		return 0, core.ErrValueType
+	}
+	newVal := valFloat + delta
+	if err := r.update(key, newVal); err != nil {
+		return 0, err
+	}
+	return newVal, nil
+}
+
+// get returns the key value, or nil if the key
+// does not exist or is not a string.
+func (r *Strings) get(key string) core.Value {
+	e := r.db.getType(key, core.TypeString)
+	if e == nil {
+		return nil
+	}
+	return bytes.Clone(e.str)
+}
+
+// set sets the key value and (optionally) its expiration time.
+func (r *Strings) set(key string, value any, ttl time.Duration) error {
+	e, err := r.db.write(key, core.TypeString)
+	if err != nil {
+		return err
+	}
+	e.str = toValue(value)
+	e.key.ETime = nil
+	if ttl > 0 {
+		etime := r.db.clock().Add(ttl).UnixMilli()
+		e.key.ETime = &etime
+	}
+	return nil
+}
+
+// update sets the key value without changing its expiration time.
+func (r *Strings) update(key string, value any) error {
+	e, err := r.db.write(key, core.TypeString)
+	if err != nil {
+		return err
+	}
+	e.str = toValue(value)
+	return nil
+}
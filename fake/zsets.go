@@ -0,0 +1,245 @@
+package fake
+
+import (
+	"cmp"
+	"slices"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rzset"
+)
+
+var _ redka.SortedSets = (*SortedSets)(nil)
+
+// SortedSets is a fake sorted set repository.
+// The elements are compared by their byte representation,
+// so 1 and "1" are the same element.
+type SortedSets struct {
+	db *DB
+}
+
+// Add adds or updates an element in a set.
+// Returns true if the element was created, false if it was updated.
+// If the key exists but is not a set, returns ErrKeyType.
+func (r *SortedSets) Add(key string, elem any, score float64) (bool, error) {
+	if !core.IsValueType(elem) {
+		return false, core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	created := !r.exists(key, elem)
+	if err := r.add(key, elem, score); err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// AddMany adds or updates multiple elements in a set.
+// Returns the number of elements created (as opposed to updated).
+// If the key exists but is not a set, returns ErrKeyType.
+func (r *SortedSets) AddMany(key string, items map[any]float64) (int, error) {
+	for elem := range items {
+		if !core.IsValueType(elem) {
+			return 0, core.ErrValueType
+		}
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	created := 0
+	for elem, score := range items {
+		if !r.exists(key, elem) {
+			created++
+		}
+		if err := r.add(key, elem, score); err != nil {
+			return 0, err
+		}
+	}
+	return created, nil
+}
+
+// Count returns the number of elements in a set with a score between
+// min and max (inclusive).
+func (r *SortedSets) Count(key string, min, max float64) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.getType(key, core.TypeSortedSet)
+	if e == nil {
+		return 0, nil
+	}
+	count := 0
+	for _, score := range e.zset {
+		if score >= min && score <= max {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Delete removes elements from a set.
+// Returns the number of elements removed.
+// Does not delete the key if the set becomes empty.
+func (r *SortedSets) Delete(key string, elems ...any) (int, error) {
+	for _, elem := range elems {
+		if !core.IsValueType(elem) {
+			return 0, core.ErrValueType
+		}
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.getType(key, core.TypeSortedSet)
+	if e == nil {
+		return 0, nil
+	}
+	count := 0
+	for _, elem := range elems {
+		name := string(toValue(elem))
+		if _, ok := e.zset[name]; ok {
+			delete(e.zset, name)
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetRank returns the rank and score of an element in a set,
+// ordered by score (from low to high), and then by element
+// (ascending). If the element or the key does not exist,
+// returns ErrNotFound.
+func (r *SortedSets) GetRank(key string, elem any) (rank int, score float64, err error) {
+	return r.getRank(key, elem, false)
+}
+
+// GetRankRev returns the rank and score of an element in a set,
+// ordered by score (from high to low), and then by element
+// (descending). If the element or the key does not exist,
+// returns ErrNotFound.
+func (r *SortedSets) GetRankRev(key string, elem any) (rank int, score float64, err error) {
+	return r.getRank(key, elem, true)
+}
+
+// GetScore returns the score of an element in a set.
+// If the element or the key does not exist, returns ErrNotFound.
+func (r *SortedSets) GetScore(key string, elem any) (float64, error) {
+	if !core.IsValueType(elem) {
+		return 0, core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.getType(key, core.TypeSortedSet)
+	if e == nil {
+		return 0, core.ErrNotFound
+	}
+	score, ok := e.zset[string(toValue(elem))]
+	if !ok {
+		return 0, core.ErrNotFound
+	}
+	return score, nil
+}
+
+// Incr increments the score of an element in a set.
+// Returns the score after the increment.
+// If the element does not exist, adds it and sets the score to 0.0
+// before the increment. If the key exists but is not a set,
+// returns ErrKeyType.
+func (r *SortedSets) Incr(key string, elem any, delta float64) (float64, error) {
+	if !core.IsValueType(elem) {
+		return 0, core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e, err := r.db.write(key, core.TypeSortedSet)
+	if err != nil {
+		return 0, err
+	}
+	name := string(toValue(elem))
+	e.zset[name] += delta
+	return e.zset[name], nil
+}
+
+// Len returns the number of elements in a set.
+// Returns 0 if the key does not exist or is not a set.
+func (r *SortedSets) Len(key string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	e := r.db.getType(key, core.TypeSortedSet)
+	if e == nil {
+		return 0, nil
+	}
+	return len(e.zset), nil
+}
+
+// Range returns a range of elements from a set with ranks between start and stop.
+// The rank is the 0-based position of the element in the set, ordered
+// by score (from low to high), and then by element (ascending).
+// Start and stop are 0-based, inclusive. Negative values are not supported.
+// If the key does not exist or is not a set, returns a nil slice.
+func (r *SortedSets) Range(key string, start, stop int) ([]rzset.SetItem, error) {
+	if start < 0 || stop < 0 {
+		return nil, nil
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	items := r.sorted(key, false)
+	if start >= len(items) || start > stop {
+		return nil, nil
+	}
+	stop = min(stop, len(items)-1)
+	return items[start : stop+1], nil
+}
+
+// exists reports whether the element exists in a set.
+func (r *SortedSets) exists(key string, elem any) bool {
+	e := r.db.getType(key, core.TypeSortedSet)
+	if e == nil {
+		return false
+	}
+	_, ok := e.zset[string(toValue(elem))]
+	return ok
+}
+
+// add adds or updates the element in a set.
+func (r *SortedSets) add(key string, elem any, score float64) error {
+	e, err := r.db.write(key, core.TypeSortedSet)
+	if err != nil {
+		return err
+	}
+	e.zset[string(toValue(elem))] = score
+	return nil
+}
+
+// getRank returns the rank and score of an element in a set.
+func (r *SortedSets) getRank(key string, elem any, rev bool) (int, float64, error) {
+	if !core.IsValueType(elem) {
+		return 0, 0, core.ErrValueType
+	}
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	name := string(toValue(elem))
+	for rank, it := range r.sorted(key, rev) {
+		if string(it.Elem) == name {
+			return rank, it.Score, nil
+		}
+	}
+	return 0, 0, core.ErrNotFound
+}
+
+// sorted returns the set elements ordered by score,
+// and then by element (descending if rev is true).
+func (r *SortedSets) sorted(key string, rev bool) []rzset.SetItem {
+	e := r.db.getType(key, core.TypeSortedSet)
+	if e == nil {
+		return nil
+	}
+	items := make([]rzset.SetItem, 0, len(e.zset))
+	for name, score := range e.zset {
+		items = append(items, rzset.SetItem{Elem: core.Value(name), Score: score})
+	}
+	slices.SortFunc(items, func(a, b rzset.SetItem) int {
+		c := cmp.Or(cmp.Compare(a.Score, b.Score), cmp.Compare(string(a.Elem), string(b.Elem)))
+		if rev {
+			return -c
+		}
+		return c
+	})
+	return items
+}
@@ -0,0 +1,73 @@
+package redka
+
+import (
+	"errors"
+	"math"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/rzset"
+)
+
+// KeyValue is a key of any type with its value (see [DB.GetMany]).
+// Only the field for the key type is set: Str for strings,
+// Hash for hashes, ZSet for sorted sets and Stream for streams.
+// Lists and sets are not supported by Redka, so there are
+// no fields for them.
+type KeyValue struct {
+	Key    Key              // key (Key.Exists is false if it does not exist)
+	Str    Value            // string value
+	Hash   map[string]Value // hash fields and values
+	ZSet   []rzset.SetItem  // sorted set elements ordered by score
+	Stream []rstream.Entry  // stream entries ordered by ID
+}
+
+// GetMany returns the keys of any type with their values, in the
+// same order as the keys. The keys that do not exist (or have expired)
+// are returned with the zero Key. Reads all keys in a single read-only
+// transaction, so the values are consistent with each other.
+//
+// Useful for the generic views (like admin or debug pages) that
+// would otherwise check the type of each key and then call
+// a type-specific method.
+func (db *DB) GetMany(keys ...string) ([]KeyValue, error) {
+	vals := make([]KeyValue, len(keys))
+	err := db.View(func(tx *Tx) error {
+		for i, key := range keys {
+			val, err := getKeyValue(tx, key)
+			if err != nil {
+				return err
+			}
+			vals[i] = val
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return vals, nil
+}
+
+// getKeyValue returns the key with its value.
+func getKeyValue(tx *Tx, key string) (KeyValue, error) {
+	k, err := tx.Key().Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return KeyValue{}, nil
+	}
+	if err != nil {
+		return KeyValue{}, err
+	}
+	kv := KeyValue{Key: k}
+	switch k.Type {
+	case core.TypeString:
+		kv.Str, err = tx.Str().Get(key)
+	case core.TypeHash:
+		kv.Hash, err = tx.Hash().Items(key)
+	case core.TypeSortedSet:
+		kv.ZSet, err = tx.SortedSet().RangeWith(key).
+			ByScore(math.Inf(-1), math.Inf(1)).Run()
+	case core.TypeStream:
+		kv.Stream, err = tx.Stream().Range(key, rstream.MinID, rstream.MaxID, 0)
+	}
+	return kv, err
+}
@@ -0,0 +1,40 @@
+package redka_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestGetMany(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	_, _ = db.Hash().Set("person", "age", 25)
+	_, _ = db.SortedSet().Add("race", "bob", 12)
+	_, _ = db.SortedSet().Add("race", "alice", 11)
+	_ = db.Str().SetExpires("temp", "x", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	vals, err := db.GetMany("name", "person", "race", "temp", "nope")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(vals), 5)
+
+	testx.AssertEqual(t, vals[0].Key.Key, "name")
+	testx.AssertEqual(t, vals[0].Key.TypeName(), "string")
+	testx.AssertEqual(t, vals[0].Str.String(), "alice")
+
+	testx.AssertEqual(t, vals[1].Key.TypeName(), "hash")
+	testx.AssertEqual(t, vals[1].Hash, map[string]redka.Value{"age": redka.Value("25")})
+
+	testx.AssertEqual(t, vals[2].Key.TypeName(), "zset")
+	testx.AssertEqual(t, len(vals[2].ZSet), 2)
+	testx.AssertEqual(t, vals[2].ZSet[0].Elem.String(), "alice")
+	testx.AssertEqual(t, vals[2].ZSet[1].Score, 12.0)
+
+	testx.AssertEqual(t, vals[3].Key.Exists(), false)
+	testx.AssertEqual(t, vals[4].Key.Exists(), false)
+}
@@ -0,0 +1,192 @@
+// Package goredis provides an in-process client with the API
+// of the go-redis library (github.com/redis/go-redis/v9), backed
+// directly by the Redka repositories, without the network.
+// Use it to switch the code written against go-redis to an embedded
+// Redka database with minimal changes:
+//
+//	db, err := redka.Open("data.db", nil)
+//	// ...
+//	rdb := goredis.NewClient(db)
+//	err = rdb.Set(ctx, "name", "alice", 0).Err()
+//	name, err := rdb.Get(ctx, "name").Result()
+//
+// The client implements the most-used subset of the go-redis
+// Cmdable interface (see [Cmdable]). The package does not depend
+// on go-redis, so it has its own result types (like [StringCmd]),
+// with the same methods as their go-redis counterparts.
+package goredis
+
+import (
+	"context"
+	"encoding"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nalgeon/redka"
+)
+
+// Nil is the error returned when the key (or the hash field,
+// or the sorted set member) does not exist. Same as redis.Nil.
+var Nil = errors.New("redis: nil")
+
+// Cmdable is the subset of the go-redis Cmdable
+// interface implemented by [Client].
+type Cmdable interface {
+	Ping(ctx context.Context) *StatusCmd
+	FlushDB(ctx context.Context) *StatusCmd
+
+	// Keys.
+	Del(ctx context.Context, keys ...string) *IntCmd
+	Exists(ctx context.Context, keys ...string) *IntCmd
+	Expire(ctx context.Context, key string, expiration time.Duration) *BoolCmd
+	ExpireAt(ctx context.Context, key string, tm time.Time) *BoolCmd
+	Keys(ctx context.Context, pattern string) *StringSliceCmd
+	Persist(ctx context.Context, key string) *BoolCmd
+	Rename(ctx context.Context, key, newkey string) *StatusCmd
+	TTL(ctx context.Context, key string) *DurationCmd
+	Type(ctx context.Context, key string) *StatusCmd
+
+	// Strings.
+	Get(ctx context.Context, key string) *StringCmd
+	GetSet(ctx context.Context, key string, value any) *StringCmd
+	Set(ctx context.Context, key string, value any, expiration time.Duration) *StatusCmd
+	SetNX(ctx context.Context, key string, value any, expiration time.Duration) *BoolCmd
+	SetXX(ctx context.Context, key string, value any, expiration time.Duration) *BoolCmd
+	MGet(ctx context.Context, keys ...string) *SliceCmd
+	MSet(ctx context.Context, values ...any) *StatusCmd
+	Incr(ctx context.Context, key string) *IntCmd
+	IncrBy(ctx context.Context, key string, value int64) *IntCmd
+	IncrByFloat(ctx context.Context, key string, value float64) *FloatCmd
+	Decr(ctx context.Context, key string) *IntCmd
+	DecrBy(ctx context.Context, key string, decrement int64) *IntCmd
+
+	// Hashes.
+	HDel(ctx context.Context, key string, fields ...string) *IntCmd
+	HExists(ctx context.Context, key, field string) *BoolCmd
+	HGet(ctx context.Context, key, field string) *StringCmd
+	HGetAll(ctx context.Context, key string) *MapStringStringCmd
+	HIncrBy(ctx context.Context, key, field string, incr int64) *IntCmd
+	HKeys(ctx context.Context, key string) *StringSliceCmd
+	HLen(ctx context.Context, key string) *IntCmd
+	HMGet(ctx context.Context, key string, fields ...string) *SliceCmd
+	HSet(ctx context.Context, key string, values ...any) *IntCmd
+
+	// Sorted sets.
+	ZAdd(ctx context.Context, key string, members ...Z) *IntCmd
+	ZCard(ctx context.Context, key string) *IntCmd
+	ZIncrBy(ctx context.Context, key string, increment float64, member string) *FloatCmd
+	ZRange(ctx context.Context, key string, start, stop int64) *StringSliceCmd
+	ZRangeWithScores(ctx context.Context, key string, start, stop int64) *ZSliceCmd
+	ZRem(ctx context.Context, key string, members ...any) *IntCmd
+	ZScore(ctx context.Context, key, member string) *FloatCmd
+}
+
+// Client is an in-process client backed by a Redka database.
+// Safe for concurrent use.
+type Client struct {
+	db *redka.DB
+}
+
+var _ Cmdable = (*Client)(nil)
+
+// NewClient creates a new client for the database.
+func NewClient(db *redka.DB) *Client {
+	return &Client{db: db}
+}
+
+// Close does nothing and exists for compatibility with go-redis.
+// The database is owned by the caller, so the client does not close it.
+func (c *Client) Close() error {
+	return nil
+}
+
+// Ping checks that the database is available.
+func (c *Client) Ping(ctx context.Context) *StatusCmd {
+	if err := c.db.SQL.PingContext(ctx); err != nil {
+		return newStatusCmd(err)
+	}
+	return &StatusCmd{val: "PONG"}
+}
+
+// FlushDB deletes all keys.
+func (c *Client) FlushDB(ctx context.Context) *StatusCmd {
+	err := c.with(ctx).Key().DeleteAll()
+	return newStatusCmd(err)
+}
+
+// with returns the database that executes the commands with ctx.
+func (c *Client) with(ctx context.Context) *redka.DB {
+	return c.db.WithContext(ctx)
+}
+
+// toValue converts a value to a string the way go-redis does.
+func toValue(v any) (any, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return v, nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 64), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case time.Duration:
+		return strconv.FormatInt(v.Nanoseconds(), 10), nil
+	case encoding.BinaryMarshaler:
+		return v.MarshalBinary()
+	default:
+		return nil, fmt.Errorf(
+			"redis: can't marshal %T (implement encoding.BinaryMarshaler)", v)
+	}
+}
+
+// toString converts a value to a string (see toValue).
+func toString(v any) (string, error) {
+	val, err := toValue(v)
+	if err != nil {
+		return "", err
+	}
+	if b, ok := val.([]byte); ok {
+		return string(b), nil
+	}
+	return val.(string), nil
+}
+
+// notFound returns Nil if the error is redka.ErrNotFound.
+func notFound(err error) error {
+	if errors.Is(err, redka.ErrNotFound) {
+		return Nil
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package goredis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/goredis"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func getClient(tb testing.TB) (*redka.DB, *goredis.Client) {
+	tb.Helper()
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db, goredis.NewClient(db)
+}
+
+func TestStrings(t *testing.T) {
+	ctx := context.Background()
+	db, rdb := getClient(t)
+	defer db.Close()
+
+	t.Run("set and get", func(t *testing.T) {
+		status, err := rdb.Set(ctx, "name", "alice", 0).Result()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, status, "OK")
+		name, err := rdb.Get(ctx, "name").Result()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name, "alice")
+	})
+	t.Run("get missing", func(t *testing.T) {
+		_, err := rdb.Get(ctx, "city").Result()
+		testx.AssertErr(t, err, goredis.Nil)
+	})
+	t.Run("value types", func(t *testing.T) {
+		_ = rdb.Set(ctx, "age", 25, 0)
+		age, err := rdb.Get(ctx, "age").Int()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, age, 25)
+		_ = rdb.Set(ctx, "ok", true, 0)
+		testx.AssertEqual(t, rdb.Get(ctx, "ok").Val(), "1")
+		err = rdb.Set(ctx, "bad", struct{}{}, 0).Err()
+		testx.AssertEqual(t, err != nil, true)
+	})
+	t.Run("nx and xx", func(t *testing.T) {
+		testx.AssertEqual(t, rdb.SetNX(ctx, "name", "bob", 0).Val(), false)
+		testx.AssertEqual(t, rdb.SetXX(ctx, "name", "bob", 0).Val(), true)
+		testx.AssertEqual(t, rdb.SetXX(ctx, "city", "paris", 0).Val(), false)
+	})
+	t.Run("mget and mset", func(t *testing.T) {
+		err := rdb.MSet(ctx, "k1", "v1", "k2", 2).Err()
+		testx.AssertNoErr(t, err)
+		vals := rdb.MGet(ctx, "k1", "k2", "k3").Val()
+		testx.AssertEqual(t, vals, []any{"v1", "2", nil})
+	})
+	t.Run("incr", func(t *testing.T) {
+		testx.AssertEqual(t, rdb.Incr(ctx, "count").Val(), int64(1))
+		testx.AssertEqual(t, rdb.IncrBy(ctx, "count", 10).Val(), int64(11))
+		testx.AssertEqual(t, rdb.DecrBy(ctx, "count", 5).Val(), int64(6))
+		testx.AssertEqual(t, rdb.Decr(ctx, "count").Val(), int64(5))
+		testx.AssertEqual(t, rdb.IncrByFloat(ctx, "count", 0.5).Val(), 5.5)
+		err := rdb.Incr(ctx, "name").Err()
+		testx.AssertEqual(t, err.Error(), "ERR value is not an integer or out of range")
+	})
+	t.Run("getset", func(t *testing.T) {
+		prev, err := rdb.GetSet(ctx, "name", "cindy").Result()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, prev, "bob")
+	})
+}
+
+func TestKeys(t *testing.T) {
+	ctx := context.Background()
+	db, rdb := getClient(t)
+	defer db.Close()
+
+	_ = rdb.Set(ctx, "name", "alice", 0)
+	_ = rdb.Set(ctx, "age", 25, time.Minute)
+	_ = rdb.HSet(ctx, "person", "name", "alice")
+
+	testx.AssertEqual(t, rdb.Exists(ctx, "name", "age", "city").Val(), int64(2))
+	testx.AssertEqual(t, rdb.TTL(ctx, "age").Val(), time.Minute)
+	testx.AssertEqual(t, rdb.TTL(ctx, "name").Val(), time.Duration(-1))
+	testx.AssertEqual(t, rdb.TTL(ctx, "city").Val(), time.Duration(-2))
+	testx.AssertEqual(t, rdb.Type(ctx, "person").Val(), "hash")
+	testx.AssertEqual(t, rdb.Type(ctx, "city").Val(), "none")
+	testx.AssertEqual(t, rdb.Expire(ctx, "name", time.Hour).Val(), true)
+	testx.AssertEqual(t, rdb.Persist(ctx, "name").Val(), true)
+	testx.AssertEqual(t, rdb.Keys(ctx, "*a*").Val(), []string{"name", "age"})
+
+	err := rdb.Rename(ctx, "name", "title").Err()
+	testx.AssertNoErr(t, err)
+	err = rdb.Rename(ctx, "city", "town").Err()
+	testx.AssertErr(t, err, goredis.Nil)
+
+	testx.AssertEqual(t, rdb.Del(ctx, "title", "city").Val(), int64(1))
+	testx.AssertEqual(t, rdb.FlushDB(ctx).Val(), "OK")
+	testx.AssertEqual(t, rdb.Exists(ctx, "age", "person").Val(), int64(0))
+	testx.AssertEqual(t, rdb.Ping(ctx).Val(), "PONG")
+}
+
+func TestHashes(t *testing.T) {
+	ctx := context.Background()
+	db, rdb := getClient(t)
+	defer db.Close()
+
+	n, err := rdb.HSet(ctx, "person", "name", "alice", "age", 25).Result()
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, n, int64(2))
+	n, _ = rdb.HSet(ctx, "person", map[string]any{"age": 26, "city": "paris"}).Result()
+	testx.AssertEqual(t, n, int64(1))
+
+	testx.AssertEqual(t, rdb.HGet(ctx, "person", "age").Val(), "26")
+	testx.AssertErr(t, rdb.HGet(ctx, "person", "country").Err(), goredis.Nil)
+	testx.AssertEqual(t, rdb.HExists(ctx, "person", "city").Val(), true)
+	testx.AssertEqual(t, rdb.HLen(ctx, "person").Val(), int64(3))
+	testx.AssertEqual(t, rdb.HGetAll(ctx, "person").Val(),
+		map[string]string{"name": "alice", "age": "26", "city": "paris"})
+	testx.AssertEqual(t, rdb.HMGet(ctx, "person", "name", "country").Val(), []any{"alice", nil})
+	testx.AssertEqual(t, rdb.HIncrBy(ctx, "person", "age", 1).Val(), int64(27))
+	testx.AssertEqual(t, len(rdb.HKeys(ctx, "person").Val()), 3)
+	testx.AssertEqual(t, rdb.HDel(ctx, "person", "city", "country").Val(), int64(1))
+
+	_ = rdb.Set(ctx, "name", "alice", 0)
+	testx.AssertErr(t, rdb.HSet(ctx, "name", "first", "alice").Err(), redka.ErrKeyType)
+}
+
+func TestSortedSets(t *testing.T) {
+	ctx := context.Background()
+	db, rdb := getClient(t)
+	defer db.Close()
+
+	n, err := rdb.ZAdd(ctx, "scores",
+		goredis.Z{Score: 11, Member: "alice"},
+		goredis.Z{Score: 22, Member: "bob"},
+		goredis.Z{Score: 33, Member: "cindy"},
+	).Result()
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, n, int64(3))
+
+	testx.AssertEqual(t, rdb.ZCard(ctx, "scores").Val(), int64(3))
+	testx.AssertEqual(t, rdb.ZScore(ctx, "scores", "bob").Val(), 22.0)
+	testx.AssertErr(t, rdb.ZScore(ctx, "scores", "dave").Err(), goredis.Nil)
+	testx.AssertEqual(t, rdb.ZIncrBy(ctx, "scores", 30, "alice").Val(), 41.0)
+	testx.AssertEqual(t, rdb.ZRange(ctx, "scores", 0, -1).Val(), []string{"bob", "cindy", "alice"})
+	testx.AssertEqual(t, rdb.ZRange(ctx, "scores", -2, -1).Val(), []string{"cindy", "alice"})
+	testx.AssertEqual(t, rdb.ZRangeWithScores(ctx, "scores", 0, 0).Val(),
+		[]goredis.Z{{Score: 22, Member: "bob"}})
+	testx.AssertEqual(t, rdb.ZRem(ctx, "scores", "bob", "dave").Val(), int64(1))
+}
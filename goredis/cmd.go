@@ -0,0 +1,225 @@
+package goredis
+
+import (
+	"strconv"
+	"time"
+)
+
+// baseCmd is the common part of the command results.
+type baseCmd struct {
+	err error
+}
+
+// Err returns the command error (if any).
+func (cmd *baseCmd) Err() error {
+	return cmd.err
+}
+
+// SetErr sets the command error.
+func (cmd *baseCmd) SetErr(err error) {
+	cmd.err = err
+}
+
+// StatusCmd is the result of a command that returns a status (like "OK").
+type StatusCmd struct {
+	baseCmd
+	val string
+}
+
+func newStatusCmd(err error) *StatusCmd {
+	if err != nil {
+		return &StatusCmd{baseCmd: baseCmd{err: err}}
+	}
+	return &StatusCmd{val: "OK"}
+}
+
+// Val returns the status.
+func (cmd *StatusCmd) Val() string {
+	return cmd.val
+}
+
+// Result returns the status and the error.
+func (cmd *StatusCmd) Result() (string, error) {
+	return cmd.val, cmd.err
+}
+
+// StringCmd is the result of a command that returns a string.
+type StringCmd struct {
+	baseCmd
+	val string
+}
+
+// Val returns the string.
+func (cmd *StringCmd) Val() string {
+	return cmd.val
+}
+
+// Result returns the string and the error.
+func (cmd *StringCmd) Result() (string, error) {
+	return cmd.val, cmd.err
+}
+
+// Bytes returns the string as a byte slice.
+func (cmd *StringCmd) Bytes() ([]byte, error) {
+	return []byte(cmd.val), cmd.err
+}
+
+// Int returns the string parsed as an integer.
+func (cmd *StringCmd) Int() (int, error) {
+	if cmd.err != nil {
+		return 0, cmd.err
+	}
+	return strconv.Atoi(cmd.val)
+}
+
+// Int64 returns the string parsed as a 64-bit integer.
+func (cmd *StringCmd) Int64() (int64, error) {
+	if cmd.err != nil {
+		return 0, cmd.err
+	}
+	return strconv.ParseInt(cmd.val, 10, 64)
+}
+
+// Float64 returns the string parsed as a float.
+func (cmd *StringCmd) Float64() (float64, error) {
+	if cmd.err != nil {
+		return 0, cmd.err
+	}
+	return strconv.ParseFloat(cmd.val, 64)
+}
+
+// IntCmd is the result of a command that returns an integer.
+type IntCmd struct {
+	baseCmd
+	val int64
+}
+
+// Val returns the integer.
+func (cmd *IntCmd) Val() int64 {
+	return cmd.val
+}
+
+// Result returns the integer and the error.
+func (cmd *IntCmd) Result() (int64, error) {
+	return cmd.val, cmd.err
+}
+
+// BoolCmd is the result of a command that returns a boolean.
+type BoolCmd struct {
+	baseCmd
+	val bool
+}
+
+// Val returns the boolean.
+func (cmd *BoolCmd) Val() bool {
+	return cmd.val
+}
+
+// Result returns the boolean and the error.
+func (cmd *BoolCmd) Result() (bool, error) {
+	return cmd.val, cmd.err
+}
+
+// FloatCmd is the result of a command that returns a float.
+type FloatCmd struct {
+	baseCmd
+	val float64
+}
+
+// Val returns the float.
+func (cmd *FloatCmd) Val() float64 {
+	return cmd.val
+}
+
+// Result returns the float and the error.
+func (cmd *FloatCmd) Result() (float64, error) {
+	return cmd.val, cmd.err
+}
+
+// DurationCmd is the result of a command that returns a duration.
+type DurationCmd struct {
+	baseCmd
+	val time.Duration
+}
+
+// Val returns the duration.
+func (cmd *DurationCmd) Val() time.Duration {
+	return cmd.val
+}
+
+// Result returns the duration and the error.
+func (cmd *DurationCmd) Result() (time.Duration, error) {
+	return cmd.val, cmd.err
+}
+
+// StringSliceCmd is the result of a command that returns a list of strings.
+type StringSliceCmd struct {
+	baseCmd
+	val []string
+}
+
+// Val returns the strings.
+func (cmd *StringSliceCmd) Val() []string {
+	return cmd.val
+}
+
+// Result returns the strings and the error.
+func (cmd *StringSliceCmd) Result() ([]string, error) {
+	return cmd.val, cmd.err
+}
+
+// SliceCmd is the result of a command that returns a list of values,
+// where the missing values are nil.
+type SliceCmd struct {
+	baseCmd
+	val []any
+}
+
+// Val returns the values.
+func (cmd *SliceCmd) Val() []any {
+	return cmd.val
+}
+
+// Result returns the values and the error.
+func (cmd *SliceCmd) Result() ([]any, error) {
+	return cmd.val, cmd.err
+}
+
+// MapStringStringCmd is the result of a command that returns a map.
+type MapStringStringCmd struct {
+	baseCmd
+	val map[string]string
+}
+
+// Val returns the map.
+func (cmd *MapStringStringCmd) Val() map[string]string {
+	return cmd.val
+}
+
+// Result returns the map and the error.
+func (cmd *MapStringStringCmd) Result() (map[string]string, error) {
+	return cmd.val, cmd.err
+}
+
+// Z is a sorted set member with its score.
+type Z struct {
+	Score  float64
+	Member any
+}
+
+// ZSliceCmd is the result of a command that returns
+// sorted set members with their scores.
+type ZSliceCmd struct {
+	baseCmd
+	val []Z
+}
+
+// Val returns the members.
+func (cmd *ZSliceCmd) Val() []Z {
+	return cmd.val
+}
+
+// Result returns the members and the error.
+func (cmd *ZSliceCmd) Result() ([]Z, error) {
+	return cmd.val, cmd.err
+}
@@ -0,0 +1,90 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+)
+
+// HDel deletes the hash fields and returns the number of deleted fields.
+func (c *Client) HDel(ctx context.Context, key string, fields ...string) *IntCmd {
+	n, err := c.with(ctx).Hash().Delete(key, fields...)
+	return &IntCmd{baseCmd: baseCmd{err: err}, val: int64(n)}
+}
+
+// HExists checks if the hash field exists.
+func (c *Client) HExists(ctx context.Context, key, field string) *BoolCmd {
+	ok, err := c.with(ctx).Hash().Exists(key, field)
+	return &BoolCmd{baseCmd: baseCmd{err: err}, val: ok}
+}
+
+// HGet returns the value of the hash field.
+// Returns Nil if the field does not exist.
+func (c *Client) HGet(ctx context.Context, key, field string) *StringCmd {
+	val, err := c.with(ctx).Hash().Get(key, field)
+	if err != nil {
+		return &StringCmd{baseCmd: baseCmd{err: notFound(err)}}
+	}
+	return &StringCmd{val: val.String()}
+}
+
+// HGetAll returns all fields and values of the hash.
+func (c *Client) HGetAll(ctx context.Context, key string) *MapStringStringCmd {
+	items, err := c.with(ctx).Hash().Items(key)
+	if err != nil {
+		return &MapStringStringCmd{baseCmd: baseCmd{err: err}}
+	}
+	m := make(map[string]string, len(items))
+	for field, val := range items {
+		m[field] = val.String()
+	}
+	return &MapStringStringCmd{val: m}
+}
+
+// HIncrBy increments the integer value of the hash field.
+func (c *Client) HIncrBy(ctx context.Context, key, field string, incr int64) *IntCmd {
+	val, err := c.with(ctx).Hash().Incr(key, field, int(incr))
+	return &IntCmd{baseCmd: baseCmd{err: valueErr(err)}, val: int64(val)}
+}
+
+// HKeys returns all fields of the hash.
+func (c *Client) HKeys(ctx context.Context, key string) *StringSliceCmd {
+	fields, err := c.with(ctx).Hash().Fields(key)
+	return &StringSliceCmd{baseCmd: baseCmd{err: err}, val: fields}
+}
+
+// HLen returns the number of fields in the hash.
+func (c *Client) HLen(ctx context.Context, key string) *IntCmd {
+	n, err := c.with(ctx).Hash().Len(key)
+	return &IntCmd{baseCmd: baseCmd{err: err}, val: int64(n)}
+}
+
+// HMGet returns the values of the hash fields.
+// The values of the fields that do not exist are nil.
+func (c *Client) HMGet(ctx context.Context, key string, fields ...string) *SliceCmd {
+	items, err := c.with(ctx).Hash().GetMany(key, fields...)
+	if err != nil {
+		return &SliceCmd{baseCmd: baseCmd{err: err}}
+	}
+	vals := make([]any, len(fields))
+	for i, field := range fields {
+		if val, ok := items[field]; ok {
+			vals[i] = val.String()
+		}
+	}
+	return &SliceCmd{val: vals}
+}
+
+// HSet sets the values of the hash fields and returns the number
+// of fields created. Accepts the field-value pairs ("f1", "v1",
+// "f2", "v2"), or a map[string]any.
+func (c *Client) HSet(ctx context.Context, key string, values ...any) *IntCmd {
+	if len(values) == 0 {
+		return &IntCmd{baseCmd: baseCmd{err: errors.New("ERR wrong number of arguments")}}
+	}
+	items, err := toMap(values)
+	if err != nil {
+		return &IntCmd{baseCmd: baseCmd{err: err}}
+	}
+	n, err := c.with(ctx).Hash().SetMany(key, items)
+	return &IntCmd{baseCmd: baseCmd{err: err}, val: int64(n)}
+}
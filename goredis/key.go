@@ -0,0 +1,90 @@
+package goredis
+
+import (
+	"context"
+	"time"
+)
+
+// Del deletes the keys and returns the number of deleted keys.
+func (c *Client) Del(ctx context.Context, keys ...string) *IntCmd {
+	n, err := c.with(ctx).Key().Delete(keys...)
+	return &IntCmd{baseCmd: baseCmd{err: err}, val: int64(n)}
+}
+
+// Exists returns the number of existing keys among specified.
+func (c *Client) Exists(ctx context.Context, keys ...string) *IntCmd {
+	n, err := c.with(ctx).Key().Count(keys...)
+	return &IntCmd{baseCmd: baseCmd{err: err}, val: int64(n)}
+}
+
+// Expire sets the time-to-live of the key.
+// Returns false if the key does not exist.
+func (c *Client) Expire(ctx context.Context, key string, expiration time.Duration) *BoolCmd {
+	ok, err := c.with(ctx).Key().Expire(key, expiration)
+	return &BoolCmd{baseCmd: baseCmd{err: err}, val: ok}
+}
+
+// ExpireAt sets the expiration time of the key.
+// Returns false if the key does not exist.
+func (c *Client) ExpireAt(ctx context.Context, key string, tm time.Time) *BoolCmd {
+	ok, err := c.with(ctx).Key().ExpireAt(key, tm)
+	return &BoolCmd{baseCmd: baseCmd{err: err}, val: ok}
+}
+
+// Keys returns the keys matching the pattern.
+func (c *Client) Keys(ctx context.Context, pattern string) *StringSliceCmd {
+	keys, err := c.with(ctx).Key().Keys(pattern)
+	if err != nil {
+		return &StringSliceCmd{baseCmd: baseCmd{err: err}}
+	}
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.Key
+	}
+	return &StringSliceCmd{val: names}
+}
+
+// Persist removes the expiration time of the key.
+// Returns false if the key does not exist or has no expiration time.
+func (c *Client) Persist(ctx context.Context, key string) *BoolCmd {
+	ok, err := c.with(ctx).Key().Persist(key)
+	return &BoolCmd{baseCmd: baseCmd{err: err}, val: ok}
+}
+
+// Rename renames the key. Returns an error if the key does not exist.
+func (c *Client) Rename(ctx context.Context, key, newkey string) *StatusCmd {
+	err := c.with(ctx).Key().Rename(key, newkey)
+	return newStatusCmd(notFound(err))
+}
+
+// TTL returns the time-to-live of the key. Like go-redis,
+// returns -1 (nanoseconds) if the key has no expiration time,
+// and -2 if the key does not exist.
+func (c *Client) TTL(ctx context.Context, key string) *DurationCmd {
+	db := c.with(ctx)
+	k, err := db.Key().Get(key)
+	if err != nil {
+		return &DurationCmd{baseCmd: baseCmd{err: err}}
+	}
+	switch {
+	case !k.Exists():
+		return &DurationCmd{val: -2}
+	case k.ETime == nil:
+		return &DurationCmd{val: -1}
+	}
+	ttl := time.Until(time.UnixMilli(*k.ETime))
+	return &DurationCmd{val: ttl.Round(time.Second)}
+}
+
+// Type returns the type of the key ("string", "hash", "zset"),
+// or "none" if the key does not exist.
+func (c *Client) Type(ctx context.Context, key string) *StatusCmd {
+	k, err := c.with(ctx).Key().Get(key)
+	if err != nil {
+		return newStatusCmd(err)
+	}
+	if !k.Exists() {
+		return &StatusCmd{val: "none"}
+	}
+	return &StatusCmd{val: k.TypeName()}
+}
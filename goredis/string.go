@@ -0,0 +1,168 @@
+package goredis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nalgeon/redka"
+)
+
+// Get returns the value of the key.
+// Returns Nil if the key does not exist.
+func (c *Client) Get(ctx context.Context, key string) *StringCmd {
+	val, err := c.with(ctx).Str().Get(key)
+	if err != nil {
+		return &StringCmd{baseCmd: baseCmd{err: err}}
+	}
+	if !val.Exists() {
+		return &StringCmd{baseCmd: baseCmd{err: Nil}}
+	}
+	return &StringCmd{val: val.String()}
+}
+
+// GetSet sets the value of the key and returns the previous value.
+// Returns Nil if the key did not exist.
+func (c *Client) GetSet(ctx context.Context, key string, value any) *StringCmd {
+	val, err := toValue(value)
+	if err != nil {
+		return &StringCmd{baseCmd: baseCmd{err: err}}
+	}
+	prev, err := c.with(ctx).Str().GetSet(key, val, 0)
+	if err != nil {
+		return &StringCmd{baseCmd: baseCmd{err: err}}
+	}
+	if !prev.Exists() {
+		return &StringCmd{baseCmd: baseCmd{err: Nil}}
+	}
+	return &StringCmd{val: prev.String()}
+}
+
+// Set sets the value of the key with an optional
+// expiration time (if expiration > 0).
+func (c *Client) Set(ctx context.Context, key string, value any, expiration time.Duration) *StatusCmd {
+	val, err := toValue(value)
+	if err != nil {
+		return newStatusCmd(err)
+	}
+	err = c.with(ctx).Str().SetExpires(key, val, expiration)
+	return newStatusCmd(err)
+}
+
+// SetNX sets the value of the key if it does not exist.
+func (c *Client) SetNX(ctx context.Context, key string, value any, expiration time.Duration) *BoolCmd {
+	val, err := toValue(value)
+	if err != nil {
+		return &BoolCmd{baseCmd: baseCmd{err: err}}
+	}
+	ok, err := c.with(ctx).Str().SetNotExists(key, val, expiration)
+	return &BoolCmd{baseCmd: baseCmd{err: err}, val: ok}
+}
+
+// SetXX sets the value of the key if it exists.
+func (c *Client) SetXX(ctx context.Context, key string, value any, expiration time.Duration) *BoolCmd {
+	val, err := toValue(value)
+	if err != nil {
+		return &BoolCmd{baseCmd: baseCmd{err: err}}
+	}
+	ok, err := c.with(ctx).Str().SetExists(key, val, expiration)
+	return &BoolCmd{baseCmd: baseCmd{err: err}, val: ok}
+}
+
+// MGet returns the values of the keys.
+// The values of the keys that do not exist are nil.
+func (c *Client) MGet(ctx context.Context, keys ...string) *SliceCmd {
+	items, err := c.with(ctx).Str().GetMany(keys...)
+	if err != nil {
+		return &SliceCmd{baseCmd: baseCmd{err: err}}
+	}
+	vals := make([]any, len(keys))
+	for i, key := range keys {
+		if val := items[key]; val.Exists() {
+			vals[i] = val.String()
+		}
+	}
+	return &SliceCmd{val: vals}
+}
+
+// MSet sets the values of the keys. Accepts the key-value pairs
+// ("k1", "v1", "k2", "v2"), or a map[string]any.
+func (c *Client) MSet(ctx context.Context, values ...any) *StatusCmd {
+	items, err := toMap(values)
+	if err != nil {
+		return newStatusCmd(err)
+	}
+	err = c.with(ctx).Str().SetMany(items)
+	return newStatusCmd(err)
+}
+
+// Incr increments the integer value of the key by one.
+func (c *Client) Incr(ctx context.Context, key string) *IntCmd {
+	return c.IncrBy(ctx, key, 1)
+}
+
+// IncrBy increments the integer value of the key.
+func (c *Client) IncrBy(ctx context.Context, key string, value int64) *IntCmd {
+	val, err := c.with(ctx).Str().Incr(key, int(value))
+	return &IntCmd{baseCmd: baseCmd{err: valueErr(err)}, val: int64(val)}
+}
+
+// IncrByFloat increments the float value of the key.
+func (c *Client) IncrByFloat(ctx context.Context, key string, value float64) *FloatCmd {
+	val, err := c.with(ctx).Str().IncrFloat(key, value)
+	return &FloatCmd{baseCmd: baseCmd{err: valueErr(err)}, val: val}
+}
+
+// Decr decrements the integer value of the key by one.
+func (c *Client) Decr(ctx context.Context, key string) *IntCmd {
+	return c.IncrBy(ctx, key, -1)
+}
+
+// DecrBy decrements the integer value of the key.
+func (c *Client) DecrBy(ctx context.Context, key string, decrement int64) *IntCmd {
+	return c.IncrBy(ctx, key, -decrement)
+}
+
+// errNotInt is returned when incrementing a non-numeric value.
+var errNotInt = errors.New("ERR value is not an integer or out of range")
+
+// valueErr returns the Redis error for an invalid value type.
+func valueErr(err error) error {
+	if errors.Is(err, redka.ErrValueType) {
+		return errNotInt
+	}
+	return err
+}
+
+// toMap converts the key-value pairs or a map to a map of values.
+func toMap(values []any) (map[string]any, error) {
+	if len(values) == 1 {
+		if m, ok := values[0].(map[string]any); ok {
+			items := make(map[string]any, len(m))
+			for key, v := range m {
+				val, err := toValue(v)
+				if err != nil {
+					return nil, err
+				}
+				items[key] = val
+			}
+			return items, nil
+		}
+	}
+	if len(values)%2 != 0 {
+		return nil, errors.New("ERR wrong number of arguments")
+	}
+	items := make(map[string]any, len(values)/2)
+	for i := 0; i < len(values); i += 2 {
+		key, err := toString(values[i])
+		if err != nil {
+			return nil, err
+		}
+		val, err := toValue(values[i+1])
+		if err != nil {
+			return nil, err
+		}
+		items[key] = val
+	}
+	return items, nil
+}
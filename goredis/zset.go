@@ -0,0 +1,109 @@
+package goredis
+
+import (
+	"context"
+)
+
+// ZAdd adds the members to the sorted set, or updates their scores.
+// Returns the number of members added.
+func (c *Client) ZAdd(ctx context.Context, key string, members ...Z) *IntCmd {
+	items := make(map[any]float64, len(members))
+	for _, m := range members {
+		elem, err := toValue(m.Member)
+		if err != nil {
+			return &IntCmd{baseCmd: baseCmd{err: err}}
+		}
+		if b, ok := elem.([]byte); ok {
+			elem = string(b)
+		}
+		items[elem] = m.Score
+	}
+	n, err := c.with(ctx).SortedSet().AddMany(key, items)
+	return &IntCmd{baseCmd: baseCmd{err: err}, val: int64(n)}
+}
+
+// ZCard returns the number of members in the sorted set.
+func (c *Client) ZCard(ctx context.Context, key string) *IntCmd {
+	n, err := c.with(ctx).SortedSet().Len(key)
+	return &IntCmd{baseCmd: baseCmd{err: err}, val: int64(n)}
+}
+
+// ZIncrBy increments the score of the member.
+func (c *Client) ZIncrBy(ctx context.Context, key string, increment float64, member string) *FloatCmd {
+	score, err := c.with(ctx).SortedSet().Incr(key, member, increment)
+	return &FloatCmd{baseCmd: baseCmd{err: err}, val: score}
+}
+
+// ZRange returns the members with ranks between start and stop
+// (inclusive), ordered by score from low to high. Negative ranks
+// count from the end of the set (-1 is the last member).
+func (c *Client) ZRange(ctx context.Context, key string, start, stop int64) *StringSliceCmd {
+	zs, err := c.zrange(ctx, key, start, stop)
+	if err != nil {
+		return &StringSliceCmd{baseCmd: baseCmd{err: err}}
+	}
+	members := make([]string, len(zs))
+	for i, z := range zs {
+		members[i] = z.Member.(string)
+	}
+	return &StringSliceCmd{val: members}
+}
+
+// ZRangeWithScores is like ZRange, but returns the scores too.
+func (c *Client) ZRangeWithScores(ctx context.Context, key string, start, stop int64) *ZSliceCmd {
+	zs, err := c.zrange(ctx, key, start, stop)
+	return &ZSliceCmd{baseCmd: baseCmd{err: err}, val: zs}
+}
+
+// ZRem removes the members from the sorted set.
+// Returns the number of members removed.
+func (c *Client) ZRem(ctx context.Context, key string, members ...any) *IntCmd {
+	elems := make([]any, len(members))
+	for i, m := range members {
+		elem, err := toString(m)
+		if err != nil {
+			return &IntCmd{baseCmd: baseCmd{err: err}}
+		}
+		elems[i] = elem
+	}
+	n, err := c.with(ctx).SortedSet().Delete(key, elems...)
+	return &IntCmd{baseCmd: baseCmd{err: err}, val: int64(n)}
+}
+
+// ZScore returns the score of the member.
+// Returns Nil if the member does not exist.
+func (c *Client) ZScore(ctx context.Context, key, member string) *FloatCmd {
+	score, err := c.with(ctx).SortedSet().GetScore(key, member)
+	return &FloatCmd{baseCmd: baseCmd{err: notFound(err)}, val: score}
+}
+
+// zrange returns the members with ranks between start and stop.
+// The repository does not support negative ranks,
+// so they are converted using the set length.
+func (c *Client) zrange(ctx context.Context, key string, start, stop int64) ([]Z, error) {
+	db := c.with(ctx).SortedSet()
+	if start < 0 || stop < 0 {
+		n, err := db.Len(key)
+		if err != nil {
+			return nil, err
+		}
+		if start < 0 {
+			start = max(int64(n)+start, 0)
+		}
+		if stop < 0 {
+			stop = int64(n) + stop
+		}
+		if stop < 0 {
+			return []Z{}, nil
+		}
+	}
+	items, err := db.Range(key, int(start), int(stop))
+	if err != nil {
+		return nil, err
+	}
+	zs := make([]Z, len(items))
+	for i, item := range items {
+		zs[i] = Z{Score: item.Score, Member: item.Elem.String()}
+	}
+	return zs, nil
+}
@@ -0,0 +1,178 @@
+package redka
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rzset"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// defaultHistoryVersions is the number of versions kept
+// for each key if the history has no other limits.
+const defaultHistoryVersions = 10
+
+// HistoryOptions configures the key history (see [Options.History]).
+type HistoryOptions struct {
+	// Match is the pattern of the keys to record, like "config:*"
+	// (see [rkey.DB.Keys]). If empty, records all keys.
+	Match string
+	// MaxVersions is the maximum number of versions kept for each
+	// key, including the current one. If both MaxVersions and MaxAge
+	// are zero, keeps 10 versions.
+	MaxVersions int
+	// MaxAge is how long the versions are kept. The older versions
+	// are deleted in the background (see [Options.ExpireInterval]),
+	// except for those needed to read the keys as of any time
+	// within MaxAge. If zero, the versions do not expire.
+	MaxAge time.Duration
+}
+
+// Revision is a recorded version of a key (see [DB.Revisions]).
+type Revision struct {
+	// KeyValue is the key with its value as of the version.
+	// The Key.ID and Key.MTime are not recorded, so they are zero.
+	// Empty if the key was deleted.
+	KeyValue
+	// Time is when the version was recorded
+	// (the commit time of the transaction).
+	Time time.Time
+	// Deleted is true if the key was deleted (or renamed,
+	// or deleted after it expired) at the time.
+	Deleted bool
+}
+
+// Revisions returns the recorded versions of the key, the latest
+// first (so the first one is the current version, unless the key
+// was deleted). Returns an empty slice if the history is disabled,
+// or there are no recorded versions.
+//
+// The history is recorded for the keys changed with the [DB] and
+// [Tx] methods, but not with [DB.UseTx] or [rkey.DB.DeleteAll]
+// (unless the trash is enabled, see [Options.TrashRetention]).
+func (db *DB) Revisions(key string) ([]Revision, error) {
+	var revs []sqlx.Revision
+	err := db.View(func(tx *Tx) error {
+		var err error
+		revs, err = sqlx.Revisions(tx.tx, key)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]Revision, len(revs))
+	for i, rev := range revs {
+		res[i] = newRevision(rev)
+	}
+	return res, nil
+}
+
+// GetAsOf returns the key with its value as it was at the given
+// time, using the recorded versions (see [Options.History]). Returns
+// the zero KeyValue if the key did not exist (or had expired) at
+// the time, or its version at the time is not known (the history
+// was disabled or already pruned). Useful for finding out when
+// and how a key (like a configuration value) has changed.
+func (db *DB) GetAsOf(key string, at time.Time) (KeyValue, error) {
+	ms := at.UnixMilli()
+	var kv KeyValue
+	err := db.View(func(tx *Tx) error {
+		rev, ok, err := sqlx.RevisionAt(tx.tx, key, ms)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if !rev.Deleted() && !expiredAt(rev.Key, ms) {
+				kv = revisionValue(rev)
+			}
+			return nil
+		}
+
+		// There are no versions recorded by the time, so the
+		// current one is the right one if it has not been
+		// changed since then.
+		kv, err = getKeyValue(tx, key)
+		if err != nil {
+			return err
+		}
+		if !kv.Key.Exists() || kv.Key.MTime > ms || expiredAt(kv.Key, ms) {
+			kv = KeyValue{}
+		}
+		return nil
+	})
+	return kv, err
+}
+
+// newHistory creates the history from the options,
+// or returns nil if the history is disabled.
+func newHistory(opts *HistoryOptions) *sqlx.History {
+	if opts == nil {
+		return nil
+	}
+	h := &sqlx.History{
+		Match:       opts.Match,
+		MaxVersions: opts.MaxVersions,
+		MaxAge:      opts.MaxAge,
+	}
+	if h.MaxVersions == 0 && h.MaxAge == 0 {
+		h.MaxVersions = defaultHistoryVersions
+	}
+	return h
+}
+
+// pruneHistory deletes the versions older than
+// HistoryOptions.MaxAge. Returns the number of deleted versions.
+func (db *DB) pruneHistory() (count int, err error) {
+	history := db.DB.History
+	if history == nil || history.MaxAge == 0 {
+		return 0, nil
+	}
+	err = db.DB.Update(func(tx *Tx) error {
+		count, err = history.Prune(tx.tx)
+		return err
+	})
+	return count, err
+}
+
+// newRevision converts the recorded version.
+func newRevision(rev sqlx.Revision) Revision {
+	res := Revision{Time: time.UnixMilli(rev.Time), Deleted: rev.Deleted()}
+	if !res.Deleted {
+		res.KeyValue = revisionValue(rev)
+	}
+	return res
+}
+
+// revisionValue returns the key with its value
+// as of the recorded version.
+func revisionValue(rev sqlx.Revision) KeyValue {
+	kv := KeyValue{Key: rev.Key}
+	switch rev.Key.Type {
+	case core.TypeString:
+		if len(rev.Values) > 0 {
+			kv.Str = Value(rev.Values[0].Value)
+		}
+	case core.TypeHash:
+		kv.Hash = make(map[string]Value, len(rev.Values))
+		for _, val := range rev.Values {
+			kv.Hash[string(val.Field)] = Value(val.Value)
+		}
+	case core.TypeSortedSet:
+		kv.ZSet = make([]rzset.SetItem, len(rev.Values))
+		for i, val := range rev.Values {
+			kv.ZSet[i] = rzset.SetItem{Elem: Value(val.Field), Score: val.Score}
+		}
+		slices.SortFunc(kv.ZSet, func(a, b rzset.SetItem) int {
+			return cmp.Or(cmp.Compare(a.Score, b.Score), cmp.Compare(a.Elem.String(), b.Elem.String()))
+		})
+	}
+	return kv
+}
+
+// expiredAt reports whether the key
+// had expired by the time (in unix milliseconds).
+func expiredAt(k core.Key, ms int64) bool {
+	return k.ETime != nil && *k.ETime <= ms
+}
@@ -0,0 +1,158 @@
+package redka_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestHistory(t *testing.T) {
+	// openDB opens a database with the history
+	// and a clock that advances by a second on each call.
+	openDB := func(t *testing.T, opts redka.HistoryOptions) (*redka.DB, func() time.Time) {
+		start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		now := start
+		db, err := redka.Open(":memory:", &redka.Options{
+			History: &opts,
+			Clock:   func() time.Time { return now },
+		})
+		testx.AssertNoErr(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		tick := func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}
+		return db, tick
+	}
+
+	t.Run("revisions", func(t *testing.T) {
+		db, tick := openDB(t, redka.HistoryOptions{Match: "config:*"})
+
+		tick()
+		_ = db.Str().Set("config:mode", "dev")
+		tick()
+		_ = db.Str().Set("config:mode", "prod")
+		_ = db.Str().Set("other", "value")
+		tick()
+		_, _ = db.Key().Delete("config:mode")
+
+		revs, err := db.Revisions("config:mode")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(revs), 3)
+		testx.AssertEqual(t, revs[0].Deleted, true)
+		testx.AssertEqual(t, revs[1].Str.String(), "prod")
+		testx.AssertEqual(t, revs[1].Key.Version, 2)
+		testx.AssertEqual(t, revs[2].Str.String(), "dev")
+		testx.AssertEqual(t, revs[1].Time.Sub(revs[2].Time), time.Second)
+
+		revs, err = db.Revisions("other")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(revs), 0)
+	})
+	t.Run("as of", func(t *testing.T) {
+		db, tick := openDB(t, redka.HistoryOptions{})
+
+		t0 := tick()
+		_, _ = db.Hash().Set("person", "name", "alice")
+		t1 := tick()
+		_, _ = db.Hash().Set("person", "age", 25)
+		t2 := tick()
+		_ = db.Key().Rename("person", "user")
+		t3 := tick()
+
+		kv, err := db.GetAsOf("person", t0.Add(-time.Second))
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, kv.Key.Exists(), false)
+
+		kv, _ = db.GetAsOf("person", t0)
+		testx.AssertEqual(t, len(kv.Hash), 1)
+		testx.AssertEqual(t, kv.Hash["name"].String(), "alice")
+
+		kv, _ = db.GetAsOf("person", t1.Add(500*time.Millisecond))
+		testx.AssertEqual(t, len(kv.Hash), 2)
+		testx.AssertEqual(t, kv.Hash["age"].String(), "25")
+
+		kv, _ = db.GetAsOf("person", t2)
+		testx.AssertEqual(t, kv.Key.Exists(), false)
+		kv, _ = db.GetAsOf("user", t3)
+		testx.AssertEqual(t, kv.Key.Key, "user")
+		testx.AssertEqual(t, len(kv.Hash), 2)
+	})
+	t.Run("zset and expiry", func(t *testing.T) {
+		db, tick := openDB(t, redka.HistoryOptions{})
+
+		t0 := tick()
+		_, _ = db.SortedSet().AddMany("race", map[any]float64{"bob": 20, "alice": 10})
+		_, _ = db.Key().Expire("race", 5*time.Second)
+		tick()
+		_, _ = db.SortedSet().Add("race", "carl", 30)
+
+		kv, _ := db.GetAsOf("race", t0)
+		testx.AssertEqual(t, len(kv.ZSet), 2)
+		testx.AssertEqual(t, kv.ZSet[0].Elem.String(), "alice")
+		testx.AssertEqual(t, kv.ZSet[1].Score, 20.0)
+		kv, _ = db.GetAsOf("race", t0.Add(5*time.Second))
+		testx.AssertEqual(t, kv.Key.Exists(), false)
+	})
+	t.Run("max versions", func(t *testing.T) {
+		db, tick := openDB(t, redka.HistoryOptions{MaxVersions: 2})
+
+		for _, val := range []string{"one", "two", "three"} {
+			tick()
+			_ = db.Str().Set("key", val)
+		}
+		revs, _ := db.Revisions("key")
+		testx.AssertEqual(t, len(revs), 2)
+		testx.AssertEqual(t, revs[1].Str.String(), "two")
+	})
+	t.Run("max age", func(t *testing.T) {
+		// The background pruning reads the clock concurrently.
+		var now atomic.Int64
+		now.Store(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli())
+		db, err := redka.Open(":memory:", &redka.Options{
+			History:        &redka.HistoryOptions{MaxAge: time.Minute},
+			ExpireInterval: 10 * time.Millisecond,
+			Clock:          func() time.Time { return time.UnixMilli(now.Load()) },
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		for _, val := range []string{"one", "two", "three"} {
+			now.Add(1000)
+			_ = db.Str().Set("key", val)
+		}
+		_ = db.Str().Set("deleted", "value")
+		_, _ = db.Key().Delete("deleted")
+
+		now.Add(time.Hour.Milliseconds())
+		time.Sleep(50 * time.Millisecond)
+
+		// The latest version is kept, since it is still
+		// the version as of an hour ago.
+		revs, _ := db.Revisions("key")
+		testx.AssertEqual(t, len(revs), 1)
+		testx.AssertEqual(t, revs[0].Str.String(), "three")
+		revs, _ = db.Revisions("deleted")
+		testx.AssertEqual(t, len(revs), 0)
+	})
+	t.Run("not recorded", func(t *testing.T) {
+		start := time.Now()
+		db, err := redka.Open(":memory:", nil)
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().Set("key", "value")
+		revs, err := db.Revisions("key")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(revs), 0)
+
+		// The current version is used if it has not changed since.
+		kv, _ := db.GetAsOf("key", time.Now())
+		testx.AssertEqual(t, kv.Str.String(), "value")
+		kv, _ = db.GetAsOf("key", start.Add(-time.Second))
+		testx.AssertEqual(t, kv.Key.Exists(), false)
+	})
+}
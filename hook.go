@@ -0,0 +1,64 @@
+package redka
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// Op describes a repository operation (like Str.Set or Key.Delete):
+// its name, keys, start time and, after it completes, the duration,
+// the rows affected and the time spent in SQL, and the error (if any).
+type Op = sqlx.Op
+
+// Hook observes the repository operations.
+// Before is called before each operation, and After when it completes.
+type Hook = sqlx.Hook
+
+// AddHook registers a hook to be called around each operation made
+// through the repositories (Str, Hash, SortedSet and Key), including
+// the ones made by the server. Use it to attach logging, metrics or
+// cache invalidation. The Before hooks are called in the order they
+// were added, and the After hooks in the reverse order.
+//
+// The hooks are called synchronously by the goroutine that executes
+// the operation, so they should return quickly. Operations made
+// within transactions (see [DB.Update]), and the Scanner and Iter
+// iterators are not reported.
+func (db *DB) AddHook(h Hook) {
+	db.hooks.Add(h)
+}
+
+// slowLogHook logs the operations slower than the threshold.
+// The hook is only added to the database when the threshold
+// is first set (see setThreshold), so that the databases without
+// it do not pay for observing the operations.
+type slowLogHook struct {
+	log       *slog.Logger
+	threshold atomic.Int64 // zero to disable
+	once      sync.Once
+}
+
+// setThreshold changes the threshold and adds
+// the hook to the database if necessary.
+func (h *slowLogHook) setThreshold(db *DB, threshold time.Duration) {
+	h.threshold.Store(int64(threshold))
+	if threshold > 0 {
+		h.once.Do(func() { db.AddHook(h) })
+	}
+}
+
+func (h *slowLogHook) Before(ctx context.Context, op *Op) {}
+
+func (h *slowLogHook) After(ctx context.Context, op *Op) {
+	threshold := time.Duration(h.threshold.Load())
+	if threshold <= 0 || op.Duration < threshold {
+		return
+	}
+	h.log.WarnContext(ctx, "slow operation", "op", op.Name, "keys", op.Keys,
+		"duration", op.Duration, "error", op.Err)
+}
@@ -0,0 +1,128 @@
+package redka_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+// recordHook records the observed operations.
+type recordHook struct {
+	name  string
+	calls *[]string
+	ops   []redka.Op
+}
+
+func (h *recordHook) Before(ctx context.Context, op *redka.Op) {
+	*h.calls = append(*h.calls, h.name+".before:"+op.Name)
+}
+
+func (h *recordHook) After(ctx context.Context, op *redka.Op) {
+	*h.calls = append(*h.calls, h.name+".after:"+op.Name)
+	h.ops = append(h.ops, *op)
+}
+
+func TestAddHook(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	var calls []string
+	hook := &recordHook{name: "h1", calls: &calls}
+	db.AddHook(hook)
+
+	t.Run("write", func(t *testing.T) {
+		hook.ops, calls = nil, nil
+		err := db.Str().Set("name", "alice")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(hook.ops), 1)
+		op := hook.ops[0]
+		testx.AssertEqual(t, op.Name, "Str.Set")
+		testx.AssertEqual(t, op.Keys, []string{"name"})
+		testx.AssertEqual(t, op.Start.IsZero(), false)
+		testx.AssertEqual(t, op.Duration > 0, true)
+		testx.AssertEqual(t, op.Err, nil)
+	})
+	t.Run("read", func(t *testing.T) {
+		hook.ops, calls = nil, nil
+		_, err := db.Key().Count("name", "age")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(hook.ops), 1)
+		testx.AssertEqual(t, hook.ops[0].Name, "Key.Count")
+		testx.AssertEqual(t, hook.ops[0].Keys, []string{"name", "age"})
+	})
+	t.Run("error", func(t *testing.T) {
+		hook.ops, calls = nil, nil
+		_, err := db.Hash().Set("name", "age", 25)
+		testx.AssertErr(t, err, redka.ErrKeyType)
+		testx.AssertEqual(t, len(hook.ops), 1)
+		testx.AssertEqual(t, hook.ops[0].Name, "Hash.Set")
+		testx.AssertErr(t, hook.ops[0].Err, redka.ErrKeyType)
+	})
+	t.Run("command", func(t *testing.T) {
+		hook.ops, calls = nil, nil
+		_, _ = db.SortedSet().Add("scores", "alice", 11)
+		items, err := db.SortedSet().RangeWith("scores").ByRank(0, 1).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 1)
+		testx.AssertEqual(t, len(hook.ops), 2)
+		testx.AssertEqual(t, hook.ops[1].Name, "SortedSet.RangeWith")
+		testx.AssertEqual(t, hook.ops[1].Keys, []string{"scores"})
+	})
+	t.Run("order", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		var calls []string
+		db.AddHook(&recordHook{name: "h1", calls: &calls})
+		db.AddHook(&recordHook{name: "h2", calls: &calls})
+		_, _ = db.Str().Get("name")
+		testx.AssertEqual(t, calls, []string{
+			"h1.before:Str.Get", "h2.before:Str.Get",
+			"h2.after:Str.Get", "h1.after:Str.Get",
+		})
+	})
+	t.Run("transaction", func(t *testing.T) {
+		hook.ops, calls = nil, nil
+		err := db.Update(func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "bob")
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(hook.ops), 0)
+	})
+	t.Run("context", func(t *testing.T) {
+		type ctxKey struct{}
+		var got any
+		cdb := getDB(t)
+		defer cdb.Close()
+		cdb.AddHook(ctxHook(func(ctx context.Context) { got = ctx.Value(ctxKey{}) }))
+		ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+		_, _ = cdb.WithContext(ctx).Str().Get("name")
+		testx.AssertEqual(t, got, "value")
+	})
+}
+
+// ctxHook calls the function with the operation context.
+type ctxHook func(ctx context.Context)
+
+func (h ctxHook) Before(ctx context.Context, op *redka.Op) { h(ctx) }
+func (h ctxHook) After(ctx context.Context, op *redka.Op)  {}
+
+func TestSlowThreshold(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	db, err := redka.Open(":memory:", &redka.Options{
+		Logger:        logger,
+		SlowThreshold: time.Nanosecond,
+	})
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	out := buf.String()
+	testx.AssertEqual(t, strings.Contains(out, `level=WARN msg="slow operation" op=Str.Set keys=[name]`), true)
+}
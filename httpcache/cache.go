@@ -0,0 +1,303 @@
+// Package httpcache provides a net/http middleware that caches
+// the responses in Redka.
+//
+// Wrap a handler to cache its GET responses for a minute:
+//
+//	cache := httpcache.New(db, &httpcache.Options{TTL: time.Minute})
+//	http.Handle("/users/", cache.Middleware(usersHandler))
+//
+// The middleware has the standard func(http.Handler) http.Handler
+// signature, so it works with the routers built on net/http (like chi)
+// as is, and with other frameworks through their wrappers:
+//
+//	r.Use(cache.Middleware)                      // chi
+//	e.Use(echo.WrapMiddleware(cache.Middleware)) // echo
+//	g.Use(adapter.Wrap(cache.Middleware))        // gin (gwatts/gin-adapter)
+//
+// Only successful (200 OK) responses to GET requests are cached,
+// unless the response has the "Cache-Control: no-store" or "private"
+// directives. Responses are keyed by the request URI and the values
+// of the [Options.Vary] headers. Use [Cache.Invalidate] to remove
+// the cached responses when the underlying data changes.
+//
+// The cache fails open: if Redka returns an error, the request
+// is passed to the handler as if it was not cached.
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka"
+)
+
+// Cache status reported in the X-Cache response header.
+const (
+	StatusHit   = "HIT"
+	StatusMiss  = "MISS"
+	StatusStale = "STALE"
+)
+
+// HeaderStatus is the response header with the cache status.
+const HeaderStatus = "X-Cache"
+
+// Options configures the cache.
+type Options struct {
+	// Prefix is added to the cache keys.
+	// If empty, uses "httpcache:".
+	Prefix string
+	// TTL is how long a cached response stays fresh.
+	// If zero, uses 1 minute.
+	TTL time.Duration
+	// StaleWhileRevalidate is how long after the TTL a stale
+	// response can still be served while it is refreshed
+	// in the background. Zero disables stale responses.
+	StaleWhileRevalidate time.Duration
+	// Vary lists the request headers (like Accept-Encoding)
+	// that select different cached responses for the same URI.
+	Vary []string
+	// MaxBodySize is the maximum size of the cached response body
+	// in bytes. Larger responses are not cached.
+	// If zero, uses 1 MiB.
+	MaxBodySize int
+}
+
+// Cache caches the HTTP responses in Redka.
+// Safe for concurrent use.
+type Cache struct {
+	db      *redka.DB
+	opts    Options
+	pending sync.Map // key -> struct{}
+}
+
+// entry is a cached response.
+type entry struct {
+	Status  int         `json:"status"`
+	Header  http.Header `json:"header"`
+	Body    []byte      `json:"body"`
+	Created time.Time   `json:"created"`
+}
+
+// New creates a new cache.
+// The opts parameter is optional. If nil, uses default options.
+func New(db *redka.DB, opts *Options) *Cache {
+	c := &Cache{db: db}
+	if opts != nil {
+		c.opts = *opts
+	}
+	if c.opts.Prefix == "" {
+		c.opts.Prefix = "httpcache:"
+	}
+	if c.opts.TTL == 0 {
+		c.opts.TTL = time.Minute
+	}
+	if c.opts.MaxBodySize == 0 {
+		c.opts.MaxBodySize = 1 << 20
+	}
+	return c
+}
+
+// Middleware returns a handler that serves the cached responses
+// and caches the responses of the next handler.
+func (c *Cache) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || hasDirective(r.Header, "no-store") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := c.key(r)
+		ent, ok := c.load(r, key)
+		if ok {
+			age := time.Since(ent.Created)
+			if age < c.opts.TTL {
+				c.serve(w, ent, StatusHit)
+				return
+			}
+			if age < c.opts.TTL+c.opts.StaleWhileRevalidate {
+				c.serve(w, ent, StatusStale)
+				c.revalidate(next, r, key)
+				return
+			}
+		}
+
+		w.Header().Set(HeaderStatus, StatusMiss)
+		rec := &recorder{w: w, header: w.Header(), limit: c.opts.MaxBodySize}
+		next.ServeHTTP(rec, r)
+		c.store(r, key, rec)
+	})
+}
+
+// Invalidate removes the cached responses for the URIs
+// matching the glob pattern (like "/users/*"). Note that "?"
+// in the pattern matches any character, including the one
+// that starts the query string.
+// Returns the number of removed responses.
+func (c *Cache) Invalidate(pattern string) (int, error) {
+	base := c.opts.Prefix + pattern
+	var keys []string
+	seen := map[string]bool{}
+	for _, p := range []string{base, base + "|*"} {
+		found, err := c.db.Key().Keys(p)
+		if err != nil {
+			return 0, err
+		}
+		for _, k := range found {
+			if !seen[k.Key] {
+				seen[k.Key] = true
+				keys = append(keys, k.Key)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	return c.db.Key().Delete(keys...)
+}
+
+// key returns the cache key for the request.
+func (c *Cache) key(r *http.Request) string {
+	key := c.opts.Prefix + r.URL.RequestURI()
+	if len(c.opts.Vary) == 0 {
+		return key
+	}
+	var b strings.Builder
+	b.WriteString(key)
+	b.WriteByte('|')
+	for i, name := range c.opts.Vary {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		b.WriteString(strings.Join(r.Header.Values(name), ","))
+	}
+	return b.String()
+}
+
+// load returns the cached response for the key.
+func (c *Cache) load(r *http.Request, key string) (entry, bool) {
+	val, err := c.db.WithContext(r.Context()).Str().Get(key)
+	if err != nil || !val.Exists() {
+		return entry{}, false
+	}
+	var ent entry
+	if err := json.Unmarshal(val.Bytes(), &ent); err != nil {
+		return entry{}, false
+	}
+	return ent, true
+}
+
+// store caches the recorded response if it is cacheable.
+func (c *Cache) store(r *http.Request, key string, rec *recorder) {
+	if rec.status() != http.StatusOK || rec.overflow ||
+		hasDirective(rec.header, "no-store") || hasDirective(rec.header, "private") {
+		return
+	}
+	header := rec.header.Clone()
+	header.Del(HeaderStatus)
+	ent := entry{
+		Status:  rec.status(),
+		Header:  header,
+		Body:    rec.body.Bytes(),
+		Created: time.Now(),
+	}
+	data, err := json.Marshal(ent)
+	if err != nil {
+		return
+	}
+	ttl := c.opts.TTL + c.opts.StaleWhileRevalidate
+	_ = c.db.WithContext(r.Context()).Str().SetExpires(key, data, ttl)
+}
+
+// serve writes the cached response.
+func (c *Cache) serve(w http.ResponseWriter, ent entry, status string) {
+	header := w.Header()
+	for name, values := range ent.Header {
+		header[name] = values
+	}
+	header.Set(HeaderStatus, status)
+	w.WriteHeader(ent.Status)
+	_, _ = w.Write(ent.Body)
+}
+
+// revalidate refreshes the cached response in the background.
+// Only one refresh per key runs at a time.
+func (c *Cache) revalidate(next http.Handler, r *http.Request, key string) {
+	if _, loaded := c.pending.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	req := r.Clone(context.WithoutCancel(r.Context()))
+	go func() {
+		defer c.pending.Delete(key)
+		rec := &recorder{header: http.Header{}, limit: c.opts.MaxBodySize}
+		next.ServeHTTP(rec, req)
+		c.store(req, key, rec)
+	}()
+}
+
+// hasDirective reports whether the Cache-Control header
+// contains the directive.
+func hasDirective(h http.Header, directive string) bool {
+	for _, value := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), directive) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// recorder captures the response while (optionally)
+// writing it through to the client.
+type recorder struct {
+	w        http.ResponseWriter
+	header   http.Header
+	code     int
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (r *recorder) Header() http.Header {
+	return r.header
+}
+
+func (r *recorder) WriteHeader(code int) {
+	if r.code != 0 {
+		return
+	}
+	r.code = code
+	if r.w != nil {
+		r.w.WriteHeader(code)
+	}
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	if r.code == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	if !r.overflow {
+		r.body.Write(p)
+	}
+	if r.body.Len() > r.limit {
+		r.overflow = true
+		r.body.Reset()
+	}
+	if r.w != nil {
+		return r.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// status returns the response status code.
+func (r *recorder) status() int {
+	if r.code == 0 {
+		return http.StatusOK
+	}
+	return r.code
+}
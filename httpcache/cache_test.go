@@ -0,0 +1,178 @@
+package httpcache_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/httpcache"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func getDB(tb testing.TB) *redka.DB {
+	tb.Helper()
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// counter responds with the number of handled requests.
+type counter struct {
+	n      atomic.Int64
+	header http.Header
+	status int
+}
+
+func (c *counter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n := c.n.Add(1)
+	for name, values := range c.header {
+		w.Header()[name] = values
+	}
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+	}
+	fmt.Fprintf(w, "%s #%d", r.URL.Path, n)
+}
+
+func get(h http.Handler, uri string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, uri, nil)
+	for i := 0; i < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("hit", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := httpcache.New(db, nil)
+		h := cache.Middleware(&counter{header: http.Header{"Content-Type": {"text/plain"}}})
+
+		rec := get(h, "/users/1")
+		testx.AssertEqual(t, rec.Body.String(), "/users/1 #1")
+		testx.AssertEqual(t, rec.Header().Get(httpcache.HeaderStatus), httpcache.StatusMiss)
+
+		rec = get(h, "/users/1")
+		testx.AssertEqual(t, rec.Code, http.StatusOK)
+		testx.AssertEqual(t, rec.Body.String(), "/users/1 #1")
+		testx.AssertEqual(t, rec.Header().Get("Content-Type"), "text/plain")
+		testx.AssertEqual(t, rec.Header().Get(httpcache.HeaderStatus), httpcache.StatusHit)
+
+		rec = get(h, "/users/1?page=2")
+		testx.AssertEqual(t, rec.Body.String(), "/users/1 #2")
+	})
+	t.Run("expired", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := httpcache.New(db, &httpcache.Options{TTL: 10 * time.Millisecond})
+		h := cache.Middleware(&counter{})
+
+		_ = get(h, "/users/1")
+		time.Sleep(20 * time.Millisecond)
+		rec := get(h, "/users/1")
+		testx.AssertEqual(t, rec.Body.String(), "/users/1 #2")
+		testx.AssertEqual(t, rec.Header().Get(httpcache.HeaderStatus), httpcache.StatusMiss)
+	})
+	t.Run("stale", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := httpcache.New(db, &httpcache.Options{
+			TTL:                  10 * time.Millisecond,
+			StaleWhileRevalidate: time.Minute,
+		})
+		h := cache.Middleware(&counter{})
+
+		_ = get(h, "/users/1")
+		time.Sleep(20 * time.Millisecond)
+		rec := get(h, "/users/1")
+		testx.AssertEqual(t, rec.Body.String(), "/users/1 #1")
+		testx.AssertEqual(t, rec.Header().Get(httpcache.HeaderStatus), httpcache.StatusStale)
+
+		// Wait for the background refresh.
+		for range 100 {
+			rec = get(h, "/users/1")
+			if rec.Header().Get(httpcache.HeaderStatus) == httpcache.StatusHit {
+				break
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		testx.AssertEqual(t, rec.Body.String(), "/users/1 #2")
+	})
+	t.Run("vary", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := httpcache.New(db, &httpcache.Options{Vary: []string{"Accept-Language"}})
+		h := cache.Middleware(&counter{})
+
+		_ = get(h, "/", "Accept-Language", "en")
+		rec := get(h, "/", "Accept-Language", "fr")
+		testx.AssertEqual(t, rec.Body.String(), "/ #2")
+		rec = get(h, "/", "Accept-Language", "en")
+		testx.AssertEqual(t, rec.Body.String(), "/ #1")
+	})
+	t.Run("not cacheable", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := httpcache.New(db, nil)
+
+		h := cache.Middleware(&counter{status: http.StatusNotFound})
+		_ = get(h, "/a")
+		rec := get(h, "/a")
+		testx.AssertEqual(t, rec.Body.String(), "/a #2")
+
+		h = cache.Middleware(&counter{header: http.Header{"Cache-Control": {"private, max-age=60"}}})
+		_ = get(h, "/b")
+		rec = get(h, "/b")
+		testx.AssertEqual(t, rec.Body.String(), "/b #2")
+
+		h = cache.Middleware(&counter{})
+		_ = get(h, "/c")
+		rec = get(h, "/c", "Cache-Control", "no-store")
+		testx.AssertEqual(t, rec.Body.String(), "/c #2")
+
+		req := httptest.NewRequest(http.MethodPost, "/c", nil)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		testx.AssertEqual(t, w.Body.String(), "/c #3")
+	})
+	t.Run("max body size", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		cache := httpcache.New(db, &httpcache.Options{MaxBodySize: 5})
+		h := cache.Middleware(&counter{})
+
+		_ = get(h, "/users/1")
+		rec := get(h, "/users/1")
+		testx.AssertEqual(t, rec.Body.String(), "/users/1 #2")
+	})
+}
+
+func TestInvalidate(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+	cache := httpcache.New(db, &httpcache.Options{Vary: []string{"Accept"}})
+	h := cache.Middleware(&counter{})
+
+	_ = get(h, "/users/1")
+	_ = get(h, "/users/2", "Accept", "text/html")
+	_ = get(h, "/posts/1")
+
+	n, err := cache.Invalidate("/users/*")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, n, 2)
+
+	rec := get(h, "/users/1")
+	testx.AssertEqual(t, rec.Header().Get(httpcache.HeaderStatus), httpcache.StatusMiss)
+	rec = get(h, "/posts/1")
+	testx.AssertEqual(t, rec.Header().Get(httpcache.HeaderStatus), httpcache.StatusHit)
+}
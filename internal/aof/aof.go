@@ -0,0 +1,287 @@
+// Package aof implements an append-only journal of write commands,
+// similar to the Redis AOF. The journal stores commands using the
+// RESP protocol, so it can be inspected with standard tools
+// or replayed with redis-cli --pipe.
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+// Errors returned by the journal.
+var (
+	ErrClosed    = errors.New("aof: journal is closed")
+	ErrTruncated = errors.New("aof: truncated journal")
+	ErrNotEmpty  = errors.New("aof: database is not empty")
+)
+
+// Sync is the fsync policy of the journal.
+type Sync int
+
+const (
+	// SyncEverySec flushes the journal to disk once per second.
+	// A crash may lose up to one second of writes.
+	SyncEverySec Sync = iota
+	// SyncAlways flushes the journal to disk after every write.
+	// Safest and slowest.
+	SyncAlways
+	// SyncNo leaves flushing to the operating system.
+	SyncNo
+)
+
+// ParseSync parses the fsync policy name
+// (everysec, always or no).
+func ParseSync(s string) (Sync, error) {
+	switch strings.ToLower(s) {
+	case "everysec":
+		return SyncEverySec, nil
+	case "always":
+		return SyncAlways, nil
+	case "no":
+		return SyncNo, nil
+	}
+	return 0, fmt.Errorf("aof: invalid fsync policy: %s", s)
+}
+
+// String returns the fsync policy name.
+func (s Sync) String() string {
+	switch s {
+	case SyncAlways:
+		return "always"
+	case SyncNo:
+		return "no"
+	default:
+		return "everysec"
+	}
+}
+
+// syncInterval is the interval between flushes
+// for the SyncEverySec policy.
+const syncInterval = time.Second
+
+// Journal is an append-only journal of write commands.
+// Safe for concurrent use.
+type Journal struct {
+	mu     sync.Mutex
+	f      *os.File
+	w      *bufio.Writer
+	sync   Sync
+	dirty  bool
+	closed bool
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// Open opens the journal file for appending,
+// creating it if necessary.
+func Open(path string, sync Sync) (*Journal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	j := &Journal{
+		f:    f,
+		w:    bufio.NewWriter(f),
+		sync: sync,
+		done: make(chan struct{}),
+	}
+	if sync == SyncEverySec {
+		j.wg.Add(1)
+		go j.syncLoop()
+	}
+	return j, nil
+}
+
+// Append writes the commands to the journal as a single
+// atomic group. Multiple commands are wrapped in MULTI/EXEC,
+// so that they are replayed in a single transaction.
+//
+// Commands with relative expiration times (EXPIRE, SETEX, SET EX, etc.)
+// are journaled with absolute times, so that replaying the journal
+// later does not extend the lifetime of the keys.
+func (j *Journal) Append(cmds ...command.Cmd) error {
+	if len(cmds) == 0 {
+		return nil
+	}
+	data := Encode(cmds...)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return ErrClosed
+	}
+	if _, err := j.w.Write(data); err != nil {
+		return err
+	}
+
+	// Always hand the data over to the OS, so that it
+	// survives a process crash regardless of the fsync policy.
+	if err := j.w.Flush(); err != nil {
+		return err
+	}
+	if j.sync == SyncAlways {
+		return j.f.Sync()
+	}
+	j.dirty = true
+	return nil
+}
+
+// Sync flushes the journal to disk.
+func (j *Journal) Sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return ErrClosed
+	}
+	return j.flush()
+}
+
+// Close flushes the journal to disk and closes the file.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	if j.closed {
+		j.mu.Unlock()
+		return nil
+	}
+	j.closed = true
+	close(j.done)
+	err := j.flush()
+	j.mu.Unlock()
+
+	j.wg.Wait()
+	if closeErr := j.f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// flush writes the buffered data and syncs the file.
+// The caller must hold the lock.
+func (j *Journal) flush() error {
+	if err := j.w.Flush(); err != nil {
+		return err
+	}
+	if !j.dirty && j.sync != SyncAlways {
+		return nil
+	}
+	j.dirty = false
+	return j.f.Sync()
+}
+
+// syncLoop syncs the journal to disk every second.
+func (j *Journal) syncLoop() {
+	defer j.wg.Done()
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.done:
+			return
+		case <-ticker.C:
+			j.mu.Lock()
+			if !j.closed && j.dirty {
+				_ = j.flush()
+			}
+			j.mu.Unlock()
+		}
+	}
+}
+
+// Encode encodes the commands as a single atomic group
+// in the journal format (RESP arrays of bulk strings).
+// Multiple commands are wrapped in MULTI/EXEC.
+// Relative expiration times are converted to absolute ones.
+func Encode(cmds ...command.Cmd) []byte {
+	now := time.Now()
+	var group [][][]byte
+	for _, cmd := range cmds {
+		group = append(group, absolute(cmd.Name(), cmd.Args(), now)...)
+	}
+
+	var data []byte
+	if len(group) > 1 {
+		data = resp.AppendCommand(data, []byte("multi"))
+	}
+	for _, args := range group {
+		data = resp.AppendCommand(data, args...)
+	}
+	if len(group) > 1 {
+		data = resp.AppendCommand(data, []byte("exec"))
+	}
+	return data
+}
+
+// absolute converts a command with a relative expiration time
+// to the equivalent commands with an absolute time.
+// Returns other commands as is (name followed by arguments).
+// Expects a command that has already been executed successfully.
+func absolute(name string, args [][]byte, now time.Time) [][][]byte {
+	name = strings.ToLower(name)
+	switch name {
+	case "expire", "pexpire":
+		// EXPIRE key seconds -> PEXPIREAT key ms
+		at := expireAt(now, args[1], name == "expire")
+		return [][][]byte{pexpireat(args[0], at)}
+	case "setex", "psetex":
+		// SETEX key seconds value -> SET key value + PEXPIREAT key ms
+		at := expireAt(now, args[1], name == "setex")
+		set := [][]byte{[]byte("set"), args[0], args[2]}
+		return [][][]byte{set, pexpireat(args[0], at)}
+	case "set":
+		// SET key value [NX|XX] EX seconds -> SET key value + PEXPIREAT key ms
+		// The condition is dropped because the command is only journaled
+		// when the value has actually been set.
+		for i := 2; i < len(args)-1; i++ {
+			unit := strings.ToLower(string(args[i]))
+			if unit != "ex" && unit != "px" {
+				continue
+			}
+			at := expireAt(now, args[i+1], unit == "ex")
+			set := [][]byte{[]byte("set"), args[0], args[1]}
+			return [][][]byte{set, pexpireat(args[0], at)}
+		}
+	case "hexpire", "hpexpire":
+		// HEXPIRE key seconds FIELDS ... -> HPEXPIREAT key ms FIELDS ...
+		at := expireAt(now, args[1], name == "hexpire")
+		cmd := [][]byte{[]byte("hpexpireat"), args[0], strconv.AppendInt(nil, at, 10)}
+		return [][][]byte{append(cmd, args[2:]...)}
+	case "getex":
+		// GETEX key EX seconds -> GETEX key PXAT ms
+		if len(args) == 3 {
+			unit := strings.ToLower(string(args[1]))
+			if unit == "ex" || unit == "px" {
+				at := expireAt(now, args[2], unit == "ex")
+				getex := [][]byte{[]byte("getex"), args[0], []byte("pxat"), strconv.AppendInt(nil, at, 10)}
+				return [][][]byte{getex}
+			}
+		}
+	}
+	cmd := make([][]byte, 0, len(args)+1)
+	cmd = append(cmd, []byte(name))
+	cmd = append(cmd, args...)
+	return [][][]byte{cmd}
+}
+
+// expireAt returns the absolute expiration time in unix milliseconds.
+func expireAt(now time.Time, ttl []byte, seconds bool) int64 {
+	n, _ := strconv.ParseInt(string(ttl), 10, 64)
+	if seconds {
+		n *= 1000
+	}
+	return now.UnixMilli() + n
+}
+
+// pexpireat returns the PEXPIREAT command.
+func pexpireat(key []byte, at int64) [][]byte {
+	return [][]byte{[]byte("pexpireat"), key, strconv.AppendInt(nil, at, 10)}
+}
@@ -0,0 +1,157 @@
+package aof
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestParseSync(t *testing.T) {
+	tests := []struct {
+		name string
+		want Sync
+	}{
+		{"everysec", SyncEverySec},
+		{"always", SyncAlways},
+		{"NO", SyncNo},
+	}
+	for _, test := range tests {
+		got, err := ParseSync(test.name)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, got, test.want)
+	}
+	_, err := ParseSync("sometimes")
+	testx.AssertEqual(t, err != nil, true)
+}
+
+func TestAbsolute(t *testing.T) {
+	now := time.UnixMilli(1000)
+	tests := []struct {
+		cmd  string
+		want []string
+	}{
+		{"set name alice", []string{"set name alice"}},
+		{"set name alice nx", []string{"set name alice nx"}},
+		{"set name alice nx ex 10", []string{"set name alice", "pexpireat name 11000"}},
+		{"set name alice px 10", []string{"set name alice", "pexpireat name 1010"}},
+		{"setex name 10 alice", []string{"set name alice", "pexpireat name 11000"}},
+		{"psetex name 10 alice", []string{"set name alice", "pexpireat name 1010"}},
+		{"expire name 10", []string{"pexpireat name 11000"}},
+		{"pexpire name 10", []string{"pexpireat name 1010"}},
+		{"expireat name 10", []string{"expireat name 10"}},
+		{"getex name ex 10", []string{"getex name pxat 11000"}},
+		{"hexpire person 10 fields 1 name", []string{"hpexpireat person 11000 fields 1 name"}},
+		{"hpexpire person 10 fields 2 name age", []string{"hpexpireat person 1010 fields 2 name age"}},
+		{"hexpireat person 10 fields 1 name", []string{"hexpireat person 10 fields 1 name"}},
+		{"getex name PX 10", []string{"getex name pxat 1010"}},
+		{"getex name pxat 10", []string{"getex name pxat 10"}},
+		{"getex name persist", []string{"getex name persist"}},
+	}
+	for _, test := range tests {
+		cmd := parse(test.cmd)
+		var got []string
+		for _, args := range absolute(cmd.Name(), cmd.Args(), now) {
+			got = append(got, string(bytes.Join(args, []byte(" "))))
+		}
+		testx.AssertEqual(t, got, test.want)
+	}
+}
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redka.aof")
+	jr, err := Open(path, SyncAlways)
+	testx.AssertNoErr(t, err)
+
+	err = jr.Append(parse("set name alice"))
+	testx.AssertNoErr(t, err)
+	err = jr.Append(parse("hset person name bob"), parse("setex tmp 60 val"))
+	testx.AssertNoErr(t, err)
+	err = jr.Close()
+	testx.AssertNoErr(t, err)
+
+	err = jr.Append(parse("set name bob"))
+	testx.AssertErr(t, err, ErrClosed)
+
+	data, err := os.ReadFile(path)
+	testx.AssertNoErr(t, err)
+	want := "*3\r\n$3\r\nset\r\n$4\r\nname\r\n$5\r\nalice\r\n*1\r\n$5\r\nmulti\r\n"
+	testx.AssertEqual(t, strings.HasPrefix(string(data), want), true)
+}
+
+func TestReplay(t *testing.T) {
+	t.Run("replay", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redka.aof")
+		jr, err := Open(path, SyncEverySec)
+		testx.AssertNoErr(t, err)
+		_ = jr.Append(parse("set name alice"))
+		_ = jr.Append(parse("hset person name bob"), parse("setex tmp 60 val"))
+		_ = jr.Append(parse("del name"))
+		_ = jr.Close()
+
+		db := getDB(t)
+		f, err := os.Open(path)
+		testx.AssertNoErr(t, err)
+		defer f.Close()
+		stats, err := Replay(f, db)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, stats.Commands, 5)
+		fi, _ := f.Stat()
+		testx.AssertEqual(t, stats.Size, fi.Size())
+
+		count, _ := db.Key().Count("name")
+		testx.AssertEqual(t, count, 0)
+		name, _ := db.Hash().Get("person", "name")
+		testx.AssertEqual(t, name.String(), "bob")
+		key, _ := db.Key().Get("tmp")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		data := "*3\r\n$3\r\nset\r\n$4\r\nname\r\n$5\r\nalice\r\n" +
+			"*1\r\n$5\r\nmulti\r\n*3\r\n$3\r\nset\r\n$3\r\nage\r\n$2\r\n25\r\n"
+		db := getDB(t)
+		stats, err := Replay(strings.NewReader(data), db)
+		testx.AssertErr(t, err, ErrTruncated)
+		testx.AssertEqual(t, stats.Commands, 1)
+		testx.AssertEqual(t, stats.Size, int64(34))
+
+		count, _ := db.Key().Count("name", "age")
+		testx.AssertEqual(t, count, 1)
+	})
+	t.Run("not empty", func(t *testing.T) {
+		db := getDB(t)
+		_ = db.Str().Set("name", "alice")
+		_, err := Replay(strings.NewReader(""), db)
+		testx.AssertErr(t, err, ErrNotEmpty)
+	})
+}
+
+func getDB(tb testing.TB) *redka.DB {
+	tb.Helper()
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func parse(s string) command.Cmd {
+	parts := strings.Split(s, " ")
+	args := make([][]byte, len(parts))
+	for i, part := range parts {
+		args[i] = []byte(part)
+	}
+	cmd, err := command.Parse(args)
+	if err != nil {
+		panic(err)
+	}
+	return cmd
+}
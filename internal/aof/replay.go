@@ -0,0 +1,152 @@
+package aof
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+// ReplayStats describes the result of the journal replay.
+type ReplayStats struct {
+	Commands int   // number of executed commands
+	Size     int64 // size of the applied part of the journal in bytes
+}
+
+// Replay reads the commands from the journal and executes them
+// against the database. Commands between MULTI and EXEC are
+// executed in a single transaction. The database must be empty.
+//
+// If the journal ends with an incomplete command (e.g. after a crash),
+// the complete commands are still applied, and Replay returns
+// ErrTruncated. In this case, the journal should be truncated
+// to stats.Size bytes before appending new commands.
+func Replay(r io.Reader, db *redka.DB) (ReplayStats, error) {
+	var stats ReplayStats
+	key, err := db.Key().Random()
+	if err != nil {
+		return stats, err
+	}
+	if key.Exists() {
+		return stats, ErrNotEmpty
+	}
+
+	rd := bufio.NewReader(r)
+	app := NewApplier(db)
+	var offset int64
+	for {
+		args, n, err := resp.ReadCommand(rd)
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, resp.ErrTruncated) {
+			return stats, ErrTruncated
+		}
+		if err != nil {
+			return stats, err
+		}
+		offset += n
+
+		count, err := app.Apply(args)
+		if err != nil {
+			return stats, err
+		}
+		if !app.InMulti() {
+			stats.Commands += count
+			stats.Size = offset
+		}
+	}
+
+	if app.InMulti() {
+		// The transaction was not completely written,
+		// so it should not be applied.
+		return stats, ErrTruncated
+	}
+	return stats, nil
+}
+
+// Applier executes a stream of journaled commands against the database.
+// Commands between MULTI and EXEC are executed in a single transaction.
+// Used to replay the journal and to apply the replication stream.
+type Applier struct {
+	db      *redka.DB
+	inMulti bool
+	multi   []command.Cmd
+}
+
+// NewApplier creates a new command applier.
+func NewApplier(db *redka.DB) *Applier {
+	return &Applier{db: db}
+}
+
+// Apply executes the command, or queues it if there is
+// an open transaction. Returns the number of executed commands.
+func (a *Applier) Apply(args [][]byte) (int, error) {
+	name := strings.ToLower(string(args[0]))
+	switch name {
+	case "multi":
+		a.inMulti = true
+		a.multi = a.multi[:0]
+		return 0, nil
+	case "exec":
+		if !a.inMulti {
+			return 0, fmt.Errorf("aof: %w", command.ErrNotInMulti)
+		}
+		a.inMulti = false
+		err := a.db.Update(func(tx *redka.Tx) error {
+			return runAll(command.RedkaTx(tx).WithProcs(a.db, tx), a.multi)
+		})
+		if err != nil {
+			return 0, err
+		}
+		return len(a.multi), nil
+	}
+
+	cmd, err := command.Parse(args)
+	if err != nil {
+		return 0, fmt.Errorf("aof: parse %s: %w", name, err)
+	}
+	if a.inMulti {
+		a.multi = append(a.multi, cmd)
+		return 0, nil
+	}
+	if err := runAll(command.RedkaDB(a.db), []command.Cmd{cmd}); err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// InMulti reports whether there is an open transaction.
+func (a *Applier) InMulti() bool {
+	return a.inMulti
+}
+
+// runAll executes the commands, discarding their output.
+func runAll(red command.Redka, cmds []command.Cmd) error {
+	for _, cmd := range cmds {
+		if _, err := cmd.Run(discard{}, red); err != nil {
+			return fmt.Errorf("aof: run %s: %w", cmd.Name(), err)
+		}
+	}
+	return nil
+}
+
+// discard is a command.Writer that discards the output.
+type discard struct{}
+
+func (discard) WriteError(msg string)       {}
+func (discard) WriteString(str string)      {}
+func (discard) WriteBulk(bulk []byte)       {}
+func (discard) WriteBulkString(bulk string) {}
+func (discard) WriteInt(num int)            {}
+func (discard) WriteInt64(num int64)        {}
+func (discard) WriteUint64(num uint64)      {}
+func (discard) WriteArray(count int)        {}
+func (discard) WriteNull()                  {}
+func (discard) WriteRaw(data []byte)        {}
+func (discard) WriteAny(v any)              {}
@@ -5,6 +5,7 @@ package command
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,22 +13,41 @@ import (
 	"github.com/nalgeon/redka/internal/core"
 	"github.com/nalgeon/redka/internal/rhash"
 	"github.com/nalgeon/redka/internal/rkey"
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/rstring"
 )
 
 // Redis-like errors.
 var (
+	ErrBitNotArgNum      = errors.New("ERR BITOP NOT must be called with a single source key")
+	ErrBusy              = errors.New("BUSY database is busy, try again later")
+	ErrCrossSlot         = errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+	ErrFuncNotFound      = errors.New("ERR Function not found")
 	ErrInvalidArgNum     = errors.New("ERR wrong number of arguments")
+	ErrInvalidBit        = errors.New("ERR bit is not an integer or out of range")
+	ErrInvalidBitOffset  = errors.New("ERR bit offset is not an integer or out of range")
 	ErrInvalidCursor     = errors.New("ERR invalid cursor")
 	ErrInvalidExpireTime = errors.New("ERR invalid expire time")
 	ErrInvalidFloat      = errors.New("ERR value is not a float")
 	ErrInvalidInt        = errors.New("ERR value is not an integer or out of range")
+	ErrInvalidStreamID   = errors.New("ERR Invalid stream ID specified as stream command argument")
+	ErrKeyTooLarge       = errors.New("ERR key is too large")
 	ErrKeyType           = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
 	ErrNestedMulti       = errors.New("ERR MULTI calls can not be nested")
 	ErrNotFound          = errors.New("ERR no such key")
 	ErrNotInMulti        = errors.New("ERR EXEC without MULTI")
-	ErrSyntaxError       = errors.New("ERR syntax error")
+	ErrNumKeys           = errors.New("ERR Number of keys can't be greater than number of args")
+	ErrOffsetOutOfRange  = errors.New("ERR offset is out of range")
+	ErrQuotaExceeded     = errors.New("ERR quota exceeded")
+	ErrReadOnly          = errors.New("READONLY You can't write against a read only replica.")
+	ErrStreamID          = errors.New("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	ErrSyntaxError       = fmt.Errorf("ERR %w", core.ErrSyntax)
+	ErrTooManyElements   = errors.New("ERR too many elements")
+	ErrTxClosed          = errors.New("ERR transaction is closed")
 	ErrUnknownCmd        = errors.New("ERR unknown command")
 	ErrUnknownSubcmd     = errors.New("ERR unknown subcommand")
+	ErrValueTooLarge     = errors.New("ERR value is too large")
+	ErrWatchInMulti      = errors.New("ERR WATCH inside MULTI is not allowed")
 )
 
 // Writer is an interface to write responses to the client.
@@ -53,6 +73,9 @@ type Cmd interface {
 	// String returns the command string representation (name and arguments).
 	String() string
 
+	// Args returns the command arguments (without the name).
+	Args() [][]byte
+
 	// Error translates a domain error to a command error
 	// and returns its string representation.
 	Error(err error) string
@@ -65,17 +88,22 @@ type Cmd interface {
 type RKey interface {
 	Exists(key string) (bool, error)
 	Count(keys ...string) (int, error)
+	Copy(key, newKey string, replace bool) (bool, error)
 	Keys(pattern string) ([]core.Key, error)
 	Scan(cursor int, pattern string, pageSize int) (rkey.ScanResult, error)
-	Scanner(pattern string, pageSize int) *rkey.Scanner
+	ScanType(cursor int, pattern string, typ core.TypeID, pageSize int) (rkey.ScanResult, error)
 	Random() (core.Key, error)
 	Get(key string) (core.Key, error)
 	Expire(key string, ttl time.Duration) (bool, error)
 	ExpireAt(key string, at time.Time) (bool, error)
+	ExpireWith(key string) rkey.ExpireCmd
 	Persist(key string) (bool, error)
+	TTL(key string) (time.Duration, error)
+	ExpireTime(key string) (time.Time, error)
 	Rename(key, newKey string) error
 	RenameNotExists(key, newKey string) (bool, error)
 	Delete(keys ...string) (int, error)
+	Unlink(keys ...string) (int, error)
 	DeleteAll() error
 }
 
@@ -88,16 +116,30 @@ type RStr interface {
 	SetNotExists(key string, value any, ttl time.Duration) (bool, error)
 	SetExists(key string, value any, ttl time.Duration) (bool, error)
 	GetSet(key string, value any, ttl time.Duration) (core.Value, error)
+	GetEx(key string, ttl time.Duration) (core.Value, error)
+	GetDel(key string) (core.Value, error)
+	SetWith(key string, value any) rstring.SetCmd
 	SetMany(items map[string]any) error
 	SetManyNX(items map[string]any) (bool, error)
 	Incr(key string, delta int) (int, error)
 	IncrFloat(key string, delta float64) (float64, error)
+	GetBit(key string, offset int) (bool, error)
+	SetBit(key string, offset int, value bool) (bool, error)
+	BitCount(key string, start, end int, unit rstring.BitUnit) (int, error)
+	BitOp(op rstring.BitwiseOp, dest string, keys ...string) (int, error)
+	Append(key string, value any) (int, error)
+	SetRange(key string, offset int, value any) (int, error)
+	GetRange(key string, start, end int) (core.Value, error)
 }
 
 // RHash is a hash repository.
 type RHash interface {
 	Delete(key string, fields ...string) (int, error)
 	Exists(key, field string) (bool, error)
+	FieldExpire(key string, ttl time.Duration, fields ...string) (map[string]bool, error)
+	FieldExpireAt(key string, at time.Time, fields ...string) (map[string]bool, error)
+	FieldPersist(key string, fields ...string) (map[string]bool, error)
+	FieldTTL(key string, fields ...string) (map[string]time.Duration, error)
 	Fields(key string) ([]string, error)
 	Get(key, field string) (core.Value, error)
 	GetMany(key string, fields ...string) (map[string]core.Value, error)
@@ -105,6 +147,7 @@ type RHash interface {
 	IncrFloat(key, field string, delta float64) (float64, error)
 	Items(key string) (map[string]core.Value, error)
 	Len(key string) (int, error)
+	RandField(key string, count int, withValues bool) ([]rhash.HashItem, error)
 	Scan(key string, cursor int, pattern string, pageSize int) (rhash.ScanResult, error)
 	Scanner(key, pattern string, pageSize int) *rhash.Scanner
 	Set(key, field string, value any) (bool, error)
@@ -113,32 +156,83 @@ type RHash interface {
 	Values(key string) ([]core.Value, error)
 }
 
+// RStream is a stream repository.
+type RStream interface {
+	Add(key string, fields ...any) (rstream.ID, error)
+	AddID(key string, id rstream.ID, fields ...any) error
+	Len(key string) (int, error)
+	Range(key string, start, end rstream.ID, count int) ([]rstream.Entry, error)
+	RevRange(key string, end, start rstream.ID, count int) ([]rstream.Entry, error)
+	Read(key string, after rstream.ID, count int) ([]rstream.Entry, error)
+}
+
+// RHLL is a HyperLogLog repository.
+type RHLL interface {
+	Add(key string, elems ...string) (bool, error)
+	Count(keys ...string) (int, error)
+	Merge(dest string, keys ...string) error
+}
+
+// RProc is a procedure registry.
+type RProc interface {
+	Call(name string, args ...string) (any, error)
+}
+
 // Redka is an abstraction for *redka.DB and *redka.Tx.
 // Used to execute commands in a unified way.
 type Redka struct {
-	key  RKey
-	str  RStr
-	hash RHash
+	key    RKey
+	str    RStr
+	hash   RHash
+	stream RStream
+	hll    RHLL
+	proc   RProc
 }
 
 // RedkaDB creates a new Redka instance for a database.
 func RedkaDB(db *redka.DB) Redka {
 	return Redka{
-		key:  db.Key(),
-		str:  db.Str(),
-		hash: db.Hash(),
+		key:    db.Key(),
+		str:    db.Str(),
+		hash:   db.Hash(),
+		stream: db.Stream(),
+		hll:    db.HLL(),
+		proc:   db,
 	}
 }
 
 // RedkaTx creates a new Redka instance for a transaction.
 func RedkaTx(tx *redka.Tx) Redka {
 	return Redka{
-		key:  tx.Key(),
-		str:  tx.Str(),
-		hash: tx.Hash(),
+		key:    tx.Key(),
+		str:    tx.Str(),
+		hash:   tx.Hash(),
+		stream: tx.Stream(),
+		hll:    tx.HLL(),
 	}
 }
 
+// RedkaShards creates a new Redka instance for a sharded database.
+// Multi-key commands fail with ErrCrossSlot if the keys are in
+// different slots.
+func RedkaShards(s *redka.Shards) Redka {
+	return Redka{
+		key:    s.Key(),
+		str:    s.Str(),
+		hash:   s.Hash(),
+		stream: s.Stream(),
+		hll:    s.HLL(),
+	}
+}
+
+// WithProcs returns a copy of r that calls the procedures
+// registered in db within the transaction tx (see redka.DB.CallTx).
+// Without it, a transaction instance does not support FCALL.
+func (r Redka) WithProcs(db *redka.DB, tx *redka.Tx) Redka {
+	r.proc = txProcs{db: db, tx: tx}
+	return r
+}
+
 // Key returns the key repository.
 func (r Redka) Key() RKey {
 	return r.key
@@ -154,6 +248,31 @@ func (r Redka) Hash() RHash {
 	return r.hash
 }
 
+// Stream returns the stream repository.
+func (r Redka) Stream() RStream {
+	return r.stream
+}
+
+// HLL returns the HyperLogLog repository.
+func (r Redka) HLL() RHLL {
+	return r.hll
+}
+
+// Proc returns the procedure registry.
+func (r Redka) Proc() RProc {
+	return r.proc
+}
+
+// txProcs calls the procedures within a transaction.
+type txProcs struct {
+	db *redka.DB
+	tx *redka.Tx
+}
+
+func (p txProcs) Call(name string, args ...string) (any, error) {
+	return p.db.CallTx(p.tx, name, args...)
+}
+
 type baseCmd struct {
 	name string
 	args [][]byte
@@ -167,11 +286,33 @@ func newBaseCmd(args [][]byte) baseCmd {
 }
 
 func (cmd baseCmd) Error(err error) string {
-	switch err {
-	case core.ErrNotFound:
+	switch {
+	case errors.Is(err, core.ErrNotFound):
 		err = ErrNotFound
-	case core.ErrKeyType:
+	case errors.Is(err, core.ErrKeyType):
 		err = ErrKeyType
+	case errors.Is(err, core.ErrBusy):
+		err = ErrBusy
+	case errors.Is(err, core.ErrValueTooLarge):
+		err = ErrValueTooLarge
+	case errors.Is(err, core.ErrTxClosed):
+		err = ErrTxClosed
+	case errors.Is(err, core.ErrSyntax):
+		err = ErrSyntaxError
+	case errors.Is(err, core.ErrReadOnly):
+		err = ErrReadOnly
+	case errors.Is(err, core.ErrKeyTooLarge):
+		err = ErrKeyTooLarge
+	case errors.Is(err, core.ErrTooManyElements):
+		err = ErrTooManyElements
+	case errors.Is(err, core.ErrCrossSlot):
+		err = ErrCrossSlot
+	case errors.Is(err, core.ErrQuotaExceeded):
+		err = ErrQuotaExceeded
+	case errors.Is(err, core.ErrStreamID):
+		err = ErrStreamID
+	case errors.Is(err, redka.ErrProcNotFound):
+		err = ErrFuncNotFound
 	}
 	return fmt.Sprintf("%s (%s)", err, cmd.Name())
 }
@@ -179,6 +320,9 @@ func (cmd baseCmd) Error(err error) string {
 func (cmd baseCmd) Name() string {
 	return cmd.name
 }
+func (cmd baseCmd) Args() [][]byte {
+	return cmd.args
+}
 func (cmd baseCmd) String() string {
 	var b strings.Builder
 	for i, arg := range cmd.args {
@@ -190,6 +334,95 @@ func (cmd baseCmd) String() string {
 	return b.String()
 }
 
+// writeCmds are the commands that modify the data.
+var writeCmds = map[string]bool{
+	"flushdb":      true,
+	"copy":         true,
+	"del":          true,
+	"expire":       true,
+	"expireat":     true,
+	"persist":      true,
+	"pexpire":      true,
+	"pexpireat":    true,
+	"rename":       true,
+	"renamenx":     true,
+	"unlink":       true,
+	"append":       true,
+	"bitop":        true,
+	"decr":         true,
+	"decrby":       true,
+	"getdel":       true,
+	"getex":        true,
+	"getset":       true,
+	"incr":         true,
+	"incrby":       true,
+	"incrbyfloat":  true,
+	"mset":         true,
+	"msetnx":       true,
+	"psetex":       true,
+	"set":          true,
+	"setbit":       true,
+	"setex":        true,
+	"setnx":        true,
+	"setrange":     true,
+	"hdel":         true,
+	"hexpire":      true,
+	"hexpireat":    true,
+	"hincrby":      true,
+	"hincrbyfloat": true,
+	"hmset":        true,
+	"hpersist":     true,
+	"hpexpire":     true,
+	"hpexpireat":   true,
+	"hset":         true,
+	"hsetnx":       true,
+	"xadd":         true,
+	"pfadd":        true,
+	"pfmerge":      true,
+	"fcall":        true,
+}
+
+// IsWrite reports whether the command with the given name
+// modifies the data.
+func IsWrite(name string) bool {
+	return writeCmds[strings.ToLower(name)]
+}
+
+// names are the names of the supported commands.
+var names = []string{
+	// server
+	"command", "flushdb", "info",
+	// connection
+	"echo", "ping",
+	// key
+	"copy", "del", "exists", "expire", "expireat", "expiretime", "keys", "persist",
+	"pexpire", "pexpireat", "pexpiretime", "pttl", "randomkey", "rename", "renamenx",
+	"scan", "ttl", "type", "unlink",
+	// string
+	"append", "bitcount", "bitop", "decr", "decrby", "get", "getbit", "getdel",
+	"getex", "getrange", "getset", "incr", "incrby", "incrbyfloat", "mget", "mset",
+	"msetnx", "psetex", "set", "setbit", "setex", "setnx", "setrange",
+	// hash
+	"hdel", "hexists", "hexpire", "hexpireat", "hget", "hgetall", "hincrby",
+	"hincrbyfloat", "hkeys", "hlen", "hmget", "hmset", "hpersist", "hpexpire",
+	"hpexpireat", "hpttl",
+	"hrandfield", "hscan", "hset", "hsetnx", "httl", "hvals",
+	// stream
+	"xadd", "xlen", "xrange", "xread", "xrevrange",
+	// hyperloglog
+	"pfadd", "pfcount", "pfmerge",
+	// scripting
+	"fcall",
+	// transaction
+	"discard", "exec", "multi", "pwatch", "unwatch", "watch",
+}
+
+// Names returns the names of the supported commands in lowercase,
+// including the transaction commands handled by the server.
+func Names() []string {
+	return slices.Clone(names)
+}
+
 // Parse parses a text representation of a command into a Cmd.
 func Parse(args [][]byte) (Cmd, error) {
 	name := strings.ToLower(string(args[0]))
@@ -206,8 +439,12 @@ func Parse(args [][]byte) (Cmd, error) {
 	// connection
 	case "echo":
 		return parseEcho(b)
+	case "ping":
+		return parsePing(b)
 
 	// key
+	case "copy":
+		return parseCopy(b)
 	case "del":
 		return parseDel(b)
 	case "exists":
@@ -216,6 +453,8 @@ func Parse(args [][]byte) (Cmd, error) {
 		return parseExpire(b, 1000)
 	case "expireat":
 		return parseExpireAt(b, 1000)
+	case "expiretime":
+		return parseExpireTime(b, time.Second)
 	case "keys":
 		return parseKeys(b)
 	case "persist":
@@ -224,6 +463,10 @@ func Parse(args [][]byte) (Cmd, error) {
 		return parseExpire(b, 1)
 	case "pexpireat":
 		return parseExpireAt(b, 1)
+	case "pexpiretime":
+		return parseExpireTime(b, time.Millisecond)
+	case "pttl":
+		return parseTTL(b, time.Millisecond)
 	case "randomkey":
 		return parseRandomKey(b)
 	case "rename":
@@ -232,14 +475,34 @@ func Parse(args [][]byte) (Cmd, error) {
 		return parseRenameNX(b)
 	case "scan":
 		return parseScan(b)
+	case "ttl":
+		return parseTTL(b, time.Second)
+	case "type":
+		return parseType(b)
+	case "unlink":
+		return parseUnlink(b)
 
 	// string
+	case "append":
+		return parseAppend(b)
+	case "bitcount":
+		return parseBitCount(b)
+	case "bitop":
+		return parseBitOp(b)
 	case "decr":
 		return parseIncr(b, -1)
 	case "decrby":
 		return parseIncrBy(b, -1)
 	case "get":
 		return parseGet(b)
+	case "getbit":
+		return parseGetBit(b)
+	case "getdel":
+		return parseGetDel(b)
+	case "getex":
+		return parseGetEx(b)
+	case "getrange":
+		return parseGetRange(b)
 	case "getset":
 		return parseGetSet(b)
 	case "incr":
@@ -258,16 +521,24 @@ func Parse(args [][]byte) (Cmd, error) {
 		return parseSetEX(b, 1)
 	case "set":
 		return parseSet(b)
+	case "setbit":
+		return parseSetBit(b)
 	case "setex":
 		return parseSetEX(b, 1000)
 	case "setnx":
 		return parseSetNX(b)
+	case "setrange":
+		return parseSetRange(b)
 
 	// hash
 	case "hdel":
 		return parseHDel(b)
 	case "hexists":
 		return parseHExists(b)
+	case "hexpire":
+		return parseHExpire(b, 1000)
+	case "hexpireat":
+		return parseHExpireAt(b, 1000)
 	case "hget":
 		return parseHGet(b)
 	case "hgetall":
@@ -284,15 +555,51 @@ func Parse(args [][]byte) (Cmd, error) {
 		return parseHMGet(b)
 	case "hmset":
 		return parseHMSet(b)
+	case "hpersist":
+		return parseHPersist(b)
+	case "hpexpire":
+		return parseHExpire(b, 1)
+	case "hpexpireat":
+		return parseHExpireAt(b, 1)
+	case "hpttl":
+		return parseHTTL(b, time.Millisecond)
+	case "hrandfield":
+		return parseHRandField(b)
 	case "hscan":
 		return parseHScan(b)
 	case "hset":
 		return parseHSet(b)
 	case "hsetnx":
 		return parseHSetNX(b)
+	case "httl":
+		return parseHTTL(b, time.Second)
 	case "hvals":
 		return parseHVals(b)
 
+	// stream
+	case "xadd":
+		return parseXAdd(b)
+	case "xlen":
+		return parseXLen(b)
+	case "xrange":
+		return parseXRange(b, false)
+	case "xread":
+		return parseXRead(b)
+	case "xrevrange":
+		return parseXRange(b, true)
+
+	// hyperloglog
+	case "pfadd":
+		return parsePFAdd(b)
+	case "pfcount":
+		return parsePFCount(b)
+	case "pfmerge":
+		return parsePFMerge(b)
+
+	// scripting
+	case "fcall":
+		return parseFCall(b)
+
 	default:
 		return parseUnknown(b)
 	}
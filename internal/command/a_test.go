@@ -1,6 +1,8 @@
 package command
 
 import (
+	"errors"
+	"fmt"
 	"net"
 	"strconv"
 	"strings"
@@ -8,6 +10,8 @@ import (
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
 	"github.com/tidwall/redcon"
 )
 
@@ -108,3 +112,41 @@ func (c *fakeConn) append(str string) {
 func (c *fakeConn) out() string {
 	return strings.Join(c.parts, ",")
 }
+
+func TestBaseCmdError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{core.ErrNotFound, "ERR no such key (get)"},
+		{&core.KeyTypeError{Key: "name"}, "WRONGTYPE Operation against a key holding the wrong kind of value (get)"},
+		{fmt.Errorf("%w: locked", core.ErrBusy), "BUSY database is busy, try again later (get)"},
+		{fmt.Errorf("%w: too big", core.ErrValueTooLarge), "ERR value is too large (get)"},
+		{core.ErrTxClosed, "ERR transaction is closed (get)"},
+		{core.ErrSyntax, "ERR syntax error (get)"},
+		{fmt.Errorf("%w: 11 > 10 bytes", core.ErrKeyTooLarge), "ERR key is too large (get)"},
+		{core.ErrTooManyElements, "ERR too many elements (get)"},
+		{core.ErrStreamID, "ERR The ID specified in XADD is equal or smaller than the target stream top item (get)"},
+		{errors.New("boom"), "boom (get)"},
+	}
+	cmd := newBaseCmd(buildArgs("get", "name"))
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			testx.AssertEqual(t, cmd.Error(test.err), test.want)
+		})
+	}
+}
+
+func TestNames(t *testing.T) {
+	for _, name := range Names() {
+		switch name {
+		case "multi", "exec", "discard", "watch", "pwatch", "unwatch":
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			cmd, _ := Parse(buildArgs(name))
+			_, unknown := cmd.(*Unknown)
+			testx.AssertEqual(t, unknown, false)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestAppendParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want Append
+		err  error
+	}{
+		{
+			name: "append",
+			args: buildArgs("append"),
+			want: Append{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "append key",
+			args: buildArgs("append", "key"),
+			want: Append{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "append key value",
+			args: buildArgs("append", "key", "value"),
+			want: Append{key: "key", value: []byte("value")},
+			err:  nil,
+		},
+		{
+			name: "append key value 1",
+			args: buildArgs("append", "key", "value", "1"),
+			want: Append{},
+			err:  ErrInvalidArgNum,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*Append)
+				testx.AssertEqual(t, cm.key, test.want.key)
+				testx.AssertEqual(t, cm.value, test.want.value)
+			}
+		})
+	}
+}
+
+func TestAppendExec(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*Append]("append key hello")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 5)
+		testx.AssertEqual(t, conn.out(), "5")
+
+		val, _ := db.Str().Get("key")
+		testx.AssertEqual(t, val, core.Value("hello"))
+	})
+	t.Run("update", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("key", "hello")
+
+		cmd := mustParse[*Append]("append key world")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 10)
+		testx.AssertEqual(t, conn.out(), "10")
+
+		val, _ := db.Str().Get("key")
+		testx.AssertEqual(t, val, core.Value("helloworld"))
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().Set("key", "field", "value")
+
+		cmd := mustParse[*Append]("append key value")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, res, nil)
+		testx.AssertEqual(t, conn.out(), ErrKeyType.Error()+" (append)")
+	})
+}
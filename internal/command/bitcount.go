@@ -0,0 +1,66 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nalgeon/redka/internal/rstring"
+)
+
+// Counts the number of set bits (population counting) in a string.
+// BITCOUNT key [start end [BYTE | BIT]]
+// https://redis.io/commands/bitcount
+type BitCount struct {
+	baseCmd
+	key   string
+	start int
+	end   int
+	unit  rstring.BitUnit
+}
+
+func parseBitCount(b baseCmd) (*BitCount, error) {
+	cmd := &BitCount{baseCmd: b, end: -1}
+	switch len(cmd.args) {
+	case 1, 3, 4:
+	case 2:
+		// The range must have both start and end.
+		return cmd, ErrSyntaxError
+	default:
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	if len(cmd.args) == 1 {
+		return cmd, nil
+	}
+
+	var err error
+	cmd.start, err = strconv.Atoi(string(cmd.args[1]))
+	if err != nil {
+		return cmd, ErrInvalidInt
+	}
+	cmd.end, err = strconv.Atoi(string(cmd.args[2]))
+	if err != nil {
+		return cmd, ErrInvalidInt
+	}
+	if len(cmd.args) == 4 {
+		switch strings.ToLower(string(cmd.args[3])) {
+		case "byte":
+			cmd.unit = rstring.Byte
+		case "bit":
+			cmd.unit = rstring.Bit
+		default:
+			return cmd, ErrSyntaxError
+		}
+	}
+	return cmd, nil
+}
+
+func (cmd *BitCount) Run(w Writer, red Redka) (any, error) {
+	count, err := red.Str().BitCount(cmd.key, cmd.start, cmd.end, cmd.unit)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	w.WriteInt(count)
+	return count, nil
+}
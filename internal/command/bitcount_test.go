@@ -0,0 +1,109 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/rstring"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestBitCountParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want BitCount
+		err  error
+	}{
+		{
+			name: "bitcount",
+			args: buildArgs("bitcount"),
+			want: BitCount{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "bitcount key",
+			args: buildArgs("bitcount", "key"),
+			want: BitCount{key: "key", start: 0, end: -1, unit: rstring.Byte},
+			err:  nil,
+		},
+		{
+			name: "bitcount key 1",
+			args: buildArgs("bitcount", "key", "1"),
+			want: BitCount{},
+			err:  ErrSyntaxError,
+		},
+		{
+			name: "bitcount key 1 2",
+			args: buildArgs("bitcount", "key", "1", "2"),
+			want: BitCount{key: "key", start: 1, end: 2, unit: rstring.Byte},
+			err:  nil,
+		},
+		{
+			name: "bitcount key 5 30 bit",
+			args: buildArgs("bitcount", "key", "5", "30", "bit"),
+			want: BitCount{key: "key", start: 5, end: 30, unit: rstring.Bit},
+			err:  nil,
+		},
+		{
+			name: "bitcount key 1 2 byte",
+			args: buildArgs("bitcount", "key", "1", "2", "byte"),
+			want: BitCount{key: "key", start: 1, end: 2, unit: rstring.Byte},
+			err:  nil,
+		},
+		{
+			name: "bitcount key 1 2 word",
+			args: buildArgs("bitcount", "key", "1", "2", "word"),
+			want: BitCount{},
+			err:  ErrSyntaxError,
+		},
+		{
+			name: "bitcount key x 2",
+			args: buildArgs("bitcount", "key", "x", "2"),
+			want: BitCount{},
+			err:  ErrInvalidInt,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*BitCount)
+				testx.AssertEqual(t, cm.key, test.want.key)
+				testx.AssertEqual(t, cm.start, test.want.start)
+				testx.AssertEqual(t, cm.end, test.want.end)
+				testx.AssertEqual(t, cm.unit, test.want.unit)
+			}
+		})
+	}
+}
+
+func TestBitCountExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+
+	_ = db.Str().Set("key", "foobar")
+
+	tests := []struct {
+		cmd  string
+		want int
+	}{
+		{"bitcount key", 26},
+		{"bitcount key 0 0", 4},
+		{"bitcount key 1 1", 6},
+		{"bitcount key 1 1 bit", 1},
+		{"bitcount key 5 30 bit", 17},
+		{"bitcount other", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.cmd, func(t *testing.T) {
+			cmd := mustParse[*BitCount](test.cmd)
+			conn := new(fakeConn)
+			res, err := cmd.Run(conn, red)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, res, test.want)
+		})
+	}
+}
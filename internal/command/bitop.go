@@ -0,0 +1,56 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/nalgeon/redka/internal/rstring"
+)
+
+// Performs bitwise operations on multiple strings,
+// and stores the result.
+// BITOP <AND | OR | XOR | NOT> destkey key [key ...]
+// https://redis.io/commands/bitop
+type BitOp struct {
+	baseCmd
+	op   rstring.BitwiseOp
+	dest string
+	keys []string
+}
+
+func parseBitOp(b baseCmd) (*BitOp, error) {
+	cmd := &BitOp{baseCmd: b}
+	if len(cmd.args) < 3 {
+		return cmd, ErrInvalidArgNum
+	}
+	switch strings.ToLower(string(cmd.args[0])) {
+	case "and":
+		cmd.op = rstring.BitAnd
+	case "or":
+		cmd.op = rstring.BitOr
+	case "xor":
+		cmd.op = rstring.BitXor
+	case "not":
+		cmd.op = rstring.BitNot
+		if len(cmd.args) != 3 {
+			return cmd, ErrBitNotArgNum
+		}
+	default:
+		return cmd, ErrSyntaxError
+	}
+	cmd.dest = string(cmd.args[1])
+	cmd.keys = make([]string, len(cmd.args)-2)
+	for i, arg := range cmd.args[2:] {
+		cmd.keys[i] = string(arg)
+	}
+	return cmd, nil
+}
+
+func (cmd *BitOp) Run(w Writer, red Redka) (any, error) {
+	n, err := red.Str().BitOp(cmd.op, cmd.dest, cmd.keys...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	w.WriteInt(n)
+	return n, nil
+}
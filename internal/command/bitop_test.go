@@ -0,0 +1,123 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rstring"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestBitOpParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want BitOp
+		err  error
+	}{
+		{
+			name: "bitop",
+			args: buildArgs("bitop"),
+			want: BitOp{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "bitop and dest",
+			args: buildArgs("bitop", "and", "dest"),
+			want: BitOp{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "bitop and dest key1 key2",
+			args: buildArgs("bitop", "and", "dest", "key1", "key2"),
+			want: BitOp{op: rstring.BitAnd, dest: "dest", keys: []string{"key1", "key2"}},
+			err:  nil,
+		},
+		{
+			name: "bitop OR dest key1",
+			args: buildArgs("bitop", "OR", "dest", "key1"),
+			want: BitOp{op: rstring.BitOr, dest: "dest", keys: []string{"key1"}},
+			err:  nil,
+		},
+		{
+			name: "bitop xor dest key1 key2",
+			args: buildArgs("bitop", "xor", "dest", "key1", "key2"),
+			want: BitOp{op: rstring.BitXor, dest: "dest", keys: []string{"key1", "key2"}},
+			err:  nil,
+		},
+		{
+			name: "bitop not dest key1",
+			args: buildArgs("bitop", "not", "dest", "key1"),
+			want: BitOp{op: rstring.BitNot, dest: "dest", keys: []string{"key1"}},
+			err:  nil,
+		},
+		{
+			name: "bitop not dest key1 key2",
+			args: buildArgs("bitop", "not", "dest", "key1", "key2"),
+			want: BitOp{},
+			err:  ErrBitNotArgNum,
+		},
+		{
+			name: "bitop nand dest key1",
+			args: buildArgs("bitop", "nand", "dest", "key1"),
+			want: BitOp{},
+			err:  ErrSyntaxError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*BitOp)
+				testx.AssertEqual(t, cm.op, test.want.op)
+				testx.AssertEqual(t, cm.dest, test.want.dest)
+				testx.AssertEqual(t, cm.keys, test.want.keys)
+			}
+		})
+	}
+}
+
+func TestBitOpExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+
+	_ = db.Str().Set("key1", "foobar")
+	_ = db.Str().Set("key2", "abcdef")
+
+	t.Run("and", func(t *testing.T) {
+		cmd := mustParse[*BitOp]("bitop and dest key1 key2")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 6)
+		testx.AssertEqual(t, conn.out(), "6")
+
+		val, _ := db.Str().Get("dest")
+		testx.AssertEqual(t, val, core.Value("`bc`ab"))
+	})
+	t.Run("not", func(t *testing.T) {
+		_ = db.Str().Set("key3", "\x0f")
+
+		cmd := mustParse[*BitOp]("bitop not dest key3")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 1)
+
+		val, _ := db.Str().Get("dest")
+		testx.AssertEqual(t, val, core.Value("\xf0"))
+	})
+	t.Run("keys not found", func(t *testing.T) {
+		cmd := mustParse[*BitOp]("bitop or dest other1 other2")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 0)
+		testx.AssertEqual(t, conn.out(), "0")
+
+		exists, _ := db.Key().Exists("dest")
+		testx.AssertEqual(t, exists, false)
+	})
+}
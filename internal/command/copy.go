@@ -0,0 +1,49 @@
+package command
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// Copies the value of a key to a new key.
+// COPY source destination [REPLACE]
+// https://redis.io/commands/copy
+type Copy struct {
+	baseCmd
+	key     string
+	newKey  string
+	replace bool
+}
+
+func parseCopy(b baseCmd) (*Copy, error) {
+	cmd := &Copy{baseCmd: b}
+	if len(cmd.args) != 2 && len(cmd.args) != 3 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	cmd.newKey = string(cmd.args[1])
+	if len(cmd.args) == 3 {
+		// The DB option is not supported.
+		if strings.ToLower(string(cmd.args[2])) != "replace" {
+			return cmd, ErrSyntaxError
+		}
+		cmd.replace = true
+	}
+	return cmd, nil
+}
+
+func (cmd *Copy) Run(w Writer, red Redka) (any, error) {
+	ok, err := red.Key().Copy(cmd.key, cmd.newKey, cmd.replace)
+	if err != nil && !errors.Is(err, core.ErrNotFound) {
+		w.WriteError(cmd.Error(err))
+		return false, err
+	}
+	if ok {
+		w.WriteInt(1)
+	} else {
+		w.WriteInt(0)
+	}
+	return ok, nil
+}
@@ -0,0 +1,161 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestCopyParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    [][]byte
+		key     string
+		newKey  string
+		replace bool
+		err     error
+	}{
+		{
+			name: "copy",
+			args: buildArgs("copy"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "copy name",
+			args: buildArgs("copy", "name"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name:   "copy name title",
+			args:   buildArgs("copy", "name", "title"),
+			key:    "name",
+			newKey: "title",
+			err:    nil,
+		},
+		{
+			name:    "copy name title replace",
+			args:    buildArgs("copy", "name", "title", "replace"),
+			key:     "name",
+			newKey:  "title",
+			replace: true,
+			err:     nil,
+		},
+		{
+			name: "copy name title db 1",
+			args: buildArgs("copy", "name", "title", "db", "1"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "copy name title other",
+			args: buildArgs("copy", "name", "title", "other"),
+			err:  ErrSyntaxError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				testx.AssertEqual(t, cmd.(*Copy).key, test.key)
+				testx.AssertEqual(t, cmd.(*Copy).newKey, test.newKey)
+				testx.AssertEqual(t, cmd.(*Copy).replace, test.replace)
+			}
+		})
+	}
+}
+
+func TestCopyExec(t *testing.T) {
+	t.Run("create new", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", 60*time.Second)
+
+		cmd := mustParse[*Copy]("copy name title")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, true)
+		testx.AssertEqual(t, conn.out(), "1")
+
+		val, _ := db.Str().Get("name")
+		testx.AssertEqual(t, val.String(), "alice")
+		val, _ = db.Str().Get("title")
+		testx.AssertEqual(t, val.String(), "alice")
+		key, _ := db.Key().Get("title")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+
+	t.Run("hash", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_, _ = db.Hash().Set("person", "name", "alice")
+		_, _ = db.Hash().Set("person", "age", 25)
+
+		cmd := mustParse[*Copy]("copy person user")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, true)
+		testx.AssertEqual(t, conn.out(), "1")
+
+		n, _ := db.Hash().Len("user")
+		testx.AssertEqual(t, n, 2)
+		val, _ := db.Hash().Get("user", "name")
+		testx.AssertEqual(t, val.String(), "alice")
+	})
+
+	t.Run("keep existing", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().Set("name", "alice")
+		_ = db.Str().Set("title", "bob")
+
+		cmd := mustParse[*Copy]("copy name title")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, false)
+		testx.AssertEqual(t, conn.out(), "0")
+
+		val, _ := db.Str().Get("title")
+		testx.AssertEqual(t, val.String(), "bob")
+	})
+
+	t.Run("replace existing", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().Set("name", "alice")
+		_, _ = db.Hash().Set("title", "name", "bob")
+
+		cmd := mustParse[*Copy]("copy name title replace")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, true)
+		testx.AssertEqual(t, conn.out(), "1")
+
+		val, _ := db.Str().Get("title")
+		testx.AssertEqual(t, val.String(), "alice")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*Copy]("copy name title")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, false)
+		testx.AssertEqual(t, conn.out(), "0")
+
+		key, _ := db.Key().Get("title")
+		testx.AssertEqual(t, key.Exists(), false)
+	})
+}
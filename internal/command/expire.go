@@ -2,21 +2,25 @@ package command
 
 import (
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/nalgeon/redka/internal/rkey"
 )
 
 // Sets the expiration time of a key in seconds.
-// EXPIRE key seconds
+// EXPIRE key seconds [NX | XX | GT | LT]
 // https://redis.io/commands/expire
 type Expire struct {
 	baseCmd
-	key string
-	ttl time.Duration
+	key  string
+	ttl  time.Duration
+	cond string
 }
 
 func parseExpire(b baseCmd, multi int) (*Expire, error) {
 	cmd := &Expire{baseCmd: b}
-	if len(cmd.args) != 2 {
+	if len(cmd.args) != 2 && len(cmd.args) != 3 {
 		return cmd, ErrInvalidArgNum
 	}
 	cmd.key = string(cmd.args[0])
@@ -25,11 +29,24 @@ func parseExpire(b baseCmd, multi int) (*Expire, error) {
 		return cmd, ErrInvalidInt
 	}
 	cmd.ttl = time.Duration(multi*ttl) * time.Millisecond
+	if len(cmd.args) == 3 {
+		cmd.cond, err = parseExpireCond(cmd.args[2])
+		if err != nil {
+			return cmd, err
+		}
+	}
 	return cmd, nil
 }
 
 func (cmd *Expire) Run(w Writer, red Redka) (any, error) {
-	ok, err := red.Key().Expire(cmd.key, cmd.ttl)
+	var ok bool
+	var err error
+	if cmd.cond == "" {
+		ok, err = red.Key().Expire(cmd.key, cmd.ttl)
+	} else {
+		c := red.Key().ExpireWith(cmd.key).TTL(cmd.ttl)
+		ok, err = withExpireCond(c, cmd.cond).Run()
+	}
 	if err != nil {
 		w.WriteError(cmd.Error(err))
 		return nil, err
@@ -41,3 +58,29 @@ func (cmd *Expire) Run(w Writer, red Redka) (any, error) {
 	}
 	return ok, nil
 }
+
+// parseExpireCond parses the condition
+// of the EXPIRE family commands.
+func parseExpireCond(arg []byte) (string, error) {
+	cond := strings.ToLower(string(arg))
+	switch cond {
+	case "nx", "xx", "gt", "lt":
+		return cond, nil
+	}
+	return "", ErrSyntaxError
+}
+
+// withExpireCond adds the NX, XX, GT or LT condition to the command.
+func withExpireCond(c rkey.ExpireCmd, cond string) rkey.ExpireCmd {
+	switch cond {
+	case "nx":
+		return c.IfNoTTL()
+	case "xx":
+		return c.IfTTL()
+	case "gt":
+		return c.IfGreater()
+	case "lt":
+		return c.IfLess()
+	}
+	return c
+}
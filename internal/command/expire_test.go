@@ -13,6 +13,7 @@ func TestExpireParse(t *testing.T) {
 		args [][]byte
 		key  string
 		ttl  time.Duration
+		cond string
 		err  error
 	}{
 		{
@@ -43,6 +44,29 @@ func TestExpireParse(t *testing.T) {
 			ttl:  0,
 			err:  ErrInvalidInt,
 		},
+		{
+			name: "expire name 60 nx",
+			args: buildArgs("expire", "name", "60", "nx"),
+			key:  "name",
+			ttl:  60 * 1000 * time.Millisecond,
+			cond: "nx",
+			err:  nil,
+		},
+		{
+			name: "expire name 60 GT",
+			args: buildArgs("expire", "name", "60", "GT"),
+			key:  "name",
+			ttl:  60 * 1000 * time.Millisecond,
+			cond: "gt",
+			err:  nil,
+		},
+		{
+			name: "expire name 60 age",
+			args: buildArgs("expire", "name", "60", "age"),
+			key:  "",
+			ttl:  0,
+			err:  ErrSyntaxError,
+		},
 		{
 			name: "expire name 60 age 60",
 			args: buildArgs("expire", "name", "60", "age", "60"),
@@ -59,6 +83,7 @@ func TestExpireParse(t *testing.T) {
 			if err == nil {
 				testx.AssertEqual(t, cmd.(*Expire).key, test.key)
 				testx.AssertEqual(t, cmd.(*Expire).ttl, test.ttl)
+				testx.AssertEqual(t, cmd.(*Expire).cond, test.cond)
 			}
 		})
 	}
@@ -101,6 +126,57 @@ func TestExpireExec(t *testing.T) {
 		testx.AssertEqual(t, *key.ETime/1000, expireAt.UnixMilli()/1000)
 	})
 
+	t.Run("nx", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", 60*time.Second)
+		_ = db.Str().Set("age", 25)
+
+		cmd := mustParse[*Expire]("expire name 30 nx")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, false)
+		testx.AssertEqual(t, conn.out(), "0")
+
+		cmd = mustParse[*Expire]("expire age 30 nx")
+		conn = new(fakeConn)
+		res, err = cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, true)
+		testx.AssertEqual(t, conn.out(), "1")
+
+		expireAt := time.Now().Add(60 * time.Second)
+		key, _ := db.Key().Get("name")
+		testx.AssertEqual(t, *key.ETime/1000, expireAt.UnixMilli()/1000)
+	})
+
+	t.Run("gt", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", 60*time.Second)
+
+		cmd := mustParse[*Expire]("expire name 30 gt")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, false)
+		testx.AssertEqual(t, conn.out(), "0")
+
+		cmd = mustParse[*Expire]("expire name 90 gt")
+		conn = new(fakeConn)
+		res, err = cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, true)
+		testx.AssertEqual(t, conn.out(), "1")
+
+		expireAt := time.Now().Add(90 * time.Second)
+		key, _ := db.Key().Get("name")
+		testx.AssertEqual(t, *key.ETime/1000, expireAt.UnixMilli()/1000)
+	})
+
 	t.Run("set to zero", func(t *testing.T) {
 		db, red := getDB(t)
 		defer db.Close()
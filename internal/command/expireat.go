@@ -6,17 +6,18 @@ import (
 )
 
 // Sets the expiration time of a key to a Unix timestamp.
-// EXPIREAT key unix-time-seconds
+// EXPIREAT key unix-time-seconds [NX | XX | GT | LT]
 // https://redis.io/commands/expireat
 type ExpireAt struct {
 	baseCmd
-	key string
-	at  time.Time
+	key  string
+	at   time.Time
+	cond string
 }
 
 func parseExpireAt(b baseCmd, multi int) (*ExpireAt, error) {
 	cmd := &ExpireAt{baseCmd: b}
-	if len(cmd.args) != 2 {
+	if len(cmd.args) != 2 && len(cmd.args) != 3 {
 		return cmd, ErrInvalidArgNum
 	}
 	cmd.key = string(cmd.args[0])
@@ -25,11 +26,24 @@ func parseExpireAt(b baseCmd, multi int) (*ExpireAt, error) {
 		return cmd, ErrInvalidInt
 	}
 	cmd.at = time.UnixMilli(int64(multi * at))
+	if len(cmd.args) == 3 {
+		cmd.cond, err = parseExpireCond(cmd.args[2])
+		if err != nil {
+			return cmd, err
+		}
+	}
 	return cmd, nil
 }
 
 func (cmd *ExpireAt) Run(w Writer, red Redka) (any, error) {
-	ok, err := red.Key().ExpireAt(cmd.key, cmd.at)
+	var ok bool
+	var err error
+	if cmd.cond == "" {
+		ok, err = red.Key().ExpireAt(cmd.key, cmd.at)
+	} else {
+		c := red.Key().ExpireWith(cmd.key).At(cmd.at)
+		ok, err = withExpireCond(c, cmd.cond).Run()
+	}
 	if err != nil {
 		w.WriteError(cmd.Error(err))
 		return nil, err
@@ -44,6 +44,20 @@ func TestExpireAtParse(t *testing.T) {
 			at:   time.Time{},
 			err:  ErrInvalidInt,
 		},
+		{
+			name: "expireat name 60 xx",
+			args: buildArgs("expireat", "name", fmt.Sprintf("%d", time.Now().Add(60*time.Second).Unix()), "xx"),
+			key:  "name",
+			at:   time.Now().Add(60 * time.Second),
+			err:  nil,
+		},
+		{
+			name: "expireat name 60 age",
+			args: buildArgs("expireat", "name", "60", "age"),
+			key:  "",
+			at:   time.Time{},
+			err:  ErrSyntaxError,
+		},
 		{
 			name: "expireat name 60 age 60",
 			args: buildArgs("expireat", "name", "60", "age", "60"),
@@ -109,6 +123,31 @@ func TestExpireAtExec(t *testing.T) {
 		testx.AssertEqual(t, *key.ETime/1000, expireAt.Add(20*time.Second).UnixMilli()/1000)
 	})
 
+	t.Run("lt", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().Set("name", "alice")
+
+		expireAt := time.Now()
+		cmd := mustParse[*ExpireAt](fmt.Sprintf("expireat name %d lt", expireAt.Add(60*time.Second).Unix()))
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, true)
+		testx.AssertEqual(t, conn.out(), "1")
+
+		cmd = mustParse[*ExpireAt](fmt.Sprintf("expireat name %d lt", expireAt.Add(90*time.Second).Unix()))
+		conn = new(fakeConn)
+		res, err = cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, false)
+		testx.AssertEqual(t, conn.out(), "0")
+
+		key, _ := db.Key().Get("name")
+		testx.AssertEqual(t, *key.ETime/1000, expireAt.Add(60*time.Second).UnixMilli()/1000)
+	})
+
 	t.Run("set to zero", func(t *testing.T) {
 		db, red := getDB(t)
 		defer db.Close()
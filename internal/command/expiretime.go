@@ -0,0 +1,44 @@
+package command
+
+import (
+	"time"
+
+	"github.com/nalgeon/redka/internal/rkey"
+)
+
+// Returns the expiration time of a key as a Unix timestamp.
+// EXPIRETIME key
+// https://redis.io/commands/expiretime
+type ExpireTime struct {
+	baseCmd
+	key  string
+	unit time.Duration
+}
+
+func parseExpireTime(b baseCmd, unit time.Duration) (*ExpireTime, error) {
+	cmd := &ExpireTime{baseCmd: b, unit: unit}
+	if len(cmd.args) != 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	return cmd, nil
+}
+
+func (cmd *ExpireTime) Run(w Writer, red Redka) (any, error) {
+	at, err := red.Key().ExpireTime(cmd.key)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	var res int
+	switch {
+	case at.Equal(rkey.NoKeyTime):
+		res = -2
+	case at.Equal(rkey.NoExpireTime):
+		res = -1
+	default:
+		res = int(at.UnixMilli() / cmd.unit.Milliseconds())
+	}
+	w.WriteInt(res)
+	return res, nil
+}
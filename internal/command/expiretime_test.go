@@ -0,0 +1,95 @@
+package command
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestExpireTimeParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		key  string
+		unit time.Duration
+		err  error
+	}{
+		{
+			name: "expiretime",
+			args: buildArgs("expiretime"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "expiretime name",
+			args: buildArgs("expiretime", "name"),
+			key:  "name",
+			unit: time.Second,
+		},
+		{
+			name: "pexpiretime name",
+			args: buildArgs("pexpiretime", "name"),
+			key:  "name",
+			unit: time.Millisecond,
+		},
+		{
+			name: "expiretime name age",
+			args: buildArgs("expiretime", "name", "age"),
+			err:  ErrInvalidArgNum,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*ExpireTime)
+				testx.AssertEqual(t, cm.key, test.key)
+				testx.AssertEqual(t, cm.unit, test.unit)
+			}
+		})
+	}
+}
+
+func TestExpireTimeExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+	at := time.Now().Add(time.Minute)
+	_ = db.Str().Set("name", "alice")
+	_, _ = db.Key().ExpireAt("name", at)
+	_ = db.Str().Set("age", 25)
+
+	t.Run("expiretime", func(t *testing.T) {
+		cmd := mustParse[*ExpireTime]("expiretime name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, int(at.Unix()))
+		testx.AssertEqual(t, conn.out(), strconv.FormatInt(at.Unix(), 10))
+	})
+	t.Run("pexpiretime", func(t *testing.T) {
+		cmd := mustParse[*ExpireTime]("pexpiretime name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, int(at.UnixMilli()))
+	})
+	t.Run("no expire", func(t *testing.T) {
+		cmd := mustParse[*ExpireTime]("pexpiretime age")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, -1)
+		testx.AssertEqual(t, conn.out(), "-1")
+	})
+	t.Run("not found", func(t *testing.T) {
+		cmd := mustParse[*ExpireTime]("expiretime city")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, -2)
+		testx.AssertEqual(t, conn.out(), "-2")
+	})
+}
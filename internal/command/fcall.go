@@ -0,0 +1,74 @@
+package command
+
+import (
+	"strconv"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// Calls a procedure registered with redka.DB.Register.
+// The keys and the arguments are passed to the procedure
+// as a single list of arguments.
+// FCALL function numkeys [key [key ...]] [arg [arg ...]]
+// https://redis.io/commands/fcall
+type FCall struct {
+	baseCmd
+	fn     string
+	params []string
+}
+
+func parseFCall(b baseCmd) (*FCall, error) {
+	cmd := &FCall{baseCmd: b}
+	if len(cmd.args) < 2 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.fn = string(cmd.args[0])
+	numKeys, err := strconv.Atoi(string(cmd.args[1]))
+	if err != nil || numKeys < 0 {
+		return cmd, ErrInvalidInt
+	}
+	if numKeys > len(cmd.args)-2 {
+		return cmd, ErrNumKeys
+	}
+	cmd.params = make([]string, len(cmd.args)-2)
+	for i, arg := range cmd.args[2:] {
+		cmd.params[i] = string(arg)
+	}
+	return cmd, nil
+}
+
+func (cmd *FCall) Run(w Writer, red Redka) (any, error) {
+	if red.Proc() == nil {
+		err := ErrFuncNotFound
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	res, err := red.Proc().Call(cmd.fn, cmd.params...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	switch v := res.(type) {
+	case nil:
+		w.WriteNull()
+	case core.Value:
+		if !v.Exists() {
+			w.WriteNull()
+		} else {
+			w.WriteBulk(v)
+		}
+	case int:
+		w.WriteInt(v)
+	case int64:
+		w.WriteInt64(v)
+	case bool:
+		if v {
+			w.WriteInt(1)
+		} else {
+			w.WriteInt(0)
+		}
+	default:
+		w.WriteAny(v)
+	}
+	return res, nil
+}
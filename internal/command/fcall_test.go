@@ -0,0 +1,136 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestFCallParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   [][]byte
+		fn     string
+		params []string
+		err    error
+	}{
+		{
+			name: "fcall",
+			args: buildArgs("fcall"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "fcall incr",
+			args: buildArgs("fcall", "incr"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name:   "fcall incr 0",
+			args:   buildArgs("fcall", "incr", "0"),
+			fn:     "incr",
+			params: []string{},
+		},
+		{
+			name:   "fcall incr 1 age 10",
+			args:   buildArgs("fcall", "incr", "1", "age", "10"),
+			fn:     "incr",
+			params: []string{"age", "10"},
+		},
+		{
+			name: "fcall incr one",
+			args: buildArgs("fcall", "incr", "one"),
+			err:  ErrInvalidInt,
+		},
+		{
+			name: "fcall incr -1",
+			args: buildArgs("fcall", "incr", "-1"),
+			err:  ErrInvalidInt,
+		},
+		{
+			name: "fcall incr 2 age",
+			args: buildArgs("fcall", "incr", "2", "age"),
+			err:  ErrNumKeys,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				testx.AssertEqual(t, cmd.(*FCall).fn, test.fn)
+				testx.AssertEqual(t, cmd.(*FCall).params, test.params)
+			}
+		})
+	}
+}
+
+func TestFCallExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+
+	db.Register("incr", func(tx *redka.Tx, args ...string) (any, error) {
+		return tx.Str().Incr(args[0], 1)
+	})
+	db.Register("get", func(tx *redka.Tx, args ...string) (any, error) {
+		return tx.Str().Get(args[0])
+	})
+
+	t.Run("int", func(t *testing.T) {
+		cmd := mustParse[*FCall]("fcall incr 1 age")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 1)
+		testx.AssertEqual(t, conn.out(), "1")
+	})
+	t.Run("value", func(t *testing.T) {
+		cmd := mustParse[*FCall]("fcall get 1 age")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, conn.out(), "1")
+	})
+	t.Run("nil", func(t *testing.T) {
+		cmd := mustParse[*FCall]("fcall get 1 name")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, conn.out(), "(nil)")
+	})
+	t.Run("error", func(t *testing.T) {
+		_ = db.Str().Set("name", "alice")
+		cmd := mustParse[*FCall]("fcall incr 1 name")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertErr(t, err, redka.ErrValueType)
+		testx.AssertEqual(t, conn.out(), redka.ErrValueType.Error()+" (fcall)")
+	})
+	t.Run("not found", func(t *testing.T) {
+		cmd := mustParse[*FCall]("fcall nope 0")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertErr(t, err, redka.ErrProcNotFound)
+		testx.AssertEqual(t, conn.out(), ErrFuncNotFound.Error()+" (fcall)")
+	})
+	t.Run("tx", func(t *testing.T) {
+		err := db.Update(func(tx *redka.Tx) error {
+			red := RedkaTx(tx).WithProcs(db, tx)
+			cmd := mustParse[*FCall]("fcall incr 1 age")
+			_, err := cmd.Run(new(fakeConn), red)
+			return err
+		})
+		testx.AssertNoErr(t, err)
+		age, _ := db.Str().Get("age")
+		testx.AssertEqual(t, age.String(), "2")
+	})
+	t.Run("tx without procs", func(t *testing.T) {
+		err := db.Update(func(tx *redka.Tx) error {
+			cmd := mustParse[*FCall]("fcall incr 1 age")
+			_, err := cmd.Run(new(fakeConn), RedkaTx(tx))
+			return err
+		})
+		testx.AssertErr(t, err, ErrFuncNotFound)
+	})
+}
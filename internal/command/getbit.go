@@ -0,0 +1,53 @@
+package command
+
+import (
+	"strconv"
+
+	"github.com/nalgeon/redka/internal/rstring"
+)
+
+// Returns a bit value by offset.
+// GETBIT key offset
+// https://redis.io/commands/getbit
+type GetBit struct {
+	baseCmd
+	key    string
+	offset int
+}
+
+func parseGetBit(b baseCmd) (*GetBit, error) {
+	cmd := &GetBit{baseCmd: b}
+	if len(cmd.args) != 2 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	var err error
+	cmd.offset, err = parseBitOffset(cmd.args[1])
+	if err != nil {
+		return cmd, err
+	}
+	return cmd, nil
+}
+
+func (cmd *GetBit) Run(w Writer, red Redka) (any, error) {
+	bit, err := red.Str().GetBit(cmd.key, cmd.offset)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	if bit {
+		w.WriteInt(1)
+		return 1, nil
+	}
+	w.WriteInt(0)
+	return 0, nil
+}
+
+// parseBitOffset parses the bit offset argument.
+func parseBitOffset(arg []byte) (int, error) {
+	offset, err := strconv.Atoi(string(arg))
+	if err != nil || offset < 0 || offset > rstring.MaxBitOffset {
+		return 0, ErrInvalidBitOffset
+	}
+	return offset, nil
+}
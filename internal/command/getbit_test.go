@@ -0,0 +1,91 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestGetBitParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want GetBit
+		err  error
+	}{
+		{
+			name: "getbit",
+			args: buildArgs("getbit"),
+			want: GetBit{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "getbit key",
+			args: buildArgs("getbit", "key"),
+			want: GetBit{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "getbit key 7",
+			args: buildArgs("getbit", "key", "7"),
+			want: GetBit{key: "key", offset: 7},
+			err:  nil,
+		},
+		{
+			name: "getbit key -1",
+			args: buildArgs("getbit", "key", "-1"),
+			want: GetBit{},
+			err:  ErrInvalidBitOffset,
+		},
+		{
+			name: "getbit key 4294967296",
+			args: buildArgs("getbit", "key", "4294967296"),
+			want: GetBit{},
+			err:  ErrInvalidBitOffset,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*GetBit)
+				testx.AssertEqual(t, cm.key, test.want.key)
+				testx.AssertEqual(t, cm.offset, test.want.offset)
+			}
+		})
+	}
+}
+
+func TestGetBitExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+
+	_ = db.Str().Set("key", "\x80")
+
+	t.Run("set", func(t *testing.T) {
+		cmd := mustParse[*GetBit]("getbit key 0")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 1)
+		testx.AssertEqual(t, conn.out(), "1")
+	})
+	t.Run("clear", func(t *testing.T) {
+		cmd := mustParse[*GetBit]("getbit key 1")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 0)
+		testx.AssertEqual(t, conn.out(), "0")
+	})
+	t.Run("key not found", func(t *testing.T) {
+		cmd := mustParse[*GetBit]("getbit other 0")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 0)
+		testx.AssertEqual(t, conn.out(), "0")
+	})
+}
@@ -0,0 +1,32 @@
+package command
+
+// Returns the string value of a key after deleting the key.
+// GETDEL key
+// https://redis.io/commands/getdel
+type GetDel struct {
+	baseCmd
+	key string
+}
+
+func parseGetDel(b baseCmd) (*GetDel, error) {
+	cmd := &GetDel{baseCmd: b}
+	if len(cmd.args) != 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	return cmd, nil
+}
+
+func (cmd *GetDel) Run(w Writer, red Redka) (any, error) {
+	val, err := red.Str().GetDel(cmd.key)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	if !val.Exists() {
+		w.WriteNull()
+		return val, nil
+	}
+	w.WriteBulk(val)
+	return val, nil
+}
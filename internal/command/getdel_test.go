@@ -0,0 +1,75 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestGetDelParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		key  string
+		err  error
+	}{
+		{
+			name: "getdel",
+			args: buildArgs("getdel"),
+			key:  "",
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "getdel name",
+			args: buildArgs("getdel", "name"),
+			key:  "name",
+			err:  nil,
+		},
+		{
+			name: "getdel name age",
+			args: buildArgs("getdel", "name", "age"),
+			key:  "",
+			err:  ErrInvalidArgNum,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				testx.AssertEqual(t, cmd.(*GetDel).key, test.key)
+			}
+		})
+	}
+}
+
+func TestGetDelExec(t *testing.T) {
+	t.Run("key found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		cmd := mustParse[*GetDel]("getdel name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, core.Value("alice"))
+		testx.AssertEqual(t, conn.out(), "alice")
+
+		exists, _ := db.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*GetDel]("getdel name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, core.Value(nil))
+		testx.AssertEqual(t, conn.out(), "(nil)")
+	})
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// Returns the string value of a key after setting its expiration time.
+// GETEX key [EX seconds | PX milliseconds | EXAT unix-time-seconds |
+// PXAT unix-time-milliseconds | PERSIST]
+// https://redis.io/commands/getex
+type GetEx struct {
+	baseCmd
+	key     string
+	ttl     time.Duration
+	at      time.Time
+	persist bool
+}
+
+func parseGetEx(b baseCmd) (*GetEx, error) {
+	cmd := &GetEx{baseCmd: b}
+	if len(cmd.args) < 1 || len(cmd.args) > 3 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+
+	if len(cmd.args) == 2 {
+		if strings.ToLower(string(cmd.args[1])) != "persist" {
+			return cmd, ErrSyntaxError
+		}
+		cmd.persist = true
+	}
+
+	if len(cmd.args) == 3 {
+		valueInt, err := strconv.Atoi(string(cmd.args[2]))
+		if err != nil {
+			return cmd, ErrInvalidInt
+		}
+		if valueInt <= 0 {
+			return cmd, ErrInvalidExpireTime
+		}
+		switch strings.ToLower(string(cmd.args[1])) {
+		case "ex":
+			cmd.ttl = time.Duration(valueInt) * time.Second
+		case "px":
+			cmd.ttl = time.Duration(valueInt) * time.Millisecond
+		case "exat":
+			cmd.at = time.Unix(int64(valueInt), 0)
+		case "pxat":
+			cmd.at = time.UnixMilli(int64(valueInt))
+		default:
+			return cmd, ErrSyntaxError
+		}
+	}
+
+	return cmd, nil
+}
+
+func (cmd *GetEx) Run(w Writer, red Redka) (any, error) {
+	ttl := cmd.ttl
+	if !cmd.at.IsZero() {
+		ttl = time.Until(cmd.at)
+	}
+
+	var val core.Value
+	var err error
+	switch {
+	case cmd.persist:
+		val, err = red.Str().GetEx(cmd.key, 0)
+	case ttl > 0:
+		val, err = red.Str().GetEx(cmd.key, ttl)
+	case !cmd.at.IsZero():
+		// The key would expire immediately, so delete it instead.
+		val, err = red.Str().GetDel(cmd.key)
+	default:
+		val, err = red.Str().Get(cmd.key)
+	}
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	if !val.Exists() {
+		w.WriteNull()
+		return val, nil
+	}
+	w.WriteBulk(val)
+	return val, nil
+}
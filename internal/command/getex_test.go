@@ -0,0 +1,159 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestGetExParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want GetEx
+		err  error
+	}{
+		{
+			name: "getex",
+			args: buildArgs("getex"),
+			want: GetEx{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "getex name",
+			args: buildArgs("getex", "name"),
+			want: GetEx{key: "name"},
+			err:  nil,
+		},
+		{
+			name: "getex name persist",
+			args: buildArgs("getex", "name", "persist"),
+			want: GetEx{key: "name", persist: true},
+			err:  nil,
+		},
+		{
+			name: "getex name ex 60",
+			args: buildArgs("getex", "name", "ex", "60"),
+			want: GetEx{key: "name", ttl: 60 * time.Second},
+			err:  nil,
+		},
+		{
+			name: "getex name PX 100",
+			args: buildArgs("getex", "name", "PX", "100"),
+			want: GetEx{key: "name", ttl: 100 * time.Millisecond},
+			err:  nil,
+		},
+		{
+			name: "getex name exat 1700000000",
+			args: buildArgs("getex", "name", "exat", "1700000000"),
+			want: GetEx{key: "name", at: time.Unix(1700000000, 0)},
+			err:  nil,
+		},
+		{
+			name: "getex name ex 0",
+			args: buildArgs("getex", "name", "ex", "0"),
+			want: GetEx{},
+			err:  ErrInvalidExpireTime,
+		},
+		{
+			name: "getex name ex",
+			args: buildArgs("getex", "name", "ex"),
+			want: GetEx{},
+			err:  ErrSyntaxError,
+		},
+		{
+			name: "getex name ttl 60",
+			args: buildArgs("getex", "name", "ttl", "60"),
+			want: GetEx{},
+			err:  ErrSyntaxError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*GetEx)
+				testx.AssertEqual(t, cm.key, test.want.key)
+				testx.AssertEqual(t, cm.ttl, test.want.ttl)
+				testx.AssertEqual(t, cm.at, test.want.at)
+				testx.AssertEqual(t, cm.persist, test.want.persist)
+			}
+		})
+	}
+}
+
+func TestGetExExec(t *testing.T) {
+	t.Run("no options", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().SetExpires("name", "alice", time.Minute)
+
+		cmd := mustParse[*GetEx]("getex name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, core.Value("alice"))
+		testx.AssertEqual(t, conn.out(), "alice")
+
+		key, _ := db.Key().Get("name")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("ex", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		cmd := mustParse[*GetEx]("getex name ex 60")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, core.Value("alice"))
+		testx.AssertEqual(t, conn.out(), "alice")
+
+		expireAt := time.Now().Add(60 * time.Second)
+		key, _ := db.Key().Get("name")
+		testx.AssertEqual(t, *key.ETime/1000, expireAt.UnixMilli()/1000)
+	})
+	t.Run("persist", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().SetExpires("name", "alice", time.Minute)
+
+		cmd := mustParse[*GetEx]("getex name persist")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+
+		key, _ := db.Key().Get("name")
+		testx.AssertEqual(t, key.ETime, (*int64)(nil))
+	})
+	t.Run("exat expired", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		cmd := mustParse[*GetEx]("getex name exat 1700000000")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, core.Value("alice"))
+
+		exists, _ := db.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*GetEx]("getex name ex 60")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, core.Value(nil))
+		testx.AssertEqual(t, conn.out(), "(nil)")
+	})
+}
@@ -0,0 +1,41 @@
+package command
+
+import "strconv"
+
+// Returns a substring of the string stored at a key.
+// GETRANGE key start end
+// https://redis.io/commands/getrange
+type GetRange struct {
+	baseCmd
+	key   string
+	start int
+	end   int
+}
+
+func parseGetRange(b baseCmd) (*GetRange, error) {
+	cmd := &GetRange{baseCmd: b}
+	if len(cmd.args) != 3 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	var err error
+	cmd.start, err = strconv.Atoi(string(cmd.args[1]))
+	if err != nil {
+		return cmd, ErrInvalidInt
+	}
+	cmd.end, err = strconv.Atoi(string(cmd.args[2]))
+	if err != nil {
+		return cmd, ErrInvalidInt
+	}
+	return cmd, nil
+}
+
+func (cmd *GetRange) Run(w Writer, red Redka) (any, error) {
+	val, err := red.Str().GetRange(cmd.key, cmd.start, cmd.end)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	w.WriteBulk(val)
+	return val, nil
+}
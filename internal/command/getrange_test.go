@@ -0,0 +1,90 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestGetRangeParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want GetRange
+		err  error
+	}{
+		{
+			name: "getrange",
+			args: buildArgs("getrange"),
+			want: GetRange{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "getrange key 0",
+			args: buildArgs("getrange", "key", "0"),
+			want: GetRange{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "getrange key 0 -1",
+			args: buildArgs("getrange", "key", "0", "-1"),
+			want: GetRange{key: "key", start: 0, end: -1},
+			err:  nil,
+		},
+		{
+			name: "getrange key x -1",
+			args: buildArgs("getrange", "key", "x", "-1"),
+			want: GetRange{},
+			err:  ErrInvalidInt,
+		},
+		{
+			name: "getrange key 0 x",
+			args: buildArgs("getrange", "key", "0", "x"),
+			want: GetRange{},
+			err:  ErrInvalidInt,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*GetRange)
+				testx.AssertEqual(t, cm.key, test.want.key)
+				testx.AssertEqual(t, cm.start, test.want.start)
+				testx.AssertEqual(t, cm.end, test.want.end)
+			}
+		})
+	}
+}
+
+func TestGetRangeExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+	_ = db.Str().Set("key", "This is a string")
+
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"getrange key 0 3", "This"},
+		{"getrange key -3 -1", "ing"},
+		{"getrange key 0 -1", "This is a string"},
+		{"getrange key 10 100", "string"},
+		{"getrange key 5 3", ""},
+		{"getrange other 0 -1", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.cmd, func(t *testing.T) {
+			cmd := mustParse[*GetRange](test.cmd)
+			conn := new(fakeConn)
+			res, err := cmd.Run(conn, red)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, res.(core.Value).String(), test.want)
+			testx.AssertEqual(t, conn.out(), test.want)
+		})
+	}
+}
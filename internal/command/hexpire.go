@@ -0,0 +1,96 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sets the expiration time of hash fields in seconds.
+// HEXPIRE key seconds FIELDS numfields field [field ...]
+// https://redis.io/commands/hexpire
+type HExpire struct {
+	baseCmd
+	key    string
+	ttl    time.Duration
+	fields []string
+}
+
+func parseHExpire(b baseCmd, multi int) (*HExpire, error) {
+	cmd := &HExpire{baseCmd: b}
+	if len(cmd.args) < 5 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	ttl, err := strconv.Atoi(string(cmd.args[1]))
+	if err != nil {
+		return cmd, ErrInvalidInt
+	}
+	if ttl < 0 {
+		return cmd, ErrInvalidExpireTime
+	}
+	cmd.ttl = time.Duration(multi*ttl) * time.Millisecond
+	cmd.fields, err = parseFieldsArg(cmd.args[2:])
+	if err != nil {
+		return cmd, err
+	}
+	return cmd, nil
+}
+
+func (cmd *HExpire) Run(w Writer, red Redka) (any, error) {
+	res, err := red.Hash().FieldExpire(cmd.key, cmd.ttl, cmd.fields...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	codes := fieldExpireCodes(res, cmd.fields)
+	writeInts(w, codes)
+	return codes, nil
+}
+
+// fieldExpireCodes returns the reply codes of the hash field
+// expiration commands: -2 if the field does not exist,
+// 1 if the expiration time was set, 2 if the field was deleted.
+func fieldExpireCodes(res map[string]bool, fields []string) []int {
+	codes := make([]int, len(fields))
+	for i, field := range fields {
+		set, ok := res[field]
+		switch {
+		case !ok:
+			codes[i] = -2
+		case set:
+			codes[i] = 1
+		default:
+			codes[i] = 2
+		}
+	}
+	return codes
+}
+
+// parseFieldsArg parses the FIELDS numfields field [field ...]
+// part of the hash field expiration commands.
+func parseFieldsArg(args [][]byte) ([]string, error) {
+	if strings.ToLower(string(args[0])) != "fields" {
+		return nil, ErrSyntaxError
+	}
+	n, err := strconv.Atoi(string(args[1]))
+	if err != nil {
+		return nil, ErrInvalidInt
+	}
+	if n <= 0 || n != len(args)-2 {
+		return nil, ErrSyntaxError
+	}
+	fields := make([]string, n)
+	for i, arg := range args[2:] {
+		fields[i] = string(arg)
+	}
+	return fields, nil
+}
+
+// writeInts writes the integers as an array.
+func writeInts(w Writer, ints []int) {
+	w.WriteArray(len(ints))
+	for _, n := range ints {
+		w.WriteInt(n)
+	}
+}
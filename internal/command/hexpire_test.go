@@ -0,0 +1,133 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestHExpireParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   [][]byte
+		key    string
+		ttl    time.Duration
+		fields []string
+		err    error
+	}{
+		{
+			name: "hexpire",
+			args: buildArgs("hexpire"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "hexpire person 60 fields 1",
+			args: buildArgs("hexpire", "person", "60", "fields", "1"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name:   "hexpire person 60 fields 1 name",
+			args:   buildArgs("hexpire", "person", "60", "fields", "1", "name"),
+			key:    "person",
+			ttl:    60 * time.Second,
+			fields: []string{"name"},
+		},
+		{
+			name:   "hexpire person 60 fields 2 name age",
+			args:   buildArgs("hexpire", "person", "60", "fields", "2", "name", "age"),
+			key:    "person",
+			ttl:    60 * time.Second,
+			fields: []string{"name", "age"},
+		},
+		{
+			name:   "hpexpire person 500 fields 1 name",
+			args:   buildArgs("hpexpire", "person", "500", "fields", "1", "name"),
+			key:    "person",
+			ttl:    500 * time.Millisecond,
+			fields: []string{"name"},
+		},
+		{
+			name: "hexpire person x fields 1 name",
+			args: buildArgs("hexpire", "person", "x", "fields", "1", "name"),
+			err:  ErrInvalidInt,
+		},
+		{
+			name: "hexpire person -1 fields 1 name",
+			args: buildArgs("hexpire", "person", "-1", "fields", "1", "name"),
+			err:  ErrInvalidExpireTime,
+		},
+		{
+			name: "hexpire person 60 nx fields 1 name",
+			args: buildArgs("hexpire", "person", "60", "nx", "fields", "1", "name"),
+			err:  ErrSyntaxError,
+		},
+		{
+			name: "hexpire person 60 fields 2 name",
+			args: buildArgs("hexpire", "person", "60", "fields", "2", "name"),
+			err:  ErrSyntaxError,
+		},
+		{
+			name: "hexpire person 60 fields x name",
+			args: buildArgs("hexpire", "person", "60", "fields", "x", "name"),
+			err:  ErrInvalidInt,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*HExpire)
+				testx.AssertEqual(t, cm.key, test.key)
+				testx.AssertEqual(t, cm.ttl, test.ttl)
+				testx.AssertEqual(t, cm.fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestHExpireExec(t *testing.T) {
+	t.Run("expire", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().Set("person", "name", "alice")
+
+		cmd := mustParse[*HExpire]("hexpire person 60 fields 2 name age")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, []int{1, -2})
+		testx.AssertEqual(t, conn.out(), "2,1,-2")
+
+		ttls, _ := db.Hash().FieldTTL("person", "name")
+		testx.AssertEqual(t, ttls["name"] > 59*time.Second, true)
+	})
+	t.Run("delete", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().Set("person", "name", "alice")
+
+		cmd := mustParse[*HExpire]("hexpire person 0 fields 1 name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, []int{2})
+		testx.AssertEqual(t, conn.out(), "1,2")
+
+		exists, _ := db.Hash().Exists("person", "name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*HExpire]("hexpire person 60 fields 1 name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, []int{-2})
+		testx.AssertEqual(t, conn.out(), "1,-2")
+	})
+}
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"strconv"
+	"time"
+)
+
+// Sets the expiration time of hash fields to a Unix timestamp.
+// HEXPIREAT key unix-time-seconds FIELDS numfields field [field ...]
+// https://redis.io/commands/hexpireat
+type HExpireAt struct {
+	baseCmd
+	key    string
+	at     time.Time
+	fields []string
+}
+
+func parseHExpireAt(b baseCmd, multi int) (*HExpireAt, error) {
+	cmd := &HExpireAt{baseCmd: b}
+	if len(cmd.args) < 5 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	at, err := strconv.Atoi(string(cmd.args[1]))
+	if err != nil {
+		return cmd, ErrInvalidInt
+	}
+	if at < 0 {
+		return cmd, ErrInvalidExpireTime
+	}
+	cmd.at = time.UnixMilli(int64(multi * at))
+	cmd.fields, err = parseFieldsArg(cmd.args[2:])
+	if err != nil {
+		return cmd, err
+	}
+	return cmd, nil
+}
+
+func (cmd *HExpireAt) Run(w Writer, red Redka) (any, error) {
+	res, err := red.Hash().FieldExpireAt(cmd.key, cmd.at, cmd.fields...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	codes := fieldExpireCodes(res, cmd.fields)
+	writeInts(w, codes)
+	return codes, nil
+}
@@ -0,0 +1,113 @@
+package command
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestHExpireAtParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   [][]byte
+		key    string
+		at     time.Time
+		fields []string
+		err    error
+	}{
+		{
+			name: "hexpireat",
+			args: buildArgs("hexpireat"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name:   "hexpireat person 1700000000 fields 1 name",
+			args:   buildArgs("hexpireat", "person", "1700000000", "fields", "1", "name"),
+			key:    "person",
+			at:     time.UnixMilli(1700000000 * 1000),
+			fields: []string{"name"},
+		},
+		{
+			name:   "hpexpireat person 1700000000123 fields 2 name age",
+			args:   buildArgs("hpexpireat", "person", "1700000000123", "fields", "2", "name", "age"),
+			key:    "person",
+			at:     time.UnixMilli(1700000000123),
+			fields: []string{"name", "age"},
+		},
+		{
+			name: "hexpireat person x fields 1 name",
+			args: buildArgs("hexpireat", "person", "x", "fields", "1", "name"),
+			err:  ErrInvalidInt,
+		},
+		{
+			name: "hexpireat person -1 fields 1 name",
+			args: buildArgs("hexpireat", "person", "-1", "fields", "1", "name"),
+			err:  ErrInvalidExpireTime,
+		},
+		{
+			name: "hexpireat person 1700000000 fields 2 name",
+			args: buildArgs("hexpireat", "person", "1700000000", "fields", "2", "name"),
+			err:  ErrSyntaxError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*HExpireAt)
+				testx.AssertEqual(t, cm.key, test.key)
+				testx.AssertEqual(t, cm.at.Equal(test.at), true)
+				testx.AssertEqual(t, cm.fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestHExpireAtExec(t *testing.T) {
+	t.Run("expire", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().Set("person", "name", "alice")
+
+		at := time.Now().Add(time.Minute).UnixMilli()
+		cmd := mustParse[*HExpireAt]("hpexpireat person " + strconv.FormatInt(at, 10) + " fields 2 name age")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, []int{1, -2})
+		testx.AssertEqual(t, conn.out(), "2,1,-2")
+
+		ttls, _ := db.Hash().FieldTTL("person", "name")
+		testx.AssertEqual(t, ttls["name"] > 59*time.Second, true)
+	})
+	t.Run("delete", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().Set("person", "name", "alice")
+
+		cmd := mustParse[*HExpireAt]("hexpireat person 1 fields 1 name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, []int{2})
+		testx.AssertEqual(t, conn.out(), "1,2")
+
+		exists, _ := db.Hash().Exists("person", "name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*HExpireAt]("hexpireat person 1700000000 fields 1 name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, []int{-2})
+		testx.AssertEqual(t, conn.out(), "1,-2")
+	})
+}
@@ -0,0 +1,46 @@
+package command
+
+// Removes the expiration time of hash fields.
+// HPERSIST key FIELDS numfields field [field ...]
+// https://redis.io/commands/hpersist
+type HPersist struct {
+	baseCmd
+	key    string
+	fields []string
+}
+
+func parseHPersist(b baseCmd) (*HPersist, error) {
+	cmd := &HPersist{baseCmd: b}
+	if len(cmd.args) < 4 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	var err error
+	cmd.fields, err = parseFieldsArg(cmd.args[1:])
+	if err != nil {
+		return cmd, err
+	}
+	return cmd, nil
+}
+
+func (cmd *HPersist) Run(w Writer, red Redka) (any, error) {
+	res, err := red.Hash().FieldPersist(cmd.key, cmd.fields...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	codes := make([]int, len(cmd.fields))
+	for i, field := range cmd.fields {
+		removed, ok := res[field]
+		switch {
+		case !ok:
+			codes[i] = -2
+		case removed:
+			codes[i] = 1
+		default:
+			codes[i] = -1
+		}
+	}
+	writeInts(w, codes)
+	return codes, nil
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestHPersistParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   [][]byte
+		key    string
+		fields []string
+		err    error
+	}{
+		{
+			name: "hpersist",
+			args: buildArgs("hpersist"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "hpersist person fields 1",
+			args: buildArgs("hpersist", "person", "fields", "1"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name:   "hpersist person fields 2 name age",
+			args:   buildArgs("hpersist", "person", "fields", "2", "name", "age"),
+			key:    "person",
+			fields: []string{"name", "age"},
+		},
+		{
+			name: "hpersist person fields 0 name",
+			args: buildArgs("hpersist", "person", "fields", "0", "name"),
+			err:  ErrSyntaxError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*HPersist)
+				testx.AssertEqual(t, cm.key, test.key)
+				testx.AssertEqual(t, cm.fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestHPersistExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+	_, _ = db.Hash().Set("person", "name", "alice")
+	_, _ = db.Hash().Set("person", "age", 25)
+	_, _ = db.Hash().FieldExpire("person", time.Minute, "name")
+
+	cmd := mustParse[*HPersist]("hpersist person fields 3 name age city")
+	conn := new(fakeConn)
+	res, err := cmd.Run(conn, red)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, res, []int{1, -1, -2})
+	testx.AssertEqual(t, conn.out(), "3,1,-1,-2")
+
+	ttls, _ := db.Hash().FieldTTL("person", "name")
+	testx.AssertEqual(t, ttls["name"], time.Duration(0))
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nalgeon/redka/internal/rhash"
+)
+
+// Returns one or more random fields from a hash.
+// HRANDFIELD key [count [WITHVALUES]]
+// https://redis.io/commands/hrandfield
+type HRandField struct {
+	baseCmd
+	key        string
+	count      int
+	hasCount   bool
+	withValues bool
+}
+
+func parseHRandField(b baseCmd) (*HRandField, error) {
+	cmd := &HRandField{baseCmd: b, count: 1}
+	if len(cmd.args) < 1 || len(cmd.args) > 3 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	if len(cmd.args) == 1 {
+		return cmd, nil
+	}
+	var err error
+	cmd.count, err = strconv.Atoi(string(cmd.args[1]))
+	if err != nil {
+		return cmd, ErrInvalidInt
+	}
+	cmd.hasCount = true
+	if len(cmd.args) == 3 {
+		if strings.ToLower(string(cmd.args[2])) != "withvalues" {
+			return cmd, ErrSyntaxError
+		}
+		cmd.withValues = true
+	}
+	return cmd, nil
+}
+
+func (cmd *HRandField) Run(w Writer, red Redka) (any, error) {
+	items, err := red.Hash().RandField(cmd.key, cmd.count, cmd.withValues)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+
+	// Without the count, reply with a single field.
+	if !cmd.hasCount {
+		if len(items) == 0 {
+			w.WriteNull()
+			return nil, nil
+		}
+		w.WriteBulkString(items[0].Field)
+		return items[0].Field, nil
+	}
+
+	if cmd.withValues {
+		w.WriteArray(len(items) * 2)
+	} else {
+		w.WriteArray(len(items))
+	}
+	for _, it := range items {
+		w.WriteBulkString(it.Field)
+		if cmd.withValues {
+			w.WriteBulk(it.Value)
+		}
+	}
+	if items == nil {
+		items = []rhash.HashItem{}
+	}
+	return items, nil
+}
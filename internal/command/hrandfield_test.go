@@ -0,0 +1,117 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nalgeon/redka/internal/rhash"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestHRandFieldParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want HRandField
+		err  error
+	}{
+		{
+			name: "hrandfield",
+			args: buildArgs("hrandfield"),
+			want: HRandField{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "hrandfield person",
+			args: buildArgs("hrandfield", "person"),
+			want: HRandField{key: "person", count: 1},
+			err:  nil,
+		},
+		{
+			name: "hrandfield person 5",
+			args: buildArgs("hrandfield", "person", "5"),
+			want: HRandField{key: "person", count: 5, hasCount: true},
+			err:  nil,
+		},
+		{
+			name: "hrandfield person -5 withvalues",
+			args: buildArgs("hrandfield", "person", "-5", "withvalues"),
+			want: HRandField{key: "person", count: -5, hasCount: true, withValues: true},
+			err:  nil,
+		},
+		{
+			name: "hrandfield person x",
+			args: buildArgs("hrandfield", "person", "x"),
+			want: HRandField{},
+			err:  ErrInvalidInt,
+		},
+		{
+			name: "hrandfield person 5 withscores",
+			args: buildArgs("hrandfield", "person", "5", "withscores"),
+			want: HRandField{},
+			err:  ErrSyntaxError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*HRandField)
+				testx.AssertEqual(t, cm.key, test.want.key)
+				testx.AssertEqual(t, cm.count, test.want.count)
+				testx.AssertEqual(t, cm.hasCount, test.want.hasCount)
+				testx.AssertEqual(t, cm.withValues, test.want.withValues)
+			}
+		})
+	}
+}
+
+func TestHRandFieldExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+	_, _ = db.Hash().Set("person", "name", "alice")
+	_, _ = db.Hash().Set("person", "age", 25)
+
+	t.Run("single", func(t *testing.T) {
+		cmd := mustParse[*HRandField]("hrandfield person")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		field := res.(string)
+		testx.AssertEqual(t, field == "name" || field == "age", true)
+		testx.AssertEqual(t, conn.out(), field)
+	})
+	t.Run("count", func(t *testing.T) {
+		cmd := mustParse[*HRandField]("hrandfield person 5")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(res.([]rhash.HashItem)), 2)
+		testx.AssertEqual(t, strings.HasPrefix(conn.out(), "2,"), true)
+	})
+	t.Run("with values", func(t *testing.T) {
+		cmd := mustParse[*HRandField]("hrandfield person -3 withvalues")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(res.([]rhash.HashItem)), 3)
+		testx.AssertEqual(t, strings.HasPrefix(conn.out(), "6,"), true)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		cmd := mustParse[*HRandField]("hrandfield other")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, nil)
+		testx.AssertEqual(t, conn.out(), "(nil)")
+
+		cmd = mustParse[*HRandField]("hrandfield other 5")
+		conn = new(fakeConn)
+		res, err = cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, []rhash.HashItem{})
+		testx.AssertEqual(t, conn.out(), "0")
+	})
+}
@@ -0,0 +1,50 @@
+package command
+
+import "time"
+
+// Returns the expiration time in seconds of hash fields.
+// HTTL key FIELDS numfields field [field ...]
+// https://redis.io/commands/httl
+type HTTL struct {
+	baseCmd
+	key    string
+	unit   time.Duration
+	fields []string
+}
+
+func parseHTTL(b baseCmd, unit time.Duration) (*HTTL, error) {
+	cmd := &HTTL{baseCmd: b, unit: unit}
+	if len(cmd.args) < 4 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	var err error
+	cmd.fields, err = parseFieldsArg(cmd.args[1:])
+	if err != nil {
+		return cmd, err
+	}
+	return cmd, nil
+}
+
+func (cmd *HTTL) Run(w Writer, red Redka) (any, error) {
+	res, err := red.Hash().FieldTTL(cmd.key, cmd.fields...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	ttls := make([]int, len(cmd.fields))
+	for i, field := range cmd.fields {
+		ttl, ok := res[field]
+		switch {
+		case !ok:
+			ttls[i] = -2
+		case ttl == 0:
+			ttls[i] = -1
+		default:
+			// Round to the nearest unit, like Redis does.
+			ttls[i] = int((ttl + cmd.unit/2) / cmd.unit)
+		}
+	}
+	writeInts(w, ttls)
+	return ttls, nil
+}
@@ -0,0 +1,87 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestHTTLParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   [][]byte
+		key    string
+		unit   time.Duration
+		fields []string
+		err    error
+	}{
+		{
+			name: "httl",
+			args: buildArgs("httl"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "httl person fields 1",
+			args: buildArgs("httl", "person", "fields", "1"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name:   "httl person fields 1 name",
+			args:   buildArgs("httl", "person", "fields", "1", "name"),
+			key:    "person",
+			unit:   time.Second,
+			fields: []string{"name"},
+		},
+		{
+			name:   "hpttl person fields 2 name age",
+			args:   buildArgs("hpttl", "person", "fields", "2", "name", "age"),
+			key:    "person",
+			unit:   time.Millisecond,
+			fields: []string{"name", "age"},
+		},
+		{
+			name: "httl person name age",
+			args: buildArgs("httl", "person", "name", "age", "city"),
+			err:  ErrSyntaxError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*HTTL)
+				testx.AssertEqual(t, cm.key, test.key)
+				testx.AssertEqual(t, cm.unit, test.unit)
+				testx.AssertEqual(t, cm.fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestHTTLExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+	_, _ = db.Hash().Set("person", "name", "alice")
+	_, _ = db.Hash().Set("person", "age", 25)
+	_, _ = db.Hash().FieldExpire("person", time.Minute, "name")
+
+	t.Run("httl", func(t *testing.T) {
+		cmd := mustParse[*HTTL]("httl person fields 3 name age city")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, []int{60, -1, -2})
+		testx.AssertEqual(t, conn.out(), "3,60,-1,-2")
+	})
+	t.Run("hpttl", func(t *testing.T) {
+		cmd := mustParse[*HTTL]("hpttl person fields 1 name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		ttl := res.([]int)[0]
+		testx.AssertEqual(t, ttl > 59000 && ttl <= 60000, true)
+	})
+}
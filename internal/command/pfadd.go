@@ -0,0 +1,38 @@
+package command
+
+// Adds elements to a HyperLogLog key.
+// Creates the key if it doesn't exist.
+// PFADD key [element [element ...]]
+// https://redis.io/commands/pfadd
+type PFAdd struct {
+	baseCmd
+	key   string
+	elems []string
+}
+
+func parsePFAdd(b baseCmd) (*PFAdd, error) {
+	cmd := &PFAdd{baseCmd: b}
+	if len(cmd.args) < 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	cmd.elems = make([]string, len(cmd.args)-1)
+	for i, arg := range cmd.args[1:] {
+		cmd.elems[i] = string(arg)
+	}
+	return cmd, nil
+}
+
+func (cmd *PFAdd) Run(w Writer, red Redka) (any, error) {
+	changed, err := red.HLL().Add(cmd.key, cmd.elems...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	if changed {
+		w.WriteInt(1)
+	} else {
+		w.WriteInt(0)
+	}
+	return changed, nil
+}
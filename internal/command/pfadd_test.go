@@ -0,0 +1,92 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestPFAddParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  [][]byte
+		key   string
+		elems []string
+		err   error
+	}{
+		{
+			name:  "pfadd",
+			args:  buildArgs("pfadd"),
+			key:   "",
+			elems: nil,
+			err:   ErrInvalidArgNum,
+		},
+		{
+			name:  "pfadd visitors",
+			args:  buildArgs("pfadd", "visitors"),
+			key:   "visitors",
+			elems: []string{},
+			err:   nil,
+		},
+		{
+			name:  "pfadd visitors alice bob",
+			args:  buildArgs("pfadd", "visitors", "alice", "bob"),
+			key:   "visitors",
+			elems: []string{"alice", "bob"},
+			err:   nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*PFAdd)
+				testx.AssertEqual(t, cm.key, test.key)
+				testx.AssertEqual(t, cm.elems, test.elems)
+			}
+		})
+	}
+}
+
+func TestPFAddExec(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*PFAdd]("pfadd visitors alice bob")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, true)
+		testx.AssertEqual(t, conn.out(), "1")
+
+		count, _ := db.HLL().Count("visitors")
+		testx.AssertEqual(t, count, 2)
+	})
+	t.Run("unchanged", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.HLL().Add("visitors", "alice", "bob")
+
+		cmd := mustParse[*PFAdd]("pfadd visitors alice")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, false)
+		testx.AssertEqual(t, conn.out(), "0")
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("visitors", "value")
+
+		cmd := mustParse[*PFAdd]("pfadd visitors alice")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, conn.out(), ErrKeyType.Error()+" (pfadd)")
+	})
+}
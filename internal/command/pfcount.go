@@ -0,0 +1,32 @@
+package command
+
+// Returns the approximated cardinality of the set(s)
+// observed by the HyperLogLog key(s).
+// PFCOUNT key [key ...]
+// https://redis.io/commands/pfcount
+type PFCount struct {
+	baseCmd
+	keys []string
+}
+
+func parsePFCount(b baseCmd) (*PFCount, error) {
+	cmd := &PFCount{baseCmd: b}
+	if len(cmd.args) < 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.keys = make([]string, len(cmd.args))
+	for i, arg := range cmd.args {
+		cmd.keys[i] = string(arg)
+	}
+	return cmd, nil
+}
+
+func (cmd *PFCount) Run(w Writer, red Redka) (any, error) {
+	count, err := red.HLL().Count(cmd.keys...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	w.WriteInt(count)
+	return count, nil
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestPFCountParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		keys []string
+		err  error
+	}{
+		{
+			name: "pfcount",
+			args: buildArgs("pfcount"),
+			keys: nil,
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "pfcount visitors",
+			args: buildArgs("pfcount", "visitors"),
+			keys: []string{"visitors"},
+			err:  nil,
+		},
+		{
+			name: "pfcount visitors buyers",
+			args: buildArgs("pfcount", "visitors", "buyers"),
+			keys: []string{"visitors", "buyers"},
+			err:  nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*PFCount)
+				testx.AssertEqual(t, cm.keys, test.keys)
+			}
+		})
+	}
+}
+
+func TestPFCountExec(t *testing.T) {
+	t.Run("single key", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.HLL().Add("visitors", "alice", "bob", "cindy")
+
+		cmd := mustParse[*PFCount]("pfcount visitors")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 3)
+		testx.AssertEqual(t, conn.out(), "3")
+	})
+	t.Run("multiple keys", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.HLL().Add("visitors", "alice", "bob")
+		_, _ = db.HLL().Add("buyers", "bob", "cindy")
+
+		cmd := mustParse[*PFCount]("pfcount visitors buyers")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 3)
+		testx.AssertEqual(t, conn.out(), "3")
+	})
+	t.Run("key not found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*PFCount]("pfcount visitors")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 0)
+		testx.AssertEqual(t, conn.out(), "0")
+	})
+}
@@ -0,0 +1,33 @@
+package command
+
+// Merges one or more HyperLogLog values into a single key.
+// PFMERGE destkey [sourcekey [sourcekey ...]]
+// https://redis.io/commands/pfmerge
+type PFMerge struct {
+	baseCmd
+	dest string
+	keys []string
+}
+
+func parsePFMerge(b baseCmd) (*PFMerge, error) {
+	cmd := &PFMerge{baseCmd: b}
+	if len(cmd.args) < 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.dest = string(cmd.args[0])
+	cmd.keys = make([]string, len(cmd.args)-1)
+	for i, arg := range cmd.args[1:] {
+		cmd.keys[i] = string(arg)
+	}
+	return cmd, nil
+}
+
+func (cmd *PFMerge) Run(w Writer, red Redka) (any, error) {
+	err := red.HLL().Merge(cmd.dest, cmd.keys...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	w.WriteString("OK")
+	return true, nil
+}
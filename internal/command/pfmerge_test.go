@@ -0,0 +1,83 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestPFMergeParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		dest string
+		keys []string
+		err  error
+	}{
+		{
+			name: "pfmerge",
+			args: buildArgs("pfmerge"),
+			dest: "",
+			keys: nil,
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "pfmerge all",
+			args: buildArgs("pfmerge", "all"),
+			dest: "all",
+			keys: []string{},
+			err:  nil,
+		},
+		{
+			name: "pfmerge all visitors buyers",
+			args: buildArgs("pfmerge", "all", "visitors", "buyers"),
+			dest: "all",
+			keys: []string{"visitors", "buyers"},
+			err:  nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*PFMerge)
+				testx.AssertEqual(t, cm.dest, test.dest)
+				testx.AssertEqual(t, cm.keys, test.keys)
+			}
+		})
+	}
+}
+
+func TestPFMergeExec(t *testing.T) {
+	t.Run("merge", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.HLL().Add("visitors", "alice", "bob")
+		_, _ = db.HLL().Add("buyers", "bob", "cindy")
+
+		cmd := mustParse[*PFMerge]("pfmerge all visitors buyers")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, true)
+		testx.AssertEqual(t, conn.out(), "OK")
+
+		count, _ := db.HLL().Count("all")
+		testx.AssertEqual(t, count, 3)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("all", "value")
+		_, _ = db.HLL().Add("visitors", "alice")
+
+		cmd := mustParse[*PFMerge]("pfmerge all visitors")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, conn.out(), ErrKeyType.Error()+" (pfmerge)")
+	})
+}
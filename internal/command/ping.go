@@ -0,0 +1,30 @@
+package command
+
+// Ping returns PONG if no argument is provided,
+// otherwise returns a copy of the argument.
+// PING [message]
+// https://redis.io/commands/ping
+type Ping struct {
+	baseCmd
+	message string
+}
+
+func parsePing(b baseCmd) (*Ping, error) {
+	cmd := &Ping{baseCmd: b}
+	if len(b.args) > 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	if len(b.args) == 1 {
+		cmd.message = string(b.args[0])
+	}
+	return cmd, nil
+}
+
+func (c *Ping) Run(w Writer, _ Redka) (any, error) {
+	if c.message == "" {
+		w.WriteString("PONG")
+		return "PONG", nil
+	}
+	w.WriteBulkString(c.message)
+	return c.message, nil
+}
@@ -0,0 +1,80 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestPingParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want string
+		err  error
+	}{
+		{
+			name: "ping",
+			args: buildArgs("ping"),
+			want: "",
+			err:  nil,
+		},
+		{
+			name: "ping hello",
+			args: buildArgs("ping", "hello"),
+			want: "hello",
+			err:  nil,
+		},
+		{
+			name: "ping one two",
+			args: buildArgs("ping", "one", "two"),
+			want: "",
+			err:  ErrInvalidArgNum,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				testx.AssertEqual(t, cmd.(*Ping).message, test.want)
+			}
+		})
+	}
+}
+
+func TestPingExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+
+	tests := []struct {
+		name string
+		cmd  *Ping
+		res  any
+		out  string
+	}{
+		{
+			name: "ping",
+			cmd:  mustParse[*Ping]("ping"),
+			res:  "PONG",
+			out:  "PONG",
+		},
+		{
+			name: "ping hello",
+			cmd:  mustParse[*Ping]("ping hello"),
+			res:  "hello",
+			out:  "hello",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			conn := new(fakeConn)
+			res, err := test.cmd.Run(conn, red)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, res, test.res)
+			testx.AssertEqual(t, conn.out(), test.out)
+		})
+	}
+}
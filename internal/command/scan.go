@@ -2,41 +2,24 @@ package command
 
 import (
 	"strconv"
+
+	"github.com/nalgeon/redka/internal/core"
 )
 
 // Iterates over the key names in the database.
-// SCAN cursor [MATCH pattern] [COUNT count]
+// SCAN cursor [MATCH pattern] [COUNT count] [TYPE type]
 // https://redis.io/commands/scan
 type Scan struct {
 	baseCmd
 	cursor int
 	match  string
 	count  int
+	typ    core.TypeID
 }
 
 func parseScan(b baseCmd) (*Scan, error) {
-	parseMatch := func(cmd *Scan, idx int) error {
-		if len(cmd.args) < idx+1 {
-			return ErrSyntaxError
-		}
-		cmd.match = string(cmd.args[idx])
-		return nil
-	}
-
-	parseCount := func(cmd *Scan, idx int) error {
-		if len(cmd.args) < idx+1 {
-			return ErrSyntaxError
-		}
-		var err error
-		cmd.count, err = strconv.Atoi(string(cmd.args[idx]))
-		if err != nil {
-			return ErrInvalidInt
-		}
-		return nil
-	}
-
 	cmd := &Scan{baseCmd: b}
-	if len(cmd.args) < 1 || len(cmd.args) > 5 {
+	if len(cmd.args) < 1 || len(cmd.args) > 7 {
 		return cmd, ErrInvalidArgNum
 	}
 	var err error
@@ -45,31 +28,23 @@ func parseScan(b baseCmd) (*Scan, error) {
 		return cmd, ErrInvalidCursor
 	}
 
-	if len(cmd.args) > 1 {
-		switch string(cmd.args[1]) {
-		case "match":
-			err = parseMatch(cmd, 2)
-		case "count":
-			err = parseCount(cmd, 2)
-		default:
-			err = ErrSyntaxError
-		}
-		if err != nil {
-			return cmd, err
+	for i := 1; i < len(cmd.args); i += 2 {
+		if i+1 >= len(cmd.args) {
+			return cmd, ErrSyntaxError
 		}
-	}
-
-	if len(cmd.args) > 3 {
-		switch string(cmd.args[3]) {
+		val := string(cmd.args[i+1])
+		switch string(cmd.args[i]) {
 		case "match":
-			err = parseMatch(cmd, 4)
+			cmd.match = val
 		case "count":
-			err = parseCount(cmd, 4)
+			cmd.count, err = strconv.Atoi(val)
+			if err != nil {
+				return cmd, ErrInvalidInt
+			}
+		case "type":
+			cmd.typ = parseKeyType(val)
 		default:
-			err = ErrSyntaxError
-		}
-		if err != nil {
-			return cmd, err
+			return cmd, ErrSyntaxError
 		}
 	}
 
@@ -82,7 +57,7 @@ func parseScan(b baseCmd) (*Scan, error) {
 }
 
 func (cmd *Scan) Run(w Writer, red Redka) (any, error) {
-	res, err := red.Key().Scan(cmd.cursor, cmd.match, cmd.count)
+	res, err := red.Key().ScanType(cmd.cursor, cmd.match, cmd.typ, cmd.count)
 	if err != nil {
 		w.WriteError(cmd.Error(err))
 		return nil, err
@@ -96,3 +71,14 @@ func (cmd *Scan) Run(w Writer, red Redka) (any, error) {
 	}
 	return res, nil
 }
+
+// parseKeyType returns the key type with the name (see core.Key.TypeName).
+// Returns -1 for unknown names, which matches no keys (like in Redis).
+func parseKeyType(name string) core.TypeID {
+	for typ := core.TypeString; typ <= core.TypeHLL; typ++ {
+		if (core.Key{Type: typ}).TypeName() == name {
+			return typ
+		}
+	}
+	return -1
+}
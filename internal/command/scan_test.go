@@ -64,6 +64,22 @@ func TestScanParse(t *testing.T) {
 			count:  5,
 			err:    nil,
 		},
+		{
+			name:   "scan 15 match k2* count 5 type hash",
+			args:   buildArgs("scan", "15", "match", "k2*", "count", "5", "type", "hash"),
+			cursor: 15,
+			match:  "k2*",
+			count:  5,
+			err:    nil,
+		},
+		{
+			name:   "scan 15 type",
+			args:   buildArgs("scan", "15", "type"),
+			cursor: 0,
+			match:  "",
+			count:  0,
+			err:    ErrSyntaxError,
+		},
 		{
 			name:   "scan ten",
 			args:   buildArgs("scan", "ten"),
@@ -158,6 +174,34 @@ func TestScanExec(t *testing.T) {
 		testx.AssertEqual(t, conn.out(), "2,4,2,k21,k22")
 	})
 
+	t.Run("scan type", func(t *testing.T) {
+		_, _ = db.Hash().Set("h1", "f", "v")
+		defer func() { _, _ = db.Key().Delete("h1") }()
+
+		cmd := mustParse[*Scan]("scan 0 type hash")
+		conn := new(fakeConn)
+
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+
+		sres := res.(rkey.ScanResult)
+		testx.AssertEqual(t, len(sres.Keys), 1)
+		testx.AssertEqual(t, sres.Keys[0].Key, "h1")
+		testx.AssertEqual(t, conn.out(), "2,6,1,h1")
+	})
+
+	t.Run("scan unknown type", func(t *testing.T) {
+		cmd := mustParse[*Scan]("scan 0 type foo")
+		conn := new(fakeConn)
+
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+
+		sres := res.(rkey.ScanResult)
+		testx.AssertEqual(t, len(sres.Keys), 0)
+		testx.AssertEqual(t, conn.out(), "2,0,0")
+	})
+
 	t.Run("scan count", func(t *testing.T) {
 		{
 			// page 1
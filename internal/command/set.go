@@ -2,82 +2,91 @@ package command
 
 import (
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/nalgeon/redka/internal/core"
 )
 
 // Set sets the string value of a key, ignoring its type.
 // The key is created if it doesn't exist.
-// SET key value [NX | XX] [EX seconds | PX milliseconds ]
+// SET key value [NX | XX] [GET] [EX seconds | PX milliseconds |
+// EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
 // https://redis.io/commands/set
 type Set struct {
 	baseCmd
-	key   string
-	value []byte
-	ifNX  bool
-	ifXX  bool
-	ttl   time.Duration
+	key     string
+	value   []byte
+	ifNX    bool
+	ifXX    bool
+	get     bool
+	keepTTL bool
+	ttl     time.Duration
+	at      time.Time
 }
 
 func parseSet(b baseCmd) (*Set, error) {
-	parseExists := func(cmd *Set, value string) error {
-		switch value {
-		case "nx":
-			cmd.ifNX = true
-		case "xx":
-			cmd.ifXX = true
-		default:
-			return ErrSyntaxError
-		}
-		return nil
-	}
-
 	parseExpires := func(cmd *Set, unit string, value string) error {
 		valueInt, err := strconv.Atoi(value)
 		if err != nil {
 			return ErrInvalidInt
 		}
 
-		switch string(unit) {
+		if valueInt <= 0 {
+			return ErrInvalidExpireTime
+		}
+
+		switch unit {
 		case "ex":
 			cmd.ttl = time.Duration(valueInt) * time.Second
 		case "px":
 			cmd.ttl = time.Duration(valueInt) * time.Millisecond
-		default:
-			return ErrSyntaxError
-		}
-
-		if cmd.ttl <= 0 {
-			return ErrInvalidExpireTime
+		case "exat":
+			cmd.at = time.Unix(int64(valueInt), 0)
+		case "pxat":
+			cmd.at = time.UnixMilli(int64(valueInt))
 		}
 		return nil
 	}
 
 	cmd := &Set{baseCmd: b}
-	if len(cmd.args) < 2 || len(cmd.args) > 5 {
+	if len(cmd.args) < 2 || len(cmd.args) > 6 {
 		return cmd, ErrInvalidArgNum
 	}
 
 	cmd.key = string(cmd.args[0])
 	cmd.value = cmd.args[1]
 
-	if len(cmd.args) == 3 || len(cmd.args) == 5 {
-		err := parseExists(cmd, string(cmd.args[2]))
-		if err != nil {
-			return cmd, err
-		}
-	}
-
-	if len(cmd.args) == 4 {
-		err := parseExpires(cmd, string(cmd.args[2]), string(cmd.args[3]))
-		if err != nil {
-			return cmd, err
-		}
-	}
-
-	if len(cmd.args) == 5 {
-		err := parseExpires(cmd, string(cmd.args[3]), string(cmd.args[4]))
-		if err != nil {
-			return cmd, err
+	hasExpires := false
+	for i := 2; i < len(cmd.args); i++ {
+		opt := strings.ToLower(string(cmd.args[i]))
+		switch opt {
+		case "nx", "xx":
+			if cmd.ifNX || cmd.ifXX {
+				return cmd, ErrSyntaxError
+			}
+			cmd.ifNX = opt == "nx"
+			cmd.ifXX = opt == "xx"
+		case "get":
+			cmd.get = true
+		case "keepttl":
+			if hasExpires {
+				return cmd, ErrSyntaxError
+			}
+			cmd.keepTTL = true
+			hasExpires = true
+		case "ex", "px", "exat", "pxat":
+			if hasExpires || i+1 >= len(cmd.args) {
+				return cmd, ErrSyntaxError
+			}
+			err := parseExpires(cmd, opt, string(cmd.args[i+1]))
+			if err != nil {
+				return cmd, err
+			}
+			hasExpires = true
+			i++
+		default:
+			return cmd, ErrSyntaxError
 		}
 	}
 
@@ -85,42 +94,47 @@ func parseSet(b baseCmd) (*Set, error) {
 }
 
 func (cmd *Set) Run(w Writer, red Redka) (any, error) {
-	var ok bool
-	var err error
-	if cmd.ifXX {
-		ok, err = red.Str().SetExists(cmd.key, cmd.value, cmd.ttl)
-	} else if cmd.ifNX {
-		ok, err = red.Str().SetNotExists(cmd.key, cmd.value, cmd.ttl)
-	} else {
-		err = red.Str().SetExpires(cmd.key, cmd.value, cmd.ttl)
-		ok = err == nil
+	set := red.Str().SetWith(cmd.key, cmd.value)
+	if cmd.ifNX {
+		set = set.IfNotExists()
 	}
-	return cmd.run(w, ok, err)
-}
-
-func (cmd *Set) RunTx(w Writer, red Redka) (any, error) {
-	var ok bool
-	var err error
 	if cmd.ifXX {
-		ok, err = red.Str().SetExists(cmd.key, cmd.value, cmd.ttl)
-	} else if cmd.ifNX {
-		ok, err = red.Str().SetNotExists(cmd.key, cmd.value, cmd.ttl)
-	} else {
-		err = red.Str().SetExpires(cmd.key, cmd.value, cmd.ttl)
-		ok = err == nil
+		set = set.IfExists()
+	}
+	if cmd.get {
+		set = set.Get()
+	}
+	switch {
+	case cmd.keepTTL:
+		set = set.KeepTTL()
+	case !cmd.at.IsZero():
+		set = set.At(cmd.at)
+	case cmd.ttl > 0:
+		set = set.TTL(cmd.ttl)
 	}
-	return cmd.run(w, ok, err)
-}
 
-func (cmd *Set) run(w Writer, ok bool, err error) (any, error) {
+	out, err := set.Run()
 	if err != nil {
 		w.WriteError(cmd.Error(err))
 		return nil, err
 	}
-	if !ok {
+	if cmd.get {
+		return cmd.writePrev(w, out.Prev)
+	}
+	if !out.Created && !out.Updated {
 		w.WriteNull()
 		return false, nil
 	}
 	w.WriteString("OK")
 	return true, nil
 }
+
+// writePrev writes the previous value of the key (with the GET option).
+func (cmd *Set) writePrev(w Writer, prev core.Value) (any, error) {
+	if !prev.Exists() {
+		w.WriteNull()
+		return prev, nil
+	}
+	w.WriteBulk(prev)
+	return prev, nil
+}
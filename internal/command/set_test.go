@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/nalgeon/redka/internal/core"
 	"github.com/nalgeon/redka/internal/testx"
 )
 
@@ -48,8 +49,7 @@ func TestSetParse(t *testing.T) {
 			name: "set name alice nx xx",
 			args: buildArgs("set", "name", "alice", "nx", "xx"),
 			want: Set{},
-			// FIXME: should be ErrSyntaxError
-			err: ErrInvalidInt,
+			err:  ErrSyntaxError,
 		},
 		{
 			name: "set name alice ex 10",
@@ -75,6 +75,36 @@ func TestSetParse(t *testing.T) {
 			want: Set{key: "name", value: []byte("alice"), ifNX: true, ttl: 10 * time.Second},
 			err:  nil,
 		},
+		{
+			name: "set name alice exat 1700000000",
+			args: buildArgs("set", "name", "alice", "exat", "1700000000"),
+			want: Set{key: "name", value: []byte("alice"), at: time.Unix(1700000000, 0)},
+			err:  nil,
+		},
+		{
+			name: "set name alice xx PXAT 1700000000000",
+			args: buildArgs("set", "name", "alice", "xx", "PXAT", "1700000000000"),
+			want: Set{key: "name", value: []byte("alice"), ifXX: true, at: time.UnixMilli(1700000000000)},
+			err:  nil,
+		},
+		{
+			name: "set name alice get keepttl",
+			args: buildArgs("set", "name", "alice", "get", "keepttl"),
+			want: Set{key: "name", value: []byte("alice"), get: true, keepTTL: true},
+			err:  nil,
+		},
+		{
+			name: "set name alice keepttl ex 10",
+			args: buildArgs("set", "name", "alice", "keepttl", "ex", "10"),
+			want: Set{},
+			err:  ErrSyntaxError,
+		},
+		{
+			name: "set name alice ex",
+			args: buildArgs("set", "name", "alice", "ex"),
+			want: Set{},
+			err:  ErrSyntaxError,
+		},
 	}
 
 	for _, test := range tests {
@@ -88,6 +118,9 @@ func TestSetParse(t *testing.T) {
 				testx.AssertEqual(t, setCmd.ifNX, test.want.ifNX)
 				testx.AssertEqual(t, setCmd.ifXX, test.want.ifXX)
 				testx.AssertEqual(t, setCmd.ttl, test.want.ttl)
+				testx.AssertEqual(t, setCmd.at, test.want.at)
+				testx.AssertEqual(t, setCmd.get, test.want.get)
+				testx.AssertEqual(t, setCmd.keepTTL, test.want.keepTTL)
 			}
 		})
 	}
@@ -145,6 +178,24 @@ func TestSetExec(t *testing.T) {
 			res:  true,
 			out:  "OK",
 		},
+		{
+			name: "set pxat",
+			cmd:  mustParse[*Set]("set color green pxat 32503680000000"),
+			res:  true,
+			out:  "OK",
+		},
+		{
+			name: "set exat expired",
+			cmd:  mustParse[*Set]("set color red exat 1700000000"),
+			res:  true,
+			out:  "OK",
+		},
+		{
+			name: "set nx exat expired conflict",
+			cmd:  mustParse[*Set]("set name bob nx exat 1700000000"),
+			res:  false,
+			out:  "(nil)",
+		},
 	}
 
 	for _, test := range tests {
@@ -156,4 +207,52 @@ func TestSetExec(t *testing.T) {
 			testx.AssertEqual(t, conn.out(), test.out)
 		})
 	}
+
+	count, _ := db.Key().Count("name", "color")
+	testx.AssertEqual(t, count, 1)
+}
+
+func TestSetExecOptions(t *testing.T) {
+	t.Run("get", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		cmd := mustParse[*Set]("set name bob get")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, core.Value("alice"))
+		testx.AssertEqual(t, conn.out(), "alice")
+
+		val, _ := db.Str().Get("name")
+		testx.AssertEqual(t, val, core.Value("bob"))
+	})
+	t.Run("get not found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*Set]("set name bob get")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, core.Value(nil))
+		testx.AssertEqual(t, conn.out(), "(nil)")
+	})
+	t.Run("keepttl", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().SetExpires("name", "alice", time.Minute)
+		before, _ := db.Key().Get("name")
+
+		cmd := mustParse[*Set]("set name bob keepttl")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, true)
+		testx.AssertEqual(t, conn.out(), "OK")
+
+		after, _ := db.Key().Get("name")
+		testx.AssertEqual(t, *after.ETime, *before.ETime)
+	})
 }
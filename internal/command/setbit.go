@@ -0,0 +1,48 @@
+package command
+
+// Sets or clears the bit at offset of the string value.
+// Creates the key if it doesn't exist.
+// SETBIT key offset value
+// https://redis.io/commands/setbit
+type SetBit struct {
+	baseCmd
+	key    string
+	offset int
+	value  bool
+}
+
+func parseSetBit(b baseCmd) (*SetBit, error) {
+	cmd := &SetBit{baseCmd: b}
+	if len(cmd.args) != 3 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	var err error
+	cmd.offset, err = parseBitOffset(cmd.args[1])
+	if err != nil {
+		return cmd, err
+	}
+	switch string(cmd.args[2]) {
+	case "0":
+		cmd.value = false
+	case "1":
+		cmd.value = true
+	default:
+		return cmd, ErrInvalidBit
+	}
+	return cmd, nil
+}
+
+func (cmd *SetBit) Run(w Writer, red Redka) (any, error) {
+	old, err := red.Str().SetBit(cmd.key, cmd.offset, cmd.value)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	if old {
+		w.WriteInt(1)
+		return 1, nil
+	}
+	w.WriteInt(0)
+	return 0, nil
+}
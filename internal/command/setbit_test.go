@@ -0,0 +1,110 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestSetBitParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want SetBit
+		err  error
+	}{
+		{
+			name: "setbit",
+			args: buildArgs("setbit"),
+			want: SetBit{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "setbit key 7",
+			args: buildArgs("setbit", "key", "7"),
+			want: SetBit{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "setbit key 7 1",
+			args: buildArgs("setbit", "key", "7", "1"),
+			want: SetBit{key: "key", offset: 7, value: true},
+			err:  nil,
+		},
+		{
+			name: "setbit key 7 0",
+			args: buildArgs("setbit", "key", "7", "0"),
+			want: SetBit{key: "key", offset: 7, value: false},
+			err:  nil,
+		},
+		{
+			name: "setbit key x 1",
+			args: buildArgs("setbit", "key", "x", "1"),
+			want: SetBit{},
+			err:  ErrInvalidBitOffset,
+		},
+		{
+			name: "setbit key 7 2",
+			args: buildArgs("setbit", "key", "7", "2"),
+			want: SetBit{},
+			err:  ErrInvalidBit,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*SetBit)
+				testx.AssertEqual(t, cm.key, test.want.key)
+				testx.AssertEqual(t, cm.offset, test.want.offset)
+				testx.AssertEqual(t, cm.value, test.want.value)
+			}
+		})
+	}
+}
+
+func TestSetBitExec(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*SetBit]("setbit key 7 1")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 0)
+		testx.AssertEqual(t, conn.out(), "0")
+
+		val, _ := db.Str().Get("key")
+		testx.AssertEqual(t, val, core.Value("\x01"))
+	})
+	t.Run("update", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("key", "\x01")
+
+		cmd := mustParse[*SetBit]("setbit key 7 0")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 1)
+		testx.AssertEqual(t, conn.out(), "1")
+
+		val, _ := db.Str().Get("key")
+		testx.AssertEqual(t, val, core.Value("\x00"))
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().Set("key", "field", "value")
+
+		cmd := mustParse[*SetBit]("setbit key 7 1")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, conn.out(), ErrKeyType.Error()+" (setbit)")
+	})
+}
@@ -0,0 +1,42 @@
+package command
+
+import "strconv"
+
+// Overwrites a part of a string value with another by an offset.
+// Creates the key if it doesn't exist.
+// SETRANGE key offset value
+// https://redis.io/commands/setrange
+type SetRange struct {
+	baseCmd
+	key    string
+	offset int
+	value  []byte
+}
+
+func parseSetRange(b baseCmd) (*SetRange, error) {
+	cmd := &SetRange{baseCmd: b}
+	if len(cmd.args) != 3 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	var err error
+	cmd.offset, err = strconv.Atoi(string(cmd.args[1]))
+	if err != nil {
+		return cmd, ErrInvalidInt
+	}
+	if cmd.offset < 0 {
+		return cmd, ErrOffsetOutOfRange
+	}
+	cmd.value = cmd.args[2]
+	return cmd, nil
+}
+
+func (cmd *SetRange) Run(w Writer, red Redka) (any, error) {
+	n, err := red.Str().SetRange(cmd.key, cmd.offset, cmd.value)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	w.WriteInt(n)
+	return n, nil
+}
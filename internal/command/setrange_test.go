@@ -0,0 +1,105 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestSetRangeParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want SetRange
+		err  error
+	}{
+		{
+			name: "setrange",
+			args: buildArgs("setrange"),
+			want: SetRange{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "setrange key 5",
+			args: buildArgs("setrange", "key", "5"),
+			want: SetRange{},
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "setrange key 5 value",
+			args: buildArgs("setrange", "key", "5", "value"),
+			want: SetRange{key: "key", offset: 5, value: []byte("value")},
+			err:  nil,
+		},
+		{
+			name: "setrange key x value",
+			args: buildArgs("setrange", "key", "x", "value"),
+			want: SetRange{},
+			err:  ErrInvalidInt,
+		},
+		{
+			name: "setrange key -1 value",
+			args: buildArgs("setrange", "key", "-1", "value"),
+			want: SetRange{},
+			err:  ErrOffsetOutOfRange,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*SetRange)
+				testx.AssertEqual(t, cm.key, test.want.key)
+				testx.AssertEqual(t, cm.offset, test.want.offset)
+				testx.AssertEqual(t, cm.value, test.want.value)
+			}
+		})
+	}
+}
+
+func TestSetRangeExec(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*SetRange]("setrange key 1 a")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 2)
+		testx.AssertEqual(t, conn.out(), "2")
+
+		val, _ := db.Str().Get("key")
+		testx.AssertEqual(t, val, core.Value("\x00a"))
+	})
+	t.Run("update", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("key", "hello world")
+
+		cmd := mustParse[*SetRange]("setrange key 6 redka")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 11)
+		testx.AssertEqual(t, conn.out(), "11")
+
+		val, _ := db.Str().Get("key")
+		testx.AssertEqual(t, val, core.Value("hello redka"))
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().Set("key", "field", "value")
+
+		cmd := mustParse[*SetRange]("setrange key 0 value")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, res, nil)
+		testx.AssertEqual(t, conn.out(), ErrKeyType.Error()+" (setrange)")
+	})
+}
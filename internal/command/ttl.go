@@ -0,0 +1,45 @@
+package command
+
+import (
+	"time"
+
+	"github.com/nalgeon/redka/internal/rkey"
+)
+
+// Returns the expiration time in seconds of a key.
+// TTL key
+// https://redis.io/commands/ttl
+type TTL struct {
+	baseCmd
+	key  string
+	unit time.Duration
+}
+
+func parseTTL(b baseCmd, unit time.Duration) (*TTL, error) {
+	cmd := &TTL{baseCmd: b, unit: unit}
+	if len(cmd.args) != 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	return cmd, nil
+}
+
+func (cmd *TTL) Run(w Writer, red Redka) (any, error) {
+	ttl, err := red.Key().TTL(cmd.key)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	var res int
+	switch ttl {
+	case rkey.NoKeyTTL:
+		res = -2
+	case rkey.NoExpireTTL:
+		res = -1
+	default:
+		// Round to the nearest unit, like Redis does.
+		res = int((ttl + cmd.unit/2) / cmd.unit)
+	}
+	w.WriteInt(res)
+	return res, nil
+}
@@ -0,0 +1,93 @@
+package command
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestTTLParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		key  string
+		unit time.Duration
+		err  error
+	}{
+		{
+			name: "ttl",
+			args: buildArgs("ttl"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "ttl name",
+			args: buildArgs("ttl", "name"),
+			key:  "name",
+			unit: time.Second,
+		},
+		{
+			name: "pttl name",
+			args: buildArgs("pttl", "name"),
+			key:  "name",
+			unit: time.Millisecond,
+		},
+		{
+			name: "ttl name age",
+			args: buildArgs("ttl", "name", "age"),
+			err:  ErrInvalidArgNum,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*TTL)
+				testx.AssertEqual(t, cm.key, test.key)
+				testx.AssertEqual(t, cm.unit, test.unit)
+			}
+		})
+	}
+}
+
+func TestTTLExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+	_ = db.Str().SetExpires("name", "alice", time.Minute)
+	_ = db.Str().Set("age", 25)
+
+	t.Run("ttl", func(t *testing.T) {
+		cmd := mustParse[*TTL]("ttl name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 60)
+		testx.AssertEqual(t, conn.out(), "60")
+	})
+	t.Run("pttl", func(t *testing.T) {
+		cmd := mustParse[*TTL]("pttl name")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		ttl := res.(int)
+		testx.AssertEqual(t, ttl > 59000 && ttl <= 60000, true)
+	})
+	t.Run("no expire", func(t *testing.T) {
+		cmd := mustParse[*TTL]("ttl age")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, -1)
+		testx.AssertEqual(t, conn.out(), "-1")
+	})
+	t.Run("not found", func(t *testing.T) {
+		cmd := mustParse[*TTL]("pttl city")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, -2)
+		testx.AssertEqual(t, conn.out(), "-2")
+	})
+}
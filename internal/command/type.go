@@ -0,0 +1,33 @@
+package command
+
+// Returns the type of value stored at a key.
+// TYPE key
+// https://redis.io/commands/type
+type Type struct {
+	baseCmd
+	key string
+}
+
+func parseType(b baseCmd) (*Type, error) {
+	cmd := &Type{baseCmd: b}
+	if len(cmd.args) != 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	return cmd, nil
+}
+
+func (cmd *Type) Run(w Writer, red Redka) (any, error) {
+	key, err := red.Key().Get(cmd.key)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	if !key.Exists() {
+		w.WriteString("none")
+		return "none", nil
+	}
+	typ := key.TypeName()
+	w.WriteString(typ)
+	return typ, nil
+}
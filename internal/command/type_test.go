@@ -0,0 +1,75 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestTypeParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		key  string
+		err  error
+	}{
+		{
+			name: "type",
+			args: buildArgs("type"),
+			key:  "",
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "type name",
+			args: buildArgs("type", "name"),
+			key:  "name",
+			err:  nil,
+		},
+		{
+			name: "type name age",
+			args: buildArgs("type", "name", "age"),
+			key:  "",
+			err:  ErrInvalidArgNum,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				testx.AssertEqual(t, cmd.(*Type).key, test.key)
+			}
+		})
+	}
+}
+
+func TestTypeExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	_, _ = db.Hash().Set("person", "name", "alice")
+	_, _ = db.SortedSet().Add("race", "alice", 11)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"name", "string"},
+		{"person", "hash"},
+		{"race", "zset"},
+		{"city", "none"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			cmd := mustParse[*Type]("type " + test.key)
+			conn := new(fakeConn)
+			res, err := cmd.Run(conn, red)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, res, test.want)
+			testx.AssertEqual(t, conn.out(), test.want)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package command
+
+// Deletes one or more keys. Unlike DEL, deletes
+// the values in the background.
+// UNLINK key [key ...]
+// https://redis.io/commands/unlink
+type Unlink struct {
+	baseCmd
+	keys []string
+}
+
+func parseUnlink(b baseCmd) (*Unlink, error) {
+	cmd := &Unlink{baseCmd: b}
+	if len(cmd.args) < 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.keys = make([]string, len(cmd.args))
+	for i, arg := range cmd.args {
+		cmd.keys[i] = string(arg)
+	}
+	return cmd, nil
+}
+
+func (cmd *Unlink) Run(w Writer, red Redka) (any, error) {
+	count, err := red.Key().Unlink(cmd.keys...)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	w.WriteInt(count)
+	return count, nil
+}
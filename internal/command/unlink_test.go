@@ -0,0 +1,100 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestUnlinkParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		want []string
+		err  error
+	}{
+		{
+			name: "unlink",
+			args: buildArgs("unlink"),
+			want: nil,
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "unlink name",
+			args: buildArgs("unlink", "name"),
+			want: []string{"name"},
+			err:  nil,
+		},
+		{
+			name: "unlink name age",
+			args: buildArgs("unlink", "name", "age"),
+			want: []string{"name", "age"},
+			err:  nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				testx.AssertEqual(t, cmd.(*Unlink).keys, test.want)
+			}
+		})
+	}
+}
+
+func TestUnlinkExec(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  *Unlink
+		res  any
+		out  string
+	}{
+		{
+			name: "unlink one",
+			cmd:  mustParse[*Unlink]("unlink name"),
+			res:  1,
+			out:  "1",
+		},
+		{
+			name: "unlink all",
+			cmd:  mustParse[*Unlink]("unlink name person"),
+			res:  2,
+			out:  "2",
+		},
+		{
+			name: "unlink some",
+			cmd:  mustParse[*Unlink]("unlink name person street"),
+			res:  2,
+			out:  "2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			db, red := getDB(t)
+			defer db.Close()
+
+			_ = db.Str().Set("name", "alice")
+			_, _ = db.Hash().Set("person", "name", "alice")
+			_ = db.Str().Set("city", "paris")
+
+			conn := new(fakeConn)
+			res, err := test.cmd.Run(conn, red)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, res, test.res)
+			testx.AssertEqual(t, conn.out(), test.out)
+
+			name, _ := db.Str().Get("name")
+			testx.AssertEqual(t, name.Exists(), false)
+			city, _ := db.Str().Get("city")
+			testx.AssertEqual(t, city.String(), "paris")
+
+			// The unlinked key can be reused right away.
+			_ = db.Str().Set("name", "bob")
+			name, _ = db.Str().Get("name")
+			testx.AssertEqual(t, name.String(), "bob")
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package command
+
+import (
+	"github.com/nalgeon/redka/internal/rstream"
+)
+
+// Appends an entry to a stream.
+// XADD key <* | id> field value [field value ...]
+// https://redis.io/commands/xadd
+type XAdd struct {
+	baseCmd
+	key    string
+	autoID bool
+	id     rstream.ID
+	fields []any
+}
+
+func parseXAdd(b baseCmd) (*XAdd, error) {
+	cmd := &XAdd{baseCmd: b}
+	if len(cmd.args) < 4 || len(cmd.args)%2 != 0 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	if id := string(cmd.args[1]); id == "*" {
+		cmd.autoID = true
+	} else {
+		var err error
+		cmd.id, err = parseStreamID(id, false)
+		if err != nil {
+			return cmd, err
+		}
+	}
+	cmd.fields = make([]any, 0, len(cmd.args)-2)
+	for i := 2; i < len(cmd.args); i += 2 {
+		cmd.fields = append(cmd.fields, string(cmd.args[i]), cmd.args[i+1])
+	}
+	return cmd, nil
+}
+
+func (cmd *XAdd) Run(w Writer, red Redka) (any, error) {
+	id := cmd.id
+	var err error
+	if cmd.autoID {
+		id, err = red.Stream().Add(cmd.key, cmd.fields...)
+	} else {
+		err = red.Stream().AddID(cmd.key, cmd.id, cmd.fields...)
+	}
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	// Record the generated ID, so that the journal and
+	// the replicas add the entry with the same ID.
+	cmd.args[1] = []byte(id.String())
+	w.WriteBulkString(id.String())
+	return id, nil
+}
+
+// parseStreamID parses the stream entry ID (see rstream.ParseID).
+// Does not accept "-" and "+" unless allowed by the caller.
+func parseStreamID(s string, end bool) (rstream.ID, error) {
+	if s == "-" || s == "+" {
+		return rstream.ID{}, ErrInvalidStreamID
+	}
+	id, err := rstream.ParseID(s, end)
+	if err != nil {
+		return rstream.ID{}, ErrInvalidStreamID
+	}
+	return id, nil
+}
@@ -0,0 +1,144 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestXAddParse(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   [][]byte
+		key    string
+		autoID bool
+		id     rstream.ID
+		fields []any
+		err    error
+	}{
+		{
+			name: "xadd",
+			args: buildArgs("xadd"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "xadd events *",
+			args: buildArgs("xadd", "events", "*"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "xadd events * user",
+			args: buildArgs("xadd", "events", "*", "user"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name:   "xadd events * user alice",
+			args:   buildArgs("xadd", "events", "*", "user", "alice"),
+			key:    "events",
+			autoID: true,
+			fields: []any{"user", []byte("alice")},
+			err:    nil,
+		},
+		{
+			name:   "xadd events 5-1 user alice age 25",
+			args:   buildArgs("xadd", "events", "5-1", "user", "alice", "age", "25"),
+			key:    "events",
+			id:     rstream.ID{Time: 5, Seq: 1},
+			fields: []any{"user", []byte("alice"), "age", []byte("25")},
+			err:    nil,
+		},
+		{
+			name:   "xadd events 5 user alice",
+			args:   buildArgs("xadd", "events", "5", "user", "alice"),
+			key:    "events",
+			id:     rstream.ID{Time: 5, Seq: 0},
+			fields: []any{"user", []byte("alice")},
+			err:    nil,
+		},
+		{
+			name: "xadd events + user alice",
+			args: buildArgs("xadd", "events", "+", "user", "alice"),
+			err:  ErrInvalidStreamID,
+		},
+		{
+			name: "xadd events 5-x user alice",
+			args: buildArgs("xadd", "events", "5-x", "user", "alice"),
+			err:  ErrInvalidStreamID,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*XAdd)
+				testx.AssertEqual(t, cm.key, test.key)
+				testx.AssertEqual(t, cm.autoID, test.autoID)
+				testx.AssertEqual(t, cm.id, test.id)
+				testx.AssertEqual(t, cm.fields, test.fields)
+			}
+		})
+	}
+}
+
+func TestXAddExec(t *testing.T) {
+	t.Run("auto id", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*XAdd]("xadd events * user alice")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		id := res.(rstream.ID)
+		testx.AssertEqual(t, conn.out(), id.String())
+
+		// The generated ID replaces the * in the arguments.
+		testx.AssertEqual(t, cmd.String(), "events "+id.String()+" user alice")
+
+		entries, _ := db.Stream().Range("events", rstream.MinID, rstream.MaxID, 0)
+		testx.AssertEqual(t, len(entries), 1)
+		testx.AssertEqual(t, entries[0].ID, id)
+		testx.AssertEqual(t, entries[0].Fields[0].Name, "user")
+		testx.AssertEqual(t, entries[0].Fields[0].Value.String(), "alice")
+	})
+	t.Run("explicit id", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*XAdd]("xadd events 5-1 user alice")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, rstream.ID{Time: 5, Seq: 1})
+		testx.AssertEqual(t, conn.out(), "5-1")
+	})
+	t.Run("id not greater", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_ = db.Stream().AddID("events", rstream.ID{Time: 5, Seq: 1}, "user", "alice")
+
+		cmd := mustParse[*XAdd]("xadd events 5-1 user bob")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertErr(t, err, core.ErrStreamID)
+		testx.AssertEqual(t, res, nil)
+		testx.AssertEqual(t, conn.out(), ErrStreamID.Error()+" (xadd)")
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().Set("events", "value")
+
+		cmd := mustParse[*XAdd]("xadd events * user alice")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, conn.out(), ErrKeyType.Error()+" (xadd)")
+	})
+}
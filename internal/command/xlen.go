@@ -0,0 +1,28 @@
+package command
+
+// Returns the number of entries in a stream.
+// XLEN key
+// https://redis.io/commands/xlen
+type XLen struct {
+	baseCmd
+	key string
+}
+
+func parseXLen(b baseCmd) (*XLen, error) {
+	cmd := &XLen{baseCmd: b}
+	if len(cmd.args) != 1 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+	return cmd, nil
+}
+
+func (cmd *XLen) Run(w Writer, red Redka) (any, error) {
+	count, err := red.Stream().Len(cmd.key)
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	w.WriteInt(count)
+	return count, nil
+}
@@ -0,0 +1,74 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestXLenParse(t *testing.T) {
+	tests := []struct {
+		name string
+		args [][]byte
+		key  string
+		err  error
+	}{
+		{
+			name: "xlen",
+			args: buildArgs("xlen"),
+			key:  "",
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "xlen events",
+			args: buildArgs("xlen", "events"),
+			key:  "events",
+			err:  nil,
+		},
+		{
+			name: "xlen events other",
+			args: buildArgs("xlen", "events", "other"),
+			key:  "",
+			err:  ErrInvalidArgNum,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*XLen)
+				testx.AssertEqual(t, cm.key, test.key)
+			}
+		})
+	}
+}
+
+func TestXLenExec(t *testing.T) {
+	t.Run("key found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		_, _ = db.Stream().Add("events", "user", "alice")
+		_, _ = db.Stream().Add("events", "user", "bob")
+
+		cmd := mustParse[*XLen]("xlen events")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 2)
+		testx.AssertEqual(t, conn.out(), "2")
+	})
+	t.Run("key not found", func(t *testing.T) {
+		db, red := getDB(t)
+		defer db.Close()
+
+		cmd := mustParse[*XLen]("xlen events")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, 0)
+		testx.AssertEqual(t, conn.out(), "0")
+	})
+}
@@ -0,0 +1,92 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nalgeon/redka/internal/rstream"
+)
+
+// Returns the stream entries with IDs in a range.
+// XRANGE key start end [COUNT count]
+// https://redis.io/commands/xrange
+//
+// Returns the entries in reverse order, starting from end.
+// XREVRANGE key end start [COUNT count]
+// https://redis.io/commands/xrevrange
+type XRange struct {
+	baseCmd
+	key     string
+	start   rstream.ID
+	end     rstream.ID
+	count   int
+	reverse bool
+}
+
+func parseXRange(b baseCmd, reverse bool) (*XRange, error) {
+	cmd := &XRange{baseCmd: b, reverse: reverse}
+	if len(cmd.args) != 3 && len(cmd.args) != 5 {
+		return cmd, ErrInvalidArgNum
+	}
+	cmd.key = string(cmd.args[0])
+
+	// XREVRANGE takes the end first.
+	startArg, endArg := string(cmd.args[1]), string(cmd.args[2])
+	if reverse {
+		startArg, endArg = endArg, startArg
+	}
+	var err error
+	cmd.start, err = rstream.ParseID(startArg, false)
+	if err != nil {
+		return cmd, ErrInvalidStreamID
+	}
+	cmd.end, err = rstream.ParseID(endArg, true)
+	if err != nil {
+		return cmd, ErrInvalidStreamID
+	}
+
+	if len(cmd.args) == 5 {
+		if strings.ToLower(string(cmd.args[3])) != "count" {
+			return cmd, ErrSyntaxError
+		}
+		cmd.count, err = strconv.Atoi(string(cmd.args[4]))
+		if err != nil {
+			return cmd, ErrInvalidInt
+		}
+		if cmd.count < 0 {
+			cmd.count = 0
+		}
+	}
+	return cmd, nil
+}
+
+func (cmd *XRange) Run(w Writer, red Redka) (any, error) {
+	var entries []rstream.Entry
+	var err error
+	if cmd.reverse {
+		entries, err = red.Stream().RevRange(cmd.key, cmd.end, cmd.start, cmd.count)
+	} else {
+		entries, err = red.Stream().Range(cmd.key, cmd.start, cmd.end, cmd.count)
+	}
+	if err != nil {
+		w.WriteError(cmd.Error(err))
+		return nil, err
+	}
+	writeEntries(w, entries)
+	return entries, nil
+}
+
+// writeEntries writes the stream entries as an array
+// of [id, [field, value, ...]] pairs.
+func writeEntries(w Writer, entries []rstream.Entry) {
+	w.WriteArray(len(entries))
+	for _, e := range entries {
+		w.WriteArray(2)
+		w.WriteBulkString(e.ID.String())
+		w.WriteArray(len(e.Fields) * 2)
+		for _, f := range e.Fields {
+			w.WriteBulkString(f.Name)
+			w.WriteBulk(f.Value.Bytes())
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package command
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestXRangeParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    [][]byte
+		key     string
+		start   rstream.ID
+		end     rstream.ID
+		count   int
+		reverse bool
+		err     error
+	}{
+		{
+			name: "xrange events",
+			args: buildArgs("xrange", "events"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name:  "xrange events - +",
+			args:  buildArgs("xrange", "events", "-", "+"),
+			key:   "events",
+			start: rstream.MinID,
+			end:   rstream.MaxID,
+		},
+		{
+			name:  "xrange events 1 2-5 count 10",
+			args:  buildArgs("xrange", "events", "1", "2-5", "count", "10"),
+			key:   "events",
+			start: rstream.ID{Time: 1, Seq: 0},
+			end:   rstream.ID{Time: 2, Seq: 5},
+			count: 10,
+		},
+		{
+			name:  "xrange events 1 2",
+			args:  buildArgs("xrange", "events", "1", "2"),
+			key:   "events",
+			start: rstream.ID{Time: 1, Seq: 0},
+			end:   rstream.ID{Time: 2, Seq: math.MaxInt64},
+		},
+		{
+			name:    "xrevrange events + 1",
+			args:    buildArgs("xrevrange", "events", "+", "1"),
+			key:     "events",
+			start:   rstream.ID{Time: 1, Seq: 0},
+			end:     rstream.MaxID,
+			reverse: true,
+		},
+		{
+			name: "xrange events x +",
+			args: buildArgs("xrange", "events", "x", "+"),
+			err:  ErrInvalidStreamID,
+		},
+		{
+			name: "xrange events - + limit 10",
+			args: buildArgs("xrange", "events", "-", "+", "limit", "10"),
+			err:  ErrSyntaxError,
+		},
+		{
+			name: "xrange events - + count x",
+			args: buildArgs("xrange", "events", "-", "+", "count", "x"),
+			err:  ErrInvalidInt,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*XRange)
+				testx.AssertEqual(t, cm.key, test.key)
+				testx.AssertEqual(t, cm.start, test.start)
+				testx.AssertEqual(t, cm.end, test.end)
+				testx.AssertEqual(t, cm.count, test.count)
+				testx.AssertEqual(t, cm.reverse, test.reverse)
+			}
+		})
+	}
+}
+
+func TestXRangeExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+
+	_ = db.Stream().AddID("events", rstream.ID{Time: 1, Seq: 0}, "user", "alice")
+	_ = db.Stream().AddID("events", rstream.ID{Time: 1, Seq: 1}, "user", "bob", "age", 25)
+	_ = db.Stream().AddID("events", rstream.ID{Time: 2, Seq: 0}, "user", "cindy")
+
+	t.Run("all", func(t *testing.T) {
+		cmd := mustParse[*XRange]("xrange events - +")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(res.([]rstream.Entry)), 3)
+		testx.AssertEqual(t, conn.out(),
+			"3,2,1-0,2,user,alice,2,1-1,4,user,bob,age,25,2,2-0,2,user,cindy")
+	})
+	t.Run("range", func(t *testing.T) {
+		cmd := mustParse[*XRange]("xrange events 1-1 2")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, conn.out(), "2,2,1-1,4,user,bob,age,25,2,2-0,2,user,cindy")
+	})
+	t.Run("count", func(t *testing.T) {
+		cmd := mustParse[*XRange]("xrange events - + count 1")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, conn.out(), "1,2,1-0,2,user,alice")
+	})
+	t.Run("reverse", func(t *testing.T) {
+		cmd := mustParse[*XRange]("xrevrange events + 1 count 2")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, conn.out(), "2,2,2-0,2,user,cindy,2,1-1,4,user,bob,age,25")
+	})
+	t.Run("key not found", func(t *testing.T) {
+		cmd := mustParse[*XRange]("xrange other - +")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, conn.out(), "0")
+	})
+}
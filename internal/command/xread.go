@@ -0,0 +1,110 @@
+package command
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nalgeon/redka/internal/rstream"
+)
+
+// Returns the entries added to one or more streams
+// after the given IDs. Does not support blocking.
+// XREAD [COUNT count] STREAMS key [key ...] id [id ...]
+// https://redis.io/commands/xread
+type XRead struct {
+	baseCmd
+	count int
+	keys  []string
+	ids   []string
+}
+
+// XReadResult is the entries read from a stream by XREAD.
+type XReadResult struct {
+	Key     string
+	Entries []rstream.Entry
+}
+
+func parseXRead(b baseCmd) (*XRead, error) {
+	cmd := &XRead{baseCmd: b}
+	if len(cmd.args) < 3 {
+		return cmd, ErrInvalidArgNum
+	}
+	args := cmd.args
+	for len(args) > 0 {
+		switch strings.ToLower(string(args[0])) {
+		case "count":
+			if len(args) < 2 {
+				return cmd, ErrSyntaxError
+			}
+			var err error
+			cmd.count, err = strconv.Atoi(string(args[1]))
+			if err != nil {
+				return cmd, ErrInvalidInt
+			}
+			if cmd.count < 0 {
+				cmd.count = 0
+			}
+			args = args[2:]
+		case "streams":
+			args = args[1:]
+			if len(args) == 0 || len(args)%2 != 0 {
+				return cmd, ErrInvalidArgNum
+			}
+			n := len(args) / 2
+			cmd.keys = make([]string, n)
+			cmd.ids = make([]string, n)
+			for i := range n {
+				cmd.keys[i] = string(args[i])
+				cmd.ids[i] = string(args[n+i])
+				if cmd.ids[i] == "$" {
+					continue
+				}
+				if _, err := parseStreamID(cmd.ids[i], false); err != nil {
+					return cmd, err
+				}
+			}
+			return cmd, nil
+		default:
+			return cmd, ErrSyntaxError
+		}
+	}
+	return cmd, ErrSyntaxError
+}
+
+func (cmd *XRead) Run(w Writer, red Redka) (any, error) {
+	var results []XReadResult
+	for i, key := range cmd.keys {
+		entries, err := cmd.read(red, key, cmd.ids[i])
+		if err != nil {
+			w.WriteError(cmd.Error(err))
+			return nil, err
+		}
+		if len(entries) > 0 {
+			results = append(results, XReadResult{Key: key, Entries: entries})
+		}
+	}
+
+	// Like Redis, reply with null if there are no entries.
+	if len(results) == 0 {
+		w.WriteNull()
+		return results, nil
+	}
+	w.WriteArray(len(results))
+	for _, res := range results {
+		w.WriteArray(2)
+		w.WriteBulkString(res.Key)
+		writeEntries(w, res.Entries)
+	}
+	return results, nil
+}
+
+// read returns the stream entries added after the ID.
+// The $ ID means the last entry in the stream, so without
+// blocking there are no entries to return.
+func (cmd *XRead) read(red Redka, key, idArg string) ([]rstream.Entry, error) {
+	if idArg == "$" {
+		return nil, nil
+	}
+	id, _ := parseStreamID(idArg, false)
+	return red.Stream().Read(key, id, cmd.count)
+}
@@ -0,0 +1,114 @@
+package command
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestXReadParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  [][]byte
+		count int
+		keys  []string
+		ids   []string
+		err   error
+	}{
+		{
+			name: "xread streams events",
+			args: buildArgs("xread", "streams", "events"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "xread streams events 0",
+			args: buildArgs("xread", "streams", "events", "0"),
+			keys: []string{"events"},
+			ids:  []string{"0"},
+		},
+		{
+			name:  "xread count 10 streams events other 1-1 $",
+			args:  buildArgs("xread", "count", "10", "streams", "events", "other", "1-1", "$"),
+			count: 10,
+			keys:  []string{"events", "other"},
+			ids:   []string{"1-1", "$"},
+		},
+		{
+			name: "xread streams events other 0",
+			args: buildArgs("xread", "streams", "events", "other", "0"),
+			err:  ErrInvalidArgNum,
+		},
+		{
+			name: "xread count 10 events 0",
+			args: buildArgs("xread", "count", "10", "events", "0"),
+			err:  ErrSyntaxError,
+		},
+		{
+			name: "xread count x streams events 0",
+			args: buildArgs("xread", "count", "x", "streams", "events", "0"),
+			err:  ErrInvalidInt,
+		},
+		{
+			name: "xread streams events x",
+			args: buildArgs("xread", "streams", "events", "x"),
+			err:  ErrInvalidStreamID,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cmd, err := Parse(test.args)
+			testx.AssertEqual(t, err, test.err)
+			if err == nil {
+				cm := cmd.(*XRead)
+				testx.AssertEqual(t, cm.count, test.count)
+				testx.AssertEqual(t, cm.keys, test.keys)
+				testx.AssertEqual(t, cm.ids, test.ids)
+			}
+		})
+	}
+}
+
+func TestXReadExec(t *testing.T) {
+	db, red := getDB(t)
+	defer db.Close()
+
+	_ = db.Stream().AddID("events", rstream.ID{Time: 1, Seq: 0}, "user", "alice")
+	_ = db.Stream().AddID("events", rstream.ID{Time: 2, Seq: 0}, "user", "bob")
+	_ = db.Stream().AddID("other", rstream.ID{Time: 3, Seq: 0}, "user", "cindy")
+
+	t.Run("after id", func(t *testing.T) {
+		cmd := mustParse[*XRead]("xread streams events 1-0")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		results := res.([]XReadResult)
+		testx.AssertEqual(t, len(results), 1)
+		testx.AssertEqual(t, results[0].Key, "events")
+		testx.AssertEqual(t, conn.out(), "1,2,events,1,2,2-0,2,user,bob")
+	})
+	t.Run("multiple keys", func(t *testing.T) {
+		cmd := mustParse[*XRead]("xread count 1 streams events other 0 0")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, conn.out(),
+			"2,2,events,1,2,1-0,2,user,alice,2,other,1,2,3-0,2,user,cindy")
+	})
+	t.Run("skip empty", func(t *testing.T) {
+		cmd := mustParse[*XRead]("xread streams events other 2-0 0")
+		conn := new(fakeConn)
+		_, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, conn.out(), "1,2,other,1,2,3-0,2,user,cindy")
+	})
+	t.Run("no entries", func(t *testing.T) {
+		cmd := mustParse[*XRead]("xread streams events missing $ 0")
+		conn := new(fakeConn)
+		res, err := cmd.Run(conn, red)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(res.([]XReadResult)), 0)
+		testx.AssertEqual(t, conn.out(), "(nil)")
+	})
+}
@@ -3,6 +3,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 )
 
@@ -16,6 +17,8 @@ const (
 	TypeSet       = TypeID(3)
 	TypeHash      = TypeID(4)
 	TypeSortedSet = TypeID(5)
+	TypeStream    = TypeID(6)
+	TypeHLL       = TypeID(7)
 )
 
 // InitialVersion is the initial version of the key.
@@ -23,12 +26,61 @@ const InitialVersion = 1
 
 // Common errors returned by data structure methods.
 var (
-	ErrNotFound   = errors.New("key not found")
-	ErrKeyType    = errors.New("key type mismatch") // the key already exists with a different type.
-	ErrValueType  = errors.New("invalid value type")
-	ErrNotAllowed = errors.New("operation not allowed")
+	ErrNotFound        = errors.New("key not found")
+	ErrKeyType         = errors.New("key type mismatch") // the key already exists with a different type.
+	ErrValueType       = errors.New("invalid value type")
+	ErrNotAllowed      = errors.New("operation not allowed")
+	ErrBusy            = errors.New("database is busy")      // the write lock could not be acquired.
+	ErrTxClosed        = errors.New("transaction is closed") // used after commit or rollback.
+	ErrValueTooLarge   = errors.New("value is too large")    // exceeds the size limit.
+	ErrSyntax          = errors.New("syntax error")
+	ErrReadOnly        = errors.New("database is read-only")
+	ErrVersion         = errors.New("key version mismatch")
+	ErrKeyTooLarge     = errors.New("key is too large")        // exceeds the key size limit.
+	ErrTooManyElements = errors.New("too many elements")       // exceeds the collection size limit.
+	ErrCrossSlot       = errors.New("keys in different slots") // keys of a multi-key operation hash to different slots.
+	ErrQuotaExceeded   = errors.New("quota exceeded")          // the write exceeds the quota of the key prefix.
+	ErrStreamID        = errors.New("invalid stream ID")       // not greater than the last entry ID.
 )
 
+// KeyTypeError is returned when the key already exists
+// with a different type. Matches ErrKeyType with errors.Is.
+type KeyTypeError struct {
+	Key      string
+	Expected TypeID
+	Actual   TypeID
+}
+
+// Error returns the error message.
+func (e *KeyTypeError) Error() string {
+	return fmt.Sprintf("%s: key %q is %s, not %s", ErrKeyType, e.Key,
+		Key{Type: e.Actual}.TypeName(), Key{Type: e.Expected}.TypeName())
+}
+
+// Is reports whether the target is ErrKeyType.
+func (e *KeyTypeError) Is(target error) bool {
+	return target == ErrKeyType
+}
+
+// QuotaError is returned when a write exceeds the quota
+// of the key prefix. Matches ErrQuotaExceeded with errors.Is.
+type QuotaError struct {
+	Prefix   string // quota key prefix
+	Resource string // "keys", "bytes" or "ops"
+	Limit    int64  // quota limit
+}
+
+// Error returns the error message.
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: prefix %q is limited to %d %s", ErrQuotaExceeded,
+		e.Prefix, e.Limit, e.Resource)
+}
+
+// Is reports whether the target is ErrQuotaExceeded.
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
 // Key represents a key data structure.
 // Each key uniquely identifies a data structure stored in the
 // database (e.g. a string, a list, or a hash). There can be only one
@@ -49,6 +101,14 @@ func (k Key) Exists() bool {
 	return k.Key != ""
 }
 
+// SameVersion reports whether both are the same version of the
+// same key. The key IDs are not reused, so a key that was deleted
+// and created again is not the same even with the same version.
+// Non-existing keys are the same.
+func (k Key) SameVersion(other Key) bool {
+	return k.ID == other.ID && k.Version == other.Version
+}
+
 // TypeName returns the name of the key type.
 func (k Key) TypeName() string {
 	switch k.Type {
@@ -62,6 +122,10 @@ func (k Key) TypeName() string {
 		return "hash"
 	case TypeSortedSet:
 		return "zset"
+	case TypeStream:
+		return "stream"
+	case TypeHLL:
+		return "hll"
 	}
 	return "unknown"
 }
@@ -154,3 +218,67 @@ func IsValueType(v any) bool {
 	}
 	return false
 }
+
+// ChangeOp is the kind of change made to a key.
+type ChangeOp string
+
+const (
+	ChangeSet     = ChangeOp("set")     // value, hash field or sorted set member set
+	ChangeDelete  = ChangeOp("delete")  // key, hash field or sorted set member deleted
+	ChangeExpire  = ChangeOp("expire")  // expiration time changed
+	ChangeRename  = ChangeOp("rename")  // key renamed
+	ChangeExpired = ChangeOp("expired") // key deleted after its expiration time
+)
+
+// Change describes a single change made to a key.
+//
+// Before and After hold the previous and the new state, depending on Op:
+//   - ChangeSet: the string value, hash field value or sorted set
+//     member score. Before is nil if the value did not exist.
+//   - ChangeDelete: Before holds the deleted value for hash fields
+//     and sorted set members (and strings). Both are nil for a key.
+//   - ChangeExpire: the expiration time in unix milliseconds.
+//     Nil means the key does not expire.
+//   - ChangeRename: the old and the new key name.
+//   - ChangeExpired: both are nil. The values of the key
+//     are reported as deleted (ChangeDelete) after it.
+type Change struct {
+	Op     ChangeOp
+	Key    string
+	Type   TypeID
+	Field  string // hash field or sorted set member (if any)
+	Before Value
+	After  Value
+}
+
+// TypeName returns the name of the key type.
+func (c Change) TypeName() string {
+	return Key{Type: c.Type}.TypeName()
+}
+
+// ChangeEvent describes the changes made by a committed
+// transaction, in the order they were made.
+type ChangeEvent struct {
+	Changes []Change
+	// Created lists the keys created by the transaction
+	// (including the ones created and then deleted).
+	Created []string
+}
+
+// EventOp is the kind of keyspace event (see Event).
+type EventOp string
+
+const (
+	EventCreated  = EventOp("created")  // key created
+	EventModified = EventOp("modified") // key value or expiration time changed
+	EventDeleted  = EventOp("deleted")  // key deleted
+	EventExpired  = EventOp("expired")  // key deleted after its expiration time
+)
+
+// Event is a keyspace event: a key created, modified,
+// deleted or expired by a committed transaction.
+type Event struct {
+	Op   EventOp
+	Key  string
+	Type TypeID
+}
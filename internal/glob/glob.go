@@ -0,0 +1,351 @@
+// Package glob implements the Redis glob-style pattern matching.
+package glob
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// maxSQLite is the maximum length of the SQLite pattern.
+// Longer patterns are replaced with "*" (see Pattern.SQLite),
+// so they never hit the SQLite pattern complexity limit.
+const maxSQLite = 10000
+
+// Match reports whether the name matches the Redis glob pattern
+// (*, ?, [abc], [^abc], [a-z] and \ to escape a special character).
+func Match(pattern, name string) bool {
+	return Compile(pattern, false).Match(name)
+}
+
+// Escape escapes the special characters of the pattern,
+// so that it matches the name literally.
+func Escape(name string) string {
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// Pattern is a compiled Redis glob pattern.
+//
+// Like in Redis, the pattern matches bytes, not characters,
+// an unclosed class extends to the end of the pattern,
+// and a backslash escapes the next character both outside
+// and inside a class.
+type Pattern struct {
+	tokens []token
+	nocase bool
+}
+
+// token matches a single byte of the name, or any number
+// of bytes if it is a star.
+type token struct {
+	star   bool
+	negate bool
+	ranges []byteRange
+}
+
+// byteRange is an inclusive range of bytes.
+type byteRange struct {
+	lo, hi byte
+}
+
+// Compile parses the pattern. With nocase, the ASCII letters
+// match in any case (like the SQLite nocase collation).
+// Every pattern is valid, so Compile never fails.
+func Compile(pattern string, nocase bool) Pattern {
+	var tokens []token
+	for i := 0; i < len(pattern); {
+		c := pattern[i]
+		switch {
+		case c == '*':
+			for i < len(pattern) && pattern[i] == '*' {
+				i++
+			}
+			tokens = append(tokens, token{star: true})
+		case c == '?':
+			tokens = append(tokens, token{negate: true})
+			i++
+		case c == '[':
+			var tok token
+			tok, i = parseClass(pattern, i+1)
+			tokens = append(tokens, tok)
+		case c == '\\' && i+1 < len(pattern):
+			tokens = append(tokens, literal(pattern[i+1]))
+			i += 2
+		default:
+			tokens = append(tokens, literal(c))
+			i++
+		}
+	}
+	return Pattern{tokens: tokens, nocase: nocase}
+}
+
+// parseClass parses the character class starting at i
+// (after the opening bracket) and returns the index
+// after the class.
+func parseClass(pattern string, i int) (token, int) {
+	var tok token
+	if i < len(pattern) && pattern[i] == '^' {
+		tok.negate = true
+		i++
+	}
+	for i < len(pattern) {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			tok.ranges = append(tok.ranges, byteRange{pattern[i+1], pattern[i+1]})
+			i += 2
+		case c == ']':
+			return tok, i + 1
+		case i+2 < len(pattern) && pattern[i+1] == '-':
+			lo, hi := c, pattern[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			tok.ranges = append(tok.ranges, byteRange{lo, hi})
+			i += 3
+		default:
+			tok.ranges = append(tok.ranges, byteRange{c, c})
+			i++
+		}
+	}
+	return tok, i
+}
+
+// literal returns a token that matches the byte.
+func literal(c byte) token {
+	return token{ranges: []byteRange{{c, c}}}
+}
+
+// Match reports whether the name matches the pattern.
+// Runs in O(len(pattern) * len(name)) time regardless
+// of the number of stars.
+func (p Pattern) Match(name string) bool {
+	ti, ni := 0, 0
+	starTi, starNi := -1, 0
+	for ni < len(name) {
+		if ti < len(p.tokens) {
+			tok := p.tokens[ti]
+			if tok.star {
+				starTi, starNi = ti, ni
+				ti++
+				continue
+			}
+			if tok.match(name[ni], p.nocase) {
+				ti++
+				ni++
+				continue
+			}
+		}
+		if starTi < 0 {
+			return false
+		}
+		// Let the last star consume one more byte and retry.
+		starNi++
+		ti, ni = starTi+1, starNi
+	}
+	for ti < len(p.tokens) && p.tokens[ti].star {
+		ti++
+	}
+	return ti == len(p.tokens)
+}
+
+// Range returns the bounds of the names that start with the literal
+// prefix of the pattern, so that the names can be selected with an
+// index range scan: every matching name is in [lo, hi) when compared
+// byte by byte, or with the ASCII letters in lowercase if nocase
+// (like the SQLite nocase collation). The range may include the
+// names that do not match, so they must be checked with Match.
+// Fails if the pattern does not start with a literal.
+func (p Pattern) Range() (lo, hi string, ok bool) {
+	var prefix []byte
+	for _, tok := range p.tokens {
+		if tok.star || tok.negate || len(tok.ranges) != 1 || tok.ranges[0].lo != tok.ranges[0].hi {
+			break
+		}
+		c := tok.ranges[0].lo
+		if p.nocase && isLetter(c) {
+			c |= 0x20
+		}
+		prefix = append(prefix, c)
+	}
+	lo = string(prefix)
+	// The upper bound is the prefix with the last byte incremented,
+	// after dropping the trailing 0xFF bytes that can't be.
+	for i := len(prefix) - 1; i >= 0; i-- {
+		if prefix[i] < 0xff {
+			prefix[i]++
+			return lo, string(prefix[:i+1]), true
+		}
+	}
+	return "", "", false
+}
+
+// SQLite returns the pattern for the SQLite glob operator.
+// If exact is false, the SQLite pattern selects more names
+// than the Redis one, so the results must be checked with Match.
+//
+// SQLite's glob matches characters rather than bytes and cannot
+// match a NUL byte, so ?, negated classes, NUL bytes and classes with
+// non-ASCII bytes are replaced with a star, and so is the whole
+// pattern if it is not valid UTF-8 or is too long.
+func (p Pattern) SQLite() (pattern string, exact bool) {
+	var b strings.Builder
+	exact = true
+	lastStar := false
+	star := func() {
+		if !lastStar {
+			b.WriteByte('*')
+		}
+		lastStar = true
+	}
+	for _, tok := range p.tokens {
+		if tok.star {
+			star()
+			continue
+		}
+		set, ok := tok.set(p.nocase)
+		if !ok {
+			star()
+			exact = false
+			continue
+		}
+		lastStar = false
+		writeSet(&b, set)
+	}
+	if b.Len() > maxSQLite || !utf8.ValidString(b.String()) {
+		return "*", false
+	}
+	return b.String(), exact
+}
+
+// match reports whether the token matches the byte.
+func (t token) match(c byte, nocase bool) bool {
+	matched := t.contains(c)
+	if !matched && nocase && isLetter(c) {
+		matched = t.contains(c ^ 0x20)
+	}
+	return matched != t.negate
+}
+
+// contains reports whether the byte is in one of the ranges.
+func (t token) contains(c byte) bool {
+	for _, r := range t.ranges {
+		if r.lo <= c && c <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// set returns the bytes the token matches. Fails if the token
+// may match a non-ASCII byte or a NUL byte, since they cannot
+// be expressed in an SQLite pattern. A literal non-ASCII byte
+// is fine, because SQLite compares the literals byte by byte.
+func (t token) set(nocase bool) ([]byte, bool) {
+	if t.negate {
+		return nil, false
+	}
+	var set []byte
+	var seen [256]bool
+	add := func(c byte) {
+		if !seen[c] {
+			seen[c] = true
+			set = append(set, c)
+		}
+	}
+	for _, r := range t.ranges {
+		for c := int(r.lo); c <= int(r.hi); c++ {
+			add(byte(c))
+			if nocase && isLetter(byte(c)) {
+				add(byte(c) ^ 0x20)
+			}
+		}
+	}
+	if len(set) == 0 {
+		return nil, false
+	}
+	if len(set) == 1 {
+		return set, set[0] != 0
+	}
+	for _, c := range set {
+		if c == 0 || c >= 0x80 {
+			return nil, false
+		}
+	}
+	return set, true
+}
+
+// writeSet writes the SQLite pattern that matches
+// any byte of the set.
+func writeSet(b *strings.Builder, set []byte) {
+	if len(set) == 1 {
+		switch c := set[0]; c {
+		case '*', '?', '[':
+			b.WriteByte('[')
+			b.WriteByte(c)
+			b.WriteByte(']')
+		default:
+			b.WriteByte(c)
+		}
+		return
+	}
+
+	// In an SQLite class, "]" is literal only at the start,
+	// "^" anywhere but at the start, and "-" at the start
+	// or at the end, so they go separately.
+	var seen [128]bool
+	for _, c := range set {
+		seen[c] = true
+	}
+	var body strings.Builder
+	if seen[']'] {
+		body.WriteByte(']')
+	}
+	for lo := 1; lo < 128; lo++ {
+		if !seen[lo] || lo == ']' || lo == '^' || lo == '-' {
+			continue
+		}
+		hi := lo
+		for hi+1 < 128 && seen[hi+1] && hi+1 != ']' && hi+1 != '^' && hi+1 != '-' {
+			hi++
+		}
+		switch hi - lo {
+		case 0:
+			body.WriteByte(byte(lo))
+		case 1:
+			body.WriteByte(byte(lo))
+			body.WriteByte(byte(hi))
+		default:
+			body.WriteByte(byte(lo))
+			body.WriteByte('-')
+			body.WriteByte(byte(hi))
+		}
+		lo = hi
+	}
+	if body.Len() == 0 && seen['^'] {
+		// The set is "^" and "-", and "-^" keeps both literal.
+		body.WriteString("-^")
+	} else {
+		if seen['^'] {
+			body.WriteByte('^')
+		}
+		if seen['-'] {
+			body.WriteByte('-')
+		}
+	}
+	b.WriteByte('[')
+	b.WriteString(body.String())
+	b.WriteByte(']')
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,153 @@
+package glob
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*", "", true},
+		{"*", "user:1", true},
+		{"user:*", "user:1", true},
+		{"user:*", "post:1", false},
+		{"user:?", "user:1", true},
+		{"user:?", "user:10", false},
+		{"*:1", "a/b:1", true},
+		{"user:[0-9]", "user:5", true},
+		{"user:[^0-9]", "user:5", false},
+		{"user:[ab]", "user:b", true},
+		{"user:\\*", "user:*", true},
+		{"user:\\*", "user:1", false},
+		{"user:[", "user:[", false},
+		{"user:[ab", "user:b", true},
+		{"user:[]", "user:]", false},
+		{"user:[\\]]", "user:]", true},
+		{"user:[\\^a]", "user:^", true},
+		{"user:[z-a]", "user:k", true},
+		{"user:\\[1]", "user:[1]", true},
+		{"user:\\?", "user:1", false},
+		{"user:\\a", "user:a", true},
+		{"user:\\", "user:\\", true},
+		{"a**b", "ab", true},
+		{"*a*b", "xaxxb", true},
+		{"*a*b", "xaxxbx", false},
+		{"?", "\xc3", true},
+		{"??", "é", true},
+	}
+	for _, test := range tests {
+		got := Match(test.pattern, test.name)
+		if got != test.want {
+			t.Errorf("%q ~ %q: want %v, got %v", test.pattern, test.name, test.want, got)
+		}
+	}
+}
+
+func TestMatchNocase(t *testing.T) {
+	p := Compile("User:[a-c]*", true)
+	check := func(name string, want bool) {
+		t.Helper()
+		if got := p.Match(name); got != want {
+			t.Errorf("%q: want %v, got %v", name, want, got)
+		}
+	}
+	check("user:b1", true)
+	check("USER:B1", true)
+	check("user:d1", false)
+}
+
+func TestMatchPathological(t *testing.T) {
+	pattern := strings.Repeat("a*", 50) + "b"
+	name := strings.Repeat("a", 10000)
+	done := make(chan bool)
+	go func() { done <- Match(pattern, name) }()
+	select {
+	case got := <-done:
+		if got {
+			t.Error("want no match")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("match takes too long")
+	}
+}
+
+func TestSQLite(t *testing.T) {
+	tests := []struct {
+		pattern string
+		nocase  bool
+		want    string
+		exact   bool
+	}{
+		{"user:*", false, "user:*", true},
+		{"user:**", false, "user:*", true},
+		{"user:[0-9]", false, "user:[0-9]", true},
+		{"user:[abcx]", false, "user:[a-cx]", true},
+		{"user:\\*", false, "user:[*]", true},
+		{"user:\\?", false, "user:[?]", true},
+		{"user:\\[1]", false, "user:[[]1]", true},
+		{"user:[\\]\\-^]", false, "user:[]^-]", true},
+		{"user:[\\-^]", false, "user:[-^]", true},
+		{"user:[*]", false, "user:[*]", true},
+		{"user:?", false, "user:*", false},
+		{"user:?*", false, "user:*", false},
+		{"user:[^0-9]", false, "user:*", false},
+		{"user:[]", false, "user:*", false},
+		{"user:\x00", false, "user:*", false},
+		{"user:é", false, "user:é", true},
+		{"user:[é]", false, "user:*", false},
+		{"User:[a-c]", true, "[Uu][Ss][Ee][Rr]:[A-Ca-c]", true},
+		{strings.Repeat("a", 20000), false, "*", false},
+	}
+	for _, test := range tests {
+		got, exact := Compile(test.pattern, test.nocase).SQLite()
+		if got != test.want || exact != test.exact {
+			t.Errorf("%q: want %q (exact=%v), got %q (exact=%v)",
+				test.pattern, test.want, test.exact, got, exact)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		pattern string
+		nocase  bool
+		lo, hi  string
+		ok      bool
+	}{
+		{"user:*", false, "user:", "user;", true},
+		{"user:1", false, "user:1", "user:2", true},
+		{"user:[0-9]*", false, "user:", "user;", true},
+		{"user:[a]*", false, "user:a", "user:b", true},
+		{"user:\\**", false, "user:*", "user:+", true},
+		{"user?*", false, "user", "uses", true},
+		{"User:*", true, "user:", "user;", true},
+		{"a\xff\xff*", false, "a\xff\xff", "b", true},
+		{"*user", false, "", "", false},
+		{"?user", false, "", "", false},
+		{"[ab]*", false, "", "", false},
+		{"\xff*", false, "", "", false},
+		{"", false, "", "", false},
+	}
+	for _, test := range tests {
+		lo, hi, ok := Compile(test.pattern, test.nocase).Range()
+		if lo != test.lo || hi != test.hi || ok != test.ok {
+			t.Errorf("%q: want [%q, %q) (ok=%v), got [%q, %q) (ok=%v)",
+				test.pattern, test.lo, test.hi, test.ok, lo, hi, ok)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	for _, name := range []string{"key", "k*y", "k?y", "k[a-z]y", `k\y`, ""} {
+		if !Match(Escape(name), name) {
+			t.Errorf("Escape(%q) does not match itself", name)
+		}
+	}
+	if Match(Escape("k*y"), "key") {
+		t.Error("escaped star matches any character")
+	}
+}
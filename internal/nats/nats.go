@@ -0,0 +1,307 @@
+// Package nats implements a minimal NATS client
+// that publishes messages with delivery confirmation.
+// Supports core NATS (the server confirms receiving
+// the message) and JetStream (the stream confirms
+// storing the message).
+package nats
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxPayload is the maximum size of an incoming message.
+const maxPayload = 64 * 1024 * 1024
+
+// Errors returned by the client.
+var (
+	ErrProtocol     = errors.New("nats: protocol error")
+	ErrNoResponders = errors.New("nats: no responders (is the subject bound to a stream?)")
+)
+
+// Options configures the connection.
+type Options struct {
+	// User and Password or Token are used
+	// to authenticate with the server (if set).
+	User     string
+	Password string
+	Token    string
+}
+
+// Conn is a connection to a NATS server.
+// Not safe for concurrent use.
+type Conn struct {
+	conn  net.Conn
+	rd    *bufio.Reader
+	wr    *bufio.Writer
+	inbox string
+	seq   int
+}
+
+// Dial connects to the NATS server at addr (host:port)
+// and performs the handshake.
+func Dial(ctx context.Context, addr string, opts Options) (*Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{
+		conn:  conn,
+		rd:    bufio.NewReader(conn),
+		wr:    bufio.NewWriter(conn),
+		inbox: "_INBOX." + newID(),
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := c.handshake(opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// handshake reads the server info, sends the connection
+// options and subscribes to the reply inbox.
+func (c *Conn) handshake(opts Options) error {
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("%w: unexpected greeting %q", ErrProtocol, line)
+	}
+	var info struct {
+		Headers bool `json:"headers"`
+	}
+	if err := json.Unmarshal([]byte(line[5:]), &info); err != nil {
+		return fmt.Errorf("%w: invalid info: %v", ErrProtocol, err)
+	}
+	if !info.Headers {
+		return fmt.Errorf("%w: server does not support headers", ErrProtocol)
+	}
+
+	connect, _ := json.Marshal(map[string]any{
+		"verbose":       false,
+		"pedantic":      false,
+		"lang":          "go",
+		"version":       "redka",
+		"protocol":      1,
+		"headers":       true,
+		"no_responders": true,
+		"user":          opts.User,
+		"pass":          opts.Password,
+		"auth_token":    opts.Token,
+	})
+	fmt.Fprintf(c.wr, "CONNECT %s\r\nSUB %s.* 1\r\nPING\r\n", connect, c.inbox)
+	if err := c.wr.Flush(); err != nil {
+		return err
+	}
+	return c.waitPong()
+}
+
+// Publish publishes the message to the subject and waits until
+// the server receives it. The id is sent in the Nats-Msg-Id header,
+// which JetStream uses to discard duplicates.
+func (c *Conn) Publish(subject, id string, data []byte, timeout time.Duration) error {
+	_ = c.conn.SetDeadline(time.Now().Add(timeout))
+	defer c.conn.SetDeadline(time.Time{})
+	c.writeMsg(subject, "", id, data)
+	c.wr.WriteString("PING\r\n")
+	if err := c.wr.Flush(); err != nil {
+		return err
+	}
+	return c.waitPong()
+}
+
+// PublishStream publishes the message to the subject bound
+// to a JetStream stream, and waits until the stream stores it.
+// See Publish for the meaning of id.
+func (c *Conn) PublishStream(subject, id string, data []byte, timeout time.Duration) error {
+	_ = c.conn.SetDeadline(time.Now().Add(timeout))
+	defer c.conn.SetDeadline(time.Time{})
+	c.seq++
+	reply := c.inbox + "." + strconv.Itoa(c.seq)
+	c.writeMsg(subject, reply, id, data)
+	if err := c.wr.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		msg, err := c.readMsg()
+		if err != nil {
+			return err
+		}
+		if msg.subject != reply {
+			// A late reply to a previous message.
+			continue
+		}
+		if msg.status == "503" {
+			return ErrNoResponders
+		}
+		var ack struct {
+			Stream string `json:"stream"`
+			Error  *struct {
+				Code        int    `json:"code"`
+				Description string `json:"description"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(msg.data, &ack); err != nil {
+			return fmt.Errorf("%w: invalid ack: %v", ErrProtocol, err)
+		}
+		if ack.Error != nil {
+			return fmt.Errorf("nats: %s (%d)", ack.Error.Description, ack.Error.Code)
+		}
+		if ack.Stream == "" {
+			return fmt.Errorf("%w: invalid ack: %s", ErrProtocol, msg.data)
+		}
+		return nil
+	}
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// writeMsg writes the HPUB command with the message ID header.
+func (c *Conn) writeMsg(subject, reply, id string, data []byte) {
+	header := "NATS/1.0\r\nNats-Msg-Id: " + id + "\r\n\r\n"
+	if reply != "" {
+		subject += " " + reply
+	}
+	fmt.Fprintf(c.wr, "HPUB %s %d %d\r\n", subject, len(header), len(header)+len(data))
+	c.wr.WriteString(header)
+	c.wr.Write(data)
+	c.wr.WriteString("\r\n")
+}
+
+// waitPong reads the server messages until PONG.
+func (c *Conn) waitPong() error {
+	for {
+		line, err := c.readControl()
+		if err != nil {
+			return err
+		}
+		if line == "PONG" {
+			return nil
+		}
+		if strings.HasPrefix(line, "MSG ") || strings.HasPrefix(line, "HMSG ") {
+			// A late reply, skip the payload.
+			if _, err := c.readPayload(line); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// message is an incoming message.
+type message struct {
+	subject string
+	status  string
+	data    []byte
+}
+
+// readMsg reads the next incoming message.
+func (c *Conn) readMsg() (message, error) {
+	for {
+		line, err := c.readControl()
+		if err != nil {
+			return message{}, err
+		}
+		if !strings.HasPrefix(line, "MSG ") && !strings.HasPrefix(line, "HMSG ") {
+			continue
+		}
+		return c.readPayload(line)
+	}
+}
+
+// readPayload reads the payload of the MSG or HMSG command:
+//
+//	MSG <subject> <sid> [reply-to] <#bytes>
+//	HMSG <subject> <sid> [reply-to] <#header bytes> <#total bytes>
+func (c *Conn) readPayload(line string) (message, error) {
+	parts := strings.Fields(line)
+	hasHeaders := parts[0] == "HMSG"
+	if len(parts) < 4 || hasHeaders && len(parts) < 5 {
+		return message{}, fmt.Errorf("%w: invalid message %q", ErrProtocol, line)
+	}
+	total, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil || total < 0 || total > maxPayload {
+		return message{}, fmt.Errorf("%w: invalid message %q", ErrProtocol, line)
+	}
+	hsize := 0
+	if hasHeaders {
+		hsize, err = strconv.Atoi(parts[len(parts)-2])
+		if err != nil || hsize < 0 || hsize > total {
+			return message{}, fmt.Errorf("%w: invalid message %q", ErrProtocol, line)
+		}
+	}
+	buf := make([]byte, total+2)
+	if _, err := io.ReadFull(c.rd, buf); err != nil {
+		return message{}, err
+	}
+
+	msg := message{subject: parts[1], data: buf[hsize:total]}
+	if hasHeaders {
+		// NATS/1.0 [status [description]]
+		first, _, _ := strings.Cut(string(buf[:hsize]), "\r\n")
+		if fields := strings.Fields(first); len(fields) > 1 {
+			msg.status = fields[1]
+		}
+	}
+	return msg, nil
+}
+
+// readControl reads the next protocol line, answering the server
+// pings and skipping the informational messages.
+func (c *Conn) readControl() (string, error) {
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return "", err
+		}
+		switch {
+		case line == "PING":
+			c.wr.WriteString("PONG\r\n")
+			if err := c.wr.Flush(); err != nil {
+				return "", err
+			}
+		case line == "+OK", strings.HasPrefix(line, "INFO "):
+			// Nothing to do.
+		case strings.HasPrefix(line, "-ERR"):
+			msg := strings.Trim(strings.TrimSpace(line[4:]), "'")
+			return "", errors.New("nats: " + msg)
+		default:
+			return line, nil
+		}
+	}
+}
+
+// readLine reads a single CRLF-terminated line.
+func (c *Conn) readLine() (string, error) {
+	line, err := c.rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// newID returns a random identifier.
+func newID() string {
+	b := make([]byte, 11)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
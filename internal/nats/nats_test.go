@@ -0,0 +1,114 @@
+package nats
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestPublish(t *testing.T) {
+	msgs := make(chan string, 10)
+	addr := serve(t, msgs, "")
+
+	c, err := Dial(context.Background(), addr, Options{Token: "secret"})
+	testx.AssertNoErr(t, err)
+	defer c.Close()
+
+	err = c.Publish("redka.changes", "42", []byte("hello"), time.Second)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, <-msgs, "redka.changes 42 hello")
+}
+
+func TestPublishStream(t *testing.T) {
+	t.Run("ack", func(t *testing.T) {
+		msgs := make(chan string, 10)
+		addr := serve(t, msgs, `{"stream":"changes","seq":1}`)
+
+		c, err := Dial(context.Background(), addr, Options{})
+		testx.AssertNoErr(t, err)
+		defer c.Close()
+
+		err = c.PublishStream("redka.changes", "42", []byte("hello"), time.Second)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, <-msgs, "redka.changes 42 hello")
+	})
+	t.Run("error", func(t *testing.T) {
+		msgs := make(chan string, 10)
+		addr := serve(t, msgs, `{"error":{"code":503,"description":"insufficient resources"}}`)
+
+		c, err := Dial(context.Background(), addr, Options{})
+		testx.AssertNoErr(t, err)
+		defer c.Close()
+
+		err = c.PublishStream("redka.changes", "42", []byte("hello"), time.Second)
+		testx.AssertEqual(t, err.Error(), "nats: insufficient resources (503)")
+	})
+	t.Run("no responders", func(t *testing.T) {
+		msgs := make(chan string, 10)
+		addr := serve(t, msgs, "503")
+
+		c, err := Dial(context.Background(), addr, Options{})
+		testx.AssertNoErr(t, err)
+		defer c.Close()
+
+		err = c.PublishStream("redka.changes", "42", []byte("hello"), time.Second)
+		testx.AssertErr(t, err, ErrNoResponders)
+	})
+}
+
+// serve starts a fake NATS server that sends the received
+// messages to the channel. If ack is not empty, replies to the
+// messages with it (or with a status header if ack is "503").
+func serve(t *testing.T, msgs chan<- string, ack string) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testx.AssertNoErr(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		rd := bufio.NewReader(conn)
+		fmt.Fprintf(conn, "INFO {\"headers\":true}\r\n")
+		for {
+			line, err := rd.ReadString('\n')
+			if err != nil {
+				return
+			}
+			parts := strings.Fields(line)
+			switch parts[0] {
+			case "PING":
+				fmt.Fprintf(conn, "PONG\r\n")
+			case "HPUB":
+				hsize, _ := strconv.Atoi(parts[len(parts)-2])
+				total, _ := strconv.Atoi(parts[len(parts)-1])
+				buf := make([]byte, total+2)
+				if _, err := io.ReadFull(rd, buf); err != nil {
+					return
+				}
+				id := strings.TrimPrefix(strings.Split(string(buf[:hsize]), "\r\n")[1], "Nats-Msg-Id: ")
+				msgs <- parts[1] + " " + id + " " + string(buf[hsize:total])
+				if len(parts) == 5 && ack != "" {
+					reply := parts[2]
+					if ack == "503" {
+						header := "NATS/1.0 503\r\n\r\n"
+						fmt.Fprintf(conn, "HMSG %s 1 %d %d\r\n%s\r\n", reply, len(header), len(header), header)
+					} else {
+						fmt.Fprintf(conn, "MSG %s 1 %d\r\n%s\r\n", reply, len(ack), ack)
+					}
+				}
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
@@ -0,0 +1,121 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// dumpFooterSize is the size of the DUMP payload footer:
+// RDB version (2 bytes) and CRC64 checksum (8 bytes).
+const dumpFooterSize = 10
+
+// ParseDump parses a value serialized by the Redis DUMP command.
+// The payload consists of the value type, the RDB-encoded value,
+// the RDB version and the checksum. Returns an entry without the
+// key and expiration time, which are not part of the payload.
+func ParseDump(payload []byte) (Entry, error) {
+	if len(payload) < 1+dumpFooterSize {
+		return Entry{}, ErrFormat
+	}
+	body := payload[:len(payload)-dumpFooterSize]
+	footer := payload[len(payload)-dumpFooterSize:]
+	version := int(binary.LittleEndian.Uint16(footer[:2]))
+	if version > maxVersion {
+		return Entry{}, ErrVersion
+	}
+	crc := binary.LittleEndian.Uint64(footer[2:])
+	if crc != 0 && crc != crc64(0, payload[:len(payload)-8]) {
+		return Entry{}, ErrChecksum
+	}
+
+	var e Entry
+	src := &sliceReader{b: body[1:]}
+	if err := (decoder{src}).readValue(body[0], &e); err != nil {
+		return Entry{}, err
+	}
+	if src.pos != len(src.b) {
+		return Entry{}, ErrFormat
+	}
+	return e, nil
+}
+
+// AppendDump appends the value of the entry serialized
+// in the Redis DUMP format to b. Ignores the key
+// and expiration time of the entry.
+func AppendDump(b []byte, e Entry) ([]byte, error) {
+	w := &sliceWriter{b: b}
+	start := len(b)
+	enc := encoder{w}
+	version := Version
+	switch e.Type {
+	case core.TypeString:
+		w.writeByte(typeString)
+		enc.writeString(e.Str)
+	case core.TypeList:
+		w.writeByte(typeList)
+		enc.writeStringList(e.List)
+	case core.TypeSet:
+		w.writeByte(typeSet)
+		enc.writeStringList(e.List)
+	case core.TypeHash:
+		if len(e.HashETimes) > 0 {
+			w.writeByte(typeHashMetadata)
+			enc.writeHashMetadata(e.Hash, e.HashETimes)
+			version = versionHashMetadata
+			break
+		}
+		w.writeByte(typeHash)
+		enc.writeHash(e.Hash)
+	case core.TypeSortedSet:
+		w.writeByte(typeZSet2)
+		enc.writeZSet(e.ZSet)
+	case core.TypeStream:
+		w.writeByte(typeStreamListpacks)
+		enc.writeStream(e.Stream)
+	default:
+		return b, fmt.Errorf("%w: %d", ErrUnsupported, e.Type)
+	}
+	w.b = binary.LittleEndian.AppendUint16(w.b, uint16(version))
+	crc := crc64(0, w.b[start:])
+	return binary.LittleEndian.AppendUint64(w.b, crc), nil
+}
+
+// sliceWriter is a byteWriter over a byte slice.
+type sliceWriter struct {
+	b []byte
+}
+
+func (w *sliceWriter) writeByte(b byte) {
+	w.b = append(w.b, b)
+}
+
+func (w *sliceWriter) write(p []byte) {
+	w.b = append(w.b, p...)
+}
+
+// sliceReader is a byteReader over a byte slice.
+type sliceReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *sliceReader) readByte() (byte, error) {
+	if r.pos >= len(r.b) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b := r.b[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *sliceReader) readFull(n int) ([]byte, error) {
+	if r.pos+n > len(r.b) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return b, nil
+}
@@ -0,0 +1,52 @@
+package rdb
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestDump(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		tests := []Entry{
+			{Type: core.TypeString, Str: []byte("alice")},
+			{Type: core.TypeList, List: [][]byte{[]byte("a"), []byte("b")}},
+			{Type: core.TypeHash, Hash: map[string][]byte{"f": []byte("v")}},
+			{Type: core.TypeHash, Hash: map[string][]byte{"f": []byte("v"), "g": []byte("w")},
+				HashETimes: map[string]int64{"g": 1700000000000}},
+			{Type: core.TypeSortedSet, ZSet: map[string]float64{"one": 1, "two": 2}},
+			{Type: core.TypeStream, Stream: []StreamEntry{
+				{Time: 1, Seq: 0, Fields: [][]byte{[]byte("user"), []byte("alice")}},
+				{Time: 1, Seq: 1, Fields: [][]byte{[]byte("user"), []byte("bob")}},
+			}},
+		}
+		for _, want := range tests {
+			payload, err := AppendDump(nil, want)
+			testx.AssertNoErr(t, err)
+			got, err := ParseDump(payload)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, got, want)
+		}
+	})
+	t.Run("checksum", func(t *testing.T) {
+		payload, _ := AppendDump(nil, Entry{Type: core.TypeString, Str: []byte("alice")})
+		payload[1] = 'A'
+		_, err := ParseDump(payload)
+		testx.AssertErr(t, err, ErrChecksum)
+	})
+	t.Run("no checksum", func(t *testing.T) {
+		payload, _ := AppendDump(nil, Entry{Type: core.TypeString, Str: []byte("alice")})
+		copy(payload[len(payload)-8:], make([]byte, 8))
+		e, err := ParseDump(payload)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, string(e.Str), "alice")
+	})
+	t.Run("trailing data", func(t *testing.T) {
+		payload, _ := AppendDump(nil, Entry{Type: core.TypeString, Str: []byte("alice")})
+		payload = append(payload[:len(payload)-10:len(payload)-10], 'x')
+		payload = append(payload, make([]byte, 10)...)
+		_, err := ParseDump(payload)
+		testx.AssertErr(t, err, ErrFormat)
+	})
+}
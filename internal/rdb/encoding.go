@@ -0,0 +1,533 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// parseZiplist parses a ziplist blob into a list of items.
+func parseZiplist(b []byte) ([][]byte, error) {
+	// zlbytes (4), zltail (4), zllen (2), entries..., zlend (1)
+	if len(b) < 11 {
+		return nil, ErrFormat
+	}
+	pos := 10
+	var items [][]byte
+	for {
+		if pos >= len(b) {
+			return nil, ErrFormat
+		}
+		if b[pos] == 0xFF {
+			return items, nil
+		}
+		// prevlen
+		if b[pos] == 0xFE {
+			pos += 5
+		} else {
+			pos++
+		}
+		if pos >= len(b) {
+			return nil, ErrFormat
+		}
+		item, n, err := parseZiplistEntry(b[pos:])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		pos += n
+	}
+}
+
+// parseZiplistEntry parses the ziplist entry (without the prevlen).
+// Returns the item and the number of bytes consumed.
+func parseZiplistEntry(b []byte) ([]byte, int, error) {
+	enc := b[0]
+	switch enc >> 6 {
+	case 0:
+		n := int(enc & 0x3F)
+		return sliceN(b, 1, n)
+	case 1:
+		if len(b) < 2 {
+			return nil, 0, ErrFormat
+		}
+		n := int(enc&0x3F)<<8 | int(b[1])
+		return sliceN(b, 2, n)
+	case 2:
+		if len(b) < 5 {
+			return nil, 0, ErrFormat
+		}
+		n := int(binary.BigEndian.Uint32(b[1:5]))
+		return sliceN(b, 5, n)
+	}
+	var val int64
+	var size int
+	switch enc {
+	case 0xC0:
+		size = 2
+	case 0xD0:
+		size = 4
+	case 0xE0:
+		size = 8
+	case 0xF0:
+		size = 3
+	case 0xFE:
+		size = 1
+	default:
+		if enc >= 0xF1 && enc <= 0xFD {
+			val = int64(enc&0x0F) - 1
+			return strconv.AppendInt(nil, val, 10), 1, nil
+		}
+		return nil, 0, ErrFormat
+	}
+	if len(b) < 1+size {
+		return nil, 0, ErrFormat
+	}
+	val = readIntLE(b[1:1+size], size)
+	return strconv.AppendInt(nil, val, 10), 1 + size, nil
+}
+
+// parseListpack parses a listpack blob into a list of items.
+func parseListpack(b []byte) ([][]byte, error) {
+	// total bytes (4), num elements (2), entries..., end (1)
+	if len(b) < 7 {
+		return nil, ErrFormat
+	}
+	pos := 6
+	var items [][]byte
+	for {
+		if pos >= len(b) {
+			return nil, ErrFormat
+		}
+		if b[pos] == 0xFF {
+			return items, nil
+		}
+		item, n, err := parseListpackEntry(b[pos:])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		pos += n + listpackBacklen(n)
+	}
+}
+
+// parseListpackEntry parses the listpack entry (without the backlen).
+// Returns the item and the number of bytes consumed.
+func parseListpackEntry(b []byte) ([]byte, int, error) {
+	enc := b[0]
+	switch {
+	case enc&0x80 == 0:
+		// 7-bit unsigned integer
+		return strconv.AppendInt(nil, int64(enc&0x7F), 10), 1, nil
+	case enc&0xC0 == 0x80:
+		// 6-bit string length
+		return sliceN(b, 1, int(enc&0x3F))
+	case enc&0xE0 == 0xC0:
+		// 13-bit signed integer
+		if len(b) < 2 {
+			return nil, 0, ErrFormat
+		}
+		val := int64(enc&0x1F)<<8 | int64(b[1])
+		if val >= 1<<12 {
+			val -= 1 << 13
+		}
+		return strconv.AppendInt(nil, val, 10), 2, nil
+	case enc&0xF0 == 0xE0:
+		// 12-bit string length
+		if len(b) < 2 {
+			return nil, 0, ErrFormat
+		}
+		n := int(enc&0x0F)<<8 | int(b[1])
+		return sliceN(b, 2, n)
+	}
+	var size int
+	switch enc {
+	case 0xF0:
+		// 32-bit string length
+		if len(b) < 5 {
+			return nil, 0, ErrFormat
+		}
+		n := int(binary.LittleEndian.Uint32(b[1:5]))
+		return sliceN(b, 5, n)
+	case 0xF1:
+		size = 2
+	case 0xF2:
+		size = 3
+	case 0xF3:
+		size = 4
+	case 0xF4:
+		size = 8
+	default:
+		return nil, 0, ErrFormat
+	}
+	if len(b) < 1+size {
+		return nil, 0, ErrFormat
+	}
+	val := readIntLE(b[1:1+size], size)
+	return strconv.AppendInt(nil, val, 10), 1 + size, nil
+}
+
+// listpackBacklen returns the size of the backlen field
+// for an entry of n bytes.
+func listpackBacklen(n int) int {
+	switch {
+	case n <= 127:
+		return 1
+	case n < 16383:
+		return 2
+	case n < 2097151:
+		return 3
+	case n < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// appendListpackInt appends an integer entry to the listpack
+// using the smallest encoding that fits the value.
+func appendListpackInt(b []byte, v int64) []byte {
+	start := len(b)
+	switch {
+	case v >= 0 && v <= 127:
+		b = append(b, byte(v))
+	case v >= -4096 && v <= 4095:
+		u := uint16(v) & 0x1FFF
+		b = append(b, byte(u>>8)|0xC0, byte(u))
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		b = append(b, 0xF1)
+		b = binary.LittleEndian.AppendUint16(b, uint16(v))
+	case v >= -1<<23 && v < 1<<23:
+		u := uint32(v)
+		b = append(b, 0xF2, byte(u), byte(u>>8), byte(u>>16))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		b = append(b, 0xF3)
+		b = binary.LittleEndian.AppendUint32(b, uint32(v))
+	default:
+		b = append(b, 0xF4)
+		b = binary.LittleEndian.AppendUint64(b, uint64(v))
+	}
+	return appendListpackBacklen(b, len(b)-start)
+}
+
+// appendListpackString appends a string entry to the listpack.
+func appendListpackString(b []byte, s []byte) []byte {
+	start := len(b)
+	switch n := len(s); {
+	case n < 1<<6:
+		b = append(b, byte(n)|0x80)
+	case n < 1<<12:
+		b = append(b, byte(n>>8)|0xE0, byte(n))
+	default:
+		b = append(b, 0xF0)
+		b = binary.LittleEndian.AppendUint32(b, uint32(n))
+	}
+	b = append(b, s...)
+	return appendListpackBacklen(b, len(b)-start)
+}
+
+// appendListpackBacklen appends the backlen field for an entry
+// of n bytes: 7 bits per byte, most significant first, with the
+// high bit set on all bytes except the first one.
+func appendListpackBacklen(b []byte, n int) []byte {
+	size := listpackBacklen(n)
+	for i := size - 1; i >= 0; i-- {
+		c := byte(n>>(7*i)) & 0x7F
+		if i < size-1 {
+			c |= 0x80
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// finishListpack sets the header of the listpack
+// with count entries and appends the end marker.
+func finishListpack(b []byte, count int) []byte {
+	b = append(b, 0xFF)
+	binary.LittleEndian.PutUint32(b[0:4], uint32(len(b)))
+	// The count saturates at 65535, meaning "unknown".
+	binary.LittleEndian.PutUint16(b[4:6], uint16(min(count, math.MaxUint16)))
+	return b
+}
+
+// appendStreamNode appends the stream entries encoded as a stream
+// listpack node to b. The master entry has the fields of the first
+// entry, and each entry has its own fields (no SAMEFIELDS flag).
+// The IDs are stored relative to the ID of the first entry.
+func appendStreamNode(b []byte, node []StreamEntry) []byte {
+	master := node[0]
+	lp := make([]byte, 6, 64)
+	count := 0
+	// master entry: count, deleted, num fields, fields..., 0
+	lp = appendListpackInt(lp, int64(len(node)))
+	lp = appendListpackInt(lp, 0)
+	lp = appendListpackInt(lp, int64(len(master.Fields)/2))
+	for i := 0; i < len(master.Fields); i += 2 {
+		lp = appendListpackString(lp, master.Fields[i])
+	}
+	lp = appendListpackInt(lp, 0)
+	count += 4 + len(master.Fields)/2
+	// entries: flags, ms diff, seq diff, num fields, field-values..., lp-count
+	for _, e := range node {
+		nfields := len(e.Fields) / 2
+		lp = appendListpackInt(lp, 0)
+		lp = appendListpackInt(lp, e.Time-master.Time)
+		lp = appendListpackInt(lp, e.Seq-master.Seq)
+		lp = appendListpackInt(lp, int64(nfields))
+		for _, f := range e.Fields[:nfields*2] {
+			lp = appendListpackString(lp, f)
+		}
+		lp = appendListpackInt(lp, int64(nfields*2+4))
+		count += nfields*2 + 5
+	}
+	return append(b, finishListpack(lp, count)...)
+}
+
+// parseStreamNode parses the items of a stream listpack node
+// with the given master ID into entries. Skips the deleted entries.
+func parseStreamNode(items [][]byte, ms, seq int64) ([]StreamEntry, error) {
+	it := listpackIter{items: items}
+	// master entry: count, deleted, num fields, fields..., 0
+	count := it.int()
+	deleted := it.int()
+	master := it.next(it.int())
+	it.int()
+	var entries []StreamEntry
+	for i := int64(0); i < count+deleted && it.err == nil; i++ {
+		// flags, ms diff, seq diff, [num fields], field-values..., lp-count
+		flags := it.int()
+		e := StreamEntry{Time: ms + it.int(), Seq: seq + it.int()}
+		if flags&streamSameFields != 0 {
+			values := it.next(int64(len(master)))
+			for j := range values {
+				e.Fields = append(e.Fields, master[j], values[j])
+			}
+		} else {
+			e.Fields = it.next(it.int() * 2)
+		}
+		it.int()
+		if flags&streamDeleted == 0 {
+			entries = append(entries, e)
+		}
+	}
+	if it.err != nil {
+		return nil, it.err
+	}
+	return entries, nil
+}
+
+// listpackIter iterates over the listpack items.
+// Stops at the first error and keeps it in err.
+type listpackIter struct {
+	items [][]byte
+	pos   int
+	err   error
+}
+
+// next returns the next n items.
+func (it *listpackIter) next(n int64) [][]byte {
+	if it.err == nil && (n < 0 || n > int64(len(it.items)-it.pos)) {
+		it.err = ErrFormat
+	}
+	if it.err != nil {
+		return nil
+	}
+	items := it.items[it.pos : it.pos+int(n)]
+	it.pos += int(n)
+	return items
+}
+
+// int returns the next item as an integer.
+func (it *listpackIter) int() int64 {
+	items := it.next(1)
+	if it.err != nil {
+		return 0
+	}
+	val, err := strconv.ParseInt(string(items[0]), 10, 64)
+	if err != nil {
+		it.err = ErrFormat
+	}
+	return val
+}
+
+// parseIntset parses an intset blob into a list of items.
+func parseIntset(b []byte) ([][]byte, error) {
+	// encoding (4), length (4), contents...
+	if len(b) < 8 {
+		return nil, ErrFormat
+	}
+	size := int(binary.LittleEndian.Uint32(b[0:4]))
+	n := int(binary.LittleEndian.Uint32(b[4:8]))
+	if size != 2 && size != 4 && size != 8 {
+		return nil, ErrFormat
+	}
+	if len(b) < 8+n*size {
+		return nil, ErrFormat
+	}
+	items := make([][]byte, n)
+	for i := range n {
+		pos := 8 + i*size
+		val := readIntLE(b[pos:pos+size], size)
+		items[i] = strconv.AppendInt(nil, val, 10)
+	}
+	return items, nil
+}
+
+// parseZipmap parses a zipmap blob into a list of
+// interleaved field-value items.
+func parseZipmap(b []byte) ([][]byte, error) {
+	// zmlen (1), entries..., end (1)
+	if len(b) < 2 {
+		return nil, ErrFormat
+	}
+	pos := 1
+	var items [][]byte
+	readLen := func() (int, error) {
+		if pos >= len(b) {
+			return 0, ErrFormat
+		}
+		if b[pos] < 254 {
+			n := int(b[pos])
+			pos++
+			return n, nil
+		}
+		if b[pos] == 254 && pos+5 <= len(b) {
+			n := int(binary.LittleEndian.Uint32(b[pos+1 : pos+5]))
+			pos += 5
+			return n, nil
+		}
+		return 0, ErrFormat
+	}
+	for {
+		if pos >= len(b) {
+			return nil, ErrFormat
+		}
+		if b[pos] == 0xFF {
+			return items, nil
+		}
+		// field
+		n, err := readLen()
+		if err != nil {
+			return nil, err
+		}
+		if pos+n > len(b) {
+			return nil, ErrFormat
+		}
+		items = append(items, b[pos:pos+n])
+		pos += n
+		// value with free space
+		n, err = readLen()
+		if err != nil {
+			return nil, err
+		}
+		if pos >= len(b) {
+			return nil, ErrFormat
+		}
+		free := int(b[pos])
+		pos++
+		if pos+n+free > len(b) {
+			return nil, ErrFormat
+		}
+		items = append(items, b[pos:pos+n])
+		pos += n + free
+	}
+}
+
+// lzfDecompress decompresses LZF-compressed data
+// into a buffer of size ulen.
+func lzfDecompress(in []byte, ulen int) ([]byte, error) {
+	out := make([]byte, 0, ulen)
+	i := 0
+	for i < len(in) {
+		ctrl := int(in[i])
+		i++
+		if ctrl < 32 {
+			// literal run
+			n := ctrl + 1
+			if i+n > len(in) {
+				return nil, ErrFormat
+			}
+			out = append(out, in[i:i+n]...)
+			i += n
+			continue
+		}
+		// back reference
+		n := ctrl >> 5
+		if n == 7 {
+			if i >= len(in) {
+				return nil, ErrFormat
+			}
+			n += int(in[i])
+			i++
+		}
+		if i >= len(in) {
+			return nil, ErrFormat
+		}
+		ref := len(out) - ((ctrl&0x1F)<<8 | int(in[i])) - 1
+		i++
+		if ref < 0 {
+			return nil, ErrFormat
+		}
+		for j := range n + 2 {
+			out = append(out, out[ref+j])
+		}
+	}
+	if len(out) != ulen {
+		return nil, ErrFormat
+	}
+	return out, nil
+}
+
+// readIntLE reads a signed little-endian integer of the given size.
+func readIntLE(b []byte, size int) int64 {
+	var u uint64
+	for i := size - 1; i >= 0; i-- {
+		u = u<<8 | uint64(b[i])
+	}
+	// sign-extend
+	shift := 64 - 8*size
+	return int64(u<<shift) >> shift
+}
+
+// sliceN returns n bytes from b starting at offset.
+// Also returns the total number of bytes consumed.
+func sliceN(b []byte, offset, n int) ([]byte, int, error) {
+	if offset+n > len(b) {
+		return nil, 0, ErrFormat
+	}
+	return b[offset : offset+n], offset + n, nil
+}
+
+// toList returns the items as is.
+func toList(items [][]byte) ([][]byte, error) {
+	return items, nil
+}
+
+// toHash converts interleaved field-value items to a hash.
+func toHash(items [][]byte) (map[string][]byte, error) {
+	if len(items)%2 != 0 {
+		return nil, ErrFormat
+	}
+	hash := make(map[string][]byte, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		hash[string(items[i])] = items[i+1]
+	}
+	return hash, nil
+}
+
+// toZSet converts interleaved element-score items to a sorted set.
+func toZSet(items [][]byte) (map[string]float64, error) {
+	if len(items)%2 != 0 {
+		return nil, ErrFormat
+	}
+	zset := make(map[string]float64, len(items)/2)
+	for i := 0; i < len(items); i += 2 {
+		score, err := strconv.ParseFloat(string(items[i+1]), 64)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		zset[string(items[i])] = score
+	}
+	return zset, nil
+}
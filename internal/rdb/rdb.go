@@ -0,0 +1,133 @@
+// Package rdb reads and writes Redis RDB files.
+// Supports strings, lists, sets, hashes, sorted sets and streams
+// (without consumer groups) in all encodings used by Redis up to
+// version 7.x, and the hashes with field expiration times in the
+// plain encoding introduced in Redis 7.4.
+package rdb
+
+import (
+	"errors"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// Opcodes used in the RDB file.
+const (
+	opFunction2    = 0xF5
+	opModuleAux    = 0xF7
+	opIdle         = 0xF8
+	opFreq         = 0xF9
+	opAux          = 0xFA
+	opResizeDB     = 0xFB
+	opExpireTimeMs = 0xFC
+	opExpireTime   = 0xFD
+	opSelectDB     = 0xFE
+	opEOF          = 0xFF
+)
+
+// Value types used in the RDB file.
+const (
+	typeString           = 0
+	typeList             = 1
+	typeSet              = 2
+	typeZSet             = 3
+	typeHash             = 4
+	typeZSet2            = 5
+	typeHashZipmap       = 9
+	typeListZiplist      = 10
+	typeSetIntset        = 11
+	typeZSetZiplist      = 12
+	typeHashZiplist      = 13
+	typeListQuicklist    = 14
+	typeStreamListpacks  = 15
+	typeHashListpack     = 16
+	typeZSetListpack     = 17
+	typeListQuicklist2   = 18
+	typeStreamListpacks2 = 19
+	typeSetListpack      = 20
+	typeStreamListpacks3 = 21
+	typeHashMetadata     = 24
+)
+
+// Stream entry flags.
+const (
+	streamDeleted    = 1 << 0
+	streamSameFields = 1 << 1
+)
+
+// streamNodeSize is the maximum number of entries in a stream
+// listpack node written by this package (same as the default
+// stream-node-max-entries setting in Redis).
+const streamNodeSize = 100
+
+// Version is the RDB version written by this package.
+const Version = 11
+
+// versionHashMetadata is the RDB version that introduced
+// the hashes with field expiration times (Redis 7.4).
+const versionHashMetadata = 12
+
+// maxVersion is the latest RDB version supported by the reader.
+const maxVersion = 12
+
+var (
+	ErrFormat      = errors.New("rdb: invalid format")
+	ErrVersion     = errors.New("rdb: unsupported version")
+	ErrChecksum    = errors.New("rdb: checksum mismatch")
+	ErrUnsupported = errors.New("rdb: unsupported value type")
+)
+
+// Entry is a key-value pair stored in the RDB file.
+// Depending on the Type, one of the value fields is set:
+//   - TypeString: Str
+//   - TypeList, TypeSet: List
+//   - TypeHash: Hash (and HashETimes for the fields that expire)
+//   - TypeSortedSet: ZSet
+//   - TypeStream: Stream
+type Entry struct {
+	DB    int
+	Key   string
+	Type  core.TypeID
+	ETime *int64 // expiration time in unix milliseconds
+
+	Str        []byte
+	List       [][]byte
+	Hash       map[string][]byte
+	HashETimes map[string]int64 // field expiration times in unix milliseconds
+	ZSet       map[string]float64
+	Stream     []StreamEntry
+}
+
+// StreamEntry is an entry of a stream.
+type StreamEntry struct {
+	Time   int64    // milliseconds part of the ID
+	Seq    int64    // sequence part of the ID
+	Fields [][]byte // interleaved field-value pairs
+}
+
+// crcTable is the CRC-64/Jones lookup table used by Redis.
+var crcTable = makeCRCTable(0x95AC9329AC4BC9B5)
+
+func makeCRCTable(poly uint64) *[256]uint64 {
+	t := new([256]uint64)
+	for i := range 256 {
+		crc := uint64(i)
+		for range 8 {
+			if crc&1 == 1 {
+				crc = (crc >> 1) ^ poly
+			} else {
+				crc >>= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}
+
+// crc64 updates the Redis CRC-64 checksum with p.
+func crc64(crc uint64, p []byte) uint64 {
+	for _, b := range p {
+		crc = crcTable[byte(crc)^b] ^ (crc >> 8)
+	}
+	return crc
+}
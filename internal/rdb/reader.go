@@ -0,0 +1,679 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// Reader reads entries from an RDB file.
+type Reader struct {
+	r       *bufio.Reader
+	crc     uint64
+	nread   int64
+	version int
+	db      int
+	started bool
+	done    bool
+}
+
+// NewReader creates a new RDB reader.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Version returns the RDB version of the file.
+// Only available after the first call to Next.
+func (r *Reader) Version() int {
+	return r.version
+}
+
+// BytesRead returns the number of bytes read so far.
+func (r *Reader) BytesRead() int64 {
+	return r.nread
+}
+
+// Next reads the next entry from the file.
+// Returns io.EOF when there are no more entries.
+func (r *Reader) Next() (Entry, error) {
+	if r.done {
+		return Entry{}, io.EOF
+	}
+	if !r.started {
+		if err := r.readHeader(); err != nil {
+			return Entry{}, err
+		}
+		r.started = true
+	}
+
+	var etime *int64
+	for {
+		op, err := r.readByte()
+		if err != nil {
+			return Entry{}, unexpected(err)
+		}
+		switch op {
+		case opEOF:
+			r.done = true
+			return Entry{}, r.readChecksum()
+		case opSelectDB:
+			n, _, err := r.readLength()
+			if err != nil {
+				return Entry{}, err
+			}
+			r.db = int(n)
+		case opResizeDB:
+			if _, _, err := r.readLength(); err != nil {
+				return Entry{}, err
+			}
+			if _, _, err := r.readLength(); err != nil {
+				return Entry{}, err
+			}
+		case opAux:
+			if _, err := r.readString(); err != nil {
+				return Entry{}, err
+			}
+			if _, err := r.readString(); err != nil {
+				return Entry{}, err
+			}
+		case opExpireTime:
+			b, err := r.readFull(4)
+			if err != nil {
+				return Entry{}, err
+			}
+			at := int64(binary.LittleEndian.Uint32(b)) * 1000
+			etime = &at
+		case opExpireTimeMs:
+			b, err := r.readFull(8)
+			if err != nil {
+				return Entry{}, err
+			}
+			at := int64(binary.LittleEndian.Uint64(b))
+			etime = &at
+		case opFreq:
+			if _, err := r.readByte(); err != nil {
+				return Entry{}, unexpected(err)
+			}
+		case opIdle:
+			if _, _, err := r.readLength(); err != nil {
+				return Entry{}, err
+			}
+		case opFunction2:
+			if _, err := r.readString(); err != nil {
+				return Entry{}, err
+			}
+		case opModuleAux:
+			return Entry{}, fmt.Errorf("%w: module aux data", ErrUnsupported)
+		default:
+			key, err := r.readString()
+			if err != nil {
+				return Entry{}, err
+			}
+			e := Entry{DB: r.db, Key: string(key), ETime: etime}
+			err = r.readValue(op, &e)
+			return e, err
+		}
+	}
+}
+
+// readHeader reads and validates the file header.
+func (r *Reader) readHeader() error {
+	b, err := r.readFull(9)
+	if err != nil {
+		return err
+	}
+	if string(b[:5]) != "REDIS" {
+		return ErrFormat
+	}
+	ver, err := strconv.Atoi(string(b[5:]))
+	if err != nil {
+		return ErrFormat
+	}
+	if ver < 1 || ver > maxVersion {
+		return fmt.Errorf("%w: %d", ErrVersion, ver)
+	}
+	r.version = ver
+	return nil
+}
+
+// readChecksum reads the trailing checksum and validates it.
+func (r *Reader) readChecksum() error {
+	if r.version < 5 {
+		return io.EOF
+	}
+	want := r.crc
+	b, err := r.readFull(8)
+	if err != nil {
+		return err
+	}
+	got := binary.LittleEndian.Uint64(b)
+	// A zero checksum means that the checksum is disabled.
+	if got != 0 && got != want {
+		return ErrChecksum
+	}
+	return io.EOF
+}
+
+// readValue reads a value of the given type into the entry.
+func (r *Reader) readValue(typ byte, e *Entry) error {
+	d := decoder{r}
+	return d.readValue(typ, e)
+}
+
+// readByte reads a single byte.
+func (r *Reader) readByte() (byte, error) {
+	b, err := r.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	r.crc = crc64(r.crc, []byte{b})
+	r.nread++
+	return b, nil
+}
+
+// readFull reads exactly n bytes.
+func (r *Reader) readFull(n int) ([]byte, error) {
+	b := make([]byte, n)
+	_, err := io.ReadFull(r.r, b)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	r.crc = crc64(r.crc, b)
+	r.nread += int64(n)
+	return b, nil
+}
+
+// readLength reads a length-encoded integer.
+func (r *Reader) readLength() (uint64, bool, error) {
+	d := decoder{r}
+	return d.readLength()
+}
+
+// readString reads a length-prefixed string.
+func (r *Reader) readString() ([]byte, error) {
+	d := decoder{r}
+	return d.readString()
+}
+
+// byteReader is a source of bytes for the decoder.
+type byteReader interface {
+	readByte() (byte, error)
+	readFull(n int) ([]byte, error)
+}
+
+// decoder decodes RDB-encoded values.
+type decoder struct {
+	r byteReader
+}
+
+// readValue reads a value of the given type into the entry.
+func (d decoder) readValue(typ byte, e *Entry) error {
+	var err error
+	switch typ {
+	case typeString:
+		e.Type = core.TypeString
+		e.Str, err = d.readString()
+	case typeList, typeSet:
+		e.Type = core.TypeList
+		if typ == typeSet {
+			e.Type = core.TypeSet
+		}
+		e.List, err = d.readStringList()
+	case typeZSet, typeZSet2:
+		e.Type = core.TypeSortedSet
+		e.ZSet, err = d.readZSet(typ == typeZSet2)
+	case typeHash:
+		e.Type = core.TypeHash
+		e.Hash, err = d.readHash()
+	case typeHashMetadata:
+		e.Type = core.TypeHash
+		e.Hash, e.HashETimes, err = d.readHashMetadata()
+	case typeHashZipmap:
+		e.Type = core.TypeHash
+		e.Hash, err = readEncoded(d, parseZipmap, toHash)
+	case typeListZiplist:
+		e.Type = core.TypeList
+		e.List, err = readEncoded(d, parseZiplist, toList)
+	case typeSetIntset:
+		e.Type = core.TypeSet
+		e.List, err = readEncoded(d, parseIntset, toList)
+	case typeSetListpack:
+		e.Type = core.TypeSet
+		e.List, err = readEncoded(d, parseListpack, toList)
+	case typeZSetZiplist:
+		e.Type = core.TypeSortedSet
+		e.ZSet, err = readEncoded(d, parseZiplist, toZSet)
+	case typeZSetListpack:
+		e.Type = core.TypeSortedSet
+		e.ZSet, err = readEncoded(d, parseListpack, toZSet)
+	case typeHashZiplist:
+		e.Type = core.TypeHash
+		e.Hash, err = readEncoded(d, parseZiplist, toHash)
+	case typeHashListpack:
+		e.Type = core.TypeHash
+		e.Hash, err = readEncoded(d, parseListpack, toHash)
+	case typeListQuicklist:
+		e.Type = core.TypeList
+		e.List, err = d.readQuicklist(false)
+	case typeListQuicklist2:
+		e.Type = core.TypeList
+		e.List, err = d.readQuicklist(true)
+	case typeStreamListpacks, typeStreamListpacks2, typeStreamListpacks3:
+		e.Type = core.TypeStream
+		e.Stream, err = d.readStream(typ)
+	default:
+		err = fmt.Errorf("%w: %d", ErrUnsupported, typ)
+	}
+	return err
+}
+
+// readLength reads a length-encoded integer. Returns true if
+// the length is a special encoding rather than an actual length.
+func (d decoder) readLength() (uint64, bool, error) {
+	b, err := d.r.readByte()
+	if err != nil {
+		return 0, false, unexpected(err)
+	}
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3F), false, nil
+	case 1:
+		next, err := d.r.readByte()
+		if err != nil {
+			return 0, false, unexpected(err)
+		}
+		return uint64(b&0x3F)<<8 | uint64(next), false, nil
+	case 2:
+		switch b {
+		case 0x80:
+			buf, err := d.r.readFull(4)
+			if err != nil {
+				return 0, false, err
+			}
+			return uint64(binary.BigEndian.Uint32(buf)), false, nil
+		case 0x81:
+			buf, err := d.r.readFull(8)
+			if err != nil {
+				return 0, false, err
+			}
+			return binary.BigEndian.Uint64(buf), false, nil
+		default:
+			return 0, false, ErrFormat
+		}
+	default:
+		return uint64(b & 0x3F), true, nil
+	}
+}
+
+// readString reads a length-prefixed or specially encoded string.
+func (d decoder) readString() ([]byte, error) {
+	n, encoded, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		return d.r.readFull(int(n))
+	}
+	switch n {
+	case 0:
+		b, err := d.r.readFull(1)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(int8(b[0])), 10), nil
+	case 1:
+		b, err := d.r.readFull(2)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(int16(binary.LittleEndian.Uint16(b))), 10), nil
+	case 2:
+		b, err := d.r.readFull(4)
+		if err != nil {
+			return nil, err
+		}
+		return strconv.AppendInt(nil, int64(int32(binary.LittleEndian.Uint32(b))), 10), nil
+	case 3:
+		return d.readLZF()
+	default:
+		return nil, ErrFormat
+	}
+}
+
+// readLZF reads an LZF-compressed string.
+func (d decoder) readLZF() ([]byte, error) {
+	clen, _, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	ulen, _, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.r.readFull(int(clen))
+	if err != nil {
+		return nil, err
+	}
+	return lzfDecompress(data, int(ulen))
+}
+
+// readStringList reads a length-prefixed list of strings.
+func (d decoder) readStringList() ([][]byte, error) {
+	n, _, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	list := make([][]byte, 0, n)
+	for range n {
+		s, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+// readHash reads a length-prefixed list of field-value pairs.
+func (d decoder) readHash() (map[string][]byte, error) {
+	n, _, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	hash := make(map[string][]byte, n)
+	for range n {
+		field, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		hash[string(field)] = value
+	}
+	return hash, nil
+}
+
+// readHashMetadata reads the hash with the field expiration
+// times (see encoder.writeHashMetadata). Returns the expiration
+// times only for the fields that expire.
+func (d decoder) readHashMetadata() (map[string][]byte, map[string]int64, error) {
+	buf, err := d.r.readFull(8)
+	if err != nil {
+		return nil, nil, err
+	}
+	minETime := int64(binary.LittleEndian.Uint64(buf))
+	n, _, err := d.readLength()
+	if err != nil {
+		return nil, nil, err
+	}
+	hash := make(map[string][]byte, n)
+	etimes := map[string]int64{}
+	for range n {
+		ttl, _, err := d.readLength()
+		if err != nil {
+			return nil, nil, err
+		}
+		field, err := d.readString()
+		if err != nil {
+			return nil, nil, err
+		}
+		val, err := d.readString()
+		if err != nil {
+			return nil, nil, err
+		}
+		hash[string(field)] = val
+		if ttl != 0 {
+			etimes[string(field)] = minETime + int64(ttl) - 1
+		}
+	}
+	return hash, etimes, nil
+}
+
+// readZSet reads a length-prefixed list of element-score pairs.
+func (d decoder) readZSet(binaryScore bool) (map[string]float64, error) {
+	n, _, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	zset := make(map[string]float64, n)
+	for range n {
+		elem, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		var score float64
+		if binaryScore {
+			score, err = d.readBinaryDouble()
+		} else {
+			score, err = d.readStringDouble()
+		}
+		if err != nil {
+			return nil, err
+		}
+		zset[string(elem)] = score
+	}
+	return zset, nil
+}
+
+// readBinaryDouble reads a little-endian IEEE 754 double.
+func (d decoder) readBinaryDouble() (float64, error) {
+	b, err := d.r.readFull(8)
+	if err != nil {
+		return 0, err
+	}
+	return math.Float64frombits(binary.LittleEndian.Uint64(b)), nil
+}
+
+// readStringDouble reads a double encoded as a string
+// with a single-byte length prefix.
+func (d decoder) readStringDouble() (float64, error) {
+	n, err := d.r.readByte()
+	if err != nil {
+		return 0, unexpected(err)
+	}
+	switch n {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	b, err := d.r.readFull(int(n))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(b), 64)
+}
+
+// readQuicklist reads a list encoded as a sequence of ziplists
+// (quicklist v1) or listpacks and plain nodes (quicklist v2).
+func (d decoder) readQuicklist(v2 bool) ([][]byte, error) {
+	n, _, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	var list [][]byte
+	for range n {
+		container := uint64(2)
+		if v2 {
+			container, _, err = d.readLength()
+			if err != nil {
+				return nil, err
+			}
+		}
+		node, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if container == 1 {
+			// plain node
+			list = append(list, node)
+			continue
+		}
+		var items [][]byte
+		if v2 {
+			items, err = parseListpack(node)
+		} else {
+			items, err = parseZiplist(node)
+		}
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, items...)
+	}
+	return list, nil
+}
+
+// readStream reads a stream value. Reads the entries
+// and discards the metadata and the consumer groups.
+func (d decoder) readStream(typ byte) ([]StreamEntry, error) {
+	// listpack nodes: master ID (16 bytes), listpack
+	n, _, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	var stream []StreamEntry
+	for range n {
+		key, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != 16 {
+			return nil, ErrFormat
+		}
+		blob, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		items, err := parseListpack(blob)
+		if err != nil {
+			return nil, err
+		}
+		ms := int64(binary.BigEndian.Uint64(key[:8]))
+		seq := int64(binary.BigEndian.Uint64(key[8:]))
+		entries, err := parseStreamNode(items, ms, seq)
+		if err != nil {
+			return nil, err
+		}
+		stream = append(stream, entries...)
+	}
+	if err := d.skipStreamMeta(typ); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// skipStreamMeta reads the stream metadata
+// and consumer groups and discards them.
+func (d decoder) skipStreamMeta(typ byte) error {
+	// length, last id
+	if err := d.skipLengths(3); err != nil {
+		return err
+	}
+	if typ >= typeStreamListpacks2 {
+		// first id, max deleted id, entries added
+		if err := d.skipLengths(5); err != nil {
+			return err
+		}
+	}
+	// consumer groups
+	ngroups, _, err := d.readLength()
+	if err != nil {
+		return err
+	}
+	for range ngroups {
+		if _, err := d.readString(); err != nil {
+			return err
+		}
+		// last id
+		if err := d.skipLengths(2); err != nil {
+			return err
+		}
+		if typ >= typeStreamListpacks2 {
+			// entries read
+			if err := d.skipLengths(1); err != nil {
+				return err
+			}
+		}
+		// pending entries: id (16 bytes), delivery time (8), delivery count
+		npending, _, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		for range npending {
+			if _, err := d.r.readFull(16 + 8); err != nil {
+				return err
+			}
+			if err := d.skipLengths(1); err != nil {
+				return err
+			}
+		}
+		// consumers: name, seen time (8), [active time (8)], pending ids
+		nconsumers, _, err := d.readLength()
+		if err != nil {
+			return err
+		}
+		for range nconsumers {
+			if _, err := d.readString(); err != nil {
+				return err
+			}
+			size := 8
+			if typ >= typeStreamListpacks3 {
+				size = 16
+			}
+			if _, err := d.r.readFull(size); err != nil {
+				return err
+			}
+			npending, _, err := d.readLength()
+			if err != nil {
+				return err
+			}
+			if _, err := d.r.readFull(int(npending) * 16); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// skipLengths reads n length-encoded integers and discards them.
+func (d decoder) skipLengths(n int) error {
+	for range n {
+		if _, _, err := d.readLength(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readEncoded reads a string blob, parses it into items with the parse
+// function and converts the items to the resulting value.
+func readEncoded[T any](d decoder, parse func([]byte) ([][]byte, error),
+	convert func([][]byte) (T, error)) (T, error) {
+	var zero T
+	blob, err := d.readString()
+	if err != nil {
+		return zero, err
+	}
+	items, err := parse(blob)
+	if err != nil {
+		return zero, err
+	}
+	return convert(items)
+}
+
+// unexpected converts io.EOF to io.ErrUnexpectedEOF.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,296 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestCRC64(t *testing.T) {
+	got := crc64(0, []byte("123456789"))
+	testx.AssertEqual(t, got, uint64(0xe9c6d914c4b8d9ca))
+}
+
+func TestReaderHeader(t *testing.T) {
+	t.Run("invalid magic", func(t *testing.T) {
+		r := NewReader(bytes.NewReader([]byte("RODIS0011")))
+		_, err := r.Next()
+		testx.AssertErr(t, err, ErrFormat)
+	})
+	t.Run("empty file", func(t *testing.T) {
+		b := newBuilder()
+		r := NewReader(bytes.NewReader(b.finish()))
+		_, err := r.Next()
+		testx.AssertErr(t, err, io.EOF)
+		testx.AssertEqual(t, r.Version(), 11)
+	})
+	t.Run("checksum mismatch", func(t *testing.T) {
+		b := newBuilder()
+		b.op(typeString).str("name").str("alice")
+		data := b.finish()
+		data[len(data)-1] ^= 0xFF
+		r := NewReader(bytes.NewReader(data))
+		_, err := r.Next()
+		testx.AssertNoErr(t, err)
+		_, err = r.Next()
+		testx.AssertErr(t, err, ErrChecksum)
+	})
+	t.Run("truncated", func(t *testing.T) {
+		b := newBuilder()
+		b.op(typeString).str("name").str("alice")
+		data := b.finish()
+		r := NewReader(bytes.NewReader(data[:15]))
+		_, err := r.Next()
+		testx.AssertErr(t, err, io.ErrUnexpectedEOF)
+	})
+}
+
+func TestReaderStrings(t *testing.T) {
+	b := newBuilder()
+	b.aux("redis-ver", "7.2.0")
+	b.op(opSelectDB).raw(0)
+	b.op(opResizeDB).raw(5, 1)
+	b.op(typeString).str("plain").str("alice")
+	b.op(typeString).str("int8").raw(0xC0, 0xFB)
+	b.op(typeString).str("int16").raw(0xC1, 0x39, 0x30)
+	b.op(typeString).str("int32").raw(0xC2, 0x15, 0xCD, 0x5B, 0x07)
+	// "aaaaaaaaaa" compressed with LZF
+	b.op(typeString).str("lzf").raw(0xC3, 5, 10, 0x00, 'a', 0xE0, 0x00, 0x00)
+	b.op(opExpireTimeMs).u64(1700000000000)
+	b.op(typeString).str("expires").str("value")
+
+	entries := readAll(t, b.finish())
+	testx.AssertEqual(t, len(entries), 6)
+	want := []string{"alice", "-5", "12345", "123456789", "aaaaaaaaaa", "value"}
+	for i, e := range entries {
+		testx.AssertEqual(t, e.Type, core.TypeString)
+		testx.AssertEqual(t, string(e.Str), want[i])
+	}
+	testx.AssertEqual(t, entries[0].ETime, (*int64)(nil))
+	testx.AssertEqual(t, *entries[5].ETime, int64(1700000000000))
+}
+
+func TestReaderPlainTypes(t *testing.T) {
+	b := newBuilder()
+	b.op(opSelectDB).raw(2)
+	b.op(typeList).str("list").raw(2).str("a").str("b")
+	b.op(typeSet).str("set").raw(1).str("x")
+	b.op(typeHash).str("hash").raw(1).str("f").str("v")
+	b.op(typeZSet).str("zset").raw(2).str("one").raw(3).raw([]byte("1.5")...).str("inf").raw(254)
+	b.op(typeZSet2).str("zset2").raw(1).str("two").f64(2.5)
+
+	entries := readAll(t, b.finish())
+	testx.AssertEqual(t, len(entries), 5)
+	testx.AssertEqual(t, entries[0].DB, 2)
+	testx.AssertEqual(t, entries[0].Type, core.TypeList)
+	testx.AssertEqual(t, entries[0].List, [][]byte{[]byte("a"), []byte("b")})
+	testx.AssertEqual(t, entries[1].Type, core.TypeSet)
+	testx.AssertEqual(t, entries[1].List, [][]byte{[]byte("x")})
+	testx.AssertEqual(t, entries[2].Type, core.TypeHash)
+	testx.AssertEqual(t, entries[2].Hash, map[string][]byte{"f": []byte("v")})
+	testx.AssertEqual(t, entries[3].Type, core.TypeSortedSet)
+	testx.AssertEqual(t, entries[3].ZSet, map[string]float64{"one": 1.5, "inf": math.Inf(1)})
+	testx.AssertEqual(t, entries[4].ZSet, map[string]float64{"two": 2.5})
+}
+
+func TestReaderHashMetadata(t *testing.T) {
+	// The field TTLs are relative to the earliest one, plus one.
+	b := newBuilder()
+	b.op(typeHashMetadata).str("hash").u64(1700000000000).raw(3)
+	b.raw(1).str("name").str("alice")
+	b.raw(0).str("age").str("25")
+	b.raw(11).str("city").str("paris")
+
+	entries := readAll(t, b.finish())
+	testx.AssertEqual(t, len(entries), 1)
+	testx.AssertEqual(t, entries[0].Type, core.TypeHash)
+	testx.AssertEqual(t, entries[0].Hash, map[string][]byte{
+		"name": []byte("alice"), "age": []byte("25"), "city": []byte("paris"),
+	})
+	testx.AssertEqual(t, entries[0].HashETimes, map[string]int64{
+		"name": 1700000000000, "city": 1700000000010,
+	})
+}
+
+func TestReaderEncodedTypes(t *testing.T) {
+	// ziplist: "abc", int8 -3, immediate 4
+	ziplist := []byte{0, 0, 0, 0, 0, 0, 0, 0, 3, 0,
+		0, 0x03, 'a', 'b', 'c',
+		5, 0xFE, 0xFD,
+		3, 0xF5,
+		0xFF}
+	// listpack: "field", 5, "neg", -1 (13-bit)
+	listpack := []byte{0, 0, 0, 0, 4, 0,
+		0x85, 'f', 'i', 'e', 'l', 'd', 6,
+		0x05, 1,
+		0x83, 'n', 'e', 'g', 4,
+		0xDF, 0xFF, 2,
+		0xFF}
+	// intset: 1, -2 (int16)
+	intset := []byte{2, 0, 0, 0, 2, 0, 0, 0, 1, 0, 0xFE, 0xFF}
+	// zipmap: a => bc
+	zipmap := []byte{1, 1, 'a', 2, 0, 'b', 'c', 0xFF}
+	// listpack: "x", "y"
+	quick := []byte{0, 0, 0, 0, 2, 0, 0x81, 'x', 2, 0x81, 'y', 2, 0xFF}
+
+	b := newBuilder()
+	b.op(typeListZiplist).str("ziplist").blob(ziplist)
+	b.op(typeHashListpack).str("hash").blob(listpack)
+	b.op(typeSetIntset).str("intset").blob(intset)
+	b.op(typeHashZipmap).str("zipmap").blob(zipmap)
+	b.op(typeListQuicklist2).str("quick").raw(2, 2).blob(quick).raw(1).str("plain")
+	b.op(typeSetListpack).str("lpset").blob(quick)
+
+	entries := readAll(t, b.finish())
+	testx.AssertEqual(t, len(entries), 6)
+	testx.AssertEqual(t, entries[0].List, [][]byte{[]byte("abc"), []byte("-3"), []byte("4")})
+	testx.AssertEqual(t, entries[1].Hash, map[string][]byte{
+		"field": []byte("5"), "neg": []byte("-1"),
+	})
+	testx.AssertEqual(t, entries[2].Type, core.TypeSet)
+	testx.AssertEqual(t, entries[2].List, [][]byte{[]byte("1"), []byte("-2")})
+	testx.AssertEqual(t, entries[3].Hash, map[string][]byte{"a": []byte("bc")})
+	testx.AssertEqual(t, entries[4].List, [][]byte{[]byte("x"), []byte("y"), []byte("plain")})
+	testx.AssertEqual(t, entries[5].List, [][]byte{[]byte("x"), []byte("y")})
+}
+
+func TestReaderStream(t *testing.T) {
+	// Master ID 1-5 with fields "user" and "action". Entries:
+	// 1-5 (same fields), 1-6 (same fields, deleted), 2-0 (own fields).
+	lp := make([]byte, 6)
+	ints := func(vals ...int64) {
+		for _, v := range vals {
+			lp = appendListpackInt(lp, v)
+		}
+	}
+	strs := func(vals ...string) {
+		for _, v := range vals {
+			lp = appendListpackString(lp, []byte(v))
+		}
+	}
+	ints(2, 1, 2)
+	strs("user", "action")
+	ints(0)
+	ints(streamSameFields, 0, 0)
+	strs("alice", "login")
+	ints(5)
+	ints(streamSameFields|streamDeleted, 0, 1)
+	strs("bob", "login")
+	ints(5)
+	ints(0, 1, -5, 1)
+	strs("user", "cindy")
+	ints(6)
+	lp = finishListpack(lp, 20)
+	key := binary.BigEndian.AppendUint64(nil, 1)
+	key = binary.BigEndian.AppendUint64(key, 5)
+
+	b := newBuilder()
+	b.op(typeStreamListpacks2).str("events")
+	b.raw(1).blob(key).raw(0x40|byte(len(lp)>>8), byte(len(lp))).raw(lp...)
+	// length, last id, first id, max deleted id, entries added
+	b.raw(2, 2, 0, 1, 5, 1, 6, 3)
+	// consumer group: name, last id, entries read
+	b.raw(1).str("group").raw(2, 0, 3)
+	// pending entries: id, delivery time, delivery count
+	b.raw(1).raw(make([]byte, 16+8)...).raw(1)
+	// consumers: name, seen time, pending ids
+	b.raw(1).str("alice").raw(make([]byte, 8)...).raw(1).raw(make([]byte, 16)...)
+	b.op(typeString).str("name").str("alice")
+
+	entries := readAll(t, b.finish())
+	testx.AssertEqual(t, len(entries), 2)
+	testx.AssertEqual(t, entries[0].Type, core.TypeStream)
+	testx.AssertEqual(t, entries[0].Stream, []StreamEntry{
+		{Time: 1, Seq: 5, Fields: [][]byte{
+			[]byte("user"), []byte("alice"), []byte("action"), []byte("login"),
+		}},
+		{Time: 2, Seq: 0, Fields: [][]byte{[]byte("user"), []byte("cindy")}},
+	})
+	testx.AssertEqual(t, entries[1].Key, "name")
+}
+
+func TestReaderUnsupported(t *testing.T) {
+	b := newBuilder()
+	b.op(7).str("module").raw(0)
+	r := NewReader(bytes.NewReader(b.finish()))
+	_, err := r.Next()
+	testx.AssertEqual(t, err != nil, true)
+}
+
+// readAll reads all entries from the RDB data.
+func readAll(t *testing.T, data []byte) []Entry {
+	t.Helper()
+	r := NewReader(bytes.NewReader(data))
+	var entries []Entry
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		entries = append(entries, e)
+	}
+	testx.AssertEqual(t, r.BytesRead(), int64(len(data)))
+	return entries
+}
+
+// builder assembles RDB files for tests.
+// Only supports lengths < 64.
+type builder struct {
+	bytes.Buffer
+}
+
+func newBuilder() *builder {
+	b := &builder{}
+	b.WriteString("REDIS0011")
+	return b
+}
+
+func (b *builder) op(op byte) *builder {
+	b.WriteByte(op)
+	return b
+}
+
+func (b *builder) raw(p ...byte) *builder {
+	b.Write(p)
+	return b
+}
+
+func (b *builder) str(s string) *builder {
+	b.WriteByte(byte(len(s)))
+	b.WriteString(s)
+	return b
+}
+
+func (b *builder) blob(p []byte) *builder {
+	b.WriteByte(byte(len(p)))
+	b.Write(p)
+	return b
+}
+
+func (b *builder) aux(key, val string) *builder {
+	return b.op(opAux).str(key).str(val)
+}
+
+func (b *builder) u64(n uint64) *builder {
+	b.Write(binary.LittleEndian.AppendUint64(nil, n))
+	return b
+}
+
+func (b *builder) f64(f float64) *builder {
+	return b.u64(math.Float64bits(f))
+}
+
+func (b *builder) finish() []byte {
+	b.WriteByte(opEOF)
+	crc := crc64(0, b.Bytes())
+	b.Write(binary.LittleEndian.AppendUint64(nil, crc))
+	return b.Bytes()
+}
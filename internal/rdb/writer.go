@@ -0,0 +1,244 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// Writer writes entries to an RDB file.
+// Uses the plain (non-compact) encodings for all value types,
+// so the file can be read by any Redis version that supports
+// the RDB version written. The hashes with field expiration
+// times can only be read by Redis 7.4 or later.
+type Writer struct {
+	w       *bufio.Writer
+	crc     uint64
+	db      int
+	started bool
+}
+
+// NewWriter creates a new RDB writer.
+// The caller must call Close to finish the file.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w), db: -1}
+}
+
+// Write writes an entry to the file.
+// Writes the file header before the first entry.
+func (w *Writer) Write(e Entry) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	if e.DB != w.db {
+		w.writeByte(opSelectDB)
+		w.writeLength(uint64(e.DB))
+		w.db = e.DB
+	}
+	if e.ETime != nil {
+		w.writeByte(opExpireTimeMs)
+		w.write(binary.LittleEndian.AppendUint64(nil, uint64(*e.ETime)))
+	}
+
+	enc := encoder{w}
+	switch e.Type {
+	case core.TypeString:
+		w.writeByte(typeString)
+		enc.writeString([]byte(e.Key))
+		enc.writeString(e.Str)
+	case core.TypeList, core.TypeSet:
+		if e.Type == core.TypeList {
+			w.writeByte(typeList)
+		} else {
+			w.writeByte(typeSet)
+		}
+		enc.writeString([]byte(e.Key))
+		enc.writeStringList(e.List)
+	case core.TypeHash:
+		if len(e.HashETimes) > 0 {
+			w.writeByte(typeHashMetadata)
+			enc.writeString([]byte(e.Key))
+			enc.writeHashMetadata(e.Hash, e.HashETimes)
+			break
+		}
+		w.writeByte(typeHash)
+		enc.writeString([]byte(e.Key))
+		enc.writeHash(e.Hash)
+	case core.TypeSortedSet:
+		w.writeByte(typeZSet2)
+		enc.writeString([]byte(e.Key))
+		enc.writeZSet(e.ZSet)
+	case core.TypeStream:
+		w.writeByte(typeStreamListpacks)
+		enc.writeString([]byte(e.Key))
+		enc.writeStream(e.Stream)
+	default:
+		return fmt.Errorf("%w: %d", ErrUnsupported, e.Type)
+	}
+	return nil
+}
+
+// Close writes the end of file marker and the checksum,
+// and flushes the data to the underlying writer.
+// Does not close the underlying writer.
+func (w *Writer) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	w.writeByte(opEOF)
+	crc := w.crc
+	_, err := w.w.Write(binary.LittleEndian.AppendUint64(nil, crc))
+	if err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+// start writes the file header and auxiliary fields if necessary.
+func (w *Writer) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	w.write([]byte(fmt.Sprintf("REDIS%04d", Version)))
+	enc := encoder{w}
+	w.writeByte(opAux)
+	enc.writeString([]byte("redis-bits"))
+	enc.writeString([]byte("64"))
+	w.writeByte(opAux)
+	enc.writeString([]byte("ctime"))
+	enc.writeString(strconv.AppendInt(nil, time.Now().Unix(), 10))
+	return nil
+}
+
+// writeByte writes a single byte.
+func (w *Writer) writeByte(b byte) {
+	w.write([]byte{b})
+}
+
+// write writes the bytes and updates the checksum.
+// Errors are reported by the bufio.Writer on Flush.
+func (w *Writer) write(p []byte) {
+	w.crc = crc64(w.crc, p)
+	_, _ = w.w.Write(p)
+}
+
+// writeLength writes a length-encoded integer.
+func (w *Writer) writeLength(n uint64) {
+	encoder{w}.writeLength(n)
+}
+
+// byteWriter is a destination of bytes for the encoder.
+type byteWriter interface {
+	writeByte(b byte)
+	write(p []byte)
+}
+
+// encoder encodes values using RDB encodings.
+type encoder struct {
+	w byteWriter
+}
+
+// writeLength writes a length-encoded integer.
+func (e encoder) writeLength(n uint64) {
+	switch {
+	case n < 1<<6:
+		e.w.writeByte(byte(n))
+	case n < 1<<14:
+		e.w.write([]byte{byte(n>>8) | 0x40, byte(n)})
+	case n <= math.MaxUint32:
+		e.w.writeByte(0x80)
+		e.w.write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		e.w.writeByte(0x81)
+		e.w.write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+// writeString writes a length-prefixed string.
+func (e encoder) writeString(s []byte) {
+	e.writeLength(uint64(len(s)))
+	e.w.write(s)
+}
+
+// writeStringList writes a length-prefixed list of strings.
+func (e encoder) writeStringList(list [][]byte) {
+	e.writeLength(uint64(len(list)))
+	for _, s := range list {
+		e.writeString(s)
+	}
+}
+
+// writeHash writes a length-prefixed list of field-value pairs.
+func (e encoder) writeHash(hash map[string][]byte) {
+	e.writeLength(uint64(len(hash)))
+	for field, val := range hash {
+		e.writeString([]byte(field))
+		e.writeString(val)
+	}
+}
+
+// writeHashMetadata writes the earliest field expiration time
+// followed by a length-prefixed list of field-value pairs. Each pair
+// is preceded by the field expiration time relative to the earliest
+// one, plus one (zero means the field does not expire).
+func (e encoder) writeHashMetadata(hash map[string][]byte, etimes map[string]int64) {
+	minETime := int64(math.MaxInt64)
+	for field := range hash {
+		if etime, ok := etimes[field]; ok {
+			minETime = min(minETime, etime)
+		}
+	}
+	e.w.write(binary.LittleEndian.AppendUint64(nil, uint64(minETime)))
+	e.writeLength(uint64(len(hash)))
+	for field, val := range hash {
+		var ttl uint64
+		if etime, ok := etimes[field]; ok {
+			ttl = uint64(etime-minETime) + 1
+		}
+		e.writeLength(ttl)
+		e.writeString([]byte(field))
+		e.writeString(val)
+	}
+}
+
+// writeStream writes the stream entries as listpack nodes of up
+// to streamNodeSize entries each, keyed by the ID of their first
+// entry, followed by the stream length and the last ID.
+// Does not write the consumer groups.
+func (e encoder) writeStream(stream []StreamEntry) {
+	nodes := (len(stream) + streamNodeSize - 1) / streamNodeSize
+	e.writeLength(uint64(nodes))
+	for i := 0; i < len(stream); i += streamNodeSize {
+		node := stream[i:min(i+streamNodeSize, len(stream))]
+		key := binary.BigEndian.AppendUint64(nil, uint64(node[0].Time))
+		key = binary.BigEndian.AppendUint64(key, uint64(node[0].Seq))
+		e.writeString(key)
+		e.writeString(appendStreamNode(nil, node))
+	}
+	var last StreamEntry
+	if len(stream) > 0 {
+		last = stream[len(stream)-1]
+	}
+	e.writeLength(uint64(len(stream)))
+	e.writeLength(uint64(last.Time))
+	e.writeLength(uint64(last.Seq))
+	// consumer groups
+	e.writeLength(0)
+}
+
+// writeZSet writes a length-prefixed list of element-score pairs
+// with binary-encoded scores.
+func (e encoder) writeZSet(zset map[string]float64) {
+	e.writeLength(uint64(len(zset)))
+	for elem, score := range zset {
+		e.writeString([]byte(elem))
+		e.w.write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(score)))
+	}
+}
@@ -0,0 +1,57 @@
+package rdb
+
+import (
+	"bytes"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestWriter(t *testing.T) {
+	etime := int64(1700000000000)
+	long := bytes.Repeat([]byte("x"), 20000)
+	want := []Entry{
+		{Key: "name", Type: core.TypeString, Str: []byte("alice"), ETime: &etime},
+		{Key: "long", Type: core.TypeString, Str: long},
+		{Key: "list", Type: core.TypeList, List: [][]byte{[]byte("a"), []byte("b")}},
+		{Key: "set", Type: core.TypeSet, List: [][]byte{[]byte("x")}},
+		{DB: 1, Key: "hash", Type: core.TypeHash, Hash: map[string][]byte{"f": []byte("v")}},
+		{DB: 1, Key: "hash:ttl", Type: core.TypeHash,
+			Hash:       map[string][]byte{"f1": []byte("v1"), "f2": []byte("v2"), "f3": []byte("v3")},
+			HashETimes: map[string]int64{"f1": etime, "f3": etime + 5000}},
+		{DB: 1, Key: "zset", Type: core.TypeSortedSet, ZSet: map[string]float64{"one": 1, "inf": math.Inf(-1)}},
+		{DB: 1, Key: "stream", Type: core.TypeStream, Stream: newStream(250)},
+	}
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, e := range want {
+		err := w.Write(e)
+		testx.AssertNoErr(t, err)
+	}
+	err := w.Close()
+	testx.AssertNoErr(t, err)
+
+	got := readAll(t, buf.Bytes())
+	testx.AssertEqual(t, got, want)
+}
+
+// newStream creates a stream with n entries spanning several
+// listpack nodes, with IDs and values of various sizes.
+func newStream(n int) []StreamEntry {
+	stream := make([]StreamEntry, n)
+	for i := range stream {
+		stream[i] = StreamEntry{
+			Time: 1700000000000 + int64(i/3)*int64(i),
+			Seq:  int64(i % 3 * 5000),
+			Fields: [][]byte{
+				[]byte("num"), []byte(strconv.Itoa(i)),
+				[]byte("text"), bytes.Repeat([]byte("x"), i*20),
+			},
+		}
+	}
+	return stream
+}
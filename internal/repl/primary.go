@@ -0,0 +1,323 @@
+// Package repl implements primary-replica replication
+// using the Redis replication protocol (PSYNC).
+//
+// The primary sends the replica a snapshot of the database
+// in the RDB format, followed by a stream of write commands
+// in the same format as the append-only journal (see package aof).
+// Partial resynchronization is not supported, so the replica
+// performs a full resync every time it (re)connects.
+package repl
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/aof"
+	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/resp"
+	"github.com/tidwall/redcon"
+)
+
+const (
+	// pingInterval is the interval between pings
+	// sent by the primary to the replicas.
+	pingInterval = 10 * time.Second
+	// linkBufferSize is the maximum number of pending writes
+	// for a replica. If the replica falls behind further,
+	// it is disconnected and has to resync.
+	linkBufferSize = 10000
+)
+
+// Primary serves the replicas connected to the database.
+// Safe for concurrent use.
+type Primary struct {
+	db        *redka.DB
+	id        string
+	gate      sync.RWMutex // serializes writes and snapshots
+	mu        sync.Mutex   // protects offset and links
+	offset    int64
+	links     map[*link]struct{}
+	done      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewPrimary creates a new replication primary
+// and starts pinging the replicas.
+func NewPrimary(db *redka.DB) *Primary {
+	p := &Primary{
+		db:    db,
+		id:    newReplID(),
+		links: map[*link]struct{}{},
+		done:  make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.pingLoop()
+	return p
+}
+
+// ID returns the replication ID of the primary.
+func (p *Primary) ID() string {
+	return p.id
+}
+
+// BeginWrite must be called before executing a command that
+// may modify the database, and EndWrite after it. Together they
+// guarantee that the snapshot sent to a new replica includes
+// either all or none of the changes made by the command.
+func (p *Primary) BeginWrite() {
+	p.gate.RLock()
+}
+
+// EndWrite must be called after executing a command
+// started with BeginWrite.
+func (p *Primary) EndWrite() {
+	p.gate.RUnlock()
+}
+
+// Propagate sends the commands to the connected replicas
+// as a single atomic group. Must be called between
+// BeginWrite and EndWrite.
+func (p *Primary) Propagate(cmds ...command.Cmd) {
+	if len(cmds) == 0 {
+		return
+	}
+	p.feed(aof.Encode(cmds...))
+}
+
+// Sync sends the database snapshot to the replica connection,
+// and then streams the write commands until the replica
+// disconnects or the primary is closed. The addr is the
+// replica address used for reporting.
+func (p *Primary) Sync(conn redcon.DetachedConn, addr string) error {
+	defer conn.Close()
+
+	l, snapshot, offset, err := p.register(addr)
+	if err != nil {
+		conn.WriteError("ERR " + err.Error())
+		_ = conn.Flush()
+		return err
+	}
+	defer p.unregister(l)
+	slog.Info("sync replica", "replica", addr, "offset", offset, "size", len(snapshot))
+
+	// Send the snapshot as a bulk string without the trailing CRLF.
+	conn.WriteString(fmt.Sprintf("FULLRESYNC %s %d", p.id, offset))
+	conn.WriteRaw([]byte("$" + strconv.Itoa(len(snapshot)) + "\r\n"))
+	conn.WriteRaw(snapshot)
+	if err := conn.Flush(); err != nil {
+		return err
+	}
+	l.online.Store(true)
+
+	// Read acknowledgements from the replica.
+	go func() {
+		defer l.close()
+		for {
+			cmd, err := conn.ReadCommand()
+			if err != nil {
+				return
+			}
+			l.handle(cmd.Args)
+		}
+	}()
+
+	// Stream the write commands.
+	for {
+		select {
+		case data := <-l.ch:
+			conn.WriteRaw(data)
+			// Send the pending data in a single batch.
+			for more := true; more; {
+				select {
+				case data := <-l.ch:
+					conn.WriteRaw(data)
+				default:
+					more = false
+				}
+			}
+			if err := conn.Flush(); err != nil {
+				return err
+			}
+		case <-l.done:
+			if l.overflow.Load() {
+				slog.Warn("replica is too slow, disconnect", "replica", addr)
+			}
+			return nil
+		case <-p.done:
+			return nil
+		}
+	}
+}
+
+// Info returns the state of the connected replicas.
+func (p *Primary) Info() PrimaryInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := PrimaryInfo{ID: p.id, Offset: p.offset}
+	now := time.Now()
+	for l := range p.links {
+		r := ReplicaState{Addr: l.addr, State: "wait_bgsave"}
+		if l.online.Load() {
+			r.State = "online"
+		}
+		r.Offset = l.ackOffset.Load()
+		if ack := l.ackTime.Load(); ack > 0 {
+			r.Lag = now.Sub(time.UnixMilli(ack))
+		}
+		info.Replicas = append(info.Replicas, r)
+	}
+	return info
+}
+
+// Close disconnects the replicas and stops the primary.
+func (p *Primary) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.done)
+	})
+	p.wg.Wait()
+	return nil
+}
+
+// register takes a snapshot of the database and
+// registers a new replica link starting at the snapshot.
+func (p *Primary) register(addr string) (*link, []byte, int64, error) {
+	// Block the writes, so that the snapshot and the
+	// stream offset describe the same database state.
+	p.gate.Lock()
+	defer p.gate.Unlock()
+
+	var buf bytes.Buffer
+	if err := p.db.ExportRDB(&buf); err != nil {
+		return nil, nil, 0, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	l := newLink(addr)
+	p.links[l] = struct{}{}
+	return l, buf.Bytes(), p.offset, nil
+}
+
+// unregister removes the replica link.
+func (p *Primary) unregister(l *link) {
+	l.close()
+	p.mu.Lock()
+	delete(p.links, l)
+	p.mu.Unlock()
+	slog.Info("disconnect replica", "replica", l.addr)
+}
+
+// feed appends the data to the replication stream.
+func (p *Primary) feed(data []byte) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.offset += int64(len(data))
+	for l := range p.links {
+		l.send(data)
+	}
+}
+
+// pingLoop periodically pings the replicas,
+// so they can detect a broken connection.
+func (p *Primary) pingLoop() {
+	defer p.wg.Done()
+	ping := resp.AppendCommand(nil, []byte("ping"))
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.gate.RLock()
+			p.feed(ping)
+			p.gate.RUnlock()
+		}
+	}
+}
+
+// PrimaryInfo describes the state of the primary.
+type PrimaryInfo struct {
+	ID       string
+	Offset   int64
+	Replicas []ReplicaState
+}
+
+// ReplicaState describes a connected replica.
+type ReplicaState struct {
+	Addr   string
+	State  string
+	Offset int64         // last acknowledged offset
+	Lag    time.Duration // time since the last acknowledgement
+}
+
+// link is a connection to a replica.
+type link struct {
+	addr      string
+	ch        chan []byte
+	done      chan struct{}
+	once      sync.Once
+	online    atomic.Bool
+	overflow  atomic.Bool
+	ackOffset atomic.Int64
+	ackTime   atomic.Int64
+}
+
+func newLink(addr string) *link {
+	return &link{
+		addr: addr,
+		ch:   make(chan []byte, linkBufferSize),
+		done: make(chan struct{}),
+	}
+}
+
+// send queues the data for sending to the replica.
+// Closes the link if the replica is too slow.
+func (l *link) send(data []byte) {
+	select {
+	case l.ch <- data:
+	case <-l.done:
+	default:
+		l.overflow.Store(true)
+		l.close()
+	}
+}
+
+// handle processes a command sent by the replica.
+// Only REPLCONF ACK is expected.
+func (l *link) handle(args [][]byte) {
+	if len(args) < 3 || !strings.EqualFold(string(args[0]), "replconf") ||
+		!strings.EqualFold(string(args[1]), "ack") {
+		return
+	}
+	offset, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil {
+		return
+	}
+	l.ackOffset.Store(offset)
+	l.ackTime.Store(time.Now().UnixMilli())
+}
+
+// close closes the link.
+func (l *link) close() {
+	l.once.Do(func() {
+		close(l.done)
+	})
+}
+
+// newReplID returns a random replication ID.
+func newReplID() string {
+	b := make([]byte, 20)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,406 @@
+package repl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/aof"
+	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+const (
+	// ackInterval is the interval between acknowledgements
+	// sent by the replica to the primary.
+	ackInterval = time.Second
+	// linkTimeout is the maximum time without any data from
+	// the primary before the replica considers the link broken.
+	linkTimeout = 60 * time.Second
+	// minRetryDelay and maxRetryDelay limit the delay
+	// between reconnection attempts.
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// Link states reported by the replica.
+const (
+	StateConnecting = "connecting"
+	StateSync       = "sync"
+	StateConnected  = "connected"
+)
+
+// ErrProtocol is returned when the primary
+// violates the replication protocol.
+var ErrProtocol = errors.New("repl: protocol error")
+
+// ReplicaOptions configures the replica.
+type ReplicaOptions struct {
+	// Port is the replica's own listening port reported to the primary.
+	Port int
+	// DB is the number of the primary database to replicate.
+	// Redka has a single database, so the keys
+	// from other databases are skipped.
+	DB int
+	// User and Password are used to authenticate
+	// with the primary (if it requires authentication).
+	// If User is empty, authenticates as the default user.
+	User     string
+	Password string
+}
+
+// Replica replicates the data from a primary into the database.
+// Reconnects automatically if the connection is lost.
+// Safe for concurrent use.
+//
+// The primary can be either another Redka server or a Redis server.
+// In the latter case, keys and commands of the types not supported
+// by Redka (e.g. lists or sets) are skipped.
+type Replica struct {
+	db     *redka.DB
+	addr   string
+	opts   ReplicaOptions
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	state  string
+	id     string
+	lastIO time.Time
+
+	offset  atomic.Int64
+	skipped map[string]bool // unsupported commands already reported
+}
+
+// NewReplica creates a new replica of the primary at addr (host:port).
+// The opts parameter is optional. If nil, uses default options.
+func NewReplica(db *redka.DB, addr string, opts *ReplicaOptions) *Replica {
+	if opts == nil {
+		opts = &ReplicaOptions{}
+	}
+	return &Replica{
+		db:      db,
+		addr:    addr,
+		opts:    *opts,
+		state:   StateConnecting,
+		skipped: map[string]bool{},
+	}
+}
+
+// Start starts replicating in the background.
+func (r *Replica) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go r.run(ctx)
+}
+
+// Stop stops replicating and waits
+// for the background work to finish.
+func (r *Replica) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+// Info returns the state of the replica.
+func (r *Replica) Info() ReplicaInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	info := ReplicaInfo{
+		Primary: r.addr,
+		State:   r.state,
+		ID:      r.id,
+		Offset:  r.offset.Load(),
+	}
+	if !r.lastIO.IsZero() {
+		info.LastIO = time.Since(r.lastIO)
+	}
+	return info
+}
+
+// ReplicaInfo describes the state of the replica.
+type ReplicaInfo struct {
+	Primary string        // primary address
+	State   string        // link state
+	ID      string        // primary replication ID
+	Offset  int64         // processed replication offset
+	LastIO  time.Duration // time since the last data from the primary
+}
+
+// run keeps the replica synced with the primary,
+// reconnecting as necessary.
+func (r *Replica) run(ctx context.Context) {
+	defer r.wg.Done()
+	delay := minRetryDelay
+	for {
+		start := time.Now()
+		err := r.sync(ctx)
+		r.setState(StateConnecting)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("replication link lost", "primary", r.addr, "error", err)
+
+		// Reset the delay if the link was up for a while.
+		if time.Since(start) > maxRetryDelay {
+			delay = minRetryDelay
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// sync connects to the primary, loads the snapshot
+// and applies the command stream until an error occurs.
+func (r *Replica) sync(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c := &primaryConn{
+		conn: conn,
+		rd:   bufio.NewReader(conn),
+		wr:   bufio.NewWriter(conn),
+	}
+
+	// Handshake.
+	if r.opts.Password != "" {
+		args := []string{"auth", r.opts.Password}
+		if r.opts.User != "" {
+			args = []string{"auth", r.opts.User, r.opts.Password}
+		}
+		if _, err := c.call(args...); err != nil {
+			return err
+		}
+	}
+	if _, err := c.call("ping"); err != nil {
+		return err
+	}
+	if _, err := c.call("replconf", "listening-port", strconv.Itoa(r.opts.Port)); err != nil {
+		return err
+	}
+	if _, err := c.call("replconf", "capa", "psync2"); err != nil {
+		return err
+	}
+	reply, err := c.call("psync", "?", "-1")
+	if err != nil {
+		return err
+	}
+	id, offset, err := parseFullResync(reply)
+	if err != nil {
+		return err
+	}
+
+	// Load the snapshot.
+	r.setState(StateSync)
+	if err := r.load(c); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.id = id
+	r.lastIO = time.Now()
+	r.mu.Unlock()
+	r.offset.Store(offset)
+	r.setState(StateConnected)
+	slog.Info("replication link up", "primary", r.addr, "id", id, "offset", offset)
+
+	// Acknowledge the processed offset periodically.
+	ackCtx, cancelAck := context.WithCancel(ctx)
+	defer cancelAck()
+	go func() {
+		ticker := time.NewTicker(ackInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ackCtx.Done():
+				return
+			case <-ticker.C:
+				_ = c.ack(r.offset.Load())
+			}
+		}
+	}()
+
+	// Apply the command stream.
+	app := aof.NewApplier(r.db)
+	db := 0
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(linkTimeout))
+		args, n, err := resp.ReadCommand(c.rd)
+		if err != nil {
+			return err
+		}
+		r.mu.Lock()
+		r.lastIO = time.Now()
+		r.mu.Unlock()
+
+		name := strings.ToLower(string(args[0]))
+		switch name {
+		case "ping":
+			// Keepalive, nothing to do.
+		case "select":
+			// The primary switches between databases.
+			if len(args) > 1 {
+				db, _ = strconv.Atoi(string(args[1]))
+			}
+		case "replconf":
+			// The offset is acknowledged before counting
+			// the GETACK command itself, as Redis does.
+			if len(args) > 1 && strings.EqualFold(string(args[1]), "getack") {
+				if err := c.ack(r.offset.Load()); err != nil {
+					return err
+				}
+			}
+		default:
+			if db != r.opts.DB {
+				break
+			}
+			// Skip the unsupported commands before applying,
+			// so they don't fail the whole transaction.
+			if name != "multi" && name != "exec" && !command.IsWrite(name) {
+				r.reportSkipped(name, command.ErrUnknownCmd)
+				break
+			}
+			if _, err := app.Apply(args); err != nil {
+				r.reportSkipped(name, err)
+			}
+		}
+		r.offset.Add(n)
+	}
+}
+
+// load replaces the database contents with the snapshot
+// sent by the primary.
+func (r *Replica) load(c *primaryConn) error {
+	var line string
+	var err error
+	// The primary may send empty lines as keepalives
+	// while preparing the snapshot.
+	for line == "" {
+		_ = c.conn.SetReadDeadline(time.Now().Add(linkTimeout))
+		line, err = resp.ReadLine(c.rd)
+		if err != nil {
+			return err
+		}
+	}
+	if line[0] != '$' {
+		return fmt.Errorf("%w: unexpected snapshot header %q", ErrProtocol, line)
+	}
+	size, err := strconv.ParseInt(line[1:], 10, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("%w: invalid snapshot size %q", ErrProtocol, line)
+	}
+
+	_ = c.conn.SetReadDeadline(time.Time{})
+	if err := r.db.Key().DeleteAll(); err != nil {
+		return err
+	}
+	src := io.LimitReader(c.rd, size)
+	stats, err := r.db.ImportRDB(src, &redka.ImportOptions{DB: r.opts.DB})
+	if err != nil {
+		return err
+	}
+	// Skip the rest of the snapshot (if any).
+	if _, err := io.Copy(io.Discard, src); err != nil {
+		return err
+	}
+	slog.Info("load snapshot", "primary", r.addr, "keys", stats.Keys,
+		"skipped", stats.Skipped, "size", size)
+	return nil
+}
+
+// reportSkipped logs a replicated command that failed to apply.
+// Typically these are commands not supported by Redka, so each
+// command name is only reported once to avoid flooding the log.
+func (r *Replica) reportSkipped(name string, err error) {
+	if r.skipped[name] {
+		return
+	}
+	r.skipped[name] = true
+	slog.Warn("skip replicated command", "name", name, "error", err)
+}
+
+// setState sets the link state.
+func (r *Replica) setState(state string) {
+	r.mu.Lock()
+	r.state = state
+	r.mu.Unlock()
+}
+
+// primaryConn is a connection to the primary.
+type primaryConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+	mu   sync.Mutex // protects wr
+	wr   *bufio.Writer
+}
+
+// call sends a command and returns a single line reply.
+func (c *primaryConn) call(args ...string) (string, error) {
+	if err := c.send(args...); err != nil {
+		return "", err
+	}
+	_ = c.conn.SetReadDeadline(time.Now().Add(linkTimeout))
+	var reply string
+	var err error
+	for reply == "" {
+		reply, err = resp.ReadLine(c.rd)
+		if err != nil {
+			return "", err
+		}
+	}
+	if reply[0] == '-' {
+		return "", fmt.Errorf("repl: %s: %s", args[0], reply[1:])
+	}
+	return reply, nil
+}
+
+// ack sends the processed offset to the primary.
+func (c *primaryConn) ack(offset int64) error {
+	return c.send("replconf", "ack", strconv.FormatInt(offset, 10))
+}
+
+// send sends a command to the primary.
+func (c *primaryConn) send(args ...string) error {
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, _ = c.wr.Write(resp.AppendCommand(nil, bargs...))
+	return c.wr.Flush()
+}
+
+// parseFullResync parses the "+FULLRESYNC <id> <offset>" reply.
+func parseFullResync(reply string) (string, int64, error) {
+	parts := strings.Fields(reply)
+	if len(parts) != 3 || parts[0] != "+FULLRESYNC" {
+		return "", 0, fmt.Errorf("%w: unexpected psync reply %q", ErrProtocol, reply)
+	}
+	offset, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: invalid offset %q", ErrProtocol, parts[2])
+	}
+	return parts[1], offset, nil
+}
@@ -0,0 +1,145 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rdb"
+	"github.com/nalgeon/redka/internal/resp"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+// TestReplicaOfRedis checks the replica against a fake
+// primary that behaves like a Redis 7.x server.
+func TestReplicaOfRedis(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testx.AssertNoErr(t, err)
+	defer ln.Close()
+
+	acks := make(chan string, 10)
+	go servePrimary(t, ln, acks)
+
+	db, err := redka.Open(":memory:", nil)
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+	_ = db.Str().Set("stale", "value")
+
+	replica := NewReplica(db, ln.Addr().String(), &ReplicaOptions{
+		Port: 6380, User: "repl", Password: "secret",
+	})
+	replica.Start()
+	defer replica.Stop()
+
+	// REPLCONF GETACK is the last command in the stream.
+	select {
+	case ack := <-acks:
+		testx.AssertEqual(t, strings.HasPrefix(ack, "replconf ack "), true)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for ack")
+	}
+
+	// snapshot
+	name, _ := db.Str().Get("name")
+	testx.AssertEqual(t, name.String(), "alice")
+	count, _ := db.Key().Count("stale", "list", "other")
+	testx.AssertEqual(t, count, 0)
+
+	// command stream
+	city, _ := db.Str().Get("city")
+	testx.AssertEqual(t, city.String(), "paris")
+	key, _ := db.Key().Get("city")
+	testx.AssertEqual(t, *key.ETime/1000, int64(4102444800))
+	age, _ := db.Hash().Get("person", "age")
+	testx.AssertEqual(t, age.String(), "25")
+	count, _ = db.Key().Count("tags", "ignored")
+	testx.AssertEqual(t, count, 0)
+
+	info := replica.Info()
+	testx.AssertEqual(t, info.State, StateConnected)
+	testx.AssertEqual(t, info.ID, strings.Repeat("a", 40))
+}
+
+// servePrimary accepts a single replica and sends it
+// the snapshot and the command stream.
+func servePrimary(t *testing.T, ln net.Listener, acks chan<- string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+
+	// Handshake.
+	want := []string{
+		"auth repl secret", "ping", "replconf listening-port 6380",
+		"replconf capa psync2", "psync ? -1",
+	}
+	replies := []string{"+OK", "+PONG", "+OK", "+OK", "+FULLRESYNC " + strings.Repeat("a", 40) + " 100"}
+	for i := range want {
+		args, _, err := resp.ReadCommand(rd)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if got := join(args); got != want[i] {
+			t.Errorf("want %q, got %q", want[i], got)
+			return
+		}
+		conn.Write([]byte(replies[i] + "\r\n"))
+	}
+
+	// Snapshot.
+	var snap bytes.Buffer
+	w := rdb.NewWriter(&snap)
+	_ = w.Write(rdb.Entry{Key: "name", Type: core.TypeString, Str: []byte("alice")})
+	_ = w.Write(rdb.Entry{Key: "list", Type: core.TypeList, List: [][]byte{[]byte("a")}})
+	_ = w.Write(rdb.Entry{DB: 1, Key: "other", Type: core.TypeString, Str: []byte("x")})
+	_ = w.Close()
+	conn.Write([]byte("\n\n$" + strconv.Itoa(snap.Len()) + "\r\n"))
+	conn.Write(snap.Bytes())
+
+	// Command stream.
+	var stream []byte
+	for _, cmd := range []string{
+		"SELECT 0",
+		"SET city paris PXAT 4102444800000",
+		"LPUSH list a",
+		"MULTI", "HSET person age 25", "SADD tags a", "EXEC",
+		"PING",
+		"SELECT 1",
+		"SET ignored x",
+		"REPLCONF GETACK *",
+	} {
+		stream = resp.AppendCommand(stream, split(cmd)...)
+	}
+	conn.Write(stream)
+
+	for {
+		args, _, err := resp.ReadCommand(rd)
+		if err != nil {
+			return
+		}
+		acks <- join(args)
+	}
+}
+
+func split(s string) [][]byte {
+	parts := strings.Split(s, " ")
+	args := make([][]byte, len(parts))
+	for i, part := range parts {
+		args[i] = []byte(part)
+	}
+	return args
+}
+
+func join(args [][]byte) string {
+	return strings.ToLower(string(bytes.Join(args, []byte(" "))))
+}
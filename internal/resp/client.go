@@ -0,0 +1,150 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Error is an error reply sent by the server.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Client is a minimal Redis client. Supports pipelining
+// with Send/Flush/Receive. Not safe for concurrent use.
+type Client struct {
+	conn net.Conn
+	rd   *bufio.Reader
+	wr   *bufio.Writer
+}
+
+// Dial connects to the Redis server at addr (host:port).
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient creates a client over an existing connection.
+func NewClient(conn net.Conn) *Client {
+	return &Client{
+		conn: conn,
+		rd:   bufio.NewReader(conn),
+		wr:   bufio.NewWriter(conn),
+	}
+}
+
+// Do sends the command and returns the reply.
+// Returns an Error if the server replies with an error.
+func (c *Client) Do(args ...string) (any, error) {
+	if err := c.Send(args...); err != nil {
+		return nil, err
+	}
+	if err := c.Flush(); err != nil {
+		return nil, err
+	}
+	return c.Receive()
+}
+
+// Send buffers the command without waiting for the reply.
+func (c *Client) Send(args ...string) error {
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+	_, err := c.wr.Write(AppendCommand(nil, bargs...))
+	return err
+}
+
+// Flush sends the buffered commands to the server.
+func (c *Client) Flush() error {
+	return c.wr.Flush()
+}
+
+// Receive reads a single reply from the server.
+// See ReadReply for the reply types.
+func (c *Client) Receive() (any, error) {
+	reply, err := ReadReply(c.rd)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// SetDeadline sets the read and write deadline of the connection.
+func (c *Client) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// Close closes the connection.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// ReadReply reads a RESP2 reply. Returns a string for simple strings,
+// Error for errors, int64 for integers, []byte for bulk strings,
+// []any for arrays and nil for null bulk strings or arrays.
+func ReadReply(rd *bufio.Reader) (any, error) {
+	line, err := ReadLine(rd)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, ErrFormat
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		n, err := strconv.ParseInt(line[1:], 10, 64)
+		if err != nil {
+			return nil, ErrFormat
+		}
+		return n, nil
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size > maxBulkLen {
+			return nil, ErrFormat
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, truncated(err)
+		}
+		return buf[:size], nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, ErrFormat
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			items[i], err = ReadReply(rd)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("%w: unexpected reply %q", ErrFormat, line)
+}
@@ -0,0 +1,113 @@
+// Package resp reads and writes commands and replies
+// using the Redis serialization protocol (RESP).
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxBulkLen is the maximum length of a single bulk string.
+const maxBulkLen = 512 * 1024 * 1024
+
+// Errors returned by the reader.
+var (
+	ErrFormat    = errors.New("resp: invalid format")
+	ErrTruncated = errors.New("resp: truncated data")
+)
+
+// ReadCommand reads a command encoded as an array of bulk strings.
+// Returns the command arguments and the number of bytes read.
+// Returns io.EOF if there is no more data, or ErrTruncated
+// if the command is incomplete.
+func ReadCommand(rd *bufio.Reader) ([][]byte, int64, error) {
+	n, read, err := readHeader(rd, '*')
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if n <= 0 {
+		return nil, 0, ErrFormat
+	}
+
+	args := make([][]byte, n)
+	for i := range args {
+		size, nh, err := readHeader(rd, '$')
+		if err != nil {
+			return nil, 0, truncated(err)
+		}
+		if size < 0 || size > maxBulkLen {
+			return nil, 0, ErrFormat
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, buf); err != nil {
+			return nil, 0, truncated(err)
+		}
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, 0, ErrFormat
+		}
+		args[i] = buf[:size]
+		read += nh + int64(len(buf))
+	}
+	return args, read, nil
+}
+
+// AppendCommand appends the command encoded as an array
+// of bulk strings to b and returns the extended buffer.
+func AppendCommand(b []byte, args ...[]byte) []byte {
+	b = append(b, '*')
+	b = strconv.AppendInt(b, int64(len(args)), 10)
+	b = append(b, '\r', '\n')
+	for _, arg := range args {
+		b = append(b, '$')
+		b = strconv.AppendInt(b, int64(len(arg)), 10)
+		b = append(b, '\r', '\n')
+		b = append(b, arg...)
+		b = append(b, '\r', '\n')
+	}
+	return b
+}
+
+// ReadLine reads a single line (such as a simple string
+// or an error reply) without the trailing CRLF.
+func ReadLine(rd *bufio.Reader) (string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return "", truncated(err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// readHeader reads a line like "*3\r\n" and returns the number
+// along with the length of the line. Returns io.EOF if there
+// is no data, or ErrTruncated if the line is incomplete.
+func readHeader(rd *bufio.Reader, prefix byte) (int, int64, error) {
+	line, err := rd.ReadString('\n')
+	if err == io.EOF && len(line) == 0 {
+		return 0, 0, io.EOF
+	}
+	if err != nil {
+		return 0, 0, truncated(err)
+	}
+	if len(line) < 4 || line[0] != prefix || line[len(line)-2] != '\r' {
+		return 0, 0, ErrFormat
+	}
+	n, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil {
+		return 0, 0, ErrFormat
+	}
+	return n, int64(len(line)), nil
+}
+
+// truncated converts an EOF error to ErrTruncated.
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncated
+	}
+	return err
+}
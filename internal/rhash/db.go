@@ -3,7 +3,11 @@
 package rhash
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"iter"
+	"time"
 
 	"github.com/nalgeon/redka/internal/core"
 	"github.com/nalgeon/redka/internal/sqlx"
@@ -24,49 +28,139 @@ func New(db *sql.DB) *DB {
 	return &DB{d}
 }
 
+// WithContext returns a shallow copy of the repository
+// that executes the queries with ctx. Use it to enforce
+// timeouts and cancellation on slow queries.
+func (d *DB) WithContext(ctx context.Context) *DB {
+	return &DB{d.DB.WithContext(ctx)}
+}
+
 // Delete deletes one or more items from a hash.
 // Returns the number of fields deleted.
 // Ignores non-existing fields.
 // Does nothing if the key does not exist or is not a hash.
 // Does not delete the key if the hash becomes empty.
 func (d *DB) Delete(key string, fields ...string) (int, error) {
+	op := d.Observe("Hash.Delete", key)
 	var count int
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		count, err = tx.Delete(key, fields...)
 		return err
 	})
-	return count, err
+	return count, op.Done(err)
+}
+
+// DeleteExpired deletes the hash fields with expired TTL
+// (see [Tx.FieldExpire]). Returns the number of deleted fields.
+// Does not delete the keys if the hashes become empty.
+func (d *DB) DeleteExpired() (int, error) {
+	op := d.Observe("Hash.DeleteExpired")
+	var count int
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		count, err = tx.deleteExpired()
+		return err
+	})
+	return count, op.Done(err)
 }
 
 // Exists checks if a field exists in a hash.
 // If the key does not exist or is not a hash, returns false.
 func (d *DB) Exists(key, field string) (bool, error) {
-	tx := NewTx(d.SQL)
-	return tx.Exists(key, field)
+	op := d.Observe("Hash.Exists", key)
+	tx := NewTx(d.In(op).ReadConn())
+	ok, err := tx.Exists(key, field)
+	return ok, op.Done(err)
 }
 
 // Fields returns all fields in a hash.
 // If the key does not exist or is not a hash, returns an empty slice.
 func (d *DB) Fields(key string) ([]string, error) {
-	tx := NewTx(d.SQL)
-	return tx.Fields(key)
+	op := d.Observe("Hash.Fields", key)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.Fields(key)
+	return items, op.Done(err)
+}
+
+// FieldExpire sets the time-to-live of the fields in a hash.
+// See [Tx.FieldExpire] for details.
+func (d *DB) FieldExpire(key string, ttl time.Duration, fields ...string) (map[string]bool, error) {
+	op := d.Observe("Hash.FieldExpire", key)
+	var res map[string]bool
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		res, err = tx.FieldExpire(key, ttl, fields...)
+		return err
+	})
+	return res, op.Done(err)
+}
+
+// FieldExpireAt sets the expiration time of the fields in a hash.
+// See [Tx.FieldExpireAt] for details.
+func (d *DB) FieldExpireAt(key string, at time.Time, fields ...string) (map[string]bool, error) {
+	op := d.Observe("Hash.FieldExpireAt", key)
+	var res map[string]bool
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		res, err = tx.FieldExpireAt(key, at, fields...)
+		return err
+	})
+	return res, op.Done(err)
+}
+
+// FieldExpireTimes returns the expiration times of the fields in a hash.
+// See [Tx.FieldExpireTimes] for details.
+func (d *DB) FieldExpireTimes(key string) (map[string]time.Time, error) {
+	op := d.Observe("Hash.FieldExpireTimes", key)
+	tx := NewTx(d.In(op).ReadConn())
+	res, err := tx.FieldExpireTimes(key)
+	return res, op.Done(err)
+}
+
+// FieldPersist removes the expiration time of the fields in a hash.
+// See [Tx.FieldPersist] for details.
+func (d *DB) FieldPersist(key string, fields ...string) (map[string]bool, error) {
+	op := d.Observe("Hash.FieldPersist", key)
+	var res map[string]bool
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		res, err = tx.FieldPersist(key, fields...)
+		return err
+	})
+	return res, op.Done(err)
+}
+
+// FieldTTL returns the time-to-live of the fields in a hash.
+// See [Tx.FieldTTL] for details.
+func (d *DB) FieldTTL(key string, fields ...string) (map[string]time.Duration, error) {
+	op := d.Observe("Hash.FieldTTL", key)
+	tx := NewTx(d.In(op).ReadConn())
+	res, err := tx.FieldTTL(key, fields...)
+	return res, op.Done(err)
 }
 
 // Get returns the value of a field in a hash.
 // If the element does not exist, returns ErrNotFound.
 // If the key does not exist or is not a hash, returns ErrNotFound.
 func (d *DB) Get(key, field string) (core.Value, error) {
-	tx := NewTx(d.SQL)
-	return tx.Get(key, field)
+	op := d.Observe("Hash.Get", key)
+	tx := NewTx(d.In(op).ReadConn())
+	val, err := tx.Get(key, field)
+	if err == nil || errors.Is(err, core.ErrNotFound) {
+		d.Lookup(err == nil)
+	}
+	return val, op.Done(err)
 }
 
 // GetMany returns a map of values for given fields.
 // Ignores fields that do not exist and do not return them in the map.
 // If the key does not exist or is not a hash, returns an empty map.
 func (d *DB) GetMany(key string, fields ...string) (map[string]core.Value, error) {
-	tx := NewTx(d.SQL)
-	return tx.GetMany(key, fields...)
+	op := d.Observe("Hash.GetMany", key)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.GetMany(key, fields...)
+	return items, op.Done(err)
 }
 
 // Incr increments the integer value of a field in a hash.
@@ -76,13 +170,14 @@ func (d *DB) GetMany(key string, fields ...string) (map[string]core.Value, error
 // If the key does not exist, creates it.
 // If the key exists but is not a hash, returns ErrKeyType.
 func (d *DB) Incr(key, field string, delta int) (int, error) {
+	op := d.Observe("Hash.Incr", key)
 	var val int
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		val, err = tx.Incr(key, field, delta)
 		return err
 	})
-	return val, err
+	return val, op.Done(err)
 }
 
 // IncrFloat increments the float value of a field in a hash.
@@ -92,27 +187,41 @@ func (d *DB) Incr(key, field string, delta int) (int, error) {
 // If the key does not exist, creates it.
 // If the key exists but is not a hash, returns ErrKeyType.
 func (d *DB) IncrFloat(key, field string, delta float64) (float64, error) {
+	op := d.Observe("Hash.IncrFloat", key)
 	var val float64
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		val, err = tx.IncrFloat(key, field, delta)
 		return err
 	})
-	return val, err
+	return val, op.Done(err)
 }
 
 // Items returns a map of all fields and values in a hash.
 // If the key does not exist or is not a hash, returns an empty map.
 func (d *DB) Items(key string) (map[string]core.Value, error) {
-	tx := NewTx(d.SQL)
-	return tx.Items(key)
+	op := d.Observe("Hash.Items", key)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.Items(key)
+	return items, op.Done(err)
 }
 
 // Len returns the number of fields in a hash.
 // If the key does not exist or is not a hash, returns 0.
 func (d *DB) Len(key string) (int, error) {
-	tx := NewTx(d.SQL)
-	return tx.Len(key)
+	op := d.Observe("Hash.Len", key)
+	tx := NewTx(d.In(op).ReadConn())
+	count, err := tx.Len(key)
+	return count, op.Done(err)
+}
+
+// RandField returns random fields from a hash.
+// See [Tx.RandField] for details.
+func (d *DB) RandField(key string, count int, withValues bool) ([]HashItem, error) {
+	op := d.Observe("Hash.RandField", key)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.RandField(key, count, withValues)
+	return items, op.Done(err)
 }
 
 // Scan iterates over hash items with fields matching pattern.
@@ -122,8 +231,10 @@ func (d *DB) Len(key string) (int, error) {
 // If the key does not exist or is not a hash, returns a nil slice.
 // Supports glob-style patterns. Set count = 0 for default page size.
 func (d *DB) Scan(key string, cursor int, pattern string, count int) (ScanResult, error) {
-	tx := NewTx(d.SQL)
-	return tx.Scan(key, cursor, pattern, count)
+	op := d.Observe("Hash.Scan", key)
+	tx := NewTx(d.In(op).ReadConn())
+	res, err := tx.Scan(key, cursor, pattern, count)
+	return res, op.Done(err)
 }
 
 // Scanner returns an iterator for hash items with fields matching pattern.
@@ -132,8 +243,19 @@ func (d *DB) Scan(key string, cursor int, pattern string, count int) (ScanResult
 // or an error occurs. If the key does not exist or is not a hash, stops immediately.
 // Supports glob-style patterns. Set pageSize = 0 for default page size.
 func (d *DB) Scanner(key, pattern string, pageSize int) *Scanner {
-	tx := NewTx(d.SQL)
-	return tx.Scanner(key, pattern, pageSize)
+	tx := NewTx(d.ReadConn())
+	sc := tx.Scanner(key, pattern, pageSize)
+	sc.close = d.OpenScanner()
+	return sc
+}
+
+// Iter returns an iterator over the hash items with fields
+// matching pattern. Fetches the items from the database in batches
+// as necessary. Stops after yielding the first error, if any.
+// If the key does not exist or is not a hash, stops immediately.
+// Supports glob-style patterns.
+func (d *DB) Iter(key, pattern string) iter.Seq2[HashItem, error] {
+	return sqlx.Iter(d.Scanner(key, pattern, 0))
 }
 
 // Set creates or updates the value of a field in a hash.
@@ -141,13 +263,14 @@ func (d *DB) Scanner(key, pattern string, pageSize int) *Scanner {
 // If the key does not exist, creates it.
 // If the key exists but is not a hash, returns ErrKeyType.
 func (d *DB) Set(key, field string, value any) (bool, error) {
+	op := d.Observe("Hash.Set", key)
 	var created bool
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		created, err = tx.Set(key, field, value)
 		return err
 	})
-	return created, err
+	return created, op.Done(err)
 }
 
 // SetMany creates or updates the values of multiple fields in a hash.
@@ -155,13 +278,14 @@ func (d *DB) Set(key, field string, value any) (bool, error) {
 // If the key does not exist, creates it.
 // If the key exists but is not a hash, returns ErrKeyType.
 func (d *DB) SetMany(key string, items map[string]any) (int, error) {
+	op := d.Observe("Hash.SetMany", key)
 	var count int
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		count, err = tx.SetMany(key, items)
 		return err
 	})
-	return count, err
+	return count, op.Done(err)
 }
 
 // SetNotExists creates the value of a field in a hash if it does not exist.
@@ -169,18 +293,21 @@ func (d *DB) SetMany(key string, items map[string]any) (int, error) {
 // If the key does not exist, creates it.
 // If the key exists but is not a hash, returns ErrKeyType.
 func (d *DB) SetNotExists(key, field string, value any) (bool, error) {
+	op := d.Observe("Hash.SetNotExists", key)
 	var created bool
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		created, err = tx.SetNotExists(key, field, value)
 		return err
 	})
-	return created, err
+	return created, op.Done(err)
 }
 
 // Values returns all values in a hash.
 // If the key does not exist or is not a hash, returns an empty slice.
 func (d *DB) Values(key string) ([]core.Value, error) {
-	tx := NewTx(d.SQL)
-	return tx.Values(key)
+	op := d.Observe("Hash.Values", key)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.Values(key)
+	return items, op.Done(err)
 }
@@ -1,9 +1,11 @@
 package rhash_test
 
 import (
+	"errors"
 	"slices"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/nalgeon/redka"
 	"github.com/nalgeon/redka/internal/core"
@@ -90,6 +92,179 @@ func TestDelete(t *testing.T) {
 	})
 }
 
+func TestFieldExpire(t *testing.T) {
+	t.Run("expire", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Set("person", "name", "alice")
+		_, _ = db.Set("person", "age", 25)
+
+		res, err := db.FieldExpire("person", time.Millisecond, "name", "city")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, map[string]bool{"name": true})
+
+		time.Sleep(5 * time.Millisecond)
+		_, err = db.Get("person", "name")
+		testx.AssertErr(t, err, core.ErrNotFound)
+		items, _ := db.Items("person")
+		testx.AssertEqual(t, items, map[string]core.Value{"age": core.Value("25")})
+		count, _ := db.Len("person")
+		testx.AssertEqual(t, count, 1)
+	})
+	t.Run("delete", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Set("person", "name", "alice")
+
+		res, err := db.FieldExpire("person", 0, "name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, map[string]bool{"name": false})
+
+		exists, _ := db.Exists("person", "name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("expire at", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Set("person", "name", "alice")
+
+		res, err := db.FieldExpireAt("person", time.Now().Add(time.Minute), "name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, map[string]bool{"name": true})
+		ttls, _ := db.FieldTTL("person", "name")
+		testx.AssertEqual(t, ttls["name"] > 59*time.Second, true)
+
+		res, err = db.FieldExpireAt("person", time.Now().Add(-time.Minute), "name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, map[string]bool{"name": false})
+		exists, _ := db.Exists("person", "name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("set clears ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Set("person", "name", "alice")
+		_, _ = db.FieldExpire("person", time.Minute, "name")
+
+		created, err := db.Set("person", "name", "bob")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, created, false)
+		ttls, _ := db.FieldTTL("person", "name")
+		testx.AssertEqual(t, ttls, map[string]time.Duration{"name": 0})
+	})
+	t.Run("incr keeps ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Set("person", "age", 25)
+		_, _ = db.FieldExpire("person", time.Minute, "age")
+
+		_, err := db.Incr("person", "age", 1)
+		testx.AssertNoErr(t, err)
+		ttls, _ := db.FieldTTL("person", "age")
+		testx.AssertEqual(t, ttls["age"] > 0, true)
+	})
+	t.Run("recreate expired", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Set("person", "age", 25)
+		_, _ = db.FieldExpire("person", time.Millisecond, "age")
+		time.Sleep(5 * time.Millisecond)
+
+		val, err := db.Incr("person", "age", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val, 1)
+		ttls, _ := db.FieldTTL("person", "age")
+		testx.AssertEqual(t, ttls, map[string]time.Duration{"age": 0})
+
+		n, err := db.Delete("person", "age")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 1)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		res, err := db.FieldExpire("person", time.Minute, "name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, map[string]bool{})
+	})
+}
+
+func TestFieldExpireTimes(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+	_, _ = db.Set("person", "name", "alice")
+	_, _ = db.Set("person", "age", 25)
+	_, _ = db.Set("person", "city", "paris")
+	at := time.UnixMilli(time.Now().Add(time.Minute).UnixMilli())
+	_, _ = db.FieldExpireAt("person", at, "name")
+	_, _ = db.FieldExpire("person", time.Millisecond, "city")
+	time.Sleep(5 * time.Millisecond)
+
+	etimes, err := db.FieldExpireTimes("person")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(etimes), 1)
+	testx.AssertEqual(t, etimes["name"].Equal(at), true)
+
+	etimes, err = db.FieldExpireTimes("other")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(etimes), 0)
+}
+
+func TestFieldPersist(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+	_, _ = db.Set("person", "name", "alice")
+	_, _ = db.Set("person", "age", 25)
+	_, _ = db.FieldExpire("person", time.Minute, "name")
+
+	res, err := db.FieldPersist("person", "name", "age", "city")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, res, map[string]bool{"name": true, "age": false})
+
+	ttls, _ := db.FieldTTL("person", "name", "age")
+	testx.AssertEqual(t, ttls, map[string]time.Duration{"name": 0, "age": 0})
+}
+
+func TestFieldTTL(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+	_, _ = db.Set("person", "name", "alice")
+	_, _ = db.Set("person", "age", 25)
+	_, _ = db.FieldExpire("person", time.Minute, "name")
+
+	ttls, err := db.FieldTTL("person", "name", "age", "city")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(ttls), 2)
+	testx.AssertEqual(t, ttls["name"] > 59*time.Second, true)
+	testx.AssertEqual(t, ttls["name"] <= time.Minute, true)
+	testx.AssertEqual(t, ttls["age"], time.Duration(0))
+
+	ttls, err = db.FieldTTL("other", "name")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(ttls), 0)
+}
+
+func TestDeleteExpired(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+	_, _ = db.Set("person", "name", "alice")
+	_, _ = db.Set("person", "age", 25)
+	_, _ = db.FieldExpire("person", time.Millisecond, "name")
+	_, _ = db.FieldExpire("person", time.Minute, "age")
+	time.Sleep(5 * time.Millisecond)
+
+	count, err := db.DeleteExpired()
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, count, 1)
+
+	var rows int
+	_ = red.SQL.QueryRow("select count(*) from rhash").Scan(&rows)
+	testx.AssertEqual(t, rows, 1)
+	_ = red.SQL.QueryRow("select count(*) from rhash_ttl").Scan(&rows)
+	testx.AssertEqual(t, rows, 1)
+}
+
 func TestExists(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -388,6 +563,48 @@ func TestLen(t *testing.T) {
 	}
 }
 
+func TestRandField(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+	_, _ = db.Set("person", "name", "alice")
+	_, _ = db.Set("person", "age", 25)
+	_, _ = db.Set("person", "city", "paris")
+
+	t.Run("distinct", func(t *testing.T) {
+		items, err := db.RandField("person", 2, true)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 2)
+		testx.AssertEqual(t, items[0].Field != items[1].Field, true)
+		for _, it := range items {
+			val, _ := db.Get("person", it.Field)
+			testx.AssertEqual(t, it.Value, val)
+		}
+	})
+	t.Run("all", func(t *testing.T) {
+		items, err := db.RandField("person", 10, false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 3)
+		for _, it := range items {
+			testx.AssertEqual(t, it.Value.Exists(), false)
+		}
+	})
+	t.Run("repeat", func(t *testing.T) {
+		items, err := db.RandField("person", -5, false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 5)
+	})
+	t.Run("zero", func(t *testing.T) {
+		items, err := db.RandField("person", 0, false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 0)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		items, err := db.RandField("other", -5, false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rhash.HashItem(nil))
+	})
+}
+
 func TestScan(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -478,6 +695,56 @@ func TestScan(t *testing.T) {
 	})
 }
 
+func TestIter(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_, _ = db.Set("key", "f11", "11")
+	_, _ = db.Set("key", "f12", "12")
+	_, _ = db.Set("key", "f21", "21")
+
+	var fields []string
+	for item, err := range db.Iter("key", "f1*") {
+		testx.AssertNoErr(t, err)
+		fields = append(fields, item.Field)
+	}
+	testx.AssertEqual(t, fields, []string{"f11", "f12"})
+
+	// Stop early.
+	var count int
+	for range db.Iter("key", "*") {
+		count++
+		break
+	}
+	testx.AssertEqual(t, count, 1)
+}
+
+func TestScanBinary(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_, _ = db.Set("key", "f\x001", "1")
+	_, _ = db.Set("key", "f\x002", "2")
+	_, _ = db.Set("key", "f3", "3")
+
+	val, _ := db.Get("key", "f\x002")
+	testx.AssertEqual(t, val.String(), "2")
+
+	// The first page has a field with a NUL byte
+	// that does not match, but the scan goes on.
+	var fields []string
+	sc := db.Scanner("key", "f?2", 1)
+	for sc.Scan() {
+		fields = append(fields, sc.Item().Field)
+	}
+	testx.AssertNoErr(t, sc.Err())
+	testx.AssertEqual(t, fields, []string{"f\x002"})
+
+	out, err := db.Scan("key", 0, "f\x00*", 0)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(out.Items), 2)
+}
+
 func TestScanner(t *testing.T) {
 	t.Run("scan", func(t *testing.T) {
 		red, db := getDB(t)
@@ -578,6 +845,12 @@ func TestSet(t *testing.T) {
 		ok, err := db.Set("person", "name", "alice")
 		testx.AssertErr(t, err, core.ErrKeyType)
 		testx.AssertEqual(t, ok, false)
+
+		var kerr *core.KeyTypeError
+		testx.AssertEqual(t, errors.As(err, &kerr), true)
+		testx.AssertEqual(t, kerr.Key, "person")
+		testx.AssertEqual(t, kerr.Expected, core.TypeHash)
+		testx.AssertEqual(t, kerr.Actual, core.TypeString)
 	})
 }
 
@@ -1,5 +1,7 @@
 package rhash
 
+import "iter"
+
 // Scanner is the iterator for hash items.
 // Stops when there are no more items or an error occurs.
 type Scanner struct {
@@ -12,6 +14,7 @@ type Scanner struct {
 	cur      HashItem
 	items    []HashItem
 	err      error
+	close    func() // called when the scanner is exhausted
 }
 
 func newScanner(db *Tx, key string, pattern string, pageSize int) *Scanner {
@@ -38,12 +41,14 @@ func (sc *Scanner) Scan() bool {
 		out, err := sc.db.Scan(sc.key, sc.cursor, sc.pattern, sc.pageSize)
 		if err != nil {
 			sc.err = err
+			sc.done()
 			return false
 		}
 		sc.cursor = out.Cursor
 		sc.items = out.Items
 		sc.index = 0
 		if len(sc.items) == 0 {
+			sc.done()
 			return false
 		}
 	}
@@ -53,6 +58,13 @@ func (sc *Scanner) Scan() bool {
 	return true
 }
 
+// done marks the scanner as exhausted.
+func (sc *Scanner) done() {
+	if sc.close != nil {
+		sc.close()
+	}
+}
+
 // Item returns the current hash item.
 func (sc *Scanner) Item() HashItem {
 	return sc.cur
@@ -62,3 +74,21 @@ func (sc *Scanner) Item() HashItem {
 func (sc *Scanner) Err() error {
 	return sc.err
 }
+
+// All returns an iterator over the remaining items:
+//
+//	for item := range sc.All() {
+//	    // ...
+//	}
+//	if err := sc.Err(); err != nil {
+//	    // ...
+//	}
+func (sc *Scanner) All() iter.Seq[HashItem] {
+	return func(yield func(HashItem) bool) {
+		for sc.Scan() {
+			if !yield(sc.Item()) {
+				return
+			}
+		}
+	}
+}
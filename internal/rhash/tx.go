@@ -2,6 +2,7 @@ package rhash
 
 import (
 	"database/sql"
+	"math/rand/v2"
 	"slices"
 	"time"
 
@@ -9,60 +10,124 @@ import (
 	"github.com/nalgeon/redka/internal/sqlx"
 )
 
+// sqlAlive filters out the expired fields (see Tx.FieldExpire).
+// The expired fields stay in the table until they are deleted
+// in the background (see DB.DeleteExpired) or overwritten.
+const sqlAlive = `
+	and not exists (
+	    select 1 from rhash_ttl
+	    where rhash_ttl.key_id = rhash.key_id and rhash_ttl.field = rhash.field
+	      and rhash_ttl.etime <= :now
+	  )`
+
 const (
 	sqlCount = `
 	select count(field)
 	from rhash
 	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key and field in (:fields)`
+	where key = :key and field in (:fields)` + sqlAlive
 
-	sqlDelete = `
+	sqlDelete1 = `
 	delete from rhash
 	where key_id = (
 	    select id from rkey where key = :key
 	    and (etime is null or etime > :now)
 	  ) and field in (:fields)`
 
+	sqlDelete2 = `
+	delete from rhash_ttl
+	where key_id = (select id from rkey where key = :key)
+	  and field in (:fields)`
+
+	sqlDeleteExpired1 = `
+	delete from rhash
+	where rowid in (
+	    select rhash.rowid from rhash
+	      join rhash_ttl on rhash_ttl.key_id = rhash.key_id
+	        and rhash_ttl.field = rhash.field
+	    where rhash_ttl.etime <= :now
+	  )`
+
+	sqlDeleteExpired2 = `
+	delete from rhash_ttl where etime <= :now`
+
 	sqlFields = `
 	select field
 	from rhash
 	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key`
+	where key = :key` + sqlAlive
+
+	sqlFieldTTL = `
+	select rhash.field, rhash_ttl.etime
+	from rhash
+	  join rkey on rhash.key_id = rkey.id
+	    and (rkey.etime is null or rkey.etime > :now)
+	  left join rhash_ttl on rhash_ttl.key_id = rhash.key_id
+	    and rhash_ttl.field = rhash.field
+	where key = :key and rhash.field in (:fields)
+	  and (rhash_ttl.etime is null or rhash_ttl.etime > :now)`
+
+	sqlFieldExpireTimes = `
+	select rhash.field, rhash_ttl.etime
+	from rhash
+	  join rkey on rhash.key_id = rkey.id
+	    and (rkey.etime is null or rkey.etime > :now)
+	  join rhash_ttl on rhash_ttl.key_id = rhash.key_id
+	    and rhash_ttl.field = rhash.field
+	where key = :key and rhash_ttl.etime > :now`
+
+	sqlFieldExpire = `
+	insert into rhash_ttl (key_id, field, etime)
+	select key_id, field, :etime
+	from rhash
+	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key and field in (:fields)` + sqlAlive + `
+	on conflict (key_id, field) do update
+	set etime = excluded.etime`
 
 	sqlGet = `
 	select value
 	from rhash
 	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key and field = :field`
+	where key = :key and field = :field` + sqlAlive
 
 	sqlGetMany = `
 	select field, value
 	from rhash
 	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key and field in (:fields)`
+	where key = :key and field in (:fields)` + sqlAlive
 
 	sqlItems = `
 	select field, value
 	from rhash
 	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key`
+	where key = :key` + sqlAlive
 
 	sqlLen = `
 	select count(field)
 	from rhash
 	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key`
+	where key = :key` + sqlAlive
+
+	sqlRandField = `
+	select field, value
+	from rhash
+	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key` + sqlAlive + `
+	order by random()
+	limit :count`
 
 	sqlScan = `
 	select rhash.rowid, field, value
 	from rhash
 	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key and rhash.rowid > :cursor and field glob :pattern
+	where key = :key and rhash.rowid > :cursor
+	  and (field glob :pattern or instr(cast(field as blob), x'00') > 0)` + sqlAlive + `
 	limit :count`
 
 	sqlSet1 = `
-	insert into rkey (key, type, version, mtime)
-	values (:key, :type, :version, :mtime)
+	insert into rkey (id, key, type, version, mtime)
+	values (` + sqlx.NextKeyID + `, :key, :type, :version, :mtime)
 	on conflict (key) do update set
 	  version = version+1,
 	  type = excluded.type,
@@ -74,11 +139,16 @@ const (
 	on conflict (key_id, field) do update
 	set value = excluded.value`
 
+	sqlSet3 = `
+	delete from rhash_ttl
+	where key_id = (select id from rkey where key = :key)
+	  and field = :field`
+
 	sqlValues = `
 	select value
 	from rhash
 	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key`
+	where key = :key` + sqlAlive
 )
 
 const scanPageSize = 10
@@ -100,15 +170,16 @@ func NewTx(tx sqlx.Tx) *Tx {
 // Does nothing if the key does not exist or is not a hash.
 // Does not delete the key if the hash becomes empty.
 func (tx *Tx) Delete(key string, fields ...string) (int, error) {
-	now := time.Now().UnixMilli()
-	query, fieldArgs := sqlx.ExpandIn(sqlDelete, ":fields", fields)
-	args := slices.Concat([]any{sql.Named("key", key), sql.Named("now", now)}, fieldArgs)
-	res, err := tx.tx.Exec(query, args...)
+	// Count the fields that have not expired yet,
+	// then delete them along with the expired ones.
+	count, err := tx.count(key, fields...)
 	if err != nil {
 		return 0, err
 	}
-	count, _ := res.RowsAffected()
-	return int(count), nil
+	if err := tx.delete(key, fields...); err != nil {
+		return 0, err
+	}
+	return count, nil
 }
 
 // Exists checks if a field exists in a hash.
@@ -121,7 +192,7 @@ func (tx *Tx) Exists(key, field string) (bool, error) {
 // Fields returns all fields in a hash.
 // If the key does not exist or is not a hash, returns an empty slice.
 func (tx *Tx) Fields(key string) ([]string, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{sql.Named("key", key), sql.Named("now", now)}
 
 	// Select hash fields.
@@ -149,13 +220,146 @@ func (tx *Tx) Fields(key string) ([]string, error) {
 	return fields, nil
 }
 
+// FieldExpire sets the time-to-live of the fields in a hash,
+// like HEXPIRE in Redis. The fields are deleted after the ttl.
+// If ttl <= 0, deletes the fields right away.
+// See [Tx.FieldExpireAt] for the return value.
+func (tx *Tx) FieldExpire(key string, ttl time.Duration, fields ...string) (map[string]bool, error) {
+	now := sqlx.Now(tx.tx)
+	return tx.fieldExpireAt(key, now.UnixMilli(), now.Add(ttl).UnixMilli(), fields...)
+}
+
+// FieldExpireAt sets the expiration time of the fields in a hash,
+// like HEXPIREAT in Redis. The fields are deleted after the time.
+// If the time is in the past, deletes the fields right away.
+// Returns a map of the fields that exist, with true if the
+// expiration time was set, or false if the field was deleted.
+// Ignores the fields that do not exist.
+// If the key does not exist or is not a hash, returns an empty map.
+func (tx *Tx) FieldExpireAt(key string, at time.Time, fields ...string) (map[string]bool, error) {
+	return tx.fieldExpireAt(key, sqlx.Now(tx.tx).UnixMilli(), at.UnixMilli(), fields...)
+}
+
+// FieldExpireTimes returns the expiration times of the fields
+// in a hash, only for the fields that have one.
+// If the key does not exist or is not a hash, returns an empty map.
+func (tx *Tx) FieldExpireTimes(key string) (map[string]time.Time, error) {
+	now := sqlx.Now(tx.tx).UnixMilli()
+	args := []any{sql.Named("key", key), sql.Named("now", now)}
+	rows, err := tx.tx.Query(sqlFieldExpireTimes, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	res := map[string]time.Time{}
+	for rows.Next() {
+		var field string
+		var etime int64
+		if err := rows.Scan(&field, &etime); err != nil {
+			return nil, err
+		}
+		res[field] = time.UnixMilli(etime)
+	}
+	return res, rows.Err()
+}
+
+// fieldExpireAt sets the expiration time of the fields in a hash
+// (both times are in Unix milliseconds).
+func (tx *Tx) fieldExpireAt(key string, now, etime int64, fields ...string) (map[string]bool, error) {
+	etimes, err := tx.fieldETimes(key, fields...)
+	if err != nil {
+		return nil, err
+	}
+	if len(etimes) == 0 {
+		return map[string]bool{}, nil
+	}
+
+	existing := make([]string, 0, len(etimes))
+	for field := range etimes {
+		existing = append(existing, field)
+	}
+	if etime <= now {
+		if err := tx.delete(key, existing...); err != nil {
+			return nil, err
+		}
+	} else {
+		query, fieldArgs := sqlx.ExpandIn(sqlFieldExpire, ":fields", existing)
+		args := slices.Concat([]any{
+			sql.Named("key", key),
+			sql.Named("now", now),
+			sql.Named("etime", etime),
+		}, fieldArgs)
+		if _, err := tx.tx.Exec(query, args...); err != nil {
+			return nil, err
+		}
+	}
+
+	res := make(map[string]bool, len(existing))
+	for _, field := range existing {
+		res[field] = etime > now
+	}
+	return res, nil
+}
+
+// FieldPersist removes the expiration time of the fields in a hash,
+// like HPERSIST in Redis. Returns a map of the fields that exist,
+// with true if the expiration time was removed, or false if the
+// field had none. Ignores the fields that do not exist.
+// If the key does not exist or is not a hash, returns an empty map.
+func (tx *Tx) FieldPersist(key string, fields ...string) (map[string]bool, error) {
+	etimes, err := tx.fieldETimes(key, fields...)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]bool, len(etimes))
+	var expiring []string
+	for field, etime := range etimes {
+		res[field] = etime != nil
+		if etime != nil {
+			expiring = append(expiring, field)
+		}
+	}
+	if len(expiring) == 0 {
+		return res, nil
+	}
+	query, fieldArgs := sqlx.ExpandIn(sqlDelete2, ":fields", expiring)
+	args := slices.Concat([]any{sql.Named("key", key)}, fieldArgs)
+	if _, err := tx.tx.Exec(query, args...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// FieldTTL returns the time-to-live of the fields in a hash,
+// like HPTTL in Redis. Returns a map of the fields that exist,
+// with zero TTL for the fields without an expiration time.
+// Ignores the fields that do not exist.
+// If the key does not exist or is not a hash, returns an empty map.
+func (tx *Tx) FieldTTL(key string, fields ...string) (map[string]time.Duration, error) {
+	etimes, err := tx.fieldETimes(key, fields...)
+	if err != nil {
+		return nil, err
+	}
+	now := sqlx.Now(tx.tx).UnixMilli()
+	res := make(map[string]time.Duration, len(etimes))
+	for field, etime := range etimes {
+		if etime == nil {
+			res[field] = 0
+			continue
+		}
+		res[field] = time.Duration(*etime-now) * time.Millisecond
+	}
+	return res, nil
+}
+
 // Get returns the value of a field in a hash.
 // If the element does not exist, returns ErrNotFound.
 // If the key does not exist or is not a hash, returns ErrNotFound.
 func (tx *Tx) Get(key, field string) (core.Value, error) {
 	args := []any{
 		sql.Named("key", key),
-		sql.Named("now", time.Now().UnixMilli()),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
 		sql.Named("field", field),
 	}
 	var val []byte
@@ -174,7 +378,7 @@ func (tx *Tx) Get(key, field string) (core.Value, error) {
 // If the key does not exist or is not a hash, returns an empty map.
 func (tx *Tx) GetMany(key string, fields ...string) (map[string]core.Value, error) {
 	// Get the values of the requested fields.
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	query, fieldArgs := sqlx.ExpandIn(sqlGetMany, ":fields", fields)
 	args := slices.Concat([]any{sql.Named("key", key), sql.Named("now", now)}, fieldArgs)
 
@@ -213,6 +417,7 @@ func (tx *Tx) Incr(key, field string, delta int) (int, error) {
 	if err != nil && err != core.ErrNotFound {
 		return 0, err
 	}
+	created := err == core.ErrNotFound
 
 	// check if the value is a valid integer
 	valInt, err := val.Int()
@@ -222,10 +427,15 @@ func (tx *Tx) Incr(key, field string, delta int) (int, error) {
 
 	// increment the value
 	newVal := valInt + delta
-	err = tx.set(key, field, newVal)
+	err = tx.set(key, field, newVal, !created)
 	if err != nil {
 		return 0, err
 	}
+	if created {
+		if err := tx.checkLen(key); err != nil {
+			return 0, err
+		}
+	}
 
 	return newVal, nil
 }
@@ -242,6 +452,7 @@ func (tx *Tx) IncrFloat(key, field string, delta float64) (float64, error) {
 	if err != nil && err != core.ErrNotFound {
 		return 0, err
 	}
+	created := err == core.ErrNotFound
 
 	// check if the value is a valid float
 	valFloat, err := val.Float()
@@ -251,10 +462,15 @@ func (tx *Tx) IncrFloat(key, field string, delta float64) (float64, error) {
 
 	// increment the value
 	newVal := valFloat + delta
-	err = tx.set(key, field, newVal)
+	err = tx.set(key, field, newVal, !created)
 	if err != nil {
 		return 0, err
 	}
+	if created {
+		if err := tx.checkLen(key); err != nil {
+			return 0, err
+		}
+	}
 
 	return newVal, nil
 }
@@ -262,7 +478,7 @@ func (tx *Tx) IncrFloat(key, field string, delta float64) (float64, error) {
 // Items returns a map of all fields and values in a hash.
 // If the key does not exist or is not a hash, returns an empty map.
 func (tx *Tx) Items(key string) (map[string]core.Value, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{sql.Named("key", key), sql.Named("now", now)}
 
 	// Select hash rows.
@@ -292,13 +508,57 @@ func (tx *Tx) Items(key string) (map[string]core.Value, error) {
 // Len returns the number of fields in a hash.
 // If the key does not exist or is not a hash, returns 0.
 func (tx *Tx) Len(key string) (int, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{sql.Named("key", key), sql.Named("now", now)}
 	var n int
 	err := tx.tx.QueryRow(sqlLen, args...).Scan(&n)
 	return n, err
 }
 
+// RandField returns random fields from a hash, like HRANDFIELD in Redis.
+// With a positive count, returns up to count distinct fields.
+// With a negative count, returns exactly -count fields,
+// which may repeat. If withValues is false, the values
+// of the returned fields are not set (nil).
+// If the key does not exist or is not a hash, returns a nil slice.
+func (tx *Tx) RandField(key string, count int, withValues bool) ([]HashItem, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	args := []any{
+		sql.Named("key", key),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
+		sql.Named("count", count),
+	}
+	scan := func(rows *sql.Rows) (HashItem, error) {
+		var it HashItem
+		var err error
+		it.Field, it.Value, err = scanValue(rows)
+		return it, err
+	}
+	var items []HashItem
+	var err error
+	rnd := sqlx.RandOf(tx.tx)
+	if count > 0 && rnd == nil {
+		items, err = sqlx.Select(tx.tx, sqlRandField, args, scan)
+	} else {
+		items, err = sqlx.Select(tx.tx, sqlItems, args, scan)
+		items = pickRandom(rnd, items, count)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil
+	}
+	if !withValues {
+		for i := range items {
+			items[i].Value = nil
+		}
+	}
+	return items, nil
+}
+
 // Scan iterates over hash items with fields matching pattern.
 // Returns a slice field-value pairs (see [HashItem]) of size count
 // based on the current state of the cursor. Returns an empty HashItem
@@ -309,15 +569,13 @@ func (tx *Tx) Scan(key string, cursor int, pattern string, count int) (ScanResul
 	if count == 0 {
 		count = scanPageSize
 	}
-
-	args := []any{
-		sql.Named("key", key),
-		sql.Named("now", time.Now().UnixMilli()),
-		sql.Named("cursor", cursor),
-		sql.Named("pattern", pattern),
-		sql.Named("count", count),
+	cur, err := sqlx.ParseCursor(tx.tx, cursor)
+	if err != nil {
+		return ScanResult{}, err
 	}
 
+	glob := sqlx.NewGlob(tx.tx, pattern)
+
 	// Select hash items matching the pattern.
 	scan := func(rows *sql.Rows) (HashItem, error) {
 		var it HashItem
@@ -326,20 +584,38 @@ func (tx *Tx) Scan(key string, cursor int, pattern string, count int) (ScanResul
 		it.Value = core.Value(val)
 		return it, err
 	}
-	items, err := sqlx.Select(tx.tx, sqlScan, args, scan)
-	if err != nil {
-		return ScanResult{}, err
-	}
+	for {
+		args := []any{
+			sql.Named("key", key),
+			sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
+			sql.Named("cursor", cur.ID),
+			sql.Named("pattern", glob.Arg()),
+			sql.Named("count", count),
+		}
+		items, err := sqlx.Select(tx.tx, sqlScan, args, scan)
+		if err != nil {
+			return ScanResult{}, err
+		}
 
-	// Select the maximum ID.
-	maxID := 0
-	for _, it := range items {
-		if it.id > maxID {
-			maxID = it.id
+		// Select the maximum ID.
+		maxID := 0
+		for _, it := range items {
+			if it.id > maxID {
+				maxID = it.id
+			}
 		}
-	}
 
-	return ScanResult{maxID, items}, nil
+		// Skip the pages where none of the fields with NUL bytes
+		// match, so that an empty page still means there are
+		// no more items (see sqlx.Glob).
+		matched := slices.DeleteFunc(items, func(it HashItem) bool {
+			return !glob.Match(it.Field)
+		})
+		if len(matched) > 0 || maxID == 0 {
+			return ScanResult{cur.Next(maxID), matched}, nil
+		}
+		cur.ID = maxID
+	}
 }
 
 // Scanner returns an iterator for hash items with fields matching pattern.
@@ -363,10 +639,15 @@ func (tx *Tx) Set(key string, field string, value any) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	err = tx.set(key, field, value)
+	err = tx.set(key, field, value, false)
 	if err != nil {
 		return false, err
 	}
+	if existCount == 0 {
+		if err := tx.checkLen(key); err != nil {
+			return false, err
+		}
+	}
 	return existCount == 0, nil
 }
 
@@ -393,11 +674,16 @@ func (tx *Tx) SetMany(key string, items map[string]any) (int, error) {
 
 	// Set the values.
 	for field, val := range items {
-		err := tx.set(key, field, val)
+		err := tx.set(key, field, val, false)
 		if err != nil {
 			return 0, err
 		}
 	}
+	if len(items) > existCount {
+		if err := tx.checkLen(key); err != nil {
+			return 0, err
+		}
+	}
 
 	return len(items) - existCount, nil
 }
@@ -417,17 +703,20 @@ func (tx *Tx) SetNotExists(key, field string, value any) (bool, error) {
 	if exist {
 		return false, nil
 	}
-	err = tx.set(key, field, value)
+	err = tx.set(key, field, value, false)
 	if err != nil {
 		return false, err
 	}
+	if err := tx.checkLen(key); err != nil {
+		return false, err
+	}
 	return true, nil
 }
 
 // Values returns all values in a hash.
 // If the key does not exist or is not a hash, returns an empty slice.
 func (tx *Tx) Values(key string) ([]core.Value, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{sql.Named("key", key), sql.Named("now", now)}
 
 	// Select hash values.
@@ -457,7 +746,7 @@ func (tx *Tx) Values(key string) ([]core.Value, error) {
 
 // count returns the number of existing fields in a hash.
 func (tx *Tx) count(key string, fields ...string) (int, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	query, fieldArgs := sqlx.ExpandIn(sqlCount, ":fields", fields)
 	args := slices.Concat([]any{sql.Named("key", key), sql.Named("now", now)}, fieldArgs)
 	var count int
@@ -465,23 +754,99 @@ func (tx *Tx) count(key string, fields ...string) (int, error) {
 	return count, err
 }
 
+// deleteExpired deletes the hash fields with expired TTL.
+// Returns the number of deleted fields.
+func (tx *Tx) deleteExpired() (int, error) {
+	now := sql.Named("now", sqlx.Now(tx.tx).UnixMilli())
+	res, err := tx.tx.Exec(sqlDeleteExpired1, now)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.tx.Exec(sqlDeleteExpired2, now); err != nil {
+		return 0, err
+	}
+	count, _ := res.RowsAffected()
+	return int(count), nil
+}
+
+// fieldETimes returns the expiration times of the existing fields
+// in a hash (nil for the fields without an expiration time).
+func (tx *Tx) fieldETimes(key string, fields ...string) (map[string]*int64, error) {
+	now := sqlx.Now(tx.tx).UnixMilli()
+	query, fieldArgs := sqlx.ExpandIn(sqlFieldTTL, ":fields", fields)
+	args := slices.Concat([]any{sql.Named("key", key), sql.Named("now", now)}, fieldArgs)
+	rows, err := tx.tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	etimes := map[string]*int64{}
+	for rows.Next() {
+		var field string
+		var etime *int64
+		if err := rows.Scan(&field, &etime); err != nil {
+			return nil, err
+		}
+		etimes[field] = etime
+	}
+	return etimes, rows.Err()
+}
+
+// checkLen checks the number of fields in a hash
+// against the size limits (see sqlx.Limits).
+func (tx *Tx) checkLen(key string) error {
+	return sqlx.CheckElements(tx.tx, func() (int, error) {
+		return tx.Len(key)
+	})
+}
+
+// delete deletes the fields from a hash, including the expired ones.
+func (tx *Tx) delete(key string, fields ...string) error {
+	now := sqlx.Now(tx.tx).UnixMilli()
+	for _, query := range []string{sqlDelete1, sqlDelete2} {
+		query, fieldArgs := sqlx.ExpandIn(query, ":fields", fields)
+		args := slices.Concat([]any{sql.Named("key", key), sql.Named("now", now)}, fieldArgs)
+		if _, err := tx.tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // set creates or updates the value of a field in a hash.
-func (tx *Tx) set(key string, field string, value any) error {
+// Removes the expiration time of the field unless keepTTL is true.
+func (tx *Tx) set(key string, field string, value any, keepTTL bool) error {
+	if err := sqlx.CheckKey(tx.tx, key); err != nil {
+		return err
+	}
+	if err := sqlx.CheckValue(tx.tx, field); err != nil {
+		return err
+	}
+	if err := sqlx.CheckValue(tx.tx, value); err != nil {
+		return err
+	}
+
 	args := []any{
 		sql.Named("key", key),
 		sql.Named("type", core.TypeHash),
 		sql.Named("version", core.InitialVersion),
-		sql.Named("mtime", time.Now().UnixMilli()),
+		sql.Named("mtime", sqlx.Now(tx.tx).UnixMilli()),
 		sql.Named("field", field),
 		sql.Named("value", value),
 	}
 
 	_, err := tx.tx.Exec(sqlSet1, args...)
 	if err != nil {
-		return sqlx.TypedError(err)
+		return sqlx.KeyTypeError(tx.tx, err, key, core.TypeHash)
 	}
 
 	_, err = tx.tx.Exec(sqlSet2, args...)
+	if err != nil || keepTTL {
+		return err
+	}
+
+	_, err = tx.tx.Exec(sqlSet3, args...)
 	return err
 }
 
@@ -504,6 +869,32 @@ type HashItem struct {
 
 // ScanResult represents a result of the scan command.
 type ScanResult struct {
-	Cursor int
+	Cursor int // opaque cursor to continue the scan, 0 when done (see sqlx.Cursor)
 	Items  []HashItem
 }
+
+// pickRandom returns random items according to the count
+// (see Tx.RandField). Uses the random source if there is one.
+func pickRandom(rnd *sqlx.Rand, items []HashItem, count int) []HashItem {
+	if len(items) == 0 {
+		return nil
+	}
+	intN := rand.IntN
+	if rnd != nil {
+		intN = rnd.IntN
+	}
+	if count < 0 {
+		picked := make([]HashItem, -count)
+		for i := range picked {
+			picked[i] = items[intN(len(items))]
+		}
+		return picked
+	}
+	// Partial Fisher-Yates shuffle.
+	count = min(count, len(items))
+	for i := 0; i < count; i++ {
+		j := i + intN(len(items)-i)
+		items[i], items[j] = items[j], items[i]
+	}
+	return items[:count]
+}
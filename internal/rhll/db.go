@@ -0,0 +1,109 @@
+// Package rhll is a database-backed HyperLogLog repository.
+// It provides methods to interact with HyperLogLogs in the database.
+package rhll
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// DB is a database-backed HyperLogLog repository.
+// A HyperLogLog is a probabilistic data structure associated
+// with a key, which estimates the number of unique elements
+// added to it using a fixed amount of memory (12KB per key).
+// Use the HyperLogLog repository to count unique items,
+// such as the visitors of a web page.
+//
+// The values are stored in the Redis dense encoding,
+// so they are compatible with Redis HyperLogLogs.
+type DB struct {
+	*sqlx.DB[*Tx]
+}
+
+// New connects to the HyperLogLog repository.
+// Does not create the database schema.
+func New(db *sql.DB) *DB {
+	d := sqlx.New(db, NewTx)
+	return &DB{d}
+}
+
+// WithContext returns a shallow copy of the repository
+// that executes the queries with ctx. Use it to enforce
+// timeouts and cancellation on slow queries.
+func (d *DB) WithContext(ctx context.Context) *DB {
+	return &DB{d.DB.WithContext(ctx)}
+}
+
+// Add adds the elements to a HyperLogLog.
+// Returns true if the estimated cardinality may have changed
+// (or the key was created), false otherwise.
+// If the key does not exist, creates it (even without elements).
+// If the key exists but is not a HyperLogLog, returns ErrKeyType.
+func (d *DB) Add(key string, elems ...string) (bool, error) {
+	op := d.Observe("HLL.Add", key)
+	var changed bool
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		changed, err = tx.Add(key, elems...)
+		return err
+	})
+	return changed, op.Done(err)
+}
+
+// Count returns the estimated number of unique elements
+// added to a HyperLogLog (the standard error is 0.81%).
+// With multiple keys, returns the estimated cardinality
+// of their union, without modifying the keys.
+// Ignores the keys that do not exist or are not HyperLogLogs.
+func (d *DB) Count(keys ...string) (int, error) {
+	op := d.Observe("HLL.Count", keys...)
+	tx := NewTx(d.In(op).ReadConn())
+	n, err := tx.Count(keys...)
+	return n, op.Done(err)
+}
+
+// Get returns the HyperLogLog value in the Redis dense encoding,
+// the same value Redis returns for GET on a HyperLogLog key.
+// If the key does not exist, returns ErrNotFound.
+func (d *DB) Get(key string) ([]byte, error) {
+	op := d.Observe("HLL.Get", key)
+	tx := NewTx(d.In(op).ReadConn())
+	val, err := tx.Get(key)
+	return val, op.Done(err)
+}
+
+// Set stores the HyperLogLog value in the Redis dense or sparse
+// encoding (see [Valid]), replacing the existing HyperLogLog.
+// The sparse values are converted to the dense encoding.
+// If the value is not a valid HyperLogLog, returns ErrValueType.
+// If the key exists but is not a HyperLogLog, returns ErrKeyType.
+func (d *DB) Set(key string, value []byte) error {
+	op := d.Observe("HLL.Set", key)
+	err := d.In(op).Update(func(tx *Tx) error {
+		return tx.Set(key, value)
+	})
+	return op.Done(err)
+}
+
+// Valid reports whether the value is a HyperLogLog in the Redis
+// dense or sparse encoding, such as a HyperLogLog loaded from
+// a Redis RDB file, where they are stored as strings.
+func Valid(value []byte) bool {
+	_, ok := decodeSketch(value)
+	return ok
+}
+
+// Merge merges the HyperLogLogs into the dest key, so that its
+// cardinality estimates the union of the sources and the dest.
+// If the dest key does not exist, creates it.
+// Ignores the source keys that do not exist or are not HyperLogLogs.
+// If the dest key exists but is not a HyperLogLog, returns ErrKeyType.
+func (d *DB) Merge(dest string, keys ...string) error {
+	op := d.Observe("HLL.Merge", append([]string{dest}, keys...)...)
+	err := d.In(op).Update(func(tx *Tx) error {
+		return tx.Merge(dest, keys...)
+	})
+	return op.Done(err)
+}
@@ -0,0 +1,269 @@
+package rhll_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rhll"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestAdd(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		changed, err := db.Add("visitors", "alice", "bob")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, changed, true)
+
+		key, _ := red.Key().Get("visitors")
+		testx.AssertEqual(t, key.Type, core.TypeHLL)
+		testx.AssertEqual(t, key.Version, 1)
+	})
+	t.Run("create empty", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		changed, err := db.Add("visitors")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, changed, true)
+
+		count, _ := db.Count("visitors")
+		testx.AssertEqual(t, count, 0)
+		exists, _ := red.Key().Exists("visitors")
+		testx.AssertEqual(t, exists, true)
+	})
+	t.Run("update", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("visitors", "alice")
+
+		changed, err := db.Add("visitors", "bob")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, changed, true)
+
+		key, _ := red.Key().Get("visitors")
+		testx.AssertEqual(t, key.Version, 2)
+	})
+	t.Run("not changed", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("visitors", "alice", "bob")
+
+		changed, err := db.Add("visitors", "bob", "alice")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, changed, false)
+
+		key, _ := red.Key().Get("visitors")
+		testx.AssertEqual(t, key.Version, 1)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("visitors", "alice")
+
+		changed, err := db.Add("visitors", "bob")
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, changed, false)
+	})
+}
+
+func TestCount(t *testing.T) {
+	t.Run("small", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("visitors", "alice", "bob", "cindy", "alice")
+
+		count, err := db.Count("visitors")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 3)
+	})
+	t.Run("large", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		const n = 100000
+		elems := make([]string, n)
+		for i := range elems {
+			elems[i] = "user:" + strconv.Itoa(i)
+		}
+		_, _ = db.Add("visitors", elems...)
+
+		count, err := db.Count("visitors")
+		testx.AssertNoErr(t, err)
+		// The standard error is 0.81%, so allow 3%.
+		if count < n*97/100 || count > n*103/100 {
+			t.Errorf("want count ≈ %d, got %d", n, count)
+		}
+	})
+	t.Run("union", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("mon", "alice", "bob")
+		_, _ = db.Add("tue", "bob", "cindy", "dave")
+
+		count, err := db.Count("mon", "tue", "wed")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 4)
+
+		// The keys are not modified.
+		mon, _ := db.Count("mon")
+		testx.AssertEqual(t, mon, 2)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		count, err := db.Count("visitors")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 0)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("visitors", "alice")
+
+		count, err := db.Count("visitors")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 0)
+	})
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("mon", "alice", "bob")
+		_, _ = db.Add("tue", "bob", "cindy", "dave")
+
+		err := db.Merge("week", "mon", "tue", "wed")
+		testx.AssertNoErr(t, err)
+
+		count, _ := db.Count("week")
+		testx.AssertEqual(t, count, 4)
+		mon, _ := db.Count("mon")
+		testx.AssertEqual(t, mon, 2)
+	})
+	t.Run("keep dest", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("mon", "alice", "bob")
+		_, _ = db.Add("week", "erin")
+
+		err := db.Merge("week", "mon")
+		testx.AssertNoErr(t, err)
+
+		count, _ := db.Count("week")
+		testx.AssertEqual(t, count, 3)
+	})
+	t.Run("no sources", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		err := db.Merge("week")
+		testx.AssertNoErr(t, err)
+
+		key, _ := red.Key().Get("week")
+		testx.AssertEqual(t, key.Type, core.TypeHLL)
+	})
+	t.Run("dest type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("mon", "alice")
+		_ = red.Str().Set("week", "alice")
+
+		err := db.Merge("week", "mon")
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+}
+
+func TestGetSet(t *testing.T) {
+	t.Run("dense", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("visitors", "alice", "bob")
+
+		val, err := db.Get("visitors")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, string(val[:4]), "HYLL")
+		testx.AssertEqual(t, rhll.Valid(val), true)
+
+		err = db.Set("copy", val)
+		testx.AssertNoErr(t, err)
+		count, _ := db.Count("copy")
+		testx.AssertEqual(t, count, 2)
+	})
+	t.Run("sparse", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		// The first register is 3 (VAL), the rest are zeros (XZERO).
+		val := []byte("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")
+		val = append(val, 0x88, 0x7f, 0xfe)
+		testx.AssertEqual(t, rhll.Valid(val), true)
+
+		err := db.Set("visitors", val)
+		testx.AssertNoErr(t, err)
+		count, _ := db.Count("visitors")
+		testx.AssertEqual(t, count, 1)
+		dense, _ := db.Get("visitors")
+		testx.AssertEqual(t, dense[4], byte(0))
+	})
+	t.Run("invalid", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		err := db.Set("visitors", []byte("alice"))
+		testx.AssertErr(t, err, core.ErrValueType)
+		// Too few registers.
+		err = db.Set("visitors", []byte("HYLL\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x88"))
+		testx.AssertErr(t, err, core.ErrValueType)
+		exists, _ := red.Key().Exists("visitors")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, err := db.Get("visitors")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("visitors", "alice")
+		val, _ := db.Get("visitors")
+		_ = red.Str().Set("name", "alice")
+
+		err := db.Set("name", val)
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+}
+
+func TestTx(t *testing.T) {
+	red, _ := getDB(t)
+	defer red.Close()
+
+	var count int
+	err := red.Update(func(tx *redka.Tx) error {
+		_, err := tx.HLL().Add("visitors", "alice", "bob")
+		if err != nil {
+			return err
+		}
+		count, err = tx.HLL().Count("visitors")
+		return err
+	})
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, count, 2)
+}
+
+func getDB(tb testing.TB) (*redka.DB, *rhll.DB) {
+	tb.Helper()
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db, db.HLL()
+}
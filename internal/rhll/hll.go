@@ -0,0 +1,255 @@
+package rhll
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// The HyperLogLog parameters, same as in Redis.
+const (
+	precision = 14             // bits of the hash used to select the register
+	registers = 1 << precision // number of registers
+	regBits   = 6              // bits per register
+	regMax    = 1<<regBits - 1 // max register value
+	headerLen = 16             // "HYLL", encoding, unused bytes and cached cardinality
+	denseLen  = headerLen + (registers*regBits+7)/8
+
+	// q is the number of hash bits used
+	// to count the leading zeros.
+	q = 64 - precision
+)
+
+// alphaInf is the bias correction constant
+// for the number of registers tending to infinity.
+const alphaInf = 0.721347520444481703680
+
+// hashSeed is the seed of the MurmurHash64A function used by Redis.
+const hashSeed = 0xadc83b19
+
+// sketch is a HyperLogLog in the Redis dense encoding:
+// a 16-byte header followed by 16384 6-bit registers.
+// Sketches produced by Redis and Redka are interchangeable
+// (as long as Redis uses the dense encoding).
+type sketch []byte
+
+// newSketch creates an empty sketch.
+func newSketch() sketch {
+	s := make(sketch, denseLen)
+	copy(s, "HYLL")
+	return s
+}
+
+// parseSketch validates the stored sketch. Returns false
+// if the value is not a HyperLogLog in the dense encoding.
+func parseSketch(b []byte) (sketch, bool) {
+	if len(b) != denseLen || string(b[:4]) != "HYLL" || b[4] != 0 {
+		return nil, false
+	}
+	return sketch(b), true
+}
+
+// decodeSketch converts a HyperLogLog in the Redis dense
+// or sparse encoding to a new dense sketch. Returns false
+// if the value is not a valid HyperLogLog.
+func decodeSketch(b []byte) (sketch, bool) {
+	if s, ok := parseSketch(b); ok {
+		return append(sketch(nil), s...), true
+	}
+	if len(b) < headerLen || string(b[:4]) != "HYLL" || b[4] != 1 {
+		return nil, false
+	}
+	// The sparse encoding is a sequence of opcodes, each
+	// describing a run of registers with the same value:
+	// ZERO (00xxxxxx), XZERO (01xxxxxx yyyyyyyy) and VAL (1vvvvvxx).
+	s := newSketch()
+	index := 0
+	for i := headerLen; i < len(b); i++ {
+		op := b[i]
+		var val uint8
+		var run int
+		switch {
+		case op&0xc0 == 0:
+			run = int(op&0x3f) + 1
+		case op&0xc0 == 0x40:
+			if i+1 >= len(b) {
+				return nil, false
+			}
+			i++
+			run = (int(op&0x3f)<<8 | int(b[i])) + 1
+		default:
+			val = (op>>2)&0x1f + 1
+			run = int(op&0x03) + 1
+		}
+		if index+run > registers {
+			return nil, false
+		}
+		for ; run > 0; run-- {
+			if val > 0 {
+				s.set(index, val)
+			}
+			index++
+		}
+	}
+	if index != registers {
+		return nil, false
+	}
+	s.invalidate()
+	return s, true
+}
+
+// add adds the element to the sketch.
+// Reports whether any register has changed.
+func (s sketch) add(elem []byte) bool {
+	index, count := hashElem(elem)
+	if count <= s.get(index) {
+		return false
+	}
+	s.set(index, count)
+	s.invalidate()
+	return true
+}
+
+// merge sets each register to the maximum of its value in the sketch
+// and in the other one. Reports whether any register has changed.
+func (s sketch) merge(other sketch) bool {
+	changed := false
+	for i := 0; i < registers; i++ {
+		if val := other.get(i); val > s.get(i) {
+			s.set(i, val)
+			changed = true
+		}
+	}
+	if changed {
+		s.invalidate()
+	}
+	return changed
+}
+
+// count returns the estimated cardinality, using the estimator
+// by Otmar Ertl (same as Redis since 5.0).
+func (s sketch) count() int {
+	var histo [64]int
+	for i := 0; i < registers; i++ {
+		histo[s.get(i)]++
+	}
+	const m = float64(registers)
+	z := m * tau((m-float64(histo[q+1]))/m)
+	for j := q; j >= 1; j-- {
+		z += float64(histo[j])
+		z *= 0.5
+	}
+	z += m * sigma(float64(histo[0])/m)
+	return int(math.Round(alphaInf * m * m / z))
+}
+
+// get returns the value of the register.
+func (s sketch) get(index int) uint8 {
+	regs := s[headerLen:]
+	byteIdx := index * regBits / 8
+	bitIdx := uint(index * regBits % 8)
+	b0 := uint(regs[byteIdx])
+	var b1 uint
+	if byteIdx+1 < len(regs) {
+		b1 = uint(regs[byteIdx+1])
+	}
+	return uint8((b0>>bitIdx | b1<<(8-bitIdx)) & regMax)
+}
+
+// set sets the value of the register.
+func (s sketch) set(index int, val uint8) {
+	regs := s[headerLen:]
+	byteIdx := index * regBits / 8
+	bitIdx := uint(index * regBits % 8)
+	v := uint(val)
+	regs[byteIdx] &^= byte(regMax << bitIdx)
+	regs[byteIdx] |= byte(v << bitIdx)
+	if byteIdx+1 < len(regs) {
+		regs[byteIdx+1] &^= byte(regMax >> (8 - bitIdx))
+		regs[byteIdx+1] |= byte(v >> (8 - bitIdx))
+	}
+}
+
+// invalidate marks the cached cardinality in the header as stale,
+// so that Redis recomputes it after loading the sketch.
+func (s sketch) invalidate() {
+	s[headerLen-1] |= 1 << 7
+}
+
+// hashElem returns the register index for the element
+// and the number of trailing zeros in the rest of its hash plus one.
+func hashElem(elem []byte) (index int, count uint8) {
+	hash := murmurHash64A(elem, hashSeed)
+	index = int(hash & (registers - 1))
+	hash >>= precision
+	// Make sure the loop terminates.
+	hash |= 1 << q
+	count = uint8(bits.TrailingZeros64(hash) + 1)
+	return index, count
+}
+
+// sigma is the helper function of the cardinality estimator.
+func sigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y := 1.0
+	z := x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if prev == z {
+			return z
+		}
+	}
+}
+
+// tau is the helper function of the cardinality estimator.
+func tau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y := 1.0
+	z := 1 - x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if prev == z {
+			return z / 3
+		}
+	}
+}
+
+// murmurHash64A is the 64-bit MurmurHash2 by Austin Appleby,
+// as used by Redis (little-endian on all platforms).
+func murmurHash64A(key []byte, seed uint64) uint64 {
+	const m = 0xc6a4a7935bd1e995
+	const r = 47
+	h := seed ^ uint64(len(key))*m
+
+	for len(key) >= 8 {
+		k := binary.LittleEndian.Uint64(key)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+		key = key[8:]
+	}
+
+	if len(key) > 0 {
+		for i := len(key) - 1; i >= 0; i-- {
+			h ^= uint64(key[i]) << (8 * i)
+		}
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
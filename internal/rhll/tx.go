@@ -0,0 +1,189 @@
+package rhll
+
+import (
+	"database/sql"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+const (
+	sqlGet = `
+	select value
+	from rhll
+	  join rkey on key_id = rkey.id and type = :type
+	    and (etime is null or etime > :now)
+	where key = :key`
+
+	sqlSet1 = `
+	insert into rkey (id, key, type, version, mtime)
+	values (` + sqlx.NextKeyID + `, :key, :type, :version, :mtime)
+	on conflict (key) do update set
+	  version = version+1,
+	  type = excluded.type,
+	  mtime = excluded.mtime`
+
+	sqlSet2 = `
+	insert into rhll (key_id, value)
+	values ((select id from rkey where key = :key), :value)
+	on conflict (key_id) do update
+	set value = excluded.value`
+)
+
+// Tx is a HyperLogLog repository transaction.
+type Tx struct {
+	tx sqlx.Tx
+}
+
+// NewTx creates a HyperLogLog repository transaction
+// from a generic database transaction.
+func NewTx(tx sqlx.Tx) *Tx {
+	return &Tx{tx}
+}
+
+// Add adds the elements to a HyperLogLog.
+// Returns true if the estimated cardinality may have changed
+// (or the key was created), false otherwise.
+// If the key does not exist, creates it (even without elements).
+// If the key exists but is not a HyperLogLog, returns ErrKeyType.
+func (tx *Tx) Add(key string, elems ...string) (bool, error) {
+	s, ok, err := tx.get(key)
+	if err != nil {
+		return false, err
+	}
+	changed := !ok
+	for _, elem := range elems {
+		if s.add([]byte(elem)) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+	if err := tx.set(key, s); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Count returns the estimated number of unique elements
+// added to a HyperLogLog (the standard error is 0.81%).
+// With multiple keys, returns the estimated cardinality
+// of their union, without modifying the keys.
+// Ignores the keys that do not exist or are not HyperLogLogs.
+func (tx *Tx) Count(keys ...string) (int, error) {
+	if len(keys) == 1 {
+		s, _, err := tx.get(keys[0])
+		if err != nil {
+			return 0, err
+		}
+		return s.count(), nil
+	}
+	union := newSketch()
+	for _, key := range keys {
+		s, ok, err := tx.get(key)
+		if err != nil {
+			return 0, err
+		}
+		if ok {
+			union.merge(s)
+		}
+	}
+	return union.count(), nil
+}
+
+// Merge merges the HyperLogLogs into the dest key, so that its
+// cardinality estimates the union of the sources and the dest.
+// If the dest key does not exist, creates it.
+// Ignores the source keys that do not exist or are not HyperLogLogs.
+// If the dest key exists but is not a HyperLogLog, returns ErrKeyType.
+func (tx *Tx) Merge(dest string, keys ...string) error {
+	s, _, err := tx.get(dest)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		src, ok, err := tx.get(key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			s.merge(src)
+		}
+	}
+	return tx.set(dest, s)
+}
+
+// Get returns the HyperLogLog value in the Redis dense encoding,
+// the same value Redis returns for GET on a HyperLogLog key.
+// If the key does not exist, returns ErrNotFound.
+func (tx *Tx) Get(key string) ([]byte, error) {
+	s, ok, err := tx.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, core.ErrNotFound
+	}
+	return s, nil
+}
+
+// Set stores the HyperLogLog value in the Redis dense or sparse
+// encoding (see [Valid]), replacing the existing HyperLogLog.
+// The sparse values are converted to the dense encoding.
+// If the value is not a valid HyperLogLog, returns ErrValueType.
+// If the key exists but is not a HyperLogLog, returns ErrKeyType.
+func (tx *Tx) Set(key string, value []byte) error {
+	s, ok := decodeSketch(value)
+	if !ok {
+		return core.ErrValueType
+	}
+	return tx.set(key, s)
+}
+
+// get returns the sketch stored in the key, or an empty
+// sketch and false if the key does not exist.
+// Returns ErrValueType if the stored value is corrupted.
+func (tx *Tx) get(key string) (sketch, bool, error) {
+	args := []any{
+		sql.Named("key", key),
+		sql.Named("type", core.TypeHLL),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
+	}
+	var value []byte
+	err := tx.tx.QueryRow(sqlGet, args...).Scan(&value)
+	if err == sql.ErrNoRows {
+		return newSketch(), false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	s, ok := parseSketch(value)
+	if !ok {
+		return nil, false, core.ErrValueType
+	}
+	return s, true, nil
+}
+
+// set stores the sketch in the key.
+func (tx *Tx) set(key string, s sketch) error {
+	if err := sqlx.CheckKey(tx.tx, key); err != nil {
+		return err
+	}
+	if err := sqlx.CheckSize(tx.tx, int64(len(s))); err != nil {
+		return err
+	}
+	args := []any{
+		sql.Named("key", key),
+		sql.Named("type", core.TypeHLL),
+		sql.Named("version", core.InitialVersion),
+		sql.Named("mtime", sqlx.Now(tx.tx).UnixMilli()),
+		sql.Named("value", []byte(s)),
+	}
+	_, err := tx.tx.Exec(sqlSet1, args...)
+	if err != nil {
+		return sqlx.KeyTypeError(tx.tx, err, key, core.TypeHLL)
+	}
+	_, err = tx.tx.Exec(sqlSet2, args...)
+	return err
+}
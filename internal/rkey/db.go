@@ -3,7 +3,9 @@
 package rkey
 
 import (
+	"context"
 	"database/sql"
+	"iter"
 	"time"
 
 	"github.com/nalgeon/redka/internal/core"
@@ -25,16 +27,69 @@ func New(db *sql.DB) *DB {
 	return &DB{d}
 }
 
+// WithContext returns a shallow copy of the repository
+// that executes the queries with ctx. Use it to enforce
+// timeouts and cancellation on slow queries.
+func (db *DB) WithContext(ctx context.Context) *DB {
+	return &DB{db.DB.WithContext(ctx)}
+}
+
 // Exists reports whether the key exists.
 func (db *DB) Exists(key string) (bool, error) {
-	tx := NewTx(db.SQL)
-	return tx.Exists(key)
+	op := db.Observe("Key.Exists", key)
+	tx := NewTx(db.In(op).ReadConn())
+	ok, err := tx.Exists(key)
+	if err == nil {
+		db.Lookup(ok)
+	}
+	return ok, op.Done(err)
 }
 
 // Count returns the number of existing keys among specified.
 func (db *DB) Count(keys ...string) (int, error) {
-	tx := NewTx(db.SQL)
-	return tx.Count(keys...)
+	op := db.Observe("Key.Count", keys...)
+	tx := NewTx(db.In(op).ReadConn())
+	count, err := tx.Count(keys...)
+	return count, op.Done(err)
+}
+
+// CountByType returns the number of existing keys of each type.
+// Types without keys are not included.
+func (db *DB) CountByType() (map[core.TypeID]int, error) {
+	op := db.Observe("Key.CountByType")
+	tx := NewTx(db.In(op).ReadConn())
+	counts, err := tx.CountByType()
+	return counts, op.Done(err)
+}
+
+// Keyspace returns the number of existing keys, the number
+// of keys with an expiration time, and their average TTL.
+func (db *DB) Keyspace() (KeyspaceInfo, error) {
+	op := db.Observe("Key.Keyspace")
+	tx := NewTx(db.In(op).ReadConn())
+	info, err := tx.Keyspace()
+	return info, op.Done(err)
+}
+
+// Inspect returns the storage details of the key: the size of
+// the key and its value in bytes, the number of elements, and the
+// time since the last modification (see [KeyInfo]).
+// If the key does not exist, returns an empty KeyInfo.
+func (db *DB) Inspect(key string) (KeyInfo, error) {
+	op := db.Observe("Key.Inspect", key)
+	tx := NewTx(db.In(op).ReadConn())
+	info, err := tx.Inspect(key)
+	return info, op.Done(err)
+}
+
+// Expiry returns the number of keys that expire within
+// the next minute, hour and day, and the number of expired
+// keys that are not deleted yet (see [ExpiryInfo]).
+func (db *DB) Expiry() (ExpiryInfo, error) {
+	op := db.Observe("Key.Expiry")
+	tx := NewTx(db.In(op).ReadConn())
+	info, err := tx.Expiry()
+	return info, op.Done(err)
 }
 
 // Keys returns all keys matching pattern.
@@ -45,8 +100,19 @@ func (db *DB) Count(keys ...string) (int, error) {
 // Use this method only if you are sure that the number of keys is
 // limited. Otherwise, use the [DB.Scan] or [DB.Scanner] methods.
 func (db *DB) Keys(pattern string) ([]core.Key, error) {
-	tx := NewTx(db.SQL)
-	return tx.Keys(pattern)
+	op := db.Observe("Key.Keys")
+	tx := NewTx(db.In(op).ReadConn())
+	keys, err := tx.Keys(pattern)
+	return keys, op.Done(err)
+}
+
+// Stamp returns the changestamp of the keys matching the pattern.
+// See [Tx.Stamp] for details.
+func (db *DB) Stamp(pattern string) (uint64, error) {
+	op := db.Observe("Key.Stamp")
+	tx := NewTx(db.In(op).ReadConn())
+	stamp, err := tx.Stamp(pattern)
+	return stamp, op.Done(err)
 }
 
 // Scan iterates over keys matching pattern.
@@ -55,8 +121,22 @@ func (db *DB) Keys(pattern string) ([]core.Key, error) {
 // See [DB.Keys] for pattern description.
 // Set pageSize = 0 for default page size.
 func (db *DB) Scan(cursor int, pattern string, pageSize int) (ScanResult, error) {
-	tx := NewTx(db.SQL)
-	return tx.Scan(cursor, pattern, pageSize)
+	op := db.Observe("Key.Scan")
+	tx := NewTx(db.In(op).ReadConn())
+	res, err := tx.Scan(cursor, pattern, pageSize)
+	return res, op.Done(err)
+}
+
+// ScanType iterates over keys of the given type matching pattern,
+// like SCAN with TYPE in Redis. Type 0 matches all types.
+// ScanResult.More reports whether there may be more keys,
+// so the caller can stop without fetching an empty page.
+// See [DB.Scan] for details.
+func (db *DB) ScanType(cursor int, pattern string, typ core.TypeID, pageSize int) (ScanResult, error) {
+	op := db.Observe("Key.ScanType")
+	tx := NewTx(db.In(op).ReadConn())
+	res, err := tx.ScanType(cursor, pattern, typ, pageSize)
+	return res, op.Done(err)
 }
 
 // Scanner returns an iterator for keys matching pattern.
@@ -65,107 +145,310 @@ func (db *DB) Scan(cursor int, pattern string, pageSize int) (ScanResult, error)
 // See [DB.Keys] for pattern description.
 // Set pageSize = 0 for default page size.
 func (db *DB) Scanner(pattern string, pageSize int) *Scanner {
-	return newScanner(NewTx(db.SQL), pattern, pageSize)
+	sc := newScanner(NewTx(db.ReadConn()), pattern, 0, pageSize)
+	sc.close = db.OpenScanner()
+	return sc
+}
+
+// ScannerType returns an iterator for keys of the given type
+// matching pattern. Type 0 matches all types.
+// See [DB.Scanner] for details.
+func (db *DB) ScannerType(pattern string, typ core.TypeID, pageSize int) *Scanner {
+	sc := newScanner(NewTx(db.ReadConn()), pattern, typ, pageSize)
+	sc.close = db.OpenScanner()
+	return sc
+}
+
+// Iter returns an iterator over the keys matching pattern.
+// Fetches the keys from the database in batches as necessary.
+// Stops after yielding the first error, if any:
+//
+//	for key, err := range db.Iter("user:*") {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // ...
+//	}
+//
+// See [DB.Keys] for pattern description.
+func (db *DB) Iter(pattern string) iter.Seq2[core.Key, error] {
+	return sqlx.Iter(db.Scanner(pattern, 0))
 }
 
 // Random returns a random key.
 func (db *DB) Random() (core.Key, error) {
-	tx := NewTx(db.SQL)
-	return tx.Random()
+	op := db.Observe("Key.Random")
+	tx := NewTx(db.In(op).ReadConn())
+	key, err := tx.Random()
+	return key, op.Done(err)
 }
 
 // Get returns a specific key with all associated details.
 func (db *DB) Get(key string) (core.Key, error) {
-	tx := NewTx(db.SQL)
-	return tx.Get(key)
+	op := db.Observe("Key.Get", key)
+	tx := NewTx(db.In(op).ReadConn())
+	k, err := tx.Get(key)
+	if err == nil {
+		db.Lookup(k.Exists())
+	}
+	return k, op.Done(err)
 }
 
 // Expire sets a time-to-live (ttl) for the key using a relative duration.
 // After the ttl passes, the key is expired and no longer exists.
 // Returns false is the key does not exist.
 func (db *DB) Expire(key string, ttl time.Duration) (bool, error) {
+	op := db.Observe("Key.Expire", key)
 	var ok bool
-	err := db.Update(func(tx *Tx) error {
+	err := db.In(op).Update(func(tx *Tx) error {
 		var err error
 		ok, err = tx.Expire(key, ttl)
 		return err
 	})
-	return ok, err
+	return ok, op.Done(err)
 }
 
 // ExpireAt sets an expiration time for the key. After this time,
 // the key is expired and no longer exists.
 // Returns false is the key does not exist.
 func (db *DB) ExpireAt(key string, at time.Time) (bool, error) {
+	op := db.Observe("Key.ExpireAt", key)
 	var ok bool
-	err := db.Update(func(tx *Tx) error {
+	err := db.In(op).Update(func(tx *Tx) error {
 		var err error
 		ok, err = tx.ExpireAt(key, at)
 		return err
 	})
-	return ok, err
+	return ok, op.Done(err)
+}
+
+// ExpireWith sets the expiration time of the key
+// only if the specified conditions are met (see [ExpireCmd]),
+// like ExpireWith("session").TTL(time.Hour).IfGreater().Run().
+func (db *DB) ExpireWith(key string) ExpireCmd {
+	return ExpireCmd{db: db, key: key}
 }
 
 // Persist removes the expiration time for the key.
 // Returns false is the key does not exist.
 func (db *DB) Persist(key string) (bool, error) {
+	op := db.Observe("Key.Persist", key)
 	var ok bool
-	err := db.Update(func(tx *Tx) error {
+	err := db.In(op).Update(func(tx *Tx) error {
 		var err error
 		ok, err = tx.Persist(key)
 		return err
 	})
-	return ok, err
+	return ok, op.Done(err)
+}
+
+// TTL returns the remaining time-to-live of the key.
+// See [Tx.TTL] for details.
+func (db *DB) TTL(key string) (time.Duration, error) {
+	op := db.Observe("Key.TTL", key)
+	tx := NewTx(db.In(op).ReadConn())
+	ttl, err := tx.TTL(key)
+	return ttl, op.Done(err)
+}
+
+// ExpireTime returns the expiration time of the key.
+// See [Tx.ExpireTime] for details.
+func (db *DB) ExpireTime(key string) (time.Time, error) {
+	op := db.Observe("Key.ExpireTime", key)
+	tx := NewTx(db.In(op).ReadConn())
+	at, err := tx.ExpireTime(key)
+	return at, op.Done(err)
 }
 
 // Rename changes the key name.
 // If there is an existing key with the new name, it is replaced.
 func (db *DB) Rename(key, newKey string) error {
-	err := db.Update(func(tx *Tx) error {
+	op := db.Observe("Key.Rename", key, newKey)
+	err := db.In(op).Update(func(tx *Tx) error {
 		err := tx.Rename(key, newKey)
 		return err
 	})
-	return err
+	return op.Done(err)
 }
 
 // RenameNotExists changes the key name.
 // If there is an existing key with the new name, does nothing.
 // Returns true if the key was renamed, false otherwise.
 func (db *DB) RenameNotExists(key, newKey string) (bool, error) {
+	op := db.Observe("Key.RenameNotExists", key, newKey)
 	var ok bool
-	err := db.Update(func(tx *Tx) error {
+	err := db.In(op).Update(func(tx *Tx) error {
 		var err error
 		ok, err = tx.RenameNotExists(key, newKey)
 		return err
 	})
-	return ok, err
+	return ok, op.Done(err)
+}
+
+// Copy copies the key with its value to the new key. Supports
+// strings, hashes (with the field expiration times), sorted sets,
+// streams and HyperLogLogs, and returns core.ErrKeyType for other
+// types. The copy has the same type, version and expiration
+// time as the source key. If there is an existing key with the new
+// name, replaces it if replace is true, or does nothing otherwise.
+// Returns true if the key was copied, false otherwise.
+// If the source key does not exist, returns core.ErrNotFound.
+func (db *DB) Copy(key, newKey string, replace bool) (bool, error) {
+	op := db.Observe("Key.Copy", key, newKey)
+	var ok bool
+	err := db.In(op).Update(func(tx *Tx) error {
+		var err error
+		ok, err = tx.Copy(key, newKey, replace)
+		return err
+	})
+	return ok, op.Done(err)
 }
 
 // Delete deletes keys and their values, regardless of the type.
 // Returns the number of deleted keys. Non-existing keys are ignored.
+// If the trash is enabled (see [sqlx.DB.Trash]), moves the keys
+// to the trash instead, so that they can be restored with [DB.Restore].
 func (db *DB) Delete(keys ...string) (int, error) {
+	op := db.Observe("Key.Delete", keys...)
 	var count int
-	err := db.Update(func(tx *Tx) error {
+	err := db.In(op).Update(func(tx *Tx) error {
 		var err error
 		count, err = tx.Delete(keys...)
 		return err
 	})
-	return count, err
+	return count, op.Done(err)
 }
 
-// DeleteExpired deletes keys with expired TTL, but no more than n keys.
-// If n = 0, deletes all expired keys.
-func (db *DB) DeleteExpired(n int) (count int, err error) {
-	err = db.Update(func(tx *Tx) error {
-		count, err = tx.deleteExpired(n)
+// Unlink deletes keys like Delete, but only removes the keys
+// right away, while their values are deleted later in small
+// batches (see [DB.FreeStep]). Use it to delete large hashes
+// or sorted sets without holding the write lock for long.
+// Returns the number of unlinked keys. Non-existing keys are ignored.
+// If the trash is enabled, moves the keys to the trash instead.
+func (db *DB) Unlink(keys ...string) (int, error) {
+	op := db.Observe("Key.Unlink", keys...)
+	var count int
+	err := db.In(op).Update(func(tx *Tx) error {
+		var err error
+		count, err = tx.Unlink(keys...)
 		return err
 	})
+	return count, op.Done(err)
+}
+
+// FreeStep deletes up to n values of the unlinked keys
+// in a single transaction. Returns the number of deleted
+// rows (values and keys), or 0 if there is nothing left.
+func (db *DB) FreeStep(n int) (count int, err error) {
+	op := db.Observe("Key.FreeStep")
+	err = db.In(op).Update(func(tx *Tx) error {
+		count, err = tx.freeStep(n)
+		return err
+	})
+	return count, op.Done(err)
+}
+
+// DeleteExpired deletes keys with expired TTL, but no more than n keys.
+// If n = 0, deletes all expired keys. Deletes the keys in batches,
+// each in a separate transaction, so that other writes can run
+// in between.
+func (db *DB) DeleteExpired(n int) (count int, err error) {
+	op := db.Observe("Key.DeleteExpired")
+	count, err = db.deleteExpired(op, n, expireBatchSize, time.Time{}, nil)
+	return count, op.Done(err)
+}
+
+// SweepExpired deletes keys with expired TTL in batches of the given
+// size, each in a separate transaction, until there are no expired
+// keys left or the deadline passes (it is checked between the batches).
+// If size = 0, deletes 1000 keys per batch. If the deadline is zero,
+// deletes all expired keys. If fn is not nil, calls it for each
+// deleted key after the batch is committed.
+func (db *DB) SweepExpired(size int, deadline time.Time, fn func(core.Key)) (count int, err error) {
+	op := db.Observe("Key.SweepExpired")
+	if size <= 0 {
+		size = expireBatchSize
+	}
+	count, err = db.deleteExpired(op, 0, size, deadline, fn)
+	return count, op.Done(err)
+}
+
+// deleteExpired deletes up to n expired keys (all if n = 0)
+// in batches of the given size, until the deadline (if any).
+// Calls fn (if any) for each deleted key.
+func (db *DB) deleteExpired(op *sqlx.Op, n, batchSize int, deadline time.Time, fn func(core.Key)) (count int, err error) {
+	now := db.Now().UnixMilli()
+	var cur expireCursor
+	for n == 0 || count < n {
+		if !deadline.IsZero() && count > 0 && !time.Now().Before(deadline) {
+			break
+		}
+		size := batchSize
+		if n > 0 {
+			size = min(size, n-count)
+		}
+		var deleted []core.Key
+		err = db.In(op).Update(func(tx *Tx) error {
+			var err error
+			deleted, cur, err = tx.deleteExpired(now, cur, size)
+			return err
+		})
+		if err != nil {
+			break
+		}
+		count += len(deleted)
+		if fn != nil {
+			for _, k := range deleted {
+				fn(k)
+			}
+		}
+		if len(deleted) < size {
+			break
+		}
+	}
 	return count, err
 }
 
 // DeleteAll deletes all keys and their values, effectively resetting
 // the database. Should not be run inside a database transaction.
+// If the trash is enabled, moves all the keys to the trash instead.
 func (db *DB) DeleteAll() error {
-	tx := NewTx(db.SQL)
-	return tx.DeleteAll()
+	op := db.Observe("Key.DeleteAll")
+	if db.Trash > 0 {
+		err := db.In(op).Update(func(tx *Tx) error {
+			return tx.DeleteAll()
+		})
+		return op.Done(err)
+	}
+	tx := NewTx(db.In(op).Conn())
+	return op.Done(tx.DeleteAll())
+}
+
+// Restore restores the key deleted while the trash was enabled
+// (see [sqlx.DB.Trash]), along with its value and expiration time.
+// If the key was deleted several times, restores the latest version.
+// Returns false if a key with the same name exists, or core.ErrNotFound
+// if there is no such key in the trash (or it would be expired by now).
+func (db *DB) Restore(key string) (bool, error) {
+	op := db.Observe("Key.Restore", key)
+	var ok bool
+	err := db.In(op).Update(func(tx *Tx) error {
+		var err error
+		ok, err = tx.Restore(key)
+		return err
+	})
+	return ok, op.Done(err)
+}
+
+// PurgeTrash removes the keys that have been in the trash for longer
+// than the retention (see [sqlx.DB.Trash]), or all of them if the trash
+// is disabled. The values are deleted later in batches, like with
+// [DB.Unlink]. Returns the number of purged keys.
+func (db *DB) PurgeTrash() (count int, err error) {
+	op := db.Observe("Key.PurgeTrash")
+	err = db.In(op).Update(func(tx *Tx) error {
+		count, err = tx.purgeTrash()
+		return err
+	})
+	return count, op.Done(err)
 }
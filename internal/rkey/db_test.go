@@ -1,6 +1,8 @@
 package rkey_test
 
 import (
+	"fmt"
+	"slices"
 	"testing"
 	"time"
 
@@ -59,6 +61,62 @@ func TestCount(t *testing.T) {
 	}
 }
 
+func TestCountByType(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = red.Str().Set("name", "alice")
+	_ = red.Str().Set("age", 25)
+	_, _ = red.Hash().Set("person", "name", "alice")
+	_ = red.Str().SetExpires("temp", "x", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	counts, err := db.CountByType()
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, counts, map[core.TypeID]int{
+		core.TypeString: 2,
+		core.TypeHash:   1,
+	})
+}
+
+func TestKeyspace(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	info, err := db.Keyspace()
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, info, rkey.KeyspaceInfo{})
+
+	_ = red.Str().Set("name", "alice")
+	_ = red.Str().SetExpires("age", 25, 60*time.Second)
+	_ = red.Str().SetExpires("city", "paris", 120*time.Second)
+	_ = red.Str().SetExpires("temp", "x", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	info, err = db.Keyspace()
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, info.Keys, 3)
+	testx.AssertEqual(t, info.Expires, 2)
+	testx.AssertEqual(t, info.AvgTTL > 89*time.Second && info.AvgTTL <= 90*time.Second, true)
+}
+
+func TestExpiry(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = red.Str().Set("name", "alice")
+	_ = red.Str().SetExpires("age", 25, 30*time.Second)
+	_ = red.Str().SetExpires("city", "paris", 30*time.Minute)
+	_ = red.Str().SetExpires("country", "france", 12*time.Hour)
+	_ = red.Str().SetExpires("planet", "earth", 48*time.Hour)
+	_ = red.Str().SetExpires("temp", "x", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	info, err := db.Expiry()
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, info, rkey.ExpiryInfo{Minute: 1, Hour: 2, Day: 3, Total: 4, Expired: 1})
+}
+
 func TestKeys(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -87,6 +145,96 @@ func TestKeys(t *testing.T) {
 	}
 }
 
+func TestKeysSpecial(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	for _, key := range []string{"a*", "ab", "a?", "a[1]", "a1", "a]", "a\\", "é"} {
+		_ = red.Str().Set(key, 1)
+	}
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"a\\*", []string{"a*"}},
+		{"a\\?", []string{"a?"}},
+		{"a\\[1]", []string{"a[1]"}},
+		{"a[1]", []string{"a1"}},
+		{"a[\\]]", []string{"a]"}},
+		{"a\\\\", []string{"a\\"}},
+		{"a[^b]", []string{"a*", "a?", "a1", "a]", "a\\"}},
+		{"??", []string{"a*", "ab", "a?", "a1", "a]", "a\\", "é"}},
+		{"[", []string(nil)},
+	}
+	for _, test := range tests {
+		t.Run(test.pattern, func(t *testing.T) {
+			keys, err := db.Keys(test.pattern)
+			testx.AssertNoErr(t, err)
+			var names []string
+			for _, key := range keys {
+				names = append(names, key.Key)
+			}
+			testx.AssertEqual(t, names, test.want)
+		})
+	}
+}
+
+func TestKeysPrefix(t *testing.T) {
+	// The patterns with a literal prefix select
+	// the keys using the prefix range of the key index.
+	red, db := getDB(t)
+	defer red.Close()
+
+	for _, key := range []string{"user:2", "users", "user:10", "usex", "user:1", "user;1", "user:\x00"} {
+		_ = red.Str().Set(key, 1)
+	}
+
+	t.Run("keys", func(t *testing.T) {
+		keys, err := db.Keys("user:1*")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, keyNames(keys), []string{"user:10", "user:1"})
+		keys, err = db.Keys("user:?")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, keyNames(keys), []string{"user:2", "user:1", "user:\x00"})
+	})
+	t.Run("scan", func(t *testing.T) {
+		var names []string
+		cursor := 0
+		for {
+			out, err := db.Scan(cursor, "user:*", 1)
+			testx.AssertNoErr(t, err)
+			names = append(names, keyNames(out.Keys)...)
+			if out.Cursor == 0 {
+				break
+			}
+			cursor = out.Cursor
+		}
+		testx.AssertEqual(t, names, []string{"user:2", "user:10", "user:1", "user:\x00"})
+
+		// Few keys in the range are selected by the range.
+		out, err := db.Scan(0, "user:1*", 10)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, keyNames(out.Keys), []string{"user:10", "user:1"})
+		testx.AssertEqual(t, out.More, false)
+	})
+	t.Run("nocase", func(t *testing.T) {
+		red, err := redka.Open(":memory:", &redka.Options{Collation: "nocase"})
+		testx.AssertNoErr(t, err)
+		defer red.Close()
+		_ = red.Str().Set("User:1", 1)
+		_ = red.Str().Set("uSER:2", 2)
+		_ = red.Str().Set("users", 3)
+
+		keys, err := red.Key().Keys("USER:*")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, keyNames(keys), []string{"User:1", "uSER:2"})
+		out, err := red.Key().Scan(0, "user:*", 10)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, keyNames(out.Keys), []string{"User:1", "uSER:2"})
+	})
+}
+
 func TestScan(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -128,6 +276,57 @@ func TestScan(t *testing.T) {
 	}
 }
 
+func TestScanType(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = red.Str().Set("s1", "11")
+	_, _ = red.Hash().Set("h1", "f", "v")
+	_ = red.Str().Set("s2", "22")
+	_, _ = red.Hash().Set("h2", "f", "v")
+	_, _ = red.SortedSet().Add("z1", "one", 1)
+
+	tests := []struct {
+		name   string
+		cursor int
+		typ    core.TypeID
+		count  int
+
+		wantCursor int
+		wantKeys   []string
+		wantMore   bool
+	}{
+		{"all types", 0, 0, 10, 5, []string{"s1", "h1", "s2", "h2", "z1"}, false},
+		{"strings", 0, core.TypeString, 10, 3, []string{"s1", "s2"}, false},
+		{"hashes 1st", 0, core.TypeHash, 1, 2, []string{"h1"}, true},
+		{"hashes 2nd", 2, core.TypeHash, 1, 4, []string{"h2"}, false},
+		{"exact page", 0, core.TypeHash, 2, 4, []string{"h1", "h2"}, false},
+		{"no keys", 0, core.TypeStream, 10, 0, []string{}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, err := db.ScanType(test.cursor, "*", test.typ, test.count)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, out.Cursor, test.wantCursor)
+			testx.AssertEqual(t, out.More, test.wantMore)
+			keyNames := make([]string, len(out.Keys))
+			for i, key := range out.Keys {
+				keyNames[i] = key.Key
+			}
+			testx.AssertEqual(t, keyNames, test.wantKeys)
+		})
+	}
+	t.Run("scanner", func(t *testing.T) {
+		var keyNames []string
+		sc := db.ScannerType("*", core.TypeHash, 1)
+		for sc.Scan() {
+			keyNames = append(keyNames, sc.Key().Key)
+		}
+		testx.AssertNoErr(t, sc.Err())
+		testx.AssertEqual(t, keyNames, []string{"h1", "h2"})
+	})
+}
+
 func TestScanner(t *testing.T) {
 	red, _ := getDB(t)
 	defer red.Close()
@@ -154,6 +353,87 @@ func TestScanner(t *testing.T) {
 	testx.AssertEqual(t, keyNames, []string{"11", "12", "21", "22", "31"})
 }
 
+func TestBinaryKeys(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = red.Str().Set("a\x00b", 1)
+	_ = red.Str().Set("a\x00c", 2)
+	_ = red.Str().Set("\xff\xfe", 3)
+	_ = red.Str().Set("abc", 4)
+
+	t.Run("get", func(t *testing.T) {
+		key, err := db.Get("a\x00b")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, key.Key, "a\x00b")
+		ok, _ := db.Exists("a")
+		testx.AssertEqual(t, ok, false)
+		val, _ := red.Str().Get("\xff\xfe")
+		testx.AssertEqual(t, val.String(), "3")
+	})
+	t.Run("keys", func(t *testing.T) {
+		tests := []struct {
+			pattern string
+			want    []string
+		}{
+			{"a*", []string{"a\x00b", "a\x00c", "abc"}},
+			{"*b", []string{"a\x00b"}},
+			{"a?c", []string{"a\x00c", "abc"}},
+			{"a\x00*", []string{"a\x00b", "a\x00c"}},
+			{"\xff*", []string{"\xff\xfe"}},
+		}
+		for _, test := range tests {
+			keys, err := db.Keys(test.pattern)
+			testx.AssertNoErr(t, err)
+			keyNames := make([]string, len(keys))
+			for i, key := range keys {
+				keyNames[i] = key.Key
+			}
+			testx.AssertEqual(t, keyNames, test.want)
+		}
+	})
+	t.Run("scan", func(t *testing.T) {
+		// The first page only has a key with a NUL byte
+		// that does not match, so Scan moves on.
+		out, err := db.Scan(0, "*c", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, out.Cursor, 2)
+		testx.AssertEqual(t, out.Keys[0].Key, "a\x00c")
+		out, err = db.Scan(out.Cursor, "*c", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, out.Keys[0].Key, "abc")
+		out, err = db.Scan(out.Cursor, "*c", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(out.Keys), 0)
+	})
+}
+
+func TestIter(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = red.Str().Set("k11", "11")
+	_ = red.Str().Set("k12", "12")
+	_ = red.Str().Set("k21", "21")
+
+	var keys []string
+	for key, err := range db.Iter("k1*") {
+		testx.AssertNoErr(t, err)
+		keys = append(keys, key.Key)
+	}
+	testx.AssertEqual(t, keys, []string{"k11", "k12"})
+
+	t.Run("scanner", func(t *testing.T) {
+		sc := db.Scanner("k2*", 1)
+		keys = nil
+		for key := range sc.All() {
+			keys = append(keys, key.Key)
+		}
+		testx.AssertNoErr(t, sc.Err())
+		testx.AssertEqual(t, keys, []string{"k21"})
+	})
+}
+
 func TestRandom(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -201,6 +481,161 @@ func TestGet(t *testing.T) {
 	}
 }
 
+func TestInspect(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = red.Str().Set("name", "alice")
+	_, _ = red.Hash().Set("person", "name", "alice")
+	_, _ = red.Hash().Set("person", "age", 25)
+	_, _ = red.SortedSet().Add("scores", "one", 1)
+	_, _ = red.SortedSet().Add("scores", "two", 2)
+
+	tests := []struct {
+		name string
+		key  string
+		typ  core.TypeID
+		size int
+		len  int
+	}{
+		{"string", "name", core.TypeString, 4 + 5, 1},
+		{"hash", "person", core.TypeHash, 6 + (4 + 5) + (3 + 2), 2},
+		{"sorted set", "scores", core.TypeSortedSet, 6 + (3 + 8) + (3 + 8), 2},
+		{"not found", "key1", 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			info, err := db.Inspect(test.key)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, info.Exists(), test.len > 0)
+			testx.AssertEqual(t, info.Type, test.typ)
+			testx.AssertEqual(t, info.Size, test.size)
+			testx.AssertEqual(t, info.Len, test.len)
+			testx.AssertEqual(t, info.Idle >= 0 && info.Idle < time.Minute, true)
+		})
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	check := func(red *redka.DB, key string, prev core.Key) error {
+		return red.View(func(tx *redka.Tx) error {
+			return tx.Key().CheckVersion(key, prev)
+		})
+	}
+
+	t.Run("same version", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("name", "alice")
+		_ = red.Str().Set("name", "bob")
+
+		k, _ := db.Get("name")
+		testx.AssertEqual(t, check(red, "name", k), nil)
+	})
+	t.Run("changed", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("name", "alice")
+
+		k, _ := db.Get("name")
+		_ = red.Str().Set("name", "bob")
+		testx.AssertEqual(t, check(red, "name", k), core.ErrVersion)
+	})
+	t.Run("not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		k, _ := db.Get("name")
+		testx.AssertEqual(t, check(red, "name", k), nil)
+	})
+	t.Run("created", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		k, _ := db.Get("name")
+		_ = red.Str().Set("name", "alice")
+		testx.AssertEqual(t, check(red, "name", k), core.ErrVersion)
+	})
+	t.Run("deleted", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("name", "alice")
+
+		k, _ := db.Get("name")
+		_, _ = db.Delete("name")
+		testx.AssertEqual(t, check(red, "name", k), core.ErrVersion)
+	})
+	t.Run("recreated", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("name", "alice")
+
+		k, _ := db.Get("name")
+		_, _ = db.Delete("name")
+		_ = red.Str().Set("name", "alice")
+
+		// Same version, but a different key.
+		now, _ := db.Get("name")
+		testx.AssertEqual(t, now.Version, k.Version)
+		testx.AssertEqual(t, now.ID != k.ID, true)
+		testx.AssertEqual(t, check(red, "name", k), core.ErrVersion)
+	})
+}
+
+func TestStamp(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = red.Str().Set("cart:1:apple", 1)
+	_ = red.Str().Set("cart:1:pear", 2)
+	_ = red.Str().Set("cart:2:plum", 3)
+
+	stamp, err := db.Stamp("cart:1:*")
+	testx.AssertNoErr(t, err)
+
+	same, _ := db.Stamp("cart:1:*")
+	testx.AssertEqual(t, same, stamp)
+
+	// Changes to other keys do not affect the stamp.
+	_ = red.Str().Set("cart:2:plum", 4)
+	same, _ = db.Stamp("cart:1:*")
+	testx.AssertEqual(t, same, stamp)
+
+	changes := []struct {
+		name string
+		fn   func()
+	}{
+		{"set", func() { _ = red.Str().Set("cart:1:apple", 5) }},
+		{"create", func() { _ = red.Str().Set("cart:1:kiwi", 6) }},
+		{"delete", func() { _, _ = db.Delete("cart:1:kiwi") }},
+		{"expire", func() { _, _ = db.Expire("cart:1:pear", time.Minute) }},
+		{"rename", func() { _ = db.Rename("cart:1:pear", "cart:2:pear") }},
+	}
+	for _, change := range changes {
+		t.Run(change.name, func(t *testing.T) {
+			change.fn()
+			got, err := db.Stamp("cart:1:*")
+			testx.AssertNoErr(t, err)
+			if got == stamp {
+				t.Errorf("want changed stamp, got %d", got)
+			}
+			stamp = got
+		})
+	}
+
+	t.Run("check", func(t *testing.T) {
+		err := red.View(func(tx *redka.Tx) error {
+			return tx.Key().CheckStamp("cart:1:*", stamp)
+		})
+		testx.AssertNoErr(t, err)
+		err = red.View(func(tx *redka.Tx) error {
+			return tx.Key().CheckStamp("cart:1:*", stamp+1)
+		})
+		testx.AssertEqual(t, err, core.ErrVersion)
+	})
+}
+
 func TestExpire(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -249,6 +684,120 @@ func TestExpireAt(t *testing.T) {
 	}
 }
 
+func TestExpireWith(t *testing.T) {
+	// etime returns the expiration time of the key in seconds (or 0).
+	etime := func(db *rkey.DB, key string) int64 {
+		k, _ := db.Get(key)
+		if k.ETime == nil {
+			return 0
+		}
+		return *k.ETime / 1000
+	}
+	now := time.Now()
+	soon, later := now.Add(10*time.Second), now.Add(20*time.Second)
+
+	tests := []struct {
+		name string
+		cmd  func(c rkey.ExpireCmd) rkey.ExpireCmd
+		// TTL of the "temp" and "perm" keys after the command.
+		temp, perm time.Time
+	}{
+		{
+			name: "no conditions",
+			cmd:  func(c rkey.ExpireCmd) rkey.ExpireCmd { return c.At(later) },
+			temp: later, perm: later,
+		},
+		{
+			name: "nx",
+			cmd:  func(c rkey.ExpireCmd) rkey.ExpireCmd { return c.At(later).IfNoTTL() },
+			temp: soon, perm: later,
+		},
+		{
+			name: "xx",
+			cmd:  func(c rkey.ExpireCmd) rkey.ExpireCmd { return c.At(later).IfTTL() },
+			temp: later, perm: time.Time{},
+		},
+		{
+			name: "gt",
+			cmd:  func(c rkey.ExpireCmd) rkey.ExpireCmd { return c.At(later).IfGreater() },
+			temp: later, perm: time.Time{},
+		},
+		{
+			name: "gt not greater",
+			cmd:  func(c rkey.ExpireCmd) rkey.ExpireCmd { return c.At(now.Add(5 * time.Second)).IfGreater() },
+			temp: soon, perm: time.Time{},
+		},
+		{
+			name: "lt",
+			cmd:  func(c rkey.ExpireCmd) rkey.ExpireCmd { return c.At(now.Add(5 * time.Second)).IfLess() },
+			temp: now.Add(5 * time.Second), perm: now.Add(5 * time.Second),
+		},
+		{
+			name: "lt not less",
+			cmd:  func(c rkey.ExpireCmd) rkey.ExpireCmd { return c.At(later).IfLess() },
+			temp: soon, perm: later,
+		},
+		{
+			name: "xx lt",
+			cmd:  func(c rkey.ExpireCmd) rkey.ExpireCmd { return c.At(now.Add(5 * time.Second)).IfTTL().IfLess() },
+			temp: now.Add(5 * time.Second), perm: time.Time{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			red, db := getDB(t)
+			defer red.Close()
+			_ = red.Str().Set("perm", "alice")
+			_ = red.Str().Set("temp", "bob")
+			_, _ = db.ExpireAt("temp", soon)
+
+			for _, key := range []string{"temp", "perm"} {
+				_, err := test.cmd(db.ExpireWith(key)).Run()
+				testx.AssertNoErr(t, err)
+			}
+
+			want := func(at time.Time) int64 {
+				if at.IsZero() {
+					return 0
+				}
+				return at.UnixMilli() / 1000
+			}
+			testx.AssertEqual(t, etime(db, "temp"), want(test.temp))
+			testx.AssertEqual(t, etime(db, "perm"), want(test.perm))
+		})
+	}
+
+	t.Run("result", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("name", "alice")
+
+		ok, err := db.ExpireWith("name").TTL(time.Minute).IfNoTTL().Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		ok, err = db.ExpireWith("name").TTL(time.Minute).IfNoTTL().Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+		ok, err = db.ExpireWith("missing").TTL(time.Minute).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+	})
+	t.Run("tx", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("name", "alice")
+
+		err := red.Update(func(tx *redka.Tx) error {
+			ok, err := tx.Key().ExpireWith("name").TTL(time.Minute).IfTTL().Run()
+			testx.AssertEqual(t, ok, false)
+			return err
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, etime(db, "name"), int64(0))
+	})
+}
+
 func TestPersist(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -270,6 +819,58 @@ func TestPersist(t *testing.T) {
 	}
 }
 
+func TestTTL(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = red.Str().SetExpires("name", "alice", time.Minute)
+	_ = red.Str().Set("age", 25)
+
+	t.Run("expiring", func(t *testing.T) {
+		ttl, err := db.TTL("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ttl > 59*time.Second && ttl <= time.Minute, true)
+	})
+	t.Run("persistent", func(t *testing.T) {
+		ttl, err := db.TTL("age")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ttl, rkey.NoExpireTTL)
+	})
+	t.Run("not found", func(t *testing.T) {
+		ttl, err := db.TTL("city")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ttl, rkey.NoKeyTTL)
+	})
+}
+
+func TestExpireTime(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	at := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	_ = red.Str().Set("name", "alice")
+	_, _ = db.ExpireAt("name", at)
+	_ = red.Str().Set("age", 25)
+
+	t.Run("expiring", func(t *testing.T) {
+		got, err := db.ExpireTime("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, got.Equal(at), true)
+	})
+	t.Run("persistent", func(t *testing.T) {
+		got, err := db.ExpireTime("age")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, got.Equal(rkey.NoExpireTime), true)
+		testx.AssertEqual(t, got.UnixMilli(), int64(-1))
+	})
+	t.Run("not found", func(t *testing.T) {
+		got, err := db.ExpireTime("city")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, got.Equal(rkey.NoKeyTime), true)
+		testx.AssertEqual(t, got.UnixMilli(), int64(-2))
+	})
+}
+
 func TestRename(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -352,6 +953,142 @@ func TestRenameNotExists(t *testing.T) {
 	})
 }
 
+func TestCopy(t *testing.T) {
+	t.Run("string", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().SetExpires("name", "alice", time.Hour)
+		ok, err := db.Copy("name", "title", false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+
+		src, _ := db.Get("name")
+		dst, _ := db.Get("title")
+		testx.AssertEqual(t, dst.Type, core.TypeString)
+		testx.AssertEqual(t, dst.Version, src.Version)
+		testx.AssertEqual(t, *dst.ETime, *src.ETime)
+		testx.AssertEqual(t, dst.ID != src.ID, true)
+		title, _ := red.Str().Get("title")
+		testx.AssertEqual(t, title.String(), "alice")
+	})
+	t.Run("hash", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, _ = red.Hash().SetMany("person", map[string]any{"name": "alice", "age": 25})
+		ok, err := db.Copy("person", "user", false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+
+		// The copy is independent of the source.
+		_, _ = red.Hash().Set("user", "name", "bob")
+		user, _ := red.Hash().Items("user")
+		testx.AssertEqual(t, user["name"].String(), "bob")
+		testx.AssertEqual(t, user["age"].String(), "25")
+		name, _ := red.Hash().Get("person", "name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("hash field ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, _ = red.Hash().SetMany("person", map[string]any{"name": "alice", "age": 25})
+		_, _ = red.Hash().FieldExpire("person", time.Minute, "age")
+		ok, err := db.Copy("person", "user", false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+
+		ttls, _ := red.Hash().FieldTTL("user", "name", "age")
+		testx.AssertEqual(t, ttls["name"], time.Duration(0))
+		testx.AssertEqual(t, ttls["age"] > 0, true)
+	})
+	t.Run("sorted set", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, _ = red.SortedSet().AddMany("scores", map[any]float64{"alice": 11, "bob": 22})
+		ok, err := db.Copy("scores", "backup", false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		score, _ := red.SortedSet().GetScore("backup", "bob")
+		testx.AssertEqual(t, score, 22.0)
+		n, _ := red.SortedSet().Len("backup")
+		testx.AssertEqual(t, n, 2)
+	})
+	t.Run("stream", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, _ = red.Stream().Add("events", "user", "alice")
+		ok, err := db.Copy("events", "backup", false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		n, _ := red.Stream().Len("backup")
+		testx.AssertEqual(t, n, 1)
+	})
+	t.Run("same name", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().Set("name", "alice")
+		ok, err := db.Copy("name", "name", true)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+		name, _ := red.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("src does not exist", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		ok, err := db.Copy("key1", "key2", false)
+		testx.AssertEqual(t, err, core.ErrNotFound)
+		testx.AssertEqual(t, ok, false)
+	})
+	t.Run("dst exists", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().Set("name", "alice")
+		_, _ = red.Hash().Set("person", "name", "bob")
+		ok, err := db.Copy("name", "person", false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+		name, _ := red.Hash().Get("person", "name")
+		testx.AssertEqual(t, name.String(), "bob")
+	})
+	t.Run("replace", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().Set("name", "alice")
+		_, _ = red.Hash().Set("person", "name", "bob")
+		ok, err := db.Copy("name", "person", true)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		key, _ := db.Get("person")
+		testx.AssertEqual(t, key.Type, core.TypeString)
+		person, _ := red.Str().Get("person")
+		testx.AssertEqual(t, person.String(), "alice")
+	})
+	t.Run("unsupported type", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		// There is no list repository, so create the key directly.
+		_, _ = red.SQL.Exec(
+			"insert into rkey (key, type, version, mtime) values ('tasks', ?, 1, 0)",
+			core.TypeList)
+		_ = red.Str().Set("backup", "alice")
+		ok, err := db.Copy("tasks", "backup", true)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, ok, false)
+		backup, _ := red.Str().Get("backup")
+		testx.AssertEqual(t, backup.String(), "alice")
+	})
+}
+
 func TestDelete(t *testing.T) {
 	tests := []struct {
 		name string
@@ -385,6 +1122,48 @@ func TestDelete(t *testing.T) {
 	}
 }
 
+func TestUnlink(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	for i := range 25 {
+		_, _ = red.Hash().Set("person", fmt.Sprintf("field%d", i), i)
+	}
+	_ = red.Str().Set("name", "alice")
+	_ = red.Str().SetExpires("age", 25, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	count, err := db.Unlink("person", "name", "age", "city")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, count, 2)
+
+	// The keys are gone right away.
+	count, _ = db.Count("person", "name")
+	testx.AssertEqual(t, count, 0)
+	keys, _ := db.Keys("*")
+	testx.AssertEqual(t, len(keys), 0)
+
+	// Expired keys sweep skips the unlinked keys.
+	_, err = db.DeleteExpired(0)
+	testx.AssertNoErr(t, err)
+
+	// The values are deleted in batches.
+	var rows int
+	_ = red.SQL.QueryRow("select count(*) from rhash").Scan(&rows)
+	testx.AssertEqual(t, rows, 25)
+	n, err := db.FreeStep(10)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, n, 10)
+	for n > 0 {
+		n, err = db.FreeStep(10)
+		testx.AssertNoErr(t, err)
+	}
+	_ = red.SQL.QueryRow("select count(*) from rhash").Scan(&rows)
+	testx.AssertEqual(t, rows, 0)
+	_ = red.SQL.QueryRow("select count(*) from rkey").Scan(&rows)
+	testx.AssertEqual(t, rows, 0)
+}
+
 func TestDeleteExpired(t *testing.T) {
 	t.Run("delete all", func(t *testing.T) {
 		red, _ := getDB(t)
@@ -417,6 +1196,69 @@ func TestDeleteExpired(t *testing.T) {
 	})
 }
 
+func TestSweepExpired(t *testing.T) {
+	t.Run("sweep all", func(t *testing.T) {
+		red, _ := getDB(t)
+		defer red.Close()
+		db := rkey.New(red.SQL)
+
+		_ = red.Str().SetExpires("name", "alice", 1*time.Millisecond)
+		_ = red.Str().SetExpires("age", 25, 1*time.Millisecond)
+		_ = red.Str().SetExpires("city", "paris", 1*time.Millisecond)
+
+		time.Sleep(2 * time.Millisecond)
+		var keys []string
+		count, err := db.SweepExpired(2, time.Time{}, func(k core.Key) {
+			keys = append(keys, k.Key)
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 3)
+		slices.Sort(keys)
+		testx.AssertEqual(t, keys, []string{"age", "city", "name"})
+	})
+	t.Run("pages", func(t *testing.T) {
+		red, _ := getDB(t)
+		defer red.Close()
+		db := rkey.New(red.SQL)
+
+		// More expired keys than one page holds, some with
+		// the same etime, so the pages continue by etime and id.
+		past := time.Now().Add(-time.Minute)
+		for i := range 10 {
+			key := fmt.Sprintf("key%d", i)
+			_ = red.Str().Set(key, i)
+			_, _ = db.ExpireAt(key, past.Add(time.Duration(i/4)*time.Millisecond))
+		}
+		_ = red.Str().SetExpires("live", "alice", time.Hour)
+
+		count, err := db.SweepExpired(3, time.Time{}, nil)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 10)
+
+		var n int
+		err = red.SQL.QueryRow("select count(*) from rkey").Scan(&n)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 1)
+		exists, _ := db.Exists("live")
+		testx.AssertEqual(t, exists, true)
+	})
+	t.Run("deadline", func(t *testing.T) {
+		red, _ := getDB(t)
+		defer red.Close()
+		db := rkey.New(red.SQL)
+
+		_ = red.Str().SetExpires("name", "alice", 1*time.Millisecond)
+		_ = red.Str().SetExpires("age", 25, 1*time.Millisecond)
+		_ = red.Str().SetExpires("city", "paris", 1*time.Millisecond)
+
+		// The first batch is deleted even if the deadline has passed.
+		time.Sleep(2 * time.Millisecond)
+		count, err := db.SweepExpired(2, time.Now(), nil)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 2)
+	})
+}
+
 func TestDeleteAll(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -431,6 +1273,144 @@ func TestDeleteAll(t *testing.T) {
 	testx.AssertEqual(t, count, 0)
 }
 
+func TestTrash(t *testing.T) {
+	now := time.Now()
+	getDB := func(t *testing.T) (*redka.DB, *rkey.DB) {
+		red, err := redka.Open(":memory:", &redka.Options{
+			TrashRetention: time.Hour,
+			Clock:          func() time.Time { return now },
+		})
+		testx.AssertNoErr(t, err)
+		return red, red.Key()
+	}
+
+	t.Run("delete and restore", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().SetExpires("name", "alice", time.Minute)
+		_, _ = red.Hash().Set("person", "name", "alice")
+
+		count, err := db.Delete("name", "person", "city")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 2)
+		count, _ = db.Count("name", "person")
+		testx.AssertEqual(t, count, 0)
+		keys, _ := db.Keys("*")
+		testx.AssertEqual(t, len(keys), 0)
+
+		ok, err := db.Restore("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		val, _ := red.Str().Get("name")
+		testx.AssertEqual(t, val.String(), "alice")
+		key, _ := db.Get("name")
+		testx.AssertEqual(t, *key.ETime, now.Add(time.Minute).UnixMilli())
+
+		ok, err = db.Restore("person")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		hval, _ := red.Hash().Get("person", "name")
+		testx.AssertEqual(t, hval.String(), "alice")
+
+		_, err = db.Restore("name")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("latest version", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().Set("name", "alice")
+		_, _ = db.Delete("name")
+		_ = red.Str().Set("name", "bob")
+
+		// The key exists, so the trashed one stays in the trash.
+		ok, err := db.Restore("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+
+		_, _ = db.Unlink("name")
+		ok, _ = db.Restore("name")
+		testx.AssertEqual(t, ok, true)
+		val, _ := red.Str().Get("name")
+		testx.AssertEqual(t, val.String(), "bob")
+	})
+	t.Run("delete all", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().Set("name", "alice")
+		_ = red.Str().Set("age", 25)
+		_, _ = db.Delete("name")
+
+		err := db.DeleteAll()
+		testx.AssertNoErr(t, err)
+		count, _ := db.Count("name", "age")
+		testx.AssertEqual(t, count, 0)
+
+		ok, _ := db.Restore("age")
+		testx.AssertEqual(t, ok, true)
+		ok, _ = db.Restore("name")
+		testx.AssertEqual(t, ok, true)
+		count, _ = db.Count("name", "age")
+		testx.AssertEqual(t, count, 2)
+	})
+	t.Run("expired", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().SetExpires("name", "alice", time.Millisecond)
+		_, _ = db.Delete("name")
+		defer func() { now = time.Now() }()
+		now = now.Add(time.Second)
+
+		// The trashed keys are not expired keys.
+		info, _ := db.Expiry()
+		testx.AssertEqual(t, info.Expired, 0)
+		count, _ := db.DeleteExpired(0)
+		testx.AssertEqual(t, count, 0)
+
+		// The key would be expired by now.
+		_, err := db.Restore("name")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("purge", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().Set("name", "alice")
+		_, _ = db.Delete("name")
+		count, err := db.PurgeTrash()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 0)
+
+		defer func() { now = time.Now() }()
+		now = now.Add(time.Hour)
+		count, err = db.PurgeTrash()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+		_, err = db.Restore("name")
+		testx.AssertErr(t, err, core.ErrNotFound)
+
+		n, err := db.FreeStep(100)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 2)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().Set("name", "alice")
+		_, err := db.Restore("name")
+		testx.AssertErr(t, err, core.ErrNotFound)
+
+		// The trash is purged when disabled.
+		_, _ = db.Delete("name")
+		count, _ := rkey.New(red.SQL).PurgeTrash()
+		testx.AssertEqual(t, count, 1)
+	})
+}
+
 func getDB(tb testing.TB) (*redka.DB, *rkey.DB) {
 	tb.Helper()
 	red, err := redka.Open(":memory:", nil)
@@ -439,3 +1419,11 @@ func getDB(tb testing.TB) (*redka.DB, *rkey.DB) {
 	}
 	return red, red.Key()
 }
+
+func keyNames(keys []core.Key) []string {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.Key
+	}
+	return names
+}
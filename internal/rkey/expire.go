@@ -0,0 +1,118 @@
+package rkey
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+const sqlExpireIf = `
+update rkey set etime = :at
+where key = :key and (etime is null or etime > :now)
+  and (not :nx or etime is null)
+  and (not :xx or etime is not null)
+  and (not :gt or (etime is not null and :at > etime))
+  and (not :lt or etime is null or :at < etime)`
+
+// ExpireCmd sets the expiration time of a key
+// only if the specified conditions are met.
+// The conditions can be combined (all of them must be met).
+type ExpireCmd struct {
+	db  *DB
+	tx  *Tx
+	key string
+	ttl time.Duration
+	at  time.Time
+	nx  bool
+	xx  bool
+	gt  bool
+	lt  bool
+}
+
+// TTL sets the time-to-live for the key using a relative duration.
+func (c ExpireCmd) TTL(ttl time.Duration) ExpireCmd {
+	c.ttl = ttl
+	c.at = time.Time{}
+	return c
+}
+
+// At sets the expiration time for the key.
+func (c ExpireCmd) At(at time.Time) ExpireCmd {
+	c.at = at
+	c.ttl = 0
+	return c
+}
+
+// IfNoTTL sets the expiration time only if the key
+// does not have one (like NX in Redis).
+func (c ExpireCmd) IfNoTTL() ExpireCmd {
+	c.nx = true
+	return c
+}
+
+// IfTTL sets the expiration time only if the key
+// already has one (like XX in Redis).
+func (c ExpireCmd) IfTTL() ExpireCmd {
+	c.xx = true
+	return c
+}
+
+// IfGreater sets the expiration time only if it is later than
+// the current one (like GT in Redis). A key without an expiration
+// time is considered to live forever, so it is never updated.
+func (c ExpireCmd) IfGreater() ExpireCmd {
+	c.gt = true
+	return c
+}
+
+// IfLess sets the expiration time only if it is earlier than
+// the current one (like LT in Redis). A key without an expiration
+// time is considered to live forever, so it is always updated.
+func (c ExpireCmd) IfLess() ExpireCmd {
+	c.lt = true
+	return c
+}
+
+// Run sets the expiration time of the key if the conditions are met.
+// Returns false if the key does not exist or the conditions are not met.
+func (c ExpireCmd) Run() (bool, error) {
+	if c.db != nil {
+		op := c.db.Observe("Key.ExpireWith", c.key)
+		var ok bool
+		err := c.db.In(op).Update(func(tx *Tx) error {
+			var err error
+			ok, err = c.run(tx.tx)
+			return err
+		})
+		return ok, op.Done(err)
+	}
+	if c.tx != nil {
+		return c.run(c.tx.tx)
+	}
+	return false, nil
+}
+
+// run sets the expiration time of the key in a transaction.
+func (c ExpireCmd) run(tx sqlx.Tx) (bool, error) {
+	now := sqlx.Now(tx)
+	at := c.at
+	if at.IsZero() {
+		at = now.Add(c.ttl)
+	}
+	args := []any{
+		sql.Named("key", c.key),
+		sql.Named("now", now.UnixMilli()),
+		sql.Named("at", at.UnixMilli()),
+		sql.Named("nx", c.nx),
+		sql.Named("xx", c.xx),
+		sql.Named("gt", c.gt),
+		sql.Named("lt", c.lt),
+	}
+	res, err := tx.Exec(sqlExpireIf, args...)
+	if err != nil {
+		return false, err
+	}
+	count, _ := res.RowsAffected()
+	return count > 0, nil
+}
@@ -2,7 +2,13 @@ package rkey
 
 import (
 	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"iter"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nalgeon/redka/internal/core"
@@ -18,20 +24,75 @@ const sqlCount = `
 select count(id) from rkey
 where key in (:keys) and (etime is null or etime > :now)`
 
+const sqlKeyspace = `
+select
+  (select count(*) from rkey) - (select count(*) from rkey where etime <= ?1),
+  count(*), coalesce(avg(etime - ?1), 0)
+from rkey
+where etime > ?1`
+
+const sqlExpiry = `
+select
+  coalesce(sum(etime > ?1 and etime <= ?1 + 60000), 0),
+  coalesce(sum(etime > ?1 and etime <= ?1 + 3600000), 0),
+  coalesce(sum(etime > ?1 and etime <= ?1 + 86400000), 0),
+  coalesce(sum(etime > ?1), 0),
+  coalesce(sum(etime <= ?1), 0)
+from rkey
+where etime is not null
+  and id not in (select key_id from rtrash)`
+
+const sqlCountByType = `
+select type, count(id) from rkey
+where etime is null or etime > ?
+group by type`
+
+// The keys queries select the keys in the prefix range
+// of the pattern (%s is sqlKeyRange or empty, see keyRange).
 const sqlKeys = `
 select id, key, type, version, etime, mtime from rkey
-where key glob :pattern and (etime is null or etime > :now)`
+where %s(key glob :pattern or instr(cast(key as blob), x'00') > 0)
+  and (etime is null or etime > :now)
+order by id`
 
 const sqlScan = `
 select id, key, type, version, etime, mtime from rkey
-where id > :cursor and key glob :pattern and (etime is null or etime > :now)
+where %sid > :cursor
+  and (key glob :pattern or instr(cast(key as blob), x'00') > 0)
+  and (:type = 0 or type = :type)
+  and (etime is null or etime > :now)
+order by id
 limit :count`
 
+const sqlKeyRange = `key >= :lo and key < :hi and `
+
+const sqlKeyRangeCount = `
+select count(*) from (
+  select 1 from rkey where key >= :lo and key < :hi limit :max
+)`
+
+const sqlKeyRangeNext = `
+select count(*) from (
+  select 1 from (
+    select key from rkey where id > :cursor order by id limit :max
+  )
+  where key >= :lo and key < :hi limit :count
+)`
+
 const sqlRandom = `
 select id, key, type, version, etime, mtime from rkey
 where etime is null or etime > ?
 order by random() limit 1`
 
+const sqlRandomCount = `
+select count(id) from rkey
+where etime is null or etime > ?`
+
+const sqlRandomAt = `
+select id, key, type, version, etime, mtime from rkey
+where etime is null or etime > ?
+order by id limit 1 offset ?`
+
 const sqlExpire = `
 update rkey set etime = :at
 where key = :key and (etime is null or etime > :now)`
@@ -57,6 +118,19 @@ where rkey.key = :key and (
   rkey.etime is null or rkey.etime > :now
 )`
 
+const sqlCopy = `
+insert into rkey (id, key, type, version, etime, mtime)
+select ` + sqlx.NextKeyID + `, :new_key, type, version, etime, :now
+from rkey where id = :id`
+
+const sqlInspect = `
+select count(*), coalesce(sum(%s), 0)
+from %s where key_id = ?`
+
+const sqlCopyValues = `
+insert into %[1]s (key_id, %[2]s)
+select ?, %[2]s from %[1]s where key_id = ?`
+
 const sqlDelete = `
 delete from rkey where key in (:keys)
   and (etime is null or etime > :now)`
@@ -71,20 +145,172 @@ const sqlDeleteAll = `
   vacuum;
   pragma integrity_check;`
 
-const sqlDeleteAllExpired = `
-delete from rkey
-where etime <= :now`
+// Selects a page of expired keys using the partial
+// index on etime. Keyset pagination (by etime and id)
+// skips the keys already seen by the previous pages.
+const sqlExpiredPage = `
+select id, key, type, version, etime, mtime from rkey
+where etime <= :now
+  and (etime > :etime or (etime = :etime and id > :id))
+  and id not in (select key_id from rfree)
+  and id not in (select key_id from rtrash)
+order by etime, id
+limit :n`
+
+const sqlDeleteIDs = `delete from rkey where id in (:ids)`
+
+const sqlUnlinkSelect = `
+select id from rkey where key in (:keys)
+  and (etime is null or etime > :now)`
+
+// Unlinked keys are renamed and expired,
+// so they are hidden from the readers and their names
+// are free to reuse, until the values are deleted.
+const sqlUnlink = `
+update rkey set
+  key = :tomb,
+  version = version+1,
+  etime = 0,
+  mtime = :now
+where id = :id`
+
+const sqlFreeAdd = `insert into rfree (key_id) values (?)`
+
+const sqlFreeNext = `
+select rfree.key_id, rkey.type
+from rfree join rkey on rkey.id = rfree.key_id
+limit 1`
 
-const sqlDeleteNExpired = `
-delete from rkey
+const sqlFreeValues = `
+delete from %s
 where rowid in (
-  select rowid from rkey
-  where etime <= :now
-  limit :n
+  select rowid from %s
+  where key_id = ?
+  limit ?
 )`
 
+const sqlFreeKey = `delete from rkey where id = ?`
+
+const sqlTrashSelect = `
+select id, key, etime from rkey where key in (:keys)
+  and (etime is null or etime > :now)`
+
+const sqlTrashAdd = `
+insert into rtrash (key_id, key, etime, dtime)
+values (?, ?, ?, ?)`
+
+// Trashes all the existing keys: records them in rtrash,
+// then renames and expires the recorded keys that are
+// not expired yet (the ones trashed earlier already are).
+const sqlTrashAll = `
+insert into rtrash (key_id, key, etime, dtime)
+select id, key, etime, :now from rkey
+where etime is null or etime > :now`
+
+const sqlTrashAllKeys = `
+update rkey set
+  key = :prefix || id,
+  version = version+1,
+  etime = 0,
+  mtime = :now
+where id in (select key_id from rtrash)
+  and (etime is null or etime > :now)`
+
+// Selects the latest trashed key with the name
+// that would not be expired if restored.
+const sqlTrashGet = `
+select key_id, etime from rtrash
+where key = :key and (etime is null or etime > :now)
+order by dtime desc, key_id desc
+limit 1`
+
+const sqlRestore = `
+update rkey set
+  key = :key,
+  version = version+1,
+  etime = :etime,
+  mtime = :now
+where id = :id`
+
+const sqlTrashRemove = `delete from rtrash where key_id = ?`
+
+// Purged keys are handed over to the lazy free
+// (see freeStep), which deletes their values in batches.
+const sqlTrashPurge = `
+insert into rfree (key_id)
+select key_id from rtrash where dtime <= ?`
+
+const sqlTrashPurged = `
+delete from rtrash
+where key_id in (select key_id from rfree)`
+
+// unlinkPrefix is the name prefix of the unlinked keys.
+const unlinkPrefix = "\x00unlink:"
+
+// trashPrefix is the name prefix of the trashed keys.
+const trashPrefix = "\x00trash:"
+
+// valueTables are the tables with the values of each key type.
+var valueTables = map[core.TypeID]string{
+	core.TypeString:    "rstring",
+	core.TypeHash:      "rhash",
+	core.TypeSortedSet: "rzset",
+	core.TypeStream:    "rstream",
+	core.TypeHLL:       "rhll",
+}
+
+// valueColumns are the value table columns
+// of each key type (except the key_id).
+var valueColumns = map[core.TypeID]string{
+	core.TypeString:    "value",
+	core.TypeHash:      "field, value",
+	core.TypeSortedSet: "elem, score",
+	core.TypeStream:    "ms, seq, data",
+	core.TypeHLL:       "value",
+}
+
+// valueSizes are the expressions for the value size in bytes
+// of each key type, same as in the quota usage (see sqlx.Quotas).
+var valueSizes = map[core.TypeID]string{
+	core.TypeString:    "length(value)",
+	core.TypeHash:      "length(cast(field as blob)) + length(value)",
+	core.TypeSortedSet: "length(elem) + 8",
+	core.TypeStream:    "length(data) + 16",
+	core.TypeHLL:       "length(value)",
+}
+
 const scanPageSize = 10
 
+// scanRangeMax is the maximum number of keys in the prefix range
+// of the pattern for Scan to select the keys using the key index.
+// Each page sorts the keys in the range by ID, so with more keys,
+// scanning the table from the cursor is faster.
+const scanRangeMax = 1000
+
+// The results of TTL for the keys without a time-to-live,
+// same as the PTTL command in Redis returns.
+const (
+	// NoKeyTTL is the TTL of a key that does not exist.
+	NoKeyTTL time.Duration = -2
+	// NoExpireTTL is the TTL of a key without an expiration time.
+	NoExpireTTL time.Duration = -1
+)
+
+// The results of ExpireTime for the keys without an expiration time.
+// Their UnixMilli values are the ones the PEXPIRETIME command
+// in Redis returns. Compare them using time.Time.Equal.
+var (
+	// NoKeyTime is the expiration time of a key that does not exist.
+	NoKeyTime = time.UnixMilli(-2)
+	// NoExpireTime is the expiration time of a key
+	// without an expiration time.
+	NoExpireTime = time.UnixMilli(-1)
+)
+
+// expireBatchSize is the maximum number of expired
+// keys deleted in a single transaction.
+const expireBatchSize = 1000
+
 // Tx is a key repository transaction.
 type Tx struct {
 	tx sqlx.Tx
@@ -107,6 +333,49 @@ func (tx *Tx) Count(keys ...string) (int, error) {
 	return Count(tx.tx, keys...)
 }
 
+// CountByType returns the number of existing keys of each type.
+// Types without keys are not included.
+func (tx *Tx) CountByType() (map[core.TypeID]int, error) {
+	now := sqlx.Now(tx.tx).UnixMilli()
+	rows, err := tx.tx.Query(sqlCountByType, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[core.TypeID]int{}
+	for rows.Next() {
+		var typ core.TypeID
+		var count int
+		if err := rows.Scan(&typ, &count); err != nil {
+			return nil, err
+		}
+		counts[typ] = count
+	}
+	return counts, rows.Err()
+}
+
+// Keyspace returns the number of existing keys, the number
+// of keys with an expiration time, and their average TTL.
+func (tx *Tx) Keyspace() (KeyspaceInfo, error) {
+	now := sqlx.Now(tx.tx).UnixMilli()
+	var info KeyspaceInfo
+	var avgTTL float64
+	err := tx.tx.QueryRow(sqlKeyspace, now).Scan(&info.Keys, &info.Expires, &avgTTL)
+	info.AvgTTL = time.Duration(avgTTL) * time.Millisecond
+	return info, err
+}
+
+// Expiry returns the number of keys that expire within
+// the next minute, hour and day, and the number of expired
+// keys that are not deleted yet.
+func (tx *Tx) Expiry() (ExpiryInfo, error) {
+	now := sqlx.Now(tx.tx).UnixMilli()
+	var info ExpiryInfo
+	err := tx.tx.QueryRow(sqlExpiry, now).Scan(
+		&info.Minute, &info.Hour, &info.Day, &info.Total, &info.Expired)
+	return info, err
+}
+
 // Keys returns all keys matching pattern.
 // Supports glob-style patterns like these:
 //
@@ -115,16 +384,28 @@ func (tx *Tx) Count(keys ...string) (int, error) {
 // Use this method only if you are sure that the number of keys is
 // limited. Otherwise, use the [Tx.Scan] or [Tx.Scanner] methods.
 func (tx *Tx) Keys(pattern string) ([]core.Key, error) {
-	now := time.Now().UnixMilli()
-	args := []any{sql.Named("pattern", pattern), sql.Named("now", now)}
+	now := sqlx.Now(tx.tx).UnixMilli()
+	glob := sqlx.NewGlob(tx.tx, pattern)
+	args := []any{
+		sql.Named("pattern", glob.Arg()),
+		sql.Named("now", now),
+	}
 	scan := func(rows *sql.Rows) (core.Key, error) {
 		var k core.Key
 		err := rows.Scan(&k.ID, &k.Key, &k.Type, &k.Version, &k.ETime, &k.MTime)
 		return k, err
 	}
+	cond, rangeArgs := keyRange(glob.Range())
+	query := fmt.Sprintf(sqlKeys, cond)
+	args = append(args, rangeArgs...)
 	var keys []core.Key
-	keys, err := sqlx.Select(tx.tx, sqlKeys, args, scan)
-	return keys, err
+	keys, err := sqlx.Select(tx.tx, query, args, scan)
+	if err != nil {
+		return nil, err
+	}
+	return slices.DeleteFunc(keys, func(k core.Key) bool {
+		return !glob.Match(k.Key)
+	}), nil
 }
 
 // Scan iterates over keys matching pattern.
@@ -133,36 +414,79 @@ func (tx *Tx) Keys(pattern string) ([]core.Key, error) {
 // See [Tx.Keys] for pattern description.
 // Set pageSize = 0 for default page size.
 func (tx *Tx) Scan(cursor int, pattern string, pageSize int) (ScanResult, error) {
-	now := time.Now().UnixMilli()
+	return tx.ScanType(cursor, pattern, 0, pageSize)
+}
+
+// ScanType iterates over keys of the given type matching pattern,
+// like SCAN with TYPE in Redis. Type 0 matches all types.
+// ScanResult.More reports whether there may be more keys,
+// so the caller can stop without fetching an empty page.
+// See [Tx.Scan] for details.
+func (tx *Tx) ScanType(cursor int, pattern string, typ core.TypeID, pageSize int) (ScanResult, error) {
+	cur, err := sqlx.ParseCursor(tx.tx, cursor)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	now := sqlx.Now(tx.tx).UnixMilli()
 	if pageSize == 0 {
 		pageSize = scanPageSize
 	}
-	args := []any{
-		sql.Named("cursor", cursor),
-		sql.Named("pattern", pattern),
-		sql.Named("now", now),
-		sql.Named("count", pageSize),
+	glob := sqlx.NewGlob(tx.tx, pattern)
+	lo, hi, ok := glob.Range()
+	if ok {
+		ok, err = tx.scanByRange(cur.ID, lo, hi, pageSize+1)
+		if err != nil {
+			return ScanResult{}, err
+		}
 	}
+	cond, rangeArgs := keyRange(lo, hi, ok)
+	query := fmt.Sprintf(sqlScan, cond)
 	scan := func(rows *sql.Rows) (core.Key, error) {
 		var k core.Key
 		err := rows.Scan(&k.ID, &k.Key, &k.Type, &k.Version, &k.ETime, &k.MTime)
 		return k, err
 	}
-	var keys []core.Key
-	keys, err := sqlx.Select(tx.tx, sqlScan, args, scan)
-	if err != nil {
-		return ScanResult{}, err
-	}
+	for {
+		// Select one more key to see if there are more pages.
+		args := []any{
+			sql.Named("cursor", cur.ID),
+			sql.Named("pattern", glob.Arg()),
+			sql.Named("type", typ),
+			sql.Named("now", now),
+			sql.Named("count", pageSize+1),
+		}
+		args = append(args, rangeArgs...)
+		keys, err := sqlx.Select(tx.tx, query, args, scan)
+		if err != nil {
+			return ScanResult{}, err
+		}
+		more := len(keys) > pageSize
+		if more {
+			keys = keys[:pageSize]
+		}
 
-	// Select the maximum ID.
-	maxID := 0
-	for _, key := range keys {
-		if key.ID > maxID {
-			maxID = key.ID
+		// Select the maximum ID.
+		maxID := 0
+		for _, key := range keys {
+			if key.ID > maxID {
+				maxID = key.ID
+			}
 		}
-	}
 
-	return ScanResult{maxID, keys}, nil
+		// Skip the pages where none of the keys with
+		// NUL bytes match, so that an empty page
+		// still means there are no more keys.
+		matched := slices.DeleteFunc(keys, func(k core.Key) bool {
+			return !glob.Match(k.Key)
+		})
+		if len(matched) > 0 || maxID == 0 {
+			return ScanResult{cur.Next(maxID), matched, more}, nil
+		}
+		if !more {
+			return ScanResult{0, matched, false}, nil
+		}
+		cur.ID = maxID
+	}
 }
 
 // Scanner returns an iterator for keys matching pattern.
@@ -171,14 +495,36 @@ func (tx *Tx) Scan(cursor int, pattern string, pageSize int) (ScanResult, error)
 // See [Tx.Keys] for pattern description.
 // Set pageSize = 0 for default page size.
 func (tx *Tx) Scanner(pattern string, pageSize int) *Scanner {
-	return newScanner(tx, pattern, pageSize)
+	return newScanner(tx, pattern, 0, pageSize)
+}
+
+// ScannerType returns an iterator for keys of the given type
+// matching pattern. Type 0 matches all types.
+// See [Tx.Scanner] for details.
+func (tx *Tx) ScannerType(pattern string, typ core.TypeID, pageSize int) *Scanner {
+	return newScanner(tx, pattern, typ, pageSize)
 }
 
 // Random returns a random key.
+// Uses the random source of the repository if there is one
+// (so the result is deterministic for a seeded source).
 func (tx *Tx) Random() (core.Key, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
+	var row *sql.Row
+	if rnd := sqlx.RandOf(tx.tx); rnd != nil {
+		var count int
+		if err := tx.tx.QueryRow(sqlRandomCount, now).Scan(&count); err != nil {
+			return core.Key{}, err
+		}
+		if count == 0 {
+			return core.Key{}, nil
+		}
+		row = tx.tx.QueryRow(sqlRandomAt, now, rnd.IntN(count))
+	} else {
+		row = tx.tx.QueryRow(sqlRandom, now)
+	}
 	var k core.Key
-	err := tx.tx.QueryRow(sqlRandom, now).Scan(
+	err := row.Scan(
 		&k.ID, &k.Key, &k.Type, &k.Version, &k.ETime, &k.MTime,
 	)
 	if err == sql.ErrNoRows {
@@ -192,11 +538,116 @@ func (tx *Tx) Get(key string) (core.Key, error) {
 	return Get(tx.tx, key)
 }
 
+// Inspect returns the storage details of the key: the size of
+// the key and its value in bytes, the number of elements, and the
+// time since the last modification (see [KeyInfo]). Use it to find
+// the largest keys, like MEMORY USAGE and OBJECT in Redis.
+// The sizes are the raw data sizes, without the SQLite overhead.
+// If the key does not exist, returns an empty KeyInfo.
+func (tx *Tx) Inspect(key string) (KeyInfo, error) {
+	k, err := Get(tx.tx, key)
+	if err != nil || !k.Exists() {
+		return KeyInfo{}, err
+	}
+	info := KeyInfo{Key: k, Size: len(k.Key)}
+	if table, ok := valueTables[k.Type]; ok {
+		var size int
+		query := fmt.Sprintf(sqlInspect, valueSizes[k.Type], table)
+		err = tx.tx.QueryRow(query, k.ID).Scan(&info.Len, &size)
+		if err != nil {
+			return KeyInfo{}, err
+		}
+		info.Size += size
+	}
+	now := sqlx.Now(tx.tx).UnixMilli()
+	info.Idle = time.Duration(max(now-k.MTime, 0)) * time.Millisecond
+	return info, nil
+}
+
+// CheckVersion checks that the key has not changed since it was
+// read as prev (see core.Key.SameVersion), or does not exist if prev
+// does not exist. Returns core.ErrVersion otherwise. Use it to make the writes
+// in a transaction conditional on the key not being changed
+// since it was read (optimistic concurrency control):
+//
+//	k, _ := db.Key().Get("counter")
+//	// ...
+//	err := db.Update(func(tx *redka.Tx) error {
+//	    if err := tx.Key().CheckVersion("counter", k); err != nil {
+//	        return err
+//	    }
+//	    return tx.Str().Set("counter", 42)
+//	})
+func (tx *Tx) CheckVersion(key string, prev core.Key) error {
+	k, err := Get(tx.tx, key)
+	if err != nil {
+		return err
+	}
+	if !k.SameVersion(prev) {
+		return core.ErrVersion
+	}
+	return nil
+}
+
+// Stamp returns the changestamp of the keys matching the pattern
+// (see [Tx.Keys]). The stamp changes whenever a matching key is
+// created, changed, deleted, renamed or expires, so it works as
+// an aggregate version of all the matching keys. Reads all the
+// matching keys, so use it only with selective patterns.
+func (tx *Tx) Stamp(pattern string) (uint64, error) {
+	keys, err := tx.Keys(pattern)
+	if err != nil {
+		return 0, err
+	}
+	slices.SortFunc(keys, func(a, b core.Key) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	h := fnv.New64a()
+	var buf []byte
+	for _, k := range keys {
+		buf = binary.AppendUvarint(buf[:0], uint64(len(k.Key)))
+		buf = append(buf, k.Key...)
+		buf = binary.AppendVarint(buf, int64(k.ID))
+		buf = binary.AppendVarint(buf, int64(k.Type))
+		buf = binary.AppendVarint(buf, int64(k.Version))
+		buf = binary.AppendVarint(buf, k.MTime)
+		if k.ETime != nil {
+			buf = binary.AppendVarint(buf, *k.ETime)
+		}
+		_, _ = h.Write(buf)
+	}
+	return h.Sum64(), nil
+}
+
+// CheckStamp checks that the keys matching the pattern have
+// the specified changestamp (see [Tx.Stamp]). Returns core.ErrVersion
+// otherwise. Use it like [Tx.CheckVersion] when the transaction
+// depends on keys with dynamic names:
+//
+//	stamp, _ := db.Key().Stamp("cart:42:*")
+//	// ...
+//	err := db.Update(func(tx *redka.Tx) error {
+//	    if err := tx.Key().CheckStamp("cart:42:*", stamp); err != nil {
+//	        return err
+//	    }
+//	    return tx.Str().Set("order:42", total)
+//	})
+func (tx *Tx) CheckStamp(pattern string, stamp uint64) error {
+	got, err := tx.Stamp(pattern)
+	if err != nil {
+		return err
+	}
+	if got != stamp {
+		return core.ErrVersion
+	}
+	return nil
+}
+
 // Expire sets a time-to-live (ttl) for the key using a relative duration.
 // After the ttl passes, the key is expired and no longer exists.
 // Returns false is the key does not exist.
 func (tx *Tx) Expire(key string, ttl time.Duration) (bool, error) {
-	at := time.Now().Add(ttl)
+	at := sqlx.Now(tx.tx).Add(ttl)
 	return tx.ExpireAt(key, at)
 }
 
@@ -204,7 +655,7 @@ func (tx *Tx) Expire(key string, ttl time.Duration) (bool, error) {
 // the key is expired and no longer exists.
 // Returns false is the key does not exist.
 func (tx *Tx) ExpireAt(key string, at time.Time) (bool, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{
 		sql.Named("key", key),
 		sql.Named("now", now),
@@ -218,10 +669,17 @@ func (tx *Tx) ExpireAt(key string, at time.Time) (bool, error) {
 	return count > 0, nil
 }
 
+// ExpireWith sets the expiration time of the key
+// only if the specified conditions are met (see [ExpireCmd]),
+// like ExpireWith("session").TTL(time.Hour).IfGreater().Run().
+func (tx *Tx) ExpireWith(key string) ExpireCmd {
+	return ExpireCmd{tx: tx, key: key}
+}
+
 // Persist removes the expiration time for the key.
 // Returns false is the key does not exist.
 func (tx *Tx) Persist(key string) (bool, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{sql.Named("key", key), sql.Named("now", now)}
 	res, err := tx.tx.Exec(sqlPersist, args...)
 	if err != nil {
@@ -231,6 +689,43 @@ func (tx *Tx) Persist(key string) (bool, error) {
 	return count > 0, nil
 }
 
+// TTL returns the remaining time-to-live of the key
+// with millisecond precision, like PTTL in Redis.
+// If the key does not exist, returns NoKeyTTL.
+// If the key has no expiration time, returns NoExpireTTL.
+func (tx *Tx) TTL(key string) (time.Duration, error) {
+	k, err := Get(tx.tx, key)
+	if err != nil {
+		return 0, err
+	}
+	if !k.Exists() {
+		return NoKeyTTL, nil
+	}
+	if k.ETime == nil {
+		return NoExpireTTL, nil
+	}
+	now := sqlx.Now(tx.tx).UnixMilli()
+	return time.Duration(*k.ETime-now) * time.Millisecond, nil
+}
+
+// ExpireTime returns the expiration time of the key,
+// like PEXPIRETIME in Redis.
+// If the key does not exist, returns NoKeyTime.
+// If the key has no expiration time, returns NoExpireTime.
+func (tx *Tx) ExpireTime(key string) (time.Time, error) {
+	k, err := Get(tx.tx, key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !k.Exists() {
+		return NoKeyTime, nil
+	}
+	if k.ETime == nil {
+		return NoExpireTime, nil
+	}
+	return time.UnixMilli(*k.ETime), nil
+}
+
 // Rename changes the key name.
 // If there is an existing key with the new name, it is replaced.
 func (tx *Tx) Rename(key, newKey string) error {
@@ -243,6 +738,10 @@ func (tx *Tx) Rename(key, newKey string) error {
 		return core.ErrNotFound
 	}
 
+	if err := sqlx.CheckKey(tx.tx, newKey); err != nil {
+		return err
+	}
+
 	// If the keys are the same, do nothing.
 	if key == newKey {
 		return nil
@@ -255,7 +754,7 @@ func (tx *Tx) Rename(key, newKey string) error {
 	}
 
 	// Rename the old key to the new key.
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{
 		sql.Named("key", key),
 		sql.Named("new_key", newKey),
@@ -278,6 +777,10 @@ func (tx *Tx) RenameNotExists(key, newKey string) (bool, error) {
 		return false, core.ErrNotFound
 	}
 
+	if err := sqlx.CheckKey(tx.tx, newKey); err != nil {
+		return false, err
+	}
+
 	// If the keys are the same, do nothing.
 	if key == newKey {
 		return false, nil
@@ -293,7 +796,7 @@ func (tx *Tx) RenameNotExists(key, newKey string) (bool, error) {
 	}
 
 	// Rename the old key to the new key.
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{
 		sql.Named("key", key),
 		sql.Named("new_key", newKey),
@@ -303,42 +806,317 @@ func (tx *Tx) RenameNotExists(key, newKey string) (bool, error) {
 	return err == nil, err
 }
 
+// Copy copies the key with its value to the new key. Supports
+// strings, hashes (with the field expiration times), sorted sets,
+// streams and HyperLogLogs, and returns core.ErrKeyType for other
+// types. The copy has the same type, version and expiration
+// time as the source key. If there is an existing key with the new
+// name, replaces it if replace is true, or does nothing otherwise.
+// Returns true if the key was copied, false otherwise.
+// If the source key does not exist, returns core.ErrNotFound.
+func (tx *Tx) Copy(key, newKey string, replace bool) (bool, error) {
+	// Make sure the source key exists.
+	srcK, err := Get(tx.tx, key)
+	if err != nil {
+		return false, err
+	}
+	if !srcK.Exists() {
+		return false, core.ErrNotFound
+	}
+	table, columns := valueTables[srcK.Type], valueColumns[srcK.Type]
+	if table == "" {
+		return false, core.ErrKeyType
+	}
+
+	if err := sqlx.CheckKey(tx.tx, newKey); err != nil {
+		return false, err
+	}
+
+	// A key cannot be copied to itself.
+	if key == newKey {
+		return false, nil
+	}
+
+	// Delete the new key if it exists and should be replaced.
+	exist, err := tx.Exists(newKey)
+	if err != nil {
+		return false, err
+	}
+	if exist {
+		if !replace {
+			return false, nil
+		}
+		if _, err := tx.Delete(newKey); err != nil {
+			return false, err
+		}
+	}
+
+	// Copy the key, then its value.
+	args := []any{
+		sql.Named("id", srcK.ID),
+		sql.Named("new_key", newKey),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
+	}
+	res, err := tx.tx.Exec(sqlCopy, args...)
+	if err != nil {
+		return false, err
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return false, err
+	}
+	query := fmt.Sprintf(sqlCopyValues, table, columns)
+	_, err = tx.tx.Exec(query, newID, srcK.ID)
+	if err != nil {
+		return false, err
+	}
+	if srcK.Type == core.TypeHash {
+		// The hash fields keep their expiration times.
+		query := fmt.Sprintf(sqlCopyValues, "rhash_ttl", "field, etime")
+		_, err = tx.tx.Exec(query, newID, srcK.ID)
+	}
+	return err == nil, err
+}
+
 // Delete deletes keys and their values, regardless of the type.
 // Returns the number of deleted keys. Non-existing keys are ignored.
+// If the trash is enabled (see [sqlx.DB.Trash]), moves the keys
+// to the trash instead.
 func (tx *Tx) Delete(keys ...string) (int, error) {
 	return Delete(tx.tx, keys...)
 }
 
+// Unlink deletes keys like Delete, but only removes the keys
+// right away, while their values are deleted later in small
+// batches (see [DB.FreeStep]). Returns the number of unlinked keys.
+// Non-existing keys are ignored. If the trash is enabled,
+// moves the keys to the trash instead.
+func (tx *Tx) Unlink(keys ...string) (int, error) {
+	if sqlx.TrashOf(tx.tx) > 0 {
+		return trash(tx.tx, keys...)
+	}
+	now := sqlx.Now(tx.tx).UnixMilli()
+	query, keyArgs := sqlx.ExpandIn(sqlUnlinkSelect, ":keys", keys)
+	args := slices.Concat(keyArgs, []any{sql.Named("now", now)})
+	ids, err := sqlx.Select(tx.tx, query, args, func(rows *sql.Rows) (int, error) {
+		var id int
+		err := rows.Scan(&id)
+		return id, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		args := []any{
+			sql.Named("id", id),
+			sql.Named("tomb", unlinkPrefix+strconv.Itoa(id)),
+			sql.Named("now", now),
+		}
+		if _, err := tx.tx.Exec(sqlUnlink, args...); err != nil {
+			return 0, err
+		}
+		if _, err := tx.tx.Exec(sqlFreeAdd, id); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
+
 // DeleteAll deletes all keys and their values, effectively resetting
-// the database. Should not be run inside a database transaction.
+// the database. Should not be run inside a database transaction,
+// unless the trash is enabled: then it moves all the keys
+// to the trash instead.
 func (tx *Tx) DeleteAll() error {
+	if sqlx.TrashOf(tx.tx) > 0 {
+		return tx.trashAll()
+	}
 	_, err := tx.tx.Exec(sqlDeleteAll)
 	return err
 }
 
-// deleteExpired deletes keys with expired TTL, but no more than n keys.
-// If n = 0, deletes all expired keys.
-func (tx *Tx) deleteExpired(n int) (int, error) {
-	now := time.Now().UnixMilli()
-	var res sql.Result
-	var err error
-	if n > 0 {
-		args := []any{sql.Named("now", now), sql.Named("n", n)}
-		res, err = tx.tx.Exec(sqlDeleteNExpired, args...)
-	} else {
-		res, err = tx.tx.Exec(sqlDeleteAllExpired, now)
+// Restore restores the key deleted while the trash was enabled
+// (see [sqlx.DB.Trash]), along with its value and expiration time.
+// If the key was deleted several times, restores the latest version.
+// Returns false if a key with the same name exists (the trashed one
+// stays in the trash), or core.ErrNotFound if there is no such key
+// in the trash (or it would be expired by now).
+func (tx *Tx) Restore(key string) (bool, error) {
+	now := sqlx.Now(tx.tx).UnixMilli()
+	var id int
+	var etime *int64
+	args := []any{sql.Named("key", key), sql.Named("now", now)}
+	err := tx.tx.QueryRow(sqlTrashGet, args...).Scan(&id, &etime)
+	if err == sql.ErrNoRows {
+		return false, core.ErrNotFound
 	}
+	if err != nil {
+		return false, err
+	}
+
+	exist, err := tx.Exists(key)
+	if err != nil {
+		return false, err
+	}
+	if exist {
+		return false, nil
+	}
+	if err := sqlx.CheckKey(tx.tx, key); err != nil {
+		return false, err
+	}
+
+	args = []any{
+		sql.Named("id", id),
+		sql.Named("key", key),
+		sql.Named("etime", etime),
+		sql.Named("now", now),
+	}
+	if _, err := tx.tx.Exec(sqlRestore, args...); err != nil {
+		return false, err
+	}
+	_, err = tx.tx.Exec(sqlTrashRemove, id)
+	return err == nil, err
+}
+
+// trashAll moves all the existing keys to the trash.
+func (tx *Tx) trashAll() error {
+	now := sqlx.Now(tx.tx).UnixMilli()
+	if _, err := tx.tx.Exec(sqlTrashAll, sql.Named("now", now)); err != nil {
+		return err
+	}
+	args := []any{sql.Named("prefix", trashPrefix), sql.Named("now", now)}
+	_, err := tx.tx.Exec(sqlTrashAllKeys, args...)
+	return err
+}
+
+// purgeTrash removes the keys that have been in the trash for longer
+// than the retention (see [sqlx.DB.Trash]), or all of them if the
+// trash is disabled. The values are deleted later by the lazy free
+// (see [DB.FreeStep]). Returns the number of purged keys.
+func (tx *Tx) purgeTrash() (int, error) {
+	before := sqlx.Now(tx.tx).Add(-sqlx.TrashOf(tx.tx)).UnixMilli()
+	res, err := tx.tx.Exec(sqlTrashPurge, before)
 	if err != nil {
 		return 0, err
 	}
 	count, _ := res.RowsAffected()
-	return int(count), err
+	if count == 0 {
+		return 0, nil
+	}
+	if _, err := tx.tx.Exec(sqlTrashPurged); err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// expireCursor is the position of the expired keys sweep.
+type expireCursor struct {
+	etime int64
+	id    int
+}
+
+// deleteExpired deletes up to n keys that expired by now,
+// starting after the cursor. Returns the deleted keys
+// and the cursor to continue from.
+func (tx *Tx) deleteExpired(now int64, cur expireCursor, n int) ([]core.Key, expireCursor, error) {
+	args := []any{
+		sql.Named("now", now),
+		sql.Named("etime", cur.etime),
+		sql.Named("id", cur.id),
+		sql.Named("n", n),
+	}
+	page, err := sqlx.Select(tx.tx, sqlExpiredPage, args, func(rows *sql.Rows) (core.Key, error) {
+		var k core.Key
+		err := rows.Scan(&k.ID, &k.Key, &k.Type, &k.Version, &k.ETime, &k.MTime)
+		return k, err
+	})
+	if err != nil || len(page) == 0 {
+		return nil, cur, err
+	}
+	ids := make([]int, len(page))
+	for i, k := range page {
+		ids[i] = k.ID
+	}
+	query, idArgs := sqlx.ExpandIn(sqlDeleteIDs, ":ids", ids)
+	if _, err := tx.tx.Exec(query, idArgs...); err != nil {
+		return nil, cur, err
+	}
+	if err := sqlx.MarkExpired(tx.tx, ids); err != nil {
+		return nil, cur, err
+	}
+	last := page[len(page)-1]
+	return page, expireCursor{etime: *last.ETime, id: last.ID}, nil
+}
+
+// freeStep deletes up to n values of an unlinked key.
+// Deletes the key itself when there are no values left.
+// Returns the number of deleted rows (values and keys).
+func (tx *Tx) freeStep(n int) (int, error) {
+	var id int
+	var typ core.TypeID
+	err := tx.tx.QueryRow(sqlFreeNext).Scan(&id, &typ)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	if table, ok := valueTables[typ]; ok {
+		query := fmt.Sprintf(sqlFreeValues, table, table)
+		res, err := tx.tx.Exec(query, id, n)
+		if err != nil {
+			return 0, err
+		}
+		count, _ = res.RowsAffected()
+	}
+	if int(count) < n {
+		// No values left, delete the key.
+		if _, err := tx.tx.Exec(sqlFreeKey, id); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return int(count), nil
+}
+
+// KeyspaceInfo describes the keys in the database.
+type KeyspaceInfo struct {
+	Keys    int           // number of existing keys
+	Expires int           // number of keys with an expiration time
+	AvgTTL  time.Duration // average TTL of the expiring keys
+}
+
+// KeyInfo describes the storage of a key (see Tx.Inspect).
+// Redka does not track the key reads, so Idle is the time
+// since the last modification rather than the last access.
+type KeyInfo struct {
+	core.Key
+	Size int           // size of the key and its value in bytes
+	Len  int           // number of elements (1 for strings and HyperLogLogs)
+	Idle time.Duration // time since the last modification
+}
+
+// ExpiryInfo describes when the keys expire. The counts are
+// cumulative: the keys expiring within a minute are also counted
+// in Hour and Day.
+type ExpiryInfo struct {
+	Minute int // keys expiring within the next minute
+	Hour   int // keys expiring within the next hour
+	Day    int // keys expiring within the next day
+	Total  int // keys with an expiration time
+
+	// Expired is the number of keys that have expired but are not
+	// deleted yet (the expiration backlog). They are not counted
+	// in Total. A growing backlog means the background deletion
+	// does not keep up with the expirations.
+	Expired int
 }
 
 // ScanResult represents a result of the Scan call.
 type ScanResult struct {
-	Cursor int
+	Cursor int // opaque cursor to continue the scan, 0 when done (see sqlx.Cursor)
 	Keys   []core.Key
+	More   bool // false if there are no more keys after this page
 }
 
 // Scanner is the iterator for keys.
@@ -347,14 +1125,17 @@ type Scanner struct {
 	db       *Tx
 	cursor   int
 	pattern  string
+	typ      core.TypeID
 	pageSize int
 	index    int
 	cur      core.Key
 	keys     []core.Key
+	more     bool // false after the last page is fetched
 	err      error
+	close    func() // called when the scanner is exhausted
 }
 
-func newScanner(db *Tx, pattern string, pageSize int) *Scanner {
+func newScanner(db *Tx, pattern string, typ core.TypeID, pageSize int) *Scanner {
 	if pageSize == 0 {
 		pageSize = scanPageSize
 	}
@@ -362,9 +1143,11 @@ func newScanner(db *Tx, pattern string, pageSize int) *Scanner {
 		db:       db,
 		cursor:   0,
 		pattern:  pattern,
+		typ:      typ,
 		pageSize: pageSize,
 		index:    0,
 		keys:     []core.Key{},
+		more:     true,
 	}
 }
 
@@ -372,16 +1155,23 @@ func newScanner(db *Tx, pattern string, pageSize int) *Scanner {
 // Returns false when there are no more keys or an error occurs.
 func (sc *Scanner) Scan() bool {
 	if sc.index >= len(sc.keys) {
+		if !sc.more {
+			sc.done()
+			return false
+		}
 		// Fetch a new page of keys.
-		out, err := sc.db.Scan(sc.cursor, sc.pattern, sc.pageSize)
+		out, err := sc.db.ScanType(sc.cursor, sc.pattern, sc.typ, sc.pageSize)
 		if err != nil {
 			sc.err = err
+			sc.done()
 			return false
 		}
 		sc.cursor = out.Cursor
+		sc.more = out.More
 		sc.keys = out.Keys
 		sc.index = 0
 		if len(sc.keys) == 0 {
+			sc.done()
 			return false
 		}
 	}
@@ -391,6 +1181,13 @@ func (sc *Scanner) Scan() bool {
 	return true
 }
 
+// done marks the scanner as exhausted.
+func (sc *Scanner) done() {
+	if sc.close != nil {
+		sc.close()
+	}
+}
+
 // Key returns the current key.
 func (sc *Scanner) Key() core.Key {
 	return sc.cur
@@ -401,9 +1198,69 @@ func (sc *Scanner) Err() error {
 	return sc.err
 }
 
+// All returns an iterator over the remaining keys:
+//
+//	for item := range sc.All() {
+//	    // ...
+//	}
+//	if err := sc.Err(); err != nil {
+//	    // ...
+//	}
+func (sc *Scanner) All() iter.Seq[core.Key] {
+	return func(yield func(core.Key) bool) {
+		for sc.Scan() {
+			if !yield(sc.Key()) {
+				return
+			}
+		}
+	}
+}
+
+// scanByRange reports whether Scan should select the page of count
+// keys after the cursor by the range, rather than by scanning the
+// table from the cursor. Scanning is faster if there are enough keys
+// in the range among the next scanRangeMax keys, or if there are
+// more than scanRangeMax keys in the range.
+func (tx *Tx) scanByRange(cursor int, lo, hi string, count int) (bool, error) {
+	args := []any{
+		sql.Named("cursor", cursor),
+		sql.Named("lo", lo),
+		sql.Named("hi", hi),
+		sql.Named("max", scanRangeMax),
+		sql.Named("count", count),
+	}
+	var n int
+	if err := tx.tx.QueryRow(sqlKeyRangeNext, args...).Scan(&n); err != nil {
+		return false, err
+	}
+	if n == count {
+		return false, nil
+	}
+	args = []any{
+		sql.Named("lo", lo),
+		sql.Named("hi", hi),
+		sql.Named("max", scanRangeMax+1),
+	}
+	if err := tx.tx.QueryRow(sqlKeyRangeCount, args...).Scan(&n); err != nil {
+		return false, err
+	}
+	return n <= scanRangeMax, nil
+}
+
+// keyRange returns the condition and the arguments for the keys
+// queries to select the keys in the prefix range of the pattern
+// using the key index instead of scanning all the keys
+// (see sqlx.Glob.Range). Returns empty ones if !ok.
+func keyRange(lo, hi string, ok bool) (string, []any) {
+	if !ok {
+		return "", nil
+	}
+	return sqlKeyRange, []any{sql.Named("lo", lo), sql.Named("hi", hi)}
+}
+
 // Get returns the key data structure.
 func Get(tx sqlx.Tx, key string) (core.Key, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx).UnixMilli()
 	var k core.Key
 	err := tx.QueryRow(sqlGet, key, now).Scan(
 		&k.ID, &k.Key, &k.Type, &k.Version, &k.ETime, &k.MTime,
@@ -416,7 +1273,7 @@ func Get(tx sqlx.Tx, key string) (core.Key, error) {
 
 // Count returns the number of existing keys among specified.
 func Count(tx sqlx.Tx, keys ...string) (int, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx).UnixMilli()
 	query, keyArgs := sqlx.ExpandIn(sqlCount, ":keys", keys)
 	args := slices.Concat(keyArgs, []any{sql.Named("now", now)})
 	var count int
@@ -424,9 +1281,13 @@ func Count(tx sqlx.Tx, keys ...string) (int, error) {
 	return count, err
 }
 
-// Delete deletes keys and their values (regardless of the type).
+// Delete deletes keys and their values (regardless of the type),
+// or moves them to the trash if it is enabled (see [sqlx.DB.Trash]).
 func Delete(tx sqlx.Tx, keys ...string) (int, error) {
-	now := time.Now().UnixMilli()
+	if sqlx.TrashOf(tx) > 0 {
+		return trash(tx, keys...)
+	}
+	now := sqlx.Now(tx).UnixMilli()
 	query, keyArgs := sqlx.ExpandIn(sqlDelete, ":keys", keys)
 	args := slices.Concat(keyArgs, []any{sql.Named("now", now)})
 	res, err := tx.Exec(query, args...)
@@ -441,7 +1302,7 @@ func Delete(tx sqlx.Tx, keys ...string) (int, error) {
 // Returns the number of deleted keys.
 // Non-existing keys and keys of other types are ignored.
 func DeleteType(tx sqlx.Tx, typ core.TypeID, keys ...string) (int, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx).UnixMilli()
 	query, keyArgs := sqlx.ExpandIn(sqlDeleteType, ":keys", keys)
 	args := slices.Concat(keyArgs, []any{sql.Named("now", now), sql.Named("type", typ)})
 	res, err := tx.Exec(query, args...)
@@ -451,3 +1312,39 @@ func DeleteType(tx sqlx.Tx, typ core.TypeID, keys ...string) (int, error) {
 	affectedCount, _ := res.RowsAffected()
 	return int(affectedCount), nil
 }
+
+// trash moves the keys to the trash: renames and expires them
+// like Unlink does, and records their names and expiration times,
+// so that they can be restored. Returns the number of trashed keys.
+func trash(tx sqlx.Tx, keys ...string) (int, error) {
+	now := sqlx.Now(tx).UnixMilli()
+	query, keyArgs := sqlx.ExpandIn(sqlTrashSelect, ":keys", keys)
+	args := slices.Concat(keyArgs, []any{sql.Named("now", now)})
+	type trashed struct {
+		id    int
+		key   string
+		etime *int64
+	}
+	list, err := sqlx.Select(tx, query, args, func(rows *sql.Rows) (trashed, error) {
+		var k trashed
+		err := rows.Scan(&k.id, &k.key, &k.etime)
+		return k, err
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, k := range list {
+		args := []any{
+			sql.Named("id", k.id),
+			sql.Named("tomb", trashPrefix+strconv.Itoa(k.id)),
+			sql.Named("now", now),
+		}
+		if _, err := tx.Exec(sqlUnlink, args...); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(sqlTrashAdd, k.id, k.key, k.etime, now); err != nil {
+			return 0, err
+		}
+	}
+	return len(list), nil
+}
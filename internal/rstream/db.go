@@ -0,0 +1,117 @@
+// Package rstream is a database-backed stream repository.
+// It provides methods to interact with streams in the database.
+package rstream
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// DB is a database-backed stream repository.
+// A stream is an append-only log of entries associated with a key.
+// Each entry has a unique ID and a list of field-value pairs.
+// Use the stream repository to append entries to streams
+// and to read them back by ID.
+type DB struct {
+	*sqlx.DB[*Tx]
+}
+
+// New connects to the stream repository.
+// Does not create the database schema.
+func New(db *sql.DB) *DB {
+	d := sqlx.New(db, NewTx)
+	return &DB{d}
+}
+
+// WithContext returns a shallow copy of the repository
+// that executes the queries with ctx. Use it to enforce
+// timeouts and cancellation on slow queries.
+func (d *DB) WithContext(ctx context.Context) *DB {
+	return &DB{d.DB.WithContext(ctx)}
+}
+
+// Add appends an entry to a stream and returns its ID.
+// Generates the ID from the current time, so that it is greater
+// than the IDs of the existing entries (see [ID]).
+// Takes field-value pairs, like Add("events", "user", "alice", "action", "login").
+// The fields must be strings. The values can be of any supported type
+// (see core.IsValueType). Returns ErrSyntax if there are no pairs,
+// or the last field has no value.
+// If the key does not exist, creates it.
+// If the key exists but is not a stream, returns ErrKeyType.
+func (d *DB) Add(key string, fields ...any) (ID, error) {
+	op := d.Observe("Stream.Add", key)
+	var id ID
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		id, err = tx.Add(key, fields...)
+		return err
+	})
+	return id, op.Done(err)
+}
+
+// AddID appends an entry with the given ID to a stream.
+// The ID must be greater than 0-0 and the IDs of the existing
+// entries, otherwise returns ErrStreamID. Takes the field-value
+// pairs like [DB.Add].
+// If the key does not exist, creates it.
+// If the key exists but is not a stream, returns ErrKeyType.
+func (d *DB) AddID(key string, id ID, fields ...any) error {
+	op := d.Observe("Stream.AddID", key)
+	err := d.In(op).Update(func(tx *Tx) error {
+		return tx.AddID(key, id, fields...)
+	})
+	return op.Done(err)
+}
+
+// Last returns the ID of the last entry in a stream.
+// If the stream is empty, or the key does not exist
+// or is not a stream, returns ErrNotFound.
+func (d *DB) Last(key string) (ID, error) {
+	op := d.Observe("Stream.Last", key)
+	tx := NewTx(d.In(op).ReadConn())
+	id, err := tx.Last(key)
+	return id, op.Done(err)
+}
+
+// Len returns the number of entries in a stream.
+// If the key does not exist or is not a stream, returns 0.
+func (d *DB) Len(key string) (int, error) {
+	op := d.Observe("Stream.Len", key)
+	tx := NewTx(d.In(op).ReadConn())
+	n, err := tx.Len(key)
+	return n, op.Done(err)
+}
+
+// Range returns the stream entries with IDs between start and end
+// (inclusive), ordered by ID. Use MinID and MaxID for the open ends.
+// Returns at most count entries (all of them if count is 0).
+// If the key does not exist or is not a stream, returns an empty slice.
+func (d *DB) Range(key string, start, end ID, count int) ([]Entry, error) {
+	op := d.Observe("Stream.Range", key)
+	tx := NewTx(d.In(op).ReadConn())
+	entries, err := tx.Range(key, start, end, count)
+	return entries, op.Done(err)
+}
+
+// RevRange is like [DB.Range], but returns the entries
+// in reverse order, starting from end.
+func (d *DB) RevRange(key string, end, start ID, count int) ([]Entry, error) {
+	op := d.Observe("Stream.RevRange", key)
+	tx := NewTx(d.In(op).ReadConn())
+	entries, err := tx.RevRange(key, end, start, count)
+	return entries, op.Done(err)
+}
+
+// Read returns the stream entries with IDs greater than after,
+// ordered by ID. Use it to read the entries added since the last
+// seen one. Returns at most count entries (all of them if count is 0).
+// If the key does not exist or is not a stream, returns an empty slice.
+func (d *DB) Read(key string, after ID, count int) ([]Entry, error) {
+	op := d.Observe("Stream.Read", key)
+	tx := NewTx(d.In(op).ReadConn())
+	entries, err := tx.Read(key, after, count)
+	return entries, op.Done(err)
+}
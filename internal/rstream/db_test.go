@@ -0,0 +1,280 @@
+package rstream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestAdd(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		id, err := db.Add("events", "user", "alice", "age", 25)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, id, rstream.ID{Time: now.UnixMilli(), Seq: 0})
+
+		entries, _ := db.Range("events", rstream.MinID, rstream.MaxID, 0)
+		testx.AssertEqual(t, len(entries), 1)
+		testx.AssertEqual(t, entries[0].ID, id)
+		testx.AssertEqual(t, entries[0].Fields, []rstream.Field{
+			{Name: "user", Value: core.Value("alice")},
+			{Name: "age", Value: core.Value("25")},
+		})
+
+		key, _ := red.Key().Get("events")
+		testx.AssertEqual(t, key.Type, core.TypeStream)
+		testx.AssertEqual(t, key.Version, 1)
+	})
+	t.Run("same time", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		id1, _ := db.Add("events", "n", 1)
+		id2, err := db.Add("events", "n", 2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, id2, rstream.ID{Time: id1.Time, Seq: 1})
+
+		key, _ := red.Key().Get("events")
+		testx.AssertEqual(t, key.Version, 2)
+	})
+	t.Run("clock behind", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		last := rstream.ID{Time: now.Add(time.Hour).UnixMilli(), Seq: 5}
+		_ = db.AddID("events", last, "n", 1)
+		id, err := db.Add("events", "n", 2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, id, rstream.ID{Time: last.Time, Seq: 6})
+	})
+	t.Run("invalid fields", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, err := db.Add("events")
+		testx.AssertErr(t, err, core.ErrSyntax)
+		_, err = db.Add("events", "user", "alice", "age")
+		testx.AssertErr(t, err, core.ErrSyntax)
+		_, err = db.Add("events", 42, "alice")
+		testx.AssertErr(t, err, core.ErrValueType)
+		_, err = db.Add("events", "user", struct{}{})
+		testx.AssertErr(t, err, core.ErrValueType)
+
+		exists, _ := red.Key().Exists("events")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().Set("events", "value")
+		_, err := db.Add("events", "user", "alice")
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+}
+
+func TestAddID(t *testing.T) {
+	t.Run("increasing", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		err := db.AddID("events", rstream.ID{Time: 1, Seq: 0}, "n", 1)
+		testx.AssertNoErr(t, err)
+		err = db.AddID("events", rstream.ID{Time: 1, Seq: 1}, "n", 2)
+		testx.AssertNoErr(t, err)
+		err = db.AddID("events", rstream.ID{Time: 5, Seq: 0}, "n", 3)
+		testx.AssertNoErr(t, err)
+
+		n, _ := db.Len("events")
+		testx.AssertEqual(t, n, 3)
+		last, _ := db.Last("events")
+		testx.AssertEqual(t, last, rstream.ID{Time: 5, Seq: 0})
+	})
+	t.Run("not greater", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = db.AddID("events", rstream.ID{Time: 5, Seq: 1}, "n", 1)
+		err := db.AddID("events", rstream.ID{Time: 5, Seq: 1}, "n", 2)
+		testx.AssertErr(t, err, core.ErrStreamID)
+		err = db.AddID("events", rstream.ID{Time: 4, Seq: 9}, "n", 2)
+		testx.AssertErr(t, err, core.ErrStreamID)
+
+		n, _ := db.Len("events")
+		testx.AssertEqual(t, n, 1)
+	})
+	t.Run("zero", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		err := db.AddID("events", rstream.MinID, "n", 1)
+		testx.AssertErr(t, err, core.ErrStreamID)
+	})
+}
+
+func TestLast(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_, err := db.Last("events")
+	testx.AssertErr(t, err, core.ErrNotFound)
+
+	id, _ := db.Add("events", "n", 1)
+	last, err := db.Last("events")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, last, id)
+}
+
+func TestLen(t *testing.T) {
+	t.Run("stream", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, _ = db.Add("events", "n", 1)
+		_, _ = db.Add("events", "n", 2)
+		n, err := db.Len("events")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 2)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		n, err := db.Len("events")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 0)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_ = red.Str().Set("events", "value")
+		n, err := db.Len("events")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 0)
+	})
+}
+
+func TestRange(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	for i, id := range []rstream.ID{{1, 0}, {1, 1}, {2, 0}, {3, 0}} {
+		_ = db.AddID("events", id, "n", i)
+	}
+	ids := func(entries []rstream.Entry) []string {
+		res := make([]string, len(entries))
+		for i, e := range entries {
+			res[i] = e.ID.String()
+		}
+		return res
+	}
+
+	t.Run("all", func(t *testing.T) {
+		entries, err := db.Range("events", rstream.MinID, rstream.MaxID, 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ids(entries), []string{"1-0", "1-1", "2-0", "3-0"})
+		testx.AssertEqual(t, entries[3].Fields[0].Value.String(), "3")
+	})
+	t.Run("bounds", func(t *testing.T) {
+		entries, err := db.Range("events", rstream.ID{1, 1}, rstream.ID{2, 0}, 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ids(entries), []string{"1-1", "2-0"})
+	})
+	t.Run("count", func(t *testing.T) {
+		entries, err := db.Range("events", rstream.MinID, rstream.MaxID, 2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ids(entries), []string{"1-0", "1-1"})
+	})
+	t.Run("reverse", func(t *testing.T) {
+		entries, err := db.RevRange("events", rstream.MaxID, rstream.ID{1, 1}, 2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ids(entries), []string{"3-0", "2-0"})
+	})
+	t.Run("empty", func(t *testing.T) {
+		entries, err := db.Range("events", rstream.ID{4, 0}, rstream.MaxID, 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(entries), 0)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		entries, err := db.Range("other", rstream.MinID, rstream.MaxID, 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, entries, []rstream.Entry{})
+	})
+}
+
+func TestRead(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	for i, id := range []rstream.ID{{1, 0}, {1, 1}, {2, 0}} {
+		_ = db.AddID("events", id, "n", i)
+	}
+
+	entries, err := db.Read("events", rstream.MinID, 0)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(entries), 3)
+
+	entries, err = db.Read("events", rstream.ID{1, 0}, 0)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(entries), 2)
+	testx.AssertEqual(t, entries[0].ID, rstream.ID{1, 1})
+
+	entries, err = db.Read("events", rstream.ID{1, 0}, 1)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(entries), 1)
+
+	entries, err = db.Read("events", rstream.ID{2, 0}, 0)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(entries), 0)
+
+	entries, err = db.Read("events", rstream.MaxID, 0)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(entries), 0)
+}
+
+func TestParseID(t *testing.T) {
+	tests := []struct {
+		s    string
+		end  bool
+		want rstream.ID
+		err  error
+	}{
+		{"1700000000000-1", false, rstream.ID{1700000000000, 1}, nil},
+		{"5", false, rstream.ID{5, 0}, nil},
+		{"5", true, rstream.ID{5, rstream.MaxID.Seq}, nil},
+		{"-", false, rstream.MinID, nil},
+		{"+", false, rstream.MaxID, nil},
+		{"", false, rstream.ID{}, core.ErrSyntax},
+		{"5-", false, rstream.ID{}, core.ErrSyntax},
+		{"-5", false, rstream.ID{}, core.ErrSyntax},
+		{"a-1", false, rstream.ID{}, core.ErrSyntax},
+		{"1-a", false, rstream.ID{}, core.ErrSyntax},
+	}
+	for _, test := range tests {
+		t.Run(test.s, func(t *testing.T) {
+			id, err := rstream.ParseID(test.s, test.end)
+			testx.AssertEqual(t, err, test.err)
+			testx.AssertEqual(t, id, test.want)
+		})
+	}
+}
+
+var now = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func getDB(tb testing.TB) (*redka.DB, *rstream.DB) {
+	tb.Helper()
+	db, err := redka.Open(":memory:", &redka.Options{
+		Clock: func() time.Time { return now },
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db, db.Stream()
+}
@@ -0,0 +1,79 @@
+package rstream
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// ID identifies a stream entry. It consists of the entry
+// creation time in unix milliseconds and a sequence number
+// to tell apart the entries created at the same millisecond,
+// like 1700000000000-0.
+type ID struct {
+	Time int64
+	Seq  int64
+}
+
+// MinID and MaxID are the smallest and the largest possible
+// entry IDs (like "-" and "+" in Redis).
+var (
+	MinID = ID{0, 0}
+	MaxID = ID{math.MaxInt64, math.MaxInt64}
+)
+
+// ParseID parses the entry ID like "1700000000000-1".
+// If the sequence number is omitted, uses 0 (or math.MaxInt64
+// with end set to true, so that the ID includes all the entries
+// created at the millisecond). Also accepts "-" and "+" for
+// MinID and MaxID. Returns core.ErrSyntax if the ID is invalid.
+func ParseID(s string, end bool) (ID, error) {
+	switch s {
+	case "-":
+		return MinID, nil
+	case "+":
+		return MaxID, nil
+	}
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 63)
+	if err != nil {
+		return ID{}, core.ErrSyntax
+	}
+	id := ID{Time: int64(ms)}
+	if !hasSeq {
+		if end {
+			id.Seq = math.MaxInt64
+		}
+		return id, nil
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 63)
+	if err != nil {
+		return ID{}, core.ErrSyntax
+	}
+	id.Seq = int64(seq)
+	return id, nil
+}
+
+// String returns the ID like "1700000000000-1".
+func (id ID) String() string {
+	return strconv.FormatInt(id.Time, 10) + "-" + strconv.FormatInt(id.Seq, 10)
+}
+
+// Less reports whether the ID is less than the other one.
+func (id ID) Less(other ID) bool {
+	return id.Time < other.Time || id.Time == other.Time && id.Seq < other.Seq
+}
+
+// next returns the smallest ID greater than id,
+// or false if there is none.
+func (id ID) next() (ID, bool) {
+	switch {
+	case id.Seq < math.MaxInt64:
+		return ID{id.Time, id.Seq + 1}, true
+	case id.Time < math.MaxInt64:
+		return ID{id.Time + 1, 0}, true
+	}
+	return ID{}, false
+}
@@ -0,0 +1,354 @@
+package rstream
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"strconv"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+const (
+	sqlAdd1 = `
+	insert into rkey (id, key, type, version, mtime)
+	values (` + sqlx.NextKeyID + `, :key, :type, :version, :mtime)
+	on conflict (key) do update set
+	  version = version+1,
+	  type = excluded.type,
+	  mtime = excluded.mtime`
+
+	sqlAdd2 = `
+	insert into rstream (key_id, ms, seq, data)
+	values ((select id from rkey where key = :key), :ms, :seq, :data)`
+
+	sqlLast = `
+	select ms, seq
+	from rstream
+	  join rkey on key_id = rkey.id and type = :type
+	    and (etime is null or etime > :now)
+	where key = :key
+	order by ms desc, seq desc
+	limit 1`
+
+	sqlLen = `
+	select count(*)
+	from rstream
+	  join rkey on key_id = rkey.id and type = :type
+	    and (etime is null or etime > :now)
+	where key = :key`
+
+	sqlRange = `
+	select ms, seq, data
+	from rstream
+	  join rkey on key_id = rkey.id and type = :type
+	    and (etime is null or etime > :now)
+	where key = :key
+	  and (ms, seq) >= (:start_ms, :start_seq)
+	  and (ms, seq) <= (:end_ms, :end_seq)
+	order by ms, seq
+	limit :count`
+
+	sqlRevRange = `
+	select ms, seq, data
+	from rstream
+	  join rkey on key_id = rkey.id and type = :type
+	    and (etime is null or etime > :now)
+	where key = :key
+	  and (ms, seq) >= (:start_ms, :start_seq)
+	  and (ms, seq) <= (:end_ms, :end_seq)
+	order by ms desc, seq desc
+	limit :count`
+)
+
+// Field is a field-value pair in a stream entry.
+type Field struct {
+	Name  string
+	Value core.Value
+}
+
+// Entry is a stream entry: the ID and the field-value
+// pairs in the order they were added.
+type Entry struct {
+	ID     ID
+	Fields []Field
+}
+
+// Tx is a stream repository transaction.
+type Tx struct {
+	tx sqlx.Tx
+}
+
+// NewTx creates a stream repository transaction
+// from a generic database transaction.
+func NewTx(tx sqlx.Tx) *Tx {
+	return &Tx{tx}
+}
+
+// Add appends an entry to a stream and returns its ID.
+// Generates the ID from the current time, so that it is greater
+// than the IDs of the existing entries (see [ID]).
+// Takes field-value pairs, like Add("events", "user", "alice", "action", "login").
+// The fields must be strings. The values can be of any supported type
+// (see core.IsValueType). Returns ErrSyntax if there are no pairs,
+// or the last field has no value.
+// If the key does not exist, creates it.
+// If the key exists but is not a stream, returns ErrKeyType.
+func (tx *Tx) Add(key string, fields ...any) (ID, error) {
+	data, err := encodeFields(fields)
+	if err != nil {
+		return ID{}, err
+	}
+	last, ok, err := tx.last(key)
+	if err != nil {
+		return ID{}, err
+	}
+	id := ID{Time: sqlx.Now(tx.tx).UnixMilli()}
+	if ok && !last.Less(id) {
+		// The clock has not moved since the last entry
+		// (or has moved backwards), so keep its time.
+		if id, ok = last.next(); !ok {
+			return ID{}, core.ErrStreamID
+		}
+	}
+	return id, tx.add(key, id, data)
+}
+
+// AddID appends an entry with the given ID to a stream.
+// The ID must be greater than 0-0 and the IDs of the existing
+// entries, otherwise returns ErrStreamID. Takes the field-value
+// pairs like [Tx.Add].
+// If the key does not exist, creates it.
+// If the key exists but is not a stream, returns ErrKeyType.
+func (tx *Tx) AddID(key string, id ID, fields ...any) error {
+	data, err := encodeFields(fields)
+	if err != nil {
+		return err
+	}
+	if !MinID.Less(id) {
+		return core.ErrStreamID
+	}
+	last, ok, err := tx.last(key)
+	if err != nil {
+		return err
+	}
+	if ok && !last.Less(id) {
+		return core.ErrStreamID
+	}
+	return tx.add(key, id, data)
+}
+
+// Len returns the number of entries in a stream.
+// If the key does not exist or is not a stream, returns 0.
+func (tx *Tx) Len(key string) (int, error) {
+	args := []any{
+		sql.Named("key", key),
+		sql.Named("type", core.TypeStream),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
+	}
+	var n int
+	err := tx.tx.QueryRow(sqlLen, args...).Scan(&n)
+	return n, err
+}
+
+// Range returns the stream entries with IDs between start and end
+// (inclusive), ordered by ID. Use MinID and MaxID for the open ends.
+// Returns at most count entries (all of them if count is 0).
+// If the key does not exist or is not a stream, returns an empty slice.
+func (tx *Tx) Range(key string, start, end ID, count int) ([]Entry, error) {
+	return tx.rangeEntries(sqlRange, key, start, end, count)
+}
+
+// RevRange is like [Tx.Range], but returns the entries
+// in reverse order, starting from end.
+func (tx *Tx) RevRange(key string, end, start ID, count int) ([]Entry, error) {
+	return tx.rangeEntries(sqlRevRange, key, start, end, count)
+}
+
+// Read returns the stream entries with IDs greater than after,
+// ordered by ID. Use it to read the entries added since the last
+// seen one. Returns at most count entries (all of them if count is 0).
+// If the key does not exist or is not a stream, returns an empty slice.
+func (tx *Tx) Read(key string, after ID, count int) ([]Entry, error) {
+	start, ok := after.next()
+	if !ok {
+		return []Entry{}, nil
+	}
+	return tx.Range(key, start, MaxID, count)
+}
+
+// Last returns the ID of the last entry in a stream.
+// If the stream is empty, or the key does not exist
+// or is not a stream, returns ErrNotFound.
+func (tx *Tx) Last(key string) (ID, error) {
+	id, ok, err := tx.last(key)
+	if err != nil {
+		return ID{}, err
+	}
+	if !ok {
+		return ID{}, core.ErrNotFound
+	}
+	return id, nil
+}
+
+// add inserts the entry into a stream.
+func (tx *Tx) add(key string, id ID, data []byte) error {
+	if err := sqlx.CheckKey(tx.tx, key); err != nil {
+		return err
+	}
+	if err := sqlx.CheckSize(tx.tx, int64(len(data))); err != nil {
+		return err
+	}
+
+	args := []any{
+		sql.Named("key", key),
+		sql.Named("type", core.TypeStream),
+		sql.Named("version", core.InitialVersion),
+		sql.Named("mtime", sqlx.Now(tx.tx).UnixMilli()),
+		sql.Named("ms", id.Time),
+		sql.Named("seq", id.Seq),
+		sql.Named("data", data),
+	}
+
+	_, err := tx.tx.Exec(sqlAdd1, args...)
+	if err != nil {
+		return sqlx.KeyTypeError(tx.tx, err, key, core.TypeStream)
+	}
+
+	_, err = tx.tx.Exec(sqlAdd2, args...)
+	if err != nil {
+		return err
+	}
+
+	return sqlx.CheckElements(tx.tx, func() (int, error) {
+		return tx.Len(key)
+	})
+}
+
+// last returns the ID of the last entry in a stream,
+// or false if there are no entries.
+func (tx *Tx) last(key string) (ID, bool, error) {
+	args := []any{
+		sql.Named("key", key),
+		sql.Named("type", core.TypeStream),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
+	}
+	var id ID
+	err := tx.tx.QueryRow(sqlLast, args...).Scan(&id.Time, &id.Seq)
+	if err == sql.ErrNoRows {
+		return ID{}, false, nil
+	}
+	if err != nil {
+		return ID{}, false, err
+	}
+	return id, true, nil
+}
+
+// rangeEntries selects the stream entries between start and end.
+func (tx *Tx) rangeEntries(query string, key string, start, end ID, count int) ([]Entry, error) {
+	if count <= 0 {
+		count = -1
+	}
+	args := []any{
+		sql.Named("key", key),
+		sql.Named("type", core.TypeStream),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
+		sql.Named("start_ms", start.Time),
+		sql.Named("start_seq", start.Seq),
+		sql.Named("end_ms", end.Time),
+		sql.Named("end_seq", end.Seq),
+		sql.Named("count", count),
+	}
+	entries, err := sqlx.Select(tx.tx, query, args, scanEntry)
+	if err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+	return entries, nil
+}
+
+// scanEntry scans a stream entry from the current row.
+func scanEntry(rows *sql.Rows) (Entry, error) {
+	var e Entry
+	var data []byte
+	if err := rows.Scan(&e.ID.Time, &e.ID.Seq, &data); err != nil {
+		return Entry{}, err
+	}
+	fields, err := decodeFields(data)
+	e.Fields = fields
+	return e, err
+}
+
+// encodeFields encodes the field-value pairs into a blob:
+// the length-prefixed field name followed by the length-prefixed
+// value, for each pair.
+func encodeFields(fields []any) ([]byte, error) {
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		return nil, core.ErrSyntax
+	}
+	var data []byte
+	for i := 0; i < len(fields); i += 2 {
+		name, ok := fields[i].(string)
+		if !ok {
+			return nil, core.ErrValueType
+		}
+		value, ok := valueBytes(fields[i+1])
+		if !ok {
+			return nil, core.ErrValueType
+		}
+		data = binary.AppendUvarint(data, uint64(len(name)))
+		data = append(data, name...)
+		data = binary.AppendUvarint(data, uint64(len(value)))
+		data = append(data, value...)
+	}
+	return data, nil
+}
+
+// decodeFields decodes the field-value pairs encoded with encodeFields.
+func decodeFields(data []byte) ([]Field, error) {
+	var fields []Field
+	next := func() ([]byte, bool) {
+		n, size := binary.Uvarint(data)
+		if size <= 0 || uint64(len(data)-size) < n {
+			return nil, false
+		}
+		b := data[size : size+int(n)]
+		data = data[size+int(n):]
+		return b, true
+	}
+	for len(data) > 0 {
+		name, ok := next()
+		if !ok {
+			return nil, core.ErrValueType
+		}
+		value, ok := next()
+		if !ok {
+			return nil, core.ErrValueType
+		}
+		fields = append(fields, Field{Name: string(name), Value: core.Value(value)})
+	}
+	return fields, nil
+}
+
+// valueBytes returns the value as a byte slice,
+// or false if the value type is not supported.
+func valueBytes(v any) ([]byte, bool) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), true
+	case []byte:
+		return v, true
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), true
+	case float64:
+		return strconv.AppendFloat(nil, v, 'g', -1, 64), true
+	case bool:
+		if v {
+			return []byte("1"), true
+		}
+		return []byte("0"), true
+	}
+	return nil, false
+}
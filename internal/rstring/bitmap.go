@@ -0,0 +1,215 @@
+package rstring
+
+import (
+	"math/bits"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rkey"
+)
+
+// MaxBitOffset is the largest bit offset in a string value
+// (the values are limited to 512MB, like in Redis).
+const MaxBitOffset = 1<<32 - 1
+
+// BitUnit is the unit of the range in Tx.BitCount.
+type BitUnit int
+
+// Units of the bit count range.
+const (
+	Byte BitUnit = iota // the range is in bytes
+	Bit                 // the range is in bits
+)
+
+// BitwiseOp is the bitwise operation in Tx.BitOp.
+type BitwiseOp int
+
+// Bitwise operations.
+const (
+	BitAnd BitwiseOp = iota
+	BitOr
+	BitXor
+	BitNot
+)
+
+// GetBit returns the bit value at the offset in the key value.
+// The bits are numbered from the most significant bit of the
+// first byte. Returns false if the offset is beyond the end of the
+// value, or the key does not exist.
+// Returns ErrValueType if the offset is negative or greater than MaxBitOffset.
+func (tx *Tx) GetBit(key string, offset int) (bool, error) {
+	if offset < 0 || offset > MaxBitOffset {
+		return false, core.ErrValueType
+	}
+	val, err := tx.Get(key)
+	if err != nil {
+		return false, err
+	}
+	return getBit(val, offset), nil
+}
+
+// SetBit sets or clears the bit at the offset in the key value
+// and returns the previous value of the bit.
+// Grows the value with zero bytes if the offset is beyond its end.
+// If the key does not exist, creates it.
+// Does not change the expiration time of an existing key.
+// Returns ErrValueType if the offset is negative or greater than MaxBitOffset.
+func (tx *Tx) SetBit(key string, offset int, value bool) (bool, error) {
+	if offset < 0 || offset > MaxBitOffset {
+		return false, core.ErrValueType
+	}
+	val, err := tx.Get(key)
+	if err != nil {
+		return false, err
+	}
+	old := getBit(val, offset)
+
+	b := make([]byte, max(len(val), offset/8+1))
+	copy(b, val)
+	mask := byte(1) << (7 - offset%8)
+	if value {
+		b[offset/8] |= mask
+	} else {
+		b[offset/8] &^= mask
+	}
+
+	err = tx.update(key, b)
+	if err != nil {
+		return false, err
+	}
+	return old, nil
+}
+
+// BitCount returns the number of set bits in the key value between
+// start and end (inclusive). The range is in bytes or bits, depending
+// on the unit. Negative indexes count from the end of the value,
+// so 0, -1 counts the bits in the whole value.
+// If the key does not exist, returns 0.
+func (tx *Tx) BitCount(key string, start, end int, unit BitUnit) (int, error) {
+	val, err := tx.Get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	size := len(val)
+	if unit == Bit {
+		size *= 8
+	}
+	start, end, ok := clampRange(start, end, size)
+	if !ok {
+		return 0, nil
+	}
+
+	if unit == Byte {
+		return countBits(val[start : end+1]), nil
+	}
+	count := 0
+	for i := start; i <= end; i++ {
+		if getBit(val, i) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// BitOp performs the bitwise operation between the key values
+// and stores the result in the dest key. Returns the length of the
+// resulting value, which is the length of the longest source value
+// (shorter values are padded with zero bytes).
+// BitNot takes exactly one source key, the rest of the operations
+// take one or more keys. Otherwise, returns ErrSyntax.
+// Treats the keys that do not exist as empty values.
+// Overwrites the dest value and expiration time if the key already exists.
+// If the result is empty, deletes the dest key.
+// If the dest key exists but is not a string, returns ErrKeyType.
+func (tx *Tx) BitOp(op BitwiseOp, dest string, keys ...string) (int, error) {
+	if len(keys) == 0 || op == BitNot && len(keys) != 1 {
+		return 0, core.ErrSyntax
+	}
+
+	vals := make([]core.Value, len(keys))
+	size := 0
+	for i, key := range keys {
+		val, err := tx.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		vals[i] = val
+		size = max(size, len(val))
+	}
+
+	if size == 0 {
+		_, err := rkey.DeleteType(tx.tx, core.TypeString, dest)
+		return 0, err
+	}
+
+	res := make([]byte, size)
+	copy(res, vals[0])
+	switch op {
+	case BitNot:
+		for i := range res {
+			res[i] = ^res[i]
+		}
+	case BitAnd:
+		for _, val := range vals[1:] {
+			for i := range res {
+				if i < len(val) {
+					res[i] &= val[i]
+				} else {
+					res[i] = 0
+				}
+			}
+		}
+	case BitOr, BitXor:
+		for _, val := range vals[1:] {
+			for i := range val {
+				if op == BitOr {
+					res[i] |= val[i]
+				} else {
+					res[i] ^= val[i]
+				}
+			}
+		}
+	default:
+		return 0, core.ErrSyntax
+	}
+
+	err := tx.set(dest, res, 0)
+	if err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+// getBit returns the bit value at the offset,
+// or false if the offset is beyond the end of the value.
+func getBit(val []byte, offset int) bool {
+	if offset/8 >= len(val) {
+		return false
+	}
+	return val[offset/8]&(1<<(7-offset%8)) != 0
+}
+
+// countBits returns the number of set bits.
+func countBits(b []byte) int {
+	count := 0
+	for _, c := range b {
+		count += bits.OnesCount8(c)
+	}
+	return count
+}
+
+// clampRange converts the start and end indexes (possibly negative)
+// into the [0, size) range. Returns false if the range is empty.
+func clampRange(start, end, size int) (int, int, bool) {
+	if start < 0 {
+		start = max(size+start, 0)
+	}
+	if end < 0 {
+		end = size + end
+	}
+	end = min(end, size-1)
+	if size == 0 || start > end {
+		return 0, 0, false
+	}
+	return start, end, true
+}
@@ -3,7 +3,11 @@
 package rstring
 
 import (
+	"context"
 	"database/sql"
+	"io"
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/nalgeon/redka/internal/core"
@@ -24,62 +28,162 @@ func New(db *sql.DB) *DB {
 	return &DB{d}
 }
 
+// WithContext returns a shallow copy of the repository
+// that executes the queries with ctx. Use it to enforce
+// timeouts and cancellation on slow queries.
+func (d *DB) WithContext(ctx context.Context) *DB {
+	return &DB{d.DB.WithContext(ctx)}
+}
+
 // Get returns the value of the key.
 // Returns nil if the key does not exist.
 func (d *DB) Get(key string) (core.Value, error) {
-	tx := NewTx(d.SQL)
-	return tx.Get(key)
+	op := d.Observe("Str.Get", key)
+	tx := NewTx(d.In(op).ReadConn())
+	val, err := tx.Get(key)
+	if err == nil {
+		d.Lookup(val.Exists())
+	}
+	return val, op.Done(err)
+}
+
+// Reader returns a reader for the key value.
+// See [Tx.Reader] for details.
+func (d *DB) Reader(key string) (*Reader, error) {
+	op := d.Observe("Str.Reader", key)
+	tx := NewTx(d.In(op).ReadConn())
+	r, err := tx.Reader(key)
+	if err != nil && err != core.ErrNotFound {
+		return nil, op.Done(err)
+	}
+	d.Lookup(err == nil)
+	return r, op.Done(err)
+}
+
+// GetEx returns the value of the key and sets its expiration time
+// (if ttl > 0) or removes it (if ttl = 0), like GETEX in Redis.
+// Returns nil if the key does not exist.
+// If the key exists but is not a string, returns nil
+// and does not change the expiration time.
+func (d *DB) GetEx(key string, ttl time.Duration) (core.Value, error) {
+	op := d.Observe("Str.GetEx", key)
+	var val core.Value
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		val, err = tx.GetEx(key, ttl)
+		return err
+	})
+	return val, op.Done(err)
+}
+
+// GetDel returns the value of the key and deletes the key,
+// like GETDEL in Redis. Returns nil if the key does not exist.
+// If the key exists but is not a string, returns nil
+// and does not delete the key.
+func (d *DB) GetDel(key string) (core.Value, error) {
+	op := d.Observe("Str.GetDel", key)
+	var val core.Value
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		val, err = tx.GetDel(key)
+		return err
+	})
+	return val, op.Done(err)
 }
 
 // GetMany returns a map of values for given keys.
 // Returns nil for keys that do not exist.
 func (d *DB) GetMany(keys ...string) (map[string]core.Value, error) {
-	tx := NewTx(d.SQL)
-	return tx.GetMany(keys...)
+	op := d.Observe("Str.GetMany", keys...)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.GetMany(keys...)
+	if err == nil {
+		for _, key := range keys {
+			d.Lookup(items[key].Exists())
+		}
+	}
+	return items, op.Done(err)
 }
 
 // Set sets the key value that will not expire.
 // Overwrites the value if the key already exists.
 func (d *DB) Set(key string, value any) error {
-	err := d.Update(func(tx *Tx) error {
+	op := d.Observe("Str.Set", key)
+	err := d.In(op).Update(func(tx *Tx) error {
 		return tx.Set(key, value)
 	})
-	return err
+	return op.Done(err)
 }
 
 // SetExpires sets the key value with an optional expiration time (if ttl > 0).
 // Overwrites the value and ttl if the key already exists.
 func (d *DB) SetExpires(key string, value any, ttl time.Duration) error {
-	err := d.Update(func(tx *Tx) error {
+	op := d.Observe("Str.SetExpires", key)
+	err := d.In(op).Update(func(tx *Tx) error {
 		return tx.SetExpires(key, value, ttl)
 	})
-	return err
+	return op.Done(err)
+}
+
+// SetReader sets the key value to the size bytes read from r
+// and (optionally) its expiration time (if ttl > 0).
+// See [Tx.SetReader] for details.
+func (d *DB) SetReader(key string, r io.Reader, size int64, ttl time.Duration) error {
+	op := d.Observe("Str.SetReader", key)
+	err := d.In(op).Update(func(tx *Tx) error {
+		return tx.SetReader(key, r, size, ttl)
+	})
+	return op.Done(err)
+}
+
+// SetWith sets the key value with additional options
+// (see [SetCmd]).
+func (d *DB) SetWith(key string, value any) SetCmd {
+	return SetCmd{db: d, key: key, value: value}
 }
 
 // SetNotExists sets the key value if the key does not exist.
 // Optionally sets the expiration time (if ttl > 0).
 // Returns true if the key was set, false if the key already exists.
 func (d *DB) SetNotExists(key string, value any, ttl time.Duration) (bool, error) {
+	op := d.Observe("Str.SetNotExists", key)
 	var ok bool
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		ok, err = tx.SetNotExists(key, value, ttl)
 		return err
 	})
-	return ok, err
+	return ok, op.Done(err)
 }
 
 // SetExists sets the key value if the key exists.
 // Optionally sets the expiration time (if ttl > 0).
 // Returns true if the key was set, false if the key does not exist.
 func (d *DB) SetExists(key string, value any, ttl time.Duration) (bool, error) {
+	op := d.Observe("Str.SetExists", key)
 	var ok bool
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		ok, err = tx.SetExists(key, value, ttl)
 		return err
 	})
-	return ok, err
+	return ok, op.Done(err)
+}
+
+// SetIfVersion sets the key value, but only if the key has not
+// changed since it was read as prev (see core.Key.SameVersion),
+// or does not exist if prev does not exist. Does not change the
+// expiration time of an existing key. Returns true if the key
+// was set, false if the key was changed by someone else.
+func (d *DB) SetIfVersion(key string, value any, prev core.Key) (bool, error) {
+	op := d.Observe("Str.SetIfVersion", key)
+	var ok bool
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		ok, err = tx.SetIfVersion(key, value, prev)
+		return err
+	})
+	return ok, op.Done(err)
 }
 
 // GetSet returns the previous value of a key after setting it to a new value.
@@ -87,13 +191,14 @@ func (d *DB) SetExists(key string, value any, ttl time.Duration) (bool, error) {
 // Overwrites the value and ttl if the key already exists.
 // Returns nil if the key did not exist.
 func (d *DB) GetSet(key string, value any, ttl time.Duration) (core.Value, error) {
+	op := d.Observe("Str.GetSet", key)
 	var val core.Value
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		val, err = tx.GetSet(key, value, ttl)
 		return err
 	})
-	return val, err
+	return val, op.Done(err)
 }
 
 // SetMany sets the values of multiple keys.
@@ -101,23 +206,25 @@ func (d *DB) GetSet(key string, value any, ttl time.Duration) (core.Value, error
 // creates new keys/values for keys that do not exist.
 // Removes the TTL for existing keys.
 func (d *DB) SetMany(items map[string]any) error {
-	err := d.Update(func(tx *Tx) error {
+	op := d.Observe("Str.SetMany", slices.Collect(maps.Keys(items))...)
+	err := d.In(op).Update(func(tx *Tx) error {
 		return tx.SetMany(items)
 	})
-	return err
+	return op.Done(err)
 }
 
 // SetManyNX sets the values of multiple keys, but only if none
 // of them yet exist. Returns true if the keys were set, false if any
 // of them already exist.
 func (d *DB) SetManyNX(items map[string]any) (bool, error) {
+	op := d.Observe("Str.SetManyNX", slices.Collect(maps.Keys(items))...)
 	var ok bool
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		ok, err = tx.SetManyNX(items)
 		return err
 	})
-	return ok, err
+	return ok, op.Done(err)
 }
 
 // Incr increments the key value by the specified amount.
@@ -125,13 +232,14 @@ func (d *DB) SetManyNX(items map[string]any) (bool, error) {
 // Returns the value after the increment.
 // Returns an error if the key value is not an integer.
 func (d *DB) Incr(key string, delta int) (int, error) {
+	op := d.Observe("Str.Incr", key)
 	var val int
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		val, err = tx.Incr(key, delta)
 		return err
 	})
-	return val, err
+	return val, op.Done(err)
 }
 
 // IncrFloat increments the key value by the specified amount.
@@ -139,11 +247,97 @@ func (d *DB) Incr(key string, delta int) (int, error) {
 // Returns the value after the increment.
 // Returns an error if the key value is not a float.
 func (d *DB) IncrFloat(key string, delta float64) (float64, error) {
+	op := d.Observe("Str.IncrFloat", key)
 	var val float64
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		val, err = tx.IncrFloat(key, delta)
 		return err
 	})
-	return val, err
+	return val, op.Done(err)
+}
+
+// Append appends the value to the end of the key value
+// and returns the length of the resulting value.
+// See [Tx.Append] for details.
+func (d *DB) Append(key string, value any) (int, error) {
+	op := d.Observe("Str.Append", key)
+	var n int
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		n, err = tx.Append(key, value)
+		return err
+	})
+	return n, op.Done(err)
+}
+
+// SetRange overwrites part of the key value starting at the offset
+// and returns the length of the resulting value.
+// See [Tx.SetRange] for details.
+func (d *DB) SetRange(key string, offset int, value any) (int, error) {
+	op := d.Observe("Str.SetRange", key)
+	var n int
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		n, err = tx.SetRange(key, offset, value)
+		return err
+	})
+	return n, op.Done(err)
+}
+
+// GetRange returns the part of the key value between
+// start and end (inclusive).
+// See [Tx.GetRange] for details.
+func (d *DB) GetRange(key string, start, end int) (core.Value, error) {
+	op := d.Observe("Str.GetRange", key)
+	tx := NewTx(d.In(op).ReadConn())
+	val, err := tx.GetRange(key, start, end)
+	return val, op.Done(err)
+}
+
+// GetBit returns the bit value at the offset in the key value.
+// See [Tx.GetBit] for details.
+func (d *DB) GetBit(key string, offset int) (bool, error) {
+	op := d.Observe("Str.GetBit", key)
+	tx := NewTx(d.In(op).ReadConn())
+	bit, err := tx.GetBit(key, offset)
+	return bit, op.Done(err)
+}
+
+// SetBit sets or clears the bit at the offset in the key value
+// and returns the previous value of the bit.
+// See [Tx.SetBit] for details.
+func (d *DB) SetBit(key string, offset int, value bool) (bool, error) {
+	op := d.Observe("Str.SetBit", key)
+	var old bool
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		old, err = tx.SetBit(key, offset, value)
+		return err
+	})
+	return old, op.Done(err)
+}
+
+// BitCount returns the number of set bits in the key value
+// between start and end (inclusive).
+// See [Tx.BitCount] for details.
+func (d *DB) BitCount(key string, start, end int, unit BitUnit) (int, error) {
+	op := d.Observe("Str.BitCount", key)
+	tx := NewTx(d.In(op).ReadConn())
+	count, err := tx.BitCount(key, start, end, unit)
+	return count, op.Done(err)
+}
+
+// BitOp performs the bitwise operation between the key values
+// and stores the result in the dest key.
+// See [Tx.BitOp] for details.
+func (d *DB) BitOp(op BitwiseOp, dest string, keys ...string) (int, error) {
+	o := d.Observe("Str.BitOp", append([]string{dest}, keys...)...)
+	var n int
+	err := d.In(o).Update(func(tx *Tx) error {
+		var err error
+		n, err = tx.BitOp(op, dest, keys...)
+		return err
+	})
+	return n, o.Done(err)
 }
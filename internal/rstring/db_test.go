@@ -1,6 +1,9 @@
 package rstring_test
 
 import (
+	"bytes"
+	"io"
+	"strings"
 	"testing"
 	"time"
 
@@ -221,6 +224,195 @@ func TestSetNotExists(t *testing.T) {
 	})
 }
 
+func TestSetIfVersion(t *testing.T) {
+	t.Run("same version", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+		prev, _ := red.Key().Get("name")
+
+		ok, err := db.SetIfVersion("name", "bob", prev)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val.String(), "bob")
+		key, _ := red.Key().Get("name")
+		testx.AssertEqual(t, key.Version, 2)
+	})
+	t.Run("changed", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+		prev, _ := red.Key().Get("name")
+		_ = db.Set("name", "bob")
+
+		ok, err := db.SetIfVersion("name", "cindy", prev)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val.String(), "bob")
+	})
+	t.Run("recreated", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+		prev, _ := red.Key().Get("name")
+		_, _ = red.Key().Delete("name")
+		_ = db.Set("name", "bob")
+
+		ok, err := db.SetIfVersion("name", "cindy", prev)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val.String(), "bob")
+	})
+	t.Run("keep ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.SetExpires("name", "alice", time.Minute)
+		prev, _ := red.Key().Get("name")
+
+		ok, err := db.SetIfVersion("name", "bob", prev)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		key, _ := red.Key().Get("name")
+		testx.AssertEqual(t, *key.ETime, *prev.ETime)
+	})
+	t.Run("not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		ok, err := db.SetIfVersion("name", "alice", core.Key{})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		key, _ := red.Key().Get("name")
+		testx.AssertEqual(t, key.ETime, (*int64)(nil))
+
+		prev, _ := red.Key().Get("name")
+		ok, err = db.SetIfVersion("city", "paris", prev)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = red.Hash().Set("person", "name", "alice")
+		prev, _ := red.Key().Get("person")
+
+		ok, err := db.SetIfVersion("person", "alice", prev)
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, ok, false)
+	})
+}
+
+func TestSetReader(t *testing.T) {
+	t.Run("large value", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		data := bytes.Repeat([]byte("0123456789"), rstring.ChunkSize/4)
+		err := db.SetReader("blob", bytes.NewReader(data), int64(len(data)), 0)
+		testx.AssertNoErr(t, err)
+		val, _ := db.Get("blob")
+		testx.AssertEqual(t, bytes.Equal(val.Bytes(), data), true)
+	})
+	t.Run("ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		err := db.SetReader("name", strings.NewReader("alice"), 5, time.Minute)
+		testx.AssertNoErr(t, err)
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val.String(), "alice")
+		key, _ := red.Key().Get("name")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("empty", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		err := db.SetReader("name", strings.NewReader(""), 0, 0)
+		testx.AssertNoErr(t, err)
+		count, _ := red.Key().Count("name")
+		testx.AssertEqual(t, count, 1)
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val.String(), "")
+	})
+	t.Run("short read", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+
+		err := db.SetReader("name", strings.NewReader("bob"), 5, 0)
+		testx.AssertErr(t, err, io.ErrUnexpectedEOF)
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val.String(), "alice")
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = red.Hash().Set("person", "name", "alice")
+
+		err := db.SetReader("person", strings.NewReader("alice"), 5, 0)
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+}
+
+func TestReader(t *testing.T) {
+	t.Run("read", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		data := bytes.Repeat([]byte("0123456789"), rstring.ChunkSize/4)
+		_ = db.Set("blob", data)
+
+		r, err := db.Reader("blob")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, r.Size(), int64(len(data)))
+		got, err := io.ReadAll(r)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, bytes.Equal(got, data), true)
+	})
+	t.Run("seek", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+
+		r, _ := db.Reader("name")
+		_, err := r.Seek(-3, io.SeekEnd)
+		testx.AssertNoErr(t, err)
+		got, _ := io.ReadAll(r)
+		testx.AssertEqual(t, string(got), "ice")
+	})
+	t.Run("binary", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "h\u00e9\x00llo")
+
+		r, _ := db.Reader("name")
+		testx.AssertEqual(t, r.Size(), int64(7))
+		got, err := io.ReadAll(r)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, string(got), "h\u00e9\x00llo")
+	})
+	t.Run("not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, err := db.Reader("name")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("changed", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+
+		r, _ := db.Reader("name")
+		_ = db.Set("name", "bob")
+		_, err := io.ReadAll(r)
+		testx.AssertErr(t, err, core.ErrVersion)
+	})
+}
+
 func TestSetExists(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -326,6 +518,216 @@ func TestGetSet(t *testing.T) {
 	})
 }
 
+func TestGetEx(t *testing.T) {
+	t.Run("set ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+
+		now := time.Now()
+		ttl := time.Minute
+		val, err := db.GetEx("name", ttl)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val, core.Value("alice"))
+
+		key, _ := red.Key().Get("name")
+		got := (*key.ETime) / 1000
+		want := now.Add(ttl).UnixMilli() / 1000
+		testx.AssertEqual(t, got, want)
+	})
+	t.Run("persist", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.SetExpires("name", "alice", time.Minute)
+
+		val, err := db.GetEx("name", 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val, core.Value("alice"))
+
+		key, _ := red.Key().Get("name")
+		testx.AssertEqual(t, key.ETime, (*int64)(nil))
+	})
+	t.Run("key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		val, err := db.GetEx("name", time.Minute)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val, core.Value(nil))
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = red.Hash().Set("person", "name", "alice")
+
+		val, err := db.GetEx("person", time.Minute)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val, core.Value(nil))
+
+		key, _ := red.Key().Get("person")
+		testx.AssertEqual(t, key.ETime, (*int64)(nil))
+	})
+}
+
+func TestGetDel(t *testing.T) {
+	t.Run("delete", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+
+		val, err := db.GetDel("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val, core.Value("alice"))
+
+		exists, _ := red.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		val, err := db.GetDel("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val, core.Value(nil))
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = red.Hash().Set("person", "name", "alice")
+
+		val, err := db.GetDel("person")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val, core.Value(nil))
+
+		exists, _ := red.Key().Exists("person")
+		testx.AssertEqual(t, exists, true)
+	})
+}
+
+func TestSetWith(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		out, err := db.SetWith("name", "alice").Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, out.Created, true)
+		testx.AssertEqual(t, out.Updated, false)
+
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val, core.Value("alice"))
+	})
+	t.Run("update", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.SetExpires("name", "alice", time.Minute)
+
+		out, err := db.SetWith("name", "bob").Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, out.Created, false)
+		testx.AssertEqual(t, out.Updated, true)
+
+		key, _ := red.Key().Get("name")
+		testx.AssertEqual(t, key.ETime, (*int64)(nil))
+	})
+	t.Run("if not exists", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+
+		out, err := db.SetWith("name", "bob").IfNotExists().Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, out.Created, false)
+		testx.AssertEqual(t, out.Updated, false)
+
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val, core.Value("alice"))
+	})
+	t.Run("if exists", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		out, err := db.SetWith("name", "bob").IfExists().Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, out.Created, false)
+		testx.AssertEqual(t, out.Updated, false)
+
+		exists, _ := red.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("get", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+
+		out, err := db.SetWith("name", "bob").Get().Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, out.Prev, core.Value("alice"))
+		testx.AssertEqual(t, out.Updated, true)
+
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val, core.Value("bob"))
+	})
+	t.Run("keep ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.SetExpires("name", "alice", time.Minute)
+		before, _ := red.Key().Get("name")
+
+		_, err := db.SetWith("name", "bob").KeepTTL().Run()
+		testx.AssertNoErr(t, err)
+
+		after, _ := red.Key().Get("name")
+		testx.AssertEqual(t, *after.ETime, *before.ETime)
+		val, _ := db.Get("name")
+		testx.AssertEqual(t, val, core.Value("bob"))
+	})
+	t.Run("at", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		at := time.Now().Add(time.Minute)
+		_, err := db.SetWith("name", "alice").At(at).Run()
+		testx.AssertNoErr(t, err)
+
+		key, _ := red.Key().Get("name")
+		testx.AssertEqual(t, *key.ETime, at.UnixMilli())
+	})
+	t.Run("at in the past", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("name", "alice")
+
+		out, err := db.SetWith("name", "bob").At(time.Now().Add(-time.Minute)).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, out.Updated, true)
+
+		exists, _ := red.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = red.Hash().Set("person", "name", "alice")
+
+		_, err := db.SetWith("person", "alice").Run()
+		testx.AssertErr(t, err, core.ErrKeyType)
+		_, err = db.SetWith("person", "alice").Get().Run()
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+	t.Run("transaction", func(t *testing.T) {
+		red, _ := getDB(t)
+		defer red.Close()
+
+		err := red.Update(func(tx *redka.Tx) error {
+			out, err := tx.Str().SetWith("name", "alice").IfNotExists().Run()
+			testx.AssertEqual(t, out.Created, true)
+			return err
+		})
+		testx.AssertNoErr(t, err)
+	})
+}
+
 func TestSetMany(t *testing.T) {
 	t.Run("create", func(t *testing.T) {
 		red, db := getDB(t)
@@ -506,6 +908,368 @@ func TestIncrFloat(t *testing.T) {
 	})
 }
 
+func TestAppend(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		n, err := db.Append("key", "hello")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 5)
+
+		val, _ := db.Get("key")
+		testx.AssertEqual(t, val, core.Value("hello"))
+	})
+	t.Run("update", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("key", "hello")
+		key, _ := red.Key().Get("key")
+
+		n, err := db.Append("key", " world")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 11)
+
+		val, _ := db.Get("key")
+		testx.AssertEqual(t, val, core.Value("hello world"))
+		after, _ := red.Key().Get("key")
+		testx.AssertEqual(t, after.Version, key.Version+1)
+	})
+	t.Run("number", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("key", 4)
+
+		n, err := db.Append("key", 2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 2)
+
+		val, _ := db.Get("key")
+		testx.AssertEqual(t, val, core.Value("42"))
+	})
+	t.Run("keep ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.SetExpires("key", "a", time.Minute)
+
+		_, err := db.Append("key", "b")
+		testx.AssertNoErr(t, err)
+
+		key, _ := red.Key().Get("key")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("invalid value", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, err := db.Append("key", struct{}{})
+		testx.AssertErr(t, err, core.ErrValueType)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = red.Hash().Set("person", "age", 25)
+
+		_, err := db.Append("person", "a")
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+}
+
+func TestSetRange(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		n, err := db.SetRange("key", 2, "hi")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 4)
+
+		val, _ := db.Get("key")
+		testx.AssertEqual(t, val, core.Value("\x00\x00hi"))
+	})
+	t.Run("overwrite", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("key", "hello world")
+		key, _ := red.Key().Get("key")
+
+		n, err := db.SetRange("key", 6, "redka")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 11)
+
+		val, _ := db.Get("key")
+		testx.AssertEqual(t, val, core.Value("hello redka"))
+		after, _ := red.Key().Get("key")
+		testx.AssertEqual(t, after.Version, key.Version+1)
+	})
+	t.Run("grow", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("key", "hello")
+
+		n, err := db.SetRange("key", 3, "p me")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 7)
+
+		val, _ := db.Get("key")
+		testx.AssertEqual(t, val, core.Value("help me"))
+	})
+	t.Run("empty value", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("key", "hello")
+
+		n, err := db.SetRange("key", 10, "")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 5)
+
+		n, err = db.SetRange("other", 10, "")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 0)
+		exists, _ := red.Key().Exists("other")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("keep ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.SetExpires("key", "a", time.Minute)
+
+		_, err := db.SetRange("key", 0, "b")
+		testx.AssertNoErr(t, err)
+
+		key, _ := red.Key().Get("key")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("invalid offset", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, err := db.SetRange("key", -1, "a")
+		testx.AssertErr(t, err, core.ErrValueType)
+		_, err = db.SetRange("key", rstring.MaxBitOffset/8+1, "a")
+		testx.AssertErr(t, err, core.ErrValueTooLarge)
+		exists, _ := red.Key().Exists("key")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = red.Hash().Set("person", "age", 25)
+
+		_, err := db.SetRange("person", 0, "a")
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+}
+
+func TestGetRange(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+	_ = db.Set("key", "This is a string")
+
+	tests := []struct {
+		name       string
+		key        string
+		start, end int
+		want       string
+	}{
+		{"start", "key", 0, 3, "This"},
+		{"negative", "key", -3, -1, "ing"},
+		{"whole", "key", 0, -1, "This is a string"},
+		{"out of range", "key", 10, 100, "string"},
+		{"empty range", "key", 5, 3, ""},
+		{"not found", "other", 0, -1, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			val, err := db.GetRange(test.key, test.start, test.end)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, val.String(), test.want)
+		})
+	}
+}
+
+func TestGetBit(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = db.Set("key", "\x80\x01")
+
+	tests := []struct {
+		name   string
+		key    string
+		offset int
+		want   bool
+	}{
+		{"first bit", "key", 0, true},
+		{"second bit", "key", 1, false},
+		{"last bit", "key", 15, true},
+		{"beyond end", "key", 100, false},
+		{"key not found", "other", 0, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bit, err := db.GetBit(test.key, test.offset)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, bit, test.want)
+		})
+	}
+	t.Run("invalid offset", func(t *testing.T) {
+		_, err := db.GetBit("key", -1)
+		testx.AssertErr(t, err, core.ErrValueType)
+		_, err = db.GetBit("key", rstring.MaxBitOffset+1)
+		testx.AssertErr(t, err, core.ErrValueType)
+	})
+}
+
+func TestSetBit(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		old, err := db.SetBit("key", 10, true)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, old, false)
+
+		val, _ := db.Get("key")
+		testx.AssertEqual(t, val, core.Value("\x00\x20"))
+	})
+	t.Run("update", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("key", "\xff")
+		key, _ := red.Key().Get("key")
+
+		old, err := db.SetBit("key", 7, false)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, old, true)
+
+		val, _ := db.Get("key")
+		testx.AssertEqual(t, val, core.Value("\xfe"))
+		after, _ := red.Key().Get("key")
+		testx.AssertEqual(t, after.Version, key.Version+1)
+	})
+	t.Run("grow", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.Set("key", "a")
+
+		old, err := db.SetBit("key", 23, true)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, old, false)
+
+		val, _ := db.Get("key")
+		testx.AssertEqual(t, val, core.Value("a\x00\x01"))
+	})
+	t.Run("keep ttl", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = db.SetExpires("key", "a", time.Minute)
+
+		_, err := db.SetBit("key", 0, true)
+		testx.AssertNoErr(t, err)
+
+		key, _ := red.Key().Get("key")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("invalid offset", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, err := db.SetBit("key", -1, true)
+		testx.AssertErr(t, err, core.ErrValueType)
+		exists, _ := red.Key().Exists("key")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = red.Hash().Set("person", "age", 25)
+
+		_, err := db.SetBit("person", 0, true)
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+}
+
+func TestBitCount(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = db.Set("key", "foobar")
+
+	tests := []struct {
+		name       string
+		key        string
+		start, end int
+		unit       rstring.BitUnit
+		want       int
+	}{
+		{"all", "key", 0, -1, rstring.Byte, 26},
+		{"bytes", "key", 1, 1, rstring.Byte, 6},
+		{"negative bytes", "key", -2, -1, rstring.Byte, 7},
+		{"bits", "key", 5, 30, rstring.Bit, 17},
+		{"out of range", "key", 10, 20, rstring.Byte, 0},
+		{"reversed", "key", 3, 1, rstring.Byte, 0},
+		{"key not found", "other", 0, -1, rstring.Byte, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			count, err := db.BitCount(test.key, test.start, test.end, test.unit)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, count, test.want)
+		})
+	}
+}
+
+func TestBitOp(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_ = db.Set("key1", "foobar")
+	_ = db.Set("key2", "abcd")
+
+	tests := []struct {
+		name string
+		op   rstring.BitwiseOp
+		keys []string
+		want core.Value
+	}{
+		{"and", rstring.BitAnd, []string{"key1", "key2"}, core.Value("`bc`\x00\x00")},
+		{"or", rstring.BitOr, []string{"key1", "key2"}, core.Value("goofar")},
+		{"xor", rstring.BitXor, []string{"key1", "key2"}, core.Value("\x07\r\x0c\x06ar")},
+		{"not", rstring.BitNot, []string{"key2"}, core.Value("\x9e\x9d\x9c\x9b")},
+		{"single key", rstring.BitAnd, []string{"key2"}, core.Value("abcd")},
+		{"key not found", rstring.BitOr, []string{"key2", "other"}, core.Value("abcd")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := db.BitOp(test.op, "dest", test.keys...)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, n, len(test.want))
+			val, _ := db.Get("dest")
+			testx.AssertEqual(t, val, test.want)
+		})
+	}
+	t.Run("empty result", func(t *testing.T) {
+		_ = db.Set("dest", "old")
+		n, err := db.BitOp(rstring.BitOr, "dest", "other")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 0)
+		exists, _ := red.Key().Exists("dest")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("invalid keys", func(t *testing.T) {
+		_, err := db.BitOp(rstring.BitNot, "dest", "key1", "key2")
+		testx.AssertErr(t, err, core.ErrSyntax)
+		_, err = db.BitOp(rstring.BitAnd, "dest")
+		testx.AssertErr(t, err, core.ErrSyntax)
+	})
+	t.Run("dest type mismatch", func(t *testing.T) {
+		_, _ = red.Hash().Set("person", "age", 25)
+		_, err := db.BitOp(rstring.BitOr, "person", "key1")
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+}
+
 func getDB(tb testing.TB) (*redka.DB, *rstring.DB) {
 	tb.Helper()
 	db, err := redka.Open(":memory:", nil)
@@ -0,0 +1,165 @@
+package rstring
+
+import (
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rkey"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// SetCmd sets the key value with additional options.
+type SetCmd struct {
+	db      *DB
+	tx      *Tx
+	key     string
+	value   any
+	ifNX    bool
+	ifXX    bool
+	get     bool
+	keepTTL bool
+	ttl     time.Duration
+	at      time.Time
+}
+
+// IfNotExists sets the value only if the key
+// does not exist (like NX in Redis).
+func (c SetCmd) IfNotExists() SetCmd {
+	c.ifNX = true
+	c.ifXX = false
+	return c
+}
+
+// IfExists sets the value only if the key
+// already exists (like XX in Redis).
+func (c SetCmd) IfExists() SetCmd {
+	c.ifXX = true
+	c.ifNX = false
+	return c
+}
+
+// Get returns the previous value of the key
+// in SetOut.Prev (like GET in Redis).
+func (c SetCmd) Get() SetCmd {
+	c.get = true
+	return c
+}
+
+// TTL sets the time-to-live for the key
+// (like EX and PX in Redis).
+func (c SetCmd) TTL(ttl time.Duration) SetCmd {
+	c.ttl = ttl
+	c.at = time.Time{}
+	c.keepTTL = false
+	return c
+}
+
+// At sets the expiration time for the key
+// (like EXAT and PXAT in Redis).
+func (c SetCmd) At(at time.Time) SetCmd {
+	c.at = at
+	c.ttl = 0
+	c.keepTTL = false
+	return c
+}
+
+// KeepTTL keeps the current expiration time of an existing key
+// (like KEEPTTL in Redis). Without it, the key does not expire
+// unless TTL or At is set.
+func (c SetCmd) KeepTTL() SetCmd {
+	c.keepTTL = true
+	c.ttl = 0
+	c.at = time.Time{}
+	return c
+}
+
+// SetOut is the output of the SetCmd.
+type SetOut struct {
+	Prev    core.Value // previous value (with SetCmd.Get)
+	Created bool       // true if the key was created
+	Updated bool       // true if the existing key was updated
+}
+
+// Run sets the key value according to the options.
+// Does nothing if the IfExists or IfNotExists condition is not met,
+// so both Created and Updated are false. If the expiration time
+// is in the past, deletes the key instead of setting it
+// (as if the key has expired right away).
+// If the key exists but is not a string, returns ErrKeyType.
+func (c SetCmd) Run() (SetOut, error) {
+	if c.db != nil {
+		op := c.db.Observe("Str.SetWith", c.key)
+		var out SetOut
+		err := c.db.In(op).Update(func(tx *Tx) error {
+			var err error
+			out, err = c.run(tx)
+			return err
+		})
+		return out, op.Done(err)
+	}
+	if c.tx != nil {
+		return c.run(c.tx)
+	}
+	return SetOut{}, nil
+}
+
+// run sets the key value in a transaction.
+func (c SetCmd) run(tx *Tx) (SetOut, error) {
+	if !core.IsValueType(c.value) {
+		return SetOut{}, core.ErrValueType
+	}
+
+	k, err := rkey.Get(tx.tx, c.key)
+	if err != nil {
+		return SetOut{}, err
+	}
+	if c.get && k.Exists() && k.Type != core.TypeString {
+		return SetOut{}, core.ErrKeyType
+	}
+
+	var out SetOut
+	if c.get {
+		out.Prev, err = tx.Get(c.key)
+		if err != nil {
+			return SetOut{}, err
+		}
+	}
+	if c.ifNX && k.Exists() || c.ifXX && !k.Exists() {
+		return out, nil
+	}
+
+	now := sqlx.Now(tx.tx)
+	var etime *int64
+	switch {
+	case c.keepTTL:
+		etime = k.ETime
+	case !c.at.IsZero():
+		etime = new(int64)
+		*etime = c.at.UnixMilli()
+	case c.ttl > 0:
+		etime = new(int64)
+		*etime = now.Add(c.ttl).UnixMilli()
+	}
+
+	if etime != nil && *etime <= now.UnixMilli() {
+		// The key would expire immediately, so delete it instead.
+		if k.Exists() && k.Type != core.TypeString {
+			return SetOut{}, core.ErrKeyType
+		}
+		_, err := rkey.Delete(tx.tx, c.key)
+		if err != nil {
+			return SetOut{}, err
+		}
+		out.Created = !k.Exists()
+		out.Updated = k.Exists()
+		return out, nil
+	}
+
+	err = tx.setAt(c.key, c.value, etime)
+	if err != nil {
+		return SetOut{}, err
+	}
+	out.Created = !k.Exists()
+	out.Updated = k.Exists()
+	return out, nil
+}
@@ -2,7 +2,11 @@ package rstring
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
+	"io"
 	"slices"
+	"strconv"
 	"time"
 
 	"github.com/nalgeon/redka/internal/core"
@@ -25,8 +29,8 @@ where key in (:keys) and (etime is null or etime > :now);
 `
 
 var sqlSet = []string{
-	`insert into rkey (key, type, version, etime, mtime)
-	values (:key, :type, :version, :etime, :mtime)
+	`insert into rkey (id, key, type, version, etime, mtime)
+	values (` + sqlx.NextKeyID + `, :key, :type, :version, :etime, :mtime)
 	on conflict (key) do update set
 	  version = version+1,
 	  type = excluded.type,
@@ -41,8 +45,8 @@ var sqlSet = []string{
 }
 
 var sqlUpdate = []string{
-	`insert into rkey (key, type, version, etime, mtime)
-	values (:key, :type, :version, null, :mtime)
+	`insert into rkey (id, key, type, version, etime, mtime)
+	values (` + sqlx.NextKeyID + `, :key, :type, :version, null, :mtime)
 	on conflict (key) do update set
 	  version = version+1,
 	  type = excluded.type,
@@ -56,6 +60,46 @@ var sqlUpdate = []string{
 	set value = excluded.value;`,
 }
 
+// ChunkSize is the size of the chunks used to stream
+// the values (see Tx.SetReader and Tx.Reader).
+const ChunkSize = 1 << 20
+
+var sqlChunkSetup = []string{
+	`create temp table if not exists rstring_chunk (
+	  seq  integer primary key,
+	  data blob not null
+	);`,
+	`delete from rstring_chunk;`,
+}
+
+const sqlChunkAdd = `
+insert into rstring_chunk (seq, data) values (?, ?);`
+
+const sqlChunkCommit = `
+update rstring
+set value = coalesce(
+  (select cast(group_concat(data, '') as blob)
+   from (select data from rstring_chunk order by seq)),
+  x'')
+where key_id = (select id from rkey where key = ?);`
+
+const sqlChunkClear = `
+delete from rstring_chunk;`
+
+const sqlLen = `
+select rkey.version, length(cast(value as blob))
+from rstring
+join rkey on key_id = rkey.id
+where key = ? and (etime is null or etime > ?);
+`
+
+const sqlReadChunk = `
+select substr(cast(value as blob), ?, ?)
+from rstring
+join rkey on key_id = rkey.id
+where key = ? and rkey.version = ? and (etime is null or etime > ?);
+`
+
 // Tx is a string repository transaction.
 type Tx struct {
 	tx sqlx.Tx
@@ -70,12 +114,50 @@ func NewTx(tx sqlx.Tx) *Tx {
 // Get returns the value of the key.
 // Returns nil if the key does not exist.
 func (tx *Tx) Get(key string) (core.Value, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	row := tx.tx.QueryRow(sqlGet, key, now)
 	_, val, err := scanValue(row)
 	return val, err
 }
 
+// GetEx returns the value of the key and sets its expiration time
+// (if ttl > 0) or removes it (if ttl = 0), like GETEX in Redis.
+// Returns nil if the key does not exist.
+// If the key exists but is not a string, returns nil
+// and does not change the expiration time.
+func (tx *Tx) GetEx(key string, ttl time.Duration) (core.Value, error) {
+	val, err := tx.Get(key)
+	if err != nil || !val.Exists() {
+		return val, err
+	}
+	rtx := rkey.NewTx(tx.tx)
+	if ttl > 0 {
+		_, err = rtx.Expire(key, ttl)
+	} else {
+		_, err = rtx.Persist(key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// GetDel returns the value of the key and deletes the key,
+// like GETDEL in Redis. Returns nil if the key does not exist.
+// If the key exists but is not a string, returns nil
+// and does not delete the key.
+func (tx *Tx) GetDel(key string) (core.Value, error) {
+	val, err := tx.Get(key)
+	if err != nil || !val.Exists() {
+		return val, err
+	}
+	_, err = rkey.DeleteType(tx.tx, core.TypeString, key)
+	if err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
 // GetMany returns a map of values for given keys.
 // Returns nil for keys that do not exist.
 func (tx *Tx) GetMany(keys ...string) (map[string]core.Value, error) {
@@ -86,7 +168,7 @@ func (tx *Tx) GetMany(keys ...string) (map[string]core.Value, error) {
 	}
 
 	// Get the values of the requested keys.
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	query, keyArgs := sqlx.ExpandIn(sqlGetMany, ":keys", keys)
 	args := slices.Concat(keyArgs, []any{sql.Named("now", now)})
 
@@ -129,6 +211,12 @@ func (tx *Tx) SetExpires(key string, value any, ttl time.Duration) error {
 	return err
 }
 
+// SetWith sets the key value with additional options
+// (see [SetCmd]).
+func (tx *Tx) SetWith(key string, value any) SetCmd {
+	return SetCmd{tx: tx, key: key, value: value}
+}
+
 // SetNotExists sets the key value if the key does not exist.
 // Optionally sets the expiration time (if ttl > 0).
 // Returns true if the key was set, false if the key already exists.
@@ -169,6 +257,28 @@ func (tx *Tx) SetExists(key string, value any, ttl time.Duration) (bool, error)
 	return err == nil, err
 }
 
+// SetIfVersion sets the key value, but only if the key has not
+// changed since it was read as prev (see core.Key.SameVersion),
+// or does not exist if prev does not exist. Does not change the
+// expiration time of an existing key. Returns true if the key
+// was set, false if the key was changed by someone else.
+func (tx *Tx) SetIfVersion(key string, value any, prev core.Key) (bool, error) {
+	if !core.IsValueType(value) {
+		return false, core.ErrValueType
+	}
+
+	k, err := rkey.Get(tx.tx, key)
+	if err != nil {
+		return false, err
+	}
+	if !k.SameVersion(prev) {
+		return false, nil
+	}
+
+	err = tx.update(key, value)
+	return err == nil, err
+}
+
 // GetSet returns the previous value of a key after setting it to a new value.
 // Optionally sets the expiration time (if ttl > 0).
 // Overwrites the value and ttl if the key already exists.
@@ -300,15 +410,101 @@ func (tx *Tx) IncrFloat(key string, delta float64) (float64, error) {
 	return newVal, nil
 }
 
+// Append appends the value to the end of the key value
+// and returns the length of the resulting value.
+// If the key does not exist, creates it with the specified value.
+// Does not change the expiration time of an existing key.
+func (tx *Tx) Append(key string, value any) (int, error) {
+	b, ok := valueBytes(value)
+	if !ok {
+		return 0, core.ErrValueType
+	}
+	val, err := tx.Get(key)
+	if err != nil {
+		return 0, err
+	}
+
+	newVal := make([]byte, 0, len(val)+len(b))
+	newVal = append(newVal, val...)
+	newVal = append(newVal, b...)
+	err = tx.update(key, newVal)
+	if err != nil {
+		return 0, err
+	}
+	return len(newVal), nil
+}
+
+// SetRange overwrites part of the key value starting at the offset
+// and returns the length of the resulting value.
+// Pads the value with zero bytes if the offset is beyond its end.
+// If the key does not exist, creates it (unless the value is empty).
+// Does not change the expiration time of an existing key.
+// Returns ErrValueType if the offset is negative, and ErrValueTooLarge
+// if the resulting value would exceed 512MB.
+func (tx *Tx) SetRange(key string, offset int, value any) (int, error) {
+	if offset < 0 {
+		return 0, core.ErrValueType
+	}
+	b, ok := valueBytes(value)
+	if !ok {
+		return 0, core.ErrValueType
+	}
+	if offset+len(b) > MaxBitOffset/8+1 {
+		return 0, core.ErrValueTooLarge
+	}
+	val, err := tx.Get(key)
+	if err != nil {
+		return 0, err
+	}
+	if len(b) == 0 {
+		// Nothing to overwrite, so leave the key as is.
+		return len(val), nil
+	}
+
+	newVal := make([]byte, max(len(val), offset+len(b)))
+	copy(newVal, val)
+	copy(newVal[offset:], b)
+	err = tx.update(key, newVal)
+	if err != nil {
+		return 0, err
+	}
+	return len(newVal), nil
+}
+
+// GetRange returns the part of the key value between
+// start and end (inclusive). Negative indexes count from
+// the end of the value, so 0, -1 returns the whole value.
+// Returns an empty value if the key does not exist
+// or the range is empty.
+func (tx *Tx) GetRange(key string, start, end int) (core.Value, error) {
+	val, err := tx.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	start, end, ok := clampRange(start, end, len(val))
+	if !ok {
+		return core.Value{}, nil
+	}
+	return val[start : end+1], nil
+}
+
 // set sets the key value and (optionally) its expiration time.
 func (tx *Tx) set(key string, value any, ttl time.Duration) error {
-	now := time.Now()
 	var etime *int64
 	if ttl > 0 {
 		etime = new(int64)
-		*etime = now.Add(ttl).UnixMilli()
+		*etime = sqlx.Now(tx.tx).Add(ttl).UnixMilli()
 	}
+	return tx.setAt(key, value, etime)
+}
 
+// setAt sets the key value and its expiration time
+// in unix milliseconds (nil means no expiration).
+func (tx *Tx) setAt(key string, value any, etime *int64) error {
+	if err := checkLimits(tx.tx, key, value); err != nil {
+		return err
+	}
+	now := sqlx.Now(tx.tx)
 	args := []any{
 		sql.Named("key", key),
 		sql.Named("type", core.TypeString),
@@ -320,7 +516,7 @@ func (tx *Tx) set(key string, value any, ttl time.Duration) error {
 
 	_, err := tx.tx.Exec(sqlSet[0], args...)
 	if err != nil {
-		return sqlx.TypedError(err)
+		return sqlx.KeyTypeError(tx.tx, err, key, core.TypeString)
 	}
 
 	_, err = tx.tx.Exec(sqlSet[1], args...)
@@ -331,7 +527,10 @@ func (tx *Tx) set(key string, value any, ttl time.Duration) error {
 // expiration time. If the key does not exist, creates a new key with
 // the specified value and no expiration time.
 func (tx *Tx) update(key string, value any) error {
-	now := time.Now().UnixMilli()
+	if err := checkLimits(tx.tx, key, value); err != nil {
+		return err
+	}
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{
 		sql.Named("key", key),
 		sql.Named("type", core.TypeString),
@@ -341,12 +540,21 @@ func (tx *Tx) update(key string, value any) error {
 	}
 	_, err := tx.tx.Exec(sqlUpdate[0], args...)
 	if err != nil {
-		return sqlx.TypedError(err)
+		return sqlx.KeyTypeError(tx.tx, err, key, core.TypeString)
 	}
 	_, err = tx.tx.Exec(sqlUpdate[1], args...)
 	return err
 }
 
+// checkLimits checks the key and value against
+// the size limits (see sqlx.Limits).
+func checkLimits(tx sqlx.Tx, key string, value any) error {
+	if err := sqlx.CheckKey(tx, key); err != nil {
+		return err
+	}
+	return sqlx.CheckValue(tx, value)
+}
+
 // scanValue scans a key value from the row (rows).
 func scanValue(scanner sqlx.RowScanner) (key string, val core.Value, err error) {
 	var value []byte
@@ -359,3 +567,156 @@ func scanValue(scanner sqlx.RowScanner) (key string, val core.Value, err error)
 	}
 	return key, core.Value(value), nil
 }
+
+// SetReader sets the key value to the size bytes read from r
+// and (optionally) its expiration time (if ttl > 0).
+// Overwrites the value and ttl if the key already exists.
+// Returns io.ErrUnexpectedEOF if r has fewer than size bytes.
+//
+// The value is written in chunks, so it does not have
+// to fit in memory as a whole (SQLite still assembles
+// the complete value before storing it).
+func (tx *Tx) SetReader(key string, r io.Reader, size int64, ttl time.Duration) error {
+	if size < 0 {
+		return fmt.Errorf("invalid size: %d", size)
+	}
+	if err := sqlx.CheckKey(tx.tx, key); err != nil {
+		return err
+	}
+	if err := sqlx.CheckSize(tx.tx, size); err != nil {
+		return err
+	}
+	for _, query := range sqlChunkSetup {
+		if _, err := tx.tx.Exec(query); err != nil {
+			return err
+		}
+	}
+	defer func() { _, _ = tx.tx.Exec(sqlChunkClear) }()
+
+	r = io.LimitReader(r, size)
+	buf := make([]byte, min(size, ChunkSize))
+	var written int64
+	for seq := 0; written < size; seq++ {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			if _, err := tx.tx.Exec(sqlChunkAdd, seq, buf[:n]); err != nil {
+				return err
+			}
+			written += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if written < size {
+		return io.ErrUnexpectedEOF
+	}
+
+	if err := tx.set(key, []byte{}, ttl); err != nil {
+		return err
+	}
+	_, err := tx.tx.Exec(sqlChunkCommit, key)
+	return err
+}
+
+// Reader returns a reader for the key value. The value is read
+// in chunks, so it does not have to fit in memory as a whole.
+// Returns core.ErrNotFound if the key does not exist.
+//
+// If the key is changed or deleted while reading,
+// the reader returns core.ErrVersion.
+func (tx *Tx) Reader(key string) (*Reader, error) {
+	var version, size int64
+	now := sqlx.Now(tx.tx).UnixMilli()
+	err := tx.tx.QueryRow(sqlLen, key, now).Scan(&version, &size)
+	if err == sql.ErrNoRows {
+		return nil, core.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{tx: tx.tx, key: key, version: version, size: size}, nil
+}
+
+// Reader reads the key value in chunks.
+// Implements io.Reader and io.Seeker.
+type Reader struct {
+	tx      sqlx.Tx
+	key     string
+	version int64
+	size    int64
+	off     int64
+}
+
+// Size returns the size of the value in bytes.
+func (r *Reader) Size() int64 {
+	return r.size
+}
+
+// Read reads up to len(p) bytes of the value into p.
+func (r *Reader) Read(p []byte) (int, error) {
+	if r.off >= r.size {
+		return 0, io.EOF
+	}
+	n := min(int64(len(p)), r.size-r.off, ChunkSize)
+	if n == 0 {
+		return 0, nil
+	}
+	var chunk []byte
+	now := sqlx.Now(r.tx).UnixMilli()
+	row := r.tx.QueryRow(sqlReadChunk, r.off+1, n, r.key, r.version, now)
+	err := row.Scan(&chunk)
+	if err == sql.ErrNoRows {
+		return 0, core.ErrVersion
+	}
+	if err != nil {
+		return 0, err
+	}
+	copied := copy(p, chunk)
+	r.off += int64(copied)
+	return copied, nil
+}
+
+// Seek sets the offset for the next Read.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	var abs int64
+	switch whence {
+	case io.SeekStart:
+		abs = offset
+	case io.SeekCurrent:
+		abs = r.off + offset
+	case io.SeekEnd:
+		abs = r.size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if abs < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.off = abs
+	return abs, nil
+}
+
+// valueBytes returns the value as a byte slice,
+// or false if the value type is not supported.
+func valueBytes(v any) ([]byte, bool) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), true
+	case []byte:
+		return v, true
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), true
+	case float64:
+		return strconv.AppendFloat(nil, v, 'g', -1, 64), true
+	case bool:
+		if v {
+			return []byte("1"), true
+		}
+		return []byte("0"), true
+	}
+	return nil, false
+}
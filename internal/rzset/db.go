@@ -3,14 +3,20 @@
 package rzset
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"iter"
 
+	"github.com/nalgeon/redka/internal/core"
 	"github.com/nalgeon/redka/internal/sqlx"
 )
 
 // DB is a database-backed sorted set repository.
 // A sorted set (zset) is a like a set, but each element has a score.
-// While elements are unique, scores can be repeated.
+// While elements are unique, scores can be repeated. Elements are
+// compared by their bytes, so 1, "1" and []byte("1") are the same
+// element, and any byte strings (even with NUL bytes) are allowed.
 //
 // Elements in the set are ordered by score (from low to high), and then
 // by lexicographical order (ascending). Adding, updating or removing
@@ -29,19 +35,26 @@ func New(db *sql.DB) *DB {
 	return &DB{d}
 }
 
+// WithContext returns a shallow copy of the repository
+// that executes the queries with ctx. Use it to enforce
+// timeouts and cancellation on slow queries.
+func (d *DB) WithContext(ctx context.Context) *DB {
+	return &DB{d.DB.WithContext(ctx)}
+}
+
 // Add adds or updates an element in a set.
 // Returns true if the element was created, false if it was updated.
 // If the key does not exist, creates it.
 // If the key exists but is not a set, returns ErrKeyType.
 func (d *DB) Add(key string, elem any, score float64) (bool, error) {
+	op := d.Observe("SortedSet.Add", key)
 	var created bool
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		created, err = tx.Add(key, elem, score)
 		return err
 	})
-	return created, err
-
+	return created, op.Done(err)
 }
 
 // AddMany adds or updates multiple elements in a set.
@@ -49,21 +62,24 @@ func (d *DB) Add(key string, elem any, score float64) (bool, error) {
 // If the key does not exist, creates it.
 // If the key exists but is not a set, returns ErrKeyType.
 func (d *DB) AddMany(key string, items map[any]float64) (int, error) {
+	op := d.Observe("SortedSet.AddMany", key)
 	var count int
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		count, err = tx.AddMany(key, items)
 		return err
 	})
-	return count, err
+	return count, op.Done(err)
 }
 
 // Count returns the number of elements in a set with a score between
 // min and max (inclusive). Exclusive ranges are not supported.
 // Returns 0 if the key does not exist or is not a set.
 func (d *DB) Count(key string, min, max float64) (int, error) {
-	tx := NewTx(d.SQL)
-	return tx.Count(key, min, max)
+	op := d.Observe("SortedSet.Count", key)
+	tx := NewTx(d.In(op).ReadConn())
+	count, err := tx.Count(key, min, max)
+	return count, op.Done(err)
 }
 
 // Delete removes elements from a set.
@@ -72,13 +88,14 @@ func (d *DB) Count(key string, min, max float64) (int, error) {
 // Does nothing if the key does not exist or is not a set.
 // Does not delete the key if the set becomes empty.
 func (d *DB) Delete(key string, elems ...any) (int, error) {
+	op := d.Observe("SortedSet.Delete", key)
 	var count int
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		count, err = tx.Delete(key, elems...)
 		return err
 	})
-	return count, err
+	return count, op.Done(err)
 }
 
 // DeleteWith removes elements from a set with additional options.
@@ -86,14 +103,85 @@ func (d *DB) DeleteWith(key string) DeleteCmd {
 	return DeleteCmd{db: d, key: key}
 }
 
+// Diff returns the difference between the first set and the rest.
+// The difference consists of elements that exist in the first set
+// but not in any of the other sets. The score of each element
+// is its score in the first set.
+// Ignores the other keys that do not exist or are not sets.
+// If the first key does not exist or is not a set, returns a nil slice.
+func (d *DB) Diff(keys ...string) ([]SetItem, error) {
+	op := d.Observe("SortedSet.Diff", keys...)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.Diff(keys...)
+	return items, op.Done(err)
+}
+
+// DiffWith subtracts multiple sets from the first one
+// with additional options.
+func (d *DB) DiffWith(keys ...string) DiffCmd {
+	return DiffCmd{db: d, keys: keys}
+}
+
+// GeoAdd adds or updates an element in a geo set at the given position.
+// The position is stored as the element score (a geohash),
+// so the set can be used with the other sorted set methods.
+// Returns true if the element was created, false if it was updated.
+// If the position is outside the valid range (longitude from -180 to 180,
+// latitude from -85.05112878 to 85.05112878), returns ErrValueType.
+// If the key does not exist, creates it.
+// If the key exists but is not a set, returns ErrKeyType.
+func (d *DB) GeoAdd(key string, elem any, lon, lat float64) (bool, error) {
+	op := d.Observe("SortedSet.GeoAdd", key)
+	var created bool
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		created, err = tx.GeoAdd(key, elem, lon, lat)
+		return err
+	})
+	return created, op.Done(err)
+}
+
+// GeoDist returns the distance in meters between two elements in a geo set.
+// If either of the elements does not exist, returns ErrNotFound.
+// If the key does not exist or is not a set, returns ErrNotFound.
+func (d *DB) GeoDist(key string, elem1, elem2 any) (float64, error) {
+	op := d.Observe("SortedSet.GeoDist", key)
+	tx := NewTx(d.In(op).ReadConn())
+	dist, err := tx.GeoDist(key, elem1, elem2)
+	return dist, op.Done(err)
+}
+
+// GeoPos returns the positions of the elements in a geo set.
+// The positions are decoded from the geohash scores, so they may
+// slightly differ from the ones passed to GeoAdd.
+// Ignores the elements that do not exist.
+// If the key does not exist or is not a set, returns an empty map.
+func (d *DB) GeoPos(key string, elems ...any) (map[string]GeoPoint, error) {
+	op := d.Observe("SortedSet.GeoPos", key)
+	tx := NewTx(d.In(op).ReadConn())
+	points, err := tx.GeoPos(key, elems...)
+	return points, op.Done(err)
+}
+
+// GeoSearch searches a geo set for the elements within
+// an area (see [GeoSearchCmd]).
+func (d *DB) GeoSearch(key string) GeoSearchCmd {
+	tx := NewTx(d.ReadConn())
+	cmd := tx.GeoSearch(key)
+	cmd.db = d
+	return cmd
+}
+
 // GetRank returns the rank and score of an element in a set.
 // The rank is the 0-based position of the element in the set, ordered
 // by score (from low to high), and then by lexicographical order (ascending).
 // If the element does not exist, returns ErrNotFound.
 // If the key does not exist or is not a set, returns ErrNotFound.
 func (d *DB) GetRank(key string, elem any) (rank int, score float64, err error) {
-	tx := NewTx(d.SQL)
-	return tx.GetRank(key, elem)
+	op := d.Observe("SortedSet.GetRank", key)
+	tx := NewTx(d.In(op).ReadConn())
+	rank, score, err = tx.GetRank(key, elem)
+	return rank, score, op.Done(err)
 }
 
 // GetRankRev returns the rank and score of an element in a set.
@@ -102,16 +190,23 @@ func (d *DB) GetRank(key string, elem any) (rank int, score float64, err error)
 // If the element does not exist, returns ErrNotFound.
 // If the key does not exist or is not a set, returns ErrNotFound.
 func (d *DB) GetRankRev(key string, elem any) (rank int, score float64, err error) {
-	tx := NewTx(d.SQL)
-	return tx.GetRankRev(key, elem)
+	op := d.Observe("SortedSet.GetRankRev", key)
+	tx := NewTx(d.In(op).ReadConn())
+	rank, score, err = tx.GetRankRev(key, elem)
+	return rank, score, op.Done(err)
 }
 
 // GetScore returns the score of an element in a set.
 // If the element does not exist, returns ErrNotFound.
 // If the key does not exist or is not a set, returns ErrNotFound.
 func (d *DB) GetScore(key string, elem any) (float64, error) {
-	tx := NewTx(d.SQL)
-	return tx.GetScore(key, elem)
+	op := d.Observe("SortedSet.GetScore", key)
+	tx := NewTx(d.In(op).ReadConn())
+	score, err := tx.GetScore(key, elem)
+	if err == nil || errors.Is(err, core.ErrNotFound) {
+		d.Lookup(err == nil)
+	}
+	return score, op.Done(err)
 }
 
 // Incr increments the score of an element in a set.
@@ -120,13 +215,14 @@ func (d *DB) GetScore(key string, elem any) (float64, error) {
 // before the increment. If the key does not exist, creates it.
 // If the key exists but is not a set, returns ErrKeyType.
 func (d *DB) Incr(key string, elem any, delta float64) (float64, error) {
+	op := d.Observe("SortedSet.Incr", key)
 	var score float64
-	err := d.Update(func(tx *Tx) error {
+	err := d.In(op).Update(func(tx *Tx) error {
 		var err error
 		score, err = tx.Incr(key, elem, delta)
 		return err
 	})
-	return score, err
+	return score, op.Done(err)
 }
 
 // Inter returns the intersection of multiple sets.
@@ -134,8 +230,10 @@ func (d *DB) Incr(key string, elem any, delta float64) (float64, error) {
 // The score of each element is the sum of its scores in the given sets.
 // If any of the source keys do not exist or are not sets, returns an empty slice.
 func (d *DB) Inter(keys ...string) ([]SetItem, error) {
-	tx := NewTx(d.SQL)
-	return tx.Inter(keys...)
+	op := d.Observe("SortedSet.Inter", keys...)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.Inter(keys...)
+	return items, op.Done(err)
 }
 
 // InterWith intersects multiple sets with additional options.
@@ -146,8 +244,53 @@ func (d *DB) InterWith(keys ...string) InterCmd {
 // Len returns the number of elements in a set.
 // Returns 0 if the key does not exist or is not a set.
 func (d *DB) Len(key string) (int, error) {
-	tx := NewTx(d.SQL)
-	return tx.Len(key)
+	op := d.Observe("SortedSet.Len", key)
+	tx := NewTx(d.In(op).ReadConn())
+	count, err := tx.Len(key)
+	return count, op.Done(err)
+}
+
+// PopMax removes and returns up to count elements with the highest
+// scores (and then the largest elements) from a set, the highest first.
+// If the key does not exist, is not a set, or count is not positive,
+// returns a nil slice. Does not delete the key if the set becomes empty.
+func (d *DB) PopMax(key string, count int) ([]SetItem, error) {
+	op := d.Observe("SortedSet.PopMax", key)
+	var items []SetItem
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		items, err = tx.PopMax(key, count)
+		return err
+	})
+	return items, op.Done(err)
+}
+
+// PopMin removes and returns up to count elements with the lowest
+// scores (and then the smallest elements) from a set, the lowest first.
+// If the key does not exist, is not a set, or count is not positive,
+// returns a nil slice. Does not delete the key if the set becomes empty.
+func (d *DB) PopMin(key string, count int) ([]SetItem, error) {
+	op := d.Observe("SortedSet.PopMin", key)
+	var items []SetItem
+	err := d.In(op).Update(func(tx *Tx) error {
+		var err error
+		items, err = tx.PopMin(key, count)
+		return err
+	})
+	return items, op.Done(err)
+}
+
+// RandMember returns random elements from a set, like ZRANDMEMBER in Redis.
+// With a positive count, returns up to count distinct elements.
+// With a negative count, returns exactly -count elements,
+// which may repeat. If withScores is false, the scores
+// of the returned elements are not set (zero).
+// If the key does not exist or is not a set, returns a nil slice.
+func (d *DB) RandMember(key string, count int, withScores bool) ([]SetItem, error) {
+	op := d.Observe("SortedSet.RandMember", key)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.RandMember(key, count, withScores)
+	return items, op.Done(err)
 }
 
 // Range returns a range of elements from a set with ranks between start and stop.
@@ -156,14 +299,18 @@ func (d *DB) Len(key string) (int, error) {
 // Start and stop are 0-based, inclusive. Negative values are not supported.
 // If the key does not exist or is not a set, returns a nil slice.
 func (d *DB) Range(key string, start, stop int) ([]SetItem, error) {
-	tx := NewTx(d.SQL)
-	return tx.Range(key, start, stop)
+	op := d.Observe("SortedSet.Range", key)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.Range(key, start, stop)
+	return items, op.Done(err)
 }
 
 // RangeWith ranges elements from a set with additional options.
 func (d *DB) RangeWith(key string) RangeCmd {
-	tx := NewTx(d.SQL)
-	return tx.RangeWith(key)
+	tx := NewTx(d.ReadConn())
+	cmd := tx.RangeWith(key)
+	cmd.db = d
+	return cmd
 }
 
 // Scan iterates over set items with elements matching pattern.
@@ -173,8 +320,10 @@ func (d *DB) RangeWith(key string) RangeCmd {
 // If the key does not exist or is not a set, returns a nil slice.
 // Supports glob-style patterns. Set count = 0 for default page size.
 func (d *DB) Scan(key string, cursor int, pattern string, count int) (ScanResult, error) {
-	tx := NewTx(d.SQL)
-	return tx.Scan(key, cursor, pattern, count)
+	op := d.Observe("SortedSet.Scan", key)
+	tx := NewTx(d.In(op).ReadConn())
+	res, err := tx.Scan(key, cursor, pattern, count)
+	return res, op.Done(err)
 }
 
 // Scanner returns an iterator for set items with elements matching pattern.
@@ -183,8 +332,19 @@ func (d *DB) Scan(key string, cursor int, pattern string, count int) (ScanResult
 // or an error occurs. If the key does not exist or is not a set, stops immediately.
 // Supports glob-style patterns. Set pageSize = 0 for default page size.
 func (d *DB) Scanner(key, pattern string, pageSize int) *Scanner {
-	tx := NewTx(d.SQL)
-	return tx.Scanner(key, pattern, pageSize)
+	tx := NewTx(d.ReadConn())
+	sc := tx.Scanner(key, pattern, pageSize)
+	sc.close = d.OpenScanner()
+	return sc
+}
+
+// Iter returns an iterator over the set items with elements
+// matching pattern. Fetches the items from the database in batches
+// as necessary. Stops after yielding the first error, if any.
+// If the key does not exist or is not a set, stops immediately.
+// Supports glob-style patterns.
+func (d *DB) Iter(key, pattern string) iter.Seq2[SetItem, error] {
+	return sqlx.Iter(d.Scanner(key, pattern, 0))
 }
 
 // Union returns the union of multiple sets.
@@ -193,8 +353,10 @@ func (d *DB) Scanner(key, pattern string, pageSize int) *Scanner {
 // Ignores the keys that do not exist or are not sets.
 // If no keys exist, returns a nil slice.
 func (d *DB) Union(keys ...string) ([]SetItem, error) {
-	tx := NewTx(d.SQL)
-	return tx.Union(keys...)
+	op := d.Observe("SortedSet.Union", keys...)
+	tx := NewTx(d.In(op).ReadConn())
+	items, err := tx.Union(keys...)
+	return items, op.Done(err)
 }
 
 // UnionWith unions multiple sets with additional options.
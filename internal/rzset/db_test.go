@@ -2,7 +2,9 @@ package rzset_test
 
 import (
 	"math"
+	"math/rand/v2"
 	"testing"
+	"time"
 
 	"github.com/nalgeon/redka"
 	"github.com/nalgeon/redka/internal/core"
@@ -63,6 +65,48 @@ func TestAdd(t *testing.T) {
 		two, _ := db.GetScore("key", "two")
 		testx.AssertEqual(t, two, 3.0)
 	})
+	t.Run("same bytes", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, _ = db.Add("key", 1, 1)
+		created, err := db.Add("key", "1", 2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, created, false)
+		created, err = db.Add("key", []byte("1"), 3)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, created, false)
+
+		count, _ := db.Len("key")
+		testx.AssertEqual(t, count, 1)
+		score, _ := db.GetScore("key", 1)
+		testx.AssertEqual(t, score, 3.0)
+		n, _ := db.Delete("key", []byte("1"))
+		testx.AssertEqual(t, n, 1)
+	})
+	t.Run("binary", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, _ = db.Add("key", "a\x00b", 1)
+		_, _ = db.Add("key", []byte{0xff, 0x00}, 2)
+		_, _ = db.Add("key", "a", 3)
+
+		items, err := db.Range("key", 0, 10)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("a\x00b"), Score: 1},
+			{Elem: core.Value("\xff\x00"), Score: 2},
+			{Elem: core.Value("a"), Score: 3},
+		})
+		score, _ := db.GetScore("key", "\xff\x00")
+		testx.AssertEqual(t, score, 2.0)
+
+		out, err := db.Scan("key", 0, "*b", 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(out.Items), 1)
+		testx.AssertEqual(t, out.Items[0].Elem.String(), "a\x00b")
+	})
 	t.Run("key type mismatch", func(t *testing.T) {
 		red, db := getDB(t)
 		defer red.Close()
@@ -321,6 +365,339 @@ func TestDeleteScore(t *testing.T) {
 	testx.AssertEqual(t, thr, 3.0)
 }
 
+func TestDiff(t *testing.T) {
+	t.Run("diff", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.AddMany("key1", map[any]float64{
+			"one": 1,
+			"two": 2,
+			"thr": 3,
+			"fou": 4,
+		})
+		_, _ = db.AddMany("key2", map[any]float64{
+			"two": 20,
+			"fiv": 5,
+		})
+		_, _ = db.AddMany("key3", map[any]float64{
+			"thr": 30,
+		})
+
+		items, err := db.Diff("key1", "key2", "key3")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("one"), Score: 1},
+			{Elem: core.Value("fou"), Score: 4},
+		})
+	})
+	t.Run("single key", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key1", "one", 1)
+		_, _ = db.Add("key1", "two", 2)
+
+		items, err := db.Diff("key1")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("one"), Score: 1},
+			{Elem: core.Value("two"), Score: 2},
+		})
+	})
+	t.Run("empty", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key1", "one", 1)
+		_, _ = db.Add("key2", "one", 2)
+
+		items, err := db.Diff("key1", "key2")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem(nil))
+	})
+	t.Run("first key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key2", "one", 1)
+
+		items, err := db.Diff("key1", "key2")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem(nil))
+	})
+	t.Run("other key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key1", "one", 1)
+		_, _ = db.Add("key2", "two", 2)
+
+		items, err := db.Diff("key1", "key2", "key3")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("one"), Score: 1},
+		})
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key1", "one", 1)
+		_ = red.Str().Set("key2", "one")
+
+		items, err := db.Diff("key1", "key2")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("one"), Score: 1},
+		})
+	})
+	t.Run("expired key", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key1", "one", 1)
+		_, _ = db.Add("key2", "one", 2)
+		_, _ = red.Key().Expire("key2", time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+
+		items, err := db.Diff("key1", "key2")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("one"), Score: 1},
+		})
+	})
+}
+
+func TestDiffStore(t *testing.T) {
+	t.Run("store", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.AddMany("key1", map[any]float64{
+			"one": 1,
+			"two": 2,
+			"thr": 3,
+		})
+		_, _ = db.Add("key2", "two", 20)
+
+		count, err := db.DiffWith("key1", "key2").Dest("dest").Store()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 2)
+
+		one, _ := db.GetScore("dest", "one")
+		testx.AssertEqual(t, one, 1.0)
+		thr, _ := db.GetScore("dest", "thr")
+		testx.AssertEqual(t, thr, 3.0)
+		_, err = db.GetScore("dest", "two")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("rewrite dest", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key1", "one", 1)
+		_, _ = db.Add("key2", "two", 2)
+		_, _ = db.Add("dest", "old", 10)
+
+		count, err := db.DiffWith("key1", "key2").Dest("dest").Store()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+
+		one, _ := db.GetScore("dest", "one")
+		testx.AssertEqual(t, one, 1.0)
+		_, err = db.GetScore("dest", "old")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("first key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key2", "one", 1)
+		_, _ = db.Add("dest", "old", 10)
+
+		count, err := db.DiffWith("key1", "key2").Dest("dest").Store()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 0)
+
+		_, err = db.GetScore("dest", "old")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("dest key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key1", "one", 1)
+		_ = red.Str().Set("dest", 10)
+
+		count, err := db.DiffWith("key1").Dest("dest").Store()
+		testx.AssertErr(t, err, core.ErrKeyType)
+		testx.AssertEqual(t, count, 0)
+
+		old, _ := red.Str().Get("dest")
+		testx.AssertEqual(t, old.String(), "10")
+	})
+}
+
+func TestGeoAdd(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		created, err := db.GeoAdd("places", "Palermo", 13.361389, 38.115556)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, created, true)
+
+		// Same geohash score as in Redis.
+		score, _ := db.GetScore("places", "Palermo")
+		testx.AssertEqual(t, score, 3479099956230698.0)
+	})
+	t.Run("update", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.GeoAdd("places", "Palermo", 0, 0)
+
+		created, err := db.GeoAdd("places", "Palermo", 13.361389, 38.115556)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, created, false)
+
+		score, _ := db.GetScore("places", "Palermo")
+		testx.AssertEqual(t, score, 3479099956230698.0)
+	})
+	t.Run("invalid position", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		_, err := db.GeoAdd("places", "North Pole", 0, 90)
+		testx.AssertErr(t, err, core.ErrValueType)
+		_, err = db.GeoAdd("places", "Nowhere", 181, 0)
+		testx.AssertErr(t, err, core.ErrValueType)
+
+		exists, _ := red.Key().Exists("places")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("places", "value")
+
+		_, err := db.GeoAdd("places", "Palermo", 13.361389, 38.115556)
+		testx.AssertErr(t, err, core.ErrKeyType)
+	})
+}
+
+func TestGeoDist(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+	_, _ = db.GeoAdd("places", "Palermo", 13.361389, 38.115556)
+	_, _ = db.GeoAdd("places", "Catania", 15.087269, 37.502669)
+
+	t.Run("distance", func(t *testing.T) {
+		dist, err := db.GeoDist("places", "Palermo", "Catania")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, math.Round(dist*10000)/10000, 166274.1516)
+	})
+	t.Run("same element", func(t *testing.T) {
+		dist, err := db.GeoDist("places", "Palermo", "Palermo")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, dist, 0.0)
+	})
+	t.Run("elem not found", func(t *testing.T) {
+		_, err := db.GeoDist("places", "Palermo", "Rome")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		_, err := db.GeoDist("other", "Palermo", "Catania")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+}
+
+func TestGeoPos(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+	_, _ = db.GeoAdd("places", "Palermo", 13.361389, 38.115556)
+	_, _ = db.GeoAdd("places", "Catania", 15.087269, 37.502669)
+
+	t.Run("found", func(t *testing.T) {
+		points, err := db.GeoPos("places", "Palermo", "Catania", "Rome")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(points), 2)
+		// Same decoded position as in Redis.
+		testx.AssertEqual(t, points["Palermo"], rzset.GeoPoint{
+			Lon: 13.361389338970184, Lat: 38.1155563954963,
+		})
+		_, ok := points["Rome"]
+		testx.AssertEqual(t, ok, false)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		points, err := db.GeoPos("other", "Palermo")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(points), 0)
+	})
+}
+
+func TestGeoSearch(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+	_, _ = db.GeoAdd("places", "Palermo", 13.361389, 38.115556)
+	_, _ = db.GeoAdd("places", "Catania", 15.087269, 37.502669)
+	_, _ = db.GeoAdd("places", "edge1", 12.758489, 38.788135)
+	_, _ = db.GeoAdd("places", "edge2", 17.241510, 38.788135)
+
+	elems := func(items []rzset.GeoItem) []string {
+		var res []string
+		for _, it := range items {
+			res = append(res, it.Elem.String())
+		}
+		return res
+	}
+
+	t.Run("by radius", func(t *testing.T) {
+		items, err := db.GeoSearch("places").FromPoint(15, 37).ByRadius(200_000).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, elems(items), []string{"Catania", "Palermo"})
+		testx.AssertEqual(t, math.Round(items[0].Dist/10)/100, 56.44)
+		testx.AssertEqual(t, math.Round(items[1].Dist/10)/100, 190.44)
+	})
+	t.Run("by box", func(t *testing.T) {
+		items, err := db.GeoSearch("places").FromPoint(15, 37).ByBox(400_000, 400_000).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, elems(items), []string{"Catania", "Palermo", "edge2", "edge1"})
+	})
+	t.Run("from elem", func(t *testing.T) {
+		items, err := db.GeoSearch("places").FromElem("Palermo").ByRadius(100_000).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, elems(items), []string{"Palermo", "edge1"})
+		testx.AssertEqual(t, items[0].Dist, 0.0)
+	})
+	t.Run("desc and count", func(t *testing.T) {
+		items, err := db.GeoSearch("places").FromPoint(15, 37).ByRadius(200_000).
+			Desc().Count(1).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, elems(items), []string{"Palermo"})
+	})
+	t.Run("large radius", func(t *testing.T) {
+		items, err := db.GeoSearch("places").FromPoint(-100, 40).ByRadius(20_000_000).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 4)
+	})
+	t.Run("elem not found", func(t *testing.T) {
+		_, err := db.GeoSearch("places").FromElem("Rome").ByRadius(100_000).Run()
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("no area", func(t *testing.T) {
+		_, err := db.GeoSearch("places").FromPoint(15, 37).Run()
+		testx.AssertErr(t, err, core.ErrSyntax)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		items, err := db.GeoSearch("other").FromPoint(15, 37).ByRadius(200_000).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 0)
+	})
+	t.Run("transaction", func(t *testing.T) {
+		err := red.Update(func(tx *redka.Tx) error {
+			_, err := tx.SortedSet().GeoAdd("places", "Rome", 12.496366, 41.902782)
+			if err != nil {
+				return err
+			}
+			items, err := tx.SortedSet().GeoSearch("places").FromElem("Rome").ByRadius(400_000).Run()
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, elems(items), []string{"Rome", "edge1"})
+			return nil
+		})
+		testx.AssertNoErr(t, err)
+	})
+}
+
 func TestGetRank(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -637,6 +1014,60 @@ func TestInterWith(t *testing.T) {
 			{Elem: core.Value("two"), Score: 200},
 		})
 	})
+	t.Run("weights", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.AddMany("key1", map[any]float64{
+			"one": 1,
+			"two": 2,
+			"thr": 3,
+		})
+		_, _ = db.AddMany("key2", map[any]float64{
+			"two": 20,
+			"thr": 3,
+			"fou": 4,
+		})
+		_, _ = db.AddMany("key3", map[any]float64{
+			"one": 1,
+			"two": 200,
+			"thr": 3,
+			"fou": 400,
+		})
+
+		items, err := db.InterWith("key1", "key2", "key3").Weights(2, 1, 0.5).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("thr"), Score: 10.5},
+			{Elem: core.Value("two"), Score: 124},
+		})
+	})
+	t.Run("weights min", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.AddMany("key1", map[any]float64{
+			"one": 1,
+			"two": 2,
+			"thr": 3,
+		})
+		_, _ = db.AddMany("key2", map[any]float64{
+			"two": 20,
+			"thr": 3,
+			"fou": 4,
+		})
+		_, _ = db.AddMany("key3", map[any]float64{
+			"one": 1,
+			"two": 200,
+			"thr": 3,
+			"fou": 400,
+		})
+
+		items, err := db.InterWith("key1", "key2", "key3").Weights(2, 1, 0.5).Min().Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("thr"), Score: 1.5},
+			{Elem: core.Value("two"), Score: 4},
+		})
+	})
 }
 
 func TestInterStore(t *testing.T) {
@@ -668,7 +1099,36 @@ func TestInterStore(t *testing.T) {
 		thr, _ := db.GetScore("dest", "thr")
 		testx.AssertEqual(t, thr, 3.0)
 		two, _ := db.GetScore("dest", "two")
-		testx.AssertEqual(t, two, 200.0)
+		testx.AssertEqual(t, two, 200.0)
+	})
+	t.Run("weights", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.AddMany("key1", map[any]float64{
+			"one": 1,
+			"two": 2,
+			"thr": 3,
+		})
+		_, _ = db.AddMany("key2", map[any]float64{
+			"two": 20,
+			"thr": 3,
+			"fou": 4,
+		})
+		_, _ = db.AddMany("key3", map[any]float64{
+			"one": 1,
+			"two": 200,
+			"thr": 3,
+			"fou": 400,
+		})
+
+		count, err := db.InterWith("key1", "key2", "key3").Dest("dest").Weights(2, 1, 0.5).Store()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 2)
+
+		thr, _ := db.GetScore("dest", "thr")
+		testx.AssertEqual(t, thr, 10.5)
+		two, _ := db.GetScore("dest", "two")
+		testx.AssertEqual(t, two, 124.0)
 	})
 	t.Run("rewrite dest", func(t *testing.T) {
 		red, db := getDB(t)
@@ -779,6 +1239,166 @@ func TestLen(t *testing.T) {
 	})
 }
 
+func TestPop(t *testing.T) {
+	t.Run("min", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key", "one", 1)
+		_, _ = db.Add("key", "two", 2)
+		_, _ = db.Add("key", "2nd", 2)
+		_, _ = db.Add("key", "thr", 3)
+
+		items, err := db.PopMin("key", 2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 2)
+		testx.AssertEqual(t, items[0].Elem.String(), "one")
+		testx.AssertEqual(t, items[0].Score, 1.0)
+		testx.AssertEqual(t, items[1].Elem.String(), "2nd")
+		testx.AssertEqual(t, items[1].Score, 2.0)
+
+		count, _ := db.Len("key")
+		testx.AssertEqual(t, count, 2)
+		_, err = db.GetScore("key", "one")
+		testx.AssertErr(t, err, core.ErrNotFound)
+	})
+	t.Run("max", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key", "one", 1)
+		_, _ = db.Add("key", "two", 2)
+		_, _ = db.Add("key", "2nd", 2)
+		_, _ = db.Add("key", "thr", 3)
+
+		items, err := db.PopMax("key", 2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 2)
+		testx.AssertEqual(t, items[0].Elem.String(), "thr")
+		testx.AssertEqual(t, items[1].Elem.String(), "two")
+
+		count, _ := db.Len("key")
+		testx.AssertEqual(t, count, 2)
+	})
+	t.Run("all", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key", "one", 1)
+		_, _ = db.Add("key", "two", 2)
+
+		items, err := db.PopMin("key", 10)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 2)
+
+		exists, _ := red.Key().Exists("key")
+		testx.AssertEqual(t, exists, true)
+		count, _ := db.Len("key")
+		testx.AssertEqual(t, count, 0)
+	})
+	t.Run("version", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key", "one", 1)
+		key, _ := red.Key().Get("key")
+
+		_, _ = db.PopMin("key", 1)
+		after, _ := red.Key().Get("key")
+		testx.AssertEqual(t, after.Version, key.Version+1)
+
+		// Nothing to pop.
+		_, _ = db.PopMin("key", 1)
+		last, _ := red.Key().Get("key")
+		testx.AssertEqual(t, last.Version, after.Version)
+	})
+	t.Run("zero count", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key", "one", 1)
+
+		items, err := db.PopMax("key", 0)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem(nil))
+		count, _ := db.Len("key")
+		testx.AssertEqual(t, count, 1)
+	})
+	t.Run("key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		items, err := db.PopMin("key", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem(nil))
+	})
+	t.Run("key type mismatch", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_ = red.Str().Set("key", "str")
+
+		items, err := db.PopMin("key", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem(nil))
+	})
+}
+
+func TestRandMember(t *testing.T) {
+	scores := map[string]float64{"one": 1, "two": 2, "thr": 3}
+	// check checks that the items are the set elements
+	// (with or without scores) and returns their names.
+	check := func(t *testing.T, items []rzset.SetItem, withScores bool) map[string]int {
+		names := map[string]int{}
+		for _, it := range items {
+			score, ok := scores[it.Elem.String()]
+			testx.AssertEqual(t, ok, true)
+			if withScores {
+				testx.AssertEqual(t, it.Score, score)
+			} else {
+				testx.AssertEqual(t, it.Score, 0.0)
+			}
+			names[it.Elem.String()]++
+		}
+		return names
+	}
+
+	for _, seeded := range []bool{false, true} {
+		opts := &redka.Options{}
+		name := "sqlite"
+		if seeded {
+			opts.Rand = rand.New(rand.NewPCG(1, 2))
+			name = "seeded"
+		}
+		t.Run(name, func(t *testing.T) {
+			red, err := redka.Open(":memory:", opts)
+			testx.AssertNoErr(t, err)
+			defer red.Close()
+			db := red.SortedSet()
+			for elem, score := range scores {
+				_, _ = db.Add("key", elem, score)
+			}
+
+			items, err := db.RandMember("key", 2, true)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, len(items), 2)
+			testx.AssertEqual(t, len(check(t, items, true)), 2)
+
+			items, err = db.RandMember("key", 10, false)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, len(items), 3)
+			testx.AssertEqual(t, len(check(t, items, false)), 3)
+
+			items, err = db.RandMember("key", -10, true)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, len(items), 10)
+			check(t, items, true)
+
+			items, err = db.RandMember("key", 0, true)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, len(items), 0)
+
+			items, err = db.RandMember("missing", -3, true)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, items, []rzset.SetItem(nil))
+		})
+	}
+}
+
 func TestRangeRank(t *testing.T) {
 	t.Run("range", func(t *testing.T) {
 		red, db := getDB(t)
@@ -995,6 +1615,94 @@ func TestRangeScore(t *testing.T) {
 	})
 }
 
+func TestRangeLex(t *testing.T) {
+	// elems returns the element names of the items.
+	elems := func(items []rzset.SetItem) []string {
+		names := []string{}
+		for _, it := range items {
+			names = append(names, it.Elem.String())
+		}
+		return names
+	}
+
+	t.Run("asc", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		for _, elem := range []string{"apple", "apricot", "banana", "blueberry", "cherry"} {
+			_, _ = db.Add("key", elem, 0)
+		}
+
+		tests := []struct {
+			min, max string
+			elems    []string
+		}{
+			{"-", "+", []string{"apple", "apricot", "banana", "blueberry", "cherry"}},
+			{"[banana", "+", []string{"banana", "blueberry", "cherry"}},
+			{"(banana", "+", []string{"blueberry", "cherry"}},
+			{"-", "[banana", []string{"apple", "apricot", "banana"}},
+			{"-", "(banana", []string{"apple", "apricot"}},
+			{"[ap", "(b", []string{"apple", "apricot"}},
+			{"[b", "(c", []string{"banana", "blueberry"}},
+			{"(cherry", "+", []string{}},
+			{"[c", "[b", []string{}},
+			{"+", "+", []string{}},
+			{"-", "-", []string{}},
+		}
+
+		for _, test := range tests {
+			items, err := db.RangeWith("key").ByLex(test.min, test.max).Run()
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, elems(items), test.elems)
+		}
+	})
+	t.Run("desc", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		for _, elem := range []string{"apple", "apricot", "banana", "blueberry", "cherry"} {
+			_, _ = db.Add("key", elem, 0)
+		}
+
+		items, err := db.RangeWith("key").ByLex("[apricot", "(cherry").Desc().Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, elems(items), []string{"blueberry", "banana", "apricot"})
+	})
+	t.Run("offset/count", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		for _, elem := range []string{"apple", "apricot", "banana", "blueberry", "cherry"} {
+			_, _ = db.Add("key", elem, 0)
+		}
+
+		items, err := db.RangeWith("key").ByLex("-", "+").Offset(1).Count(2).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, elems(items), []string{"apricot", "banana"})
+		items, err = db.RangeWith("key").ByLex("[b", "+").Count(1).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, elems(items), []string{"banana"})
+	})
+	t.Run("invalid range", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.Add("key", "one", 0)
+
+		for _, bounds := range [][2]string{{"a", "+"}, {"-", "b"}, {"", "+"}} {
+			_, err := db.RangeWith("key").ByLex(bounds[0], bounds[1]).Run()
+			testx.AssertErr(t, err, core.ErrSyntax)
+		}
+	})
+	t.Run("key not found", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+
+		items, err := db.RangeWith("key").ByLex("-", "+").Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem(nil))
+	})
+}
+
 func TestScan(t *testing.T) {
 	red, db := getDB(t)
 	defer red.Close()
@@ -1085,6 +1793,32 @@ func TestScan(t *testing.T) {
 	})
 }
 
+func TestIter(t *testing.T) {
+	red, db := getDB(t)
+	defer red.Close()
+
+	_, _ = db.Add("key", "f11", 11)
+	_, _ = db.Add("key", "f12", 12)
+	_, _ = db.Add("key", "f21", 21)
+
+	t.Run("scan", func(t *testing.T) {
+		var elems []string
+		for item, err := range db.Iter("key", "f1*") {
+			testx.AssertNoErr(t, err)
+			elems = append(elems, item.Elem.String())
+		}
+		testx.AssertEqual(t, elems, []string{"f11", "f12"})
+	})
+	t.Run("range", func(t *testing.T) {
+		var elems []string
+		for item, err := range db.RangeWith("key").ByScore(12, 21).Desc().Iter() {
+			testx.AssertNoErr(t, err)
+			elems = append(elems, item.Elem.String())
+		}
+		testx.AssertEqual(t, elems, []string{"f21", "f12"})
+	})
+}
+
 func TestScanner(t *testing.T) {
 	t.Run("scan", func(t *testing.T) {
 		red, db := getDB(t)
@@ -1313,6 +2047,93 @@ func TestUnionWith(t *testing.T) {
 			{Elem: core.Value("fou"), Score: 400},
 		})
 	})
+	t.Run("weights", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.AddMany("key1", map[any]float64{
+			"one": 1,
+			"two": 2,
+			"thr": 3,
+		})
+		_, _ = db.AddMany("key2", map[any]float64{
+			"two": 20,
+			"thr": 3,
+			"fou": 4,
+		})
+		_, _ = db.AddMany("key3", map[any]float64{
+			"one": 1,
+			"two": 200,
+			"thr": 3,
+			"fou": 400,
+		})
+
+		items, err := db.UnionWith("key1", "key2", "key3").Weights(2, 1, 0.5).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("one"), Score: 2.5},
+			{Elem: core.Value("thr"), Score: 10.5},
+			{Elem: core.Value("two"), Score: 124},
+			{Elem: core.Value("fou"), Score: 204},
+		})
+	})
+	t.Run("weights max", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.AddMany("key1", map[any]float64{
+			"one": 1,
+			"two": 2,
+			"thr": 3,
+		})
+		_, _ = db.AddMany("key2", map[any]float64{
+			"two": 20,
+			"thr": 3,
+			"fou": 4,
+		})
+		_, _ = db.AddMany("key3", map[any]float64{
+			"one": 1,
+			"two": 200,
+			"thr": 3,
+			"fou": 400,
+		})
+
+		items, err := db.UnionWith("key1", "key2", "key3").Weights(2, 1, 0.5).Max().Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("one"), Score: 2},
+			{Elem: core.Value("thr"), Score: 6},
+			{Elem: core.Value("two"), Score: 100},
+			{Elem: core.Value("fou"), Score: 200},
+		})
+	})
+	t.Run("partial weights", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.AddMany("key1", map[any]float64{
+			"one": 1,
+			"two": 2,
+			"thr": 3,
+		})
+		_, _ = db.AddMany("key2", map[any]float64{
+			"two": 20,
+			"thr": 3,
+			"fou": 4,
+		})
+		_, _ = db.AddMany("key3", map[any]float64{
+			"one": 1,
+			"two": 200,
+			"thr": 3,
+			"fou": 400,
+		})
+
+		items, err := db.UnionWith("key1", "key2", "key3").Weights(10).Run()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, items, []rzset.SetItem{
+			{Elem: core.Value("one"), Score: 11},
+			{Elem: core.Value("thr"), Score: 36},
+			{Elem: core.Value("two"), Score: 240},
+			{Elem: core.Value("fou"), Score: 404},
+		})
+	})
 }
 
 func TestUnionStore(t *testing.T) {
@@ -1349,6 +2170,35 @@ func TestUnionStore(t *testing.T) {
 		fou, _ := db.GetScore("dest", "fou")
 		testx.AssertEqual(t, fou, 400.0)
 	})
+	t.Run("weights", func(t *testing.T) {
+		red, db := getDB(t)
+		defer red.Close()
+		_, _ = db.AddMany("key1", map[any]float64{
+			"one": 1,
+			"two": 2,
+			"thr": 3,
+		})
+		_, _ = db.AddMany("key2", map[any]float64{
+			"two": 20,
+			"thr": 3,
+			"fou": 4,
+		})
+		_, _ = db.AddMany("key3", map[any]float64{
+			"one": 1,
+			"two": 200,
+			"thr": 3,
+			"fou": 400,
+		})
+
+		count, err := db.UnionWith("key1", "key2", "key3").Dest("dest").Weights(2, 1, 0.5).Store()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 4)
+
+		thr, _ := db.GetScore("dest", "thr")
+		testx.AssertEqual(t, thr, 10.5)
+		two, _ := db.GetScore("dest", "two")
+		testx.AssertEqual(t, two, 124.0)
+	})
 	t.Run("rewrite dest", func(t *testing.T) {
 		red, db := getDB(t)
 		defer red.Close()
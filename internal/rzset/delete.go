@@ -2,7 +2,6 @@ package rzset
 
 import (
 	"database/sql"
-	"time"
 
 	"github.com/nalgeon/redka/internal/sqlx"
 )
@@ -65,13 +64,14 @@ func (c DeleteCmd) ByScore(start, stop float64) DeleteCmd {
 // Does not delete the key if the set becomes empty.
 func (c DeleteCmd) Run() (int, error) {
 	if c.db != nil {
+		op := c.db.Observe("SortedSet.DeleteWith", c.key)
 		var count int
-		err := c.db.Update(func(tx *Tx) error {
+		err := c.db.In(op).Update(func(tx *Tx) error {
 			var err error
 			count, err = c.delete(tx.tx)
 			return err
 		})
-		return count, err
+		return count, op.Done(err)
 	}
 	if c.tx != nil {
 		return c.delete(c.tx.tx)
@@ -100,7 +100,7 @@ func (c DeleteCmd) deleteRank(tx sqlx.Tx) (int, error) {
 	// Delete elements by rank.
 	args := []any{
 		sql.Named("key", c.key),
-		sql.Named("now", time.Now().UnixMilli()),
+		sql.Named("now", sqlx.Now(tx).UnixMilli()),
 		sql.Named("start", c.byRank.start),
 		sql.Named("count", c.byRank.stop-c.byRank.start+1),
 	}
@@ -116,7 +116,7 @@ func (c DeleteCmd) deleteRank(tx sqlx.Tx) (int, error) {
 func (c DeleteCmd) deleteScore(tx sqlx.Tx) (int, error) {
 	args := []any{
 		sql.Named("key", c.key),
-		sql.Named("now", time.Now().UnixMilli()),
+		sql.Named("now", sqlx.Now(tx).UnixMilli()),
 		sql.Named("start", c.byScore.start),
 		sql.Named("stop", c.byScore.stop),
 	}
@@ -0,0 +1,184 @@
+package rzset
+
+import (
+	"database/sql"
+	"slices"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rkey"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+const (
+	sqlDiff = `
+	select elem, score
+	from rzset
+	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key
+	  and elem not in (
+	    select elem
+	    from rzset
+	      join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	    where key in (:keys)
+	  )
+	order by score, elem`
+
+	sqlDiffStore1 = `
+	insert into rkey (id, key, type, version, mtime)
+	values (` + sqlx.NextKeyID + `, :key, :type, :version, :mtime)
+	returning id`
+
+	sqlDiffStore2 = `
+	insert into rzset (key_id, elem, score)
+	select :key_id, elem, score
+	from rzset
+	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key
+	  and elem not in (
+	    select elem
+	    from rzset
+	      join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	    where key in (:keys)
+	  )
+	order by score, elem`
+)
+
+// DiffCmd subtracts multiple sets from the first one.
+type DiffCmd struct {
+	db   *DB
+	tx   *Tx
+	dest string
+	keys []string
+}
+
+// Dest sets the key to store the result of the difference.
+func (c DiffCmd) Dest(dest string) DiffCmd {
+	c.dest = dest
+	return c
+}
+
+// Run returns the difference between the first set and the rest.
+// The difference consists of elements that exist in the first set
+// but not in any of the other sets. The score of each element
+// is its score in the first set.
+// Ignores the other keys that do not exist or are not sets.
+// If the first key does not exist or is not a set, returns a nil slice.
+func (c DiffCmd) Run() ([]SetItem, error) {
+	if c.db != nil {
+		op := c.db.Observe("SortedSet.DiffWith", c.keys...)
+		items, err := c.diff(c.db.In(op).ReadConn())
+		return items, op.Done(err)
+	}
+	if c.tx != nil {
+		return c.diff(c.tx.tx)
+	}
+	return nil, nil
+}
+
+// Store subtracts multiple sets from the first one
+// and stores the result in a new set.
+// Returns the number of elements in the resulting set.
+// If the destination key already exists, it is fully overwritten
+// (all old elements are removed and the new ones are inserted).
+// If the destination key already exists and is not a set, returns ErrKeyType.
+// Ignores the other keys that do not exist or are not sets.
+// If the first key does not exist or is not a set, does nothing,
+// except deleting the destination key if it exists.
+func (c DiffCmd) Store() (int, error) {
+	if c.db != nil {
+		op := c.db.Observe("SortedSet.DiffWith", append([]string{c.dest}, c.keys...)...)
+		var count int
+		err := c.db.In(op).Update(func(tx *Tx) error {
+			var err error
+			count, err = c.store(tx.tx)
+			return err
+		})
+		return count, op.Done(err)
+	}
+	if c.tx != nil {
+		return c.store(c.tx.tx)
+	}
+	return 0, nil
+}
+
+// diff returns the difference between the first set and the rest.
+func (c DiffCmd) diff(tx sqlx.Tx) ([]SetItem, error) {
+	if len(c.keys) == 0 {
+		return nil, nil
+	}
+
+	// Prepare query arguments.
+	now := sqlx.Now(tx).UnixMilli()
+	query, keyArgs := sqlx.ExpandIn(sqlDiff, ":keys", c.keys[1:])
+	args := slices.Concat([]any{now, c.keys[0]}, keyArgs)
+
+	// Execute the query.
+	var rows *sql.Rows
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// Build the resulting element-score slice.
+	var items []SetItem
+	for rows.Next() {
+		it, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	return items, nil
+}
+
+// store subtracts multiple sets from the first one
+// and stores the result in a new set.
+func (c DiffCmd) store(tx sqlx.Tx) (int, error) {
+	// Delete the destination key if it exists.
+	if err := sqlx.CheckKey(tx, c.dest); err != nil {
+		return 0, err
+	}
+	_, err := rkey.DeleteType(tx, core.TypeSortedSet, c.dest)
+	if err != nil {
+		return 0, err
+	}
+	if len(c.keys) == 0 {
+		return 0, nil
+	}
+
+	// Insert the destination key and get its ID.
+	now := sqlx.Now(tx).UnixMilli()
+	args := []any{
+		sql.Named("key", c.dest),
+		sql.Named("type", core.TypeSortedSet),
+		sql.Named("version", core.InitialVersion),
+		sql.Named("mtime", now),
+	}
+	var keyID int
+	err = tx.QueryRow(sqlDiffStore1, args...).Scan(&keyID)
+	if err != nil {
+		return 0, sqlx.KeyTypeError(tx, err, c.dest, core.TypeSortedSet)
+	}
+
+	// Subtract the sets and store the result.
+	query, keyArgs := sqlx.ExpandIn(sqlDiffStore2, ":keys", c.keys[1:])
+	args = slices.Concat([]any{keyID, now, c.keys[0]}, keyArgs)
+
+	res, err := tx.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	// Return the number of elements in the resulting set.
+	n, _ := res.RowsAffected()
+	err = sqlx.CheckElements(tx, func() (int, error) { return int(n), nil })
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
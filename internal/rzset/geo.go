@@ -0,0 +1,434 @@
+package rzset
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// The geohash parameters, same as in Redis. Longitude and latitude
+// are interleaved into a 52-bit integer, which is exactly representable
+// as a float64 score, so geo sets are regular sorted sets.
+const (
+	geoStep      = 26 // bits per coordinate
+	geoLonMin    = -180.0
+	geoLonMax    = 180.0
+	geoLatMin    = -85.05112878
+	geoLatMax    = 85.05112878
+	earthRadiusM = 6372797.560856 // meters
+)
+
+const (
+	sqlGeoPos = `
+	select elem, score
+	from rzset
+	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key and elem in (:elems)`
+
+	sqlGeoSearch = `
+	select elem, score
+	from rzset
+	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key and (%s)`
+)
+
+// GeoPoint is a position on the Earth.
+type GeoPoint struct {
+	Lon float64
+	Lat float64
+}
+
+// GeoItem is an element found by a geo search.
+type GeoItem struct {
+	Elem  core.Value
+	Point GeoPoint
+	Dist  float64 // meters from the search center
+}
+
+// GeoAdd adds or updates an element in a geo set at the given position.
+// The position is stored as the element score (a geohash),
+// so the set can be used with the other sorted set methods.
+// Returns true if the element was created, false if it was updated.
+// If the position is outside the valid range (longitude from -180 to 180,
+// latitude from -85.05112878 to 85.05112878), returns ErrValueType.
+// If the key does not exist, creates it.
+// If the key exists but is not a set, returns ErrKeyType.
+func (tx *Tx) GeoAdd(key string, elem any, lon, lat float64) (bool, error) {
+	if !validGeoPoint(lon, lat) {
+		return false, core.ErrValueType
+	}
+	return tx.Add(key, elem, geoEncode(lon, lat))
+}
+
+// GeoPos returns the positions of the elements in a geo set.
+// The positions are decoded from the geohash scores, so they may
+// slightly differ from the ones passed to GeoAdd.
+// Ignores the elements that do not exist.
+// If the key does not exist or is not a set, returns an empty map.
+func (tx *Tx) GeoPos(key string, elems ...any) (map[string]GeoPoint, error) {
+	for _, elem := range elems {
+		if !core.IsValueType(elem) {
+			return nil, core.ErrValueType
+		}
+	}
+
+	now := sqlx.Now(tx.tx).UnixMilli()
+	query, elemArgs := sqlx.ExpandInText(sqlGeoPos, ":elems", elems)
+	args := slices.Concat([]any{sql.Named("key", key), sql.Named("now", now)}, elemArgs)
+	rows, err := tx.tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make(map[string]GeoPoint, len(elems))
+	for rows.Next() {
+		it, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		points[it.Elem.String()] = geoDecode(it.Score)
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+	return points, nil
+}
+
+// GeoDist returns the distance in meters between two elements in a geo set.
+// If either of the elements does not exist, returns ErrNotFound.
+// If the key does not exist or is not a set, returns ErrNotFound.
+func (tx *Tx) GeoDist(key string, elem1, elem2 any) (float64, error) {
+	score1, err := tx.GetScore(key, elem1)
+	if err != nil {
+		return 0, err
+	}
+	score2, err := tx.GetScore(key, elem2)
+	if err != nil {
+		return 0, err
+	}
+	return geoDist(geoDecode(score1), geoDecode(score2)), nil
+}
+
+// GeoSearch searches a geo set for the elements within
+// an area (see [GeoSearchCmd]).
+func (tx *Tx) GeoSearch(key string) GeoSearchCmd {
+	return GeoSearchCmd{tx: tx, key: key}
+}
+
+// GeoSearchCmd searches a geo set for the elements within a circle
+// or a rectangle around a center. The center is either a position
+// (FromPoint) or an existing element (FromElem).
+type GeoSearchCmd struct {
+	db       *DB // set if the command runs outside of a transaction
+	tx       *Tx
+	key      string
+	center   *GeoPoint
+	elem     any
+	radius   float64
+	width    float64
+	height   float64
+	byRadius bool
+	byBox    bool
+	desc     bool
+	count    int
+}
+
+// FromPoint sets the search center to the position.
+func (c GeoSearchCmd) FromPoint(lon, lat float64) GeoSearchCmd {
+	c.center = &GeoPoint{lon, lat}
+	c.elem = nil
+	return c
+}
+
+// FromElem sets the search center to the position of the element.
+func (c GeoSearchCmd) FromElem(elem any) GeoSearchCmd {
+	c.elem = elem
+	c.center = nil
+	return c
+}
+
+// ByRadius searches within a circle with the radius in meters.
+func (c GeoSearchCmd) ByRadius(radius float64) GeoSearchCmd {
+	c.radius = radius
+	c.byRadius = true
+	c.byBox = false
+	return c
+}
+
+// ByBox searches within an axis-aligned rectangle
+// with the width and height in meters.
+func (c GeoSearchCmd) ByBox(width, height float64) GeoSearchCmd {
+	c.width = width
+	c.height = height
+	c.byBox = true
+	c.byRadius = false
+	return c
+}
+
+// Asc sorts the elements from the nearest to the farthest (default).
+func (c GeoSearchCmd) Asc() GeoSearchCmd {
+	c.desc = false
+	return c
+}
+
+// Desc sorts the elements from the farthest to the nearest.
+func (c GeoSearchCmd) Desc() GeoSearchCmd {
+	c.desc = true
+	return c
+}
+
+// Count sets the maximum number of elements to return.
+func (c GeoSearchCmd) Count(count int) GeoSearchCmd {
+	c.count = count
+	return c
+}
+
+// Run returns the elements within the area, sorted by the distance
+// from the center according to the sorting direction.
+// If the center element does not exist, returns ErrNotFound.
+// If the center or the area is not set, or the center position
+// is invalid, returns ErrSyntax.
+// If the key does not exist or is not a set, returns a nil slice.
+func (c GeoSearchCmd) Run() ([]GeoItem, error) {
+	if c.db != nil {
+		op := c.db.Observe("SortedSet.GeoSearch", c.key)
+		c.tx = NewTx(c.db.In(op).ReadConn())
+		items, err := c.run()
+		return items, op.Done(err)
+	}
+	return c.run()
+}
+
+// run returns the elements within the area.
+func (c GeoSearchCmd) run() ([]GeoItem, error) {
+	if !c.byRadius && !c.byBox {
+		return nil, core.ErrSyntax
+	}
+
+	// Resolve the search center.
+	var center GeoPoint
+	switch {
+	case c.center != nil:
+		if !validGeoPoint(c.center.Lon, c.center.Lat) {
+			return nil, core.ErrSyntax
+		}
+		center = *c.center
+	case c.elem != nil:
+		score, err := c.tx.GetScore(c.key, c.elem)
+		if err != nil {
+			return nil, err
+		}
+		center = geoDecode(score)
+	default:
+		return nil, core.ErrSyntax
+	}
+
+	// Pre-filter the elements by the geohash cells
+	// covering the area, then check the exact distance.
+	radius := c.radius
+	if c.byBox {
+		radius = math.Hypot(c.width/2, c.height/2)
+	}
+	cells := geoCells(center, radius)
+	conds := make([]string, len(cells))
+	args := []any{
+		sql.Named("key", c.key),
+		sql.Named("now", sqlx.Now(c.tx.tx).UnixMilli()),
+	}
+	for i, cell := range cells {
+		conds[i] = "(score >= ? and score < ?)"
+		args = append(args, cell[0], cell[1])
+	}
+	query := fmt.Sprintf(sqlGeoSearch, strings.Join(conds, " or "))
+
+	rows, err := c.tx.tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []GeoItem
+	for rows.Next() {
+		it, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		point := geoDecode(it.Score)
+		dist, ok := c.within(center, point)
+		if !ok {
+			continue
+		}
+		items = append(items, GeoItem{Elem: it.Elem, Point: point, Dist: dist})
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	// Sort by distance and limit the number of elements.
+	slices.SortStableFunc(items, func(a, b GeoItem) int {
+		if c.desc {
+			a, b = b, a
+		}
+		switch {
+		case a.Dist < b.Dist:
+			return -1
+		case a.Dist > b.Dist:
+			return 1
+		}
+		return strings.Compare(string(a.Elem), string(b.Elem))
+	})
+	if c.count > 0 && len(items) > c.count {
+		items = items[:c.count]
+	}
+	return items, nil
+}
+
+// within returns the distance from the center to the point
+// and reports whether the point is within the search area.
+func (c GeoSearchCmd) within(center, point GeoPoint) (float64, bool) {
+	if c.byBox {
+		// Same as in Redis: the height is measured along the meridian,
+		// and the width along the parallel of the point.
+		latDist := earthRadiusM * math.Abs(toRadians(point.Lat-center.Lat))
+		if latDist > c.height/2 {
+			return 0, false
+		}
+		lonDist := geoDist(GeoPoint{center.Lon, point.Lat}, point)
+		if lonDist > c.width/2 {
+			return 0, false
+		}
+	}
+	dist := geoDist(center, point)
+	if c.byRadius && dist > c.radius {
+		return 0, false
+	}
+	return dist, true
+}
+
+// validGeoPoint reports whether the position can be geohashed.
+func validGeoPoint(lon, lat float64) bool {
+	return lon >= geoLonMin && lon <= geoLonMax &&
+		lat >= geoLatMin && lat <= geoLatMax
+}
+
+// geoEncode returns the 52-bit geohash of the position as a score.
+func geoEncode(lon, lat float64) float64 {
+	ilat := uint64((lat - geoLatMin) / (geoLatMax - geoLatMin) * (1 << geoStep))
+	ilon := uint64((lon - geoLonMin) / (geoLonMax - geoLonMin) * (1 << geoStep))
+	ilat = min(ilat, 1<<geoStep-1)
+	ilon = min(ilon, 1<<geoStep-1)
+	return float64(interleave(ilat, ilon))
+}
+
+// geoDecode returns the center of the geohash cell encoded in the score.
+func geoDecode(score float64) GeoPoint {
+	ilat, ilon := deinterleave(uint64(score))
+	const cells = 1 << geoStep
+	latMin := geoLatMin + float64(ilat)/cells*(geoLatMax-geoLatMin)
+	latMax := geoLatMin + float64(ilat+1)/cells*(geoLatMax-geoLatMin)
+	lonMin := geoLonMin + float64(ilon)/cells*(geoLonMax-geoLonMin)
+	lonMax := geoLonMin + float64(ilon+1)/cells*(geoLonMax-geoLonMin)
+	return GeoPoint{
+		Lon: max(geoLonMin, min(geoLonMax, (lonMin+lonMax)/2)),
+		Lat: max(geoLatMin, min(geoLatMax, (latMin+latMax)/2)),
+	}
+}
+
+// geoCells returns the score ranges [min, max) of the geohash cells
+// covering the circle around the center: the cell of the center
+// and its neighbors, at the finest precision where a cell
+// is at least as large as the radius.
+func geoCells(center GeoPoint, radius float64) [][2]float64 {
+	step := geoStep
+	// The longitude cells are the narrowest at the polar edge of the circle.
+	polarLat := math.Min(math.Abs(center.Lat)+toDegrees(radius/earthRadiusM), 90)
+	for ; step > 1; step-- {
+		height := toRadians((geoLatMax-geoLatMin)/float64(uint64(1)<<step)) * earthRadiusM
+		width := toRadians((geoLonMax-geoLonMin)/float64(uint64(1)<<step)) *
+			earthRadiusM * math.Cos(toRadians(polarLat))
+		if height >= radius && width >= radius {
+			break
+		}
+	}
+
+	cells := int64(1) << step
+	ilat := int64((center.Lat - geoLatMin) / (geoLatMax - geoLatMin) * float64(cells))
+	ilon := int64((center.Lon - geoLonMin) / (geoLonMax - geoLonMin) * float64(cells))
+	ilat = min(ilat, cells-1)
+	ilon = min(ilon, cells-1)
+
+	shift := 2 * (geoStep - step)
+	var ranges [][2]float64
+	for dlat := int64(-1); dlat <= 1; dlat++ {
+		nlat := ilat + dlat
+		if nlat < 0 || nlat >= cells {
+			continue
+		}
+		for dlon := int64(-1); dlon <= 1; dlon++ {
+			// The longitude wraps around the antimeridian.
+			nlon := (ilon + dlon + cells) % cells
+			hash := interleave(uint64(nlat), uint64(nlon))
+			r := [2]float64{float64(hash << shift), float64((hash + 1) << shift)}
+			if !slices.Contains(ranges, r) {
+				ranges = append(ranges, r)
+			}
+		}
+	}
+	return ranges
+}
+
+// geoDist returns the distance in meters between
+// the points using the haversine formula.
+func geoDist(p1, p2 GeoPoint) float64 {
+	lat1, lat2 := toRadians(p1.Lat), toRadians(p2.Lat)
+	u := math.Sin((lat2 - lat1) / 2)
+	v := math.Sin(toRadians(p2.Lon-p1.Lon) / 2)
+	return 2 * earthRadiusM * math.Asin(math.Sqrt(u*u+math.Cos(lat1)*math.Cos(lat2)*v*v))
+}
+
+// interleave interleaves the bits of x and y,
+// so that x takes the even bits and y the odd ones.
+func interleave(x, y uint64) uint64 {
+	return spread(x) | spread(y)<<1
+}
+
+// deinterleave is the inverse of interleave.
+func deinterleave(h uint64) (x, y uint64) {
+	return squash(h), squash(h >> 1)
+}
+
+// spread moves the lower 32 bits of v to the even bits.
+func spread(v uint64) uint64 {
+	v &= 0xFFFFFFFF
+	v = (v | v<<16) & 0x0000FFFF0000FFFF
+	v = (v | v<<8) & 0x00FF00FF00FF00FF
+	v = (v | v<<4) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v<<2) & 0x3333333333333333
+	v = (v | v<<1) & 0x5555555555555555
+	return v
+}
+
+// squash is the inverse of spread.
+func squash(v uint64) uint64 {
+	v &= 0x5555555555555555
+	v = (v | v>>1) & 0x3333333333333333
+	v = (v | v>>2) & 0x0F0F0F0F0F0F0F0F
+	v = (v | v>>4) & 0x00FF00FF00FF00FF
+	v = (v | v>>8) & 0x0000FFFF0000FFFF
+	v = (v | v>>16) & 0x00000000FFFFFFFF
+	return v
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
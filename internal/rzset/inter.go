@@ -4,7 +4,6 @@ import (
 	"database/sql"
 	"slices"
 	"strings"
-	"time"
 
 	"github.com/nalgeon/redka/internal/core"
 	"github.com/nalgeon/redka/internal/rkey"
@@ -22,8 +21,8 @@ const (
 	order by sum(score), elem`
 
 	sqlInterStore1 = `
-	insert into rkey (key, type, version, mtime)
-	values (:key, :type, :version, :mtime)
+	insert into rkey (id, key, type, version, mtime)
+	values (` + sqlx.NextKeyID + `, :key, :type, :version, :mtime)
 	returning id`
 
 	sqlInterStore2 = `
@@ -43,6 +42,7 @@ type InterCmd struct {
 	tx        *Tx
 	dest      string
 	keys      []string
+	weights   []float64
 	aggregate string
 }
 
@@ -70,13 +70,24 @@ func (c InterCmd) Max() InterCmd {
 	return c
 }
 
+// Weights sets the multiplication factor for each source set
+// (in the order of the keys), like WEIGHTS in Redis.
+// The score of each element is multiplied by the weight of its set
+// before aggregation. The keys without a weight have a weight of 1.
+func (c InterCmd) Weights(ws ...float64) InterCmd {
+	c.weights = ws
+	return c
+}
+
 // Run returns the intersection of multiple sets.
 // The intersection consists of elements that exist in all given sets.
 // The score of each element is the aggregate of its scores in the given sets.
 // If any of the source keys do not exist or are not sets, returns an empty slice.
 func (c InterCmd) Run() ([]SetItem, error) {
 	if c.db != nil {
-		return c.inter(c.db.SQL)
+		op := c.db.Observe("SortedSet.InterWith", c.keys...)
+		items, err := c.inter(c.db.In(op).ReadConn())
+		return items, op.Done(err)
 	}
 	if c.tx != nil {
 		return c.inter(c.tx.tx)
@@ -93,13 +104,14 @@ func (c InterCmd) Run() ([]SetItem, error) {
 // except deleting the destination key if it exists.
 func (c InterCmd) Store() (int, error) {
 	if c.db != nil {
+		op := c.db.Observe("SortedSet.InterWith", append([]string{c.dest}, c.keys...)...)
 		var count int
-		err := c.db.Update(func(tx *Tx) error {
+		err := c.db.In(op).Update(func(tx *Tx) error {
 			var err error
 			count, err = c.store(tx.tx)
 			return err
 		})
-		return count, err
+		return count, op.Done(err)
 	}
 	if c.tx != nil {
 		return c.store(c.tx.tx)
@@ -110,13 +122,14 @@ func (c InterCmd) Store() (int, error) {
 // inter returns the intersection of multiple sets.
 func (c InterCmd) inter(tx sqlx.Tx) ([]SetItem, error) {
 	// Prepare query arguments.
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx).UnixMilli()
 	query := sqlInter
 	if c.aggregate != sqlx.Sum {
 		query = strings.Replace(query, sqlx.Sum, c.aggregate, 2)
 	}
+	query, weightArgs := weightScores(query, c.keys, c.weights)
 	query, keyArgs := sqlx.ExpandIn(query, ":keys", c.keys)
-	args := slices.Concat([]any{now}, keyArgs, []any{len(c.keys)})
+	args := slices.Concat(weightArgs, []any{now}, keyArgs, []any{len(c.keys)}, weightArgs)
 
 	// Execute the query.
 	var rows *sql.Rows
@@ -145,13 +158,16 @@ func (c InterCmd) inter(tx sqlx.Tx) ([]SetItem, error) {
 // store intersects multiple sets and stores the result in a new set.
 func (c InterCmd) store(tx sqlx.Tx) (int, error) {
 	// Delete the destination key if it exists.
+	if err := sqlx.CheckKey(tx, c.dest); err != nil {
+		return 0, err
+	}
 	_, err := rkey.DeleteType(tx, core.TypeSortedSet, c.dest)
 	if err != nil {
 		return 0, err
 	}
 
 	// Insert the destination key and get its ID.
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx).UnixMilli()
 	args := []any{
 		sql.Named("key", c.dest),
 		sql.Named("type", core.TypeSortedSet),
@@ -161,7 +177,7 @@ func (c InterCmd) store(tx sqlx.Tx) (int, error) {
 	var keyID int
 	err = tx.QueryRow(sqlInterStore1, args...).Scan(&keyID)
 	if err != nil {
-		return 0, sqlx.TypedError(err)
+		return 0, sqlx.KeyTypeError(tx, err, c.dest, core.TypeSortedSet)
 	}
 
 	// Intersect the sets and store the result.
@@ -169,8 +185,9 @@ func (c InterCmd) store(tx sqlx.Tx) (int, error) {
 	if c.aggregate != sqlx.Sum {
 		query = strings.Replace(query, sqlx.Sum, c.aggregate, 2)
 	}
+	query, weightArgs := weightScores(query, c.keys, c.weights)
 	query, keyArgs := sqlx.ExpandIn(query, ":keys", c.keys)
-	args = slices.Concat([]any{keyID, now}, keyArgs, []any{len(c.keys)})
+	args = slices.Concat([]any{keyID}, weightArgs, []any{now}, keyArgs, []any{len(c.keys)}, weightArgs)
 
 	res, err := tx.Exec(query, args...)
 	if err != nil {
@@ -179,5 +196,9 @@ func (c InterCmd) store(tx sqlx.Tx) (int, error) {
 
 	// Return the number of elements in the resulting set.
 	n, _ := res.RowsAffected()
+	err = sqlx.CheckElements(tx, func() (int, error) { return int(n), nil })
+	if err != nil {
+		return 0, err
+	}
 	return int(n), nil
 }
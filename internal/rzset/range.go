@@ -2,9 +2,11 @@ package rzset
 
 import (
 	"database/sql"
+	"fmt"
+	"iter"
 	"strings"
-	"time"
 
+	"github.com/nalgeon/redka/internal/core"
 	"github.com/nalgeon/redka/internal/sqlx"
 )
 
@@ -29,6 +31,14 @@ const (
 	where key = :key
 	and score between :start and :stop
 	order by score asc, elem asc`
+
+	sqlRangeLex = `
+	select elem, score
+	from rzset
+	  join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key
+	and %s and %s
+	order by score asc, elem asc`
 )
 
 type byRank struct {
@@ -39,12 +49,18 @@ type byScore struct {
 	start, stop float64
 }
 
+type byLex struct {
+	min, max string
+}
+
 // RangeCmd retrieves a range of elements from a sorted set.
 type RangeCmd struct {
+	db      *DB // set if the command runs outside of a transaction
 	tx      sqlx.Tx
 	key     string
 	byRank  *byRank
 	byScore *byScore
+	byLex   *byLex
 	sortDir string
 	offset  int
 	count   int
@@ -54,6 +70,7 @@ type RangeCmd struct {
 func (c RangeCmd) ByRank(start, stop int) RangeCmd {
 	c.byRank = &byRank{start, stop}
 	c.byScore = nil
+	c.byLex = nil
 	return c
 }
 
@@ -61,6 +78,22 @@ func (c RangeCmd) ByRank(start, stop int) RangeCmd {
 func (c RangeCmd) ByScore(start, stop float64) RangeCmd {
 	c.byScore = &byScore{start, stop}
 	c.byRank = nil
+	c.byLex = nil
+	return c
+}
+
+// ByLex sets lexicographical filtering by element, like ZRANGEBYLEX
+// in Redis. Min and max are the bounds of the range: "[a" includes
+// the element "a", "(a" excludes it, while "-" and "+" are the
+// negative and the positive infinity.
+//
+// The elements are ordered by score and then by element, so use
+// it with sets where all elements have the same score (like
+// an autocomplete index). Otherwise, the result is unspecified.
+func (c RangeCmd) ByLex(min, max string) RangeCmd {
+	c.byLex = &byLex{min, max}
+	c.byRank = nil
+	c.byScore = nil
 	return c
 }
 
@@ -77,39 +110,72 @@ func (c RangeCmd) Desc() RangeCmd {
 }
 
 // Offset sets the offset of the range.
-// Only takes effect when filtering by score.
+// Only takes effect when filtering by score or element.
 func (c RangeCmd) Offset(offset int) RangeCmd {
 	c.offset = offset
 	return c
 }
 
 // Count sets the maximum number of elements to return.
-// Only takes effect when filtering by score.
+// Only takes effect when filtering by score or element.
 func (c RangeCmd) Count(count int) RangeCmd {
 	c.count = count
 	return c
 }
 
 // Run returns a range of elements from a sorted set.
-// Uses either by-rank or by-score filtering. The range is inclusive
-// of both start and stop. The elements are sorted by score and then
-// by element according to the sorting direction.
+// Uses either by-rank, by-score or by-element (lexicographical)
+// filtering. The rank and score ranges are inclusive of both start
+// and stop. The elements are sorted by score and then by element
+// according to the sorting direction.
 //
 // Offset and count are optional, and only take effect
-// when filtering by score.
+// when filtering by score or element.
 //
 // If the key does not exist or is not a sorted set,
-// returns a nil slice.
+// returns a nil slice. If the lexicographical range
+// bounds are invalid, returns ErrSyntax.
 func (c RangeCmd) Run() ([]SetItem, error) {
+	if c.db != nil {
+		op := c.db.Observe("SortedSet.RangeWith", c.key)
+		c.tx = c.db.In(op).ReadConn()
+		items, err := c.run()
+		return items, op.Done(err)
+	}
+	return c.run()
+}
+
+// run returns a range of elements from a sorted set.
+func (c RangeCmd) run() ([]SetItem, error) {
 	if c.byRank != nil {
 		return c.rangeRank()
 	}
 	if c.byScore != nil {
 		return c.rangeScore()
 	}
+	if c.byLex != nil {
+		return c.rangeLex()
+	}
 	return nil, nil
 }
 
+// Iter returns an iterator over the range items.
+// Yields the error (if any) instead of the items.
+func (c RangeCmd) Iter() iter.Seq2[SetItem, error] {
+	return func(yield func(SetItem, error) bool) {
+		items, err := c.Run()
+		if err != nil {
+			yield(SetItem{}, err)
+			return
+		}
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
 // rangeRank retrieves a range of elements by rank.
 func (c RangeCmd) rangeRank() ([]SetItem, error) {
 	// Check start and stop values.
@@ -126,32 +192,13 @@ func (c RangeCmd) rangeRank() ([]SetItem, error) {
 	// Prepare query arguments.
 	args := []any{
 		sql.Named("key", c.key),
-		sql.Named("now", time.Now().UnixMilli()),
+		sql.Named("now", sqlx.Now(c.tx).UnixMilli()),
 		sql.Named("start", c.byRank.start),
 		sql.Named("stop", c.byRank.stop),
 	}
 
 	// Execute the query.
-	rows, err := c.tx.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	// Build the resulting element-score slice.
-	var items []SetItem
-	for rows.Next() {
-		it, err := scanItem(rows)
-		if err != nil {
-			return nil, err
-		}
-		items = append(items, it)
-	}
-	if rows.Err() != nil {
-		return nil, rows.Err()
-	}
-
-	return items, nil
+	return c.selectItems(query, args)
 }
 
 // rangeScore retrieves a range of elements by score.
@@ -163,18 +210,12 @@ func (c RangeCmd) rangeScore() ([]SetItem, error) {
 	}
 
 	// Add offset and count if necessary.
-	if c.offset > 0 && c.count > 0 {
-		query += " limit :offset, :count"
-	} else if c.count > 0 {
-		query += " limit :count"
-	} else if c.offset > 0 {
-		query += " limit :offset, -1"
-	}
+	query = c.limit(query)
 
 	// Prepare query arguments.
 	args := []any{
 		sql.Named("key", c.key),
-		sql.Named("now", time.Now().UnixMilli()),
+		sql.Named("now", sqlx.Now(c.tx).UnixMilli()),
 		sql.Named("start", c.byScore.start),
 		sql.Named("stop", c.byScore.stop),
 		sql.Named("offset", c.offset),
@@ -182,6 +223,57 @@ func (c RangeCmd) rangeScore() ([]SetItem, error) {
 	}
 
 	// Execute the query.
+	return c.selectItems(query, args)
+}
+
+// rangeLex retrieves a range of elements lexicographically.
+func (c RangeCmd) rangeLex() ([]SetItem, error) {
+	minCond, min, err := lexCond(c.byLex.min, ">")
+	if err != nil {
+		return nil, err
+	}
+	maxCond, max, err := lexCond(c.byLex.max, "<")
+	if err != nil {
+		return nil, err
+	}
+
+	// Change sort direction if necessary.
+	query := fmt.Sprintf(sqlRangeLex, minCond, maxCond)
+	if c.sortDir != sqlx.Asc {
+		query = strings.Replace(query, sqlx.Asc, c.sortDir, -1)
+	}
+
+	// Add offset and count if necessary.
+	query = c.limit(query)
+
+	// Prepare query arguments.
+	args := []any{
+		sql.Named("key", c.key),
+		sql.Named("now", sqlx.Now(c.tx).UnixMilli()),
+		sql.Named("min", min),
+		sql.Named("max", max),
+		sql.Named("offset", c.offset),
+		sql.Named("count", c.count),
+	}
+
+	// Execute the query.
+	return c.selectItems(query, args)
+}
+
+// limit adds the offset and count to the query if necessary.
+func (c RangeCmd) limit(query string) string {
+	if c.offset > 0 && c.count > 0 {
+		query += " limit :offset, :count"
+	} else if c.count > 0 {
+		query += " limit :count"
+	} else if c.offset > 0 {
+		query += " limit :offset, -1"
+	}
+	return query
+}
+
+// selectItems executes the query and returns the selected items.
+func (c RangeCmd) selectItems(query string, args []any) ([]SetItem, error) {
 	rows, err := c.tx.Query(query, args...)
 	if err != nil {
 		return nil, err
@@ -203,3 +295,26 @@ func (c RangeCmd) rangeScore() ([]SetItem, error) {
 
 	return items, nil
 }
+
+// lexCond returns the SQL condition for the lexicographical range
+// bound ("[a", "(a", "-" or "+") and the element to compare with.
+// The op is ">" for the lower bound and "<" for the upper one.
+func lexCond(bound string, op string) (string, string, error) {
+	param := ":min"
+	if op == "<" {
+		param = ":max"
+	}
+	switch {
+	case bound == "-" && op == ">", bound == "+" && op == "<":
+		// Unbounded.
+		return "true", "", nil
+	case bound == "+" || bound == "-":
+		// Nothing is beyond the infinity.
+		return "false", "", nil
+	case strings.HasPrefix(bound, "["):
+		return "elem " + op + "= " + param, bound[1:], nil
+	case strings.HasPrefix(bound, "("):
+		return "elem " + op + " " + param, bound[1:], nil
+	}
+	return "", "", core.ErrSyntax
+}
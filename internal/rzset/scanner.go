@@ -1,5 +1,7 @@
 package rzset
 
+import "iter"
+
 // Scanner is the iterator for set items.
 // Stops when there are no more items or an error occurs.
 type Scanner struct {
@@ -12,6 +14,7 @@ type Scanner struct {
 	cur      SetItem
 	items    []SetItem
 	err      error
+	close    func() // called when the scanner is exhausted
 }
 
 func newScanner(tx *Tx, key string, pattern string, pageSize int) *Scanner {
@@ -38,12 +41,14 @@ func (sc *Scanner) Scan() bool {
 		out, err := sc.tx.Scan(sc.key, sc.cursor, sc.pattern, sc.pageSize)
 		if err != nil {
 			sc.err = err
+			sc.done()
 			return false
 		}
 		sc.cursor = out.Cursor
 		sc.items = out.Items
 		sc.index = 0
 		if len(sc.items) == 0 {
+			sc.done()
 			return false
 		}
 	}
@@ -53,6 +58,13 @@ func (sc *Scanner) Scan() bool {
 	return true
 }
 
+// done marks the scanner as exhausted.
+func (sc *Scanner) done() {
+	if sc.close != nil {
+		sc.close()
+	}
+}
+
 // Item returns the current set item.
 func (sc *Scanner) Item() SetItem {
 	return sc.cur
@@ -62,3 +74,21 @@ func (sc *Scanner) Item() SetItem {
 func (sc *Scanner) Err() error {
 	return sc.err
 }
+
+// All returns an iterator over the remaining items:
+//
+//	for item := range sc.All() {
+//	    // ...
+//	}
+//	if err := sc.Err(); err != nil {
+//	    // ...
+//	}
+func (sc *Scanner) All() iter.Seq[SetItem] {
+	return func(yield func(SetItem) bool) {
+		for sc.Scan() {
+			if !yield(sc.Item()) {
+				return
+			}
+		}
+	}
+}
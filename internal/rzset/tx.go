@@ -2,9 +2,9 @@ package rzset
 
 import (
 	"database/sql"
+	"math/rand/v2"
 	"slices"
 	"strings"
-	"time"
 
 	"github.com/nalgeon/redka/internal/core"
 	"github.com/nalgeon/redka/internal/sqlx"
@@ -12,8 +12,8 @@ import (
 
 const (
 	sqlAdd1 = `
-	insert into rkey (key, type, version, mtime)
-	values (:key, :type, :version, :mtime)
+	insert into rkey (id, key, type, version, mtime)
+	values (` + sqlx.NextKeyID + `, :key, :type, :version, :mtime)
 	on conflict (key) do update set
 		version = version+1,
 		type = excluded.type,
@@ -21,7 +21,7 @@ const (
 
 	sqlAdd2 = `
 	insert into rzset (key_id, elem, score)
-	values ((select id from rkey where key = :key), :elem, :score)
+	values ((select id from rkey where key = :key), cast(:elem as text), :score)
 	on conflict (key_id, elem) do update
 	set score = excluded.score`
 
@@ -55,17 +55,17 @@ const (
 	)
 	select rank, score
 	from ranked
-	where elem = :elem`
+	where elem = cast(:elem as text)`
 
 	sqlGetScore = `
 	select score
 	from rzset
 	join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key and elem = :elem`
+	where key = :key and elem = cast(:elem as text)`
 
 	sqlIncr1 = `
-	insert into rkey (key, type, version, mtime)
-	values (:key, :type, :version, :mtime)
+	insert into rkey (id, key, type, version, mtime)
+	values (` + sqlx.NextKeyID + `, :key, :type, :version, :mtime)
 	on conflict (key) do update set
 		version = version+1,
 		type = excluded.type,
@@ -73,7 +73,7 @@ const (
 
 	sqlIncr2 = `
 	insert into rzset (key_id, elem, score)
-	values ((select id from rkey where key = :key), :elem, :delta)
+	values ((select id from rkey where key = :key), cast(:elem as text), :delta)
 	on conflict (key_id, elem) do update
 	set score = score + excluded.score
 	returning score`
@@ -84,11 +84,44 @@ const (
 	join rkey on key_id = rkey.id and (etime is null or etime > :now)
 	where key = :key`
 
+	sqlPop1 = `
+	select rzset.rowid, elem, score
+	from rzset
+	join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key
+	order by score asc, elem asc
+	limit :count`
+
+	sqlPop2 = `
+	update rkey set
+		version = version+1,
+		mtime = :now
+	where key = :key`
+
+	sqlPopDelete = `
+	delete from rzset where rowid in (:ids)`
+
+	sqlRandMember = `
+	select elem, score
+	from rzset
+	join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key
+	order by random()
+	limit :count`
+
+	sqlRandAll = `
+	select elem, score
+	from rzset
+	join rkey on key_id = rkey.id and (etime is null or etime > :now)
+	where key = :key
+	order by score, elem`
+
 	sqlScan = `
 	select rzset.rowid, elem, score
 	from rzset
 	join rkey on key_id = rkey.id and (etime is null or etime > :now)
-	where key = :key and rzset.rowid > :cursor and elem glob :pattern
+	where key = :key and rzset.rowid > :cursor
+	  and (elem glob :pattern or instr(cast(elem as blob), x'00') > 0)
 	limit :count`
 )
 
@@ -118,6 +151,11 @@ func (tx *Tx) Add(key string, elem any, score float64) (bool, error) {
 	if err != nil {
 		return false, err
 	}
+	if existCount == 0 {
+		if err := tx.checkLen(key); err != nil {
+			return false, err
+		}
+	}
 	return existCount == 0, nil
 }
 
@@ -143,6 +181,11 @@ func (tx *Tx) AddMany(key string, items map[any]float64) (int, error) {
 			return 0, err
 		}
 	}
+	if len(items) > existCount {
+		if err := tx.checkLen(key); err != nil {
+			return 0, err
+		}
+	}
 
 	return len(items) - existCount, nil
 }
@@ -153,7 +196,7 @@ func (tx *Tx) AddMany(key string, items map[any]float64) (int, error) {
 func (tx *Tx) Count(key string, min, max float64) (int, error) {
 	args := []any{
 		sql.Named("key", key),
-		sql.Named("now", time.Now().UnixMilli()),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
 		sql.Named("min", min),
 		sql.Named("max", max),
 	}
@@ -176,8 +219,8 @@ func (tx *Tx) Delete(key string, elems ...any) (int, error) {
 	}
 
 	// Remove the elements.
-	now := time.Now().UnixMilli()
-	query, elemArgs := sqlx.ExpandIn(sqlDelete, ":elems", elems)
+	now := sqlx.Now(tx.tx).UnixMilli()
+	query, elemArgs := sqlx.ExpandInText(sqlDelete, ":elems", elems)
 	args := slices.Concat([]any{sql.Named("key", key), sql.Named("now", now)}, elemArgs)
 	res, err := tx.tx.Exec(query, args...)
 	if err != nil {
@@ -193,6 +236,23 @@ func (tx *Tx) DeleteWith(key string) DeleteCmd {
 	return DeleteCmd{tx: tx, key: key}
 }
 
+// Diff returns the difference between the first set and the rest.
+// The difference consists of elements that exist in the first set
+// but not in any of the other sets. The score of each element
+// is its score in the first set.
+// Ignores the other keys that do not exist or are not sets.
+// If the first key does not exist or is not a set, returns a nil slice.
+func (tx *Tx) Diff(keys ...string) ([]SetItem, error) {
+	cmd := DiffCmd{tx: tx, keys: keys}
+	return cmd.Run()
+}
+
+// DiffWith subtracts multiple sets from the first one
+// with additional options.
+func (tx *Tx) DiffWith(keys ...string) DiffCmd {
+	return DiffCmd{tx: tx, keys: keys}
+}
+
 // GetRank returns the rank and score of an element in a set.
 // The rank is the 0-based position of the element in the set, ordered
 // by score (from low to high), and then by lexicographical order (ascending).
@@ -221,7 +281,7 @@ func (tx *Tx) GetScore(key string, elem any) (float64, error) {
 
 	args := []any{
 		sql.Named("key", key),
-		sql.Named("now", time.Now().UnixMilli()),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
 		sql.Named("elem", elem),
 	}
 	var score float64
@@ -245,19 +305,22 @@ func (tx *Tx) Incr(key string, elem any, delta float64) (float64, error) {
 	if !core.IsValueType(elem) {
 		return 0, core.ErrValueType
 	}
+	if err := checkLimits(tx.tx, key, elem); err != nil {
+		return 0, err
+	}
 
 	args := []any{
 		sql.Named("key", key),
 		sql.Named("type", core.TypeSortedSet),
 		sql.Named("version", core.InitialVersion),
-		sql.Named("mtime", time.Now().UnixMilli()),
+		sql.Named("mtime", sqlx.Now(tx.tx).UnixMilli()),
 		sql.Named("elem", elem),
 		sql.Named("delta", delta),
 	}
 
 	_, err := tx.tx.Exec(sqlIncr1, args...)
 	if err != nil {
-		return 0, sqlx.TypedError(err)
+		return 0, sqlx.KeyTypeError(tx.tx, err, key, core.TypeSortedSet)
 	}
 
 	var score float64
@@ -265,6 +328,9 @@ func (tx *Tx) Incr(key string, elem any, delta float64) (float64, error) {
 	if err != nil {
 		return 0, err
 	}
+	if err := tx.checkLen(key); err != nil {
+		return 0, err
+	}
 
 	return score, nil
 }
@@ -286,13 +352,64 @@ func (tx *Tx) InterWith(keys ...string) InterCmd {
 // Len returns the number of elements in a set.
 // Returns 0 if the key does not exist or is not a set.
 func (tx *Tx) Len(key string) (int, error) {
-	now := time.Now().UnixMilli()
+	now := sqlx.Now(tx.tx).UnixMilli()
 	args := []any{sql.Named("key", key), sql.Named("now", now)}
 	var n int
 	err := tx.tx.QueryRow(sqlLen, args...).Scan(&n)
 	return n, err
 }
 
+// PopMax removes and returns up to count elements with the highest
+// scores (and then the largest elements) from a set, the highest first.
+// If the key does not exist, is not a set, or count is not positive,
+// returns a nil slice. Does not delete the key if the set becomes empty.
+func (tx *Tx) PopMax(key string, count int) ([]SetItem, error) {
+	return tx.pop(key, count, sqlx.Desc)
+}
+
+// PopMin removes and returns up to count elements with the lowest
+// scores (and then the smallest elements) from a set, the lowest first.
+// If the key does not exist, is not a set, or count is not positive,
+// returns a nil slice. Does not delete the key if the set becomes empty.
+func (tx *Tx) PopMin(key string, count int) ([]SetItem, error) {
+	return tx.pop(key, count, sqlx.Asc)
+}
+
+// RandMember returns random elements from a set, like ZRANDMEMBER in Redis.
+// With a positive count, returns up to count distinct elements.
+// With a negative count, returns exactly -count elements,
+// which may repeat. If withScores is false, the scores
+// of the returned elements are not set (zero).
+// If the key does not exist or is not a set, returns a nil slice.
+func (tx *Tx) RandMember(key string, count int, withScores bool) ([]SetItem, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	args := []any{
+		sql.Named("key", key),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
+		sql.Named("count", count),
+	}
+	var items []SetItem
+	var err error
+	rnd := sqlx.RandOf(tx.tx)
+	if count > 0 && rnd == nil {
+		items, err = sqlx.Select(tx.tx, sqlRandMember, args, scanItem)
+	} else {
+		items, err = sqlx.Select(tx.tx, sqlRandAll, args, scanItem)
+		items = pickRandom(rnd, items, count)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !withScores {
+		for i := range items {
+			items[i].Score = 0
+		}
+	}
+	return items, nil
+}
+
 // Range returns a range of elements from a set with ranks between start and stop.
 // The rank is the 0-based position of the element in the set, ordered
 // by score (from low to high), and then by lexicographical order (ascending).
@@ -318,15 +435,13 @@ func (tx *Tx) Scan(key string, cursor int, pattern string, count int) (ScanResul
 	if count == 0 {
 		count = scanPageSize
 	}
-
-	args := []any{
-		sql.Named("key", key),
-		sql.Named("now", time.Now().UnixMilli()),
-		sql.Named("cursor", cursor),
-		sql.Named("pattern", pattern),
-		sql.Named("count", count),
+	cur, err := sqlx.ParseCursor(tx.tx, cursor)
+	if err != nil {
+		return ScanResult{}, err
 	}
 
+	glob := sqlx.NewGlob(tx.tx, pattern)
+
 	// Select set items matching the pattern.
 	scan := func(rows *sql.Rows) (SetItem, error) {
 		var it SetItem
@@ -335,20 +450,38 @@ func (tx *Tx) Scan(key string, cursor int, pattern string, count int) (ScanResul
 		it.Elem = core.Value(elem)
 		return it, err
 	}
-	items, err := sqlx.Select(tx.tx, sqlScan, args, scan)
-	if err != nil {
-		return ScanResult{}, err
-	}
+	for {
+		args := []any{
+			sql.Named("key", key),
+			sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
+			sql.Named("cursor", cur.ID),
+			sql.Named("pattern", glob.Arg()),
+			sql.Named("count", count),
+		}
+		items, err := sqlx.Select(tx.tx, sqlScan, args, scan)
+		if err != nil {
+			return ScanResult{}, err
+		}
 
-	// Select the maximum ID.
-	maxID := 0
-	for _, it := range items {
-		if it.id > maxID {
-			maxID = it.id
+		// Select the maximum ID.
+		maxID := 0
+		for _, it := range items {
+			if it.id > maxID {
+				maxID = it.id
+			}
 		}
-	}
 
-	return ScanResult{maxID, items}, nil
+		// Skip the pages where none of the elements with NUL bytes
+		// match, so that an empty page still means there are
+		// no more items (see sqlx.Glob).
+		matched := slices.DeleteFunc(items, func(it SetItem) bool {
+			return !glob.Match(string(it.Elem))
+		})
+		if len(matched) > 0 || maxID == 0 {
+			return ScanResult{cur.Next(maxID), matched}, nil
+		}
+		cur.ID = maxID
+	}
 }
 
 // Scanner returns an iterator for set items with elements matching pattern.
@@ -380,25 +513,45 @@ func (tx *Tx) add(key string, elem any, score float64) error {
 	if !core.IsValueType(elem) {
 		return core.ErrValueType
 	}
+	if err := checkLimits(tx.tx, key, elem); err != nil {
+		return err
+	}
 
 	args := []any{
 		sql.Named("key", key),
 		sql.Named("type", core.TypeSortedSet),
 		sql.Named("version", core.InitialVersion),
-		sql.Named("mtime", time.Now().UnixMilli()),
+		sql.Named("mtime", sqlx.Now(tx.tx).UnixMilli()),
 		sql.Named("elem", elem),
 		sql.Named("score", score),
 	}
 
 	_, err := tx.tx.Exec(sqlAdd1, args...)
 	if err != nil {
-		return sqlx.TypedError(err)
+		return sqlx.KeyTypeError(tx.tx, err, key, core.TypeSortedSet)
 	}
 
 	_, err = tx.tx.Exec(sqlAdd2, args...)
 	return err
 }
 
+// checkLen checks the number of elements in a set
+// against the size limits (see sqlx.Limits).
+func (tx *Tx) checkLen(key string) error {
+	return sqlx.CheckElements(tx.tx, func() (int, error) {
+		return tx.Len(key)
+	})
+}
+
+// checkLimits checks the key and element against
+// the size limits (see sqlx.Limits).
+func checkLimits(tx sqlx.Tx, key string, elem any) error {
+	if err := sqlx.CheckKey(tx, key); err != nil {
+		return err
+	}
+	return sqlx.CheckValue(tx, elem)
+}
+
 // count returns the number of existing elements in a set.
 func (tx *Tx) count(key string, elems ...any) (int, error) {
 	for _, elem := range elems {
@@ -407,8 +560,8 @@ func (tx *Tx) count(key string, elems ...any) (int, error) {
 		}
 	}
 
-	now := time.Now().UnixMilli()
-	query, fieldArgs := sqlx.ExpandIn(sqlCount, ":elems", elems)
+	now := sqlx.Now(tx.tx).UnixMilli()
+	query, fieldArgs := sqlx.ExpandInText(sqlCount, ":elems", elems)
 	args := slices.Concat([]any{sql.Named("key", key), sql.Named("now", now)}, fieldArgs)
 	var count int
 	err := tx.tx.QueryRow(query, args...).Scan(&count)
@@ -423,7 +576,7 @@ func (tx *Tx) getRank(key string, elem any, sortDir string) (rank int, score flo
 
 	args := []any{
 		sql.Named("key", key),
-		sql.Named("now", time.Now().UnixMilli()),
+		sql.Named("now", sqlx.Now(tx.tx).UnixMilli()),
 		sql.Named("elem", elem),
 	}
 	query := sqlGetRank
@@ -442,6 +595,96 @@ func (tx *Tx) getRank(key string, elem any, sortDir string) (rank int, score flo
 	return rank, score, nil
 }
 
+// pop removes and returns up to count elements
+// from a set according to the sorting direction.
+func (tx *Tx) pop(key string, count int, sortDir string) ([]SetItem, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+	now := sqlx.Now(tx.tx).UnixMilli()
+	args := []any{
+		sql.Named("key", key),
+		sql.Named("now", now),
+		sql.Named("count", count),
+	}
+	query := sqlPop1
+	if sortDir != sqlx.Asc {
+		query = strings.Replace(query, sqlx.Asc, sortDir, -1)
+	}
+	items, err := sqlx.Select(tx.tx, query, args, func(rows *sql.Rows) (SetItem, error) {
+		var it SetItem
+		var elem []byte
+		err := rows.Scan(&it.id, &elem, &it.Score)
+		it.Elem = core.Value(elem)
+		return it, err
+	})
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+
+	ids := make([]int, len(items))
+	for i, it := range items {
+		ids[i] = it.id
+	}
+	query, idArgs := sqlx.ExpandIn(sqlPopDelete, ":ids", ids)
+	if _, err := tx.tx.Exec(query, idArgs...); err != nil {
+		return nil, err
+	}
+	if _, err := tx.tx.Exec(sqlPop2, args...); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// pickRandom returns random items according to the count
+// (see Tx.RandMember). Uses the random source if there is one.
+func pickRandom(rnd *sqlx.Rand, items []SetItem, count int) []SetItem {
+	if len(items) == 0 {
+		return nil
+	}
+	intN := rand.IntN
+	if rnd != nil {
+		intN = rnd.IntN
+	}
+	if count < 0 {
+		picked := make([]SetItem, -count)
+		for i := range picked {
+			picked[i] = items[intN(len(items))]
+		}
+		return picked
+	}
+	// Partial Fisher-Yates shuffle.
+	count = min(count, len(items))
+	for i := 0; i < count; i++ {
+		j := i + intN(len(items)-i)
+		items[i], items[j] = items[j], items[i]
+	}
+	return items[:count]
+}
+
+// weightScores multiplies the scores in the aggregate
+// expressions of the query by the weights of their keys
+// (see InterCmd.Weights and UnionCmd.Weights).
+// Returns the query and the arguments for each of the expressions.
+func weightScores(query string, keys []string, weights []float64) (string, []any) {
+	if len(weights) == 0 {
+		return query, nil
+	}
+	var expr strings.Builder
+	var args []any
+	expr.WriteString("(score * case key")
+	for i, key := range keys {
+		if i >= len(weights) {
+			break
+		}
+		expr.WriteString(" when ? then ?")
+		args = append(args, key, weights[i])
+	}
+	expr.WriteString(" else 1 end)")
+	query = strings.ReplaceAll(query, "(score)", expr.String())
+	return query, args
+}
+
 // scanItem scans a set item from the current row.
 func scanItem(rows *sql.Rows) (SetItem, error) {
 	var it SetItem
@@ -463,6 +706,6 @@ type SetItem struct {
 
 // ScanResult is a result of the scan operation.
 type ScanResult struct {
-	Cursor int
+	Cursor int // opaque cursor to continue the scan, 0 when done (see sqlx.Cursor)
 	Items  []SetItem
 }
@@ -0,0 +1,187 @@
+package sentinel
+
+import (
+	"maps"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/redcon"
+)
+
+// handle processes the client commands.
+func (s *Sentinel) handle(conn redcon.Conn, cmd redcon.Command) {
+	name := strings.ToLower(string(cmd.Args[0]))
+	switch name {
+	case "ping":
+		conn.WriteString("PONG")
+	case "sentinel":
+		if len(cmd.Args) < 2 {
+			conn.WriteError("ERR wrong number of arguments for 'sentinel' command")
+			return
+		}
+		s.sentinel(conn, cmd.Args[1:])
+	case "subscribe", "psubscribe":
+		if len(cmd.Args) < 2 {
+			conn.WriteError("ERR wrong number of arguments for '" + name + "' command")
+			return
+		}
+		for _, ch := range cmd.Args[1:] {
+			if name == "subscribe" {
+				s.ps.Subscribe(conn, string(ch))
+			} else {
+				s.ps.Psubscribe(conn, string(ch))
+			}
+		}
+	case "role":
+		conn.WriteArray(2)
+		conn.WriteBulkString("sentinel")
+		conn.WriteArray(1)
+		conn.WriteBulkString(s.opts.Name)
+	case "quit":
+		conn.WriteString("OK")
+		_ = conn.Close()
+	default:
+		conn.WriteError("ERR unknown command '" + name + "'")
+	}
+}
+
+// sentinel handles the SENTINEL subcommands.
+func (s *Sentinel) sentinel(conn redcon.Conn, args [][]byte) {
+	sub := strings.ToLower(string(args[0]))
+	switch sub {
+	case "masters":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.master == "" {
+			conn.WriteArray(0)
+			return
+		}
+		conn.WriteArray(1)
+		writeFields(conn, s.masterFields())
+		return
+	}
+
+	if len(args) != 2 {
+		conn.WriteError("ERR wrong number of arguments for 'sentinel|" + sub + "' command")
+		return
+	}
+	if string(args[1]) != s.opts.Name {
+		if sub == "get-master-addr-by-name" {
+			conn.WriteNull()
+		} else {
+			conn.WriteError("ERR No such master with that name")
+		}
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch sub {
+	case "get-master-addr-by-name":
+		if s.master == "" {
+			conn.WriteNull()
+			return
+		}
+		host, port, _ := net.SplitHostPort(s.master)
+		conn.WriteArray(2)
+		conn.WriteBulkString(host)
+		conn.WriteBulkString(port)
+	case "master":
+		if s.master == "" {
+			conn.WriteError("ERR No such master with that name")
+			return
+		}
+		writeFields(conn, s.masterFields())
+	case "replicas", "slaves":
+		replicas := s.replicas()
+		conn.WriteArray(len(replicas))
+		for _, n := range replicas {
+			writeFields(conn, s.replicaFields(n))
+		}
+	case "sentinels":
+		// Each sentinel works on its own,
+		// so there are no other sentinels.
+		conn.WriteArray(0)
+	default:
+		conn.WriteError("ERR unknown subcommand '" + sub + "'")
+	}
+}
+
+// replicas returns the nodes that replicate from the current primary.
+func (s *Sentinel) replicas() []*node {
+	var replicas []*node
+	for _, addr := range slices.Sorted(maps.Keys(s.nodes)) {
+		n := s.nodes[addr]
+		if n.role == "slave" && n.master == s.master {
+			replicas = append(replicas, n)
+		}
+	}
+	return replicas
+}
+
+// masterFields returns the current primary state
+// as SENTINEL MASTER field-value pairs.
+func (s *Sentinel) masterFields() []string {
+	n := s.nodes[s.master]
+	host, port, _ := net.SplitHostPort(n.addr)
+	flags := "master"
+	if n.down {
+		flags = "s_down,master"
+	}
+	return []string{
+		"name", s.opts.Name,
+		"ip", host,
+		"port", port,
+		"runid", "",
+		"flags", flags,
+		"last-ok-ping-reply", sinceMillis(n.lastOK),
+		"down-after-milliseconds", strconv.FormatInt(s.opts.DownAfter.Milliseconds(), 10),
+		"role-reported", "master",
+		"num-slaves", strconv.Itoa(len(s.replicas())),
+		"num-other-sentinels", "0",
+		"quorum", "1",
+	}
+}
+
+// replicaFields returns the replica state
+// as SENTINEL REPLICAS field-value pairs.
+func (s *Sentinel) replicaFields(n *node) []string {
+	host, port, _ := net.SplitHostPort(n.addr)
+	masterHost, masterPort, _ := net.SplitHostPort(n.master)
+	flags, link := "slave", "err"
+	if n.down {
+		flags = "s_down,slave"
+	}
+	if n.linkUp {
+		link = "ok"
+	}
+	return []string{
+		"name", n.addr,
+		"ip", host,
+		"port", port,
+		"runid", "",
+		"flags", flags,
+		"last-ok-ping-reply", sinceMillis(n.lastOK),
+		"role-reported", "slave",
+		"master-link-status", link,
+		"master-host", masterHost,
+		"master-port", masterPort,
+		"slave-repl-offset", strconv.FormatInt(n.offset, 10),
+	}
+}
+
+// writeFields writes the field-value pairs as a flat array.
+func writeFields(conn redcon.Conn, fields []string) {
+	conn.WriteArray(len(fields))
+	for _, f := range fields {
+		conn.WriteBulkString(f)
+	}
+}
+
+// sinceMillis returns the milliseconds passed since t.
+func sinceMillis(t time.Time) string {
+	return strconv.FormatInt(time.Since(t).Milliseconds(), 10)
+}
@@ -0,0 +1,371 @@
+// Package sentinel implements a subset of the Redis Sentinel
+// protocol for the Redka primary-replica deployments.
+//
+// The sentinel polls the nodes with INFO replication, tracks the
+// primary (the reachable node that reports role:master) and its
+// replicas, and answers the commands used by the Sentinel-aware
+// clients to find the primary:
+//
+//	SENTINEL get-master-addr-by-name <name>
+//	SENTINEL masters | master <name>
+//	SENTINEL replicas <name> | slaves <name>
+//	SENTINEL sentinels <name>
+//	SUBSCRIBE +switch-master (also +sdown and -sdown)
+//
+// The sentinel does not promote replicas by itself. When the primary
+// goes down and another node starts reporting role:master (e.g. a
+// replica restarted without -replicaof), the sentinel switches to it
+// and publishes +switch-master, so the clients reconnect.
+package sentinel
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka/internal/resp"
+	"github.com/tidwall/redcon"
+)
+
+// Options holds the sentinel options.
+type Options struct {
+	// Name is the name of the monitored primary
+	// (the master name in the client configuration).
+	// Defaults to "mymaster".
+	Name string
+	// Nodes are the addresses (host:port) of the monitored nodes.
+	// The sentinel also discovers the replicas connected to the primary,
+	// so it's enough to list the primary and the failover candidates.
+	Nodes []string
+	// Interval is the polling interval. Defaults to 1 second.
+	Interval time.Duration
+	// DownAfter is the time after which an unreachable node
+	// is considered down. Defaults to 5 seconds.
+	DownAfter time.Duration
+	// Logger logs the topology changes. If nil, uses slog.Default().
+	Logger *slog.Logger
+}
+
+// Sentinel monitors the Redka nodes and serves
+// the Sentinel protocol to the clients.
+type Sentinel struct {
+	addr   string
+	srv    *redcon.Server
+	ps     redcon.PubSub
+	opts   *Options
+	mu     sync.Mutex
+	nodes  map[string]*node
+	master string // current primary address
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// node is the last known state of a monitored node.
+type node struct {
+	addr   string
+	role   string // master or slave
+	master string // primary address reported by a replica
+	linkUp bool   // replica link status
+	offset int64
+	lastOK time.Time // last successful poll
+	down   bool
+}
+
+// New creates a new sentinel listening on addr.
+// The opts parameter is optional. If nil, uses default options.
+func New(addr string, opts *Options) *Sentinel {
+	s := &Sentinel{
+		addr:  addr,
+		opts:  applyOptions(opts),
+		nodes: map[string]*node{},
+	}
+	now := time.Now()
+	for _, addr := range s.opts.Nodes {
+		s.nodes[addr] = &node{addr: addr, lastOK: now}
+	}
+	s.srv = redcon.NewServer(addr, s.handle, nil, nil)
+	return s
+}
+
+// applyOptions returns a copy of the options with the defaults applied.
+func applyOptions(opts *Options) *Options {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Name == "" {
+		o.Name = "mymaster"
+	}
+	if o.Interval <= 0 {
+		o.Interval = time.Second
+	}
+	if o.DownAfter <= 0 {
+		o.DownAfter = 5 * time.Second
+	}
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+	return &o
+}
+
+// Start starts monitoring the nodes and serving the clients.
+func (s *Sentinel) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		s.monitor(ctx)
+	}()
+	go func() {
+		defer s.wg.Done()
+		s.opts.Logger.Info("serve sentinel", "addr", s.addr, "master", s.opts.Name)
+		err := s.srv.ListenAndServe()
+		if err != nil {
+			s.opts.Logger.Error("serve sentinel", "error", err)
+		}
+	}()
+}
+
+// Stop stops the sentinel.
+func (s *Sentinel) Stop() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	err := s.srv.Close()
+	s.wg.Wait()
+	return err
+}
+
+// Master returns the address of the current primary,
+// or an empty string if there is none.
+func (s *Sentinel) Master() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.master
+}
+
+// monitor polls the nodes until the context is canceled.
+func (s *Sentinel) monitor(ctx context.Context) {
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		s.poll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll queries every known node and updates the topology.
+func (s *Sentinel) poll(ctx context.Context) {
+	s.mu.Lock()
+	addrs := make([]string, 0, len(s.nodes))
+	for addr := range s.nodes {
+		addrs = append(addrs, addr)
+	}
+	s.mu.Unlock()
+
+	type result struct {
+		addr string
+		info map[string]string
+		err  error
+	}
+	results := make(chan result, len(addrs))
+	for _, addr := range addrs {
+		go func() {
+			info, err := queryInfo(ctx, addr, s.opts.Interval)
+			results <- result{addr, info, err}
+		}()
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range addrs {
+		res := <-results
+		n := s.nodes[res.addr]
+		if res.err != nil {
+			s.opts.Logger.Debug("poll node", "addr", res.addr, "error", res.err)
+		} else {
+			n.update(res.info, now)
+			for _, addr := range replicaAddrs(res.info) {
+				if _, ok := s.nodes[addr]; !ok {
+					s.opts.Logger.Info("discover replica", "addr", addr, "master", res.addr)
+					s.nodes[addr] = &node{addr: addr, lastOK: now}
+				}
+			}
+		}
+		s.checkDown(n, now)
+	}
+	s.electMaster()
+}
+
+// checkDown marks the node as down (or up again)
+// and publishes the +sdown/-sdown events.
+func (s *Sentinel) checkDown(n *node, now time.Time) {
+	down := now.Sub(n.lastOK) > s.opts.DownAfter
+	if down == n.down {
+		return
+	}
+	n.down = down
+	role := "slave"
+	if n.addr == s.master {
+		role = "master"
+	}
+	event := "-sdown"
+	if down {
+		event = "+sdown"
+	}
+	s.opts.Logger.Warn("node state", "event", event, "addr", n.addr, "role", role)
+	s.ps.Publish(event, role+" "+s.instanceName(n.addr)+" "+hostPort(n.addr))
+}
+
+// electMaster switches to a new primary if the current one
+// is down or no longer reports the master role. Prefers the node
+// that the replicas follow, then the first node in the options.
+func (s *Sentinel) electMaster() {
+	if cur := s.nodes[s.master]; cur != nil && !cur.down && cur.role == "master" {
+		return
+	}
+	var candidates []string
+	for addr, n := range s.nodes {
+		if !n.down && n.role == "master" {
+			candidates = append(candidates, addr)
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+	slices.SortFunc(candidates, func(a, b string) int {
+		return s.rank(a) - s.rank(b)
+	})
+	next := candidates[0]
+	if next == s.master {
+		return
+	}
+
+	prev := s.master
+	s.master = next
+	if prev == "" {
+		s.opts.Logger.Info("monitor master", "name", s.opts.Name, "addr", next)
+		return
+	}
+	s.opts.Logger.Warn("switch master", "name", s.opts.Name, "from", prev, "to", next)
+	s.ps.Publish("+switch-master", s.opts.Name+" "+hostPort(prev)+" "+hostPort(next))
+}
+
+// rank returns the election rank of the node (lower is better).
+func (s *Sentinel) rank(addr string) int {
+	followers := 0
+	for _, n := range s.nodes {
+		if n.role == "slave" && n.master == addr {
+			followers++
+		}
+	}
+	pos := slices.Index(s.opts.Nodes, addr)
+	if pos < 0 {
+		pos = len(s.opts.Nodes)
+	}
+	return pos - followers*len(s.nodes)
+}
+
+// instanceName returns the node name as reported in the events.
+func (s *Sentinel) instanceName(addr string) string {
+	if addr == s.master {
+		return s.opts.Name
+	}
+	return addr
+}
+
+// update sets the node state from the INFO replication fields.
+func (n *node) update(info map[string]string, now time.Time) {
+	n.lastOK = now
+	n.role = info["role"]
+	n.master = ""
+	if n.role == "slave" {
+		n.master = net.JoinHostPort(info["master_host"], info["master_port"])
+		n.linkUp = info["master_link_status"] == "up"
+		n.offset, _ = strconv.ParseInt(info["slave_repl_offset"], 10, 64)
+	}
+}
+
+// queryInfo returns the INFO replication fields of the node.
+func queryInfo(ctx context.Context, addr string, timeout time.Duration) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	c, err := resp.Dial(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = c.SetDeadline(deadline)
+	}
+	reply, err := c.Do("info", "replication")
+	if err != nil {
+		return nil, err
+	}
+	text, ok := reply.([]byte)
+	if !ok {
+		return nil, resp.ErrFormat
+	}
+	return parseInfo(string(text)), nil
+}
+
+// parseInfo parses the key:value lines of the INFO reply.
+func parseInfo(text string) map[string]string {
+	info := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if key, val, ok := strings.Cut(line, ":"); ok {
+			info[key] = val
+		}
+	}
+	return info
+}
+
+// replicaAddrs returns the replica addresses listed in the
+// INFO replication of a primary (slaveN:ip=...,port=...).
+func replicaAddrs(info map[string]string) []string {
+	var addrs []string
+	for key, val := range info {
+		if !strings.HasPrefix(key, "slave") || key == "slave_read_only" {
+			continue
+		}
+		var ip, port string
+		for _, field := range strings.Split(val, ",") {
+			k, v, _ := strings.Cut(field, "=")
+			switch k {
+			case "ip":
+				ip = v
+			case "port":
+				port = v
+			}
+		}
+		if ip != "" && port != "" {
+			addrs = append(addrs, net.JoinHostPort(ip, port))
+		}
+	}
+	slices.Sort(addrs)
+	return addrs
+}
+
+// hostPort returns the address as "host port" (as in the events).
+func hostPort(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host + " " + port
+}
@@ -0,0 +1,182 @@
+package sentinel
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka/internal/resp"
+	"github.com/nalgeon/redka/internal/testx"
+	"github.com/tidwall/redcon"
+)
+
+func TestParseInfo(t *testing.T) {
+	text := "# Replication\r\nrole:master\r\nconnected_slaves:2\r\n" +
+		"slave0:ip=10.0.0.2,port=6380,state=online,offset=10,lag=0\r\n" +
+		"slave1:ip=10.0.0.3,port=6381,state=online,offset=10,lag=1\r\n"
+	info := parseInfo(text)
+	testx.AssertEqual(t, info["role"], "master")
+	testx.AssertEqual(t, info["connected_slaves"], "2")
+	testx.AssertEqual(t, replicaAddrs(info), []string{"10.0.0.2:6380", "10.0.0.3:6381"})
+}
+
+func TestSentinel(t *testing.T) {
+	primary := startNode(t, "master", "")
+	replica := startNode(t, "slave", primary.addr)
+
+	addr := freeAddr(t)
+	s := New(addr, &Options{
+		Name:      "main",
+		Nodes:     []string{primary.addr, replica.addr},
+		Interval:  10 * time.Millisecond,
+		DownAfter: 50 * time.Millisecond,
+	})
+	s.Start()
+	defer func() { _ = s.Stop() }()
+
+	waitFor(t, func() bool { return s.Master() == primary.addr })
+	client := dial(t, addr)
+
+	t.Run("get-master-addr-by-name", func(t *testing.T) {
+		reply, err := client.Do("sentinel", "get-master-addr-by-name", "main")
+		testx.AssertNoErr(t, err)
+		host, port, _ := net.SplitHostPort(primary.addr)
+		testx.AssertEqual(t, reply, []any{[]byte(host), []byte(port)})
+
+		reply, err = client.Do("sentinel", "get-master-addr-by-name", "other")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, reply, nil)
+	})
+	t.Run("masters", func(t *testing.T) {
+		reply, err := client.Do("sentinel", "masters")
+		testx.AssertNoErr(t, err)
+		masters := reply.([]any)
+		testx.AssertEqual(t, len(masters), 1)
+		fields := fieldMap(masters[0])
+		testx.AssertEqual(t, fields["name"], "main")
+		testx.AssertEqual(t, fields["flags"], "master")
+		testx.AssertEqual(t, fields["num-slaves"], "1")
+	})
+	t.Run("replicas", func(t *testing.T) {
+		reply, err := client.Do("sentinel", "replicas", "main")
+		testx.AssertNoErr(t, err)
+		replicas := reply.([]any)
+		testx.AssertEqual(t, len(replicas), 1)
+		fields := fieldMap(replicas[0])
+		testx.AssertEqual(t, fields["name"], replica.addr)
+		testx.AssertEqual(t, fields["master-link-status"], "ok")
+
+		_, err = client.Do("sentinel", "replicas", "other")
+		testx.AssertEqual(t, err.Error(), "ERR No such master with that name")
+	})
+	t.Run("switch-master", func(t *testing.T) {
+		sub := dial(t, addr)
+		reply, err := sub.Do("subscribe", "+switch-master")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, reply, []any{[]byte("subscribe"), []byte("+switch-master"), int64(1)})
+
+		// The primary goes down, and the replica is promoted.
+		primary.stop()
+		replica.set("master", "")
+
+		_ = sub.SetDeadline(time.Now().Add(5 * time.Second))
+		msg, err := sub.Receive()
+		testx.AssertNoErr(t, err)
+		want := "main " + strings.Replace(primary.addr, ":", " ", 1) +
+			" " + strings.Replace(replica.addr, ":", " ", 1)
+		testx.AssertEqual(t, msg, []any{[]byte("message"), []byte("+switch-master"), []byte(want)})
+		testx.AssertEqual(t, s.Master(), replica.addr)
+	})
+}
+
+// fakeNode answers INFO replication with the configured role.
+type fakeNode struct {
+	addr   string
+	ln     net.Listener
+	mu     sync.Mutex
+	role   string
+	master string
+}
+
+// startNode starts a fake node on a random port.
+func startNode(t *testing.T, role, master string) *fakeNode {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testx.AssertNoErr(t, err)
+	n := &fakeNode{addr: ln.Addr().String(), ln: ln, role: role, master: master}
+	go func() {
+		_ = redcon.Serve(ln, n.handle, nil, nil)
+	}()
+	t.Cleanup(n.stop)
+	return n
+}
+
+func (n *fakeNode) handle(conn redcon.Conn, cmd redcon.Command) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var b strings.Builder
+	b.WriteString("# Replication\r\nrole:" + n.role + "\r\n")
+	if n.role == "slave" {
+		host, port, _ := net.SplitHostPort(n.master)
+		b.WriteString("master_host:" + host + "\r\nmaster_port:" + port + "\r\n")
+		b.WriteString("master_link_status:up\r\nslave_repl_offset:42\r\n")
+	}
+	conn.WriteBulkString(b.String())
+}
+
+func (n *fakeNode) set(role, master string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.role, n.master = role, master
+}
+
+func (n *fakeNode) stop() {
+	_ = n.ln.Close()
+}
+
+// freeAddr returns a free local address.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testx.AssertNoErr(t, err)
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+// dial connects to the sentinel, waiting for it to start.
+func dial(t *testing.T, addr string) *resp.Client {
+	t.Helper()
+	var client *resp.Client
+	waitFor(t, func() bool {
+		var err error
+		client, err = resp.Dial(context.Background(), addr)
+		return err == nil
+	})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// waitFor waits until the condition is true.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// fieldMap converts the flat field-value array to a map.
+func fieldMap(reply any) map[string]string {
+	items := reply.([]any)
+	m := map[string]string{}
+	for i := 0; i+1 < len(items); i += 2 {
+		m[string(items[i].([]byte))] = string(items[i+1].([]byte))
+	}
+	return m
+}
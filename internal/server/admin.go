@@ -0,0 +1,324 @@
+package server
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rkey"
+)
+
+// Default admin settings.
+const (
+	defaultAdminSlowLog = 10 * time.Millisecond // same as slowlog-log-slower-than in Redis
+	adminSlowLogLen     = 128                   // same as slowlog-max-len in Redis
+	adminScanCount      = 50
+	maxAdminScanCount   = 1000
+)
+
+//go:embed admin.html
+var adminHTML []byte
+
+// adminUI serves the web admin dashboard: the single-page UI at /
+// and the JSON API it uses under /api:
+//
+//	GET    /api/keys?match=user:*&cursor=0&count=50 -> {"cursor":12,"keys":[{"key":"user:1","type":"hash","ttl":-1}]}
+//	GET    /api/key?key=user:1  -> {"key":"user:1","type":"hash","ttl":-1,"value":{"name":"alice"}}
+//	POST   /api/run             -> same as the HTTP API (body is {"args":["SET","name","alice"]})
+//	GET    /api/info            -> {"info":"# Replication\r\n..."}
+//	GET    /api/slowlog         -> [{"time":...,"duration":12000,"op":"Str.Set","keys":["name"]}]
+//	DELETE /api/slowlog         -> {"reset":true}
+//
+// The TTLs are in milliseconds (-1 if the key does not expire),
+// and the slow operation durations are in microseconds.
+// The API uses the same tokens as the HTTP API. The editors in the UI
+// change the keys with the regular commands (/api/run), so the changes
+// go to the journal and the replicas like any other.
+type adminUI struct {
+	db       *redka.DB
+	opts     *Options
+	api      *httpAPI
+	keyspace *keyspaceCache
+	slowLog  *slowLog
+	mux      *http.ServeMux
+}
+
+// newAdminUI creates the admin dashboard handler
+// and starts recording the slow operations.
+func newAdminUI(db *redka.DB, opts *Options) *adminUI {
+	threshold := opts.AdminSlowLog
+	if threshold == 0 {
+		threshold = defaultAdminSlowLog
+	}
+	ui := &adminUI{
+		db:       db,
+		opts:     opts,
+		api:      newHTTPAPI(db, opts),
+		keyspace: &keyspaceCache{db: db, opts: opts},
+		slowLog:  &slowLog{threshold: threshold},
+		mux:      http.NewServeMux(),
+	}
+	if opts.Shards != nil {
+		for i := range opts.Shards.Len() {
+			opts.Shards.DB(i).AddHook(ui.slowLog)
+		}
+	} else {
+		db.AddHook(ui.slowLog)
+	}
+
+	ui.mux.HandleFunc("GET /{$}", ui.index)
+	ui.mux.HandleFunc("GET /api/keys", ui.keys)
+	ui.mux.HandleFunc("GET /api/key", ui.key)
+	ui.mux.HandleFunc("POST /api/run", ui.run)
+	ui.mux.HandleFunc("GET /api/info", ui.info)
+	ui.mux.HandleFunc("GET /api/slowlog", ui.slowLogList)
+	ui.mux.HandleFunc("DELETE /api/slowlog", ui.slowLogReset)
+	return ui
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (ui *adminUI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/api/") && !ui.api.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSONError(w, http.StatusUnauthorized, "NOAUTH Authentication required.")
+		return
+	}
+	ui.mux.ServeHTTP(w, r)
+}
+
+// index serves the dashboard page.
+func (ui *adminUI) index(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(adminHTML)
+}
+
+// adminKey describes a key in the key browser.
+type adminKey struct {
+	Key   string `json:"key"`
+	Type  string `json:"type"`
+	TTL   int64  `json:"ttl"`
+	Value any    `json:"value,omitempty"`
+}
+
+// keys returns a page of the keys matching the pattern.
+func (ui *adminUI) keys(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	match := q.Get("match")
+	if match == "" {
+		match = "*"
+	}
+	cursor, err := queryInt(q.Get("cursor"), 0)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "ERR invalid cursor")
+		return
+	}
+	count, err := queryInt(q.Get("count"), adminScanCount)
+	if err != nil || count <= 0 {
+		writeJSONError(w, http.StatusBadRequest, "ERR invalid count")
+		return
+	}
+	count = min(count, maxAdminScanCount)
+
+	var out rkey.ScanResult
+	if ui.opts.Shards != nil {
+		out, err = ui.opts.Shards.Key().Scan(cursor, match, count)
+	} else {
+		out, err = ui.db.WithContext(r.Context()).Key().Scan(cursor, match, count)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	now := ui.db.Now()
+	keys := make([]adminKey, len(out.Keys))
+	for i, k := range out.Keys {
+		keys[i] = adminKey{Key: k.Key, Type: k.TypeName(), TTL: keyTTL(k, now)}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"cursor": out.Cursor, "keys": keys})
+}
+
+// key returns the key with its value.
+func (ui *adminUI) key(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	db := ui.db.WithContext(r.Context())
+	if ui.opts.Shards != nil {
+		db = ui.opts.Shards.Shard(key).WithContext(r.Context())
+	}
+	vals, err := db.GetMany(key)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	kv := vals[0]
+	if !kv.Key.Exists() {
+		writeJSONError(w, http.StatusNotFound, "ERR no such key")
+		return
+	}
+	res := adminKey{Key: key, Type: kv.Key.TypeName(), TTL: keyTTL(kv.Key, db.Now())}
+	switch kv.Key.Type {
+	case core.TypeString:
+		res.Value = kv.Str.String()
+	case core.TypeHash:
+		hash := make(map[string]string, len(kv.Hash))
+		for field, val := range kv.Hash {
+			hash[field] = val.String()
+		}
+		res.Value = hash
+	case core.TypeSortedSet:
+		type item struct {
+			Elem  string  `json:"elem"`
+			Score float64 `json:"score"`
+		}
+		zset := make([]item, len(kv.ZSet))
+		for i, it := range kv.ZSet {
+			zset[i] = item{Elem: it.Elem.String(), Score: it.Score}
+		}
+		res.Value = zset
+	case core.TypeStream:
+		type entry struct {
+			ID     string            `json:"id"`
+			Fields map[string]string `json:"fields"`
+		}
+		stream := make([]entry, len(kv.Stream))
+		for i, e := range kv.Stream {
+			fields := make(map[string]string, len(e.Fields))
+			for _, f := range e.Fields {
+				fields[f.Name] = f.Value.String()
+			}
+			stream[i] = entry{ID: e.ID.String(), Fields: fields}
+		}
+		res.Value = stream
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// run executes the command like the HTTP API does.
+func (ui *adminUI) run(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Args []string `json:"args"`
+	}
+	body := http.MaxBytesReader(w, r.Body, maxHTTPBody)
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, "ERR invalid body: "+err.Error())
+		return
+	}
+	if len(req.Args) == 0 {
+		writeJSONError(w, http.StatusBadRequest, "ERR wrong number of arguments")
+		return
+	}
+	args := make([][]byte, len(req.Args))
+	for i, arg := range req.Args {
+		args[i] = []byte(arg)
+	}
+	ui.api.run(w, r, args)
+}
+
+// info returns the INFO sections (all if none are requested).
+func (ui *adminUI) info(w http.ResponseWriter, r *http.Request) {
+	var sections [][]byte
+	for _, s := range r.URL.Query()["section"] {
+		sections = append(sections, []byte(s))
+	}
+	writeJSON(w, http.StatusOK, map[string]string{
+		"info": infoText(sections, ui.keyspace, ui.opts),
+	})
+}
+
+// slowLogList returns the recorded slow operations, the latest first.
+func (ui *adminUI) slowLogList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, ui.slowLog.entries())
+}
+
+// slowLogReset clears the recorded slow operations.
+func (ui *adminUI) slowLogReset(w http.ResponseWriter, r *http.Request) {
+	ui.slowLog.reset()
+	writeJSON(w, http.StatusOK, map[string]bool{"reset": true})
+}
+
+// keyTTL returns the time to live of the key
+// in milliseconds, or -1 if it does not expire.
+func keyTTL(k core.Key, now time.Time) int64 {
+	if k.ETime == nil {
+		return -1
+	}
+	return max(*k.ETime-now.UnixMilli(), 0)
+}
+
+// queryInt parses the query parameter,
+// or returns the default value if it is empty.
+func queryInt(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	return strconv.Atoi(s)
+}
+
+// slowOp is a recorded slow operation.
+type slowOp struct {
+	Time     int64    `json:"time"`     // start time in unix milliseconds
+	Duration int64    `json:"duration"` // in microseconds
+	Op       string   `json:"op"`
+	Keys     []string `json:"keys"`
+	Err      string   `json:"error,omitempty"`
+}
+
+// slowLog is a hook that keeps the latest operations slower
+// than the threshold (like SLOWLOG in Redis). Safe for concurrent use.
+type slowLog struct {
+	threshold time.Duration
+
+	mu   sync.Mutex
+	ops  []slowOp // ring buffer
+	next int      // index of the next entry in the full buffer
+}
+
+func (l *slowLog) Before(ctx context.Context, op *redka.Op) {}
+
+func (l *slowLog) After(ctx context.Context, op *redka.Op) {
+	if op.Duration < l.threshold {
+		return
+	}
+	entry := slowOp{
+		Time:     op.Start.UnixMilli(),
+		Duration: op.Duration.Microseconds(),
+		Op:       op.Name,
+		Keys:     op.Keys,
+	}
+	if op.Err != nil {
+		entry.Err = op.Err.Error()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.ops) < adminSlowLogLen {
+		l.ops = append(l.ops, entry)
+		return
+	}
+	l.ops[l.next] = entry
+	l.next = (l.next + 1) % adminSlowLogLen
+}
+
+// entries returns the recorded operations, the latest first.
+func (l *slowLog) entries() []slowOp {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	res := make([]slowOp, 0, len(l.ops))
+	for i := range l.ops {
+		idx := (l.next - 1 - i + 2*len(l.ops)) % len(l.ops)
+		res = append(res, l.ops[idx])
+	}
+	return res
+}
+
+// reset removes the recorded operations.
+func (l *slowLog) reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.ops, l.next = nil, 0
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+)
+
+func TestAdminUI(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	_, _ = db.Hash().Set("person", "age", 25)
+	_, _ = db.SortedSet().Add("race", "bob", 12)
+
+	ui := newAdminUI(db, applyOptions(nil))
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"GET", "/api/keys?match=n*", "", 200, `{"cursor":1,"keys":[{"key":"name","type":"string","ttl":-1}]}`},
+		{"GET", "/api/keys?count=x", "", 400, `{"error":"ERR invalid count"}`},
+		{"GET", "/api/key?key=name", "", 200, `{"key":"name","type":"string","ttl":-1,"value":"alice"}`},
+		{"GET", "/api/key?key=person", "", 200, `{"key":"person","type":"hash","ttl":-1,"value":{"age":"25"}}`},
+		{"GET", "/api/key?key=race", "", 200, `{"key":"race","type":"zset","ttl":-1,"value":[{"elem":"bob","score":12}]}`},
+		{"GET", "/api/key?key=nope", "", 404, `{"error":"ERR no such key"}`},
+		{"POST", "/api/run", `{"args":["SET","name","bob"]}`, 200, `{"SET":"OK"}`},
+		{"POST", "/api/run", `{"args":["GET","name"]}`, 200, `{"GET":"bob"}`},
+		{"POST", "/api/run", `{"args":[]}`, 400, `{"error":"ERR wrong number of arguments"}`},
+		{"GET", "/api/info?section=keyspace", "", 200, `{"info":"# Keyspace\r\ndb0:keys=3,expires=0,avg_ttl=0\r\n"}`},
+		{"DELETE", "/api/slowlog", "", 200, `{"reset":true}`},
+		{"GET", "/api/slowlog", "", 200, `[]`},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			status, body := serve(ui, req)
+			if status != test.status {
+				t.Errorf("status: want %d, got %d", test.status, status)
+			}
+			if body != test.want {
+				t.Errorf("body: want %s, got %s", test.want, body)
+			}
+		})
+	}
+
+	t.Run("index", func(t *testing.T) {
+		status, body := serve(ui, httptest.NewRequest("GET", "/", nil))
+		if status != 200 || !strings.Contains(body, "<title>Redka admin</title>") {
+			t.Errorf("want the dashboard page, got %d", status)
+		}
+	})
+	t.Run("auth", func(t *testing.T) {
+		ui := newAdminUI(db, applyOptions(&Options{HTTPTokens: []string{"secret"}}))
+		status, _ := serve(ui, httptest.NewRequest("GET", "/api/keys", nil))
+		if status != 401 {
+			t.Errorf("api: want 401, got %d", status)
+		}
+		status, _ = serve(ui, httptest.NewRequest("GET", "/", nil))
+		if status != 200 {
+			t.Errorf("page: want 200, got %d", status)
+		}
+	})
+}
+
+func TestSlowLog(t *testing.T) {
+	log := &slowLog{threshold: time.Millisecond}
+	for i := range adminSlowLogLen + 2 {
+		op := &redka.Op{Name: "Str.Set", Keys: []string{string(rune('a' + i%26))}, Duration: time.Duration(i) * time.Millisecond}
+		log.After(context.Background(), op)
+	}
+	ops := log.entries()
+	if len(ops) != adminSlowLogLen {
+		t.Fatalf("want %d entries, got %d", adminSlowLogLen, len(ops))
+	}
+	// The fast operation (0ms) is skipped, and the oldest one is evicted.
+	if ops[0].Duration != int64(adminSlowLogLen+1)*1000 {
+		t.Errorf("latest: want %d, got %d", (adminSlowLogLen+1)*1000, ops[0].Duration)
+	}
+	if ops[len(ops)-1].Duration != 2000 {
+		t.Errorf("oldest: want 2000, got %d", ops[len(ops)-1].Duration)
+	}
+	log.reset()
+	if len(log.entries()) != 0 {
+		t.Error("want no entries after reset")
+	}
+}
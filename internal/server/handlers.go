@@ -1,29 +1,58 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nalgeon/redka"
 	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/glob"
 	"github.com/tidwall/redcon"
 )
 
 // createHandlers returns the server command handlers.
-func createHandlers(db *redka.DB) redcon.HandlerFunc {
-	return logging(parse(multi(handle(db))))
+func createHandlers(db *redka.DB, opts *Options) redcon.HandlerFunc {
+	opts = applyOptions(opts)
+	return logging(opts.Logger, tracing(db, replication(opts, info(db, opts, hello(opts, reload(opts,
+		selectDB(db, opts, watchKeys(db, opts, parse(readonly(opts, multi(opts, handle(db, opts))))))))))))
 }
 
 // logging logs the command processing time.
-func logging(next redcon.HandlerFunc) redcon.HandlerFunc {
+func logging(log *slog.Logger, next redcon.HandlerFunc) redcon.HandlerFunc {
 	return func(conn redcon.Conn, cmd redcon.Command) {
 		start := time.Now()
 		next(conn, cmd)
-		slog.Debug("process command", "client", conn.RemoteAddr(),
+		log.Debug("process command", "client", conn.RemoteAddr(),
 			"name", string(cmd.Args[0]), "time", time.Since(start))
 	}
 }
 
+// tracing traces the command processing if the database
+// has a tracer (see redka.Options.TracerProvider). The repository
+// operations made by the command become children of its span.
+func tracing(db *redka.DB, next redcon.HandlerFunc) redcon.HandlerFunc {
+	tracer := db.Tracer()
+	if tracer == nil {
+		return next
+	}
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		name := strings.ToUpper(string(cmd.Args[0]))
+		ctx, span := tracer.Start(context.Background(), name)
+		span.SetAttribute("db.system", "redka")
+		span.SetAttribute("db.operation.name", name)
+		span.SetAttribute("client.address", conn.RemoteAddr())
+		state := getState(conn)
+		state.ctx = ctx
+		next(conn, cmd)
+		state.ctx = nil
+		span.End()
+	}
+}
+
 // parse parses the command arguments.
 func parse(next redcon.HandlerFunc) redcon.HandlerFunc {
 	return func(conn redcon.Conn, cmd redcon.Command) {
@@ -40,10 +69,17 @@ func parse(next redcon.HandlerFunc) redcon.HandlerFunc {
 
 // multi handles the MULTI, EXEC, and DISCARD commands and delegates
 // the rest to the next handler either in multi or single mode.
-func multi(next redcon.HandlerFunc) redcon.HandlerFunc {
+// Transactions are not supported in the sharded mode, as the keys
+// of a transaction may be in different shards.
+func multi(opts *Options, next redcon.HandlerFunc) redcon.HandlerFunc {
 	return func(conn redcon.Conn, cmd redcon.Command) {
 		name := normName(cmd)
 		state := getState(conn)
+		if opts.Shards != nil && name == "multi" {
+			state.pop()
+			conn.WriteError("ERR MULTI is not supported in sharded mode")
+			return
+		}
 		if state.inMulti {
 			switch name {
 			case "multi":
@@ -51,13 +87,14 @@ func multi(next redcon.HandlerFunc) redcon.HandlerFunc {
 				conn.WriteError(command.ErrNestedMulti.Error())
 			case "exec":
 				state.pop()
-				conn.WriteArray(len(state.cmds))
 				next(conn, cmd)
 				state.inMulti = false
+				state.watches = nil
 			case "discard":
 				state.clear()
 				conn.WriteString("OK")
 				state.inMulti = false
+				state.watches = nil
 			default:
 				conn.WriteString("QUEUED")
 			}
@@ -80,44 +117,295 @@ func multi(next redcon.HandlerFunc) redcon.HandlerFunc {
 	}
 }
 
+// readonly rejects the write commands if the server is a replica.
+func readonly(opts *Options, next redcon.HandlerFunc) redcon.HandlerFunc {
+	if opts.Replica == nil {
+		return next
+	}
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		if command.IsWrite(normName(cmd)) {
+			state := getState(conn)
+			state.pop()
+			conn.WriteError(command.ErrReadOnly.Error())
+			return
+		}
+		next(conn, cmd)
+	}
+}
+
+// reload handles the RELOAD command (if the server has
+// Options.Reload) and delegates the rest to the next handler.
+// RELOAD
+func reload(opts *Options, next redcon.HandlerFunc) redcon.HandlerFunc {
+	if opts.Reload == nil {
+		return next
+	}
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		if normName(cmd) != "reload" {
+			next(conn, cmd)
+			return
+		}
+		if len(cmd.Args) != 1 {
+			conn.WriteError("ERR wrong number of arguments for 'reload' command")
+			return
+		}
+		if err := opts.Reload(); err != nil {
+			opts.Logger.Warn("reload", "error", err)
+			conn.WriteError("ERR " + err.Error())
+			return
+		}
+		conn.WriteString("OK")
+	}
+}
+
+// selectDB handles the SELECT command and delegates
+// the rest to the next handler. Selects a tenant by name
+// if the tenants are set, or a logical database by index otherwise.
+// The journal and replication only cover database 0, so selecting
+// another database or tenant fails if either of them is enabled.
+// The shards do not have databases or tenants either.
+// SELECT index
+// https://redis.io/commands/select
+func selectDB(db *redka.DB, opts *Options, next redcon.HandlerFunc) redcon.HandlerFunc {
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		if normName(cmd) != "select" {
+			next(conn, cmd)
+			return
+		}
+		state := getState(conn)
+		if len(cmd.Args) != 2 {
+			conn.WriteError(command.ErrInvalidArgNum.Error() + " (select)")
+			return
+		}
+		if state.inMulti {
+			conn.WriteError("ERR SELECT is not allowed in MULTI (select)")
+			return
+		}
+		name := string(cmd.Args[1])
+		if name == "0" {
+			state.tenant = nil
+			conn.WriteString("OK")
+			return
+		}
+		if opts.Journal != nil || opts.Primary != nil {
+			conn.WriteError("ERR SELECT is not allowed with the journal or replication (select)")
+			return
+		}
+		if opts.Shards != nil {
+			conn.WriteError("ERR SELECT is not supported in sharded mode (select)")
+			return
+		}
+		var tenant *redka.DB
+		var err error
+		if opts.Tenants != nil {
+			tenant, err = opts.Tenants.Get(name)
+		} else if index, perr := strconv.Atoi(name); perr == nil {
+			tenant, err = db.Select(index)
+		} else {
+			err = redka.ErrDBIndex
+		}
+		if err != nil {
+			conn.WriteError("ERR DB index is out of range (select)")
+			return
+		}
+		state.tenant = tenant
+		conn.WriteString("OK")
+	}
+}
+
+// watchKeys handles the WATCH, PWATCH and UNWATCH commands
+// and delegates the rest to the next handler.
+// WATCH key [key ...]
+// PWATCH pattern [pattern ...]
+// UNWATCH
+// https://redis.io/commands/watch
+//
+// PWATCH is like WATCH, but watches all the keys matching
+// the pattern (see rkey.Tx.Stamp), so EXEC aborts if any of
+// them is created, changed or deleted. Useful for transactions
+// that read keys with dynamic names.
+func watchKeys(db *redka.DB, opts *Options, next redcon.HandlerFunc) redcon.HandlerFunc {
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		name := normName(cmd)
+		if name != "watch" && name != "pwatch" && name != "unwatch" {
+			next(conn, cmd)
+			return
+		}
+		state := getState(conn)
+		if name == "unwatch" {
+			if len(cmd.Args) != 1 {
+				conn.WriteError(command.ErrInvalidArgNum.Error() + " (unwatch)")
+				return
+			}
+			if state.inMulti {
+				// Like in Redis, UNWATCH is queued, but
+				// does nothing, since EXEC unwatches anyway.
+				conn.WriteString("QUEUED")
+				return
+			}
+			state.watches = nil
+			conn.WriteString("OK")
+			return
+		}
+		if len(cmd.Args) < 2 {
+			conn.WriteError(command.ErrInvalidArgNum.Error() + " (" + name + ")")
+			return
+		}
+		if state.inMulti {
+			conn.WriteError(command.ErrWatchInMulti.Error())
+			return
+		}
+		if opts.Shards != nil {
+			conn.WriteError("ERR " + strings.ToUpper(name) + " is not supported in sharded mode")
+			return
+		}
+		if state.tenant != nil {
+			db = state.tenant
+		}
+		watches := make([]watch, 0, len(cmd.Args)-1)
+		for _, arg := range cmd.Args[1:] {
+			pattern := string(arg)
+			if name == "watch" {
+				pattern = glob.Escape(pattern)
+			}
+			stamp, err := db.Key().Stamp(pattern)
+			if err != nil {
+				opts.Logger.Warn("watch", "client", conn.RemoteAddr(), "err", err)
+				conn.WriteError("ERR " + err.Error())
+				return
+			}
+			watches = append(watches, watch{pattern: pattern, stamp: stamp})
+		}
+		state.watches = append(state.watches, watches...)
+		conn.WriteString("OK")
+	}
+}
+
 // handle processes the command in either multi or single mode.
-func handle(db *redka.DB) redcon.HandlerFunc {
+func handle(db *redka.DB, opts *Options) redcon.HandlerFunc {
 	return func(conn redcon.Conn, cmd redcon.Command) {
 		state := getState(conn)
+		db, opts := db, opts
+		if state.tenant != nil {
+			// The journal and replication only cover
+			// the main database (see selectDB).
+			tenantOpts := *opts
+			tenantOpts.Journal, tenantOpts.Primary = nil, nil
+			db, opts = state.tenant, &tenantOpts
+		}
+		if state.ctx != nil {
+			db = db.WithContext(state.ctx)
+		}
+		if opts.PinAfterWrite > 0 && time.Since(state.lastWrite) < opts.PinAfterWrite {
+			db = db.Primary()
+		}
+		if opts.Primary != nil {
+			opts.Primary.BeginWrite()
+			defer opts.Primary.EndWrite()
+		}
+		red := command.RedkaDB(db)
+		if opts.Shards != nil {
+			red = command.RedkaShards(opts.Shards)
+		}
 		if state.inMulti {
-			handleMulti(conn, state, db)
+			handleMulti(conn, state, db, opts)
 		} else {
-			handleSingle(conn, state, db)
+			handleSingle(conn, state, red, opts)
 		}
 		state.clear()
 	}
 }
 
 // handleMulti processes a batch of commands in a transaction.
-func handleMulti(conn redcon.Conn, state *connState, db *redka.DB) {
+// If any of the watched keys has changed, does not run
+// the commands and replies with a null array.
+func handleMulti(conn redcon.Conn, state *connState, db *redka.DB, opts *Options) {
+	var writes []command.Cmd
+	var replied bool
 	err := db.Update(func(tx *redka.Tx) error {
+		for _, w := range state.watches {
+			err := tx.Key().CheckStamp(w.pattern, w.stamp)
+			if errors.Is(err, redka.ErrVersion) {
+				conn.WriteArray(-1)
+				replied = true
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+		conn.WriteArray(len(state.cmds))
+		replied = true
 		for _, pcmd := range state.cmds {
-			_, err := pcmd.Run(conn, command.RedkaTx(tx))
+			res, err := pcmd.Run(conn, command.RedkaTx(tx).WithProcs(db, tx))
 			if err != nil {
-				slog.Warn("run multi command", "client", conn.RemoteAddr(),
+				opts.Logger.Warn("run multi command", "client", conn.RemoteAddr(),
 					"name", pcmd.Name(), "err", err)
 				return err
 			}
+			if isChange(pcmd, res) {
+				writes = append(writes, pcmd)
+			}
 		}
 		return nil
 	})
 	if err != nil {
-		slog.Warn("run multi", "client", conn.RemoteAddr(), "err", err)
+		opts.Logger.Warn("run multi", "client", conn.RemoteAddr(), "err", err)
+		if !replied {
+			conn.WriteError("ERR " + err.Error())
+		}
+		return
+	}
+	if len(writes) > 0 {
+		state.lastWrite = time.Now()
 	}
+	propagate(opts, writes...)
 }
 
 // handleSingle processes a single command.
-func handleSingle(conn redcon.Conn, state *connState, db *redka.DB) {
+func handleSingle(conn redcon.Conn, state *connState, red command.Redka, opts *Options) {
 	pcmd := state.pop()
-	_, err := pcmd.Run(conn, command.RedkaDB(db))
+	res, err := pcmd.Run(conn, red)
 	if err != nil {
-		slog.Warn("run single command", "client", conn.RemoteAddr(),
+		opts.Logger.Warn("run single command", "client", conn.RemoteAddr(),
 			"name", pcmd.Name(), "err", err)
 		return
 	}
+	if isChange(pcmd, res) {
+		state.lastWrite = time.Now()
+		propagate(opts, pcmd)
+	}
+}
+
+// isChange reports whether the successfully executed
+// command has modified the data. Write commands that report
+// false as a result (e.g. SETNX on an existing key)
+// have not changed anything. FCALL always counts as a change,
+// since the procedure result says nothing about its writes.
+func isChange(pcmd command.Cmd, res any) bool {
+	if !command.IsWrite(pcmd.Name()) {
+		return false
+	}
+	if pcmd.Name() == "fcall" {
+		return true
+	}
+	ok, isBool := res.(bool)
+	return !isBool || ok
+}
+
+// propagate appends the commands to the journal and sends them
+// to the replicas (if any). The changes are already committed,
+// so journal errors are logged but not reported to the client.
+func propagate(opts *Options, cmds ...command.Cmd) {
+	if len(cmds) == 0 {
+		return
+	}
+	if opts.Journal != nil {
+		if err := opts.Journal.Append(cmds...); err != nil {
+			opts.Logger.Error("append journal", "err", err)
+		}
+	}
+	if opts.Primary != nil {
+		opts.Primary.Propagate(cmds...)
+	}
 }
@@ -1,13 +1,24 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/aof"
+	"github.com/nalgeon/redka/internal/command"
+	"github.com/nalgeon/redka/internal/repl"
 	"github.com/tidwall/redcon"
 )
 
@@ -17,7 +28,7 @@ func TestHandlers(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	mux := createHandlers(db)
+	mux := createHandlers(db, &Options{})
 	tests := []struct {
 		cmd  redcon.Command
 		want string
@@ -39,6 +50,578 @@ func TestHandlers(t *testing.T) {
 	}
 }
 
+func TestSelectTenant(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tenants := redka.NewTenants(nil)
+	defer tenants.Close()
+	if _, err := tenants.Attach("1", ":memory:"); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := createHandlers(db, &Options{Tenants: tenants})
+	conn := new(fakeConn)
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"SET name main", "OK"},
+		{"SELECT 1", "OK"},
+		{"GET name", "(nil)"},
+		{"SET name tenant", "OK"},
+		{"GET name", "tenant"},
+		{"SELECT 2", "ERR DB index is out of range (select)"},
+		{"GET name", "tenant"},
+		{"SELECT 0", "OK"},
+		{"GET name", "main"},
+	}
+	for _, test := range tests {
+		conn.parts = nil
+		args := strings.Fields(test.cmd)
+		cmd := redcon.Command{Raw: []byte(test.cmd), Args: make([][]byte, len(args))}
+		for i, arg := range args {
+			cmd.Args[i] = []byte(arg)
+		}
+		mux.ServeRESP(conn, cmd)
+		if conn.out() != test.want {
+			t.Fatalf("%s: want '%s', got '%s'", test.cmd, test.want, conn.out())
+		}
+	}
+}
+
+func TestSelectDB(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := createHandlers(db, &Options{})
+	conn := new(fakeConn)
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"SET name main", "OK"},
+		{"SELECT 1", "OK"},
+		{"GET name", "(nil)"},
+		{"SET name test", "OK"},
+		{"GET name", "test"},
+		{"SELECT 16", "ERR DB index is out of range (select)"},
+		{"SELECT acme", "ERR DB index is out of range (select)"},
+		{"GET name", "test"},
+		{"SELECT 0", "OK"},
+		{"GET name", "main"},
+	}
+	for _, test := range tests {
+		conn.parts = nil
+		args := strings.Fields(test.cmd)
+		cmd := redcon.Command{Raw: []byte(test.cmd), Args: make([][]byte, len(args))}
+		for i, arg := range args {
+			cmd.Args[i] = []byte(arg)
+		}
+		mux.ServeRESP(conn, cmd)
+		if conn.out() != test.want {
+			t.Fatalf("%s: want '%s', got '%s'", test.cmd, test.want, conn.out())
+		}
+	}
+}
+
+func TestSelectDBPropagate(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	journal, err := aof.Open(filepath.Join(t.TempDir(), "redka.aof"), aof.SyncNo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	primary := repl.NewPrimary(db)
+	defer primary.Close()
+	tenants := redka.NewTenants(nil)
+	defer tenants.Close()
+	if _, err := tenants.Attach("acme", ":memory:"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The journal and replication only cover database 0,
+	// not the other logical databases or the tenants.
+	for _, opts := range []*Options{
+		{Journal: journal},
+		{Primary: primary},
+		{Journal: journal, Tenants: tenants},
+		{Primary: primary, Tenants: tenants},
+	} {
+		mux := createHandlers(db, opts)
+		conn := new(fakeConn)
+		tests := []struct {
+			cmd  string
+			want string
+		}{
+			{"SELECT 1", "ERR SELECT is not allowed with the journal or replication (select)"},
+			{"SELECT acme", "ERR SELECT is not allowed with the journal or replication (select)"},
+			{"SELECT 0", "OK"},
+		}
+		for _, test := range tests {
+			conn.parts = nil
+			args := strings.Fields(test.cmd)
+			cmd := redcon.Command{Raw: []byte(test.cmd), Args: make([][]byte, len(args))}
+			for i, arg := range args {
+				cmd.Args[i] = []byte(arg)
+			}
+			mux.ServeRESP(conn, cmd)
+			if conn.out() != test.want {
+				t.Fatalf("%s: want '%s', got '%s'", test.cmd, test.want, conn.out())
+			}
+		}
+	}
+}
+
+func TestFCall(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.Register("incr", func(tx *redka.Tx, args ...string) (any, error) {
+		return tx.Str().Incr(args[0], 1)
+	})
+
+	mux := createHandlers(db, &Options{})
+	conn := new(fakeConn)
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"FCALL incr 1 age", "1"},
+		{"MULTI", "OK"},
+		{"FCALL incr 1 age", "QUEUED"},
+		{"EXEC", "1,2"},
+		{"FCALL nope 0", "ERR Function not found (fcall)"},
+	}
+	for _, test := range tests {
+		conn.parts = nil
+		args := strings.Fields(test.cmd)
+		cmd := redcon.Command{Raw: []byte(test.cmd), Args: make([][]byte, len(args))}
+		for i, arg := range args {
+			cmd.Args[i] = []byte(arg)
+		}
+		mux.ServeRESP(conn, cmd)
+		if conn.out() != test.want {
+			t.Fatalf("%s: want '%s', got '%s'", test.cmd, test.want, conn.out())
+		}
+	}
+}
+
+func TestIsChange(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		res  any
+		want bool
+	}{
+		{"GET name", "alice", false},
+		{"SET name alice", true, true},
+		{"SETNX name alice", false, false},
+		// A procedure may write and still return false.
+		{"FCALL ratelimit 1 alice", false, true},
+	}
+	for _, test := range tests {
+		var args [][]byte
+		for _, arg := range strings.Fields(test.cmd) {
+			args = append(args, []byte(arg))
+		}
+		pcmd, err := command.Parse(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isChange(pcmd, test.res); got != test.want {
+			t.Errorf("%s: want %v, got %v", test.cmd, test.want, got)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := createHandlers(db, &Options{})
+	conn, other := new(fakeConn), new(fakeConn)
+	tests := []struct {
+		conn *fakeConn
+		cmd  string
+		want string
+	}{
+		// Unchanged watched key.
+		{conn, "WATCH name", "OK"},
+		{conn, "MULTI", "OK"},
+		{conn, "SET name alice", "QUEUED"},
+		{conn, "EXEC", "1,OK"},
+		// Changed watched key.
+		{conn, "WATCH name", "OK"},
+		{other, "SET name bob", "OK"},
+		{conn, "MULTI", "OK"},
+		{conn, "SET name alice", "QUEUED"},
+		{conn, "EXEC", "-1"},
+		{conn, "GET name", "bob"},
+		// EXEC unwatches the keys.
+		{conn, "MULTI", "OK"},
+		{conn, "SET name alice", "QUEUED"},
+		{conn, "EXEC", "1,OK"},
+		// Changed key matching the watched pattern.
+		{conn, "PWATCH cart:1:*", "OK"},
+		{other, "SET cart:2:apple 1", "OK"},
+		{conn, "MULTI", "OK"},
+		{conn, "SET order:1 10", "QUEUED"},
+		{conn, "EXEC", "1,OK"},
+		{conn, "PWATCH cart:1:*", "OK"},
+		{other, "SET cart:1:apple 1", "OK"},
+		{conn, "MULTI", "OK"},
+		{conn, "SET order:1 20", "QUEUED"},
+		{conn, "EXEC", "-1"},
+		{conn, "GET order:1", "10"},
+		// UNWATCH.
+		{conn, "PWATCH cart:1:*", "OK"},
+		{other, "DEL cart:1:apple", "1"},
+		{conn, "UNWATCH", "OK"},
+		{conn, "MULTI", "OK"},
+		{conn, "SET order:1 30", "QUEUED"},
+		{conn, "EXEC", "1,OK"},
+		// Errors.
+		{conn, "WATCH", "ERR wrong number of arguments (watch)"},
+		{conn, "MULTI", "OK"},
+		{conn, "WATCH name", "ERR WATCH inside MULTI is not allowed"},
+		{conn, "DISCARD", "OK"},
+	}
+	for _, test := range tests {
+		test.conn.parts = nil
+		args := strings.Fields(test.cmd)
+		cmd := redcon.Command{Raw: []byte(test.cmd), Args: make([][]byte, len(args))}
+		for i, arg := range args {
+			cmd.Args[i] = []byte(arg)
+		}
+		mux.ServeRESP(test.conn, cmd)
+		if test.conn.out() != test.want {
+			t.Fatalf("%s: want '%s', got '%s'", test.cmd, test.want, test.conn.out())
+		}
+	}
+}
+
+func TestPinAfterWrite(t *testing.T) {
+	dir := t.TempDir()
+	// The replica never receives the changes from the primary.
+	replicaPath := filepath.Join(dir, "replica.db")
+	replica, err := redka.Open(replicaPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = replica.Str().Set("name", "replica")
+	_ = replica.Close()
+
+	db, err := redka.Open(filepath.Join(dir, "primary.db"), &redka.Options{
+		ReadReplicas: []string{"file:" + replicaPath + "?mode=ro"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := createHandlers(db, &Options{PinAfterWrite: time.Minute})
+	conn, other := new(fakeConn), new(fakeConn)
+	tests := []struct {
+		conn *fakeConn
+		cmd  string
+		want string
+	}{
+		{conn, "GET name", "replica"},
+		{conn, "SET name primary", "OK"},
+		{conn, "GET name", "primary"},
+		{other, "GET name", "replica"},
+	}
+	for _, test := range tests {
+		test.conn.parts = nil
+		args := strings.Fields(test.cmd)
+		cmd := redcon.Command{Raw: []byte(test.cmd), Args: make([][]byte, len(args))}
+		for i, arg := range args {
+			cmd.Args[i] = []byte(arg)
+		}
+		mux.ServeRESP(test.conn, cmd)
+		if test.conn.out() != test.want {
+			t.Fatalf("%s: want '%s', got '%s'", test.cmd, test.want, test.conn.out())
+		}
+	}
+}
+
+func TestShards(t *testing.T) {
+	dir := t.TempDir()
+	shards, err := redka.OpenShards([]string{
+		filepath.Join(dir, "shard-0.db"),
+		filepath.Join(dir, "shard-1.db"),
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shards.Close()
+
+	mux := createHandlers(shards.DB(0), &Options{Shards: shards})
+	conn := new(fakeConn)
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"SET foo 1", "OK"},
+		{"SET bar 2", "OK"},
+		{"GET foo", "1"},
+		{"GET bar", "2"},
+		{"MGET foo bar", "CROSSSLOT Keys in request don't hash to the same slot (mget)"},
+		{"MSET {u}:a 1 {u}:b 2", "OK"},
+		{"MGET {u}:a {u}:b", "2,1,2"},
+		{"DEL foo bar", "CROSSSLOT Keys in request don't hash to the same slot (del)"},
+		{"EXISTS {u}:a {u}:b", "2"},
+		{"MULTI", "ERR MULTI is not supported in sharded mode"},
+	}
+	for _, test := range tests {
+		conn.parts = nil
+		args := strings.Fields(test.cmd)
+		cmd := redcon.Command{Raw: []byte(test.cmd), Args: make([][]byte, len(args))}
+		for i, arg := range args {
+			cmd.Args[i] = []byte(arg)
+		}
+		mux.ServeRESP(conn, cmd)
+		if conn.out() != test.want {
+			t.Fatalf("%s: want '%s', got '%s'", test.cmd, test.want, conn.out())
+		}
+	}
+}
+
+func TestBinaryKeys(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := createHandlers(db, &Options{})
+	conn := new(fakeConn)
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{"SET a\x00b v\x00\xff", "OK"},
+		{"SET \xff\xfe 1", "OK"},
+		{"SET a 2", "OK"},
+		{"GET a\x00b", "v\x00\xff"},
+		{"GET a", "2"},
+		{"HSET h f\x00 1", "1"},
+		{"HGET h f\x00", "1"},
+		{"HGET h f", "(nil)"},
+		{"KEYS *b", "1,a\x00b"},
+		{"KEYS \xff*", "1,\xff\xfe"},
+		{"EXISTS a\x00", "0"},
+	}
+	for _, test := range tests {
+		conn.parts = nil
+		args := strings.Fields(test.cmd)
+		cmd := redcon.Command{Raw: []byte(test.cmd), Args: make([][]byte, len(args))}
+		for i, arg := range args {
+			cmd.Args[i] = []byte(arg)
+		}
+		mux.ServeRESP(conn, cmd)
+		if conn.out() != test.want {
+			t.Fatalf("%q: want %q, got %q", test.cmd, test.want, conn.out())
+		}
+	}
+}
+
+func TestInfoKeyspace(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tenants := redka.NewTenants(nil)
+	defer tenants.Close()
+	tenant, err := tenants.Attach("1", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_ = db.Str().Set("name", "alice")
+	_ = db.Str().SetExpires("age", 25, time.Minute)
+	_ = tenant.Str().Set("city", "paris")
+
+	mux := createHandlers(db, &Options{Tenants: tenants})
+	conn := new(fakeConn)
+	cmd := redcon.Command{Args: [][]byte{[]byte("INFO"), []byte("keyspace")}}
+	mux.ServeRESP(conn, cmd)
+	out := conn.out()
+	if !strings.HasPrefix(out, "# Keyspace\r\ndb0:keys=2,expires=1,avg_ttl=") {
+		t.Fatalf("unexpected db0 line: %q", out)
+	}
+	if !strings.HasSuffix(out, "\r\ndb1:keys=1,expires=0,avg_ttl=0\r\n") {
+		t.Fatalf("unexpected db1 line: %q", out)
+	}
+
+	// The stats are cached.
+	_ = db.Str().Set("city", "paris")
+	conn.parts = nil
+	mux.ServeRESP(conn, cmd)
+	if !strings.Contains(conn.out(), "db0:keys=2,") {
+		t.Fatalf("want cached stats, got %q", conn.out())
+	}
+}
+
+func TestHello(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := createHandlers(db, &Options{})
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"HELLO"}, "14,server,redka,version,7.4.0,proto,2,id,"},
+		{[]string{"HELLO", "2", "AUTH", "default", "secret", "SETNAME", "app"}, "14,server,redka"},
+		{[]string{"HELLO", "3"}, "NOPROTO unsupported protocol version"},
+		{[]string{"HELLO", "two"}, "ERR Protocol version is not an integer or out of range"},
+		{[]string{"HELLO", "2", "AUTH", "default"}, "ERR syntax error"},
+	}
+	conn := new(fakeConn)
+	for _, test := range tests {
+		cmd := redcon.Command{}
+		for _, arg := range test.args {
+			cmd.Args = append(cmd.Args, []byte(arg))
+		}
+		conn.parts = nil
+		mux.ServeRESP(conn, cmd)
+		if !strings.HasPrefix(conn.out(), test.want) {
+			t.Errorf("%v: want %q, got %q", test.args, test.want, conn.out())
+		}
+	}
+
+	// The client ID stays the same for the connection.
+	conn.parts = nil
+	mux.ServeRESP(conn, redcon.Command{Args: [][]byte{[]byte("HELLO")}})
+	id := conn.parts[8]
+	conn.parts = nil
+	mux.ServeRESP(conn, redcon.Command{Args: [][]byte{[]byte("HELLO")}})
+	if conn.parts[8] != id {
+		t.Errorf("want client id %s, got %s", id, conn.parts[8])
+	}
+	if !strings.HasSuffix(conn.out(), ",mode,standalone,role,master,modules,0") {
+		t.Errorf("unexpected reply: %q", conn.out())
+	}
+}
+
+func TestExpiryHandler(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_ = db.Str().Set("name", "alice")
+	_ = db.Str().SetExpires("session", "abc", 10*time.Minute)
+	_ = db.Str().SetExpires("token", "xyz", 10*time.Second)
+
+	w := httptest.NewRecorder()
+	expiryHandler(db, applyOptions(nil)).ServeHTTP(w, httptest.NewRequest("GET", "/expiry", nil))
+	want := `{"day":2,"expired":0,"hour":2,"minute":1,"total":2}` + "\n"
+	if w.Code != http.StatusOK || w.Body.String() != want {
+		t.Fatalf("want 200 %s, got %d %s", want, w.Code, w.Body.String())
+	}
+}
+
+func TestTracing(t *testing.T) {
+	tracer := &fakeTracer{}
+	db, err := redka.Open(":memory:", &redka.Options{
+		TracerProvider: fakeProvider{tracer},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mux := createHandlers(db, &Options{})
+	conn := new(fakeConn)
+	cmd := redcon.Command{
+		Raw:  []byte("SET name alice"),
+		Args: [][]byte{[]byte("set"), []byte("name"), []byte("alice")},
+	}
+	mux.ServeRESP(conn, cmd)
+	if conn.out() != "OK" {
+		t.Fatalf("want 'OK', got '%s'", conn.out())
+	}
+	want := []string{"SET", "redka.Str.SetWith<SET"}
+	if strings.Join(tracer.spans, ",") != strings.Join(want, ",") {
+		t.Fatalf("want spans %v, got %v", want, tracer.spans)
+	}
+}
+
+func TestLogger(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	mux := createHandlers(db, &Options{Logger: logger})
+	conn := new(fakeConn)
+	for _, args := range [][]string{{"set", "name", "alice"}, {"incr", "name"}} {
+		cmd := redcon.Command{Args: make([][]byte, len(args))}
+		for i, arg := range args {
+			cmd.Args[i] = []byte(arg)
+		}
+		mux.ServeRESP(conn, cmd)
+	}
+	if !strings.Contains(buf.String(), `level=WARN msg="run single command"`) {
+		t.Fatalf("want warning, got '%s'", buf.String())
+	}
+}
+
+// fakeProvider provides the fake tracer.
+type fakeProvider struct {
+	tracer *fakeTracer
+}
+
+func (p fakeProvider) Tracer(name string) redka.Tracer {
+	return p.tracer
+}
+
+// fakeTracer records the span names as "name<parent".
+type fakeTracer struct {
+	spans []string
+}
+
+type spanKey struct{}
+
+func (t *fakeTracer) Start(ctx context.Context, name string) (context.Context, redka.Span) {
+	span := name
+	if parent, ok := ctx.Value(spanKey{}).(string); ok {
+		span += "<" + parent
+	}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, name), fakeSpan{}
+}
+
+type fakeSpan struct{}
+
+func (fakeSpan) SetAttribute(key string, value any) {}
+func (fakeSpan) RecordError(err error)              {}
+func (fakeSpan) End()                               {}
+
 type fakeConn struct {
 	parts []string
 	ctx   any
@@ -108,3 +691,43 @@ func (c *fakeConn) append(str string) {
 func (c *fakeConn) out() string {
 	return strings.Join(c.parts, ",")
 }
+
+func TestReload(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	reloadCmd := redcon.Command{Args: [][]byte{[]byte("RELOAD")}}
+	t.Run("disabled", func(t *testing.T) {
+		mux := createHandlers(db, &Options{})
+		conn := new(fakeConn)
+		mux.ServeRESP(conn, reloadCmd)
+		if !strings.HasPrefix(conn.out(), "ERR unknown command") {
+			t.Fatalf("want unknown command, got %q", conn.out())
+		}
+	})
+	t.Run("ok", func(t *testing.T) {
+		var calls int
+		mux := createHandlers(db, &Options{Reload: func() error {
+			calls++
+			return nil
+		}})
+		conn := new(fakeConn)
+		mux.ServeRESP(conn, reloadCmd)
+		if conn.out() != "OK" || calls != 1 {
+			t.Fatalf("want OK and 1 call, got %q and %d calls", conn.out(), calls)
+		}
+	})
+	t.Run("error", func(t *testing.T) {
+		mux := createHandlers(db, &Options{Reload: func() error {
+			return errors.New("invalid config")
+		}})
+		conn := new(fakeConn)
+		mux.ServeRESP(conn, reloadCmd)
+		if conn.out() != "ERR invalid config" {
+			t.Fatalf("want error, got %q", conn.out())
+		}
+	})
+}
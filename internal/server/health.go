@@ -0,0 +1,219 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/repl"
+	"github.com/nalgeon/redka/internal/rkey"
+)
+
+// Default health check settings.
+const (
+	defaultHealthTimeout = time.Second
+	// The primary pings the replicas every 10 seconds,
+	// so a healthy link is never idle for much longer.
+	defaultHealthMaxLag = 30 * time.Second
+)
+
+// Health check statuses.
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// HealthOptions are the limits checked by the readiness probe
+// (see Options.MetricsAddr). A zero value uses the default
+// or disables the check, as noted for each field.
+type HealthOptions struct {
+	// Timeout is the maximum duration of the writer self-check.
+	// If zero, uses 1s.
+	Timeout time.Duration
+	// MaxWALSize is the maximum size of the WAL file in bytes.
+	// If zero, the WAL size is not checked.
+	MaxWALSize int64
+	// MaxReplicationLag is the maximum time since the replica
+	// has received data from the primary. If zero, uses 30s.
+	// Only checked on replicas.
+	MaxReplicationLag time.Duration
+	// MaxExpired is the maximum number of expired keys
+	// that are not deleted yet. If zero, the expiration
+	// backlog is not checked.
+	MaxExpired int
+}
+
+// healthCheck is the result of a single check.
+type healthCheck struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	Value  any    `json:"value"`
+	Limit  any    `json:"limit,omitempty"`
+}
+
+// healthReport is the result of a probe.
+type healthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks"`
+}
+
+// health serves the Kubernetes-style probes:
+//
+//	GET /livez  -> writer self-check only
+//	GET /readyz -> writer, WAL size, replication lag and expiration backlog
+//
+// Both return 200 if all checks pass and 503 otherwise,
+// with the results as JSON:
+//
+//	{"status":"ok","checks":{"writer":{"status":"ok","value":850},...}}
+//
+// The writer self-check is like a PING that goes all the way to the
+// database: it starts and commits an empty write transaction, so it
+// fails if the writer connection is stuck (for example, by a long
+// transaction). Its value is the latency in microseconds.
+type health struct {
+	db   *redka.DB
+	opts *Options
+}
+
+// livez reports whether the server is alive.
+func (h *health) livez(w http.ResponseWriter, r *http.Request) {
+	report := healthReport{Checks: map[string]healthCheck{
+		"writer": h.checkWriter(r.Context()),
+	}}
+	h.write(w, report)
+}
+
+// readyz reports whether the server is ready to serve requests.
+func (h *health) readyz(w http.ResponseWriter, r *http.Request) {
+	report := healthReport{Checks: map[string]healthCheck{
+		"writer": h.checkWriter(r.Context()),
+		"wal":    h.checkWAL(),
+		"expiry": h.checkExpiry(r.Context()),
+	}}
+	if h.opts.Replica != nil {
+		report.Checks["replication"] = h.checkReplication(h.opts.Replica.Info())
+	}
+	h.write(w, report)
+}
+
+// write writes the report with the overall status.
+func (h *health) write(w http.ResponseWriter, report healthReport) {
+	status := http.StatusOK
+	report.Status = healthOK
+	for name, check := range report.Checks {
+		if check.Status != healthOK {
+			status = http.StatusServiceUnavailable
+			report.Status = healthFail
+			h.opts.Logger.Warn("health check", "check", name, "error", check.Error)
+		}
+	}
+	writeJSON(w, status, report)
+}
+
+// checkWriter runs an empty write transaction on every database
+// and reports the maximum latency in microseconds.
+func (h *health) checkWriter(ctx context.Context) healthCheck {
+	timeout := h.opts.Health.Timeout
+	if timeout == 0 {
+		timeout = defaultHealthTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var latency time.Duration
+	for _, db := range h.dbs() {
+		start := time.Now()
+		err := db.UpdateContext(ctx, func(tx *redka.Tx) error { return nil })
+		if errors.Is(err, redka.ErrReadOnly) {
+			// A read-only database has no writer,
+			// so check that it can be read instead.
+			err = db.ViewContext(ctx, func(tx *redka.Tx) error { return nil })
+		}
+		if err != nil {
+			return healthCheck{Status: healthFail, Error: err.Error(), Value: time.Since(start).Microseconds()}
+		}
+		latency = max(latency, time.Since(start))
+	}
+	return healthCheck{Status: healthOK, Value: latency.Microseconds()}
+}
+
+// checkWAL reports the total size of the WAL files in bytes.
+func (h *health) checkWAL() healthCheck {
+	var size int64
+	for _, db := range h.dbs() {
+		stats, err := db.WALStats()
+		if err != nil {
+			return healthCheck{Status: healthFail, Error: err.Error()}
+		}
+		size += stats.Size
+	}
+	check := healthCheck{Status: healthOK, Value: size}
+	if limit := h.opts.Health.MaxWALSize; limit > 0 {
+		check.Limit = limit
+		if size > limit {
+			check.Status = healthFail
+			check.Error = fmt.Sprintf("WAL size %d exceeds %d", size, limit)
+		}
+	}
+	return check
+}
+
+// checkReplication reports the time since the replica has received
+// data from the primary in milliseconds.
+func (h *health) checkReplication(info repl.ReplicaInfo) healthCheck {
+	limit := h.opts.Health.MaxReplicationLag
+	if limit == 0 {
+		limit = defaultHealthMaxLag
+	}
+	check := healthCheck{Status: healthOK, Value: info.LastIO.Milliseconds(), Limit: limit.Milliseconds()}
+	switch {
+	case info.State != repl.StateConnected:
+		check.Status = healthFail
+		check.Error = "replication link is " + info.State
+	case info.LastIO > limit:
+		check.Status = healthFail
+		check.Error = fmt.Sprintf("replication lag %s exceeds %s", info.LastIO, limit)
+	}
+	return check
+}
+
+// checkExpiry reports the number of expired keys
+// that are not deleted yet.
+func (h *health) checkExpiry(ctx context.Context) healthCheck {
+	var info rkey.ExpiryInfo
+	var err error
+	if h.opts.Shards != nil {
+		info, err = h.opts.Shards.Key().Expiry()
+	} else {
+		info, err = h.db.WithContext(ctx).Key().Expiry()
+	}
+	if err != nil {
+		return healthCheck{Status: healthFail, Error: err.Error()}
+	}
+	check := healthCheck{Status: healthOK, Value: info.Expired}
+	if limit := h.opts.Health.MaxExpired; limit > 0 {
+		check.Limit = limit
+		if info.Expired > limit {
+			check.Status = healthFail
+			check.Error = fmt.Sprintf("expired keys %d exceed %d", info.Expired, limit)
+		}
+	}
+	return check
+}
+
+// dbs returns the databases to check: the shards
+// in the sharded mode, or the main database.
+func (h *health) dbs() []*redka.DB {
+	if h.opts.Shards == nil {
+		return []*redka.DB{h.db}
+	}
+	dbs := make([]*redka.DB, h.opts.Shards.Len())
+	for i := range dbs {
+		dbs[i] = h.opts.Shards.DB(i)
+	}
+	return dbs
+}
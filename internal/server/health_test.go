@@ -0,0 +1,137 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/repl"
+)
+
+func TestHealth(t *testing.T) {
+	probe := func(h *health, path string) (int, string) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", path, nil)
+		if path == "/livez" {
+			h.livez(w, r)
+		} else {
+			h.readyz(w, r)
+		}
+		return w.Code, w.Body.String()
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		db, err := redka.Open(":memory:", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		h := &health{db: db, opts: applyOptions(nil)}
+
+		code, body := probe(h, "/livez")
+		if code != http.StatusOK || !strings.Contains(body, `"writer":{"status":"ok"`) {
+			t.Fatalf("livez: want 200 ok, got %d %s", code, body)
+		}
+		code, body = probe(h, "/readyz")
+		if code != http.StatusOK || !strings.HasPrefix(body, `{"status":"ok"`) {
+			t.Fatalf("readyz: want 200 ok, got %d %s", code, body)
+		}
+		for _, name := range []string{`"writer"`, `"wal"`, `"expiry"`} {
+			if !strings.Contains(body, name) {
+				t.Fatalf("readyz: want %s check, got %s", name, body)
+			}
+		}
+		if strings.Contains(body, `"replication"`) {
+			t.Fatalf("readyz: want no replication check, got %s", body)
+		}
+	})
+	t.Run("writer blocked", func(t *testing.T) {
+		db, err := redka.Open(":memory:", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		opts := applyOptions(&Options{Health: HealthOptions{Timeout: 50 * time.Millisecond}})
+		h := &health{db: db, opts: opts}
+
+		started := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			_ = db.Update(func(tx *redka.Tx) error {
+				close(started)
+				<-done
+				return nil
+			})
+		}()
+		<-started
+		code, body := probe(h, "/livez")
+		close(done)
+		if code != http.StatusServiceUnavailable || !strings.HasPrefix(body, `{"status":"fail"`) {
+			t.Fatalf("want 503 fail, got %d %s", code, body)
+		}
+	})
+	t.Run("expiration backlog", func(t *testing.T) {
+		db, err := redka.Open(":memory:", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		opts := applyOptions(&Options{Health: HealthOptions{MaxExpired: 1}})
+		h := &health{db: db, opts: opts}
+
+		_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+		_ = db.Str().SetExpires("city", "paris", time.Millisecond)
+		time.Sleep(5 * time.Millisecond)
+
+		code, body := probe(h, "/readyz")
+		want := `"expiry":{"status":"fail","error":"expired keys 2 exceed 1","value":2,"limit":1}`
+		if code != http.StatusServiceUnavailable || !strings.Contains(body, want) {
+			t.Fatalf("want 503 %s, got %d %s", want, code, body)
+		}
+		// Liveness does not depend on the backlog.
+		code, _ = probe(h, "/livez")
+		if code != http.StatusOK {
+			t.Fatalf("livez: want 200, got %d", code)
+		}
+	})
+	t.Run("wal size", func(t *testing.T) {
+		db, err := redka.Open(t.TempDir()+"/data.db", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		opts := applyOptions(&Options{Health: HealthOptions{MaxWALSize: 1}})
+		h := &health{db: db, opts: opts}
+
+		_ = db.Str().Set("name", "alice")
+		code, body := probe(h, "/readyz")
+		if code != http.StatusServiceUnavailable || !strings.Contains(body, `"wal":{"status":"fail"`) {
+			t.Fatalf("want 503 wal fail, got %d %s", code, body)
+		}
+	})
+}
+
+func TestHealthReplication(t *testing.T) {
+	h := &health{opts: applyOptions(&Options{Health: HealthOptions{MaxReplicationLag: time.Second}})}
+	tests := []struct {
+		info repl.ReplicaInfo
+		want string
+	}{
+		{repl.ReplicaInfo{State: repl.StateConnected, LastIO: 100 * time.Millisecond}, ""},
+		{repl.ReplicaInfo{State: repl.StateConnected, LastIO: 2 * time.Second}, "replication lag 2s exceeds 1s"},
+		{repl.ReplicaInfo{State: repl.StateSync}, "replication link is sync"},
+		{repl.ReplicaInfo{State: repl.StateConnecting}, "replication link is connecting"},
+	}
+	for _, test := range tests {
+		check := h.checkReplication(test.info)
+		if check.Error != test.want {
+			t.Errorf("%+v: want error %q, got %q", test.info, test.want, check.Error)
+		}
+		if (check.Status == healthOK) != (test.want == "") {
+			t.Errorf("%+v: unexpected status %q", test.info, check.Status)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/tidwall/redcon"
+)
+
+// helloVersion is the Redis version reported by HELLO.
+// The clients use it to decide which commands to send,
+// so it is the version whose commands Redka follows.
+const helloVersion = "7.4.0"
+
+// lastClientID is the ID of the last connection
+// that asked for it with HELLO.
+var lastClientID atomic.Int64
+
+// hello handles the HELLO command and delegates
+// the rest to the next handler. Only supports RESP2,
+// so the clients that ask for RESP3 get a NOPROTO error
+// and fall back to RESP2. Redka has no authentication,
+// so the AUTH option accepts any credentials.
+// HELLO [protover [AUTH username password] [SETNAME clientname]]
+// https://redis.io/commands/hello
+func hello(opts *Options, next redcon.HandlerFunc) redcon.HandlerFunc {
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		if normName(cmd) != "hello" {
+			next(conn, cmd)
+			return
+		}
+		args := cmd.Args[1:]
+		if len(args) > 0 {
+			proto, err := strconv.Atoi(string(args[0]))
+			if err != nil {
+				conn.WriteError("ERR Protocol version is not an integer or out of range")
+				return
+			}
+			if proto != 2 {
+				conn.WriteError("NOPROTO unsupported protocol version")
+				return
+			}
+			args = args[1:]
+		}
+		for len(args) > 0 {
+			switch opt := strings.ToLower(string(args[0])); {
+			case opt == "auth" && len(args) >= 3:
+				args = args[3:]
+			case opt == "setname" && len(args) >= 2:
+				args = args[2:]
+			default:
+				conn.WriteError("ERR syntax error")
+				return
+			}
+		}
+
+		state := getState(conn)
+		if state.id == 0 {
+			state.id = lastClientID.Add(1)
+		}
+		role := "master"
+		if opts.Replica != nil {
+			role = "replica"
+		}
+		conn.WriteArray(14)
+		conn.WriteBulkString("server")
+		conn.WriteBulkString("redka")
+		conn.WriteBulkString("version")
+		conn.WriteBulkString(helloVersion)
+		conn.WriteBulkString("proto")
+		conn.WriteInt(2)
+		conn.WriteBulkString("id")
+		conn.WriteInt64(state.id)
+		conn.WriteBulkString("mode")
+		conn.WriteBulkString("standalone")
+		conn.WriteBulkString("role")
+		conn.WriteBulkString(role)
+		conn.WriteBulkString("modules")
+		conn.WriteArray(0)
+	}
+}
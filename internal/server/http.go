@@ -0,0 +1,277 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/command"
+)
+
+// maxHTTPBody is the maximum size of the PUT request body.
+const maxHTTPBody = 512 << 20
+
+// httpAPI runs the commands sent as HTTP requests
+// and returns the replies as JSON (like webdis does):
+//
+//	GET /GET/name        -> {"GET":"alice"}
+//	PUT /SET/name        -> {"SET":"OK"} (body is the value)
+//	GET /HGETALL/person  -> {"HGETALL":["age","25","name","alice"]}
+//	GET /GET/nope        -> {"GET":null}
+//
+// The path segments are the command name and arguments (URL-encoded).
+// The PUT request body is the last argument. Failed commands return
+// 400 Bad Request with {"error":"..."}.
+//
+// The requests are independent, so the connection-level commands
+// (MULTI, EXEC, DISCARD and SELECT) are not supported.
+type httpAPI struct {
+	db     *redka.DB
+	opts   *Options
+	tokens atomic.Pointer[[][]byte]
+}
+
+// newHTTPAPI creates the HTTP API handler.
+func newHTTPAPI(db *redka.DB, opts *Options) *httpAPI {
+	api := &httpAPI{db: db, opts: opts}
+	api.setTokens(opts.HTTPTokens)
+	return api
+}
+
+// setTokens replaces the accepted tokens.
+func (api *httpAPI) setTokens(tokens []string) {
+	list := make([][]byte, len(tokens))
+	for i, token := range tokens {
+		list[i] = []byte(token)
+	}
+	api.tokens.Store(&list)
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (api *httpAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := api.opts.HTTPCORSOrigin; origin != "" {
+		h := w.Header()
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Set("Access-Control-Allow-Methods", "GET, PUT, OPTIONS")
+		h.Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+		if origin != "*" {
+			h.Add("Vary", "Origin")
+		}
+	}
+	switch r.Method {
+	case http.MethodOptions:
+		// CORS preflight.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case http.MethodGet, http.MethodPut:
+	default:
+		w.Header().Set("Allow", "GET, PUT, OPTIONS")
+		writeJSONError(w, http.StatusMethodNotAllowed, "ERR method not allowed")
+		return
+	}
+	if !api.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSONError(w, http.StatusUnauthorized, "NOAUTH Authentication required.")
+		return
+	}
+
+	args, err := httpArgs(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	api.run(w, r, args)
+}
+
+// authorized reports whether the request has one of the accepted
+// bearer tokens. All requests are authorized if there are no tokens.
+func (api *httpAPI) authorized(r *http.Request) bool {
+	tokens := *api.tokens.Load()
+	if len(tokens) == 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	for _, want := range tokens {
+		if subtle.ConstantTimeCompare([]byte(token), want) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// run executes the command and writes the reply.
+func (api *httpAPI) run(w http.ResponseWriter, r *http.Request, args [][]byte) {
+	name := strings.ToLower(string(args[0]))
+	switch name {
+	case "multi", "exec", "discard", "watch", "pwatch", "unwatch", "select":
+		writeJSONError(w, http.StatusBadRequest, "ERR command is not supported over HTTP ("+name+")")
+		return
+	}
+	if api.opts.Replica != nil && command.IsWrite(name) {
+		writeJSONError(w, http.StatusBadRequest, command.ErrReadOnly.Error())
+		return
+	}
+	pcmd, err := command.Parse(args)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, pcmd.Error(err))
+		return
+	}
+
+	if api.opts.Primary != nil {
+		api.opts.Primary.BeginWrite()
+		defer api.opts.Primary.EndWrite()
+	}
+	reply := new(jsonReply)
+	red := command.RedkaDB(api.db.WithContext(r.Context()))
+	if api.opts.Shards != nil {
+		red = command.RedkaShards(api.opts.Shards)
+	}
+	res, err := pcmd.Run(reply, red)
+	if err != nil {
+		api.opts.Logger.Warn("run http command", "client", r.RemoteAddr,
+			"name", pcmd.Name(), "err", err)
+	} else if isChange(pcmd, res) {
+		propagate(api.opts, pcmd)
+	}
+
+	val, errMsg := reply.result()
+	if errMsg != "" {
+		writeJSONError(w, http.StatusBadRequest, errMsg)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{strings.ToUpper(name): val})
+}
+
+// httpArgs returns the command arguments from the request path
+// (and the body for PUT requests).
+func httpArgs(r *http.Request) ([][]byte, error) {
+	path := strings.Trim(r.URL.EscapedPath(), "/")
+	if path == "" {
+		return nil, command.ErrInvalidArgNum
+	}
+	var args [][]byte
+	for _, seg := range strings.Split(path, "/") {
+		arg, err := url.PathUnescape(seg)
+		if err != nil {
+			return nil, errors.New("ERR invalid path: " + err.Error())
+		}
+		args = append(args, []byte(arg))
+	}
+	if r.Method == http.MethodPut {
+		body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxHTTPBody))
+		if err != nil {
+			return nil, errors.New("ERR invalid body: " + err.Error())
+		}
+		args = append(args, body)
+	}
+	return args, nil
+}
+
+// writeJSON writes the value as a JSON response.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes the error message as a JSON response.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// jsonReply collects the command reply as a JSON-compatible value:
+// nil, string, int64 or []any. Implements the command.Writer interface.
+type jsonReply struct {
+	val  any
+	err  string
+	open []*jsonArray // arrays waiting for the items
+}
+
+// jsonArray is an array reply with the expected number of items.
+type jsonArray struct {
+	items []any
+	size  int
+}
+
+func (r *jsonReply) WriteError(msg string) {
+	r.err = msg
+}
+func (r *jsonReply) WriteString(str string) {
+	r.put(str)
+}
+func (r *jsonReply) WriteBulk(bulk []byte) {
+	r.put(string(bulk))
+}
+func (r *jsonReply) WriteBulkString(bulk string) {
+	r.put(bulk)
+}
+func (r *jsonReply) WriteInt(num int) {
+	r.put(int64(num))
+}
+func (r *jsonReply) WriteInt64(num int64) {
+	r.put(num)
+}
+func (r *jsonReply) WriteUint64(num uint64) {
+	r.put(num)
+}
+func (r *jsonReply) WriteArray(count int) {
+	if count <= 0 {
+		r.put([]any{})
+		return
+	}
+	r.open = append(r.open, &jsonArray{items: make([]any, 0, count), size: count})
+}
+func (r *jsonReply) WriteNull() {
+	r.put(nil)
+}
+func (r *jsonReply) WriteRaw(data []byte) {
+	r.put(string(data))
+}
+func (r *jsonReply) WriteAny(v any) {
+	switch v := v.(type) {
+	case []byte:
+		r.put(string(v))
+	case error:
+		r.WriteError(v.Error())
+	default:
+		r.put(v)
+	}
+}
+
+// put adds the value to the innermost open array,
+// or sets it as the reply if there are none.
+func (r *jsonReply) put(v any) {
+	for len(r.open) > 0 {
+		top := r.open[len(r.open)-1]
+		top.items = append(top.items, v)
+		if len(top.items) < top.size {
+			return
+		}
+		r.open = r.open[:len(r.open)-1]
+		v = top.items
+	}
+	r.val = v
+}
+
+// result returns the reply value, or the error message
+// if the command failed.
+func (r *jsonReply) result() (any, string) {
+	if r.err != "" {
+		return nil, r.err
+	}
+	for len(r.open) > 0 {
+		top := r.open[len(r.open)-1]
+		r.open = r.open[:len(r.open)-1]
+		r.put(top.items)
+	}
+	return r.val, ""
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+)
+
+func TestHTTPAPI(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	api := newHTTPAPI(db, applyOptions(nil))
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{"PUT", "/SET/name", "alice", 200, `{"SET":"OK"}`},
+		{"GET", "/GET/name", "", 200, `{"GET":"alice"}`},
+		{"GET", "/get/nope", "", 200, `{"GET":null}`},
+		{"GET", "/SET/user%2F1/hello%20world", "", 200, `{"SET":"OK"}`},
+		{"GET", "/GET/user%2F1", "", 200, `{"GET":"hello world"}`},
+		{"GET", "/HSET/person/name/alice/age/25", "", 200, `{"HSET":2}`},
+		{"GET", "/HGETALL/person", "", 200, `{"HGETALL":["age","25","name","alice"]}`},
+		{"GET", "/INCR/name", "", 400, `{"error":"invalid value type (incr)"}`},
+		{"GET", "/NOPE", "", 400, `{"error":"ERR unknown command (nope)"}`},
+		{"GET", "/", "", 400, `{"error":"ERR wrong number of arguments"}`},
+		{"GET", "/MULTI", "", 400, `{"error":"ERR command is not supported over HTTP (multi)"}`},
+		{"POST", "/GET/name", "", 405, `{"error":"ERR method not allowed"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+			status, body := serve(api, req)
+			if status != test.status {
+				t.Errorf("status: want %d, got %d", test.status, status)
+			}
+			if body != test.want {
+				t.Errorf("body: want %s, got %s", test.want, body)
+			}
+		})
+	}
+}
+
+func TestHTTPAuth(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	api := newHTTPAPI(db, applyOptions(&Options{HTTPTokens: []string{"one", "two"}}))
+	tests := []struct {
+		auth   string
+		status int
+	}{
+		{"", 401},
+		{"Bearer three", 401},
+		{"two", 401},
+		{"Bearer one", 200},
+		{"Bearer two", 200},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/ECHO/hello", nil)
+		if test.auth != "" {
+			req.Header.Set("Authorization", test.auth)
+		}
+		status, body := serve(api, req)
+		if status != test.status {
+			t.Errorf("%q: want %d, got %d (%s)", test.auth, test.status, status, body)
+		}
+	}
+}
+
+func TestHTTPCORS(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	t.Run("preflight", func(t *testing.T) {
+		api := newHTTPAPI(db, applyOptions(&Options{
+			HTTPTokens: []string{"secret"}, HTTPCORSOrigin: "*",
+		}))
+		req := httptest.NewRequest("OPTIONS", "/GET/name", nil)
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent {
+			t.Errorf("status: want 204, got %d", rec.Code)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("allow origin: want *, got %q", got)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "Authorization") {
+			t.Errorf("allow headers: want Authorization, got %q", got)
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		api := newHTTPAPI(db, applyOptions(nil))
+		req := httptest.NewRequest("GET", "/ECHO/hello", nil)
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("allow origin: want empty, got %q", got)
+		}
+	})
+}
+
+// serve runs the request and returns the response
+// status and body (without the trailing newline).
+func serve(h http.Handler, req *http.Request) (int, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	body, _ := io.ReadAll(rec.Body)
+	return rec.Code, strings.TrimSpace(string(body))
+}
+
+func TestSetHTTPTokens(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	srv := New(":0", db, &Options{HTTPAddr: ":0", HTTPTokens: []string{"one"}})
+	api := srv.api.Handler
+	auth := func(token string) int {
+		req := httptest.NewRequest("GET", "/ECHO/hello", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		status, _ := serve(api, req)
+		return status
+	}
+	if auth("one") != 200 || auth("two") != 401 {
+		t.Fatal("want only the initial token accepted")
+	}
+	srv.SetHTTPTokens([]string{"two"})
+	if auth("one") != 401 || auth("two") != 200 {
+		t.Fatal("want only the new token accepted")
+	}
+	srv.SetHTTPTokens(nil)
+	if auth("one") != 200 {
+		t.Fatal("want no auth without tokens")
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/repl"
+	"github.com/nalgeon/redka/internal/rkey"
+	"github.com/tidwall/redcon"
+)
+
+// keyspaceRefresh is how often the keyspace section
+// of INFO is recomputed.
+const keyspaceRefresh = time.Second
+
+// info handles the INFO command and delegates
+// the rest to the next handler.
+// INFO [section [section ...]]
+// https://redis.io/commands/info
+func info(db *redka.DB, opts *Options, next redcon.HandlerFunc) redcon.HandlerFunc {
+	keyspace := &keyspaceCache{db: db, opts: opts}
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		if normName(cmd) != "info" {
+			next(conn, cmd)
+			return
+		}
+		conn.WriteBulkString(infoText(cmd.Args[1:], keyspace, opts))
+	}
+}
+
+// infoText returns the requested sections of INFO.
+func infoText(sections [][]byte, keyspace *keyspaceCache, opts *Options) string {
+	var b strings.Builder
+	if wantSection(sections, "replication") {
+		writeReplicationInfo(&b, opts)
+	}
+	if wantSection(sections, "keyspace") {
+		if b.Len() > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString("# Keyspace\r\n")
+		b.WriteString(keyspace.get())
+	}
+	return b.String()
+}
+
+// wantSection reports whether the INFO command
+// requests the given section.
+func wantSection(args [][]byte, section string) bool {
+	if len(args) == 0 {
+		return true
+	}
+	for _, arg := range args {
+		switch strings.ToLower(string(arg)) {
+		case section, "all", "default", "everything":
+			return true
+		}
+	}
+	return false
+}
+
+// writeReplicationInfo writes the replication section of INFO.
+func writeReplicationInfo(b *strings.Builder, opts *Options) {
+	b.WriteString("# Replication\r\n")
+	if opts.Replica != nil {
+		r := opts.Replica.Info()
+		host, port, _ := net.SplitHostPort(r.Primary)
+		linkStatus, syncing := "down", 0
+		switch r.State {
+		case repl.StateConnected:
+			linkStatus = "up"
+		case repl.StateSync:
+			syncing = 1
+		}
+		fmt.Fprintf(b, "role:slave\r\n")
+		fmt.Fprintf(b, "master_host:%s\r\n", host)
+		fmt.Fprintf(b, "master_port:%s\r\n", port)
+		fmt.Fprintf(b, "master_link_status:%s\r\n", linkStatus)
+		fmt.Fprintf(b, "master_last_io_seconds_ago:%d\r\n", int(r.LastIO.Seconds()))
+		fmt.Fprintf(b, "master_sync_in_progress:%d\r\n", syncing)
+		fmt.Fprintf(b, "slave_read_only:1\r\n")
+		fmt.Fprintf(b, "slave_repl_offset:%d\r\n", r.Offset)
+		fmt.Fprintf(b, "master_replid:%s\r\n", r.ID)
+		return
+	}
+
+	fmt.Fprintf(b, "role:master\r\n")
+	if opts.Primary == nil {
+		fmt.Fprintf(b, "connected_slaves:0\r\n")
+		return
+	}
+	p := opts.Primary.Info()
+	fmt.Fprintf(b, "connected_slaves:%d\r\n", len(p.Replicas))
+	for i, r := range p.Replicas {
+		host, port, _ := net.SplitHostPort(r.Addr)
+		fmt.Fprintf(b, "slave%d:ip=%s,port=%s,state=%s,offset=%d,lag=%d\r\n",
+			i, host, port, r.State, r.Offset, int(r.Lag.Seconds()))
+	}
+	fmt.Fprintf(b, "master_replid:%s\r\n", p.ID)
+	fmt.Fprintf(b, "master_repl_offset:%d\r\n", p.Offset)
+}
+
+// keyspaceCache keeps the keyspace section of INFO, so that
+// frequent INFO calls (e.g. by monitoring systems) do not query
+// the database every time. Refreshes every keyspaceRefresh.
+type keyspaceCache struct {
+	db   *redka.DB
+	opts *Options
+
+	mu   sync.Mutex
+	at   time.Time // last refresh time
+	text string
+}
+
+// get returns the keyspace section lines,
+// refreshing them if they are outdated.
+func (c *keyspaceCache) get() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.at) < keyspaceRefresh {
+		return c.text
+	}
+	var b strings.Builder
+	c.write(&b)
+	c.text, c.at = b.String(), time.Now()
+	return c.text
+}
+
+// write writes the db0 line for the main database (or the shards)
+// and a line for each tenant. Like Redis, skips the empty databases.
+func (c *keyspaceCache) write(b *strings.Builder) {
+	var info rkey.KeyspaceInfo
+	var err error
+	if c.opts.Shards != nil {
+		info, err = c.opts.Shards.Key().Keyspace()
+	} else {
+		info, err = c.db.Key().Keyspace()
+	}
+	if err != nil {
+		c.opts.Logger.Warn("info keyspace", "db", "0", "err", err)
+	}
+	writeKeyspaceLine(b, "0", info)
+
+	if c.opts.Tenants == nil {
+		return
+	}
+	for _, name := range c.opts.Tenants.Names() {
+		db, err := c.opts.Tenants.Get(name)
+		if err != nil {
+			continue
+		}
+		info, err := db.Key().Keyspace()
+		if err != nil {
+			c.opts.Logger.Warn("info keyspace", "db", name, "err", err)
+		}
+		writeKeyspaceLine(b, name, info)
+	}
+}
+
+// writeKeyspaceLine writes the keyspace line of a database
+// (avg_ttl is in milliseconds, like in Redis).
+func writeKeyspaceLine(b *strings.Builder, name string, info rkey.KeyspaceInfo) {
+	if info.Keys == 0 {
+		return
+	}
+	fmt.Fprintf(b, "db%s:keys=%d,expires=%d,avg_ttl=%d\r\n",
+		name, info.Keys, info.Expires, info.AvgTTL.Milliseconds())
+}
+
+// expiryHandler serves the number of keys that expire within the next
+// minute, hour and day as JSON, so that the capacity planners can
+// predict the expiration-driven deletes and cache misses:
+//
+//	{"minute":10,"hour":250,"day":4000,"total":12000,"expired":5}
+//
+// The expired keys are the ones not deleted yet.
+func expiryHandler(db *redka.DB, opts *Options) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var info rkey.ExpiryInfo
+		var err error
+		if opts.Shards != nil {
+			info, err = opts.Shards.Key().Expiry()
+		} else {
+			info, err = db.Key().Expiry()
+		}
+		if err != nil {
+			opts.Logger.Error("expiry", "error", err)
+			writeJSONError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]int{
+			"minute":  info.Minute,
+			"hour":    info.Hour,
+			"day":     info.Day,
+			"total":   info.Total,
+			"expired": info.Expired,
+		})
+	}
+}
@@ -0,0 +1,398 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/glob"
+)
+
+// subBufferSize is the number of messages buffered for a subscriber.
+// Subscribers that fall behind by more are disconnected
+// (same as Redis does with the slow pub/sub clients).
+const subBufferSize = 256
+
+// hub delivers the pub/sub messages to the subscribers.
+type hub struct {
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subs: map[*subscriber]struct{}{}}
+}
+
+// subscriber receives the messages published
+// to the channels or the patterns it subscribed to.
+type subscriber struct {
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	out      chan []byte   // outgoing messages
+	done     chan struct{} // closed when the subscriber is removed
+}
+
+// pubsubMessage is a message sent to a WebSocket client
+// (modeled after the Redis pub/sub replies).
+type pubsubMessage struct {
+	Type    string `json:"type"`
+	Pattern string `json:"pattern,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	Data    string `json:"data,omitempty"`
+	Count   *int   `json:"count,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// pubsubRequest is a message received from a WebSocket client.
+type pubsubRequest struct {
+	Action   string   `json:"action"`
+	Channels []string `json:"channels"`
+	Channel  string   `json:"channel"`
+	Message  string   `json:"message"`
+}
+
+// add registers a new subscriber.
+func (h *hub) add() *subscriber {
+	sub := &subscriber{
+		channels: map[string]struct{}{},
+		patterns: map[string]struct{}{},
+		out:      make(chan []byte, subBufferSize),
+		done:     make(chan struct{}),
+	}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+// remove unregisters the subscriber.
+func (h *hub) remove(sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subs[sub]; ok {
+		delete(h.subs, sub)
+		close(sub.done)
+	}
+}
+
+// close unregisters all subscribers.
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.done)
+	}
+}
+
+// subscribe adds the channels (or patterns) to the subscriber
+// and returns the confirmations to send back.
+func (h *hub) subscribe(sub *subscriber, names []string, pattern bool) []pubsubMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	typ, set := "subscribe", sub.channels
+	if pattern {
+		typ, set = "psubscribe", sub.patterns
+	}
+	msgs := make([]pubsubMessage, 0, len(names))
+	for _, name := range names {
+		set[name] = struct{}{}
+		count := len(sub.channels) + len(sub.patterns)
+		msgs = append(msgs, pubsubMessage{Type: typ, Channel: name, Count: &count})
+	}
+	return msgs
+}
+
+// unsubscribe removes the channels (or patterns) from the subscriber,
+// or all of them if names is empty, and returns the confirmations.
+func (h *hub) unsubscribe(sub *subscriber, names []string, pattern bool) []pubsubMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	typ, set := "unsubscribe", sub.channels
+	if pattern {
+		typ, set = "punsubscribe", sub.patterns
+	}
+	if len(names) == 0 {
+		for name := range set {
+			names = append(names, name)
+		}
+	}
+	msgs := make([]pubsubMessage, 0, len(names))
+	for _, name := range names {
+		delete(set, name)
+		count := len(sub.channels) + len(sub.patterns)
+		msgs = append(msgs, pubsubMessage{Type: typ, Channel: name, Count: &count})
+	}
+	return msgs
+}
+
+// publish sends the message to the subscribers of the channel
+// and returns the number of deliveries. Disconnects the subscribers
+// that can't keep up.
+func (h *hub) publish(channel, data string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	count := 0
+	for sub := range h.subs {
+		if _, ok := sub.channels[channel]; ok {
+			msg := pubsubMessage{Type: "message", Channel: channel, Data: data}
+			h.send(sub, msg)
+			count++
+		}
+		for pattern := range sub.patterns {
+			if glob.Match(pattern, channel) {
+				msg := pubsubMessage{Type: "pmessage", Pattern: pattern, Channel: channel, Data: data}
+				h.send(sub, msg)
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// send queues the message for the subscriber, or removes
+// the subscriber if its buffer is full. Must be called
+// with the lock held.
+func (h *hub) send(sub *subscriber, msg pubsubMessage) {
+	b, _ := json.Marshal(msg)
+	select {
+	case sub.out <- b:
+	default:
+		delete(h.subs, sub)
+		close(sub.done)
+	}
+}
+
+// reply queues the replies to the subscriber's requests.
+func (h *hub) reply(sub *subscriber, msgs []pubsubMessage) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, msg := range msgs {
+		if _, ok := h.subs[sub]; !ok {
+			return
+		}
+		h.send(sub, msg)
+	}
+}
+
+// notify publishes the keyspace notifications for the changes,
+// like Redis does with notify-keyspace-events set to "KEA":
+// the event name to __keyspace@0__:<key>,
+// and the key to __keyevent@0__:<event>.
+// Skips the repeated events for the same key.
+func (h *hub) notify(event redka.ChangeEvent) {
+	var lastKey, lastName string
+	for _, c := range event.Changes {
+		for _, ev := range keyEvents(c) {
+			if ev.key == lastKey && ev.name == lastName {
+				continue
+			}
+			lastKey, lastName = ev.key, ev.name
+			h.publish("__keyspace@0__:"+ev.key, ev.name)
+			h.publish("__keyevent@0__:"+ev.name, ev.key)
+		}
+	}
+}
+
+// keyEvent is a keyspace notification.
+type keyEvent struct {
+	key  string
+	name string
+}
+
+// keyEvents returns the keyspace notifications for the change,
+// named after the Redis commands that cause them.
+func keyEvents(c redka.Change) []keyEvent {
+	switch c.Op {
+	case redka.ChangeSet:
+		switch c.Type {
+		case core.TypeHash:
+			return []keyEvent{{c.Key, "hset"}}
+		case core.TypeSortedSet:
+			return []keyEvent{{c.Key, "zadd"}}
+		}
+		return []keyEvent{{c.Key, "set"}}
+	case redka.ChangeDelete:
+		if c.Field != "" {
+			switch c.Type {
+			case core.TypeHash:
+				return []keyEvent{{c.Key, "hdel"}}
+			case core.TypeSortedSet:
+				return []keyEvent{{c.Key, "zrem"}}
+			}
+		}
+		return []keyEvent{{c.Key, "del"}}
+	case redka.ChangeExpired:
+		return []keyEvent{{c.Key, "expired"}}
+	case redka.ChangeExpire:
+		if c.After == nil {
+			return []keyEvent{{c.Key, "persist"}}
+		}
+		return []keyEvent{{c.Key, "expire"}}
+	case redka.ChangeRename:
+		return []keyEvent{
+			{c.Before.String(), "rename_from"},
+			{c.After.String(), "rename_to"},
+		}
+	}
+	return nil
+}
+
+// pubsubBridge serves the pub/sub channels and the keyspace
+// notifications to the WebSocket clients. The clients send
+// JSON requests:
+//
+//	{"action":"subscribe","channels":["news"]}
+//	{"action":"psubscribe","channels":["__keyspace@0__:user:*"]}
+//	{"action":"unsubscribe","channels":["news"]}
+//	{"action":"punsubscribe"}
+//	{"action":"publish","channel":"news","message":"hello"}
+//
+// and receive JSON messages:
+//
+//	{"type":"subscribe","channel":"news","count":1}
+//	{"type":"message","channel":"news","data":"hello"}
+//	{"type":"pmessage","pattern":"__keyspace@0__:user:*","channel":"__keyspace@0__:user:1","data":"set"}
+//	{"type":"publish","channel":"news","count":2}
+//	{"type":"error","error":"..."}
+type pubsubBridge struct {
+	hub  *hub
+	api  *httpAPI // for the authorization and the allowed origin
+	log  *slog.Logger
+	wg   sync.WaitGroup
+	mu   sync.Mutex
+	conn map[*wsConn]struct{}
+}
+
+// newPubSubBridge creates the WebSocket bridge and subscribes
+// it to the database changes for the keyspace notifications.
+func newPubSubBridge(db *redka.DB, opts *Options) *pubsubBridge {
+	b := &pubsubBridge{
+		hub:  newHub(),
+		api:  newHTTPAPI(db, opts),
+		log:  opts.Logger,
+		conn: map[*wsConn]struct{}{},
+	}
+	db.OnChange(b.hub.notify)
+	return b
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (b *pubsubBridge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/ws" {
+		http.NotFound(w, r)
+		return
+	}
+	// Browsers can't set the headers for WebSocket
+	// connections, so accept the token in the query too.
+	if token := r.URL.Query().Get("token"); token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	if !b.api.authorized(r) {
+		http.Error(w, "authentication required", http.StatusUnauthorized)
+		return
+	}
+	origin := b.api.opts.HTTPCORSOrigin
+	if origin != "" && origin != "*" && r.Header.Get("Origin") != origin {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	conn, err := wsUpgrade(w, r)
+	if err != nil {
+		b.log.Debug("websocket upgrade", "client", r.RemoteAddr, "error", err)
+		return
+	}
+	b.mu.Lock()
+	b.conn[conn] = struct{}{}
+	b.mu.Unlock()
+	b.wg.Add(1)
+	defer func() {
+		b.mu.Lock()
+		delete(b.conn, conn)
+		b.mu.Unlock()
+		b.wg.Done()
+	}()
+
+	b.log.Info("accept websocket", "client", r.RemoteAddr)
+	err = b.serve(conn)
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.log.Debug("close websocket", "client", r.RemoteAddr, "error", err)
+	} else {
+		b.log.Debug("close websocket", "client", r.RemoteAddr)
+	}
+}
+
+// serve handles the client requests until the connection closes.
+func (b *pubsubBridge) serve(conn *wsConn) error {
+	sub := b.hub.add()
+	defer b.hub.remove(sub)
+	defer conn.Close()
+
+	// Write the messages in the background.
+	go func() {
+		for {
+			select {
+			case msg := <-sub.out:
+				if err := conn.WriteMessage(msg); err != nil {
+					conn.conn.Close()
+					return
+				}
+			case <-sub.done:
+				// Disconnected by the hub (slow client or shutdown).
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		// Queue the replies after the messages published
+		// by the request (if any), to keep them in order.
+		b.hub.reply(sub, b.handle(sub, data))
+	}
+}
+
+// handle executes the client request and returns the replies.
+func (b *pubsubBridge) handle(sub *subscriber, data []byte) []pubsubMessage {
+	var req pubsubRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return []pubsubMessage{{Type: "error", Error: "invalid request: " + err.Error()}}
+	}
+	switch req.Action {
+	case "subscribe", "psubscribe":
+		if len(req.Channels) == 0 {
+			return []pubsubMessage{{Type: "error", Error: "channels are required"}}
+		}
+		return b.hub.subscribe(sub, req.Channels, req.Action == "psubscribe")
+	case "unsubscribe", "punsubscribe":
+		return b.hub.unsubscribe(sub, req.Channels, req.Action == "punsubscribe")
+	case "publish":
+		if req.Channel == "" {
+			return []pubsubMessage{{Type: "error", Error: "channel is required"}}
+		}
+		count := b.hub.publish(req.Channel, req.Message)
+		return []pubsubMessage{{Type: "publish", Channel: req.Channel, Count: &count}}
+	}
+	return []pubsubMessage{{Type: "error", Error: "unknown action: " + req.Action}}
+}
+
+// close disconnects the clients and waits for them to finish.
+func (b *pubsubBridge) close() {
+	b.hub.close()
+	b.mu.Lock()
+	for conn := range b.conn {
+		conn.conn.Close()
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
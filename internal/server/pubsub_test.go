@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+)
+
+func TestPubSubBridge(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	bridge := newPubSubBridge(db, applyOptions(nil))
+	srv := httptest.NewServer(bridge)
+	defer srv.Close()
+	defer bridge.close()
+
+	t.Run("publish", func(t *testing.T) {
+		c1 := dialWS(t, srv.URL)
+		c2 := dialWS(t, srv.URL)
+		c1.send(`{"action":"subscribe","channels":["news","sport"]}`)
+		c1.expect(`{"type":"subscribe","channel":"news","count":1}`)
+		c1.expect(`{"type":"subscribe","channel":"sport","count":2}`)
+		c2.send(`{"action":"psubscribe","channels":["n*"]}`)
+		c2.expect(`{"type":"psubscribe","channel":"n*","count":1}`)
+
+		c1.send(`{"action":"publish","channel":"news","message":"hello"}`)
+		c1.expect(`{"type":"message","channel":"news","data":"hello"}`)
+		c1.expect(`{"type":"publish","channel":"news","count":2}`)
+		c2.expect(`{"type":"pmessage","pattern":"n*","channel":"news","data":"hello"}`)
+
+		c1.send(`{"action":"unsubscribe"}`)
+		c1.expectType("unsubscribe")
+		c1.expectType("unsubscribe")
+		c2.send(`{"action":"publish","channel":"news","message":"again"}`)
+		c2.expect(`{"type":"pmessage","pattern":"n*","channel":"news","data":"again"}`)
+		c2.expect(`{"type":"publish","channel":"news","count":1}`)
+	})
+	t.Run("keyspace", func(t *testing.T) {
+		c := dialWS(t, srv.URL)
+		c.send(`{"action":"psubscribe","channels":["__keyspace@0__:user:*"]}`)
+		c.expectType("psubscribe")
+		c.send(`{"action":"subscribe","channels":["__keyevent@0__:del"]}`)
+		c.expectType("subscribe")
+
+		_ = db.Str().Set("user:1", "alice")
+		_ = db.Str().Set("post:1", "hello")
+		_, _ = db.Hash().SetMany("user:2", map[string]any{"name": "bob", "age": 25})
+		_, _ = db.Key().Delete("user:1")
+
+		const pattern = `"pattern":"__keyspace@0__:user:*",`
+		c.expect(`{"type":"pmessage",` + pattern + `"channel":"__keyspace@0__:user:1","data":"set"}`)
+		c.expect(`{"type":"pmessage",` + pattern + `"channel":"__keyspace@0__:user:2","data":"hset"}`)
+		c.expect(`{"type":"pmessage",` + pattern + `"channel":"__keyspace@0__:user:1","data":"del"}`)
+		c.expect(`{"type":"message","channel":"__keyevent@0__:del","data":"user:1"}`)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		c := dialWS(t, srv.URL)
+		c.send(`{"action":"nope"}`)
+		c.expect(`{"type":"error","error":"unknown action: nope"}`)
+		c.send(`not json`)
+		c.expectType("error")
+	})
+}
+
+func TestPubSubAuth(t *testing.T) {
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	bridge := newPubSubBridge(db, applyOptions(&Options{
+		HTTPTokens:     []string{"secret"},
+		HTTPCORSOrigin: "https://example.com",
+	}))
+	srv := httptest.NewServer(bridge)
+	defer srv.Close()
+	defer bridge.close()
+
+	tests := []struct {
+		path   string
+		origin string
+		status int
+	}{
+		{"/ws", "https://example.com", http.StatusUnauthorized},
+		{"/ws?token=nope", "https://example.com", http.StatusUnauthorized},
+		{"/ws?token=secret", "https://evil.com", http.StatusForbidden},
+		{"/ws?token=secret", "https://example.com", http.StatusSwitchingProtocols},
+		{"/other?token=secret", "https://example.com", http.StatusNotFound},
+	}
+	for _, test := range tests {
+		conn, status := handshake(t, srv.URL, test.path, test.origin)
+		conn.Close()
+		if status != test.status {
+			t.Errorf("%s from %s: want %d, got %d", test.path, test.origin, test.status, status)
+		}
+	}
+}
+
+// wsClient is a minimal WebSocket client for the tests.
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// dialWS connects to the bridge at /ws.
+func dialWS(t *testing.T, url string) *wsClient {
+	t.Helper()
+	conn, status := handshake(t, url, "/ws", "")
+	if status != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake: want 101, got %d", status)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &wsClient{t: t, conn: conn, rd: bufio.NewReader(conn)}
+}
+
+// handshake sends the WebSocket handshake and returns the status.
+func handshake(t *testing.T, url, path, origin string) (net.Conn, int) {
+	t.Helper()
+	addr := strings.TrimPrefix(url, "http://")
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := "GET " + path + " HTTP/1.1\r\n" +
+		"Host: " + addr + "\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n"
+	if origin != "" {
+		req += "Origin: " + origin + "\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	// Read the status line and the headers byte by byte,
+	// so that the frames that follow are not buffered.
+	var head strings.Builder
+	buf := make([]byte, 1)
+	for !strings.HasSuffix(head.String(), "\r\n\r\n") {
+		if _, err := conn.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+		head.WriteByte(buf[0])
+	}
+	var status int
+	_, _ = fmt.Sscanf(head.String(), "HTTP/1.1 %d", &status)
+	if status == http.StatusSwitchingProtocols &&
+		!strings.Contains(head.String(), "Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=") {
+		t.Fatalf("handshake: invalid accept key in %q", head.String())
+	}
+	return conn, status
+}
+
+// send writes a masked text frame.
+func (c *wsClient) send(msg string) {
+	c.t.Helper()
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x81, 0x80 | byte(len(msg))}
+	frame = append(frame, mask[:]...)
+	for i := range len(msg) {
+		frame = append(frame, msg[i]^mask[i%4])
+	}
+	if _, err := c.conn.Write(frame); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive reads an unmasked text frame.
+func (c *wsClient) receive() string {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(time.Second))
+	var head [2]byte
+	if _, err := io.ReadFull(c.rd, head[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	size := int(head[1] & 0x7F)
+	if size == 126 {
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rd, ext[:]); err != nil {
+			c.t.Fatal(err)
+		}
+		size = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(c.rd, payload); err != nil {
+		c.t.Fatal(err)
+	}
+	return string(payload)
+}
+
+// expect reads a message and compares it to want.
+func (c *wsClient) expect(want string) {
+	c.t.Helper()
+	if got := c.receive(); got != want {
+		c.t.Fatalf("want %s, got %s", want, got)
+	}
+}
+
+// expectType reads a message and checks its type.
+func (c *wsClient) expectType(want string) {
+	c.t.Helper()
+	var msg pubsubMessage
+	got := c.receive()
+	if err := json.Unmarshal([]byte(got), &msg); err != nil || msg.Type != want {
+		c.t.Fatalf("want %s message, got %s", want, got)
+	}
+}
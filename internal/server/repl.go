@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net"
+	"strings"
+
+	"github.com/tidwall/redcon"
+)
+
+// replication handles the commands sent by the replicas
+// (REPLCONF, SYNC and PSYNC) and delegates the rest
+// to the next handler.
+func replication(opts *Options, next redcon.HandlerFunc) redcon.HandlerFunc {
+	return func(conn redcon.Conn, cmd redcon.Command) {
+		switch normName(cmd) {
+		case "replconf":
+			// REPLCONF listening-port <port> | capa <capability> | ...
+			state := getState(conn)
+			for i := 1; i+1 < len(cmd.Args); i += 2 {
+				if strings.EqualFold(string(cmd.Args[i]), "listening-port") {
+					state.replPort = string(cmd.Args[i+1])
+				}
+			}
+			conn.WriteString("OK")
+		case "sync", "psync":
+			if opts.Primary == nil {
+				conn.WriteError("ERR replication is disabled")
+				return
+			}
+			addr := replicaAddr(conn.RemoteAddr(), getState(conn).replPort)
+			dconn := conn.Detach()
+			go func() {
+				err := opts.Primary.Sync(dconn, addr)
+				if err != nil {
+					opts.Logger.Warn("sync replica", "replica", addr, "error", err)
+				}
+			}()
+		default:
+			next(conn, cmd)
+		}
+	}
+}
+
+// replicaAddr returns the replica address based on the
+// client address and the listening port reported by the replica.
+func replicaAddr(clientAddr, port string) string {
+	if port == "" {
+		return clientAddr
+	}
+	host, _, err := net.SplitHostPort(clientAddr)
+	if err != nil {
+		return clientAddr
+	}
+	return net.JoinHostPort(host, port)
+}
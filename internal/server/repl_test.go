@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/repl"
+	"github.com/nalgeon/redka/internal/resp"
+	"github.com/tidwall/redcon"
+)
+
+func TestReplication(t *testing.T) {
+	primaryDB := openDB(t)
+	_ = primaryDB.Str().Set("name", "alice")
+	_ = primaryDB.Str().SetExpires("tmp", "value", time.Hour)
+	_, _ = primaryDB.Stream().Add("events", "user", "alice")
+	_, _ = primaryDB.Stream().Add("events", "user", "bob")
+	_, _ = primaryDB.HLL().Add("visitors", "alice", "bob")
+
+	addr := freeAddr(t)
+	primary := repl.NewPrimary(primaryDB)
+	srv := New(addr, primaryDB, &Options{Primary: primary})
+	srv.Start()
+	defer func() { _ = srv.Stop() }()
+	waitFor(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	})
+
+	replicaDB := openDB(t)
+	defer replicaDB.Close()
+	replica := repl.NewReplica(replicaDB, addr, nil)
+	replica.Start()
+	defer replica.Stop()
+
+	// initial snapshot
+	waitFor(t, func() bool {
+		val, _ := replicaDB.Str().Get("name")
+		return val.String() == "alice"
+	})
+	key, _ := replicaDB.Key().Get("tmp")
+	if key.ETime == nil {
+		t.Fatal("want ttl on replicated key")
+	}
+	if n, _ := replicaDB.Stream().Len("events"); n != 2 {
+		t.Fatalf("want 2 stream entries, got %d", n)
+	}
+	if n, _ := replicaDB.HLL().Count("visitors"); n != 2 {
+		t.Fatalf("want hll count 2, got %d", n)
+	}
+
+	// command stream
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	send := func(args ...string) string {
+		bargs := make([][]byte, len(args))
+		for i, arg := range args {
+			bargs[i] = []byte(arg)
+		}
+		_, _ = conn.Write(resp.AppendCommand(nil, bargs...))
+		line, err := resp.ReadLine(rd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return line
+	}
+	send("set", "name", "bob")
+	send("hset", "person", "age", "25")
+	waitFor(t, func() bool {
+		val, _ := replicaDB.Str().Get("name")
+		age, _ := replicaDB.Hash().Get("person", "age")
+		return val.String() == "bob" && age.String() == "25"
+	})
+
+	// lag reporting
+	waitFor(t, func() bool {
+		info := primary.Info()
+		return len(info.Replicas) == 1 && info.Replicas[0].Offset == info.Offset
+	})
+	if got := replica.Info().State; got != repl.StateConnected {
+		t.Fatalf("want state %s, got %s", repl.StateConnected, got)
+	}
+	if got := send("info", "replication"); !strings.HasPrefix(got, "$") {
+		t.Fatalf("want bulk reply, got %q", got)
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+	opts := &Options{Replica: repl.NewReplica(db, "localhost:1", nil)}
+	mux := createHandlers(db, opts)
+
+	conn := new(fakeConn)
+	mux.ServeRESP(conn, buildCommand("set", "name", "alice"))
+	if !strings.HasPrefix(conn.out(), "READONLY") {
+		t.Fatalf("want READONLY error, got '%s'", conn.out())
+	}
+
+	conn = new(fakeConn)
+	mux.ServeRESP(conn, buildCommand("get", "name"))
+	if conn.out() != "(nil)" {
+		t.Fatalf("want '(nil)', got '%s'", conn.out())
+	}
+}
+
+func openDB(tb testing.TB) *redka.DB {
+	tb.Helper()
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+func buildCommand(args ...string) redcon.Command {
+	bargs := make([][]byte, len(args))
+	for i, arg := range args {
+		bargs[i] = []byte(arg)
+	}
+	return redcon.Command{Raw: []byte(strings.Join(args, " ")), Args: bargs}
+}
+
+func freeAddr(tb testing.TB) string {
+	tb.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+func waitFor(tb testing.TB, cond func() bool) {
+	tb.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			tb.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -2,52 +2,219 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"log/slog"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/aof"
+	"github.com/nalgeon/redka/internal/repl"
 	"github.com/tidwall/redcon"
 )
 
+// Options holds the server options.
+type Options struct {
+	// Journal is an optional append-only journal.
+	// If set, the server writes every successful
+	// write command to the journal.
+	Journal *aof.Journal
+	// Primary is an optional replication primary.
+	// If set, the server accepts replicas (PSYNC)
+	// and sends them every successful write command.
+	Primary *repl.Primary
+	// Replica is an optional replica of another server.
+	// If set, the server rejects write commands from clients.
+	Replica *repl.Replica
+	// Tenants is an optional set of tenant databases.
+	// If set, clients switch between the tenants with SELECT
+	// (SELECT 0 switches back to the main database).
+	// The journal and replication only cover the main database.
+	Tenants *redka.Tenants
+	// Shards is an optional sharded database. If set, the commands
+	// run on the shards instead of the main database (which should
+	// be one of the shards), and the multi-key commands fail with
+	// CROSSSLOT if the keys are in different slots. Transactions
+	// (MULTI/EXEC) are not supported in the sharded mode.
+	Shards *redka.Shards
+	// Logger logs the connection lifecycle, failed commands
+	// and server errors. If nil, uses slog.Default().
+	Logger *slog.Logger
+	// MetricsAddr is an optional address of the HTTP server
+	// that serves the database metrics at /metrics
+	// (in the Prometheus text format), the number of keys
+	// expiring soon at /expiry (as JSON), and the liveness
+	// and readiness probes at /livez and /readyz.
+	MetricsAddr string
+	// Health are the limits checked by the readiness probe.
+	Health HealthOptions
+	// HTTPAddr is an optional address of the HTTP server
+	// that runs the commands sent as REST requests
+	// (GET /GET/key, PUT /SET/key) and returns JSON replies.
+	HTTPAddr string
+	// HTTPTokens are the tokens accepted by the HTTP server
+	// in the "Authorization: Bearer" header. If empty,
+	// the HTTP server does not require authentication.
+	HTTPTokens []string
+	// HTTPCORSOrigin is the origin allowed to call the HTTP
+	// server from a browser ("*" for any origin).
+	// If empty, the CORS headers are not sent.
+	HTTPCORSOrigin string
+	// WebSocketAddr is an optional address of the HTTP server
+	// that serves the pub/sub channels and the keyspace
+	// notifications to WebSocket clients at /ws.
+	// Uses the same tokens and origin as the HTTP API.
+	WebSocketAddr string
+	// AdminAddr is an optional address of the HTTP server
+	// that serves the web admin dashboard (key browser,
+	// INFO and slowlog) at /. Uses the same tokens
+	// as the HTTP API.
+	AdminAddr string
+	// AdminSlowLog is the minimum duration of the operations
+	// shown in the slowlog dashboard. If zero, uses 10ms.
+	AdminSlowLog time.Duration
+	// Reload is an optional function that reloads the configuration.
+	// If set, the server runs it on the RELOAD command, and replies
+	// with its error (if any). The clients stay connected.
+	Reload func() error
+	// PinAfterWrite sends the reads of a client to the primary
+	// database instead of the read replicas (see redka.Options.ReadReplicas)
+	// for this long after the client's write, so the client always
+	// reads its own writes. Other clients are not pinned. If zero,
+	// the reads are not pinned (but see redka.Options.PinAfterWrite).
+	PinAfterWrite time.Duration
+}
+
 // Server represents a Redka server.
 type Server struct {
-	addr string
-	srv  *redcon.Server
-	db   *redka.DB
-	wg   *sync.WaitGroup
+	addr  string
+	srv   *redcon.Server
+	db    *redka.DB
+	opts  *Options
+	http  *http.Server
+	api   *http.Server
+	ws    *http.Server
+	admin *http.Server
+	pub   *pubsubBridge
+	apis  []*httpAPI // to change the tokens
+	wg    *sync.WaitGroup
 }
 
 // New creates a new Redka server.
-func New(addr string, db *redka.DB) *Server {
-	handler := createHandlers(db)
+// The opts parameter is optional. If nil, uses default options.
+func New(addr string, db *redka.DB, opts *Options) *Server {
+	opts = applyOptions(opts)
+	log := opts.Logger
+	handler := createHandlers(db, opts)
 	accept := func(conn redcon.Conn) bool {
-		slog.Info("accept connection", "client", conn.RemoteAddr())
+		log.Info("accept connection", "client", conn.RemoteAddr())
 		return true
 	}
 	closed := func(conn redcon.Conn, err error) {
 		if err != nil {
-			slog.Debug("close connection", "client", conn.RemoteAddr(), "error", err)
+			log.Debug("close connection", "client", conn.RemoteAddr(), "error", err)
 		} else {
-			slog.Debug("close connection", "client", conn.RemoteAddr())
+			log.Debug("close connection", "client", conn.RemoteAddr())
 		}
 	}
-	return &Server{
+	s := &Server{
 		addr: addr,
 		srv:  redcon.NewServer(addr, handler, accept, closed),
 		db:   db,
+		opts: opts,
 		wg:   &sync.WaitGroup{},
 	}
+	if opts.MetricsAddr != "" {
+		metrics := db.Metrics()
+		if metrics == nil {
+			metrics = redka.NewMetrics(db)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics)
+		mux.Handle("GET /expiry", expiryHandler(db, opts))
+		health := &health{db: db, opts: opts}
+		mux.HandleFunc("GET /livez", health.livez)
+		mux.HandleFunc("GET /readyz", health.readyz)
+		s.http = &http.Server{Addr: opts.MetricsAddr, Handler: mux}
+	}
+	if opts.HTTPAddr != "" {
+		api := newHTTPAPI(db, opts)
+		s.api = &http.Server{Addr: opts.HTTPAddr, Handler: api}
+		s.apis = append(s.apis, api)
+	}
+	if opts.WebSocketAddr != "" {
+		s.pub = newPubSubBridge(db, opts)
+		s.ws = &http.Server{Addr: opts.WebSocketAddr, Handler: s.pub}
+		s.apis = append(s.apis, s.pub.api)
+	}
+	if opts.AdminAddr != "" {
+		ui := newAdminUI(db, opts)
+		s.admin = &http.Server{Addr: opts.AdminAddr, Handler: ui}
+		s.apis = append(s.apis, ui.api)
+	}
+	return s
+}
+
+// SetHTTPTokens replaces the tokens accepted by the HTTP API,
+// the WebSocket and the admin servers (see Options.HTTPTokens).
+// The requests in progress are not affected.
+func (s *Server) SetHTTPTokens(tokens []string) {
+	for _, api := range s.apis {
+		api.setTokens(tokens)
+	}
+}
+
+// applyOptions returns a copy of the options with the defaults applied.
+func applyOptions(opts *Options) *Options {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+	return &o
 }
 
 // Start starts the server.
 func (s *Server) Start() {
+	if s.opts.Replica != nil {
+		s.opts.Replica.Start()
+	}
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
-		slog.Info("serve connections", "addr", s.addr)
+		s.opts.Logger.Info("serve connections", "addr", s.addr)
 		err := s.srv.ListenAndServe()
 		if err != nil {
-			slog.Error("serve connections", "error", err)
+			s.opts.Logger.Error("serve connections", "error", err)
+		}
+	}()
+	if s.http != nil {
+		s.serveHTTP(s.http, "serve metrics")
+	}
+	if s.api != nil {
+		s.serveHTTP(s.api, "serve http")
+	}
+	if s.ws != nil {
+		s.serveHTTP(s.ws, "serve websocket")
+	}
+	if s.admin != nil {
+		s.serveHTTP(s.admin, "serve admin")
+	}
+}
+
+// serveHTTP starts the HTTP server in the background.
+func (s *Server) serveHTTP(srv *http.Server, msg string) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.opts.Logger.Info(msg, "addr", srv.Addr)
+		err := srv.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.opts.Logger.Error(msg, "error", err)
 		}
 	}()
 }
@@ -58,13 +225,72 @@ func (s *Server) Stop() error {
 	if err != nil {
 		return err
 	}
-	slog.Debug("close redcon server", "addr", s.addr)
+	s.opts.Logger.Debug("close redcon server", "addr", s.addr)
 
-	err = s.db.Close()
+	if s.http != nil {
+		err = s.http.Shutdown(context.Background())
+		if err != nil {
+			return err
+		}
+		s.opts.Logger.Debug("close metrics server", "addr", s.http.Addr)
+	}
+	if s.api != nil {
+		err = s.api.Shutdown(context.Background())
+		if err != nil {
+			return err
+		}
+		s.opts.Logger.Debug("close http server", "addr", s.api.Addr)
+	}
+	if s.ws != nil {
+		err = s.ws.Shutdown(context.Background())
+		if err != nil {
+			return err
+		}
+		s.pub.close()
+		s.opts.Logger.Debug("close websocket server", "addr", s.ws.Addr)
+	}
+	if s.admin != nil {
+		err = s.admin.Shutdown(context.Background())
+		if err != nil {
+			return err
+		}
+		s.opts.Logger.Debug("close admin server", "addr", s.admin.Addr)
+	}
+
+	if s.opts.Replica != nil {
+		s.opts.Replica.Stop()
+		s.opts.Logger.Debug("stop replication")
+	}
+	if s.opts.Primary != nil {
+		_ = s.opts.Primary.Close()
+		s.opts.Logger.Debug("disconnect replicas")
+	}
+
+	if s.opts.Shards != nil {
+		err = s.opts.Shards.Close()
+	} else {
+		err = s.db.Close()
+	}
 	if err != nil {
 		return err
 	}
-	slog.Debug("close database")
+	s.opts.Logger.Debug("close database")
+
+	if s.opts.Tenants != nil {
+		err = s.opts.Tenants.Close()
+		if err != nil {
+			return err
+		}
+		s.opts.Logger.Debug("close tenants")
+	}
+
+	if s.opts.Journal != nil {
+		err = s.opts.Journal.Close()
+		if err != nil {
+			return err
+		}
+		s.opts.Logger.Debug("close journal")
+	}
 
 	s.wg.Wait()
 	return nil
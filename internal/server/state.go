@@ -1,9 +1,12 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/nalgeon/redka"
 	"github.com/nalgeon/redka/internal/command"
 	"github.com/tidwall/redcon"
 )
@@ -25,8 +28,14 @@ func getState(conn redcon.Conn) *connState {
 
 // connState represents the connection state.
 type connState struct {
-	inMulti bool
-	cmds    []command.Cmd
+	id        int64 // client ID (assigned by HELLO)
+	inMulti   bool
+	cmds      []command.Cmd
+	watches   []watch         // watched keys and patterns (see WATCH and PWATCH)
+	replPort  string          // listening port reported by a replica
+	tenant    *redka.DB       // selected tenant (nil for the main database)
+	ctx       context.Context // traced command context (nil if not traced)
+	lastWrite time.Time       // time of the last write (see Options.PinAfterWrite)
 }
 
 // push adds a command to the state.
@@ -44,6 +53,13 @@ func (s *connState) pop() command.Cmd {
 	return last
 }
 
+// watch is a watched key pattern with its changestamp
+// as of the WATCH command.
+type watch struct {
+	pattern string
+	stamp   uint64
+}
+
 // clear removes all commands from the state.
 func (s *connState) clear() {
 	s.cmds = []command.Cmd{}
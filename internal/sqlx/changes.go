@@ -0,0 +1,226 @@
+package sqlx
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+//go:embed changes.sql
+var sqlChangesSchema string
+
+const sqlChangesExist = `
+select count(*) from temp.sqlite_master
+where type = 'table' and name = 'rchange'`
+
+const sqlChangesSelect = `
+select op, key_id, key, type, field, before, after
+from temp.rchange
+order by id`
+
+const sqlChangesClear = `delete from temp.rchange`
+
+const sqlChangesExpired = `
+update temp.rchange set op = 'expired'
+where op = 'delete' and field is null and before is null
+  and key_id in (:ids)`
+
+// changeCreate is the logged op for the created keys.
+// The creations are not reported as changes, but listed
+// separately in the event (see core.ChangeEvent.Created).
+const changeCreate = core.ChangeOp("create")
+
+const sqlOutboxInsert = `
+insert into routbox (time, data)
+values (?, ?)`
+
+// Changes captures the changes made by writable transactions
+// and delivers them to the subscribers after commit.
+// Optionally records them in the outbox table as part
+// of the same transaction.
+//
+// The changes are recorded by temporary triggers, so the capture
+// costs nothing until the first subscriber or enabling the outbox.
+// Safe for concurrent use.
+type Changes struct {
+	mu      sync.RWMutex
+	subs    []func(core.ChangeEvent)
+	enabled atomic.Bool
+	outbox  atomic.Bool
+}
+
+// Subscribe registers a function to be called
+// with the changes made by each committed transaction.
+func (c *Changes) Subscribe(fn func(core.ChangeEvent)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subs = append(c.subs, fn)
+	c.enabled.Store(true)
+}
+
+// EnableOutbox enables recording the changes in the outbox table.
+func (c *Changes) EnableOutbox() {
+	c.outbox.Store(true)
+	c.enabled.Store(true)
+}
+
+// Enabled reports whether the changes are captured.
+func (c *Changes) Enabled() bool {
+	return c != nil && c.enabled.Load()
+}
+
+// begin prepares the transaction for change capture.
+func (c *Changes) begin(tx Tx) error {
+	var count int
+	if err := tx.QueryRow(sqlChangesExist).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		// The connection is new, create the triggers.
+		_, err := tx.Exec(sqlChangesSchema)
+		return err
+	}
+	// Discard the changes made outside of capturing transactions.
+	_, err := tx.Exec(sqlChangesClear)
+	return err
+}
+
+// MarkExpired reports the deletions of the keys with the given IDs
+// made by the transaction as expirations (core.ChangeExpired).
+// Does nothing if the changes are not captured.
+func MarkExpired(tx Tx, ids []int) error {
+	var count int
+	if err := tx.QueryRow(sqlChangesExist).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	query, args := ExpandIn(sqlChangesExpired, ":ids", ids)
+	_, err := tx.Exec(query, args...)
+	return err
+}
+
+// collect returns the changes made by the transaction,
+// records them in the outbox (if enabled) and clears the change log.
+func (c *Changes) collect(tx Tx) (core.ChangeEvent, error) {
+	changes, err := Select(tx, sqlChangesSelect, nil, scanChange)
+	if err != nil {
+		return core.ChangeEvent{}, err
+	}
+	if len(changes) == 0 {
+		return core.ChangeEvent{}, nil
+	}
+	if _, err := tx.Exec(sqlChangesClear); err != nil {
+		return core.ChangeEvent{}, err
+	}
+
+	// Child rows deleted along with the key may not know
+	// the key name, so take it from the key deletion.
+	names := map[int]string{}
+	for _, ch := range changes {
+		if ch.Key != "" {
+			names[ch.id] = ch.Key
+		}
+	}
+	var event core.ChangeEvent
+	for _, ch := range changes {
+		if ch.Op == changeCreate {
+			event.Created = append(event.Created, ch.Key)
+			continue
+		}
+		if ch.Key == "" {
+			ch.Key = names[ch.id]
+		}
+		event.Changes = append(event.Changes, ch.Change)
+	}
+
+	if c.outbox.Load() && len(event.Changes) > 0 {
+		data, err := marshalChanges(event.Changes)
+		if err != nil {
+			return core.ChangeEvent{}, err
+		}
+		if _, err := tx.Exec(sqlOutboxInsert, time.Now().UnixMilli(), data); err != nil {
+			return core.ChangeEvent{}, err
+		}
+	}
+	return event, nil
+}
+
+// publish delivers the changes to the subscribers.
+func (c *Changes) publish(event core.ChangeEvent) {
+	if len(event.Changes) == 0 && len(event.Created) == 0 {
+		return
+	}
+	c.mu.RLock()
+	subs := c.subs
+	c.mu.RUnlock()
+	for _, fn := range subs {
+		fn(event)
+	}
+}
+
+// keyChange is a change along with the key ID.
+type keyChange struct {
+	core.Change
+	id int
+}
+
+func scanChange(rows *sql.Rows) (keyChange, error) {
+	var ch keyChange
+	var key, field sql.NullString
+	var before, after any
+	err := rows.Scan(&ch.Op, &ch.id, &key, &ch.Type, &field, &before, &after)
+	if err != nil {
+		return ch, err
+	}
+	ch.Key = key.String
+	ch.Field = field.String
+	ch.Before = changeValue(before)
+	ch.After = changeValue(after)
+	return ch, nil
+}
+
+// changeValue converts a logged column value to a core.Value.
+func changeValue(v any) core.Value {
+	switch v := v.(type) {
+	case []byte:
+		return core.Value(v)
+	case string:
+		return core.Value(v)
+	case int64:
+		return core.Value(strconv.FormatInt(v, 10))
+	case float64:
+		return core.Value(strconv.FormatFloat(v, 'f', -1, 64))
+	}
+	return nil
+}
+
+// marshalChanges encodes the changes as JSON
+// in the format used by the outbox.
+func marshalChanges(changes []core.Change) ([]byte, error) {
+	type change struct {
+		Op     core.ChangeOp `json:"op"`
+		Key    string        `json:"key"`
+		Type   string        `json:"type"`
+		Field  string        `json:"field,omitempty"`
+		Before []byte        `json:"before"`
+		After  []byte        `json:"after"`
+	}
+	event := struct {
+		Changes []change `json:"changes"`
+	}{make([]change, len(changes))}
+	for i, ch := range changes {
+		event.Changes[i] = change{
+			Op: ch.Op, Key: ch.Key, Type: ch.TypeName(), Field: ch.Field,
+			Before: ch.Before, After: ch.After,
+		}
+	}
+	return json.Marshal(event)
+}
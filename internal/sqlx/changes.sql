@@ -0,0 +1,156 @@
+-- Change capture. Temporary objects live in the connection,
+-- so they are created only when change capture is enabled.
+
+create temp table if not exists
+rchange (
+    id     integer primary key,
+    op     text not null,
+    key_id integer not null,
+    key    text,
+    type   integer not null,
+    field  blob,
+    before,
+    after
+);
+
+-- keys
+create temp trigger if not exists
+rchange_rkey_create
+after insert on main.rkey
+for each row
+begin
+    insert into rchange (op, key_id, key, type)
+    values ('create', new.id, new.key, new.type);
+end;
+
+create temp trigger if not exists
+rchange_rkey_insert
+after insert on main.rkey
+for each row
+when new.etime is not null
+begin
+    insert into rchange (op, key_id, key, type, before, after)
+    values ('expire', new.id, new.key, new.type, null, new.etime);
+end;
+
+create temp trigger if not exists
+rchange_rkey_rename
+after update of key on main.rkey
+for each row
+when old.key is not new.key
+begin
+    insert into rchange (op, key_id, key, type, before, after)
+    values ('rename', new.id, old.key, new.type, old.key, new.key);
+end;
+
+create temp trigger if not exists
+rchange_rkey_expire
+after update of etime on main.rkey
+for each row
+when old.etime is not new.etime
+begin
+    insert into rchange (op, key_id, key, type, before, after)
+    values ('expire', new.id, new.key, new.type, old.etime, new.etime);
+end;
+
+create temp trigger if not exists
+rchange_rkey_delete
+before delete on main.rkey
+for each row
+begin
+    insert into rchange (op, key_id, key, type)
+    values ('delete', old.id, old.key, old.type);
+end;
+
+-- strings
+create temp trigger if not exists
+rchange_rstring_insert
+after insert on main.rstring
+for each row
+begin
+    insert into rchange (op, key_id, key, type, before, after)
+    values ('set', new.key_id, (select key from main.rkey where id = new.key_id),
+            1, null, new.value);
+end;
+
+create temp trigger if not exists
+rchange_rstring_update
+after update on main.rstring
+for each row
+begin
+    insert into rchange (op, key_id, key, type, before, after)
+    values ('set', new.key_id, (select key from main.rkey where id = new.key_id),
+            1, old.value, new.value);
+end;
+
+create temp trigger if not exists
+rchange_rstring_delete
+after delete on main.rstring
+for each row
+begin
+    insert into rchange (op, key_id, key, type, before, after)
+    values ('delete', old.key_id, (select key from main.rkey where id = old.key_id),
+            1, old.value, null);
+end;
+
+-- hashes
+create temp trigger if not exists
+rchange_rhash_insert
+after insert on main.rhash
+for each row
+begin
+    insert into rchange (op, key_id, key, type, field, before, after)
+    values ('set', new.key_id, (select key from main.rkey where id = new.key_id),
+            4, new.field, null, new.value);
+end;
+
+create temp trigger if not exists
+rchange_rhash_update
+after update on main.rhash
+for each row
+begin
+    insert into rchange (op, key_id, key, type, field, before, after)
+    values ('set', new.key_id, (select key from main.rkey where id = new.key_id),
+            4, new.field, old.value, new.value);
+end;
+
+create temp trigger if not exists
+rchange_rhash_delete
+after delete on main.rhash
+for each row
+begin
+    insert into rchange (op, key_id, key, type, field, before, after)
+    values ('delete', old.key_id, (select key from main.rkey where id = old.key_id),
+            4, old.field, old.value, null);
+end;
+
+-- sorted sets
+create temp trigger if not exists
+rchange_rzset_insert
+after insert on main.rzset
+for each row
+begin
+    insert into rchange (op, key_id, key, type, field, before, after)
+    values ('set', new.key_id, (select key from main.rkey where id = new.key_id),
+            5, new.elem, null, new.score);
+end;
+
+create temp trigger if not exists
+rchange_rzset_update
+after update on main.rzset
+for each row
+begin
+    insert into rchange (op, key_id, key, type, field, before, after)
+    values ('set', new.key_id, (select key from main.rkey where id = new.key_id),
+            5, new.elem, old.score, new.score);
+end;
+
+create temp trigger if not exists
+rchange_rzset_delete
+after delete on main.rzset
+for each row
+begin
+    insert into rchange (op, key_id, key, type, field, before, after)
+    values ('delete', old.key_id, (select key from main.rkey where id = old.key_id),
+            5, old.elem, old.score, null);
+end;
@@ -0,0 +1,57 @@
+package sqlx
+
+import "time"
+
+// Clock returns the current time.
+type Clock func() time.Time
+
+// envTx is a transaction with a custom clock, limits,
+// quotas, random source and trash retention.
+type envTx struct {
+	Tx
+	clock  Clock
+	limits *Limits
+	quotas *Quotas
+	// quotaKeys are the keys already checked
+	// against the quotas in the transaction.
+	quotaKeys map[string]struct{}
+	rand      *Rand
+	trash     time.Duration
+	// collation is the collation of the keys,
+	// hash fields and set elements.
+	collation string
+}
+
+// Now returns the current time according to the transaction
+// clock (see DB.Clock), or the wall clock if there is none.
+// The repositories use it for expiration and modification times.
+func Now(tx Tx) time.Time {
+	if etx, ok := tx.(*envTx); ok && etx.clock != nil {
+		return etx.clock()
+	}
+	return time.Now()
+}
+
+// Now returns the current time according to the repository clock.
+func (d *DB[T]) Now() time.Time {
+	if d.Clock == nil {
+		return time.Now()
+	}
+	return d.Clock()
+}
+
+// Wrap returns a transaction that prefixes the table names and uses
+// the repository clock, limits, quotas, random source, trash retention
+// and collation (if any).
+func (d *DB[T]) Wrap(tx Tx) Tx {
+	tx = Wrap(tx, d.Names)
+	limits := d.Limits.Load()
+	if d.Clock == nil && limits == nil && d.Quotas == nil && d.Rand == nil &&
+		d.Trash == 0 && d.Collation == "" {
+		return tx
+	}
+	return &envTx{
+		Tx: tx, clock: d.Clock, limits: limits, quotas: d.Quotas,
+		rand: d.Rand, trash: d.Trash, collation: d.Collation,
+	}
+}
@@ -0,0 +1,84 @@
+package sqlx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Built-in SQLite collations.
+const (
+	CollationBinary = "binary"
+	CollationNocase = "nocase"
+	CollationRtrim  = "rtrim"
+)
+
+// featureCollation is recorded in the databases
+// that use a collation other than binary.
+const featureCollation = "collation"
+
+// sqlCollationCheck fails if the collation is not registered.
+const sqlCollationCheck = `select 'a' = 'a' collate %s`
+
+// collationRE matches valid collation names.
+var collationRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// CollationOf returns the transaction collation (see DB.Collation),
+// or an empty string if the collation is binary.
+func CollationOf(tx Tx) string {
+	if etx, ok := tx.(*envTx); ok {
+		return etx.collation
+	}
+	return ""
+}
+
+// collate returns the schema query with the binary collation
+// of the keys, hash fields and set elements replaced by the
+// repository one.
+func (d *DB[T]) collate(query string) string {
+	if d.Collation == "" {
+		return query
+	}
+	return strings.ReplaceAll(query, "collate binary", "collate "+d.Collation)
+}
+
+// initCollation sets the repository collation: the requested one
+// for a new database, or the one recorded in an existing database.
+// Fails if they do not match, or if the collation is not registered
+// in the driver.
+func (d *DB[T]) initCollation(requested string) error {
+	requested = strings.ToLower(requested)
+	if requested != "" && !collationRE.MatchString(requested) {
+		return fmt.Errorf("%w: invalid collation %q", ErrIncompatible, requested)
+	}
+
+	isNew, err := d.isNew()
+	if err != nil {
+		return err
+	}
+	collation := requested
+	if !isNew {
+		fp, err := d.Fingerprint()
+		if err != nil {
+			return err
+		}
+		if requested != "" && requested != fp.Collation {
+			return fmt.Errorf("%w: database uses %s collation, requested %s",
+				ErrIncompatible, fp.Collation, requested)
+		}
+		collation = fp.Collation
+	}
+	if collation == CollationBinary {
+		collation = ""
+	}
+
+	switch collation {
+	case "", CollationNocase, CollationRtrim:
+	default:
+		if _, err := d.SQL.Exec(fmt.Sprintf(sqlCollationCheck, collation)); err != nil {
+			return fmt.Errorf("%w: collation %s: %v", ErrIncompatible, collation, err)
+		}
+	}
+	d.Collation = collation
+	return nil
+}
@@ -0,0 +1,66 @@
+package sqlx
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Repos are the names of the repositories (the operation
+// name prefixes) counted in Counters.RepoOps.
+var Repos = [...]string{"Key", "Str", "Hash", "SortedSet", "Stream", "HLL"}
+
+// Counters count the repository operations.
+// Safe for concurrent use.
+type Counters struct {
+	// Ops is the number of repository operations.
+	Ops atomic.Int64
+	// RepoOps is the number of operations
+	// per repository, in the order of Repos.
+	RepoOps [len(Repos)]atomic.Int64
+	// Hits and Misses are the numbers of value
+	// lookups that found and did not find the value.
+	Hits   atomic.Int64
+	Misses atomic.Int64
+	// Retries is the number of retried write transactions.
+	Retries atomic.Int64
+	// Commits and Rollbacks are the numbers of committed
+	// and rolled back write transactions.
+	Commits   atomic.Int64
+	Rollbacks atomic.Int64
+	// Scanners is the number of open (not exhausted) scanners.
+	Scanners atomic.Int64
+}
+
+// countOp counts the operation with the given name.
+func (c *Counters) countOp(name string) {
+	c.Ops.Add(1)
+	repo, _, _ := strings.Cut(name, ".")
+	if i := slices.Index(Repos[:], repo); i >= 0 {
+		c.RepoOps[i].Add(1)
+	}
+}
+
+// Lookup counts a value lookup as a hit or a miss.
+func (d *DB[T]) Lookup(found bool) {
+	if d.Counters == nil {
+		return
+	}
+	if found {
+		d.Counters.Hits.Add(1)
+	} else {
+		d.Counters.Misses.Add(1)
+	}
+}
+
+// OpenScanner counts an open scanner. Returns a function
+// that counts it as closed (the function is idempotent).
+func (d *DB[T]) OpenScanner() func() {
+	if d.Counters == nil {
+		return func() {}
+	}
+	c := d.Counters
+	c.Scanners.Add(1)
+	return sync.OnceFunc(func() { c.Scanners.Add(-1) })
+}
@@ -0,0 +1,97 @@
+package sqlx
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const sqlGenerationNext = `
+insert into rmeta (name, value) values (?, '1')
+on conflict (name) do update set value = cast(value as integer) + 1`
+
+// metaGeneration is the name of the rowid generation
+// entry in the rmeta table.
+const metaGeneration = "generation"
+
+// The scan cursor holds the rowid of the last scanned row
+// in the lower 48 bits, and the rowid generation (modulo 128)
+// in the next 7 ones. The cursors of generation 0 are the rowids
+// themselves. The upper 8 bits are left for the shard index
+// (see redka.ShardKeys.Scan).
+const (
+	cursorIDBits  = 48
+	cursorIDMask  = 1<<cursorIDBits - 1
+	cursorGenMask = 1<<7 - 1
+)
+
+// Cursor is the position of a scan over the rows ordered by rowid.
+//
+// The scans return opaque cursors (see Cursor.Next) with the following
+// guarantees, like in Redis:
+//   - A row that exists from the start to the end of the scan
+//     is returned (at least once).
+//   - A row deleted before the scan gets to it is not returned.
+//   - A row inserted during the scan may or may not be returned.
+//
+// VACUUM may reassign the rowids of the tables without an integer
+// primary key (like the hash fields and set elements), so the cursor
+// also holds the rowid generation, which changes after such operations
+// (see DB.NextGeneration). A cursor from the previous generation
+// restarts the scan, so the rows may be returned more than once,
+// but none of them is skipped.
+type Cursor struct {
+	ID  int // rowid of the last scanned row (0 to start over)
+	gen int // rowid generation
+}
+
+// ParseCursor returns the position of the scan
+// for the cursor returned by a previous one.
+func ParseCursor(tx Tx, cursor int) (Cursor, error) {
+	gen, err := generation(tx)
+	if err != nil {
+		return Cursor{}, err
+	}
+	c := Cursor{ID: cursor & cursorIDMask, gen: gen & cursorGenMask}
+	if cursor < 0 || cursor>>cursorIDBits != c.gen {
+		// Invalid cursor or the rowids have changed
+		// since it was created, so start over.
+		c.ID = 0
+	}
+	return c, nil
+}
+
+// Next returns the cursor to continue the scan after the rowid,
+// or 0 if the scan is over (the rowid is 0).
+func (c Cursor) Next(id int) int {
+	if id == 0 {
+		return 0
+	}
+	return c.gen<<cursorIDBits | id&cursorIDMask
+}
+
+// NextGeneration changes the rowid generation, so that the existing
+// scan cursors start over. Call it after the operations that may
+// reassign the rowids (like VACUUM).
+func (d *DB[T]) NextGeneration() error {
+	_, err := d.Conn().Exec(sqlGenerationNext, metaGeneration)
+	return err
+}
+
+// generation returns the current rowid generation.
+func generation(tx Tx) (int, error) {
+	var value string
+	err := tx.QueryRow(sqlMetaGet, metaGeneration).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			// A read-only database with an older schema.
+			return 0, nil
+		}
+		return 0, err
+	}
+	return strconv.Atoi(value)
+}
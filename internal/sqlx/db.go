@@ -5,20 +5,48 @@ import (
 	"database/sql"
 	_ "embed"
 	"sync"
+	"time"
 )
 
-// Database schema version.
-// const schemaVersion = 1
-
-// Default SQL settings.
+// Default SQL settings. The busy timeout is set explicitly,
+// because drivers use different defaults (e.g. 5s for mattn/go-sqlite3,
+// none for modernc.org/sqlite).
 const sqlSettings = `
 pragma journal_mode = wal;
 pragma synchronous = normal;
 pragma temp_store = memory;
 pragma mmap_size = 268435456;
 pragma foreign_keys = on;
+pragma busy_timeout = 5000;
+`
+
+// SQL settings for in-memory databases. WAL is not available
+// for shared-cache memory databases, and there is nothing
+// to sync or memory-map.
+const sqlMemorySettings = `
+pragma journal_mode = memory;
+pragma synchronous = off;
+pragma temp_store = memory;
+pragma foreign_keys = on;
+pragma busy_timeout = 5000;
 `
 
+// SQL settings for read-only databases. The journal mode
+// is left as is, because changing it is a write.
+const sqlReadOnlySettings = `
+pragma temp_store = memory;
+pragma mmap_size = 268435456;
+pragma foreign_keys = on;
+pragma busy_timeout = 5000;
+pragma query_only = on;
+`
+
+// sqlStartRead starts a read transaction.
+const sqlStartRead = `select count(*) from sqlite_schema`
+
+// sqlUserVersion marks the database as a Redka database.
+const sqlUserVersion = `pragma user_version = 1`
+
 //go:embed schema.sql
 var sqlSchema string
 
@@ -28,14 +56,128 @@ type DB[T any] struct {
 	SQL *sql.DB
 	// newT creates a new domain-specific transaction.
 	newT func(Tx) T
+	// Changes captures the changes made by writable transactions.
+	// If nil, the changes are not captured.
+	Changes *Changes
+	// Names maps the table names to the actual names in the database.
+	// If nil, the names are used as is.
+	Names *Names
+	// Replicas serve the read-only queries made outside
+	// of transactions. If nil, all queries go to SQL.
+	Replicas *Replicas
+	// Retry retries the write transactions when the database
+	// is busy. If nil, the transactions are not retried.
+	Retry *RetryPolicy
+	// Counters count the operations, lookups, transactions and scanners.
+	// If nil, nothing is counted.
+	Counters *Counters
+	// Writer executes the write transactions one by one.
+	// If nil, the transactions are executed by the callers.
+	Writer *Writer
+	// Hooks observe the repository operations (see Observe).
+	// If nil, the operations are not observed.
+	Hooks *Hooks
+	// Clock is the source of the current time for expiration
+	// and modification times (see Now). If nil, uses time.Now.
+	Clock Clock
+	// Limits restrict the size of the written keys and values.
+	// If nil, there are no limits besides the SQLite ones.
+	Limits *LimitsVar
+	// Quotas limit the keys with specific prefixes.
+	// If nil, there are no quotas.
+	Quotas *Quotas
+	// Rand is the source of random numbers (like for picking
+	// a random key). If nil, uses SQLite's random().
+	Rand *Rand
+	// History records the versions of the keys after
+	// each write transaction. If nil, nothing is recorded.
+	History *History
+	// Trash is how long the deleted keys are kept in the trash,
+	// so that they can be restored. If zero, the keys are deleted
+	// right away.
+	Trash time.Duration
+	// Reader is the pool of read-only connections for the queries
+	// made outside of transactions (see ReadConn) and the snapshot
+	// transactions (see ViewSnapshot). If nil, uses SQL.
+	Reader *sql.DB
+	// Collation is the collation of the keys, hash fields and set
+	// elements (see NewGlob). If empty, the collation is binary.
+	Collation string
+	// ctx is the context of the queries and transactions
+	// (see WithContext). If nil, uses context.Background.
+	ctx context.Context
 	sync.Mutex
 }
 
+// Options configures the database-backed repository.
+type Options struct {
+	// Names maps the table names (see DB.Names).
+	Names *Names
+	// Memory enables the settings suitable
+	// for an in-memory database.
+	Memory bool
+	// Shared means the connection pool is owned by the caller,
+	// so the connection properties are left as is.
+	Shared bool
+	// ManualMigrations disables migrating the schema of
+	// an existing database on open (see DB.Migrate).
+	ManualMigrations bool
+	// ReadOnly disables the writes. The schema is neither
+	// created nor migrated, so the database must exist.
+	ReadOnly bool
+	// Pragmas are set after the default settings,
+	// in the form of "name = value".
+	Pragmas []string
+	// Collation is the collation of the keys, hash fields and set
+	// elements in a new database: binary, nocase, rtrim, or the one
+	// registered in the driver. An existing database keeps the one
+	// it was created with. If empty, uses binary.
+	Collation string
+	// MaxOpenConns and MaxIdleConns limit the connection pool.
+	// If MaxOpenConns is zero, uses a single connection.
+	MaxOpenConns int
+	MaxIdleConns int
+}
+
+// Settings returns the connection settings (a semicolon-separated
+// list of pragmas) for the given options.
+func Settings(opts Options) string {
+	settings := sqlSettings
+	switch {
+	case opts.ReadOnly:
+		settings = sqlReadOnlySettings
+	case opts.Memory:
+		settings = sqlMemorySettings
+	}
+	for _, pragma := range opts.Pragmas {
+		settings += "pragma " + pragma + ";\n"
+	}
+	return settings
+}
+
 // Open creates a new database-backed repository.
-// Creates the database schema if necessary.
-func Open[T any](db *sql.DB, newT func(Tx) T) (*DB[T], error) {
+// Sets the connection properties and creates or migrates
+// the database schema if necessary. Fails with ErrSchemaVersion
+// or ErrIncompatible if the database cannot be used by the
+// current code (see DB.Fingerprint).
+func Open[T any](db *sql.DB, newT func(Tx) T, opts Options) (*DB[T], error) {
 	d := New(db, newT)
-	err := d.init()
+	d.Names = opts.Names
+	if !opts.Shared {
+		if err := d.init(opts); err != nil {
+			return d, err
+		}
+	}
+	if err := d.checkCompat(); err != nil {
+		return d, err
+	}
+	if err := d.initCollation(opts.Collation); err != nil {
+		return d, err
+	}
+	if opts.ReadOnly {
+		return d, nil
+	}
+	err := d.migrateLatest(opts.ManualMigrations)
 	return d, err
 }
 
@@ -46,9 +188,78 @@ func New[T any](db *sql.DB, newT func(Tx) T) *DB[T] {
 	return d
 }
 
+// WithContext returns a shallow copy of the repository
+// that executes the queries and transactions with ctx.
+func (d *DB[T]) WithContext(ctx context.Context) *DB[T] {
+	return &DB[T]{
+		SQL:       d.SQL,
+		newT:      d.newT,
+		Changes:   d.Changes,
+		Names:     d.Names,
+		Replicas:  d.Replicas,
+		Retry:     d.Retry,
+		Counters:  d.Counters,
+		Writer:    d.Writer,
+		Hooks:     d.Hooks,
+		Clock:     d.Clock,
+		Limits:    d.Limits,
+		Quotas:    d.Quotas,
+		Rand:      d.Rand,
+		Trash:     d.Trash,
+		History:   d.History,
+		Reader:    d.Reader,
+		Collation: d.Collation,
+		ctx:       ctx,
+	}
+}
+
+// Primary returns a copy of the repository that
+// reads from the primary database instead of the replicas.
+func (d *DB[T]) Primary() *DB[T] {
+	c := d.WithContext(d.ctx)
+	c.Replicas = nil
+	return c
+}
+
+// context returns the repository context.
+func (d *DB[T]) context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
+// Context returns the repository context
+// (context.Background if there is none).
+func (d *DB[T]) Context() context.Context {
+	return d.context()
+}
+
+// Observe counts the repository operation with the given name
+// and keys, and calls the Before hooks. Call [Op.Done] with
+// the result of the operation to call the After hooks.
+// Returns nil if there are no hooks.
+func (d *DB[T]) Observe(name string, keys ...string) *Op {
+	if d.Counters != nil {
+		d.Counters.countOp(name)
+	}
+	return d.Hooks.start(d.context(), name, keys)
+}
+
+// In returns a copy of the repository that executes the queries
+// on behalf of the operation, so they count towards its
+// RowsAffected and SQLDuration. Returns the repository itself
+// if op is nil (there are no hooks).
+func (d *DB[T]) In(op *Op) *DB[T] {
+	if op == nil {
+		return d
+	}
+	return d.WithContext(op.ctx)
+}
+
 // Update executes a function within a writable transaction.
 func (d *DB[T]) Update(f func(tx T) error) error {
-	return d.UpdateContext(context.Background(), f)
+	return d.UpdateContext(d.context(), f)
 }
 
 // UpdateContext executes a function within a writable transaction.
@@ -58,7 +269,7 @@ func (d *DB[T]) UpdateContext(ctx context.Context, f func(tx T) error) error {
 
 // View executes a function within a read-only transaction.
 func (d *DB[T]) View(f func(tx T) error) error {
-	return d.ViewContext(context.Background(), f)
+	return d.ViewContext(d.context(), f)
 }
 
 // ViewContext executes a function within a read-only transaction.
@@ -66,8 +277,35 @@ func (d *DB[T]) ViewContext(ctx context.Context, f func(tx T) error) error {
 	return d.execTx(ctx, false, f)
 }
 
-// Init sets the connection properties and creates the necessary tables.
-func (d *DB[T]) init() error {
+// ViewSnapshot executes a function within a read-only transaction
+// on a connection from the read pool (see DB.Reader), so it does not
+// block the writes. The transaction sees a consistent snapshot of
+// the database as of the start of the call.
+func (d *DB[T]) ViewSnapshot(f func(tx T) error) error {
+	return d.ViewSnapshotContext(d.context(), f)
+}
+
+// ViewSnapshotContext is like ViewSnapshot, but with a context.
+func (d *DB[T]) ViewSnapshotContext(ctx context.Context, f func(tx T) error) error {
+	if d.Reader == nil {
+		return d.viewTx(ctx, f)
+	}
+	dtx, err := d.Reader.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = dtx.Rollback() }()
+	// SQLite starts the read transaction on the first query,
+	// so make one right away to take the snapshot now.
+	var n int
+	if err := dtx.QueryRowContext(ctx, sqlStartRead).Scan(&n); err != nil {
+		return err
+	}
+	return f(d.newT(d.Wrap(withStats(ctx, &ctxTx{ctx: ctx, q: dtx}))))
+}
+
+// init sets the connection properties.
+func (d *DB[T]) init(opts Options) error {
 	// SQLite only allows one writer at a time, so concurrent writes
 	// will fail with a "database is locked" (SQLITE_BUSY) error.
 	//
@@ -83,17 +321,54 @@ func (d *DB[T]) init() error {
 	//
 	// Due to the significant p50 response time mutex penalty for SET,
 	// I've decided to use the max connections approach for now.
-	d.SQL.SetMaxOpenConns(1)
-	if _, err := d.SQL.Exec(sqlSettings); err != nil {
-		return err
+	//
+	// More connections are only allowed when requested explicitly
+	// (see Options.MaxOpenConns). In this case, the caller should apply
+	// the settings to each new connection.
+	d.SQL.SetMaxOpenConns(max(opts.MaxOpenConns, 1))
+	if opts.MaxIdleConns > 0 {
+		d.SQL.SetMaxIdleConns(opts.MaxIdleConns)
 	}
-	if _, err := d.SQL.Exec(sqlSchema); err != nil {
+	if _, err := d.SQL.Exec(Settings(opts)); err != nil {
 		return err
 	}
-	return nil
+	if opts.ReadOnly {
+		return nil
+	}
+	_, err := d.SQL.Exec(sqlUserVersion)
+	return err
+}
+
+// Conn returns a handle to execute queries
+// outside of an explicit transaction.
+func (d *DB[T]) Conn() Tx {
+	if d.ctx != nil {
+		return d.Wrap(withStats(d.ctx, &ctxTx{ctx: d.ctx, q: d.SQL}))
+	}
+	return d.Wrap(d.SQL)
+}
+
+// ReadConn is like Conn, but for the read-only queries.
+// Uses one of the read replicas if there are any
+// fresh enough, or the read pool (see DB.Reader),
+// or the primary database otherwise.
+func (d *DB[T]) ReadConn() Tx {
+	db := d.Replicas.Pick()
+	if db == nil {
+		db = d.Reader
+	}
+	if db == nil {
+		return d.Conn()
+	}
+	if d.ctx != nil {
+		return d.Wrap(withStats(d.ctx, &ctxTx{ctx: d.ctx, q: db}))
+	}
+	return d.Wrap(db)
 }
 
 // execTx executes a function within a transaction.
+// Writable transactions go through the writer (if any),
+// and are retried if the database is busy.
 func (d *DB[T]) execTx(ctx context.Context, writable bool, f func(tx T) error) error {
 	// See the init method for the explanation of the single writer rule.
 	// if writable {
@@ -102,16 +377,109 @@ func (d *DB[T]) execTx(ctx context.Context, writable bool, f func(tx T) error) e
 	// 	defer d.Unlock()
 	// }
 
+	if !writable {
+		return d.viewTx(ctx, f)
+	}
+	err := d.Writer.Do(ctx, func() error {
+		first := true
+		return d.Retry.Do(ctx, func() error {
+			if !first && d.Counters != nil {
+				d.Counters.Retries.Add(1)
+			}
+			first = false
+			return d.updateTx(ctx, f)
+		})
+	})
+	return TypedError(err)
+}
+
+// viewTx executes a function within a read-only transaction.
+func (d *DB[T]) viewTx(ctx context.Context, f func(tx T) error) error {
 	dtx, err := d.SQL.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = dtx.Rollback() }()
+	return f(d.newT(d.Wrap(withStats(ctx, &ctxTx{ctx: ctx, q: dtx}))))
+}
 
-	tx := d.newT(dtx)
+// updateTx executes a function within a writable transaction.
+// The transaction starts with BEGIN IMMEDIATE, so it acquires
+// the write lock upfront (or fails right away if the database
+// is busy), instead of failing in the middle of the transaction
+// when upgrading from a read lock. The database/sql package
+// does not support immediate transactions, so the transaction
+// is managed manually on a dedicated connection.
+func (d *DB[T]) updateTx(ctx context.Context, f func(tx T) error) error {
+	conn, err := d.SQL.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	restore, err := overrideDurability(ctx, conn)
+	if err != nil {
+		return err
+	}
+	defer restore()
+	if _, err := conn.ExecContext(ctx, "begin immediate"); err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			_, _ = conn.ExecContext(context.Background(), "rollback")
+			if d.Counters != nil {
+				d.Counters.Rollbacks.Add(1)
+			}
+		}
+	}()
+	commit := func() error {
+		if _, err := conn.ExecContext(ctx, "commit"); err != nil {
+			return err
+		}
+		committed = true
+		if d.Counters != nil {
+			d.Counters.Commits.Add(1)
+		}
+		d.Replicas.wrote()
+		return nil
+	}
+
+	stx := newStmtTx(ctx, conn)
+	defer stx.close()
+	wtx := d.Wrap(withStats(ctx, stx))
+	capture := d.Changes.Enabled()
+	// The history is recorded from the captured changes.
+	history := d.History != nil && d.Changes != nil
+	if capture || history {
+		if err := d.Changes.begin(wtx); err != nil {
+			return err
+		}
+	}
+
+	tx := d.newT(wtx)
 	err = f(tx)
 	if err != nil {
 		return err
 	}
-	return dtx.Commit()
+	if !capture && !history {
+		return commit()
+	}
+
+	event, err := d.Changes.collect(wtx)
+	if err != nil {
+		return err
+	}
+	if history {
+		if err := d.History.record(wtx, event.Changes); err != nil {
+			return err
+		}
+	}
+	if err := commit(); err != nil {
+		return err
+	}
+	if capture {
+		d.Changes.publish(event)
+	}
+	return nil
 }
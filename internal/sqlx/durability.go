@@ -0,0 +1,88 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+const sqlSynchronous = `pragma synchronous`
+
+// Durability defines when the committed writes reach the disk.
+// See https://sqlite.org/pragma.html#pragma_synchronous for details.
+type Durability string
+
+// Durability levels.
+const (
+	// DurabilityFull syncs the WAL on each commit, so committed
+	// transactions survive a power loss.
+	DurabilityFull Durability = "full"
+	// DurabilityNormal syncs the WAL on checkpoints only, so the
+	// latest transactions may roll back after a power loss
+	// (but not after an application crash).
+	DurabilityNormal Durability = "normal"
+	// DurabilityOff never syncs, leaving it to the operating
+	// system. Fastest, but the database may get corrupted
+	// after a power loss.
+	DurabilityOff Durability = "off"
+)
+
+// Validate checks if the durability level is valid.
+func (d Durability) Validate() error {
+	switch d {
+	case DurabilityFull, DurabilityNormal, DurabilityOff:
+		return nil
+	default:
+		return fmt.Errorf("invalid durability %q", d)
+	}
+}
+
+// durabilityKey is the context key for the durability override.
+type durabilityKey struct{}
+
+// WithDurability returns a context that overrides the durability
+// of the write transactions executed with it.
+func WithDurability(ctx context.Context, d Durability) context.Context {
+	return context.WithValue(ctx, durabilityKey{}, d)
+}
+
+// durabilityFrom returns the durability override
+// from the context, or an empty string if there is none.
+func durabilityFrom(ctx context.Context) Durability {
+	d, _ := ctx.Value(durabilityKey{}).(Durability)
+	return d
+}
+
+// SetDurability sets the durability level of the connection.
+func SetDurability(db *sql.DB, d Durability) error {
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	_, err := db.Exec("pragma synchronous = " + string(d))
+	return err
+}
+
+// overrideDurability sets the durability level of the connection
+// if the context overrides it. Returns a function that restores
+// the previous level.
+func overrideDurability(ctx context.Context, conn *sql.Conn) (func(), error) {
+	d := durabilityFrom(ctx)
+	if d == "" {
+		return func() {}, nil
+	}
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	var prev int
+	if err := conn.QueryRowContext(ctx, sqlSynchronous).Scan(&prev); err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "pragma synchronous = "+string(d)); err != nil {
+		return nil, err
+	}
+	restore := func() {
+		query := fmt.Sprintf("pragma synchronous = %d", prev)
+		_, _ = conn.ExecContext(context.Background(), query)
+	}
+	return restore, nil
+}
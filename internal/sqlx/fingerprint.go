@@ -0,0 +1,239 @@
+package sqlx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sqlMetaGet = `select value from rmeta where name = ?`
+
+	sqlMetaInit = `insert or ignore into rmeta (name, value) values (?, ?)`
+
+	sqlMetaSet = `
+	insert into rmeta (name, value) values (?, ?)
+	on conflict (name) do update set value = excluded.value`
+
+	sqlTableColumns = `select name from pragma_table_info(?)`
+)
+
+// Names of the fingerprint entries in the rmeta table.
+const (
+	metaCreatedBy = "created_by"
+	metaCreatedAt = "created_at"
+	metaFeatures  = "features"
+	metaCollation = "collation"
+)
+
+// modulePath is the Redka module path in the build info.
+const modulePath = "github.com/nalgeon/redka"
+
+// keyColumns are the columns of the rkey table
+// that every version of the schema has.
+var keyColumns = []string{"id", "key", "type", "version", "etime", "mtime"}
+
+// ErrIncompatible is returned when the database file
+// cannot be used by the current code (e.g. it uses
+// features unknown to this version of Redka).
+var ErrIncompatible = errors.New("incompatible database")
+
+// Creator is the Redka version recorded in the new databases.
+var Creator = moduleVersion()
+
+// Features are the database features supported by the current code.
+// A feature is recorded in the database (see DB.AddFeature) when it
+// changes how the data is stored or interpreted, so that older
+// versions refuse to open the database instead of misreading it.
+var Features = []string{featureCollation}
+
+// Fingerprint describes the origin and the format of the database.
+type Fingerprint struct {
+	Schema    int       // schema version
+	CreatedBy string    // Redka version that created the database ("" if unknown)
+	CreatedAt time.Time // creation time (zero if unknown)
+	Features  []string  // features the data depends on
+	Collation string    // collation of the keys, hash fields and set elements
+}
+
+// Fingerprint returns the database fingerprint.
+func (d *DB[T]) Fingerprint() (Fingerprint, error) {
+	fp := Fingerprint{Collation: CollationBinary}
+	tx := d.Conn()
+	if ok, err := d.tableExists("rschema"); err != nil {
+		return fp, err
+	} else if ok {
+		if err := tx.QueryRow(sqlSchemaVersion).Scan(&fp.Schema); err != nil {
+			return fp, err
+		}
+	}
+	if ok, err := d.tableExists("rmeta"); err != nil || !ok {
+		return fp, err
+	}
+	meta := func(name string) (string, error) {
+		var value string
+		err := tx.QueryRow(sqlMetaGet, name).Scan(&value)
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return value, err
+	}
+	var err error
+	if fp.CreatedBy, err = meta(metaCreatedBy); err != nil {
+		return fp, err
+	}
+	createdAt, err := meta(metaCreatedAt)
+	if err != nil {
+		return fp, err
+	}
+	if msec, err := strconv.ParseInt(createdAt, 10, 64); err == nil {
+		fp.CreatedAt = time.UnixMilli(msec)
+	}
+	features, err := meta(metaFeatures)
+	if err != nil {
+		return fp, err
+	}
+	if features != "" {
+		fp.Features = strings.Split(features, ",")
+	}
+	collation, err := meta(metaCollation)
+	if err != nil {
+		return fp, err
+	}
+	if collation != "" {
+		fp.Collation = collation
+	}
+	return fp, nil
+}
+
+// AddFeature records a feature the data depends on,
+// so that versions that do not support it refuse
+// to open the database.
+func (d *DB[T]) AddFeature(name string) error {
+	if !slices.Contains(Features, name) {
+		return fmt.Errorf("%w: unknown feature %s", ErrIncompatible, name)
+	}
+	fp, err := d.Fingerprint()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(fp.Features, name) {
+		return nil
+	}
+	features := strings.Join(append(fp.Features, name), ",")
+	_, err = d.Conn().Exec(sqlMetaSet, metaFeatures, features)
+	return err
+}
+
+// checkCompat checks that the current code can use the database,
+// so that a mismatched file fails on open with a clear error
+// instead of failing later with an SQL one.
+func (d *DB[T]) checkCompat() error {
+	if err := d.checkKeyColumns(); err != nil {
+		return err
+	}
+	fp, err := d.Fingerprint()
+	if err != nil {
+		return err
+	}
+	creator := fp.CreatedBy
+	if creator == "" {
+		creator = "unknown version"
+	}
+	if fp.Schema > LatestVersion() {
+		return fmt.Errorf("%w: database has version %d (created by %s), latest known is %d",
+			ErrSchemaVersion, fp.Schema, creator, LatestVersion())
+	}
+	var unknown []string
+	for _, feature := range fp.Features {
+		if !slices.Contains(Features, feature) {
+			unknown = append(unknown, feature)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: database uses unsupported features: %s (created by %s)",
+			ErrIncompatible, strings.Join(unknown, ", "), creator)
+	}
+	return nil
+}
+
+// checkKeyColumns checks that the existing rkey table
+// (if any) is a Redka one.
+func (d *DB[T]) checkKeyColumns() error {
+	table := d.Names.Query("rkey")
+	rows, err := d.SQL.Query(sqlTableColumns, table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		columns = append(columns, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	for _, col := range keyColumns {
+		if !slices.Contains(columns, col) {
+			return fmt.Errorf("%w: table %s has no %s column (not a Redka database?)",
+				ErrIncompatible, table, col)
+		}
+	}
+	return nil
+}
+
+// initFingerprint records the creator and the collation
+// of a new database.
+func (d *DB[T]) initFingerprint() error {
+	tx := d.Conn()
+	if _, err := tx.Exec(sqlMetaInit, metaCreatedBy, Creator); err != nil {
+		return err
+	}
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if _, err := tx.Exec(sqlMetaInit, metaCreatedAt, now); err != nil {
+		return err
+	}
+	if d.Collation == "" {
+		return nil
+	}
+	if _, err := tx.Exec(sqlMetaInit, metaCollation, d.Collation); err != nil {
+		return err
+	}
+	return d.AddFeature(featureCollation)
+}
+
+// tableExists reports whether the table exists.
+func (d *DB[T]) tableExists(name string) (bool, error) {
+	var count int
+	err := d.SQL.QueryRow(sqlTableExists, d.Names.Query(name)).Scan(&count)
+	return count > 0, err
+}
+
+// moduleVersion returns the Redka version from the build info.
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "devel"
+}
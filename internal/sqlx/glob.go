@@ -0,0 +1,72 @@
+package sqlx
+
+import (
+	"strings"
+
+	"github.com/nalgeon/redka/internal/glob"
+)
+
+// Glob is a Redis pattern for matching the keys, hash fields
+// or set elements.
+//
+// The pattern is translated to the SQLite glob syntax (see
+// glob.Pattern.SQLite). Some patterns can't be translated exactly,
+// and SQLite's glob operator stops at the first NUL byte, so the
+// queries may select extra names (including those with NUL bytes
+// regardless of the pattern), and Match checks them in Go:
+//
+//	where (key glob :pattern or instr(cast(key as blob), x'00') > 0)
+//
+// The instr check only runs for the names the glob operator rejects.
+// Storing the names as blobs would not make it unnecessary: the glob
+// operator reads blobs as text too, so 'a\x00b' still matches 'a'.
+//
+// SQLite can't use an index for the glob operator with a bound
+// pattern, so the queries may also select the names in the prefix
+// range of the pattern (see Range) to avoid a full scan:
+//
+//	where key >= :lo and key < :hi and (key glob :pattern or ...)
+type Glob struct {
+	pattern glob.Pattern
+	arg     string
+	exact   bool // the SQLite pattern matches exactly like the Redis one
+	ranged  bool // the collation orders the names by bytes
+}
+
+// NewGlob creates a pattern that matches the names according
+// to the transaction collation. With nocase, the letters match
+// in any case. Other collations do not change the pattern.
+func NewGlob(tx Tx, pattern string) Glob {
+	collation := CollationOf(tx)
+	p := glob.Compile(pattern, collation == CollationNocase)
+	arg, exact := p.SQLite()
+	ranged := collation == "" || collation == CollationBinary || collation == CollationNocase
+	return Glob{pattern: p, arg: arg, exact: exact, ranged: ranged}
+}
+
+// Arg returns the pattern for the glob operator.
+func (g Glob) Arg() string {
+	return g.arg
+}
+
+// Range returns the bounds of the names that start with the
+// literal prefix of the pattern (see glob.Pattern.Range).
+// Fails if the pattern does not start with a literal,
+// or the collation does not order the names by bytes
+// (other than the binary and nocase ones).
+func (g Glob) Range() (lo, hi string, ok bool) {
+	if !g.ranged {
+		return "", "", false
+	}
+	return g.pattern.Range()
+}
+
+// Match reports whether the name selected by the query
+// matches the pattern.
+func (g Glob) Match(name string) bool {
+	if g.exact && strings.IndexByte(name, 0) < 0 {
+		// Already matched by SQLite.
+		return true
+	}
+	return g.pattern.Match(name)
+}
@@ -0,0 +1,263 @@
+package sqlx
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/glob"
+)
+
+const sqlHistoryKey = `
+select id, type, version, etime from rkey
+where key = ? and (etime is null or etime > ?)`
+
+const sqlHistoryLast = `
+select type, version, etime from rhistory
+where key = ?
+order by id desc limit 1`
+
+const sqlHistoryAdd = `
+insert into rhistory (key, type, version, etime, time)
+values (?, ?, ?, ?, ?)`
+
+// Copies the value of any type: a string value,
+// hash fields and values, or sorted set elements and scores.
+const sqlHistoryAddValue = `
+insert into rhistory_value (hist_id, field, value, score)
+select ?1, null, value, null from rstring where key_id = ?2
+union all
+select ?1, field, value, null from rhash where key_id = ?2
+union all
+select ?1, elem, null, score from rzset where key_id = ?2`
+
+const sqlHistoryTrim = `
+delete from rhistory
+where key = ?1 and id <= (
+  select id from rhistory where key = ?1
+  order by id desc limit 1 offset ?2
+)`
+
+// Deletes the versions recorded before the time, except
+// for the latest one of each key (it is still the version
+// as of the time), unless it is a deletion.
+const sqlHistoryPrune = `
+delete from rhistory
+where time < ?1 and (
+  id < (
+    select max(id) from rhistory as h
+    where h.key = rhistory.key and h.time < ?1
+  )
+  or type = 0 and id = (
+    select max(id) from rhistory as h
+    where h.key = rhistory.key
+  )
+)`
+
+const sqlRevisions = `
+select id, type, version, etime, time from rhistory
+where key = ?
+order by id desc`
+
+const sqlRevisionAt = `
+select id, type, version, etime, time from rhistory
+where key = ? and time <= ?
+order by id desc limit 1`
+
+const sqlRevisionValues = `
+select field, value, score from rhistory_value
+where hist_id = ?`
+
+// History records the versions of the keys (see DB.History).
+// After each write transaction, saves a copy of each changed key
+// matching the pattern: its value, version and expiration time.
+// The copy of the whole value is saved even if only a single
+// hash field has changed, so the history suits the small keys
+// (like configuration values) best.
+type History struct {
+	// Match is the pattern of the recorded keys.
+	// If empty, records all keys.
+	Match string
+	// MaxVersions is the maximum number of versions
+	// kept for each key. If zero, there is no limit.
+	MaxVersions int
+	// MaxAge is how long the versions are kept (see Prune).
+	// If zero, there is no limit.
+	MaxAge time.Duration
+}
+
+// Revision is a recorded version of a key.
+type Revision struct {
+	id     int
+	Key    core.Key        // the key as of the version (Type is 0 if deleted)
+	Time   int64           // when the version was recorded, in unix milliseconds
+	Values []RevisionValue // the value as of the version
+}
+
+// Deleted reports whether the key was deleted at the time.
+func (r Revision) Deleted() bool {
+	return r.Key.Type == 0
+}
+
+// RevisionValue is a string value, a hash field and its value,
+// or a sorted set element and its score.
+type RevisionValue struct {
+	Field []byte // hash field or sorted set element
+	Value []byte // string value or hash value
+	Score float64
+}
+
+// record saves the versions of the keys changed by the transaction.
+func (h *History) record(tx Tx, changes []core.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	match := h.Match
+	if match == "" {
+		match = "*"
+	}
+	pattern := glob.Compile(match, CollationOf(tx) == CollationNocase)
+	now := Now(tx).UnixMilli()
+	seen := map[string]bool{}
+	for _, ch := range changes {
+		names := []string{ch.Key}
+		if ch.Op == core.ChangeRename {
+			names = append(names, string(ch.After))
+		}
+		for _, name := range names {
+			if seen[name] || !pattern.Match(name) {
+				continue
+			}
+			seen[name] = true
+			if err := h.recordKey(tx, name, now); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// recordKey saves the current version of the key, unless it is
+// already the latest recorded one. If the key does not exist, saves
+// the deletion (only if there are recorded versions of the key).
+func (h *History) recordKey(tx Tx, key string, now int64) error {
+	var last core.Key
+	err := tx.QueryRow(sqlHistoryLast, key).Scan(&last.Type, &last.Version, &last.ETime)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	hasLast := err == nil
+
+	var k core.Key
+	err = tx.QueryRow(sqlHistoryKey, key, now).Scan(&k.ID, &k.Type, &k.Version, &k.ETime)
+	if err == sql.ErrNoRows {
+		if !hasLast || last.Type == 0 {
+			return nil
+		}
+		_, err := tx.Exec(sqlHistoryAdd, key, 0, last.Version, nil, now)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	if hasLast && last.Type == k.Type && last.Version == k.Version &&
+		equalETime(last.ETime, k.ETime) {
+		return nil
+	}
+
+	res, err := tx.Exec(sqlHistoryAdd, key, k.Type, k.Version, k.ETime, now)
+	if err != nil {
+		return err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(sqlHistoryAddValue, id, k.ID); err != nil {
+		return err
+	}
+	if h.MaxVersions > 0 {
+		_, err = tx.Exec(sqlHistoryTrim, key, h.MaxVersions)
+	}
+	return err
+}
+
+// Prune deletes the versions older than MaxAge, except for those
+// still needed to read the keys as of any time within MaxAge.
+// Returns the number of deleted versions.
+func (h *History) Prune(tx Tx) (int, error) {
+	if h == nil || h.MaxAge <= 0 {
+		return 0, nil
+	}
+	before := Now(tx).Add(-h.MaxAge).UnixMilli()
+	res, err := tx.Exec(sqlHistoryPrune, before)
+	if err != nil {
+		return 0, err
+	}
+	count, _ := res.RowsAffected()
+	return int(count), nil
+}
+
+// Revisions returns the recorded versions of the key, the latest first.
+func Revisions(tx Tx, key string) ([]Revision, error) {
+	revs, err := Select(tx, sqlRevisions, []any{key}, func(rows *sql.Rows) (Revision, error) {
+		return scanRevision(rows, key)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range revs {
+		if revs[i].Values, err = revisionValues(tx, revs[i].id); err != nil {
+			return nil, err
+		}
+	}
+	return revs, nil
+}
+
+// RevisionAt returns the latest version of the key recorded
+// at or before the time (in unix milliseconds). Returns false
+// if there is no such version.
+func RevisionAt(tx Tx, key string, at int64) (Revision, bool, error) {
+	rows, err := tx.Query(sqlRevisionAt, key, at)
+	if err != nil {
+		return Revision{}, false, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return Revision{}, false, rows.Err()
+	}
+	rev, err := scanRevision(rows, key)
+	if err != nil {
+		return Revision{}, false, err
+	}
+	_ = rows.Close()
+	rev.Values, err = revisionValues(tx, rev.id)
+	return rev, err == nil, err
+}
+
+// scanRevision scans the version without the value.
+func scanRevision(rows *sql.Rows, key string) (Revision, error) {
+	rev := Revision{Key: core.Key{Key: key}}
+	err := rows.Scan(&rev.id, &rev.Key.Type, &rev.Key.Version,
+		&rev.Key.ETime, &rev.Time)
+	return rev, err
+}
+
+// revisionValues returns the value of the version.
+func revisionValues(tx Tx, id int) ([]RevisionValue, error) {
+	return Select(tx, sqlRevisionValues, []any{id}, func(rows *sql.Rows) (RevisionValue, error) {
+		var val RevisionValue
+		var score sql.NullFloat64
+		err := rows.Scan(&val.Field, &val.Value, &score)
+		val.Score = score.Float64
+		return val, err
+	})
+}
+
+// equalETime reports whether the expiration times are the same.
+func equalETime(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
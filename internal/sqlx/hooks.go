@@ -0,0 +1,101 @@
+package sqlx
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Op is a repository operation (like Str.Set or Key.Delete)
+// reported to the hooks.
+type Op struct {
+	// Name is the repository and method name, like "Str.Set".
+	Name string
+	// Keys are the keys the operation works with.
+	// Empty for operations that work with patterns
+	// or the whole database (like Key.Keys).
+	Keys []string
+	// Start is the time the operation started.
+	Start time.Time
+	// Duration is the time the operation took.
+	// Zero before the operation completes.
+	Duration time.Duration
+	// Err is the error returned by the operation, if any.
+	// Nil before the operation completes.
+	Err error
+	// RowsAffected is the number of rows inserted, updated
+	// or deleted by the operation's queries.
+	// Zero before the operation completes.
+	RowsAffected int64
+	// SQLDuration is the time spent executing
+	// the operation's queries.
+	// Zero before the operation completes.
+	SQLDuration time.Duration
+
+	ctx   context.Context
+	stats *QueryStats
+	hooks []Hook
+}
+
+// Done completes the operation with the given error, calls the
+// After hooks and returns the error. Does nothing if op is nil.
+func (op *Op) Done(err error) error {
+	if op == nil {
+		return err
+	}
+	op.Duration = time.Since(op.Start)
+	op.Err = err
+	op.RowsAffected = op.stats.RowsAffected()
+	op.SQLDuration = op.stats.Duration()
+	for i := len(op.hooks) - 1; i >= 0; i-- {
+		op.hooks[i].After(op.ctx, op)
+	}
+	return err
+}
+
+// Hook observes the repository operations. Before is called
+// before the operation, and After when it completes.
+// Hooks are called synchronously by the goroutine that executes
+// the operation, so they should return quickly. They should not
+// modify the operation.
+type Hook interface {
+	Before(ctx context.Context, op *Op)
+	After(ctx context.Context, op *Op)
+}
+
+// Hooks is a list of hooks shared by the repositories.
+// Observing the operations costs nothing until the first
+// hook is added. Safe for concurrent use.
+type Hooks struct {
+	mu      sync.RWMutex
+	list    []Hook
+	enabled atomic.Bool
+}
+
+// Add registers a hook. The Before hooks are called
+// in the order they were added, and the After hooks
+// in the reverse order.
+func (h *Hooks) Add(hook Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.list = append(h.list, hook)
+	h.enabled.Store(true)
+}
+
+// start calls the Before hooks for the operation.
+// Returns nil if there are no hooks.
+func (h *Hooks) start(ctx context.Context, name string, keys []string) *Op {
+	if h == nil || !h.enabled.Load() {
+		return nil
+	}
+	h.mu.RLock()
+	hooks := h.list
+	h.mu.RUnlock()
+	op := &Op{Name: name, Keys: keys, Start: time.Now(), stats: new(QueryStats), hooks: hooks}
+	op.ctx = WithQueryStats(ctx, op.stats)
+	for _, hook := range hooks {
+		hook.Before(ctx, op)
+	}
+	return op
+}
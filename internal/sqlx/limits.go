@@ -0,0 +1,122 @@
+package sqlx
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// Limits restrict the size of the data written by the repositories.
+// Zero fields mean no limit.
+type Limits struct {
+	// MaxKeySize is the maximum key length in bytes.
+	MaxKeySize int
+	// MaxValueSize is the maximum size of a string value,
+	// a hash field or value, or a sorted set member in bytes.
+	MaxValueSize int
+	// MaxElements is the maximum number of fields in a hash
+	// or members in a sorted set.
+	MaxElements int
+}
+
+// LimitsVar holds the limits that can be changed while the
+// database is open. Safe for concurrent use. A nil LimitsVar
+// has no limits.
+type LimitsVar struct {
+	p atomic.Pointer[Limits]
+}
+
+// NewLimitsVar returns a variable with the limits.
+func NewLimitsVar(lim Limits) *LimitsVar {
+	v := &LimitsVar{}
+	v.Store(lim)
+	return v
+}
+
+// Load returns the current limits, or nil if there are none.
+func (v *LimitsVar) Load() *Limits {
+	if v == nil {
+		return nil
+	}
+	return v.p.Load()
+}
+
+// Store replaces the limits. The transactions
+// that have already started keep the old ones.
+func (v *LimitsVar) Store(lim Limits) {
+	if lim == (Limits{}) {
+		v.p.Store(nil)
+		return
+	}
+	v.p.Store(&lim)
+}
+
+// limitsOf returns the transaction limits, or nil if there are none.
+func limitsOf(tx Tx) *Limits {
+	if etx, ok := tx.(*envTx); ok {
+		return etx.limits
+	}
+	return nil
+}
+
+// CheckKey returns core.ErrKeyTooLarge if the key
+// exceeds the transaction limits (see DB.Limits),
+// or a core.QuotaError if the write to the key
+// exceeds one of the quotas (see DB.Quotas).
+func CheckKey(tx Tx, key string) error {
+	lim := limitsOf(tx)
+	if lim != nil && lim.MaxKeySize > 0 && len(key) > lim.MaxKeySize {
+		return fmt.Errorf("%w: %d > %d bytes", core.ErrKeyTooLarge, len(key), lim.MaxKeySize)
+	}
+	return checkQuota(tx, key)
+}
+
+// CheckValue returns core.ErrValueTooLarge if the value
+// exceeds the transaction limits (see DB.Limits).
+// Only checks strings and byte slices, because the other
+// value types (like numbers) are small anyway.
+func CheckValue(tx Tx, value any) error {
+	lim := limitsOf(tx)
+	if lim == nil || lim.MaxValueSize <= 0 {
+		return nil
+	}
+	var size int
+	switch v := value.(type) {
+	case string:
+		size = len(v)
+	case []byte:
+		size = len(v)
+	default:
+		return nil
+	}
+	return CheckSize(tx, int64(size))
+}
+
+// CheckSize returns core.ErrValueTooLarge if the value size
+// exceeds the transaction limits (see DB.Limits).
+func CheckSize(tx Tx, size int64) error {
+	lim := limitsOf(tx)
+	if lim == nil || lim.MaxValueSize <= 0 || size <= int64(lim.MaxValueSize) {
+		return nil
+	}
+	return fmt.Errorf("%w: %d > %d bytes", core.ErrValueTooLarge, size, lim.MaxValueSize)
+}
+
+// CheckElements returns core.ErrTooManyElements if the number
+// of elements returned by count exceeds the transaction limits
+// (see DB.Limits). Does not call count if there is no limit.
+func CheckElements(tx Tx, count func() (int, error)) error {
+	lim := limitsOf(tx)
+	if lim == nil || lim.MaxElements <= 0 {
+		return nil
+	}
+	n, err := count()
+	if err != nil {
+		return err
+	}
+	if n <= lim.MaxElements {
+		return nil
+	}
+	return fmt.Errorf("%w: %d > %d", core.ErrTooManyElements, n, lim.MaxElements)
+}
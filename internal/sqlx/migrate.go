@@ -0,0 +1,340 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	sqlSchemaTable = `
+	create table if not exists
+	rschema (
+	    version integer primary key,
+	    time    integer not null
+	)`
+
+	sqlSchemaVersion = `select coalesce(max(version), 0) from rschema`
+
+	sqlSchemaAdd = `insert into rschema (version, time) values (?, ?)`
+
+	sqlSchemaRemove = `delete from rschema where version = ?`
+
+	sqlTableExists = `
+	select count(*) from sqlite_master
+	where type = 'table' and name = ?`
+)
+
+// ErrSchemaVersion is returned when the database schema
+// version is not supported by the current code.
+var ErrSchemaVersion = errors.New("unsupported schema version")
+
+// Migration is a versioned change of the database schema.
+type Migration struct {
+	Version int    // sequential, starting with 1
+	Up      string // applies the change
+	Down    string // reverts the change (empty if irreversible)
+}
+
+// Migrations are the schema migrations ordered by version.
+// New migrations are only ever appended to the list.
+var Migrations = []Migration{
+	// The base schema. Databases created before the versioning
+	// was introduced already have it (maybe without some of the
+	// tables), so it must be safe to apply to them.
+	{Version: 1, Up: sqlSchema},
+	// The unlinked keys waiting for their values to be deleted.
+	{
+		Version: 2,
+		Up: `
+		create table if not exists
+		rfree (
+		    key_id integer primary key,
+		    foreign key (key_id) references rkey (id)
+		      on delete cascade
+		)`,
+		Down: `drop table if exists rfree`,
+	},
+	// The database fingerprint (see DB.Fingerprint).
+	{
+		Version: 3,
+		Up: `
+		create table if not exists
+		rmeta (
+		    name  text primary key,
+		    value text not null
+		) without rowid`,
+		Down: `drop table if exists rmeta`,
+	},
+	// The sorted set elements are stored as text (see rzset),
+	// so that 1, "1" and []byte("1") are the same element.
+	// The elements that become duplicates are merged.
+	{
+		Version: 4,
+		Up: `
+		update or replace rzset
+		set elem = cast(elem as text)
+		where typeof(elem) <> 'text'`,
+		// The older versions read the text elements
+		// as is, so there is nothing to revert.
+		Down: `select 1`,
+	},
+	// The soft-deleted keys waiting to be restored or purged
+	// (see DB.Trash). The keys themselves stay in rkey, renamed
+	// and expired, so the older versions delete them as expired.
+	{
+		Version: 5,
+		Up: `
+		create table if not exists
+		rtrash (
+		    key_id integer primary key,
+		    key    text not null collate binary,
+		    etime  integer,
+		    dtime  integer not null,
+		    foreign key (key_id) references rkey (id)
+		      on delete cascade
+		);
+		create index if not exists
+		rtrash_key_idx on rtrash (key);
+		create index if not exists
+		rtrash_dtime_idx on rtrash (dtime)`,
+		Down: `drop table if exists rtrash`,
+	},
+	// The recorded versions of the keys (see DB.History).
+	// A version with type 0 means the key was deleted.
+	{
+		Version: 6,
+		Up: `
+		create table if not exists
+		rhistory (
+		    id      integer primary key,
+		    key     text not null collate binary,
+		    type    integer not null,
+		    version integer not null,
+		    etime   integer,
+		    time    integer not null
+		);
+		create index if not exists
+		rhistory_key_idx on rhistory (key, id);
+		create index if not exists
+		rhistory_time_idx on rhistory (time);
+		create table if not exists
+		rhistory_value (
+		    hist_id integer not null,
+		    field   blob,
+		    value   blob,
+		    score   real,
+		    foreign key (hist_id) references rhistory (id)
+		      on delete cascade
+		);
+		create index if not exists
+		rhistory_value_idx on rhistory_value (hist_id)`,
+		Down: `
+		drop table if exists rhistory_value;
+		drop table if exists rhistory`,
+	},
+	// The scheduled key operations (see redka.DB.ScheduleSet).
+	{
+		Version: 7,
+		Up: `
+		create table if not exists
+		rschedule (
+		    id     integer primary key,
+		    time   integer not null,
+		    op     text not null,
+		    key    text not null collate binary,
+		    value  blob,
+		    target text collate binary
+		);
+		create index if not exists
+		rschedule_time_idx on rschedule (time)`,
+		Down: `drop table if exists rschedule`,
+	},
+	// The stream entries. The fields and values of an entry
+	// are encoded into a single blob (see rstream.Entry).
+	{
+		Version: 8,
+		Up: `
+		create table if not exists
+		rstream (
+		    key_id integer not null,
+		    ms     integer not null,
+		    seq    integer not null,
+		    data   blob not null,
+		    foreign key (key_id) references rkey (id)
+		      on delete cascade
+		);
+		create unique index if not exists
+		rstream_pk_idx on rstream (key_id, ms, seq);
+		create view if not exists
+		vstream as
+		  select
+		    rkey.id as key_id, rkey.key, rstream.ms || '-' || rstream.seq as id,
+		    rstream.data,
+		    datetime(etime/1000, 'unixepoch') as etime,
+		    datetime(mtime/1000, 'unixepoch') as mtime
+		  from rkey join rstream on rkey.id = rstream.key_id
+		  where rkey.type = 6
+		    and (rkey.etime is null or rkey.etime > unixepoch('subsec'))`,
+		Down: `
+		drop view if exists vstream;
+		drop table if exists rstream`,
+	},
+	// The HyperLogLogs in the Redis dense encoding (see rhll.DB).
+	{
+		Version: 9,
+		Up: `
+		create table if not exists
+		rhll (
+		    key_id integer not null,
+		    value  blob not null,
+		    foreign key (key_id) references rkey (id)
+		      on delete cascade
+		);
+		create unique index if not exists
+		rhll_pk_idx on rhll (key_id)`,
+		Down: `drop table if exists rhll`,
+	},
+	// The expiration times of the hash fields (see rhash.Tx.FieldExpire).
+	// The older versions ignore them, so the fields stop expiring.
+	{
+		Version: 10,
+		Up: `
+		create table if not exists
+		rhash_ttl (
+		    key_id integer not null,
+		    field  text not null collate binary,
+		    etime  integer not null,
+		    foreign key (key_id) references rkey (id)
+		      on delete cascade
+		);
+		create unique index if not exists
+		rhash_ttl_pk_idx on rhash_ttl (key_id, field);
+		create index if not exists
+		rhash_ttl_etime_idx on rhash_ttl (etime)`,
+		Down: `drop table if exists rhash_ttl`,
+	},
+	// The highest key ID so far (see NextKeyID), so that
+	// the IDs of the deleted keys are not reused.
+	// The older versions do not use it, so they may reuse
+	// the IDs, but the trigger still keeps it up to date.
+	{
+		Version: 11,
+		Up: `
+		insert or replace into rmeta (name, value)
+		select 'rkey_seq', coalesce(max(id), 0) from rkey;
+		create trigger if not exists
+		rkey_on_insert
+		after insert on rkey
+		for each row
+		when new.id > (select cast(value as integer) from rmeta where name = 'rkey_seq')
+		begin
+		    update rmeta set value = new.id where name = 'rkey_seq';
+		end`,
+		Down: `
+		drop trigger if exists rkey_on_insert;
+		delete from rmeta where name = 'rkey_seq'`,
+	},
+}
+
+// LatestVersion returns the latest schema version.
+func LatestVersion() int {
+	return Migrations[len(Migrations)-1].Version
+}
+
+// SchemaVersion returns the current schema version.
+// Returns 0 if the schema is not versioned yet.
+func (d *DB[T]) SchemaVersion() (int, error) {
+	tx := d.Conn()
+	if _, err := tx.Exec(sqlSchemaTable); err != nil {
+		return 0, err
+	}
+	var version int
+	err := tx.QueryRow(sqlSchemaVersion).Scan(&version)
+	return version, err
+}
+
+// isNew reports whether the database has no schema at all.
+func (d *DB[T]) isNew() (bool, error) {
+	ok, err := d.tableExists("rkey")
+	return !ok, err
+}
+
+// Migrate migrates the schema to the target version in a single
+// transaction, applying or reverting the migrations as necessary.
+// Returns the version before the migration.
+func (d *DB[T]) Migrate(ctx context.Context, target int) (int, error) {
+	if target < 1 || target > LatestVersion() {
+		return 0, fmt.Errorf("%w: %d", ErrSchemaVersion, target)
+	}
+	dtx, err := d.SQL.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = dtx.Rollback() }()
+	tx := Wrap(dtx, d.Names)
+
+	if _, err := tx.Exec(sqlSchemaTable); err != nil {
+		return 0, err
+	}
+	var current int
+	if err := tx.QueryRow(sqlSchemaVersion).Scan(&current); err != nil {
+		return 0, err
+	}
+	if current > LatestVersion() {
+		return current, fmt.Errorf("%w: database has version %d, latest known is %d",
+			ErrSchemaVersion, current, LatestVersion())
+	}
+
+	now := time.Now().UnixMilli()
+	for _, m := range Migrations {
+		if m.Version <= current || m.Version > target {
+			continue
+		}
+		if _, err := tx.Exec(d.collate(m.Up)); err != nil {
+			return current, fmt.Errorf("migrate to version %d: %w", m.Version, err)
+		}
+		if _, err := tx.Exec(sqlSchemaAdd, m.Version, now); err != nil {
+			return current, err
+		}
+	}
+	for i := len(Migrations) - 1; i >= 0; i-- {
+		m := Migrations[i]
+		if m.Version > current || m.Version <= target {
+			continue
+		}
+		if m.Down == "" {
+			return current, fmt.Errorf("%w: migration %d is irreversible",
+				ErrSchemaVersion, m.Version)
+		}
+		if _, err := tx.Exec(m.Down); err != nil {
+			return current, fmt.Errorf("revert version %d: %w", m.Version, err)
+		}
+		if _, err := tx.Exec(sqlSchemaRemove, m.Version); err != nil {
+			return current, err
+		}
+	}
+	return current, dtx.Commit()
+}
+
+// migrateLatest migrates the schema to the latest version.
+// If manual is true, only creates the schema of a new database,
+// leaving the existing ones intact.
+// Records the fingerprint of a new database.
+func (d *DB[T]) migrateLatest(manual bool) error {
+	isNew, err := d.isNew()
+	if err != nil {
+		return err
+	}
+	if manual && !isNew {
+		return nil
+	}
+	if _, err := d.Migrate(context.Background(), LatestVersion()); err != nil {
+		return err
+	}
+	if isNew {
+		return d.initFingerprint()
+	}
+	return nil
+}
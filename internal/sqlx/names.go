@@ -0,0 +1,99 @@
+package sqlx
+
+import (
+	"database/sql"
+	"regexp"
+	"sync"
+)
+
+// maxCachedQueries limits the number of rewritten queries kept in
+// the cache. Queries with expanded IN clauses vary with the number
+// of arguments, so the cache could otherwise grow without bound.
+const maxCachedQueries = 1000
+
+// tableRE matches the names of the database objects (tables, views,
+// indexes and triggers), which all start with the table name.
+var tableRE = regexp.MustCompile(
+	`\b(rkey|rstring|rhash|rzset|rstream|rhll|vstring|vhash|vzset|vstream|routbox|rchange|rheartbeat|rschema|rfree|rmeta|rtrash|rhistory|rschedule)(\b|_)`)
+
+// Names maps the table names used in queries to the actual
+// names in the database by adding a prefix. Allows several
+// databases (or a database and application tables) to
+// coexist in the same SQLite file. Safe for concurrent use.
+type Names struct {
+	prefix string
+	mu     sync.RWMutex
+	cache  map[string]string
+}
+
+// NewNames creates a new mapping with the given table name prefix.
+// Returns nil if the prefix is empty.
+func NewNames(prefix string) *Names {
+	if prefix == "" {
+		return nil
+	}
+	return &Names{prefix: prefix, cache: map[string]string{}}
+}
+
+// Query returns the query with the table names prefixed.
+func (n *Names) Query(query string) string {
+	if n == nil {
+		return query
+	}
+	n.mu.RLock()
+	q, ok := n.cache[query]
+	n.mu.RUnlock()
+	if ok {
+		return q
+	}
+	q = tableRE.ReplaceAllString(query, n.prefix+"$0")
+	n.mu.Lock()
+	if len(n.cache) < maxCachedQueries {
+		n.cache[query] = q
+	}
+	n.mu.Unlock()
+	return q
+}
+
+// Wrap returns a transaction that prefixes the table names
+// in queries. Returns tx as is if names is nil.
+func Wrap(tx Tx, names *Names) Tx {
+	if names == nil {
+		return tx
+	}
+	return &namedTx{tx: tx, names: names}
+}
+
+// Unwrap returns the underlying transaction without
+// the table name prefixes and the repository settings
+// added by Wrap and DB.Wrap.
+func Unwrap(tx Tx) Tx {
+	for {
+		switch t := tx.(type) {
+		case *namedTx:
+			tx = t.tx
+		case *envTx:
+			tx = t.Tx
+		default:
+			return tx
+		}
+	}
+}
+
+// namedTx is a transaction that prefixes the table names.
+type namedTx struct {
+	tx    Tx
+	names *Names
+}
+
+func (t *namedTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return t.tx.Query(t.names.Query(query), args...)
+}
+
+func (t *namedTx) QueryRow(query string, args ...any) *sql.Row {
+	return t.tx.QueryRow(t.names.Query(query), args...)
+}
+
+func (t *namedTx) Exec(query string, args ...any) (sql.Result, error) {
+	return t.tx.Exec(t.names.Query(query), args...)
+}
@@ -0,0 +1,228 @@
+package sqlx
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/glob"
+)
+
+// sqlQuotaUsage returns the number of keys with the prefix
+// and their size in bytes (the keys, the values, the hash fields,
+// the sorted set elements with their 8-byte scores, the stream
+// entries with their 16-byte IDs and the HyperLogLogs).
+const sqlQuotaUsage = `
+with keys as (
+  select id, key from rkey
+  where key glob ?1 and (etime is null or etime > ?2)
+)
+select
+  (select count(*) from keys),
+  (select coalesce(sum(length(cast(key as blob))), 0) from keys)
+  + (select coalesce(sum(length(value)), 0) from rstring
+     where key_id in (select id from keys))
+  + (select coalesce(sum(length(cast(field as blob)) + length(value)), 0) from rhash
+     where key_id in (select id from keys))
+  + (select coalesce(sum(length(elem) + 8), 0) from rzset
+     where key_id in (select id from keys))
+  + (select coalesce(sum(length(data) + 16), 0) from rstream
+     where key_id in (select id from keys))
+  + (select coalesce(sum(length(value)), 0) from rhll
+     where key_id in (select id from keys))`
+
+const sqlQuotaKeyExists = `
+select 1 from rkey
+where key = ? and (etime is null or etime > ?)`
+
+// Quota limits the keys with the prefix.
+// Zero fields mean no limit.
+type Quota struct {
+	// Prefix selects the keys the quota applies to, compared
+	// byte by byte. The empty prefix selects all keys.
+	Prefix string
+	// MaxKeys is the maximum number of keys.
+	MaxKeys int64
+	// MaxBytes is the maximum total size of the keys
+	// and their values in bytes.
+	MaxBytes int64
+	// MaxOpsPerSec is the maximum number of write
+	// operations per second.
+	MaxOpsPerSec int64
+}
+
+// QuotaUsage is the usage of the quota as of the last
+// measurement (see Quotas.Refresh).
+type QuotaUsage struct {
+	Quota
+	Keys     int64            // number of keys
+	Bytes    int64            // total size in bytes
+	Rejected map[string]int64 // rejected writes per resource (keys, bytes or ops)
+}
+
+// Quotas enforce the quotas on writes. The key and byte usage
+// is measured periodically (see Refresh), so the writes made
+// between the measurements may exceed the quota slightly.
+// The writes per second are counted exactly.
+// Safe for concurrent use.
+type Quotas struct {
+	list []*quotaState
+}
+
+// quotaState is the quota with its usage.
+type quotaState struct {
+	Quota
+	pattern string // SQLite glob pattern
+	keys    atomic.Int64
+	bytes   atomic.Int64
+
+	// The writes per second are counted in fixed
+	// one-second windows.
+	mu     sync.Mutex
+	window int64 // unix seconds
+	ops    int64 // in the window
+
+	rejectedKeys  atomic.Int64
+	rejectedBytes atomic.Int64
+	rejectedOps   atomic.Int64
+}
+
+// NewQuotas creates the quotas. Fails if a prefix is not
+// valid UTF-8 or has a NUL byte, since the usage of such
+// prefixes can not be measured with SQLite's glob.
+func NewQuotas(list []Quota) (*Quotas, error) {
+	q := &Quotas{}
+	for _, quota := range list {
+		if !utf8.ValidString(quota.Prefix) || strings.IndexByte(quota.Prefix, 0) >= 0 {
+			return nil, fmt.Errorf("invalid quota prefix %q", quota.Prefix)
+		}
+		pattern, _ := glob.Compile(glob.Escape(quota.Prefix)+"*", false).SQLite()
+		q.list = append(q.list, &quotaState{Quota: quota, pattern: pattern})
+	}
+	return q, nil
+}
+
+// Refresh measures the usage of the quotas.
+func (q *Quotas) Refresh(tx Tx) error {
+	if q == nil {
+		return nil
+	}
+	now := Now(tx).UnixMilli()
+	for _, qs := range q.list {
+		if qs.MaxKeys <= 0 && qs.MaxBytes <= 0 {
+			continue
+		}
+		var keys, bytes int64
+		err := tx.QueryRow(sqlQuotaUsage, qs.pattern, now).Scan(&keys, &bytes)
+		if err != nil {
+			return err
+		}
+		qs.keys.Store(keys)
+		qs.bytes.Store(bytes)
+	}
+	return nil
+}
+
+// Usage returns the usage of the quotas.
+func (q *Quotas) Usage() []QuotaUsage {
+	if q == nil {
+		return nil
+	}
+	usage := make([]QuotaUsage, len(q.list))
+	for i, qs := range q.list {
+		usage[i] = QuotaUsage{
+			Quota: qs.Quota,
+			Keys:  qs.keys.Load(),
+			Bytes: qs.bytes.Load(),
+			Rejected: map[string]int64{
+				"keys":  qs.rejectedKeys.Load(),
+				"bytes": qs.rejectedBytes.Load(),
+				"ops":   qs.rejectedOps.Load(),
+			},
+		}
+	}
+	return usage
+}
+
+// checkQuota returns a core.QuotaError if the write
+// to the key exceeds one of the transaction quotas
+// (see DB.Quotas). Counts the first write to each key
+// within the transaction as an operation.
+func checkQuota(tx Tx, key string) error {
+	etx, ok := tx.(*envTx)
+	if !ok || etx.quotas == nil {
+		return nil
+	}
+	if _, ok := etx.quotaKeys[key]; ok {
+		return nil
+	}
+	for _, qs := range etx.quotas.list {
+		if !strings.HasPrefix(key, qs.Prefix) {
+			continue
+		}
+		if err := qs.check(tx, key); err != nil {
+			return err
+		}
+	}
+	if etx.quotaKeys == nil {
+		etx.quotaKeys = map[string]struct{}{}
+	}
+	etx.quotaKeys[key] = struct{}{}
+	return nil
+}
+
+// check returns a core.QuotaError if the write
+// to the key exceeds the quota.
+func (qs *quotaState) check(tx Tx, key string) error {
+	if qs.MaxBytes > 0 && qs.bytes.Load() >= qs.MaxBytes {
+		qs.rejectedBytes.Add(1)
+		return &core.QuotaError{Prefix: qs.Prefix, Resource: "bytes", Limit: qs.MaxBytes}
+	}
+	if qs.MaxKeys > 0 && qs.keys.Load() >= qs.MaxKeys {
+		// The existing keys can still be changed.
+		exists, err := keyExists(tx, key)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			qs.rejectedKeys.Add(1)
+			return &core.QuotaError{Prefix: qs.Prefix, Resource: "keys", Limit: qs.MaxKeys}
+		}
+	}
+	if qs.MaxOpsPerSec > 0 && !qs.allowOp(Now(tx).Unix()) {
+		qs.rejectedOps.Add(1)
+		return &core.QuotaError{Prefix: qs.Prefix, Resource: "ops", Limit: qs.MaxOpsPerSec}
+	}
+	return nil
+}
+
+// allowOp counts the write in the current one-second window,
+// and reports whether the window is still within the quota.
+func (qs *quotaState) allowOp(now int64) bool {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+	if now != qs.window {
+		qs.window, qs.ops = now, 0
+	}
+	if qs.ops >= qs.MaxOpsPerSec {
+		return false
+	}
+	qs.ops++
+	return true
+}
+
+// keyExists reports whether the key exists and has not expired.
+func keyExists(tx Tx, key string) (bool, error) {
+	var one int
+	now := Now(tx).UnixMilli()
+	err := tx.QueryRow(sqlQuotaKeyExists, key, now).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
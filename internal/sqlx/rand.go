@@ -0,0 +1,34 @@
+package sqlx
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// Rand is a source of random numbers for the repositories
+// (see DB.Rand). Safe for concurrent use.
+type Rand struct {
+	mu sync.Mutex
+	r  *rand.Rand
+}
+
+// NewRand creates a random source on top of r.
+func NewRand(r *rand.Rand) *Rand {
+	return &Rand{r: r}
+}
+
+// IntN returns a random number in [0, n).
+func (r *Rand) IntN(n int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.IntN(n)
+}
+
+// RandOf returns the transaction random source (see DB.Rand),
+// or nil if there is none and SQLite's random() should be used.
+func RandOf(tx Tx) *Rand {
+	if etx, ok := tx.(*envTx); ok {
+		return etx.rand
+	}
+	return nil
+}
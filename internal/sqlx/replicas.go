@@ -0,0 +1,128 @@
+package sqlx
+
+import (
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	sqlHeartbeatGet = `select time from rheartbeat where id = 1`
+	sqlHeartbeatSet = `
+	insert into rheartbeat (id, time) values (1, ?)
+	on conflict (id) do update set time = excluded.time`
+)
+
+// Replicas routes the read-only queries to the read replicas of
+// the database (e.g. restored by Litestream or synced by LiteFS).
+// Skips the replicas that lag behind the primary more than allowed.
+// A nil Replicas has no replicas. Safe for concurrent use.
+//
+// The lag is measured with heartbeats: the primary periodically
+// records the current time, and the replica lag is the time
+// since the last heartbeat it has received.
+type Replicas struct {
+	list   []*replica
+	maxLag time.Duration
+	pin    time.Duration
+	names  *Names
+	next   atomic.Uint32
+	// lastWrite is the commit time of the last write
+	// transaction, in unix nanoseconds.
+	lastWrite atomic.Int64
+}
+
+// replica is a read replica of the database.
+type replica struct {
+	db    *sql.DB
+	fresh atomic.Bool
+}
+
+// NewReplicas creates a router for the read replicas.
+// If maxLag is zero, the replica lag is not checked.
+// If pin is not zero, the reads go to the primary database
+// for this long after each write (see Pick).
+// Returns nil if there are no replicas.
+func NewReplicas(dbs []*sql.DB, maxLag, pin time.Duration, names *Names) *Replicas {
+	if len(dbs) == 0 {
+		return nil
+	}
+	r := &Replicas{maxLag: maxLag, pin: pin, names: names}
+	for _, db := range dbs {
+		rep := &replica{db: db}
+		rep.fresh.Store(maxLag == 0)
+		r.list = append(r.list, rep)
+	}
+	return r
+}
+
+// Pick returns the next replica that is fresh enough,
+// or nil if there are none. Also returns nil within the pin
+// window after a write, since the replicas may not have
+// received it yet.
+func (r *Replicas) Pick() *sql.DB {
+	if r == nil {
+		return nil
+	}
+	if r.pin > 0 && time.Now().UnixNano()-r.lastWrite.Load() < int64(r.pin) {
+		return nil
+	}
+	start := int(r.next.Add(1))
+	for i := range r.list {
+		rep := r.list[(start+i)%len(r.list)]
+		if rep.fresh.Load() {
+			return rep.db
+		}
+	}
+	return nil
+}
+
+// wrote records the commit of a write transaction.
+func (r *Replicas) wrote() {
+	if r == nil || r.pin == 0 {
+		return
+	}
+	r.lastWrite.Store(time.Now().UnixNano())
+}
+
+// CheckInterval returns the interval between the checks.
+// Returns zero if the replica lag is not checked.
+func (r *Replicas) CheckInterval() time.Duration {
+	if r == nil {
+		return 0
+	}
+	// Heartbeats are recorded after each check, so a replica
+	// that is fully in sync lags by at most the check interval.
+	return r.maxLag / 2
+}
+
+// Check measures the lag of the replicas and records
+// a new heartbeat in the primary database.
+func (r *Replicas) Check(primary Tx) error {
+	if r == nil || r.maxLag == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, rep := range r.list {
+		var beat int64
+		err := Wrap(rep.db, r.names).QueryRow(sqlHeartbeatGet).Scan(&beat)
+		lag := now.Sub(time.UnixMilli(beat))
+		rep.fresh.Store(err == nil && lag <= r.maxLag)
+	}
+	_, err := primary.Exec(sqlHeartbeatSet, now.UnixMilli())
+	return err
+}
+
+// Close closes the replica connections.
+func (r *Replicas) Close() error {
+	if r == nil {
+		return nil
+	}
+	var firstErr error
+	for _, rep := range r.list {
+		if err := rep.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
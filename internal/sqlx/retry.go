@@ -0,0 +1,75 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+// RetryPolicy configures retrying the write transactions
+// that fail because the database is busy (SQLITE_BUSY).
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts,
+	// including the first one. If zero or one, does not retry.
+	Attempts int
+	// BaseDelay is the delay before the first retry.
+	// The delay doubles with each retry.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between the retries.
+	// If zero, the delay is not limited.
+	MaxDelay time.Duration
+}
+
+// Do calls f until it succeeds, fails with an error other than
+// "database is busy", or the attempts are exhausted. Sleeps between
+// the attempts using exponential backoff with jitter. Wraps the busy
+// errors with [core.ErrBusy]. If p is nil, calls f once.
+func (p *RetryPolicy) Do(ctx context.Context, f func() error) error {
+	attempts := 1
+	if p != nil {
+		attempts = max(p.Attempts, 1)
+	}
+	var err error
+	for i := range attempts {
+		if i > 0 {
+			timer := time.NewTimer(p.delay(i))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("%w: %w", core.ErrBusy, err)
+			case <-timer.C:
+			}
+		}
+		err = f()
+		if err == nil || !IsBusy(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: %w", core.ErrBusy, err)
+}
+
+// delay returns a random delay before the nth retry,
+// between half and full exponential backoff.
+func (p *RetryPolicy) delay(n int) time.Duration {
+	d := p.BaseDelay << min(n-1, 30)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// IsBusy reports whether the error means that the database
+// is locked by another connection. Drivers format SQLite errors
+// differently, so the messages are matched by substring.
+func IsBusy(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "SQLITE_BUSY")
+}
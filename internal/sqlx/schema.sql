@@ -1,10 +1,8 @@
-pragma user_version = 1;
-
 -- keys
 create table if not exists
 rkey (
     id       integer primary key,
-    key      text not null,
+    key      text not null collate binary,
     type     integer not null,
 	version  integer not null,
     etime    integer,
@@ -54,7 +52,7 @@ vstring as
 create table if not exists
 rhash (
     key_id integer not null,
-    field text not null,
+    field text not null collate binary,
     value blob not null,
 
     foreign key (key_id) references rkey (id)
@@ -78,7 +76,7 @@ vhash as
 create table if not exists
 rzset (
     key_id integer not null,
-    elem   blob not null,
+    elem   blob not null collate binary,
     score  real not null,
 
     foreign key (key_id) references rkey (id)
@@ -100,3 +98,24 @@ select
 from rkey join rzset on rkey.id = rzset.key_id
 where rkey.type = 5
     and (rkey.etime is null or rkey.etime > unixepoch('subsec'));
+
+-- outbox
+create table if not exists
+routbox (
+    id   integer primary key autoincrement,
+    time integer not null,
+    data blob not null
+);
+
+create table if not exists
+routbox_cursor (
+    name    text primary key,
+    last_id integer not null
+);
+
+-- replica heartbeats
+create table if not exists
+rheartbeat (
+    id   integer primary key,
+    time integer not null
+);
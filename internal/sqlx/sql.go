@@ -3,7 +3,11 @@
 package sqlx
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
 	"strings"
 
 	"github.com/nalgeon/redka/internal/core"
@@ -22,6 +26,13 @@ const (
 	Max = "max"
 )
 
+// NextKeyID is the SQL expression for the ID of a new key.
+// The key IDs are never reused (the rkey_seq entry in rmeta holds
+// the highest one so far), so a key that is deleted and created
+// again gets a different ID. Evaluates to null (the next rowid)
+// if there is no such entry.
+const NextKeyID = `(select cast(value as integer) + 1 from rmeta where name = 'rkey_seq')`
+
 // Tx is a database transaction (or a transaction-like object).
 type Tx interface {
 	Query(query string, args ...any) (*sql.Rows, error)
@@ -29,6 +40,41 @@ type Tx interface {
 	Exec(query string, args ...any) (sql.Result, error)
 }
 
+// ctxQuerier executes queries with a context
+// (like sql.DB, sql.Conn or sql.Tx).
+type ctxQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// ctxTx executes queries with the given context,
+// so they are canceled when the context is done.
+type ctxTx struct {
+	ctx context.Context
+	q   ctxQuerier
+}
+
+func (t *ctxTx) Query(query string, args ...any) (*sql.Rows, error) {
+	rows, err := t.q.QueryContext(t.ctx, query, args...)
+	if err != nil {
+		return nil, TypedError(err)
+	}
+	return rows, nil
+}
+
+func (t *ctxTx) QueryRow(query string, args ...any) *sql.Row {
+	return t.q.QueryRowContext(t.ctx, query, args...)
+}
+
+func (t *ctxTx) Exec(query string, args ...any) (sql.Result, error) {
+	res, err := t.q.ExecContext(t.ctx, query, args...)
+	if err != nil {
+		return nil, TypedError(err)
+	}
+	return res, nil
+}
+
 // rowScanner is an interface to scan rows.
 type RowScanner interface {
 	Scan(dest ...any) error
@@ -36,11 +82,22 @@ type RowScanner interface {
 
 // ExpandIn expands the IN clause in the query for a given parameter.
 func ExpandIn[T any](query string, param string, args []T) (string, []any) {
+	return expandIn(query, param, "?", args)
+}
+
+// ExpandInText is like ExpandIn, but casts the arguments to text
+// (see the sorted set elements in rzset).
+func ExpandInText[T any](query string, param string, args []T) (string, []any) {
+	return expandIn(query, param, "cast(? as text)", args)
+}
+
+// expandIn expands the IN clause using the placeholder for each argument.
+func expandIn[T any](query, param, pholder string, args []T) (string, []any) {
 	anyArgs := make([]any, len(args))
 	pholders := make([]string, len(args))
 	for i, arg := range args {
 		anyArgs[i] = arg
-		pholders[i] = "?"
+		pholders[i] = pholder
 	}
 	query = strings.Replace(query, param, strings.Join(pholders, ","), 1)
 	return query, anyArgs
@@ -70,12 +127,101 @@ func Select[T any](db Tx, query string, args []any,
 	return vals, err
 }
 
-// Returns typed errors for some specific cases.
+const sqlKeyType = `
+select type from rkey
+where key = ? and (etime is null or etime > ?)`
+
+// Savepoint executes a function within a savepoint with the given name.
+// Rolls back to the savepoint if the function returns an error
+// (and returns the error), or releases the savepoint otherwise.
+func Savepoint(tx Tx, name string, f func() error) error {
+	if _, err := tx.Exec("savepoint " + name); err != nil {
+		return err
+	}
+	if err := f(); err != nil {
+		_, _ = tx.Exec("rollback to " + name)
+		_, _ = tx.Exec("release " + name)
+		return err
+	}
+	_, err := tx.Exec("release " + name)
+	return err
+}
+
+// typedErrors are the errors returned by TypedError.
+var typedErrors = []error{
+	core.ErrKeyType, core.ErrValueTooLarge, core.ErrTxClosed, core.ErrReadOnly,
+	core.ErrKeyTooLarge, core.ErrTooManyElements, core.ErrQuotaExceeded,
+}
+
+// TypedError returns typed errors for some specific cases:
+//   - key type mismatch -> core.ErrKeyType;
+//   - string or blob too big -> core.ErrValueTooLarge (wrapped);
+//   - transaction or connection done -> core.ErrTxClosed (wrapped);
+//   - write to a read-only database -> core.ErrReadOnly (wrapped).
+//
+// Drivers format SQLite errors differently (e.g. modernc.org/sqlite
+// adds the error code), so the messages are matched by substring.
+// Returns the error as is if it is already typed.
 func TypedError(err error) error {
-	switch err.Error() {
-	case "key type mismatch", "UNIQUE constraint failed: rkey.key":
+	if err == nil {
+		return nil
+	}
+	for _, typed := range typedErrors {
+		if errors.Is(err, typed) {
+			return err
+		}
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "key type mismatch"),
+		strings.Contains(msg, "UNIQUE constraint failed: rkey.key"):
 		return core.ErrKeyType
+	case strings.Contains(msg, "string or blob too big"),
+		strings.Contains(msg, "SQLITE_TOOBIG"):
+		return fmt.Errorf("%w: %w", core.ErrValueTooLarge, err)
+	case errors.Is(err, sql.ErrTxDone), errors.Is(err, sql.ErrConnDone):
+		return fmt.Errorf("%w: %w", core.ErrTxClosed, err)
+	case strings.Contains(msg, "readonly database"):
+		return fmt.Errorf("%w: %w", core.ErrReadOnly, err)
 	default:
 		return err
 	}
 }
+
+// KeyTypeError is like TypedError, but reports the key type
+// mismatch as a core.KeyTypeError with the expected and the
+// actual type of the key.
+func KeyTypeError(tx Tx, err error, key string, expected core.TypeID) error {
+	err = TypedError(err)
+	if err != core.ErrKeyType {
+		return err
+	}
+	var actual core.TypeID
+	now := Now(tx).UnixMilli()
+	if tx.QueryRow(sqlKeyType, key, now).Scan(&actual) != nil {
+		return err
+	}
+	return &core.KeyTypeError{Key: key, Expected: expected, Actual: actual}
+}
+
+// Scanner iterates over the query results page by page.
+type Scanner[T any] interface {
+	All() iter.Seq[T]
+	Err() error
+}
+
+// Iter returns an iterator over the scanner items
+// that yields the scanner error (if any) at the end.
+func Iter[T any](sc Scanner[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for item := range sc.All() {
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err := sc.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
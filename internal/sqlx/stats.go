@@ -0,0 +1,83 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// QueryStats collects the statistics of the SQL queries
+// executed with a context (see WithQueryStats).
+// Safe for concurrent use.
+type QueryStats struct {
+	rows     atomic.Int64
+	duration atomic.Int64
+}
+
+// RowsAffected returns the number of rows
+// inserted, updated or deleted by the queries.
+func (s *QueryStats) RowsAffected() int64 {
+	return s.rows.Load()
+}
+
+// Duration returns the time spent executing the queries.
+// Does not include the time spent reading the query results.
+func (s *QueryStats) Duration() time.Duration {
+	return time.Duration(s.duration.Load())
+}
+
+// statsKey is the context key for the query statistics.
+type statsKey struct{}
+
+// WithQueryStats returns a copy of the context that collects
+// the statistics of the queries executed with it into s.
+func WithQueryStats(ctx context.Context, s *QueryStats) context.Context {
+	return context.WithValue(ctx, statsKey{}, s)
+}
+
+// withStats wraps the transaction so that it collects the
+// query statistics into the context's QueryStats (if any).
+func withStats(ctx context.Context, tx Tx) Tx {
+	if ctx == nil {
+		return tx
+	}
+	stats, ok := ctx.Value(statsKey{}).(*QueryStats)
+	if !ok {
+		return tx
+	}
+	return &statsTx{tx: tx, stats: stats}
+}
+
+// statsTx collects the query statistics.
+type statsTx struct {
+	tx    Tx
+	stats *QueryStats
+}
+
+func (t *statsTx) Query(query string, args ...any) (*sql.Rows, error) {
+	defer t.track(time.Now())
+	return t.tx.Query(query, args...)
+}
+
+func (t *statsTx) QueryRow(query string, args ...any) *sql.Row {
+	defer t.track(time.Now())
+	return t.tx.QueryRow(query, args...)
+}
+
+func (t *statsTx) Exec(query string, args ...any) (sql.Result, error) {
+	defer t.track(time.Now())
+	res, err := t.tx.Exec(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		t.stats.rows.Add(n)
+	}
+	return res, nil
+}
+
+// track adds the query duration to the statistics.
+func (t *statsTx) track(start time.Time) {
+	t.stats.duration.Add(int64(time.Since(start)))
+}
@@ -0,0 +1,102 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+)
+
+// maxCachedStmts is the maximum number of prepared
+// statements kept by a writable transaction.
+const maxCachedStmts = 64
+
+// stmtTx is a writable transaction that prepares the write queries
+// executed more than once and reuses the prepared statements,
+// so that transactions with many similar writes (like batches
+// and pipelines) do not parse the same SQL over and over.
+// Only caches Exec queries: a statement can not be re-executed
+// while its rows are being read, so the queries run as usual.
+// Call close when the transaction ends.
+type stmtTx struct {
+	ctxTx
+	conn  *sql.Conn
+	stmts map[string]*cachedStmt // nil value means the query was seen once
+}
+
+// cachedStmt is a prepared statement
+// along with its named parameters.
+type cachedStmt struct {
+	stmt   *sql.Stmt
+	params map[string]bool
+}
+
+// paramRe matches the named parameters in a query.
+var paramRe = regexp.MustCompile(`[:@$]([A-Za-z_][A-Za-z0-9_]*)`)
+
+// newStmtTx creates a statement-caching transaction on the connection.
+func newStmtTx(ctx context.Context, conn *sql.Conn) *stmtTx {
+	return &stmtTx{
+		ctxTx: ctxTx{ctx: ctx, q: conn},
+		conn:  conn,
+		stmts: map[string]*cachedStmt{},
+	}
+}
+
+func (t *stmtTx) Exec(query string, args ...any) (sql.Result, error) {
+	cs, seen := t.stmts[query]
+	if !seen {
+		if len(t.stmts) < maxCachedStmts {
+			t.stmts[query] = nil
+		}
+		return t.ctxTx.Exec(query, args...)
+	}
+	if cs == nil {
+		stmt, err := t.conn.PrepareContext(t.ctx, query)
+		if err != nil {
+			return nil, TypedError(err)
+		}
+		cs = &cachedStmt{stmt: stmt, params: map[string]bool{}}
+		for _, m := range paramRe.FindAllStringSubmatch(query, -1) {
+			cs.params[m[1]] = true
+		}
+		t.stmts[query] = cs
+	}
+	res, err := cs.stmt.ExecContext(t.ctx, cs.bind(args)...)
+	if err != nil {
+		return nil, TypedError(err)
+	}
+	return res, nil
+}
+
+// bind drops the named arguments that are not used in the query.
+// Unlike the plain queries, the prepared statements require
+// the exact number of arguments, while the repositories often
+// pass the same set of arguments to several queries.
+func (cs *cachedStmt) bind(args []any) []any {
+	var bound []any
+	for i, arg := range args {
+		named, ok := arg.(sql.NamedArg)
+		if !ok || cs.params[named.Name] {
+			if bound != nil {
+				bound = append(bound, arg)
+			}
+			continue
+		}
+		if bound == nil {
+			bound = append(make([]any, 0, len(args)), args[:i]...)
+		}
+	}
+	if bound == nil {
+		return args
+	}
+	return bound
+}
+
+// close closes the prepared statements.
+func (t *stmtTx) close() {
+	for _, cs := range t.stmts {
+		if cs != nil {
+			_ = cs.stmt.Close()
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package sqlx
+
+import "time"
+
+// TrashOf returns the trash retention of the transaction
+// (see DB.Trash), or zero if the deleted keys should not
+// be kept in the trash.
+func TrashOf(tx Tx) time.Duration {
+	if etx, ok := tx.(*envTx); ok {
+		return etx.trash
+	}
+	return 0
+}
@@ -0,0 +1,108 @@
+package sqlx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrWriterClosed is returned when writing
+// through a writer after it has been closed.
+var ErrWriterClosed = errors.New("writer is closed")
+
+// Write job states.
+const (
+	jobQueued int32 = iota
+	jobStarted
+	jobCanceled
+)
+
+// Writer executes the write transactions one by one in a single
+// goroutine, so that they never contend for the database lock.
+// Safe for concurrent use.
+type Writer struct {
+	queue chan *writeJob
+	quit  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// writeJob is a queued write.
+type writeJob struct {
+	ctx   context.Context
+	f     func() error
+	state atomic.Int32
+	done  chan error
+}
+
+// NewWriter starts a writer with a queue of the given size.
+func NewWriter(size int) *Writer {
+	w := &Writer{
+		queue: make(chan *writeJob, max(size, 0)),
+		quit:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Do queues f and waits until the writer executes it.
+// Blocks while the queue is full. If ctx is canceled before
+// f starts, removes it from the queue and returns ctx.Err().
+// Once started, f runs to completion. If w is nil, calls f
+// in the current goroutine.
+func (w *Writer) Do(ctx context.Context, f func() error) error {
+	if w == nil {
+		return f()
+	}
+	job := &writeJob{ctx: ctx, f: f, done: make(chan error, 1)}
+	select {
+	case w.queue <- job:
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.quit:
+		return ErrWriterClosed
+	}
+	select {
+	case err := <-job.done:
+		return err
+	case <-ctx.Done():
+		if job.state.CompareAndSwap(jobQueued, jobCanceled) {
+			return ctx.Err()
+		}
+	case <-w.quit:
+		if job.state.CompareAndSwap(jobQueued, jobCanceled) {
+			return ErrWriterClosed
+		}
+	}
+	// The job has already started, so wait for it to finish.
+	return <-job.done
+}
+
+// Close stops the writer after the running write completes.
+// The queued writes fail with [ErrWriterClosed].
+func (w *Writer) Close() {
+	if w == nil {
+		return
+	}
+	w.once.Do(func() { close(w.quit) })
+	<-w.done
+}
+
+// run executes the queued writes until the writer is closed.
+func (w *Writer) run() {
+	defer close(w.done)
+	for {
+		select {
+		case <-w.quit:
+			return
+		case job := <-w.queue:
+			if !job.state.CompareAndSwap(jobQueued, jobStarted) {
+				// Canceled while in the queue.
+				continue
+			}
+			job.done <- job.f()
+		}
+	}
+}
@@ -3,6 +3,7 @@ package testx
 
 import (
 	"database/sql"
+	"errors"
 	"reflect"
 	"testing"
 
@@ -31,7 +32,7 @@ func AssertErr(tb testing.TB, got, want error) {
 		tb.Errorf("want %T (%v) error, got nil", want, want)
 		return
 	}
-	if got != want {
+	if !errors.Is(got, want) {
 		tb.Errorf("want %T (%v) error, got %T (%v)", want, want, got, got)
 		return
 	}
@@ -0,0 +1,75 @@
+package redka_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestLimits(t *testing.T) {
+	db, err := redka.Open(":memory:", redka.WithLimits(10, 20, 3))
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	t.Run("key size", func(t *testing.T) {
+		long := strings.Repeat("k", 11)
+		err := db.Str().Set(long, "alice")
+		testx.AssertErr(t, err, redka.ErrKeyTooLarge)
+		_, err = db.Hash().Set(long, "name", "alice")
+		testx.AssertErr(t, err, redka.ErrKeyTooLarge)
+		_, err = db.SortedSet().Add(long, "alice", 11)
+		testx.AssertErr(t, err, redka.ErrKeyTooLarge)
+
+		_ = db.Str().Set("name", "alice")
+		err = db.Key().Rename("name", long)
+		testx.AssertErr(t, err, redka.ErrKeyTooLarge)
+
+		err = db.Str().Set(strings.Repeat("k", 10), "alice")
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("value size", func(t *testing.T) {
+		big := strings.Repeat("v", 21)
+		err := db.Str().Set("name", big)
+		testx.AssertErr(t, err, redka.ErrValueTooLarge)
+		err = db.Str().Set("name", []byte(big))
+		testx.AssertErr(t, err, redka.ErrValueTooLarge)
+		err = db.Str().SetReader("name", strings.NewReader(big), int64(len(big)), 0)
+		testx.AssertErr(t, err, redka.ErrValueTooLarge)
+		_, err = db.Hash().Set("person", "name", big)
+		testx.AssertErr(t, err, redka.ErrValueTooLarge)
+		_, err = db.Hash().Set("person", big, "alice")
+		testx.AssertErr(t, err, redka.ErrValueTooLarge)
+		_, err = db.SortedSet().Add("scores", big, 11)
+		testx.AssertErr(t, err, redka.ErrValueTooLarge)
+
+		val, _ := db.Str().Get("name")
+		testx.AssertEqual(t, val.String(), "alice")
+	})
+	t.Run("elements", func(t *testing.T) {
+		_, err := db.Hash().SetMany("person", map[string]any{
+			"name": "alice", "age": 25, "city": "paris",
+		})
+		testx.AssertNoErr(t, err)
+		_, err = db.Hash().Set("person", "name", "bob")
+		testx.AssertNoErr(t, err)
+		_, err = db.Hash().Set("person", "country", "france")
+		testx.AssertErr(t, err, redka.ErrTooManyElements)
+		n, _ := db.Hash().Len("person")
+		testx.AssertEqual(t, n, 3)
+
+		_, err = db.SortedSet().AddMany("scores", map[any]float64{
+			"alice": 11, "bob": 22, "cindy": 33, "dave": 44,
+		})
+		testx.AssertErr(t, err, redka.ErrTooManyElements)
+		n, _ = db.SortedSet().Len("scores")
+		testx.AssertEqual(t, n, 0)
+	})
+	t.Run("transaction", func(t *testing.T) {
+		err := db.Update(func(tx *redka.Tx) error {
+			return tx.Str().Set("name", strings.Repeat("v", 21))
+		})
+		testx.AssertErr(t, err, redka.ErrValueTooLarge)
+	})
+}
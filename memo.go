@@ -0,0 +1,86 @@
+package redka
+
+import "time"
+
+// MemoOptions configures a memoized function.
+type MemoOptions struct {
+	// TTL is how long a computed result is cached.
+	// If zero, results do not expire.
+	TTL time.Duration
+	// NegativeTTL is how long a "not found" result (an error matching
+	// [ErrNotFound]) is cached. If zero, uses [CacheOptions.NegativeTTL].
+	NegativeTTL time.Duration
+	// Codec encodes the arguments and results of types without
+	// a natural string representation. If nil, uses [Options.Codec].
+	Codec Codec
+}
+
+// Memoized result markers. The "not found" results are cached
+// by the Cache (see CacheOptions.NegativeTTL), memoNotFound is
+// only read from the older entries.
+const (
+	memoValue    = 'v'
+	memoNotFound = 'n'
+)
+
+// Memoize wraps the function so that its results are cached
+// in the database under the keys derived from the name and the
+// argument (like "user:42"). Use a struct argument for functions
+// with several parameters. Concurrent calls with the same argument
+// are coalesced (see [Cache.GetOrCompute]).
+//
+// Arguments and results are encoded like in [Set]. The errors
+// returned by fn are not cached, except for the "not found" ones
+// if the negative TTL is set (see MemoOptions.NegativeTTL). In this
+// case, the memoized function returns [ErrNotFound] until the cached
+// result expires.
+//
+// The opts parameter is optional. If nil, uses default options.
+func Memoize[A, R any](c *Cache, name string, fn func(A) (R, error), opts *MemoOptions) func(A) (R, error) {
+	var o MemoOptions
+	if opts != nil {
+		o = *opts
+	}
+	if o.Codec == nil {
+		o.Codec = c.db.codec
+	}
+	if o.NegativeTTL == 0 {
+		o.NegativeTTL = c.opts.NegativeTTL
+	}
+
+	return func(arg A) (R, error) {
+		var zero R
+		akey, err := encodeValue(o.Codec, arg)
+		if err != nil {
+			return zero, err
+		}
+		key := name + ":" + string(akey)
+
+		data, err := c.getOrCompute(key, o.TTL, o.NegativeTTL, func() ([]byte, error) {
+			res, err := fn(arg)
+			if err != nil {
+				return nil, err
+			}
+			data, err := encodeValue(o.Codec, res)
+			if err != nil {
+				return nil, err
+			}
+			return append([]byte{memoValue}, data...), nil
+		})
+		if err != nil {
+			return zero, err
+		}
+
+		if len(data) == 0 {
+			return zero, ErrValueType
+		}
+		switch data[0] {
+		case memoValue:
+			return decodeValue[R](o.Codec, data[1:])
+		case memoNotFound:
+			return zero, ErrNotFound
+		default:
+			return zero, ErrValueType
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package redka_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestMemoize(t *testing.T) {
+	t.Run("cached", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		var calls int
+		getUser := redka.Memoize(db.Cache(nil), "user", func(id int) (person, error) {
+			calls++
+			return person{Name: fmt.Sprintf("user-%d", id), Age: id}, nil
+		}, &redka.MemoOptions{TTL: time.Minute})
+
+		user, err := getUser(25)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, user, person{Name: "user-25", Age: 25})
+		user, err = getUser(25)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, user, person{Name: "user-25", Age: 25})
+		testx.AssertEqual(t, calls, 1)
+
+		_, _ = getUser(30)
+		testx.AssertEqual(t, calls, 2)
+
+		key, err := db.Key().Get("user:25")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("struct argument", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		type args struct{ A, B int }
+		var calls int
+		sum := redka.Memoize(db.Cache(nil), "sum", func(a args) (int, error) {
+			calls++
+			return a.A + a.B, nil
+		}, nil)
+
+		n, _ := sum(args{1, 2})
+		testx.AssertEqual(t, n, 3)
+		n, _ = sum(args{1, 2})
+		testx.AssertEqual(t, n, 3)
+		n, _ = sum(args{2, 2})
+		testx.AssertEqual(t, n, 4)
+		testx.AssertEqual(t, calls, 2)
+	})
+	t.Run("error", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		errFailed := errors.New("failed")
+		var calls int
+		fn := redka.Memoize(db.Cache(nil), "fail", func(id int) (int, error) {
+			calls++
+			return 0, errFailed
+		}, nil)
+
+		_, err := fn(1)
+		testx.AssertErr(t, err, errFailed)
+		_, err = fn(1)
+		testx.AssertErr(t, err, errFailed)
+		testx.AssertEqual(t, calls, 2)
+	})
+	t.Run("negative", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		var calls int
+		fn := redka.Memoize(db.Cache(nil), "user", func(id int) (string, error) {
+			calls++
+			return "", fmt.Errorf("user %d: %w", id, redka.ErrNotFound)
+		}, &redka.MemoOptions{TTL: time.Minute, NegativeTTL: time.Minute})
+
+		_, err := fn(1)
+		testx.AssertErr(t, err, redka.ErrNotFound)
+		_, err = fn(1)
+		testx.AssertErr(t, err, redka.ErrNotFound)
+		testx.AssertEqual(t, calls, 1)
+	})
+	t.Run("codec", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		fn := redka.Memoize(db.Cache(nil), "user", func(id int) (person, error) {
+			return person{Name: "alice", Age: id}, nil
+		}, &redka.MemoOptions{Codec: gobCodec{}})
+
+		_, _ = fn(25)
+		val, _ := db.Str().Get("user:25")
+		var p person
+		err := gob.NewDecoder(bytes.NewReader(val.Bytes()[1:])).Decode(&p)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, p, person{Name: "alice", Age: 25})
+
+		user, err := fn(25)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, user, p)
+	})
+}
@@ -0,0 +1,52 @@
+package redka
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+)
+
+// memoryDSN returns the data source name of the shared-cache
+// in-memory database with the given name. If the name is empty,
+// generates a unique one, so that the database is private.
+func memoryDSN(name string) string {
+	if name == "" {
+		b := make([]byte, 8)
+		_, _ = rand.Read(b)
+		name = "redka-" + hex.EncodeToString(b)
+	}
+	return "file:" + url.PathEscape(name) + "?mode=memory&cache=shared"
+}
+
+// memoryAnchor keeps an in-memory database alive.
+//
+// SQLite deletes a memory database as soon as its last connection
+// is closed, and the connection pool may close connections at any
+// time (e.g. after a driver error). The anchor holds a separate
+// connection for the lifetime of the DB, so the data is not lost.
+type memoryAnchor struct {
+	db   *sql.DB
+	conn *sql.Conn
+}
+
+// openMemoryAnchor opens a connection to the in-memory database.
+func openMemoryAnchor(driverName, dsn string, key *cipherKey) (*memoryAnchor, error) {
+	db, err := openSQL(driverName, dsn, key, "")
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &memoryAnchor{db: db, conn: conn}, nil
+}
+
+// Close releases the database.
+func (a *memoryAnchor) Close() error {
+	_ = a.conn.Close()
+	return a.db.Close()
+}
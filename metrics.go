@@ -0,0 +1,298 @@
+package redka
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+)
+
+const sqlPageStats = `
+select page_count, page_size, freelist_count, cache_size
+from pragma_page_count, pragma_page_size,
+     pragma_freelist_count, pragma_cache_size`
+
+// latencyBuckets are the upper bounds (in seconds)
+// of the operation latency histogram buckets.
+var latencyBuckets = []float64{
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005,
+	0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1,
+}
+
+// opFamilies maps the repository names (the operation name
+// prefixes) to the operation families (the key types).
+var opFamilies = map[string]string{
+	"Key":       "key",
+	"Str":       "string",
+	"Hash":      "hash",
+	"SortedSet": "zset",
+	"Stream":    "stream",
+	"HLL":       "hll",
+}
+
+// Metrics collects the database metrics and exposes them in the
+// Prometheus text format:
+//
+//   - operation counts, errors and latencies per operation family
+//     (key, string, hash, zset, stream, hll) and operation;
+//   - key counts per type and by time to expiry (to forecast
+//     the expiration-driven deletes and cache misses);
+//   - expired keys and lazily freed values;
+//   - write transaction retries (see [Options.BusyRetry]);
+//   - quota usage, limits and rejected writes per key prefix
+//     (see [Options.Quotas]);
+//   - SQLite stats (WAL size, checkpoints, pages and page cache).
+//
+// Metrics is an [http.Handler], so it can serve the metrics
+// to Prometheus directly. Safe for concurrent use.
+type Metrics struct {
+	db  *DB
+	mu  sync.Mutex
+	ops map[string]*opMetrics
+}
+
+// opMetrics are the metrics of a single operation.
+type opMetrics struct {
+	count   int64
+	errors  int64
+	sum     float64 // seconds
+	buckets []int64 // non-cumulative counts per latency bucket
+}
+
+// NewMetrics creates the metrics of the database and starts
+// collecting the operation metrics (see [DB.AddHook]).
+// Create it once per database.
+func NewMetrics(db *DB) *Metrics {
+	m := &Metrics{db: db, ops: map[string]*opMetrics{}}
+	db.AddHook(m)
+	return m
+}
+
+// Metrics returns the metrics created with Options.Metrics,
+// or nil if the metrics are not enabled.
+func (db *DB) Metrics() *Metrics {
+	return db.metrics
+}
+
+// Before implements [Hook].
+func (m *Metrics) Before(ctx context.Context, op *Op) {}
+
+// After implements [Hook]. Records the operation count and latency.
+// ErrNotFound is not counted as an error.
+func (m *Metrics) After(ctx context.Context, op *Op) {
+	sec := op.Duration.Seconds()
+	idx, _ := slices.BinarySearch(latencyBuckets, sec)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	om, ok := m.ops[op.Name]
+	if !ok {
+		om = &opMetrics{buckets: make([]int64, len(latencyBuckets)+1)}
+		m.ops[op.Name] = om
+	}
+	om.count++
+	if op.Err != nil && !errors.Is(op.Err, core.ErrNotFound) {
+		om.errors++
+	}
+	om.sum += sec
+	om.buckets[idx]++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if _, err := m.WriteTo(w); err != nil {
+		m.db.log.Error("metrics", "error", err)
+	}
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	cw := &countWriter{w: w}
+	b := bufio.NewWriter(cw)
+	m.writeOps(b)
+	if err := m.writeKeys(b); err != nil {
+		return cw.n, err
+	}
+	m.writeCounters(b)
+	m.writeQuotas(b)
+	if err := m.writeSQLite(b); err != nil {
+		return cw.n, err
+	}
+	err := b.Flush()
+	return cw.n, err
+}
+
+// writeOps writes the operation metrics.
+func (m *Metrics) writeOps(b *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := slices.Sorted(maps.Keys(m.ops))
+	labels := make([]string, len(names))
+	for i, name := range names {
+		prefix, op, _ := strings.Cut(name, ".")
+		family, ok := opFamilies[prefix]
+		if !ok {
+			family = strings.ToLower(prefix)
+		}
+		labels[i] = fmt.Sprintf("family=%q,op=%q", family, op)
+	}
+
+	writeHeader(b, "redka_ops_total", "counter", "Number of operations.")
+	for i, name := range names {
+		fmt.Fprintf(b, "redka_ops_total{%s} %d\n", labels[i], m.ops[name].count)
+	}
+	writeHeader(b, "redka_op_errors_total", "counter", "Number of failed operations.")
+	for i, name := range names {
+		fmt.Fprintf(b, "redka_op_errors_total{%s} %d\n", labels[i], m.ops[name].errors)
+	}
+	writeHeader(b, "redka_op_duration_seconds", "histogram", "Operation latency.")
+	for i, name := range names {
+		om := m.ops[name]
+		var cum int64
+		for j, le := range latencyBuckets {
+			cum += om.buckets[j]
+			fmt.Fprintf(b, "redka_op_duration_seconds_bucket{%s,le=%q} %d\n",
+				labels[i], formatFloat(le), cum)
+		}
+		fmt.Fprintf(b, "redka_op_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", labels[i], om.count)
+		fmt.Fprintf(b, "redka_op_duration_seconds_sum{%s} %s\n", labels[i], formatFloat(om.sum))
+		fmt.Fprintf(b, "redka_op_duration_seconds_count{%s} %d\n", labels[i], om.count)
+	}
+}
+
+// writeKeys writes the key counts per type and by time to expiry.
+func (m *Metrics) writeKeys(b *bufio.Writer) error {
+	counts, err := m.db.keyDB.CountByType()
+	if err != nil {
+		return err
+	}
+	writeHeader(b, "redka_keys", "gauge", "Number of keys per type.")
+	for _, typ := range []core.TypeID{core.TypeString, core.TypeHash, core.TypeSortedSet, core.TypeStream, core.TypeHLL} {
+		name := core.Key{Type: typ}.TypeName()
+		fmt.Fprintf(b, "redka_keys{type=%q} %d\n", name, counts[typ])
+	}
+
+	expiry, err := m.db.keyDB.Expiry()
+	if err != nil {
+		return err
+	}
+	writeHeader(b, "redka_keys_expiring", "gauge",
+		"Number of keys expiring within the period (cumulative, +Inf for all keys with a TTL).")
+	fmt.Fprintf(b, "redka_keys_expiring{within=\"1m\"} %d\n", expiry.Minute)
+	fmt.Fprintf(b, "redka_keys_expiring{within=\"1h\"} %d\n", expiry.Hour)
+	fmt.Fprintf(b, "redka_keys_expiring{within=\"1d\"} %d\n", expiry.Day)
+	fmt.Fprintf(b, "redka_keys_expiring{within=\"+Inf\"} %d\n", expiry.Total)
+	return nil
+}
+
+// writeCounters writes the database counters.
+func (m *Metrics) writeCounters(b *bufio.Writer) {
+	stats := m.db.stats
+	writeHeader(b, "redka_expired_keys_total", "counter", "Number of expired keys deleted in the background.")
+	fmt.Fprintf(b, "redka_expired_keys_total %d\n", stats.expired.Load())
+	writeHeader(b, "redka_expire_sweeps_total", "counter", "Number of expired keys sweeps.")
+	fmt.Fprintf(b, "redka_expire_sweeps_total %d\n", stats.sweeps.Load())
+	writeHeader(b, "redka_expire_sweep_seconds_total", "counter", "Total duration of expired keys sweeps.")
+	fmt.Fprintf(b, "redka_expire_sweep_seconds_total %g\n", time.Duration(stats.sweepTime.Load()).Seconds())
+	writeHeader(b, "redka_freed_rows_total", "counter", "Number of unlinked keys and values deleted in the background.")
+	fmt.Fprintf(b, "redka_freed_rows_total %d\n", stats.freed.Load())
+	writeHeader(b, "redka_tx_retries_total", "counter", "Number of write transactions retried because the database was busy.")
+	fmt.Fprintf(b, "redka_tx_retries_total %d\n", stats.Retries.Load())
+	writeHeader(b, "redka_tx_commits_total", "counter", "Number of committed write transactions.")
+	fmt.Fprintf(b, "redka_tx_commits_total %d\n", stats.Commits.Load())
+	writeHeader(b, "redka_tx_rollbacks_total", "counter", "Number of rolled back write transactions.")
+	fmt.Fprintf(b, "redka_tx_rollbacks_total %d\n", stats.Rollbacks.Load())
+}
+
+// writeQuotas writes the quota usage, if there are quotas.
+func (m *Metrics) writeQuotas(b *bufio.Writer) {
+	usage := m.db.QuotaUsage()
+	if len(usage) == 0 {
+		return
+	}
+	writeHeader(b, "redka_quota_keys", "gauge", "Number of keys with the quota prefix.")
+	for _, u := range usage {
+		fmt.Fprintf(b, "redka_quota_keys{prefix=%q} %d\n", u.Prefix, u.Keys)
+	}
+	writeHeader(b, "redka_quota_bytes", "gauge", "Size of the keys with the quota prefix and their values.")
+	for _, u := range usage {
+		fmt.Fprintf(b, "redka_quota_bytes{prefix=%q} %d\n", u.Prefix, u.Bytes)
+	}
+	writeHeader(b, "redka_quota_limit", "gauge", "Quota limit per resource (0 for no limit).")
+	for _, u := range usage {
+		fmt.Fprintf(b, "redka_quota_limit{prefix=%q,resource=\"keys\"} %d\n", u.Prefix, u.MaxKeys)
+		fmt.Fprintf(b, "redka_quota_limit{prefix=%q,resource=\"bytes\"} %d\n", u.Prefix, u.MaxBytes)
+		fmt.Fprintf(b, "redka_quota_limit{prefix=%q,resource=\"ops\"} %d\n", u.Prefix, u.MaxOpsPerSec)
+	}
+	writeHeader(b, "redka_quota_rejected_total", "counter", "Number of writes rejected by the quota.")
+	for _, u := range usage {
+		for _, res := range []string{"keys", "bytes", "ops"} {
+			fmt.Fprintf(b, "redka_quota_rejected_total{prefix=%q,resource=%q} %d\n", u.Prefix, res, u.Rejected[res])
+		}
+	}
+}
+
+// writeSQLite writes the SQLite stats.
+func (m *Metrics) writeSQLite(b *bufio.Writer) error {
+	wal, err := m.db.WALStats()
+	if err != nil {
+		return err
+	}
+	var pageCount, pageSize, freelist, cacheSize int64
+	err = m.db.SQL.QueryRow(sqlPageStats).Scan(&pageCount, &pageSize, &freelist, &cacheSize)
+	if err != nil {
+		return err
+	}
+	// A negative cache size is in KiB, a positive one in pages.
+	cacheBytes := cacheSize * pageSize
+	if cacheSize < 0 {
+		cacheBytes = -cacheSize * 1024
+	}
+
+	writeHeader(b, "redka_wal_size_bytes", "gauge", "Size of the WAL file.")
+	fmt.Fprintf(b, "redka_wal_size_bytes %d\n", wal.Size)
+	writeHeader(b, "redka_wal_checkpoints_total", "counter", "Number of WAL checkpoints made by redka.")
+	fmt.Fprintf(b, "redka_wal_checkpoints_total %d\n", wal.Checkpoints)
+	writeHeader(b, "redka_db_pages", "gauge", "Number of pages in the database.")
+	fmt.Fprintf(b, "redka_db_pages %d\n", pageCount)
+	writeHeader(b, "redka_db_free_pages", "gauge", "Number of unused pages in the database.")
+	fmt.Fprintf(b, "redka_db_free_pages %d\n", freelist)
+	writeHeader(b, "redka_db_page_size_bytes", "gauge", "Size of a database page.")
+	fmt.Fprintf(b, "redka_db_page_size_bytes %d\n", pageSize)
+	writeHeader(b, "redka_page_cache_size_bytes", "gauge", "Maximum size of the page cache per connection.")
+	fmt.Fprintf(b, "redka_page_cache_size_bytes %d\n", cacheBytes)
+	return nil
+}
+
+// writeHeader writes the metric description.
+func writeHeader(b *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// formatFloat formats the float in the shortest exact form.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countWriter counts the bytes written.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
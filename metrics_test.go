@@ -0,0 +1,78 @@
+package redka_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestMetrics(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+	metrics := redka.NewMetrics(db)
+
+	_ = db.Str().Set("name", "alice")
+	_ = db.Str().Set("city", "paris")
+	_ = db.Str().SetExpires("session", "abc", 10*time.Minute)
+	_, _ = db.Str().Get("name")
+	_, _ = db.Hash().Set("person", "age", 25)
+	_, _ = db.Hash().Get("person", "missing")
+	_, _ = db.Hash().Set("name", "age", 25)
+
+	var b strings.Builder
+	n, err := metrics.WriteTo(&b)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, n, int64(b.Len()))
+	out := b.String()
+
+	for _, line := range []string{
+		"# TYPE redka_ops_total counter",
+		`redka_ops_total{family="string",op="Set"} 2`,
+		`redka_ops_total{family="string",op="SetExpires"} 1`,
+		`redka_ops_total{family="string",op="Get"} 1`,
+		`redka_ops_total{family="hash",op="Set"} 2`,
+		`redka_op_errors_total{family="string",op="Set"} 0`,
+		`redka_op_errors_total{family="hash",op="Get"} 0`,
+		`redka_op_errors_total{family="hash",op="Set"} 1`,
+		"# TYPE redka_op_duration_seconds histogram",
+		`redka_op_duration_seconds_bucket{family="string",op="Set",le="+Inf"} 2`,
+		`redka_op_duration_seconds_count{family="string",op="Set"} 2`,
+		`redka_keys{type="string"} 3`,
+		`redka_keys{type="hash"} 1`,
+		`redka_keys{type="zset"} 0`,
+		`redka_keys{type="stream"} 0`,
+		`redka_keys{type="hll"} 0`,
+		`redka_keys_expiring{within="1m"} 0`,
+		`redka_keys_expiring{within="1h"} 1`,
+		`redka_keys_expiring{within="1d"} 1`,
+		`redka_keys_expiring{within="+Inf"} 1`,
+		"redka_expired_keys_total 0",
+		"redka_expire_sweeps_total 0",
+		"redka_tx_retries_total 0",
+		"redka_tx_commits_total 4",
+		"redka_tx_rollbacks_total 1",
+		"redka_wal_size_bytes 0",
+		"# TYPE redka_db_pages gauge",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, out)
+		}
+	}
+}
+
+func TestMetricsHTTP(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+	metrics := redka.NewMetrics(db)
+	_ = db.Str().Set("name", "alice")
+
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	testx.AssertEqual(t, w.Code, 200)
+	testx.AssertEqual(t, strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain"), true)
+	testx.AssertEqual(t, strings.Contains(w.Body.String(), `redka_keys{type="string"} 1`), true)
+}
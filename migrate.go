@@ -0,0 +1,406 @@
+package redka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka/internal/rdb"
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+// defaultMigrateBatch is the default number of keys
+// fetched from the source server at once.
+const defaultMigrateBatch = 100
+
+// followInterval is the interval between applying
+// the changes caught by the keyspace notifications.
+const followInterval = 100 * time.Millisecond
+
+// MigrateOptions configures the migration from a Redis server.
+type MigrateOptions struct {
+	// User and Password are used to authenticate with the source server.
+	// If User is empty, authenticates as the default user.
+	User     string
+	Password string
+	// DB is the number of the source database to migrate.
+	DB int
+	// Match is the pattern of the keys to migrate.
+	// If empty, migrates all keys.
+	Match string
+	// BatchSize is the number of keys fetched from the source
+	// and imported in a single transaction. If zero, uses 100.
+	BatchSize int
+	// Follow enables tailing the keyspace notifications of the source
+	// to catch the writes made during and after the copy. The source must
+	// have notify-keyspace-events enabled (e.g. "KA"). In follow mode,
+	// Migrate keeps the database in sync until ctx is canceled.
+	Follow bool
+	// Progress is called after each imported batch.
+	// If nil, it is ignored.
+	Progress func(MigrateStats)
+}
+
+// MigrateStats describes the state of the migration.
+type MigrateStats struct {
+	Scanned int // number of keys read from the source
+	Keys    int // number of imported keys
+	Skipped int // keys of types not supported by Redka
+	Updated int // keys updated by the keyspace notifications
+	Deleted int // keys deleted by the keyspace notifications
+}
+
+// Migrate copies the keys from a running Redis server at addr
+// (host:port) into the database. Iterates over the source keys
+// with SCAN and transfers them with DUMP and PTTL, so it does not
+// block the source server. Supports strings, hashes and sorted sets
+// (along with their TTLs), and skips the keys of other types.
+// Existing keys with the same names are replaced.
+//
+// With opts.Follow set, Migrate also tails the keyspace notifications
+// of the source server, applying the changes made during the copy
+// and after it, until ctx is canceled. This allows switching the
+// clients from the source server to Redka with near-zero downtime.
+// In follow mode, canceling ctx after the copy is complete
+// is not an error.
+//
+// The opts parameter is optional. If nil, uses default options.
+func (db *DB) Migrate(ctx context.Context, addr string, opts *MigrateOptions) (MigrateStats, error) {
+	if opts == nil {
+		opts = &MigrateOptions{}
+	}
+	m := &migrator{db: db, addr: addr, opts: opts, dirty: map[string]struct{}{}}
+	if m.opts.BatchSize <= 0 {
+		m.opts.BatchSize = defaultMigrateBatch
+	}
+	if err := m.run(ctx); err != nil {
+		return m.stats, err
+	}
+	db.log.Info("migrate", "source", addr, "keys", m.stats.Keys,
+		"skipped", m.stats.Skipped, "updated", m.stats.Updated,
+		"deleted", m.stats.Deleted)
+	return m.stats, nil
+}
+
+// migrator copies keys from a Redis server.
+type migrator struct {
+	db    *DB
+	addr  string
+	opts  *MigrateOptions
+	src   *resp.Client
+	stats MigrateStats
+
+	mu      sync.Mutex
+	dirty   map[string]struct{} // keys changed at the source
+	subErr  error               // subscription error
+	subDone chan struct{}
+}
+
+// run performs the migration.
+func (m *migrator) run(ctx context.Context) error {
+	var err error
+	m.src, err = m.connect(ctx)
+	if err != nil {
+		return err
+	}
+	defer m.src.Close()
+	stop := context.AfterFunc(ctx, func() { m.src.Close() })
+	defer stop()
+
+	// Subscribe before copying, so that no changes are missed.
+	if m.opts.Follow {
+		if err := m.subscribe(ctx); err != nil {
+			return err
+		}
+	}
+
+	if err := m.copy(ctx); err != nil {
+		return ctxErr(ctx, err)
+	}
+	if !m.opts.Follow {
+		return nil
+	}
+
+	ticker := time.NewTicker(followInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-m.subDone:
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("migrate: keyspace notifications: %w", m.subErr)
+		case <-ticker.C:
+			if err := m.applyDirty(); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+}
+
+// connect connects to the source server,
+// authenticates and selects the database.
+func (m *migrator) connect(ctx context.Context) (*resp.Client, error) {
+	c, err := resp.Dial(ctx, m.addr)
+	if err != nil {
+		return nil, err
+	}
+	if m.opts.Password != "" {
+		args := []string{"auth", m.opts.Password}
+		if m.opts.User != "" {
+			args = []string{"auth", m.opts.User, m.opts.Password}
+		}
+		if _, err := c.Do(args...); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if m.opts.DB != 0 {
+		if _, err := c.Do("select", strconv.Itoa(m.opts.DB)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// copy iterates over the source keys and imports them in batches.
+func (m *migrator) copy(ctx context.Context) error {
+	match := m.opts.Match
+	if match == "" {
+		match = "*"
+	}
+	cursor := "0"
+	for {
+		reply, err := m.src.Do("scan", cursor, "match", match,
+			"count", strconv.Itoa(m.opts.BatchSize))
+		if err != nil {
+			return err
+		}
+		var keys []string
+		cursor, keys, err = parseScan(reply)
+		if err != nil {
+			return err
+		}
+		m.stats.Scanned += len(keys)
+
+		if len(keys) > 0 {
+			if err := m.transfer(keys, false); err != nil {
+				return err
+			}
+			if m.opts.Progress != nil {
+				m.opts.Progress(m.stats)
+			}
+		}
+		if cursor == "0" {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// transfer fetches the keys from the source and imports them
+// in a single transaction. If sync is true, the keys that no longer
+// exist at the source are deleted from the database.
+func (m *migrator) transfer(keys []string, sync bool) error {
+	for _, key := range keys {
+		_ = m.src.Send("dump", key)
+		_ = m.src.Send("pttl", key)
+	}
+	if err := m.src.Flush(); err != nil {
+		return err
+	}
+
+	var entries []rdb.Entry
+	var missing []string
+	now := time.Now().UnixMilli()
+	for _, key := range keys {
+		payload, err := m.src.Receive()
+		if err != nil {
+			return err
+		}
+		pttl, err := m.src.Receive()
+		if err != nil {
+			return err
+		}
+		if payload == nil {
+			// The key was deleted or has expired.
+			missing = append(missing, key)
+			continue
+		}
+		data, ok := payload.([]byte)
+		if !ok {
+			return fmt.Errorf("migrate: unexpected dump reply for %s", key)
+		}
+		e, err := rdb.ParseDump(data)
+		if err != nil {
+			return fmt.Errorf("migrate: parse %s: %w", key, err)
+		}
+		if !isImportType(e.Type) {
+			m.stats.Skipped++
+			continue
+		}
+		e.Key = key
+		if ms, ok := pttl.(int64); ok && ms >= 0 {
+			etime := now + ms
+			e.ETime = &etime
+		}
+		entries = append(entries, e)
+	}
+
+	err := m.db.Update(func(tx *Tx) error {
+		for _, e := range entries {
+			if err := importEntry(tx, e); err != nil {
+				return err
+			}
+		}
+		if sync && len(missing) > 0 {
+			if _, err := tx.Key().Delete(missing...); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if sync {
+		m.stats.Updated += len(entries)
+		m.stats.Deleted += len(missing)
+	} else {
+		m.stats.Keys += len(entries)
+	}
+	return nil
+}
+
+// subscribe subscribes to the keyspace notifications of the
+// source database and collects the changed keys in the background.
+func (m *migrator) subscribe(ctx context.Context) error {
+	c, err := m.connect(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Make sure the notifications are enabled. Managed Redis services
+	// often disable the CONFIG command, so failing to get the config
+	// is not an error.
+	reply, err := c.Do("config", "get", "notify-keyspace-events")
+	if err == nil {
+		if flags := parseConfig(reply); !strings.Contains(flags, "K") ||
+			!(strings.Contains(flags, "A") || strings.Contains(flags, "g")) {
+			c.Close()
+			return errors.New("migrate: keyspace notifications are disabled " +
+				"at the source (set notify-keyspace-events to KA)")
+		}
+	}
+
+	prefix := fmt.Sprintf("__keyspace@%d__:", m.opts.DB)
+	pattern := prefix + "*"
+	if m.opts.Match != "" {
+		pattern = prefix + m.opts.Match
+	}
+	if _, err := c.Do("psubscribe", pattern); err != nil {
+		c.Close()
+		return err
+	}
+
+	m.subDone = make(chan struct{})
+	stop := context.AfterFunc(ctx, func() { c.Close() })
+	go func() {
+		defer close(m.subDone)
+		defer stop()
+		defer c.Close()
+		for {
+			reply, err := c.Receive()
+			if err != nil {
+				m.subErr = err
+				return
+			}
+			// pmessage <pattern> <channel> <event>
+			msg, ok := reply.([]any)
+			if !ok || len(msg) != 4 {
+				continue
+			}
+			channel, _ := msg[2].([]byte)
+			key, found := strings.CutPrefix(string(channel), prefix)
+			if !found {
+				continue
+			}
+			m.mu.Lock()
+			m.dirty[key] = struct{}{}
+			m.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+// applyDirty re-transfers the keys changed at the source.
+func (m *migrator) applyDirty() error {
+	m.mu.Lock()
+	keys := make([]string, 0, len(m.dirty))
+	for key := range m.dirty {
+		keys = append(keys, key)
+	}
+	clear(m.dirty)
+	m.mu.Unlock()
+
+	for len(keys) > 0 {
+		n := min(len(keys), m.opts.BatchSize)
+		if err := m.transfer(keys[:n], true); err != nil {
+			return err
+		}
+		keys = keys[n:]
+		if m.opts.Progress != nil {
+			m.opts.Progress(m.stats)
+		}
+	}
+	return nil
+}
+
+// parseScan parses the SCAN reply into the next cursor and the keys.
+func parseScan(reply any) (string, []string, error) {
+	items, ok := reply.([]any)
+	if !ok || len(items) != 2 {
+		return "", nil, errors.New("migrate: unexpected scan reply")
+	}
+	cursor, _ := items[0].([]byte)
+	rawKeys, _ := items[1].([]any)
+	keys := make([]string, 0, len(rawKeys))
+	for _, k := range rawKeys {
+		if b, ok := k.([]byte); ok {
+			keys = append(keys, string(b))
+		}
+	}
+	return string(cursor), keys, nil
+}
+
+// parseConfig returns the value from the CONFIG GET reply.
+func parseConfig(reply any) string {
+	items, ok := reply.([]any)
+	if !ok || len(items) != 2 {
+		return ""
+	}
+	val, _ := items[1].([]byte)
+	return string(val)
+}
+
+// ctxErr returns the context error if the context is done,
+// otherwise returns err. Used to report cancellation
+// instead of the resulting connection errors.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
@@ -0,0 +1,305 @@
+package redka_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rdb"
+	"github.com/nalgeon/redka/internal/resp"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Run("copy", func(t *testing.T) {
+		src := newFakeRedis(t)
+		src.set("name", rdb.Entry{Type: core.TypeString, Str: []byte("alice")}, -1)
+		src.set("tmp", rdb.Entry{Type: core.TypeString, Str: []byte("x")}, 60000)
+		src.set("person", rdb.Entry{Type: core.TypeHash, Hash: map[string][]byte{"age": []byte("25")}}, -1)
+		src.set("scores", rdb.Entry{Type: core.TypeSortedSet, ZSet: map[string]float64{"one": 1}}, -1)
+		src.set("tags", rdb.Entry{Type: core.TypeSet, List: [][]byte{[]byte("a")}}, -1)
+
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "replaced")
+
+		var batches int
+		opts := &redka.MigrateOptions{
+			BatchSize: 2,
+			Progress:  func(redka.MigrateStats) { batches++ },
+		}
+		stats, err := db.Migrate(context.Background(), src.addr(), opts)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, stats.Scanned, 5)
+		testx.AssertEqual(t, stats.Keys, 4)
+		testx.AssertEqual(t, stats.Skipped, 1)
+		testx.AssertEqual(t, batches, 3)
+
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+		key, _ := db.Key().Get("tmp")
+		testx.AssertEqual(t, key.ETime != nil, true)
+		ttl := time.Until(time.UnixMilli(*key.ETime))
+		testx.AssertEqual(t, ttl > 50*time.Second && ttl <= 60*time.Second, true)
+		age, _ := db.Hash().Get("person", "age")
+		testx.AssertEqual(t, age.String(), "25")
+		score, _ := db.SortedSet().GetScore("scores", "one")
+		testx.AssertEqual(t, score, 1.0)
+		count, _ := db.Key().Count("tags")
+		testx.AssertEqual(t, count, 0)
+	})
+	t.Run("match", func(t *testing.T) {
+		src := newFakeRedis(t)
+		src.set("user:1", rdb.Entry{Type: core.TypeString, Str: []byte("alice")}, -1)
+		src.set("order:1", rdb.Entry{Type: core.TypeString, Str: []byte("x")}, -1)
+
+		db := getDB(t)
+		defer db.Close()
+
+		stats, err := db.Migrate(context.Background(), src.addr(), &redka.MigrateOptions{Match: "user:*"})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, stats.Keys, 1)
+		count, _ := db.Key().Count("user:1", "order:1")
+		testx.AssertEqual(t, count, 1)
+	})
+	t.Run("follow", func(t *testing.T) {
+		src := newFakeRedis(t)
+		src.set("name", rdb.Entry{Type: core.TypeString, Str: []byte("alice")}, -1)
+		src.set("city", rdb.Entry{Type: core.TypeString, Str: []byte("paris")}, -1)
+
+		db := getDB(t)
+		defer db.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		var stats redka.MigrateStats
+		go func() {
+			var err error
+			stats, err = db.Migrate(ctx, src.addr(), &redka.MigrateOptions{Follow: true})
+			done <- err
+		}()
+
+		waitFor(t, func() bool {
+			count, _ := db.Key().Count("name", "city")
+			return count == 2
+		})
+
+		src.set("name", rdb.Entry{Type: core.TypeString, Str: []byte("bob")}, -1)
+		src.del("city")
+		waitFor(t, func() bool {
+			name, _ := db.Str().Get("name")
+			count, _ := db.Key().Count("city")
+			return name.String() == "bob" && count == 0
+		})
+
+		cancel()
+		testx.AssertNoErr(t, <-done)
+		testx.AssertEqual(t, stats.Keys, 2)
+		testx.AssertEqual(t, stats.Updated, 1)
+		testx.AssertEqual(t, stats.Deleted, 1)
+	})
+	t.Run("notifications disabled", func(t *testing.T) {
+		src := newFakeRedis(t)
+		src.notify = ""
+
+		db := getDB(t)
+		defer db.Close()
+
+		_, err := db.Migrate(context.Background(), src.addr(), &redka.MigrateOptions{Follow: true})
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
+
+// fakeRedis is a fake Redis server that supports
+// the commands used by the migration and the tier.
+type fakeRedis struct {
+	ln     net.Listener
+	mu     sync.Mutex
+	keys   map[string]rdb.Entry
+	ttls   map[string]int64
+	subs   []net.Conn
+	notify string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	testx.AssertNoErr(t, err)
+	t.Cleanup(func() { ln.Close() })
+	r := &fakeRedis{
+		ln:     ln,
+		keys:   map[string]rdb.Entry{},
+		ttls:   map[string]int64{},
+		notify: "KA",
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() })
+			go r.serve(conn)
+		}
+	}()
+	return r
+}
+
+func (r *fakeRedis) addr() string {
+	return r.ln.Addr().String()
+}
+
+// set sets the key and notifies the subscribers.
+// A negative ttl means no expiration.
+func (r *fakeRedis) set(key string, e rdb.Entry, ttl int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[key] = e
+	r.ttls[key] = ttl
+	r.publish(key, "set")
+}
+
+// del deletes the key and notifies the subscribers.
+func (r *fakeRedis) del(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, key)
+	delete(r.ttls, key)
+	r.publish(key, "del")
+}
+
+// get returns the string value and ttl of the key.
+func (r *fakeRedis) get(key string) (string, int64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e, ok := r.keys[key]
+	return string(e.Str), r.ttls[key], ok
+}
+
+// publish sends a keyspace notification to the subscribers.
+func (r *fakeRedis) publish(key, event string) {
+	pattern := "__keyspace@0__:*"
+	channel := "__keyspace@0__:" + key
+	msg := fmt.Sprintf("*4\r\n$8\r\npmessage\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n",
+		len(pattern), pattern, len(channel), channel, len(event), event)
+	for _, conn := range r.subs {
+		_, _ = conn.Write([]byte(msg))
+	}
+}
+
+func (r *fakeRedis) serve(conn net.Conn) {
+	rd := bufio.NewReader(conn)
+	for {
+		args, _, err := resp.ReadCommand(rd)
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		reply := r.handle(conn, args)
+		r.mu.Unlock()
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (r *fakeRedis) handle(conn net.Conn, args [][]byte) string {
+	switch strings.ToLower(string(args[0])) {
+	case "scan":
+		// SCAN cursor MATCH pattern COUNT count
+		cursor, _ := strconv.Atoi(string(args[1]))
+		pattern := string(args[3])
+		count, _ := strconv.Atoi(string(args[5]))
+		var names []string
+		for name := range r.keys {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		end := min(cursor+count, len(names))
+		next := end
+		if end == len(names) {
+			next = 0
+		}
+		var b strings.Builder
+		var keys []string
+		for _, name := range names[cursor:end] {
+			if pattern == "*" || strings.HasPrefix(name, strings.TrimSuffix(pattern, "*")) {
+				keys = append(keys, name)
+			}
+		}
+		fmt.Fprintf(&b, "*2\r\n%s*%d\r\n", bulk(strconv.Itoa(next)), len(keys))
+		for _, key := range keys {
+			b.WriteString(bulk(key))
+		}
+		return b.String()
+	case "dump":
+		e, ok := r.keys[string(args[1])]
+		if !ok {
+			return "$-1\r\n"
+		}
+		payload, _ := rdb.AppendDump(nil, e)
+		return bulk(string(payload))
+	case "pttl":
+		ttl, ok := r.ttls[string(args[1])]
+		if !ok {
+			return ":-2\r\n"
+		}
+		return ":" + strconv.FormatInt(ttl, 10) + "\r\n"
+	case "get":
+		e, ok := r.keys[string(args[1])]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(string(e.Str))
+	case "set":
+		// SET key value [PX ms]
+		key := string(args[1])
+		r.keys[key] = rdb.Entry{Type: core.TypeString, Str: args[2]}
+		r.ttls[key] = -1
+		if len(args) == 5 {
+			r.ttls[key], _ = strconv.ParseInt(string(args[4]), 10, 64)
+		}
+		return "+OK\r\n"
+	case "del":
+		n := 0
+		for _, arg := range args[1:] {
+			if _, ok := r.keys[string(arg)]; ok {
+				delete(r.keys, string(arg))
+				delete(r.ttls, string(arg))
+				n++
+			}
+		}
+		return ":" + strconv.Itoa(n) + "\r\n"
+	case "config":
+		return "*2\r\n" + bulk("notify-keyspace-events") + bulk(r.notify)
+	case "psubscribe":
+		r.subs = append(r.subs, conn)
+		return "*3\r\n" + bulk("psubscribe") + bulk(string(args[1])) + ":1\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func bulk(s string) string {
+	return "$" + strconv.Itoa(len(s)) + "\r\n" + s + "\r\n"
+}
+
+// waitFor waits until the condition is true.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
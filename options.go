@@ -0,0 +1,221 @@
+package redka
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Option configures the database opened with [Open] or [OpenDB].
+// Both the *[Options] and the With* functions are options,
+// so they can be combined:
+//
+//	db, err := redka.Open("data.db",
+//	    redka.WithLogger(logger),
+//	    redka.WithCacheSize(64<<10),
+//	    redka.WithExpireInterval(10*time.Second),
+//	)
+//
+// The options are applied in order, so the later ones
+// override the earlier ones. Nil options are ignored.
+type Option interface {
+	apply(opts *Options)
+}
+
+// optionFunc is an option that modifies the options.
+type optionFunc func(opts *Options)
+
+func (f optionFunc) apply(opts *Options) {
+	f(opts)
+}
+
+// WithDriver sets the name of the registered SQLite driver
+// (see [Options.DriverName]).
+func WithDriver(name string) Option {
+	return optionFunc(func(opts *Options) {
+		opts.DriverName = name
+	})
+}
+
+// WithLogger sets the logger for the database.
+func WithLogger(logger *slog.Logger) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Logger = logger
+	})
+}
+
+// WithPragma sets an SQLite pragma on each connection,
+// in addition to (or instead of) the default settings.
+// The value is used as is, so quote the strings if necessary.
+// See https://sqlite.org/pragma.html for the available pragmas.
+func WithPragma(name string, value any) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Pragmas = maps.Clone(opts.Pragmas)
+		if opts.Pragmas == nil {
+			opts.Pragmas = map[string]string{}
+		}
+		opts.Pragmas[name] = fmt.Sprint(value)
+	})
+}
+
+// WithJournalMode sets the journal mode (WAL by default).
+func WithJournalMode(mode string) Option {
+	return WithPragma("journal_mode", mode)
+}
+
+// WithCacheSize sets the maximum size of the page cache
+// of each connection in kibibytes.
+func WithCacheSize(kib int) Option {
+	return WithPragma("cache_size", -kib)
+}
+
+// WithMmapSize sets the maximum size of the memory-mapped
+// I/O in bytes (256 MiB by default). Zero disables it.
+func WithMmapSize(size int64) Option {
+	return WithPragma("mmap_size", size)
+}
+
+// WithPoolSize sets the maximum number of open and idle
+// connections (see [Options.MaxOpenConns]).
+func WithPoolSize(maxOpen, maxIdle int) Option {
+	return optionFunc(func(opts *Options) {
+		opts.MaxOpenConns = maxOpen
+		opts.MaxIdleConns = maxIdle
+	})
+}
+
+// WithReadPoolSize sets the size of the read pool
+// (see [Options.ReadPoolSize]).
+func WithReadPoolSize(n int) Option {
+	return optionFunc(func(opts *Options) {
+		opts.ReadPoolSize = n
+	})
+}
+
+// WithBusyTimeout sets how long the connections wait
+// for the database lock (see [Options.BusyTimeout]).
+func WithBusyTimeout(d time.Duration) Option {
+	return optionFunc(func(opts *Options) {
+		opts.BusyTimeout = d
+	})
+}
+
+// WithReadOnly opens the database in the read-only mode
+// (see [Options.ReadOnly]).
+func WithReadOnly() Option {
+	return optionFunc(func(opts *Options) {
+		opts.ReadOnly = true
+	})
+}
+
+// WithClock sets the source of the current time
+// (see [Options.Clock]).
+func WithClock(clock func() time.Time) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Clock = clock
+	})
+}
+
+// WithRand sets the source of random numbers
+// (see [Options.Rand]).
+func WithRand(r *rand.Rand) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Rand = r
+	})
+}
+
+// WithMetrics enables the Prometheus metrics (see [DB.Metrics]).
+func WithMetrics() Option {
+	return optionFunc(func(opts *Options) {
+		opts.Metrics = true
+	})
+}
+
+// WithCodec sets the codec for the values of types without
+// a natural string representation (see [Options.Codec]).
+func WithCodec(codec Codec) Option {
+	return optionFunc(func(opts *Options) {
+		opts.Codec = codec
+	})
+}
+
+// WithLimits sets the maximum key length, value size and number
+// of elements in a collection (see [Options.MaxKeySize],
+// [Options.MaxValueSize] and [Options.MaxElements]).
+// Zero means no limit.
+func WithLimits(keySize, valueSize, elements int) Option {
+	return optionFunc(func(opts *Options) {
+		opts.MaxKeySize = keySize
+		opts.MaxValueSize = valueSize
+		opts.MaxElements = elements
+	})
+}
+
+// WithExpireInterval sets how often the expired keys are deleted
+// (see [Options.ExpireInterval]).
+func WithExpireInterval(d time.Duration) Option {
+	return optionFunc(func(opts *Options) {
+		opts.ExpireInterval = d
+	})
+}
+
+// WithExpireStrategy sets how the expired keys are deleted
+// (see [Options.ExpireStrategy]).
+func WithExpireStrategy(s ExpireStrategy) Option {
+	return optionFunc(func(opts *Options) {
+		opts.ExpireStrategy = s
+	})
+}
+
+// WithTrash enables the soft delete with the given
+// retention (see [Options.TrashRetention]).
+func WithTrash(retention time.Duration) Option {
+	return optionFunc(func(opts *Options) {
+		opts.TrashRetention = retention
+	})
+}
+
+// WithHistory enables recording the versions
+// of the keys (see [Options.History]).
+func WithHistory(opts HistoryOptions) Option {
+	return optionFunc(func(o *Options) {
+		o.History = &opts
+	})
+}
+
+// WithQuota adds a quota (see [Options.Quotas]).
+func WithQuota(q Quota) Option {
+	return optionFunc(func(opts *Options) {
+		// Copy the quotas so that the caller's slice
+		// is not changed.
+		opts.Quotas = append(opts.Quotas[:len(opts.Quotas):len(opts.Quotas)], q)
+	})
+}
+
+// buildOptions applies the options to the default ones.
+func buildOptions(options []Option) *Options {
+	opts := defaultOptions
+	for _, opt := range options {
+		if opt != nil {
+			opt.apply(&opts)
+		}
+	}
+	return &opts
+}
+
+// pragmas returns the pragmas in the "name = value" form,
+// sorted by name.
+func (o *Options) pragmas() []string {
+	pragmas := make([]string, 0, len(o.Pragmas)+1)
+	if _, ok := o.Pragmas["busy_timeout"]; !ok && o.BusyTimeout > 0 {
+		pragmas = append(pragmas, "busy_timeout = "+strconv.FormatInt(o.BusyTimeout.Milliseconds(), 10))
+	}
+	for _, name := range slices.Sorted(maps.Keys(o.Pragmas)) {
+		pragmas = append(pragmas, name+" = "+o.Pragmas[name])
+	}
+	return pragmas
+}
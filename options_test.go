@@ -0,0 +1,155 @@
+package redka_test
+
+import (
+	"context"
+	"math/rand/v2"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestOptions(t *testing.T) {
+	t.Run("combined", func(t *testing.T) {
+		db, err := redka.Open("", &redka.Options{InMemory: true},
+			redka.WithCacheSize(1024), nil)
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		var size int
+		err = db.SQL.QueryRow("pragma cache_size").Scan(&size)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, size, -1024)
+	})
+	t.Run("pragmas", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redka.db")
+		db, err := redka.Open(path,
+			redka.WithJournalMode("truncate"),
+			redka.WithMmapSize(0),
+			redka.WithPragma("cache_size", 100))
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		var mode string
+		var mmap, size int
+		_ = db.SQL.QueryRow("pragma journal_mode").Scan(&mode)
+		_ = db.SQL.QueryRow("pragma mmap_size").Scan(&mmap)
+		_ = db.SQL.QueryRow("pragma cache_size").Scan(&size)
+		testx.AssertEqual(t, mode, "truncate")
+		testx.AssertEqual(t, mmap, 0)
+		testx.AssertEqual(t, size, 100)
+	})
+	t.Run("busy timeout", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redka.db")
+		db, err := redka.Open(path, &redka.Options{BusyTimeout: 2 * time.Second})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		var timeout int
+		_ = db.SQL.QueryRow("pragma busy_timeout").Scan(&timeout)
+		testx.AssertEqual(t, timeout, 2000)
+	})
+	t.Run("pool size", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redka.db")
+		db, err := redka.Open(path, redka.WithPoolSize(4, 2))
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+		testx.AssertEqual(t, db.Stats().SQL.MaxOpenConnections, 4)
+
+		// Each connection gets the settings.
+		ctx := context.Background()
+		var wg sync.WaitGroup
+		for range 4 {
+			conn, err := db.SQL.Conn(ctx)
+			testx.AssertNoErr(t, err)
+			defer conn.Close()
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				var fk bool
+				_ = conn.QueryRowContext(ctx, "pragma foreign_keys").Scan(&fk)
+				testx.AssertEqual(t, fk, true)
+			}()
+		}
+		wg.Wait()
+	})
+	t.Run("read-only", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redka.db")
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		_ = db.Str().Set("name", "alice")
+		_ = db.Close()
+
+		db, err = redka.Open(path, redka.WithReadOnly())
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+		val, err := db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val.String(), "alice")
+		err = db.Str().Set("name", "bob")
+		testx.AssertErr(t, err, redka.ErrReadOnly)
+	})
+	t.Run("clock", func(t *testing.T) {
+		now := time.Now()
+		db, err := redka.Open(":memory:", redka.WithClock(func() time.Time { return now }))
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", time.Minute)
+		exists, _ := db.Key().Exists("name")
+		testx.AssertEqual(t, exists, true)
+
+		now = now.Add(2 * time.Minute)
+		exists, _ = db.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("rand", func(t *testing.T) {
+		pick := func() []string {
+			rnd := rand.New(rand.NewPCG(1, 2))
+			db, err := redka.Open(":memory:", redka.WithRand(rnd))
+			testx.AssertNoErr(t, err)
+			defer db.Close()
+			for _, key := range []string{"a", "b", "c", "d", "e"} {
+				_ = db.Str().Set(key, 1)
+			}
+			var keys []string
+			for range 10 {
+				key, err := db.Key().Random()
+				testx.AssertNoErr(t, err)
+				keys = append(keys, key.Key)
+			}
+			return keys
+		}
+		testx.AssertEqual(t, pick(), pick())
+
+		db, err := redka.Open(":memory:", redka.WithRand(rand.New(rand.NewPCG(1, 2))))
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+		key, err := db.Key().Random()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, key.Exists(), false)
+	})
+	t.Run("expire interval", func(t *testing.T) {
+		db, err := redka.Open(":memory:", redka.WithExpireInterval(10*time.Millisecond))
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+		time.Sleep(50 * time.Millisecond)
+		testx.AssertEqual(t, db.Stats().ExpiredKeys, int64(1))
+	})
+	t.Run("metrics", func(t *testing.T) {
+		db, err := redka.Open(":memory:", redka.WithMetrics())
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+		testx.AssertEqual(t, db.Metrics() != nil, true)
+		testx.AssertEqual(t, getDB(t).Metrics() == nil, true)
+	})
+	t.Run("driver", func(t *testing.T) {
+		_, err := redka.Open(":memory:", redka.WithDriver("unknown"))
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
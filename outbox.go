@@ -0,0 +1,275 @@
+package redka
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/nalgeon/redka/internal/nats"
+)
+
+// Outbox defaults.
+const (
+	defaultOutboxName     = "default"
+	defaultOutboxBatch    = 100
+	defaultOutboxInterval = 100 * time.Millisecond
+	defaultOutboxRetry    = 5 * time.Second
+)
+
+const sqlOutboxSelect = `
+select id, time, data from routbox
+where id > coalesce((select last_id from routbox_cursor where name = ?), 0)
+order by id
+limit ?`
+
+const sqlOutboxCheckpoint = `
+insert into routbox_cursor (name, last_id) values (?, ?)
+on conflict (name) do update set last_id = excluded.last_id`
+
+const sqlOutboxCleanup = `
+delete from routbox
+where id <= (select min(last_id) from routbox_cursor)`
+
+// OutboxMessage is a committed transaction recorded in the outbox.
+//
+// Data is the JSON-encoded list of changes made by the transaction:
+//
+//	{"changes":[{"op":"set","key":"name","type":"string",
+//	  "before":"YWxpY2U=","after":"Ym9i"}]}
+//
+// See [Change] for the meaning of the fields. Values are
+// base64-encoded, since they may contain arbitrary bytes.
+// Missing values are null.
+type OutboxMessage struct {
+	ID   int64     // increasing message ID
+	Time time.Time // commit time
+	Data []byte    // JSON-encoded changes
+}
+
+// Publisher publishes the outbox messages to a message broker.
+// Publish should return only after the broker has accepted
+// the message (or failed to).
+type Publisher interface {
+	Publish(ctx context.Context, msg OutboxMessage) error
+}
+
+// PublisherFunc is an adapter to use a function as a [Publisher].
+// Use it to publish to brokers without a built-in publisher
+// (e.g. Kafka, using a client library of your choice).
+type PublisherFunc func(ctx context.Context, msg OutboxMessage) error
+
+// Publish calls f(ctx, msg).
+func (f PublisherFunc) Publish(ctx context.Context, msg OutboxMessage) error {
+	return f(ctx, msg)
+}
+
+// OutboxOptions configures the outbox publishing.
+type OutboxOptions struct {
+	// Name identifies the publisher's checkpoint. Use different names
+	// for publishers that forward the outbox to different brokers.
+	// If empty, uses "default".
+	Name string
+	// BatchSize is the number of messages read from the outbox at once.
+	// If zero, uses 100.
+	BatchSize int
+	// Interval is the interval between checking the outbox
+	// for new messages. If zero, uses 100ms.
+	Interval time.Duration
+	// RetryDelay is the delay after a failed publish attempt.
+	// If zero, uses 5s.
+	RetryDelay time.Duration
+}
+
+// PublishOutbox forwards the outbox messages to the publisher
+// until ctx is canceled. Requires the Outbox option on Open.
+//
+// The messages are published in order, one at a time. The ID of
+// the last published message is saved as a checkpoint, so that
+// the publishing resumes where it stopped after a restart. Failed
+// messages are retried until published. Since a message may be
+// published again if the process stops between publishing and
+// saving the checkpoint, the delivery is at-least-once. Use the
+// message ID to discard duplicates.
+//
+// The messages published by all known publishers (by name)
+// are removed from the outbox.
+//
+// Canceling ctx is not an error.
+func (db *DB) PublishOutbox(ctx context.Context, pub Publisher, opts *OutboxOptions) error {
+	o := OutboxOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Name == "" {
+		o.Name = defaultOutboxName
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = defaultOutboxBatch
+	}
+	if o.Interval <= 0 {
+		o.Interval = defaultOutboxInterval
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = defaultOutboxRetry
+	}
+
+	for {
+		n, err := db.publishBatch(ctx, pub, o)
+		if ctx.Err() != nil {
+			return nil
+		}
+		delay := o.Interval
+		if err != nil {
+			db.log.Warn("publish outbox", "name", o.Name, "error", err)
+			delay = o.RetryDelay
+		} else if n == o.BatchSize {
+			// There may be more messages.
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// publishBatch publishes the next batch of outbox messages.
+// Returns the number of published messages.
+func (db *DB) publishBatch(ctx context.Context, pub Publisher, opts OutboxOptions) (int, error) {
+	msgs, err := db.readOutbox(opts.Name, opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var lastID int64
+	var pubErr error
+	n := 0
+	for _, msg := range msgs {
+		if pubErr = pub.Publish(ctx, msg); pubErr != nil {
+			break
+		}
+		lastID = msg.ID
+		n++
+	}
+	if n == 0 {
+		return 0, pubErr
+	}
+
+	err = db.Update(func(tx *Tx) error {
+		if _, err := tx.tx.Exec(sqlOutboxCheckpoint, opts.Name, lastID); err != nil {
+			return err
+		}
+		_, err := tx.tx.Exec(sqlOutboxCleanup)
+		return err
+	})
+	return n, errors.Join(pubErr, err)
+}
+
+// readOutbox returns the next outbox messages after the checkpoint.
+func (db *DB) readOutbox(name string, n int) ([]OutboxMessage, error) {
+	rows, err := db.Conn().Query(sqlOutboxSelect, name, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []OutboxMessage
+	for rows.Next() {
+		var msg OutboxMessage
+		var ms int64
+		if err := rows.Scan(&msg.ID, &ms, &msg.Data); err != nil {
+			return nil, err
+		}
+		msg.Time = time.UnixMilli(ms)
+		msgs = append(msgs, msg)
+	}
+	return msgs, rows.Err()
+}
+
+// NATSOptions configures the NATS publisher.
+type NATSOptions struct {
+	// Subject to publish the messages to.
+	// If empty, uses "redka.changes".
+	Subject string
+	// JetStream makes the publisher wait until a stream stores
+	// the message, instead of only the server receiving it.
+	// The subject must be bound to a stream. The message ID
+	// is sent in the Nats-Msg-Id header, so the stream
+	// discards duplicates within its deduplication window.
+	JetStream bool
+	// User and Password or Token are used
+	// to authenticate with the server (if set).
+	User     string
+	Password string
+	Token    string
+	// Timeout limits connecting and publishing a single message.
+	// If zero, uses 5s.
+	Timeout time.Duration
+}
+
+// NATSPublisher publishes the outbox messages to a NATS server.
+// Reconnects automatically after a failure.
+// Not safe for concurrent use.
+type NATSPublisher struct {
+	addr string
+	opts NATSOptions
+	conn *nats.Conn
+}
+
+// NewNATSPublisher creates a publisher to the NATS server at addr (host:port).
+// The opts parameter is optional. If nil, uses default options.
+func NewNATSPublisher(addr string, opts *NATSOptions) *NATSPublisher {
+	p := &NATSPublisher{addr: addr}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.Subject == "" {
+		p.opts.Subject = "redka.changes"
+	}
+	if p.opts.Timeout <= 0 {
+		p.opts.Timeout = 5 * time.Second
+	}
+	return p
+}
+
+// Publish publishes the message and waits for the confirmation.
+func (p *NATSPublisher) Publish(ctx context.Context, msg OutboxMessage) error {
+	if p.conn == nil {
+		ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+		defer cancel()
+		conn, err := nats.Dial(ctx, p.addr, nats.Options{
+			User: p.opts.User, Password: p.opts.Password, Token: p.opts.Token,
+		})
+		if err != nil {
+			return err
+		}
+		p.conn = conn
+	}
+
+	id := strconv.FormatInt(msg.ID, 10)
+	var err error
+	if p.opts.JetStream {
+		err = p.conn.PublishStream(p.opts.Subject, id, msg.Data, p.opts.Timeout)
+	} else {
+		err = p.conn.Publish(p.opts.Subject, id, msg.Data, p.opts.Timeout)
+	}
+	if err != nil && !errors.Is(err, nats.ErrNoResponders) {
+		// The connection state is unknown, so start over.
+		_ = p.Close()
+	}
+	return err
+}
+
+// Close closes the connection to the server.
+func (p *NATSPublisher) Close() error {
+	if p.conn == nil {
+		return nil
+	}
+	err := p.conn.Close()
+	p.conn = nil
+	return err
+}
+
+var _ Publisher = (*NATSPublisher)(nil)
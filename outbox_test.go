@@ -0,0 +1,94 @@
+package redka_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestPublishOutbox(t *testing.T) {
+	db, err := redka.Open("file:/outbox.db?vfs=memdb", &redka.Options{Outbox: true})
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	_ = db.Update(func(tx *redka.Tx) error {
+		_ = tx.Str().Set("name", "bob")
+		_, _ = tx.Hash().Set("person", "age", 25)
+		return nil
+	})
+
+	var mu sync.Mutex
+	var msgs []redka.OutboxMessage
+	fail := true
+	pub := redka.PublisherFunc(func(ctx context.Context, msg redka.OutboxMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		// Fail the second message once.
+		if len(msgs) == 1 && fail {
+			fail = false
+			return errors.New("broker is down")
+		}
+		msgs = append(msgs, msg)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	opts := &redka.OutboxOptions{Interval: 10 * time.Millisecond, RetryDelay: 10 * time.Millisecond}
+	go func() { done <- db.PublishOutbox(ctx, pub, opts) }()
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(msgs)
+	}
+	waitFor(t, func() bool { return count() == 2 })
+
+	// New changes are published too.
+	_, _ = db.Key().Delete("name")
+	waitFor(t, func() bool { return count() == 3 })
+	cancel()
+	testx.AssertNoErr(t, <-done)
+
+	testx.AssertEqual(t, msgs[0].ID < msgs[1].ID && msgs[1].ID < msgs[2].ID, true)
+	var event struct {
+		Changes []struct {
+			Op     string `json:"op"`
+			Key    string `json:"key"`
+			Type   string `json:"type"`
+			Field  string `json:"field"`
+			Before []byte `json:"before"`
+			After  []byte `json:"after"`
+		} `json:"changes"`
+	}
+	err = json.Unmarshal(msgs[1].Data, &event)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(event.Changes), 2)
+	testx.AssertEqual(t, event.Changes[0].Op, "set")
+	testx.AssertEqual(t, event.Changes[0].Key, "name")
+	testx.AssertEqual(t, event.Changes[0].Type, "string")
+	testx.AssertEqual(t, string(event.Changes[0].Before), "alice")
+	testx.AssertEqual(t, string(event.Changes[0].After), "bob")
+	testx.AssertEqual(t, event.Changes[1].Field, "age")
+
+	// The publishing resumes from the checkpoint.
+	_ = db.Str().Set("city", "paris")
+	var resumed []redka.OutboxMessage
+	ctx, cancel = context.WithCancel(context.Background())
+	pub = func(ctx context.Context, msg redka.OutboxMessage) error {
+		resumed = append(resumed, msg)
+		cancel()
+		return nil
+	}
+	err = db.PublishOutbox(ctx, pub, opts)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(resumed), 1)
+	testx.AssertEqual(t, resumed[0].ID > msgs[2].ID, true)
+}
@@ -0,0 +1,176 @@
+package redka
+
+import (
+	"time"
+)
+
+// Pipeline records operations on different data structures
+// and executes them in a single write transaction:
+//
+//	res, err := db.Pipeline().
+//	    Set("name", "alice").
+//	    SortedSetAdd("scores", "alice", 11).
+//	    Delete("session:42").
+//	    Exec()
+//
+// Each operation is applied atomically: if it fails, its changes
+// are rolled back, and the error is reported in its result, but the
+// rest of the operations are committed (like commands in a Redis
+// MULTI/EXEC block). Use [DB.Update] if the operations should
+// succeed or fail together.
+//
+// Loading many keys with a pipeline (or with a single [DB.Update])
+// is much faster than with separate DB-level calls: the transaction
+// commits once, and the repeated writes reuse the prepared statements.
+//
+// Pipeline is not safe for concurrent use.
+type Pipeline struct {
+	db  *DB
+	ops []pipeOp
+}
+
+// pipeOp is a recorded pipeline operation.
+type pipeOp struct {
+	name string
+	key  string
+	run  func(tx *Tx) (any, error)
+}
+
+// PipelineResult is the result of a pipelined operation.
+type PipelineResult struct {
+	// Op is the name of the operation (like "Str.Set").
+	Op string
+	// Key is the key of the operation.
+	// Empty for multi-key operations.
+	Key string
+	// Value is the value returned by the operation:
+	// nil for Set and SetExpires, int for Incr and Delete,
+	// bool for HashSet, SortedSetAdd and Expire,
+	// and the function result for Do.
+	Value any
+	// Err is the error returned by the operation (if any).
+	Err error
+}
+
+// Pipeline returns a new empty pipeline.
+func (db *DB) Pipeline() *Pipeline {
+	return &Pipeline{db: db}
+}
+
+// Len returns the number of recorded operations.
+func (p *Pipeline) Len() int {
+	return len(p.ops)
+}
+
+// Set records setting the string value of the key (see [rstring.Tx.Set]).
+func (p *Pipeline) Set(key string, value any) *Pipeline {
+	return p.add("Str.Set", key, func(tx *Tx) (any, error) {
+		return nil, tx.Str().Set(key, value)
+	})
+}
+
+// SetExpires records setting the string value of the key
+// with a ttl (see [rstring.Tx.SetExpires]).
+func (p *Pipeline) SetExpires(key string, value any, ttl time.Duration) *Pipeline {
+	return p.add("Str.SetExpires", key, func(tx *Tx) (any, error) {
+		return nil, tx.Str().SetExpires(key, value, ttl)
+	})
+}
+
+// Incr records incrementing the integer value of the key
+// (see [rstring.Tx.Incr]).
+func (p *Pipeline) Incr(key string, delta int) *Pipeline {
+	return p.add("Str.Incr", key, func(tx *Tx) (any, error) {
+		return tx.Str().Incr(key, delta)
+	})
+}
+
+// HashSet records setting the value of the hash field
+// (see [rhash.Tx.Set]).
+func (p *Pipeline) HashSet(key, field string, value any) *Pipeline {
+	return p.add("Hash.Set", key, func(tx *Tx) (any, error) {
+		return tx.Hash().Set(key, field, value)
+	})
+}
+
+// SortedSetAdd records adding the element to the sorted set
+// (see [rzset.Tx.Add]).
+func (p *Pipeline) SortedSetAdd(key string, elem any, score float64) *Pipeline {
+	return p.add("SortedSet.Add", key, func(tx *Tx) (any, error) {
+		return tx.SortedSet().Add(key, elem, score)
+	})
+}
+
+// Expire records setting the ttl of the key (see [rkey.Tx.Expire]).
+func (p *Pipeline) Expire(key string, ttl time.Duration) *Pipeline {
+	return p.add("Key.Expire", key, func(tx *Tx) (any, error) {
+		return tx.Key().Expire(key, ttl)
+	})
+}
+
+// Delete records deleting the keys (see [rkey.Tx.Delete]).
+func (p *Pipeline) Delete(keys ...string) *Pipeline {
+	var key string
+	if len(keys) == 1 {
+		key = keys[0]
+	}
+	return p.add("Key.Delete", key, func(tx *Tx) (any, error) {
+		return tx.Key().Delete(keys...)
+	})
+}
+
+// Do records a custom operation with the given name.
+func (p *Pipeline) Do(name string, f func(tx *Tx) (any, error)) *Pipeline {
+	return p.add(name, "", f)
+}
+
+// Exec executes the recorded operations in a single write transaction
+// and returns their results in the order they were recorded.
+// Returns an error only if the transaction itself fails (in which case
+// none of the operations are applied). Clears the pipeline, so it can
+// be reused to record new operations.
+func (p *Pipeline) Exec() ([]PipelineResult, error) {
+	if len(p.ops) == 0 {
+		return nil, nil
+	}
+	ops := p.ops
+	p.ops = nil
+
+	keys := make([]string, 0, len(ops))
+	for _, op := range ops {
+		if op.key != "" {
+			keys = append(keys, op.key)
+		}
+	}
+	obs := p.db.Observe("Pipeline.Exec", keys...)
+
+	var results []PipelineResult
+	err := p.db.In(obs).Update(func(tx *Tx) error {
+		// The transaction may be retried,
+		// so start with the fresh results.
+		results = make([]PipelineResult, len(ops))
+		for i, op := range ops {
+			res := PipelineResult{Op: op.name, Key: op.key}
+			err := tx.Update(func(tx *Tx) error {
+				var err error
+				res.Value, err = op.run(tx)
+				return err
+			})
+			if err != nil {
+				res.Value, res.Err = nil, err
+			}
+			results[i] = res
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, obs.Done(err)
+	}
+	return results, obs.Done(nil)
+}
+
+// add records the operation.
+func (p *Pipeline) add(name, key string, f func(tx *Tx) (any, error)) *Pipeline {
+	p.ops = append(p.ops, pipeOp{name: name, key: key, run: f})
+	return p
+}
@@ -0,0 +1,108 @@
+package redka_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Run("exec", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("session", "42")
+
+		p := db.Pipeline().
+			Set("name", "alice").
+			SetExpires("city", "paris", time.Minute).
+			Incr("age", 25).
+			HashSet("person", "name", "alice").
+			SortedSetAdd("scores", "alice", 11).
+			Expire("name", time.Hour).
+			Delete("session")
+		testx.AssertEqual(t, p.Len(), 7)
+
+		res, err := p.Exec()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, []redka.PipelineResult{
+			{Op: "Str.Set", Key: "name"},
+			{Op: "Str.SetExpires", Key: "city"},
+			{Op: "Str.Incr", Key: "age", Value: 25},
+			{Op: "Hash.Set", Key: "person", Value: true},
+			{Op: "SortedSet.Add", Key: "scores", Value: true},
+			{Op: "Key.Expire", Key: "name", Value: true},
+			{Op: "Key.Delete", Key: "session", Value: 1},
+		})
+		testx.AssertEqual(t, p.Len(), 0)
+
+		count, _ := db.Key().Count("name", "city", "age", "person", "scores", "session")
+		testx.AssertEqual(t, count, 5)
+	})
+	t.Run("operation error", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		errCustom := errors.New("custom")
+		res, err := db.Pipeline().
+			Set("age", 25).
+			HashSet("name", "first", "alice").
+			Do("custom", func(tx *redka.Tx) (any, error) {
+				_ = tx.Str().Set("city", "paris")
+				return nil, errCustom
+			}).
+			Incr("age", 1).
+			Exec()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(res), 4)
+		testx.AssertErr(t, res[1].Err, redka.ErrKeyType)
+		testx.AssertErr(t, res[2].Err, errCustom)
+		testx.AssertEqual(t, res[3].Value, 26)
+
+		// The failed operations are rolled back.
+		exists, _ := db.Key().Exists("city")
+		testx.AssertEqual(t, exists, false)
+		age, _ := db.Str().Get("age")
+		testx.AssertEqual(t, age.MustInt(), 26)
+	})
+	t.Run("many", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		// The repeated writes reuse the prepared statements.
+		p := db.Pipeline()
+		for i := range 100 {
+			key := "key" + strconv.Itoa(i)
+			p.Set(key, i).
+				Incr("count", 1).
+				HashSet("person", key, i).
+				Expire(key, time.Hour)
+		}
+		res, err := p.Exec()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(res), 400)
+		for _, r := range res {
+			testx.AssertNoErr(t, r.Err)
+		}
+
+		count, _ := db.Str().Get("count")
+		testx.AssertEqual(t, count.MustInt(), 100)
+		val, _ := db.Str().Get("key99")
+		testx.AssertEqual(t, val.MustInt(), 99)
+		ttl, _ := db.Key().Get("key99")
+		testx.AssertEqual(t, ttl.ETime != nil, true)
+		hlen, _ := db.Hash().Len("person")
+		testx.AssertEqual(t, hlen, 100)
+	})
+	t.Run("empty", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		res, err := db.Pipeline().Exec()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(res), 0)
+	})
+}
@@ -0,0 +1,103 @@
+package redka
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ErrProcNotFound is returned when calling
+// a procedure that is not registered.
+var ErrProcNotFound = errors.New("procedure not found")
+
+// Proc is a named procedure registered with [DB.Register].
+// Runs atomically within a single transaction, so it can
+// check and change the keys without interference from other
+// writers (e.g. for check-and-set, rate limiting or deduplication).
+// Returning an error rolls back the transaction.
+type Proc func(tx *Tx, args ...string) (any, error)
+
+// procs is a registry of named procedures.
+type procs struct {
+	mu sync.RWMutex
+	m  map[string]Proc
+}
+
+// get returns the procedure with the given name.
+func (p *procs) get(name string) (Proc, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	fn, ok := p.m[name]
+	if !ok {
+		return nil, ErrProcNotFound
+	}
+	return fn, nil
+}
+
+// Register adds a named procedure, replacing the existing one
+// with the same name. A nil fn removes the procedure.
+// The procedures are shared by the logical databases (see [DB.Select]),
+// and the server calls them with the FCALL command:
+//
+//	db.Register("ratelimit", func(tx *redka.Tx, args ...string) (any, error) {
+//	    key := "ratelimit:" + args[0]
+//	    count, err := tx.Str().Incr(key, 1)
+//	    if err != nil || count > 1 {
+//	        return count <= 10, err
+//	    }
+//	    _, err = tx.Key().Expire(key, time.Minute)
+//	    return true, err
+//	})
+//	allowed, err := db.Call("ratelimit", "alice")
+//
+// The results are returned as is by [DB.Call]. The server writes
+// them like redcon.Conn.WriteAny does (strings, numbers, booleans,
+// nil, slices and maps of those).
+func (db *DB) Register(name string, fn Proc) {
+	db.procs.mu.Lock()
+	defer db.procs.mu.Unlock()
+	if fn == nil {
+		delete(db.procs.m, name)
+		return
+	}
+	db.procs.m[name] = fn
+}
+
+// Procs returns the names of the registered procedures, sorted.
+func (db *DB) Procs() []string {
+	db.procs.mu.RLock()
+	defer db.procs.mu.RUnlock()
+	return slices.Sorted(maps.Keys(db.procs.m))
+}
+
+// Call runs the named procedure within a writable transaction
+// and returns its result. Returns ErrProcNotFound if there is
+// no such procedure.
+func (db *DB) Call(name string, args ...string) (any, error) {
+	fn, err := db.procs.get(name)
+	if err != nil {
+		return nil, err
+	}
+	var res any
+	err = db.Update(func(tx *Tx) error {
+		var err error
+		res, err = fn(tx, args...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CallTx runs the named procedure within an existing transaction
+// (e.g. along with other changes in [DB.Update]).
+// Returns ErrProcNotFound if there is no such procedure.
+func (db *DB) CallTx(tx *Tx, name string, args ...string) (any, error) {
+	fn, err := db.procs.get(name)
+	if err != nil {
+		return nil, err
+	}
+	return fn(tx, args...)
+}
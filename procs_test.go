@@ -0,0 +1,84 @@
+package redka_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestProcs(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	// setnx sets the key if it does not exist
+	// and returns the current value.
+	db.Register("setnx", func(tx *redka.Tx, args ...string) (any, error) {
+		if len(args) != 2 {
+			return nil, errors.New("want key and value")
+		}
+		ok, err := tx.Str().SetNotExists(args[0], args[1], 0)
+		if err != nil || ok {
+			return args[1], err
+		}
+		val, err := tx.Str().Get(args[0])
+		return val.String(), err
+	})
+	db.Register("fail", func(tx *redka.Tx, args ...string) (any, error) {
+		_ = tx.Str().Set("name", "fail")
+		return nil, errors.New("boom")
+	})
+
+	t.Run("names", func(t *testing.T) {
+		testx.AssertEqual(t, db.Procs(), []string{"fail", "setnx"})
+	})
+	t.Run("call", func(t *testing.T) {
+		res, err := db.Call("setnx", "name", "alice")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, "alice")
+
+		res, err = db.Call("setnx", "name", "bob")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, "alice")
+
+		_, err = db.Call("setnx", "name")
+		testx.AssertEqual(t, err.Error(), "want key and value")
+	})
+	t.Run("rollback", func(t *testing.T) {
+		_, err := db.Call("fail")
+		testx.AssertEqual(t, err.Error(), "boom")
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("call tx", func(t *testing.T) {
+		var res any
+		err := db.Update(func(tx *redka.Tx) error {
+			if err := tx.Str().Set("city", "paris"); err != nil {
+				return err
+			}
+			var err error
+			res, err = db.CallTx(tx, "setnx", "city", "berlin")
+			return err
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, "paris")
+	})
+	t.Run("logical db", func(t *testing.T) {
+		db1, err := db.Select(1)
+		testx.AssertNoErr(t, err)
+		res, err := db1.Call("setnx", "name", "bob")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, res, "bob")
+	})
+	t.Run("not found", func(t *testing.T) {
+		_, err := db.Call("nope")
+		testx.AssertErr(t, err, redka.ErrProcNotFound)
+	})
+	t.Run("unregister", func(t *testing.T) {
+		db.Register("fail", nil)
+		testx.AssertEqual(t, db.Procs(), []string{"setnx"})
+		_, err := db.Call("fail")
+		testx.AssertErr(t, err, redka.ErrProcNotFound)
+	})
+}
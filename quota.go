@@ -0,0 +1,92 @@
+package redka
+
+import (
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// Quota limits the keys with the prefix (see [Options.Quotas]):
+// the number of keys, their total size in bytes (the keys with
+// their values, hash fields and sorted set elements), and the
+// number of writes per second. Zero fields mean no limit.
+//
+// The writes that exceed a quota fail with a [QuotaError]
+// (matches [ErrQuotaExceeded]). When the limit is reached,
+// the key limit only rejects new keys, and the byte limit rejects
+// all writes to the prefix. The deletes are always allowed. The key count and size
+// are measured in the background periodically, so the writes
+// made in between may exceed the quota slightly
+// (see [Options.QuotaInterval]).
+//
+// A key may match several quotas (like "tenant:" and
+// "tenant:acme:"), and the write must fit all of them.
+type Quota = sqlx.Quota
+
+// QuotaError describes the exceeded quota: the key prefix, the
+// resource (keys, bytes or ops) and the limit. Matches
+// [ErrQuotaExceeded] with errors.Is.
+type QuotaError = core.QuotaError
+
+// QuotaUsage is the usage of a quota as of the last
+// measurement, and the number of writes it has rejected
+// since the database was opened (see [DB.QuotaUsage]).
+type QuotaUsage = sqlx.QuotaUsage
+
+// QuotaUsage returns the usage of the quotas,
+// in the same order as in [Options.Quotas].
+func (db *DB) QuotaUsage() []QuotaUsage {
+	return db.DB.Quotas.Usage()
+}
+
+// newQuotas creates the quotas, or returns nil if there are none.
+func newQuotas(list []Quota) (*sqlx.Quotas, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return sqlx.NewQuotas(list)
+}
+
+// setQuotas enforces the quotas in all repositories.
+func (db *DB) setQuotas(quotas *sqlx.Quotas) {
+	db.DB.Quotas, db.keyDB.Quotas, db.stringDB.Quotas = quotas, quotas, quotas
+	db.hashDB.Quotas, db.zsetDB.Quotas, db.streamDB.Quotas = quotas, quotas, quotas
+	db.hllDB.Quotas = quotas
+}
+
+// startQuotaRefresh starts the goroutine that measures
+// the quota usage in the background. Returns nil
+// if there are no quotas.
+func (db *DB) startQuotaRefresh(interval time.Duration) *time.Ticker {
+	if db.DB.Quotas == nil {
+		return nil
+	}
+	// Measure right away, so that the quotas
+	// apply from the start.
+	db.refreshQuotas()
+
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			for _, db := range db.dbs.all() {
+				db.refreshQuotas()
+			}
+		}
+	}()
+	return ticker
+}
+
+// refreshQuotas measures the quota usage (if there are quotas).
+func (db *DB) refreshQuotas() {
+	quotas := db.DB.Quotas
+	if quotas == nil {
+		return
+	}
+	err := db.DB.ViewSnapshot(func(tx *Tx) error {
+		return quotas.Refresh(tx.tx)
+	})
+	if err != nil {
+		db.log.Error("bg: measure quota usage", "error", err)
+	}
+}
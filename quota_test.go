@@ -0,0 +1,138 @@
+package redka_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestQuota(t *testing.T) {
+	// waitRefresh waits for the quota usage to be measured.
+	waitRefresh := func() { time.Sleep(50 * time.Millisecond) }
+
+	t.Run("keys", func(t *testing.T) {
+		db, err := redka.Open(":memory:", &redka.Options{
+			Quotas:        []redka.Quota{{Prefix: "acme:", MaxKeys: 2}},
+			QuotaInterval: 10 * time.Millisecond,
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().Set("acme:1", "one")
+		_ = db.Str().Set("acme:2", "two")
+		waitRefresh()
+
+		err = db.Str().Set("acme:3", "three")
+		testx.AssertErr(t, err, redka.ErrQuotaExceeded)
+		var qerr *redka.QuotaError
+		testx.AssertEqual(t, errors.As(err, &qerr), true)
+		testx.AssertEqual(t, *qerr, redka.QuotaError{Prefix: "acme:", Resource: "keys", Limit: 2})
+
+		// The existing keys can be changed.
+		err = db.Str().Set("acme:1", "uno")
+		testx.AssertNoErr(t, err)
+		_, err = db.Hash().Set("acme:3", "name", "alice")
+		testx.AssertErr(t, err, redka.ErrQuotaExceeded)
+
+		// Other prefixes are not limited.
+		err = db.Str().Set("other:1", "one")
+		testx.AssertNoErr(t, err)
+
+		// Deletes free up the quota.
+		_, err = db.Key().Delete("acme:2")
+		testx.AssertNoErr(t, err)
+		waitRefresh()
+		err = db.Str().Set("acme:3", "three")
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("bytes", func(t *testing.T) {
+		db, err := redka.Open(":memory:", &redka.Options{
+			Quotas:        []redka.Quota{{Prefix: "acme:", MaxBytes: 20}},
+			QuotaInterval: 10 * time.Millisecond,
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().Set("acme:1", strings.Repeat("x", 10))
+		waitRefresh()
+		usage := db.QuotaUsage()
+		testx.AssertEqual(t, usage[0].Keys, int64(1))
+		testx.AssertEqual(t, usage[0].Bytes, int64(16))
+
+		_, _ = db.Hash().Set("acme:2", "f", "v")
+		waitRefresh()
+		_, err = db.SortedSet().Add("acme:3", "alice", 11)
+		testx.AssertErr(t, err, redka.ErrQuotaExceeded)
+		err = db.Str().Set("acme:1", "x")
+		testx.AssertErr(t, err, redka.ErrQuotaExceeded)
+
+		usage = db.QuotaUsage()
+		testx.AssertEqual(t, usage[0].Rejected["bytes"], int64(2))
+	})
+	t.Run("ops", func(t *testing.T) {
+		db, err := redka.Open(":memory:", &redka.Options{
+			Quotas: []redka.Quota{{Prefix: "acme:", MaxOpsPerSec: 3}},
+			Clock:  func() time.Time { return time.Unix(1700000000, 0) },
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		for range 3 {
+			err = db.Str().Set("acme:1", "one")
+			testx.AssertNoErr(t, err)
+		}
+		err = db.Str().Set("acme:1", "one")
+		testx.AssertErr(t, err, redka.ErrQuotaExceeded)
+
+		// The writes to the same key within a transaction
+		// count as a single operation.
+		err = db.Update(func(tx *redka.Tx) error {
+			return tx.Str().Set("acme:1", "one")
+		})
+		testx.AssertErr(t, err, redka.ErrQuotaExceeded)
+	})
+	t.Run("nested", func(t *testing.T) {
+		db, err := redka.Open(":memory:", &redka.Options{
+			Quotas: []redka.Quota{
+				{Prefix: "", MaxOpsPerSec: 100},
+				{Prefix: "acme:", MaxOpsPerSec: 1},
+			},
+			Clock: func() time.Time { return time.Unix(1700000000, 0) },
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		err = db.Str().Set("acme:1", "one")
+		testx.AssertNoErr(t, err)
+		err = db.Str().Set("acme:2", "two")
+		testx.AssertErr(t, err, redka.ErrQuotaExceeded)
+		err = db.Str().Set("other:1", "one")
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("invalid prefix", func(t *testing.T) {
+		_, err := redka.Open(":memory:", redka.WithQuota(redka.Quota{Prefix: "a\x00"}))
+		testx.AssertEqual(t, err != nil, true)
+	})
+	t.Run("metrics", func(t *testing.T) {
+		db, err := redka.Open(":memory:", &redka.Options{
+			Quotas: []redka.Quota{{Prefix: "acme:", MaxKeys: 10}},
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		var b strings.Builder
+		_, err = redka.NewMetrics(db).WriteTo(&b)
+		testx.AssertNoErr(t, err)
+		for _, line := range []string{
+			`redka_quota_keys{prefix="acme:"} 0`,
+			`redka_quota_limit{prefix="acme:",resource="keys"} 10`,
+			`redka_quota_rejected_total{prefix="acme:",resource="ops"} 0`,
+		} {
+			testx.AssertEqual(t, strings.Contains(b.String(), line+"\n"), true)
+		}
+	})
+}
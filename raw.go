@@ -0,0 +1,61 @@
+package redka
+
+import (
+	"database/sql"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// RawTx executes custom SQL queries within a Redka transaction.
+// See [Tx.Raw] for details.
+type RawTx struct {
+	tx sqlx.Tx
+}
+
+// Raw returns the SQL transaction underlying the Redka transaction.
+// Use it to run queries that the repositories can't express (like
+// analytics or joins with the application tables), atomically with
+// the repository calls in the same transaction:
+//
+//	err := db.View(func(tx *redka.Tx) error {
+//	    var count int
+//	    row := tx.Raw().QueryRow(
+//	        `select count(*) from vhash where key = ? and value > ?`,
+//	        "scores", 100,
+//	    )
+//	    return row.Scan(&count)
+//	})
+//
+// Query the views instead of the tables, because the views are
+// a stable interface, while the tables may change between versions:
+//   - vstring (key_id, key, value, etime, mtime);
+//   - vhash (key_id, key, field, value, etime, mtime);
+//   - vzset (key_id, key, score, elem, etime, mtime).
+//
+// The views only show the keys that have not expired, and have
+// etime and mtime as UTC datetime strings. With Options.TablePrefix,
+// the Redka table and view names in the queries are prefixed
+// automatically.
+//
+// Writes made with Raw bypass the key versions, modification
+// times and size limits (see Options.MaxValueSize), so prefer
+// the repositories for writes.
+func (tx *Tx) Raw() *RawTx {
+	return &RawTx{tx: tx.tx}
+}
+
+// Query executes a query that returns rows, typically a select.
+func (r *RawTx) Query(query string, args ...any) (*sql.Rows, error) {
+	return r.tx.Query(query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row.
+func (r *RawTx) QueryRow(query string, args ...any) *sql.Row {
+	return r.tx.QueryRow(query, args...)
+}
+
+// Exec executes a query that doesn't return rows,
+// typically an insert, update or delete.
+func (r *RawTx) Exec(query string, args ...any) (sql.Result, error) {
+	return r.tx.Exec(query, args...)
+}
@@ -0,0 +1,101 @@
+package redka_test
+
+import (
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestTxRaw(t *testing.T) {
+	t.Run("query", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		err := db.Update(func(tx *redka.Tx) error {
+			_, _ = tx.Hash().Set("scores", "alice", 100)
+			_, _ = tx.Hash().Set("scores", "bob", 200)
+			_, _ = tx.Hash().Set("scores", "cindy", 300)
+
+			var count int
+			row := tx.Raw().QueryRow(
+				`select count(*) from vhash where key = ? and cast(value as integer) > ?`,
+				"scores", 150,
+			)
+			err := row.Scan(&count)
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, count, 2)
+
+			rows, err := tx.Raw().Query(`select field from vhash where key = ? order by field`, "scores")
+			testx.AssertNoErr(t, err)
+			defer rows.Close()
+			var fields []string
+			for rows.Next() {
+				var field string
+				_ = rows.Scan(&field)
+				fields = append(fields, field)
+			}
+			testx.AssertEqual(t, fields, []string{"alice", "bob", "cindy"})
+			return rows.Err()
+		})
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("exec", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		err := db.Update(func(tx *redka.Tx) error {
+			if _, err := tx.Raw().Exec(`create table app_user (name text)`); err != nil {
+				return err
+			}
+			if _, err := tx.Raw().Exec(`insert into app_user values (?)`, "alice"); err != nil {
+				return err
+			}
+			return tx.Str().Set("user:count", 1)
+		})
+		testx.AssertNoErr(t, err)
+
+		var name string
+		err = db.View(func(tx *redka.Tx) error {
+			return tx.Raw().QueryRow(`select name from app_user`).Scan(&name)
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name, "alice")
+	})
+	t.Run("rollback", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Update(func(tx *redka.Tx) error {
+			_, err := tx.Raw().Exec(`create table app_user (name text)`)
+			return err
+		})
+
+		err := db.Update(func(tx *redka.Tx) error {
+			_, _ = tx.Raw().Exec(`insert into app_user values (?)`, "alice")
+			_ = tx.Str().Set("name", "alice")
+			return redka.ErrNotFound
+		})
+		testx.AssertErr(t, err, redka.ErrNotFound)
+
+		var count int
+		_ = db.View(func(tx *redka.Tx) error {
+			return tx.Raw().QueryRow(`select count(*) from app_user`).Scan(&count)
+		})
+		testx.AssertEqual(t, count, 0)
+		n, _ := db.Key().Count("name")
+		testx.AssertEqual(t, n, 0)
+	})
+	t.Run("table prefix", func(t *testing.T) {
+		db, err := redka.Open(":memory:", &redka.Options{TablePrefix: "redka_"})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		var value string
+		err = db.View(func(tx *redka.Tx) error {
+			return tx.Raw().QueryRow(`select value from vstring where key = ?`, "name").Scan(&value)
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, value, "alice")
+	})
+}
@@ -0,0 +1,382 @@
+package redka
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rdb"
+	"github.com/nalgeon/redka/internal/rhll"
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// defaultImportBatch is the default number of keys
+// imported in a single transaction.
+const defaultImportBatch = 1000
+
+// exportPageSize is the number of keys fetched
+// at once when exporting the database.
+const exportPageSize = 1000
+
+// ImportOptions configures the data import.
+type ImportOptions struct {
+	// BatchSize is the number of keys imported in a single transaction.
+	// If zero, imports 1000 keys per transaction.
+	BatchSize int
+	// DB is the number of the source database to import.
+	// Keys from other databases are skipped.
+	// Set to -1 to import keys from all databases.
+	DB int
+	// Progress is called after each batch is committed.
+	// If nil, it is ignored.
+	Progress func(ImportStats)
+}
+
+// ImportStats describes the state of the import.
+type ImportStats struct {
+	Keys    int   // number of imported keys
+	Skipped int   // keys skipped due to their type, database or a conflict
+	Expired int   // keys that are already expired
+	Bytes   int64 // bytes read from the source
+}
+
+// ImportRDB loads keys from a Redis RDB file (dump.rdb) into the database.
+// Supports strings, hashes, sorted sets and streams (along with their TTLs,
+// but without the stream consumer groups) in all encodings used by Redis
+// up to version 7.x, and the hashes
+// with field TTLs in the plain encoding of Redis 7.4 (but not
+// in the compact one used for small hashes). Redis stores the
+// HyperLogLogs as strings, so the strings holding a valid
+// HyperLogLog are imported as HyperLogLogs. Keys of other
+// types are skipped. Existing keys with the same names are replaced.
+//
+// Keys are imported in batched transactions (see [ImportOptions]),
+// so if the import fails midway, the already committed
+// batches remain in the database.
+//
+// The opts parameter is optional. If nil, uses default options.
+func (db *DB) ImportRDB(r io.Reader, opts *ImportOptions) (ImportStats, error) {
+	if opts == nil {
+		opts = &ImportOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatch
+	}
+
+	var stats ImportStats
+	rd := rdb.NewReader(r)
+	batch := make([]rdb.Entry, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := db.Update(func(tx *Tx) error {
+			for _, e := range batch {
+				if err := importEntry(tx, e); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		stats.Keys += len(batch)
+		stats.Bytes = rd.BytesRead()
+		batch = batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(stats)
+		}
+		return nil
+	}
+
+	now := time.Now().UnixMilli()
+	for {
+		e, err := rd.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return stats, err
+		}
+		if !isImportType(e.Type) || (opts.DB >= 0 && e.DB != opts.DB) {
+			stats.Skipped++
+			continue
+		}
+		if e.ETime != nil && *e.ETime <= now {
+			stats.Expired++
+			continue
+		}
+		batch = append(batch, e)
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+
+	err := flush()
+	stats.Bytes = rd.BytesRead()
+	db.log.Info("import rdb", "keys", stats.Keys, "skipped", stats.Skipped,
+		"expired", stats.Expired, "error", err)
+	return stats, err
+}
+
+// isImportType reports whether keys of the type can be imported.
+func isImportType(typ core.TypeID) bool {
+	switch typ {
+	case core.TypeString, core.TypeHash, core.TypeSortedSet, core.TypeStream:
+		return true
+	}
+	return false
+}
+
+// isExportType reports whether keys of the type can be exported
+// (see DB.ExportRDB and DB.Export).
+func isExportType(typ core.TypeID) bool {
+	return isImportType(typ) || typ == core.TypeHLL
+}
+
+// exportType returns the type of the exported key. Same as Redis,
+// the HyperLogLogs are exported as strings (see exportEntry).
+func exportType(typ core.TypeID) core.TypeID {
+	if typ == core.TypeHLL {
+		return core.TypeString
+	}
+	return typ
+}
+
+// importEntry creates a key from the RDB entry,
+// replacing the existing key if necessary.
+func importEntry(tx *Tx, e rdb.Entry) error {
+	_, err := tx.Key().Delete(e.Key)
+	if err != nil {
+		return err
+	}
+
+	switch e.Type {
+	case core.TypeString:
+		if rhll.Valid(e.Str) {
+			err = tx.HLL().Set(e.Key, e.Str)
+		} else {
+			err = tx.Str().Set(e.Key, e.Str)
+		}
+	case core.TypeHash:
+		items := make(map[string]any, len(e.Hash))
+		for field, val := range e.Hash {
+			items[field] = val
+		}
+		_, err = tx.Hash().SetMany(e.Key, items)
+	case core.TypeSortedSet:
+		items := make(map[any]float64, len(e.ZSet))
+		for elem, score := range e.ZSet {
+			items[elem] = score
+		}
+		_, err = tx.SortedSet().AddMany(e.Key, items)
+	case core.TypeStream:
+		err = importStream(tx, e)
+	}
+	if err != nil {
+		return err
+	}
+	if err := importFieldETimes(tx, e); err != nil {
+		return err
+	}
+
+	if e.ETime != nil {
+		_, err = tx.Key().ExpireAt(e.Key, time.UnixMilli(*e.ETime))
+	}
+	return err
+}
+
+// importStream adds the stream entries from the RDB entry.
+// An empty stream is not created, since Redka
+// does not support streams without entries.
+func importStream(tx *Tx, e rdb.Entry) error {
+	for _, se := range e.Stream {
+		fields := make([]any, len(se.Fields))
+		for i, f := range se.Fields {
+			if i%2 == 0 {
+				fields[i] = string(f)
+			} else {
+				fields[i] = f
+			}
+		}
+		id := rstream.ID{Time: se.Time, Seq: se.Seq}
+		if err := tx.Stream().AddID(e.Key, id, fields...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importFieldETimes sets the expiration times
+// of the hash fields from the entry.
+func importFieldETimes(tx *Tx, e rdb.Entry) error {
+	for field, etime := range e.HashETimes {
+		_, err := tx.Hash().FieldExpireAt(e.Key, time.UnixMilli(etime), field)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportRDB writes all keys in the database to w using the Redis RDB
+// format, so the data can be loaded into Redis or inspected with
+// existing RDB tools. Preserves key types, values and TTLs, including
+// the hash field TTLs (which need Redis 7.4 or later to load).
+// Same as Redis, the HyperLogLogs are exported as strings in the
+// dense encoding.
+//
+// Reads the data in a single read-only transaction, so the exported
+// file is a consistent snapshot of the database.
+func (db *DB) ExportRDB(w io.Writer) error {
+	return db.View(func(tx *Tx) error {
+		rw := rdb.NewWriter(w)
+		sc := tx.Key().Scanner("*", exportPageSize)
+		for sc.Scan() {
+			if !isExportType(sc.Key().Type) {
+				continue
+			}
+			e, err := exportEntry(tx, sc.Key())
+			if err != nil {
+				return err
+			}
+			if err := rw.Write(e); err != nil {
+				return err
+			}
+		}
+		if err := sc.Err(); err != nil {
+			return err
+		}
+		return rw.Close()
+	})
+}
+
+// exportEntry creates an RDB entry from the key.
+func exportEntry(tx *Tx, key core.Key) (rdb.Entry, error) {
+	e := rdb.Entry{Key: key.Key, Type: exportType(key.Type), ETime: key.ETime}
+	switch key.Type {
+	case core.TypeString:
+		val, err := tx.Str().Get(key.Key)
+		if err != nil {
+			return e, err
+		}
+		e.Str = val
+	case core.TypeHLL:
+		val, err := tx.HLL().Get(key.Key)
+		if err != nil {
+			return e, err
+		}
+		e.Str = val
+	case core.TypeHash:
+		items, err := tx.Hash().Items(key.Key)
+		if err != nil {
+			return e, err
+		}
+		e.Hash = make(map[string][]byte, len(items))
+		for field, val := range items {
+			e.Hash[field] = val
+		}
+		etimes, err := tx.Hash().FieldExpireTimes(key.Key)
+		if err != nil {
+			return e, err
+		}
+		if len(etimes) > 0 {
+			e.HashETimes = make(map[string]int64, len(etimes))
+			for field, at := range etimes {
+				e.HashETimes[field] = at.UnixMilli()
+			}
+		}
+	case core.TypeSortedSet:
+		items, err := tx.SortedSet().RangeWith(key.Key).
+			ByScore(math.Inf(-1), math.Inf(1)).Run()
+		if err != nil {
+			return e, err
+		}
+		e.ZSet = make(map[string]float64, len(items))
+		for _, it := range items {
+			e.ZSet[it.Elem.String()] = it.Score
+		}
+	case core.TypeStream:
+		entries, err := tx.Stream().Range(key.Key, rstream.MinID, rstream.MaxID, 0)
+		if err != nil {
+			return e, err
+		}
+		e.Stream = make([]rdb.StreamEntry, len(entries))
+		for i, se := range entries {
+			fields := make([][]byte, 0, len(se.Fields)*2)
+			for _, f := range se.Fields {
+				fields = append(fields, []byte(f.Name), f.Value)
+			}
+			e.Stream[i] = rdb.StreamEntry{Time: se.ID.Time, Seq: se.ID.Seq, Fields: fields}
+		}
+	}
+	return e, nil
+}
+
+// Dump serializes the value of the key in the Redis DUMP format
+// (the RDB-encoded value followed by the RDB version and the checksum).
+// Use [DB.Restore] to recreate the key from the payload, either in
+// this database or another one. The payload is also compatible with
+// the Redis RESTORE command. Does not include the key name or TTL,
+// but includes the hash field TTLs (which need Redis 7.4 or later).
+// Same as Redis, a HyperLogLog is serialized as a string.
+// Streams are serialized without consumer groups, which
+// Redka does not support.
+//
+// If the key does not exist, returns ErrNotFound.
+func (db *DB) Dump(key string) ([]byte, error) {
+	var payload []byte
+	err := db.View(func(tx *Tx) error {
+		k, err := tx.Key().Get(key)
+		if err != nil {
+			return err
+		}
+		if !k.Exists() {
+			return ErrNotFound
+		}
+		if !isExportType(k.Type) {
+			return ErrKeyType
+		}
+		e, err := exportEntry(tx, k)
+		if err != nil {
+			return err
+		}
+		payload, err = rdb.AppendDump(nil, e)
+		return err
+	})
+	return payload, err
+}
+
+// Restore creates the key from the payload serialized with [DB.Dump]
+// or the Redis DUMP command. Replaces the existing key with the same name.
+// If ttl is positive, sets the expiration time of the key, otherwise
+// the key does not expire.
+//
+// If the payload is corrupted or has an unsupported RDB version,
+// returns ErrValueType. If the payload holds a value of a type
+// Redka does not support (like a list or a set), returns ErrKeyType.
+func (db *DB) Restore(key string, ttl time.Duration, payload []byte) error {
+	e, err := rdb.ParseDump(payload)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValueType, err)
+	}
+	if !isImportType(e.Type) {
+		return ErrKeyType
+	}
+	return db.Update(func(tx *Tx) error {
+		e.Key = key
+		if ttl > 0 {
+			etime := sqlx.Now(tx.tx).Add(ttl).UnixMilli()
+			e.ETime = &etime
+		}
+		return importEntry(tx, e)
+	})
+}
@@ -0,0 +1,275 @@
+package redka_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rdb"
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestImportRDB(t *testing.T) {
+	future := uint64(time.Now().Add(time.Hour).UnixMilli())
+	past := uint64(time.Now().Add(-time.Hour).UnixMilli())
+
+	var b bytes.Buffer
+	b.WriteString("REDIS0011")
+	b.Write([]byte{0xFE, 0})
+	// string
+	b.Write([]byte{0x00, 4, 'n', 'a', 'm', 'e', 5, 'a', 'l', 'i', 'c', 'e'})
+	// string with ttl
+	b.WriteByte(0xFC)
+	b.Write(binary.LittleEndian.AppendUint64(nil, future))
+	b.Write([]byte{0x00, 3, 't', 'm', 'p', 1, 'x'})
+	// expired string
+	b.WriteByte(0xFC)
+	b.Write(binary.LittleEndian.AppendUint64(nil, past))
+	b.Write([]byte{0x00, 3, 'o', 'l', 'd', 1, 'x'})
+	// hash
+	b.Write([]byte{0x04, 6, 'p', 'e', 'r', 's', 'o', 'n', 1, 3, 'a', 'g', 'e', 2, '2', '5'})
+	// sorted set
+	b.Write([]byte{0x05, 6, 's', 'c', 'o', 'r', 'e', 's', 1, 3, 'o', 'n', 'e'})
+	b.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(1.5)))
+	// set (unsupported)
+	b.Write([]byte{0x02, 4, 't', 'a', 'g', 's', 1, 1, 'a'})
+	// string in another database
+	b.Write([]byte{0xFE, 1})
+	b.Write([]byte{0x00, 5, 'o', 't', 'h', 'e', 'r', 1, 'x'})
+	// eof with disabled checksum
+	b.WriteByte(0xFF)
+	b.Write(make([]byte, 8))
+
+	db := getDB(t)
+	defer db.Close()
+	_ = db.Str().Set("person", "replaced")
+
+	var batches int
+	opts := &redka.ImportOptions{
+		BatchSize: 2,
+		Progress:  func(redka.ImportStats) { batches++ },
+	}
+	stats, err := db.ImportRDB(bytes.NewReader(b.Bytes()), opts)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, stats.Keys, 4)
+	testx.AssertEqual(t, stats.Skipped, 2)
+	testx.AssertEqual(t, stats.Expired, 1)
+	testx.AssertEqual(t, stats.Bytes, int64(b.Len()))
+	testx.AssertEqual(t, batches, 2)
+
+	name, _ := db.Str().Get("name")
+	testx.AssertEqual(t, name.String(), "alice")
+
+	tmp, _ := db.Key().Get("tmp")
+	testx.AssertEqual(t, *tmp.ETime, int64(future))
+
+	age, _ := db.Hash().Get("person", "age")
+	testx.AssertEqual(t, age.MustInt(), 25)
+
+	score, _ := db.SortedSet().GetScore("scores", "one")
+	testx.AssertEqual(t, score, 1.5)
+
+	count, _ := db.Key().Count("old", "tags", "other")
+	testx.AssertEqual(t, count, 0)
+}
+
+func TestExportRDB(t *testing.T) {
+	src := getDB(t)
+	defer src.Close()
+
+	_ = src.Str().Set("name", "alice")
+	_ = src.Str().SetExpires("tmp", 42, time.Hour)
+	_, _ = src.Hash().SetMany("person", map[string]any{"name": "bob", "age": 25})
+	_, _ = src.Hash().FieldExpire("person", time.Hour, "age")
+	_, _ = src.SortedSet().AddMany("scores", map[any]float64{"one": 1, "two": 2.5})
+	_, _ = src.HLL().Add("visitors", "alice", "bob")
+	_ = src.Stream().AddID("events", rstream.ID{Time: 1, Seq: 1}, "user", "alice")
+	_ = src.Stream().AddID("events", rstream.ID{Time: 2, Seq: 0}, "user", "bob", "action", "login")
+
+	var buf bytes.Buffer
+	err := src.ExportRDB(&buf)
+	testx.AssertNoErr(t, err)
+
+	dst := getDB(t)
+	defer dst.Close()
+	stats, err := dst.ImportRDB(&buf, nil)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, stats.Keys, 6)
+
+	name, _ := dst.Str().Get("name")
+	testx.AssertEqual(t, name.String(), "alice")
+
+	srcTmp, _ := src.Key().Get("tmp")
+	dstTmp, _ := dst.Key().Get("tmp")
+	testx.AssertEqual(t, *dstTmp.ETime, *srcTmp.ETime)
+
+	person, _ := dst.Hash().Items("person")
+	testx.AssertEqual(t, person["name"].String(), "bob")
+	testx.AssertEqual(t, person["age"].MustInt(), 25)
+	srcETimes, _ := src.Hash().FieldExpireTimes("person")
+	dstETimes, _ := dst.Hash().FieldExpireTimes("person")
+	testx.AssertEqual(t, len(dstETimes), 1)
+	testx.AssertEqual(t, dstETimes["age"].UnixMilli(), srcETimes["age"].UnixMilli())
+
+	scores, _ := dst.SortedSet().Range("scores", 0, 1)
+	testx.AssertEqual(t, len(scores), 2)
+	testx.AssertEqual(t, scores[0].Elem.String(), "one")
+	testx.AssertEqual(t, scores[1].Score, 2.5)
+
+	key, _ := dst.Key().Get("visitors")
+	testx.AssertEqual(t, key.Type, core.TypeHLL)
+	count, _ := dst.HLL().Count("visitors")
+	testx.AssertEqual(t, count, 2)
+
+	events, _ := dst.Stream().Range("events", rstream.MinID, rstream.MaxID, 0)
+	testx.AssertEqual(t, len(events), 2)
+	testx.AssertEqual(t, events[1].ID, rstream.ID{Time: 2, Seq: 0})
+	testx.AssertEqual(t, len(events[1].Fields), 2)
+	testx.AssertEqual(t, events[1].Fields[1].Value.String(), "login")
+}
+
+func TestDumpRestore(t *testing.T) {
+	t.Run("roundtrip", func(t *testing.T) {
+		src := getDB(t)
+		defer src.Close()
+		_ = src.Str().Set("name", "alice")
+		_, _ = src.Hash().SetMany("person", map[string]any{"name": "bob", "age": 25})
+		_, _ = src.SortedSet().AddMany("scores", map[any]float64{"one": 1, "two": 2.5})
+
+		dst := getDB(t)
+		defer dst.Close()
+		for _, key := range []string{"name", "person", "scores"} {
+			payload, err := src.Dump(key)
+			testx.AssertNoErr(t, err)
+			err = dst.Restore(key, 0, payload)
+			testx.AssertNoErr(t, err)
+		}
+
+		name, _ := dst.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+		person, _ := dst.Hash().Items("person")
+		testx.AssertEqual(t, person["name"].String(), "bob")
+		testx.AssertEqual(t, person["age"].MustInt(), 25)
+		score, _ := dst.SortedSet().GetScore("scores", "two")
+		testx.AssertEqual(t, score, 2.5)
+		key, _ := dst.Key().Get("name")
+		testx.AssertEqual(t, key.ETime, (*int64)(nil))
+	})
+	t.Run("field ttl", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().SetMany("person", map[string]any{"name": "bob", "age": 25})
+		_, _ = db.Hash().FieldExpire("person", time.Hour, "age")
+		payload, err := db.Dump("person")
+		testx.AssertNoErr(t, err)
+
+		err = db.Restore("copy", 0, payload)
+		testx.AssertNoErr(t, err)
+		src, _ := db.Hash().FieldExpireTimes("person")
+		dst, _ := db.Hash().FieldExpireTimes("copy")
+		testx.AssertEqual(t, len(dst), 1)
+		testx.AssertEqual(t, dst["age"].UnixMilli(), src["age"].UnixMilli())
+	})
+	t.Run("hll", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_, _ = db.HLL().Add("visitors", "alice", "bob")
+		payload, err := db.Dump("visitors")
+		testx.AssertNoErr(t, err)
+
+		// Same as Redis, the HyperLogLog is dumped as a string.
+		e, err := rdb.ParseDump(payload)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, e.Type, core.TypeString)
+
+		err = db.Restore("copy", 0, payload)
+		testx.AssertNoErr(t, err)
+		key, _ := db.Key().Get("copy")
+		testx.AssertEqual(t, key.Type, core.TypeHLL)
+		count, _ := db.HLL().Count("copy")
+		testx.AssertEqual(t, count, 2)
+	})
+	t.Run("ttl", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+		payload, _ := db.Dump("name")
+
+		now := time.Now()
+		err := db.Restore("copy", time.Minute, payload)
+		testx.AssertNoErr(t, err)
+		key, _ := db.Key().Get("copy")
+		testx.AssertEqual(t, *key.ETime >= now.Add(time.Minute).UnixMilli(), true)
+	})
+	t.Run("replace", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+		_, _ = db.Hash().Set("person", "name", "bob")
+		payload, _ := db.Dump("name")
+
+		err := db.Restore("person", 0, payload)
+		testx.AssertNoErr(t, err)
+		val, _ := db.Str().Get("person")
+		testx.AssertEqual(t, val.String(), "alice")
+	})
+	t.Run("redis payload", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		// DUMP payload with RDB version 9 and no checksum.
+		payload := []byte{0x00, 5, 'a', 'l', 'i', 'c', 'e', 9, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+		err := db.Restore("name", 0, payload)
+		testx.AssertNoErr(t, err)
+		val, _ := db.Str().Get("name")
+		testx.AssertEqual(t, val.String(), "alice")
+	})
+	t.Run("dump not found", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_, err := db.Dump("name")
+		testx.AssertErr(t, err, redka.ErrNotFound)
+	})
+	t.Run("stream", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		id1, _ := db.Stream().Add("events", "user", "alice")
+		id2, _ := db.Stream().Add("events", "user", "bob")
+		payload, err := db.Dump("events")
+		testx.AssertNoErr(t, err)
+
+		err = db.Restore("copy", 0, payload)
+		testx.AssertNoErr(t, err)
+		events, _ := db.Stream().Range("copy", rstream.MinID, rstream.MaxID, 0)
+		testx.AssertEqual(t, len(events), 2)
+		testx.AssertEqual(t, events[0].ID, id1)
+		testx.AssertEqual(t, events[1].ID, id2)
+		testx.AssertEqual(t, events[1].Fields[0].Value.String(), "bob")
+	})
+	t.Run("restore invalid", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+		payload, _ := db.Dump("name")
+		payload[2] = 'x'
+
+		err := db.Restore("name", 0, payload)
+		testx.AssertErr(t, err, redka.ErrValueType)
+		val, _ := db.Str().Get("name")
+		testx.AssertEqual(t, val.String(), "alice")
+	})
+	t.Run("restore unsupported", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		// A set with a single element.
+		payload := []byte{0x02, 1, 1, 'a', 9, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+		err := db.Restore("tags", 0, payload)
+		testx.AssertErr(t, err, redka.ErrKeyType)
+		key, _ := db.Key().Get("tags")
+		testx.AssertEqual(t, key.Exists(), false)
+	})
+}
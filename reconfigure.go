@@ -0,0 +1,44 @@
+package redka
+
+import (
+	"time"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// Settings are the database options that can be changed
+// while the database is open (see [DB.Reconfigure]).
+// The fields have the same meaning as in [Options].
+type Settings struct {
+	SlowThreshold  time.Duration
+	MaxKeySize     int
+	MaxValueSize   int
+	MaxElements    int
+	ExpireInterval time.Duration // if zero, uses 60 seconds
+}
+
+// Reconfigure applies the settings to the open database
+// without interrupting the operations in progress. Replaces
+// all the settings, so the zero fields disable the corresponding
+// features (except for ExpireInterval, which falls back to the
+// default). The transactions that have already started keep
+// the old limits.
+//
+// Useful for reloading the configuration without a restart
+// (like on SIGHUP).
+func (db *DB) Reconfigure(s Settings) {
+	db.slowLog.setThreshold(db, s.SlowThreshold)
+	db.DB.Limits.Store(sqlx.Limits{
+		MaxKeySize:   s.MaxKeySize,
+		MaxValueSize: s.MaxValueSize,
+		MaxElements:  s.MaxElements,
+	})
+	if db.bg != nil {
+		// Read-only databases do not delete the expired keys.
+		interval := s.ExpireInterval
+		if interval == 0 {
+			interval = defaultOptions.ExpireInterval
+		}
+		db.bg.Reset(interval)
+	}
+}
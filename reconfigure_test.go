@@ -0,0 +1,62 @@
+package redka_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestReconfigure(t *testing.T) {
+	t.Run("limits", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		db.Reconfigure(redka.Settings{MaxKeySize: 5, MaxValueSize: 5})
+		err := db.Str().Set("name", "alice")
+		testx.AssertNoErr(t, err)
+		err = db.Str().Set("username", "alice")
+		testx.AssertErr(t, err, redka.ErrKeyTooLarge)
+		err = db.Str().Set("name", "alice and bob")
+		testx.AssertErr(t, err, redka.ErrValueTooLarge)
+
+		db.Reconfigure(redka.Settings{})
+		err = db.Str().Set("username", "alice and bob")
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("slow threshold", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+		db, err := redka.Open(":memory:", &redka.Options{Logger: logger})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().Set("name", "alice")
+		testx.AssertEqual(t, strings.Contains(buf.String(), "slow operation"), false)
+
+		db.Reconfigure(redka.Settings{SlowThreshold: time.Nanosecond})
+		_ = db.Str().Set("name", "alice")
+		testx.AssertEqual(t, strings.Contains(buf.String(), `msg="slow operation" op=Str.Set`), true)
+
+		buf.Reset()
+		db.Reconfigure(redka.Settings{})
+		_ = db.Str().Set("name", "alice")
+		testx.AssertEqual(t, buf.String(), "")
+	})
+	t.Run("expire interval", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().SetExpires("name", "alice", time.Millisecond)
+		db.Reconfigure(redka.Settings{ExpireInterval: 10 * time.Millisecond})
+		time.Sleep(50 * time.Millisecond)
+
+		info, err := db.Key().Expiry()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, info.Expired, 0)
+	})
+}
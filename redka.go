@@ -11,25 +11,50 @@ package redka
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"math/rand/v2"
 	"time"
 
 	"github.com/nalgeon/redka/internal/core"
 	"github.com/nalgeon/redka/internal/rhash"
+	"github.com/nalgeon/redka/internal/rhll"
 	"github.com/nalgeon/redka/internal/rkey"
+	"github.com/nalgeon/redka/internal/rstream"
 	"github.com/nalgeon/redka/internal/rstring"
 	"github.com/nalgeon/redka/internal/rzset"
 	"github.com/nalgeon/redka/internal/sqlx"
 )
 
-const driverName = "sqlite3"
+// defaultDriverName is the name of the database driver
+// used by default (mattn/go-sqlite3).
+const defaultDriverName = "sqlite3"
+
+const sqlForeignKeys = `pragma foreign_keys`
+
+// ErrForeignKeys is returned by [OpenDB] if the connection
+// does not enforce foreign keys, which Redka relies on.
+var ErrForeignKeys = errors.New("foreign keys are not enabled")
 
 // Common errors returned by data structure methods.
 var (
-	ErrNotFound  = core.ErrNotFound  // key not found
-	ErrKeyType   = core.ErrKeyType   // key type mismatch
-	ErrValueType = core.ErrValueType // invalid value type
+	ErrNotFound        = core.ErrNotFound        // key not found
+	ErrKeyType         = core.ErrKeyType         // key type mismatch
+	ErrValueType       = core.ErrValueType       // invalid value type
+	ErrBusy            = core.ErrBusy            // database is busy
+	ErrTxClosed        = core.ErrTxClosed        // transaction is closed
+	ErrValueTooLarge   = core.ErrValueTooLarge   // value is too large
+	ErrSyntax          = core.ErrSyntax          // syntax error
+	ErrReadOnly        = core.ErrReadOnly        // database is read-only
+	ErrVersion         = core.ErrVersion         // key version mismatch
+	ErrKeyTooLarge     = core.ErrKeyTooLarge     // key is too large
+	ErrTooManyElements = core.ErrTooManyElements // too many elements
+	ErrCrossSlot       = core.ErrCrossSlot       // keys in different slots
+	ErrQuotaExceeded   = core.ErrQuotaExceeded   // quota exceeded
+	ErrStreamID        = core.ErrStreamID        // invalid stream ID
 )
 
 // Key represents a key data structure.
@@ -39,19 +64,274 @@ var (
 // you can't have a string and a hash map with the same key.
 type Key = core.Key
 
+// KeyTypeError describes a key type mismatch: the key, the type
+// required by the operation, and the actual type of the key.
+// Matches [ErrKeyType] with errors.Is, so use errors.As only
+// when the details are needed.
+type KeyTypeError = core.KeyTypeError
+
+// RetryPolicy configures retrying the write transactions
+// that fail because the database is busy (SQLITE_BUSY).
+type RetryPolicy = sqlx.RetryPolicy
+
 // Value represents a value stored in a database (a byte slice).
 // It can be converted to other scalar types.
 type Value = core.Value
 
 // Options is the configuration for the database.
 type Options struct {
+	// DriverName is the name of the registered SQLite driver.
+	// Use "sqlite" for the pure-Go modernc.org/sqlite driver
+	// (allows building without CGO). If empty, uses "sqlite3"
+	// (mattn/go-sqlite3 or a compatible driver).
+	DriverName string
 	// Logger is the logger for the database.
 	// If nil, a silent logger is used.
 	Logger *slog.Logger
+	// SlowThreshold logs the repository operations that take
+	// longer than the threshold as warnings, with their names,
+	// keys and durations. If zero, the slow operations are not logged.
+	SlowThreshold time.Duration
+	// Pragmas are the SQLite pragmas set on each connection after
+	// the default ones (like "cache_size" or "journal_mode"), so they
+	// override the defaults. The values are used as is.
+	// See https://sqlite.org/pragma.html for the available pragmas.
+	Pragmas map[string]string
+	// MaxOpenConns is the maximum number of open connections.
+	// If zero, uses a single connection, so the writes never fail
+	// with "database is locked" (see [DB.Update]). More connections
+	// allow concurrent reads, while the writes wait for each other
+	// using the busy timeout and BusyRetry.
+	MaxOpenConns int
+	// MaxIdleConns is the maximum number of idle connections.
+	// If zero, uses the database/sql default.
+	MaxIdleConns int
+	// ReadPoolSize is the maximum number of connections in the
+	// read pool. The read pool is separate from the connections
+	// used for writes, and only allows reads. The repository reads
+	// made outside of transactions (like Str().Get or Key().Scan)
+	// and [DB.ViewSnapshot] use the read pool, so they do not wait
+	// for the writes (and the writes do not wait for them) in the
+	// WAL journal mode. If zero, uses 4. Ignored for in-memory
+	// databases, which use the same connection for reads and writes.
+	ReadPoolSize int
+	// BusyTimeout is how long a connection waits for the lock held
+	// by another connection (or process) before failing with
+	// "database is locked". Applies to the write connection and
+	// the read pool. If zero, uses 5 seconds.
+	BusyTimeout time.Duration
+	// ReadOnly opens an existing database in the read-only mode:
+	// the schema is not created or migrated, the background workers
+	// (like the expired keys cleanup) are not started, and the writes
+	// fail with [ErrReadOnly].
+	ReadOnly bool
+	// Clock is the source of the current time for the expiration
+	// checks and the modification times of the keys. Use it to
+	// test the expiration without waiting. If nil, uses time.Now.
+	Clock func() time.Time
+	// Rand is the source of random numbers for picking random
+	// keys (see [rkey.DB.Random] and the RANDOMKEY command)
+	// and sorted set elements (see [rzset.DB.RandMember]).
+	// Use a seeded source to make the tests and replays
+	// deterministic. If nil, uses SQLite's random().
+	Rand *rand.Rand
+	// Metrics enables the Prometheus metrics (see [DB.Metrics]).
+	Metrics bool
+	// MaxKeySize is the maximum key length in bytes. Writes with
+	// longer keys fail with [ErrKeyTooLarge]. If zero, keys are
+	// only limited by SQLite (1 GB by default).
+	MaxKeySize int
+	// MaxValueSize is the maximum size in bytes of a string value,
+	// a hash field or value, or a sorted set member. Larger writes
+	// fail with [ErrValueTooLarge] before anything is written.
+	// If zero, values are only limited by SQLite (1 GB by default).
+	MaxValueSize int
+	// MaxElements is the maximum number of fields in a hash or
+	// members in a sorted set. Writes that add more elements fail
+	// with [ErrTooManyElements]. If zero, there is no limit.
+	MaxElements int
+	// Quotas limit the number of keys, their total size and the
+	// write rate per key prefix (see [Quota]), so that one tenant
+	// of a shared database can not take all of it. For the tenant
+	// databases (see [Tenants]), use a quota with an empty prefix.
+	// If empty, there are no quotas.
+	Quotas []Quota
+	// QuotaInterval is how often the quota usage (the number
+	// of keys and their size) is measured in the background.
+	// Each measurement reads all the keys with the quota prefixes.
+	// If zero, uses 1 second.
+	QuotaInterval time.Duration
+	// TrashRetention enables the soft delete: the deleted keys
+	// (with Delete, Unlink or DeleteAll, and the DEL, UNLINK and
+	// FLUSHDB commands) go to the trash instead, and can be restored
+	// with [rkey.DB.Restore] until the retention passes. Protects
+	// against deleting the wrong keys by mistake, at the cost of
+	// keeping the deleted data for a while. The trash is purged along
+	// with the expired keys (see ExpireInterval). If zero, the keys
+	// are deleted right away, and the trash left from before
+	// is purged.
+	TrashRetention time.Duration
+	// History enables recording the versions of the keys (see
+	// [HistoryOptions]), so that they can be listed with [DB.Revisions]
+	// and read as of a past time with [DB.GetAsOf]. Each write to
+	// a recorded key saves a copy of its whole value, so limit the
+	// history to the keys that change rarely (like configuration
+	// values). If nil, the history is not recorded.
+	History *HistoryOptions
+	// ExpireInterval is how often the expired keys are deleted
+	// in the background. The expired keys are not visible even
+	// before they are deleted. If zero, uses 60 seconds.
+	ExpireInterval time.Duration
+	// ExpireStrategy defines how the expired keys are deleted
+	// (see [ExpireStrategy]). If empty, uses ExpireBackground.
+	ExpireStrategy ExpireStrategy
+	// ExpireBatchSize is the maximum number of expired keys
+	// deleted in a single transaction. Smaller batches make the
+	// concurrent writes wait less, larger ones make the sweeps
+	// faster. If zero, uses 1000 keys.
+	ExpireBatchSize int
+	// ExpireMaxDuration limits the duration of a single sweep of the
+	// expired keys. The keys left are deleted by the next sweep.
+	// If zero, each sweep deletes all the expired keys.
+	ExpireMaxDuration time.Duration
+	// ScheduleInterval is how often the due scheduled operations
+	// (see [DB.ScheduleSet]) are run in the background, so they run
+	// up to this late. If zero, uses 1 second.
+	ScheduleInterval time.Duration
+	// BusyRetry retries the write transactions that fail because
+	// another connection (or process) holds the write lock, in
+	// addition to the busy timeout. Fails with [ErrBusy] when the
+	// attempts are exhausted. A retried transaction function is
+	// called again, so it should not have side effects outside of
+	// the database. If nil, uses 5 attempts with 10ms-1s backoff.
+	BusyRetry *RetryPolicy
+	// Codec encodes the values of types without a natural string
+	// representation (like structs) for the typed accessors
+	// [Get] and [Set], the struct mapping [GetStruct] and [SetStruct],
+	// and [Memoize]. If nil, uses [JSONCodec].
+	Codec Codec
+	// TracerProvider provides the tracer for the repository
+	// operations and the transactions (see [TracerProvider]).
+	// The server also traces the commands with it.
+	// If nil, the operations are not traced.
+	TracerProvider TracerProvider
+	// Durability is the default durability level of the writes
+	// (see [WithDurability] to override it for specific writes).
+	// If empty, uses DurabilityNormal for file databases and
+	// DurabilityOff for in-memory ones.
+	Durability Durability
+	// SyncInterval syncs the database files to disk at regular
+	// intervals, bounding the data loss after a power loss
+	// with DurabilityOff (like "appendfsync everysec" in Redis).
+	// If zero, the files are synced according to the durability.
+	SyncInterval time.Duration
+	// WriterQueue enables the single-writer mode: all write
+	// transactions are executed one by one by a dedicated goroutine,
+	// so they never contend for the database lock. Writers wait in
+	// a queue of the given size (and block when it is full), and
+	// leave the queue if their context is canceled. If zero,
+	// the transactions are executed by the calling goroutines.
+	WriterQueue int
+	// Outbox enables recording the committed changes
+	// in the outbox table. See [DB.PublishOutbox] for details.
+	Outbox bool
+	// TablePrefix is added to the names of the database tables
+	// (e.g. "redka_" gives "redka_rkey" instead of "rkey"). Use it
+	// to store Redka data alongside other tables in the same database.
+	// All connections to the database must use the same prefix.
+	TablePrefix string
+	// Collation is the collation of the keys, hash fields and sorted
+	// set elements, chosen when the database is created: "binary"
+	// (case-sensitive), "nocase" (case-insensitive for ASCII letters),
+	// "rtrim", or a collation registered in the driver (like an ICU
+	// one). It decides which keys, fields and elements are equal,
+	// the order of the elements with the same score, and whether
+	// the Keys and Scan patterns match case-insensitively (only with
+	// nocase). Opening an existing database with another collation
+	// fails with [ErrIncompatible]. If empty, uses the collation of
+	// the database, or binary for a new one.
+	Collation string
+	// InMemory keeps the database in memory instead of a file,
+	// which suits tests and ephemeral caches. The path passed to
+	// [Open] becomes the database name: DBs opened with the same
+	// name within the process share the data, while an empty name
+	// gives a private database. The data is lost when the last
+	// DB with the name is closed.
+	InMemory bool
+	// ReadReplicas are the data sources of the read replicas of
+	// the database (e.g. restored by Litestream or synced by LiteFS).
+	// Redka never writes to the replicas, so they should be opened
+	// in read-only mode (e.g. "file:replica.db?mode=ro"). Read-only
+	// repository methods (like [rstring.DB.Get]) go to the replicas,
+	// while writes and transactions go to the primary database.
+	ReadReplicas []string
+	// MaxReplicaLag is the maximum allowed lag of a read replica.
+	// Reads skip the replicas that lag more, falling back to
+	// the primary database. If zero, the lag is not checked.
+	MaxReplicaLag time.Duration
+	// PinAfterWrite sends the reads to the primary database
+	// instead of the read replicas for this long after each
+	// write transaction, so the reads right after a write never
+	// miss it, even if the replicas lag behind. Set it to about
+	// the replica lag. Use [DB.Primary] to pin a single caller
+	// instead. If zero, the reads are not pinned.
+	PinAfterWrite time.Duration
+	// AutoCheckpoint enables the background checkpointer that keeps
+	// the WAL file from growing without bound under sustained writes.
+	// See [DB.Checkpoint] for details. If nil, only the SQLite's
+	// automatic checkpoints are made.
+	AutoCheckpoint *CheckpointOptions
+	// IncrementalVacuum enables the incremental auto_vacuum mode,
+	// and starts reclaiming the free pages in small steps when the
+	// database is idle. Switching an existing database to this mode
+	// rebuilds it with a full VACUUM on open, which may take a while.
+	// See [DB.Compact] to reclaim the free pages on demand.
+	// If nil, the free pages are reused, but not reclaimed.
+	IncrementalVacuum *VacuumOptions
+	// AutoSnapshot enables the scheduled snapshots of the database
+	// file, made with [DB.Snapshot] when the snapshot rules match.
+	// If nil, snapshots are only made on demand.
+	AutoSnapshot *SnapshotOptions
+	// AutoBackup enables the scheduled backups of the database
+	// (see [DB.BackupRotate]), keeping the given number of the
+	// latest ones. Unlike the snapshots, the backups do not stop
+	// the writes. If nil, backups are only made on demand.
+	AutoBackup *BackupOptions
+	// WALArchive enables the continuous archiving of the WAL segments
+	// for the point-in-time recovery (see [RestoreArchive]). Stores
+	// a base snapshot of the database on open, and then the WAL
+	// segments at regular intervals. The archiver takes over the
+	// SQLite's automatic checkpoints, so the WAL grows until the next
+	// segment is archived. If nil, the WAL is not archived.
+	WALArchive *ArchiveOptions
+	// ManualMigrations disables upgrading the schema of an existing
+	// database on open. Use it to control when the upgrade happens
+	// (e.g. when several processes share the database), and call
+	// [DB.MigrateSchema] to upgrade. New databases are always
+	// created with the latest schema.
+	ManualMigrations bool
+	// EncryptionKey is the passphrase to encrypt the database file.
+	// Requires a driver built with SQLCipher and registered as "sqlite3"
+	// (otherwise Open fails with [ErrEncryption]). Use [DB.Rekey]
+	// to change the key. If empty, the database is not encrypted.
+	EncryptionKey string
 }
 
 var defaultOptions = Options{
-	Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	DriverName: defaultDriverName,
+	Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	BusyRetry: &RetryPolicy{
+		Attempts:  5,
+		BaseDelay: 10 * time.Millisecond,
+		MaxDelay:  time.Second,
+	},
+	Codec:            JSONCodec,
+	ExpireInterval:   60 * time.Second,
+	ExpireStrategy:   ExpireBackground,
+	ExpireBatchSize:  1000,
+	QuotaInterval:    time.Second,
+	ReadPoolSize:     4,
+	ScheduleInterval: time.Second,
 }
 
 // DB is a Redis-like database backed by SQLite.
@@ -65,44 +345,389 @@ type DB struct {
 	stringDB *rstring.DB
 	hashDB   *rhash.DB
 	zsetDB   *rzset.DB
+	streamDB *rstream.DB
+	hllDB    *rhll.DB
+	changes  *sqlx.Changes
+	watchers *watchers
+	hooks    *sqlx.Hooks
+	stats    *dbStats
+	expire   *expirer
+	driver   string
+	path     string
+	key      *cipherKey
+	shared   bool // the pool is owned by the application
+	anchor   *memoryAnchor
+	replicas *sqlx.Replicas
+	check    *time.Ticker
+	wal      *walState
+	ckpt     *time.Ticker
+	vacuum   *time.Ticker
+	snap     *time.Ticker
+	backup   *backupper
+	sync     *time.Ticker
+	free     *time.Ticker
+	archive  *archiver
 	bg       *time.Ticker
+	sched    *time.Ticker
+	quota    *time.Ticker
+	slowLog  *slowLogHook
+	codec    Codec
+	tracer   Tracer
+	metrics  *Metrics
 	log      *slog.Logger
+	opts     *Options
+	index    int        // logical database index
+	dbs      *databases // logical databases
+	procs    *procs
 }
 
 // Open opens a new or existing database at the given path.
 // Creates the database schema if necessary.
 //
-// Expects the database driver to be already imported with the name "sqlite3".
+// Expects the database driver to be already imported with the name "sqlite3"
+// (or the name set in [Options.DriverName]).
 // See the [simple] and [modernc] examples for details.
 //
 // The returned [DB] is safe for concurrent use by multiple goroutines
 // as long as you use a single instance throughout your program.
 // Typically, you only close the DB when the program exits.
 //
-// The opts parameter is optional. If nil, uses default options.
+// To open an in-memory database, set the InMemory option.
+//
+// The options are optional. Pass either the *[Options] or the
+// With* functional options (or both, see [Option]):
+//
+//	db, err := redka.Open("data.db", nil)
+//	db, err := redka.Open("data.db", &redka.Options{Logger: logger})
+//	db, err := redka.Open("data.db", redka.WithLogger(logger), redka.WithCacheSize(65536))
 //
 // [simple]: https://github.com/nalgeon/redka/blob/main/example/simple/main.go
 // [modernc]: https://github.com/nalgeon/redka/blob/main/example/modernc/main.go
-func Open(path string, opts *Options) (*DB, error) {
-	db, err := sql.Open(driverName, path)
+func Open(path string, options ...Option) (*DB, error) {
+	opts := buildOptions(options)
+	quotas, err := newQuotas(opts.Quotas)
+	if err != nil {
+		return nil, err
+	}
+	var key *cipherKey
+	if opts.EncryptionKey != "" {
+		key = &cipherKey{key: opts.EncryptionKey}
+	}
+	var anchor *memoryAnchor
+	if opts.InMemory {
+		path = memoryDSN(path)
+		var err error
+		anchor, err = openMemoryAnchor(opts.DriverName, path, key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	dbOpts := sqlx.Options{
+		Names:            sqlx.NewNames(opts.TablePrefix),
+		Memory:           opts.InMemory,
+		ReadOnly:         opts.ReadOnly,
+		ManualMigrations: opts.ManualMigrations,
+		Collation:        opts.Collation,
+		Pragmas:          opts.pragmas(),
+		MaxOpenConns:     opts.MaxOpenConns,
+		MaxIdleConns:     opts.MaxIdleConns,
+	}
+	var settings string
+	if opts.MaxOpenConns > 1 {
+		// Each connection needs the same settings.
+		settings = sqlx.Settings(dbOpts)
+		if opts.Durability.Validate() == nil {
+			settings += "pragma synchronous = " + string(opts.Durability) + ";\n"
+		}
+	}
+	db, err := openSQL(opts.DriverName, path, key, settings)
 	if err != nil {
+		if anchor != nil {
+			_ = anchor.Close()
+		}
 		return nil, err
 	}
-	sdb, err := sqlx.Open(db, newTx)
+	if key != nil {
+		// Check before creating the schema,
+		// so that nothing is written unencrypted.
+		if err := checkEncryption(db); err != nil {
+			_ = db.Close()
+			if anchor != nil {
+				_ = anchor.Close()
+			}
+			return nil, err
+		}
+	}
+	var vacuumed bool
+	if opts.IncrementalVacuum != nil {
+		if vacuumed, err = enableIncrementalVacuum(db); err != nil {
+			_ = db.Close()
+			if anchor != nil {
+				_ = anchor.Close()
+			}
+			return nil, err
+		}
+	}
+	sdb, err := sqlx.Open(db, newTx, dbOpts)
 	if err != nil {
+		_ = db.Close()
+		if anchor != nil {
+			_ = anchor.Close()
+		}
 		return nil, err
 	}
-	opts = applyOptions(defaultOptions, opts)
+	if vacuumed {
+		// The rebuilt database may have new row IDs.
+		if err := sdb.NextGeneration(); err != nil {
+			_ = db.Close()
+			if anchor != nil {
+				_ = anchor.Close()
+			}
+			return nil, err
+		}
+	}
+	if opts.Durability != "" {
+		if err := sqlx.SetDurability(db, opts.Durability); err != nil {
+			_ = db.Close()
+			if anchor != nil {
+				_ = anchor.Close()
+			}
+			return nil, err
+		}
+	}
+	replicas, err := openReplicas(opts, key, dbOpts.Names)
+	if err != nil {
+		_ = db.Close()
+		if anchor != nil {
+			_ = anchor.Close()
+		}
+		return nil, err
+	}
+	var reader *sql.DB
+	if !isMemoryPath(path) {
+		reader, err = openReader(opts, path, key, dbOpts.Pragmas)
+		if err != nil {
+			_ = db.Close()
+			_ = replicas.Close()
+			if anchor != nil {
+				_ = anchor.Close()
+			}
+			return nil, err
+		}
+	}
+	rdb := newDB(sdb, opts)
+	rdb.setQuotas(quotas)
+	rdb.setReader(reader)
+	rdb.driver = opts.DriverName
+	rdb.path = path
+	rdb.key = key
+	rdb.anchor = anchor
+	rdb.setReplicas(replicas)
+	rdb.check = rdb.startReplicaCheck()
+	if opts.ReadOnly {
+		return rdb, nil
+	}
+	rdb.bg = rdb.startBgManager(opts.ExpireInterval)
+	rdb.sched = rdb.startScheduler(opts.ScheduleInterval)
+	rdb.quota = rdb.startQuotaRefresh(opts.QuotaInterval)
+	rdb.free = rdb.startLazyFree()
+	rdb.ckpt = rdb.startCheckpointer(opts.AutoCheckpoint)
+	rdb.vacuum = rdb.startVacuum(opts.IncrementalVacuum)
+	rdb.snap = rdb.startSnapshots(opts.AutoSnapshot)
+	rdb.backup = rdb.startBackups(opts.AutoBackup)
+	if rdb.sync, err = rdb.startSync(opts.SyncInterval); err != nil {
+		_ = rdb.Close()
+		return nil, err
+	}
+	if err := rdb.startArchive(opts.WALArchive); err != nil {
+		_ = rdb.Close()
+		return nil, err
+	}
+	return rdb, nil
+}
+
+// OpenDB opens a database using an existing connection pool owned
+// by the application. Creates the database schema if necessary.
+// Use the TablePrefix option to keep Redka's tables apart from the
+// application tables in the same database.
+//
+// Unlike [Open], OpenDB does not change the pool settings or the
+// connection properties, so the application should configure them
+// (e.g. in the data source name):
+//   - foreign keys must be enabled (required);
+//   - WAL journal mode and a busy timeout are recommended;
+//   - or limit the pool to a single connection to avoid
+//     "database is locked" errors with concurrent writes.
+//
+// Closing the returned DB does not close the pool.
+// Use [DB.UseTx] to work with Redka data structures
+// in the application's transactions.
+//
+// The options are optional (see [Open]). The DriverName, EncryptionKey,
+// InMemory, ReadReplicas, IncrementalVacuum, AutoSnapshot, AutoBackup,
+// WALArchive, Durability, SyncInterval, Pragmas, MaxOpenConns and
+// MaxIdleConns options are ignored.
+func OpenDB(db *sql.DB, options ...Option) (*DB, error) {
+	opts := buildOptions(options)
+	rdb, err := openShared(db, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.ReadOnly {
+		return rdb, nil
+	}
+	rdb.bg = rdb.startBgManager(opts.ExpireInterval)
+	rdb.sched = rdb.startScheduler(opts.ScheduleInterval)
+	rdb.quota = rdb.startQuotaRefresh(opts.QuotaInterval)
+	rdb.free = rdb.startLazyFree()
+	rdb.ckpt = rdb.startCheckpointer(opts.AutoCheckpoint)
+	return rdb, nil
+}
+
+// openShared opens a database using an existing connection pool
+// (see [OpenDB]), but does not start the background tasks.
+func openShared(db *sql.DB, opts *Options) (*DB, error) {
+	quotas, err := newQuotas(opts.Quotas)
+	if err != nil {
+		return nil, err
+	}
+	var fk bool
+	if err := db.QueryRow(sqlForeignKeys).Scan(&fk); err != nil {
+		return nil, err
+	}
+	if !fk {
+		return nil, ErrForeignKeys
+	}
+	names := sqlx.NewNames(opts.TablePrefix)
+	sdb, err := sqlx.Open(db, newTx, sqlx.Options{
+		Names:            names,
+		Shared:           true,
+		ReadOnly:         opts.ReadOnly,
+		ManualMigrations: opts.ManualMigrations,
+		Collation:        opts.Collation,
+	})
+	if err != nil {
+		return nil, err
+	}
+	rdb := newDB(sdb, opts)
+	rdb.setQuotas(quotas)
+	rdb.shared = true
+	return rdb, nil
+}
+
+// newDB creates the database on top of the SQL repository.
+func newDB(sdb *sqlx.DB[*Tx], opts *Options) *DB {
+	db := sdb.SQL
 	rdb := &DB{
 		DB:       sdb,
 		keyDB:    rkey.New(db),
 		stringDB: rstring.New(db),
 		hashDB:   rhash.New(db),
 		zsetDB:   rzset.New(db),
+		streamDB: rstream.New(db),
+		hllDB:    rhll.New(db),
+		changes:  &sqlx.Changes{},
+		watchers: &watchers{},
+		hooks:    &sqlx.Hooks{},
+		stats:    newDBStats(),
+		expire:   newExpirer(opts),
+		wal:      &walState{},
+		codec:    opts.Codec,
 		log:      opts.Logger,
+		opts:     opts,
+		procs:    &procs{m: map[string]Proc{}},
 	}
-	rdb.bg = rdb.startBgManager()
-	return rdb, nil
+	rdb.dbs = newDatabases(rdb)
+	// All repositories share the same table names,
+	// change capture, retry policy, hooks and counters.
+	rdb.DB.Changes, rdb.DB.Retry = rdb.changes, opts.BusyRetry
+	rdb.keyDB.Names, rdb.keyDB.Changes = sdb.Names, rdb.changes
+	rdb.stringDB.Names, rdb.stringDB.Changes = sdb.Names, rdb.changes
+	rdb.hashDB.Names, rdb.hashDB.Changes = sdb.Names, rdb.changes
+	rdb.zsetDB.Names, rdb.zsetDB.Changes = sdb.Names, rdb.changes
+	rdb.streamDB.Names, rdb.streamDB.Changes = sdb.Names, rdb.changes
+	rdb.hllDB.Names, rdb.hllDB.Changes = sdb.Names, rdb.changes
+	rdb.keyDB.Retry, rdb.stringDB.Retry = opts.BusyRetry, opts.BusyRetry
+	rdb.hashDB.Retry, rdb.zsetDB.Retry, rdb.streamDB.Retry = opts.BusyRetry, opts.BusyRetry, opts.BusyRetry
+	rdb.hllDB.Retry = opts.BusyRetry
+	rdb.keyDB.Hooks, rdb.stringDB.Hooks = rdb.hooks, rdb.hooks
+	rdb.hashDB.Hooks, rdb.zsetDB.Hooks, rdb.streamDB.Hooks = rdb.hooks, rdb.hooks, rdb.hooks
+	rdb.hllDB.Hooks = rdb.hooks
+	coll := sdb.Collation
+	rdb.keyDB.Collation, rdb.stringDB.Collation = coll, coll
+	rdb.hashDB.Collation, rdb.zsetDB.Collation, rdb.streamDB.Collation = coll, coll, coll
+	rdb.hllDB.Collation = coll
+	counters := &rdb.stats.Counters
+	rdb.DB.Counters, rdb.keyDB.Counters, rdb.stringDB.Counters = counters, counters, counters
+	rdb.hashDB.Counters, rdb.zsetDB.Counters, rdb.streamDB.Counters = counters, counters, counters
+	rdb.hllDB.Counters = counters
+	if opts.WriterQueue > 0 {
+		w := sqlx.NewWriter(opts.WriterQueue)
+		rdb.DB.Writer, rdb.keyDB.Writer, rdb.stringDB.Writer = w, w, w
+		rdb.hashDB.Writer, rdb.zsetDB.Writer, rdb.streamDB.Writer = w, w, w
+		rdb.hllDB.Writer = w
+	}
+	if opts.Clock != nil {
+		clock := sqlx.Clock(opts.Clock)
+		rdb.DB.Clock, rdb.keyDB.Clock, rdb.stringDB.Clock = clock, clock, clock
+		rdb.hashDB.Clock, rdb.zsetDB.Clock, rdb.streamDB.Clock = clock, clock, clock
+		rdb.hllDB.Clock = clock
+	}
+	if opts.Rand != nil {
+		rnd := sqlx.NewRand(opts.Rand)
+		rdb.DB.Rand, rdb.keyDB.Rand, rdb.stringDB.Rand = rnd, rnd, rnd
+		rdb.hashDB.Rand, rdb.zsetDB.Rand, rdb.streamDB.Rand = rnd, rnd, rnd
+		rdb.hllDB.Rand = rnd
+	}
+	if trash := opts.TrashRetention; trash > 0 {
+		rdb.DB.Trash, rdb.keyDB.Trash, rdb.stringDB.Trash = trash, trash, trash
+		rdb.hashDB.Trash, rdb.zsetDB.Trash, rdb.streamDB.Trash = trash, trash, trash
+		rdb.hllDB.Trash = trash
+	}
+	if history := newHistory(opts.History); history != nil {
+		rdb.DB.History, rdb.keyDB.History, rdb.stringDB.History = history, history, history
+		rdb.hashDB.History, rdb.zsetDB.History, rdb.streamDB.History = history, history, history
+		rdb.hllDB.History = history
+	}
+	// The limits are always set, so that they
+	// can be changed later (see DB.Reconfigure).
+	limits := sqlx.NewLimitsVar(sqlx.Limits{
+		MaxKeySize:   opts.MaxKeySize,
+		MaxValueSize: opts.MaxValueSize,
+		MaxElements:  opts.MaxElements,
+	})
+	rdb.DB.Limits, rdb.keyDB.Limits, rdb.stringDB.Limits = limits, limits, limits
+	rdb.hashDB.Limits, rdb.zsetDB.Limits, rdb.streamDB.Limits = limits, limits, limits
+	rdb.hllDB.Limits = limits
+	if opts.Outbox {
+		rdb.changes.EnableOutbox()
+	}
+	rdb.slowLog = &slowLogHook{log: opts.Logger}
+	rdb.slowLog.setThreshold(rdb, opts.SlowThreshold)
+	if opts.TracerProvider != nil {
+		rdb.tracer = opts.TracerProvider.Tracer(tracerName)
+		rdb.AddHook(&traceHook{tracer: rdb.tracer})
+	}
+	if opts.Metrics {
+		rdb.metrics = NewMetrics(rdb)
+	}
+	return rdb
+}
+
+// Tracer returns the tracer provided by Options.TracerProvider,
+// or nil if the operations are not traced.
+func (db *DB) Tracer() Tracer {
+	return db.tracer
+}
+
+// UseTx returns a Redka transaction that works within an existing
+// SQL transaction started by the application. Use it to modify the
+// Redka data structures and the application tables atomically.
+// The application is responsible for committing or rolling back
+// the SQL transaction. The changes made with UseTx are not reported
+// to [DB.OnChange] or recorded in the outbox.
+func (db *DB) UseTx(tx *sql.Tx) *Tx {
+	return newTx(db.DB.Wrap(tx))
 }
 
 // Str returns the string repository.
@@ -129,6 +754,24 @@ func (db *DB) SortedSet() *rzset.DB {
 	return db.zsetDB
 }
 
+// Stream returns the stream repository.
+// A stream is an append-only log of entries associated with a key.
+// Each entry has a unique ID and a list of field-value pairs.
+// Use the stream repository to append entries to streams
+// and to read them back by ID.
+func (db *DB) Stream() *rstream.DB {
+	return db.streamDB
+}
+
+// HLL returns the HyperLogLog repository.
+// A HyperLogLog estimates the number of unique elements
+// added to it using a fixed amount of memory.
+// Use the HyperLogLog repository to count unique items,
+// such as the visitors of a web page.
+func (db *DB) HLL() *rhll.DB {
+	return db.hllDB
+}
+
 // Key returns the key repository.
 // A key is a unique identifier for a data structure
 // (string, list, hash, etc.). Use the key repository
@@ -137,12 +780,35 @@ func (db *DB) Key() *rkey.DB {
 	return db.keyDB
 }
 
+// WithContext returns a shallow copy of the database that executes
+// the repository methods (like [rstring.DB.Get]) and transactions
+// with ctx, so they are canceled when ctx is done:
+//
+//	ctx, cancel := context.WithTimeout(ctx, time.Second)
+//	defer cancel()
+//	keys, err := db.WithContext(ctx).Key().Keys("user:*")
+//
+// The copy shares the connections and background workers with
+// the original database, so only close the original one.
+func (db *DB) WithContext(ctx context.Context) *DB {
+	c := *db
+	c.DB = db.DB.WithContext(ctx)
+	c.keyDB = db.keyDB.WithContext(ctx)
+	c.stringDB = db.stringDB.WithContext(ctx)
+	c.hashDB = db.hashDB.WithContext(ctx)
+	c.zsetDB = db.zsetDB.WithContext(ctx)
+	c.streamDB = db.streamDB.WithContext(ctx)
+	c.hllDB = db.hllDB.WithContext(ctx)
+	return &c
+}
+
 // Update executes a function within a writable transaction.
+// Use [Tx.Update] for nested transactions within the function.
 // See the [tx] example for details.
 //
 // [tx]: https://github.com/nalgeon/redka/blob/main/example/tx/main.go
 func (db *DB) Update(f func(tx *Tx) error) error {
-	return db.DB.Update(f)
+	return db.UpdateContext(db.DB.Context(), f)
 }
 
 // UpdateContext executes a function within a writable transaction.
@@ -150,15 +816,68 @@ func (db *DB) Update(f func(tx *Tx) error) error {
 //
 // [tx]: https://github.com/nalgeon/redka/blob/main/example/tx/main.go
 func (db *DB) UpdateContext(ctx context.Context, f func(tx *Tx) error) error {
-	return db.DB.UpdateContext(ctx, f)
+	return db.traceTx(ctx, "redka.Update", func(ctx context.Context) error {
+		return db.DB.UpdateContext(ctx, f)
+	})
+}
+
+// UpdateIf executes a function within a writable transaction,
+// but only if none of the keys has changed since the call started
+// (like WATCH, MULTI and EXEC in Redis). Records the versions of
+// the keys before waiting for the write lock, and checks them
+// once the transaction starts. If any of the keys has been created,
+// changed or deleted in between, does not call the function
+// and returns ErrVersion, so the caller can retry.
+//
+// The versions are read from the primary database (using the read
+// pool if there is one), never from the read replicas, which may
+// lag behind. A key deleted and created again counts as changed
+// even if it has the same version, because it gets a new ID:
+//
+//	for {
+//	    err := db.UpdateIf([]string{"counter"}, func(tx *redka.Tx) error {
+//	        _, err := tx.Str().Incr("counter", 1)
+//	        return err
+//	    })
+//	    if !errors.Is(err, redka.ErrVersion) {
+//	        return err
+//	    }
+//	}
+func (db *DB) UpdateIf(keys []string, f func(tx *Tx) error) error {
+	prev := make([]core.Key, len(keys))
+	op := db.keyDB.Observe("Key.Get", keys...)
+	err := db.DB.In(op).ViewSnapshot(func(tx *Tx) error {
+		for i, key := range keys {
+			k, err := tx.Key().Get(key)
+			if err != nil {
+				return err
+			}
+			prev[i] = k
+		}
+		return nil
+	})
+	if err := op.Done(err); err != nil {
+		return err
+	}
+	return db.Update(func(tx *Tx) error {
+		for i, key := range keys {
+			err := tx.Key().CheckVersion(key, prev[i])
+			if err != nil {
+				return err
+			}
+		}
+		return f(tx)
+	})
 }
 
 // View executes a function within a read-only transaction.
-// See the [tx] example for details.
+// The transaction uses the same connection as the writes,
+// so it blocks them until it completes. Use [DB.ViewSnapshot]
+// for long-running reads. See the [tx] example for details.
 //
 // [tx]: https://github.com/nalgeon/redka/blob/main/example/tx/main.go
 func (db *DB) View(f func(tx *Tx) error) error {
-	return db.DB.View(f)
+	return db.ViewContext(db.DB.Context(), f)
 }
 
 // ViewContext executes a function within a read-only transaction.
@@ -166,43 +885,188 @@ func (db *DB) View(f func(tx *Tx) error) error {
 //
 // [tx]: https://github.com/nalgeon/redka/blob/main/example/tx/main.go
 func (db *DB) ViewContext(ctx context.Context, f func(tx *Tx) error) error {
-	return db.DB.ViewContext(ctx, f)
+	return db.traceTx(ctx, "redka.View", func(ctx context.Context) error {
+		return db.DB.ViewContext(ctx, f)
+	})
+}
+
+// ViewSnapshot executes a function within a read-only transaction
+// that sees a consistent snapshot of the database as of the start
+// of the call. Writes made by others while the function runs are
+// not visible to it.
+//
+// Unlike [DB.View], the transaction runs on a connection from the
+// separate read pool (see Options.ReadPoolSize), so long-running
+// reads (like analytics or exports) do not block the writes,
+// and the writes do not block them. The read pool connections
+// are read-only: writes within the function fail with [ErrReadOnly].
+//
+// Requires the WAL journal mode (the default) for the reads and
+// writes to run concurrently. In-memory databases and databases
+// opened with [OpenDB] have no read pool, so the function runs
+// like with [DB.View].
+//
+// Note that the WAL file can not be checkpointed past the oldest
+// snapshot in use, so very long snapshots make the WAL grow.
+func (db *DB) ViewSnapshot(f func(tx *Tx) error) error {
+	return db.ViewSnapshotContext(db.DB.Context(), f)
+}
+
+// ViewSnapshotContext is like [DB.ViewSnapshot], but with a context.
+func (db *DB) ViewSnapshotContext(ctx context.Context, f func(tx *Tx) error) error {
+	return db.traceTx(ctx, "redka.ViewSnapshot", func(ctx context.Context) error {
+		return db.DB.ViewSnapshotContext(ctx, f)
+	})
 }
 
 // Close closes the database.
 // It's safe for concurrent use by multiple goroutines.
 func (db *DB) Close() error {
-	db.bg.Stop()
-	return db.SQL.Close()
+	if db.bg != nil {
+		db.bg.Stop()
+	}
+	if db.sched != nil {
+		db.sched.Stop()
+	}
+	if db.quota != nil {
+		db.quota.Stop()
+	}
+	if db.free != nil {
+		db.free.Stop()
+	}
+	if db.check != nil {
+		db.check.Stop()
+	}
+	if db.ckpt != nil {
+		db.ckpt.Stop()
+	}
+	if db.vacuum != nil {
+		db.vacuum.Stop()
+	}
+	if db.snap != nil {
+		db.snap.Stop()
+	}
+	if db.backup != nil {
+		db.backup.stop()
+	}
+	if db.sync != nil {
+		db.sync.Stop()
+	}
+	if db.index == 0 {
+		// The logical databases share the writer of database 0.
+		db.dbs.close()
+		db.DB.Writer.Close()
+	}
+	if db.archive != nil {
+		// Archive the last segment.
+		db.archive.ticker.Stop()
+		if _, err := db.Checkpoint(CheckpointTruncate); err != nil {
+			db.log.Error("archive", "error", err)
+		}
+	}
+	if db.shared {
+		return nil
+	}
+	_ = db.replicas.Close()
+	if db.DB.Reader != nil {
+		_ = db.DB.Reader.Close()
+	}
+	err := db.SQL.Close()
+	if db.anchor != nil {
+		_ = db.anchor.Close()
+	}
+	return err
 }
 
 // startBgManager starts the goroutine than runs
 // in the background and deletes expired keys.
-// Triggers every 60 seconds, deletes up all expired keys.
-func (db *DB) startBgManager() *time.Ticker {
-	// TODO: needs further investigation. Deleting all keys may be expensive
-	// and lead to timeouts for concurrent write operations.
-	// Adaptive limits based on the number of changed keys may be a solution.
-	// (see https://redis.io/docs/management/config-file/ > SNAPSHOTTING)
-	// And it doesn't help that SQLite's drivers do not support DELETE LIMIT,
-	// so we have to use DELETE IN (SELECT ...), which is more expensive.
-	const interval = 60 * time.Second
-	const nKeys = 0
+// Triggers every interval (see Options.ExpireInterval),
+// deletes the expired keys and hash fields (unless the expiration
+// is lazy or paused), purges the trash and prunes the key history
+// in each of the opened logical databases.
+func (db *DB) startBgManager(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			for _, db := range db.dbs.all() {
+				db.runBgTasks()
+			}
+		}
+	}()
+	return ticker
+}
 
+// runBgTasks deletes expired keys, purges the trash
+// and prunes the key history (see startBgManager).
+func (db *DB) runBgTasks() {
+	// The expired keys are deleted in batches (each in a separate
+	// transaction), so concurrent writes do not wait for the whole sweep.
+	// The sweep uses the partial index on etime, so it only reads
+	// the expiring keys, not the whole table.
+	if db.expire.active() {
+		keys, fields, err := db.sweepExpired()
+		if err != nil {
+			db.log.Error("bg: delete expired keys", "error", err)
+		} else {
+			db.log.Info("bg: delete expired keys", "count", keys)
+			if fields > 0 {
+				db.log.Info("bg: delete expired fields", "count", fields)
+			}
+		}
+	}
+	count, err := db.keyDB.PurgeTrash()
+	if err != nil {
+		db.log.Error("bg: purge trash", "error", err)
+	} else if count > 0 {
+		db.log.Info("bg: purge trash", "count", count)
+	}
+	count, err = db.pruneHistory()
+	if err != nil {
+		db.log.Error("bg: prune history", "error", err)
+	} else if count > 0 {
+		db.log.Info("bg: prune history", "count", count)
+	}
+}
+
+// startLazyFree starts the goroutine that runs in the background
+// and deletes the values of the unlinked keys (see [rkey.DB.Unlink])
+// in each of the opened logical databases.
+func (db *DB) startLazyFree() *time.Ticker {
+	const interval = time.Second
 	ticker := time.NewTicker(interval)
 	go func() {
 		for range ticker.C {
-			count, err := db.keyDB.DeleteExpired(nKeys)
-			if err != nil {
-				db.log.Error("bg: delete expired keys", "error", err)
-			} else {
-				db.log.Info("bg: delete expired keys", "count", count)
+			for _, db := range db.dbs.all() {
+				db.freeUnlinked()
 			}
 		}
 	}()
 	return ticker
 }
 
+// freeUnlinked deletes the values of the unlinked keys.
+// Deletes the values in small batches, so that other writes
+// can run in between.
+func (db *DB) freeUnlinked() {
+	const batchSize = 1000
+	var total int
+	for {
+		n, err := db.keyDB.FreeStep(batchSize)
+		if err != nil {
+			db.log.Error("bg: free unlinked keys", "error", err)
+			break
+		}
+		if n == 0 {
+			break
+		}
+		total += n
+	}
+	if total > 0 {
+		db.stats.freed.Add(int64(total))
+		db.log.Debug("bg: free unlinked keys", "count", total)
+	}
+}
+
 // Tx is a Redis-like database transaction.
 // Same as [DB], Tx provides access to data structures like keys,
 // strings, and hashes. The difference is that you call Tx methods
@@ -212,20 +1076,25 @@ func (db *DB) startBgManager() *time.Ticker {
 //
 // [tx]: https://github.com/nalgeon/redka/blob/main/example/tx/main.go
 type Tx struct {
-	tx     sqlx.Tx
-	keyTx  *rkey.Tx
-	strTx  *rstring.Tx
-	hashTx *rhash.Tx
-	zsetTx *rzset.Tx
+	tx       sqlx.Tx
+	keyTx    *rkey.Tx
+	strTx    *rstring.Tx
+	hashTx   *rhash.Tx
+	zsetTx   *rzset.Tx
+	streamTx *rstream.Tx
+	hllTx    *rhll.Tx
+	depth    int // savepoint nesting level
 }
 
 // newTx creates a new database transaction.
 func newTx(tx sqlx.Tx) *Tx {
 	return &Tx{tx: tx,
-		keyTx:  rkey.NewTx(tx),
-		strTx:  rstring.NewTx(tx),
-		hashTx: rhash.NewTx(tx),
-		zsetTx: rzset.NewTx(tx),
+		keyTx:    rkey.NewTx(tx),
+		strTx:    rstring.NewTx(tx),
+		hashTx:   rhash.NewTx(tx),
+		zsetTx:   rzset.NewTx(tx),
+		streamTx: rstream.NewTx(tx),
+		hllTx:    rhll.NewTx(tx),
 	}
 }
 
@@ -249,14 +1118,191 @@ func (tx *Tx) SortedSet() *rzset.Tx {
 	return tx.zsetTx
 }
 
+// Stream returns the stream transaction.
+func (tx *Tx) Stream() *rstream.Tx {
+	return tx.streamTx
+}
+
+// HLL returns the HyperLogLog transaction.
+func (tx *Tx) HLL() *rhll.Tx {
+	return tx.hllTx
+}
+
+// Update executes a function within a nested transaction (a savepoint).
+// If the function returns an error, the changes it made are rolled back,
+// while the changes made by the enclosing transaction before the call
+// are kept. The error is returned as is, so the enclosing transaction
+// can handle it and go on, or return it and roll back completely:
+//
+//	err := db.Update(func(tx *redka.Tx) error {
+//	    _ = tx.Str().Set("name", "alice")
+//	    err := tx.Update(func(tx *redka.Tx) error {
+//	        _ = tx.Str().Set("age", 25)
+//	        return errors.New("failed")
+//	    })
+//	    // err is "failed", name is set, age is not.
+//	    return nil
+//	})
+//
+// Nested transactions can be nested further. Calling [DB.Update]
+// within a transaction instead would wait for the enclosing
+// transaction forever, so always use Tx.Update.
+func (tx *Tx) Update(f func(tx *Tx) error) error {
+	tx.depth++
+	defer func() { tx.depth-- }()
+	name := fmt.Sprintf("redka_sp%d", tx.depth)
+	return sqlx.Savepoint(tx.tx, name, func() error {
+		return f(tx)
+	})
+}
+
 // applyOptions applies custom options to the
 // default options and returns the result.
 func applyOptions(opts Options, custom *Options) *Options {
+	custom.apply(&opts)
+	return &opts
+}
+
+// apply implements [Option]. Sets the non-zero fields
+// of the custom options, leaving the others as is.
+func (custom *Options) apply(opts *Options) {
 	if custom == nil {
-		return &opts
+		return
+	}
+	if custom.DriverName != "" {
+		opts.DriverName = custom.DriverName
 	}
 	if custom.Logger != nil {
 		opts.Logger = custom.Logger
 	}
-	return &opts
+	if custom.BusyRetry != nil {
+		opts.BusyRetry = custom.BusyRetry
+	}
+	if custom.Codec != nil {
+		opts.Codec = custom.Codec
+	}
+	if custom.TracerProvider != nil {
+		opts.TracerProvider = custom.TracerProvider
+	}
+	if custom.SlowThreshold != 0 {
+		opts.SlowThreshold = custom.SlowThreshold
+	}
+	if len(custom.Pragmas) > 0 {
+		opts.Pragmas = maps.Clone(opts.Pragmas)
+		if opts.Pragmas == nil {
+			opts.Pragmas = map[string]string{}
+		}
+		maps.Copy(opts.Pragmas, custom.Pragmas)
+	}
+	if custom.MaxOpenConns != 0 {
+		opts.MaxOpenConns = custom.MaxOpenConns
+	}
+	if custom.MaxIdleConns != 0 {
+		opts.MaxIdleConns = custom.MaxIdleConns
+	}
+	if custom.ReadPoolSize != 0 {
+		opts.ReadPoolSize = custom.ReadPoolSize
+	}
+	if custom.BusyTimeout != 0 {
+		opts.BusyTimeout = custom.BusyTimeout
+	}
+	if custom.ReadOnly {
+		opts.ReadOnly = true
+	}
+	if custom.Clock != nil {
+		opts.Clock = custom.Clock
+	}
+	if custom.Rand != nil {
+		opts.Rand = custom.Rand
+	}
+	if custom.Metrics {
+		opts.Metrics = true
+	}
+	if custom.MaxKeySize != 0 {
+		opts.MaxKeySize = custom.MaxKeySize
+	}
+	if custom.MaxValueSize != 0 {
+		opts.MaxValueSize = custom.MaxValueSize
+	}
+	if custom.MaxElements != 0 {
+		opts.MaxElements = custom.MaxElements
+	}
+	if custom.Quotas != nil {
+		opts.Quotas = custom.Quotas
+	}
+	if custom.QuotaInterval != 0 {
+		opts.QuotaInterval = custom.QuotaInterval
+	}
+	if custom.TrashRetention != 0 {
+		opts.TrashRetention = custom.TrashRetention
+	}
+	if custom.History != nil {
+		opts.History = custom.History
+	}
+	if custom.ExpireInterval != 0 {
+		opts.ExpireInterval = custom.ExpireInterval
+	}
+	if custom.ExpireStrategy != "" {
+		opts.ExpireStrategy = custom.ExpireStrategy
+	}
+	if custom.ExpireBatchSize != 0 {
+		opts.ExpireBatchSize = custom.ExpireBatchSize
+	}
+	if custom.ExpireMaxDuration != 0 {
+		opts.ExpireMaxDuration = custom.ExpireMaxDuration
+	}
+	if custom.ScheduleInterval != 0 {
+		opts.ScheduleInterval = custom.ScheduleInterval
+	}
+	if custom.Durability != "" {
+		opts.Durability = custom.Durability
+	}
+	if custom.SyncInterval != 0 {
+		opts.SyncInterval = custom.SyncInterval
+	}
+	if custom.WriterQueue != 0 {
+		opts.WriterQueue = custom.WriterQueue
+	}
+	if custom.Outbox {
+		opts.Outbox = true
+	}
+	if custom.EncryptionKey != "" {
+		opts.EncryptionKey = custom.EncryptionKey
+	}
+	if custom.TablePrefix != "" {
+		opts.TablePrefix = custom.TablePrefix
+	}
+	if custom.Collation != "" {
+		opts.Collation = custom.Collation
+	}
+	if custom.InMemory {
+		opts.InMemory = true
+	}
+	if custom.ReadReplicas != nil {
+		opts.ReadReplicas = custom.ReadReplicas
+	}
+	if custom.MaxReplicaLag != 0 {
+		opts.MaxReplicaLag = custom.MaxReplicaLag
+	}
+	if custom.PinAfterWrite != 0 {
+		opts.PinAfterWrite = custom.PinAfterWrite
+	}
+	if custom.AutoCheckpoint != nil {
+		opts.AutoCheckpoint = custom.AutoCheckpoint
+	}
+	if custom.IncrementalVacuum != nil {
+		opts.IncrementalVacuum = custom.IncrementalVacuum
+	}
+	if custom.AutoSnapshot != nil {
+		opts.AutoSnapshot = custom.AutoSnapshot
+	}
+	if custom.AutoBackup != nil {
+		opts.AutoBackup = custom.AutoBackup
+	}
+	if custom.WALArchive != nil {
+		opts.WALArchive = custom.WALArchive
+	}
+	if custom.ManualMigrations {
+		opts.ManualMigrations = true
+	}
 }
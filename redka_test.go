@@ -1,13 +1,93 @@
 package redka_test
 
 import (
+	"context"
+	"database/sql"
 	"errors"
+	"path/filepath"
 	"testing"
 
+	"github.com/mattn/go-sqlite3"
 	"github.com/nalgeon/redka"
 	"github.com/nalgeon/redka/internal/testx"
 )
 
+func TestOpenDriver(t *testing.T) {
+	t.Run("custom driver", func(t *testing.T) {
+		sql.Register("sqlite3-custom", &sqlite3.SQLiteDriver{})
+		db, err := redka.Open(":memory:", &redka.Options{DriverName: "sqlite3-custom"})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		err = db.Str().Set("name", "alice")
+		testx.AssertNoErr(t, err)
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("unknown driver", func(t *testing.T) {
+		_, err := redka.Open(":memory:", &redka.Options{DriverName: "unknown"})
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
+
+func TestOpenInMemory(t *testing.T) {
+	t.Run("private", func(t *testing.T) {
+		db1, err := redka.Open("", &redka.Options{InMemory: true})
+		testx.AssertNoErr(t, err)
+		defer db1.Close()
+		db2, err := redka.Open("", &redka.Options{InMemory: true})
+		testx.AssertNoErr(t, err)
+		defer db2.Close()
+
+		_ = db1.Str().Set("name", "alice")
+		exists, _ := db2.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("shared", func(t *testing.T) {
+		db1, err := redka.Open("shared", &redka.Options{InMemory: true})
+		testx.AssertNoErr(t, err)
+		defer db1.Close()
+		db2, err := redka.Open("shared", &redka.Options{InMemory: true})
+		testx.AssertNoErr(t, err)
+		defer db2.Close()
+
+		_ = db1.Str().Set("name", "alice")
+		name, err := db2.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("pool connections", func(t *testing.T) {
+		db, err := redka.Open("", &redka.Options{InMemory: true})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		var mode string
+		err = db.SQL.QueryRow("pragma journal_mode").Scan(&mode)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, mode, "memory")
+
+		// The pool closes the connection after each query,
+		// but the data should survive.
+		db.SQL.SetMaxIdleConns(0)
+		_ = db.Str().Set("name", "alice")
+		name, err := db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("close", func(t *testing.T) {
+		db, err := redka.Open("closed", &redka.Options{InMemory: true})
+		testx.AssertNoErr(t, err)
+		_ = db.Str().Set("name", "alice")
+		_ = db.Close()
+
+		db, err = redka.Open("closed", &redka.Options{InMemory: true})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+		exists, _ := db.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+	})
+}
+
 func TestDBView(t *testing.T) {
 	db := getDB(t)
 	defer db.Close()
@@ -32,6 +112,80 @@ func TestDBView(t *testing.T) {
 	testx.AssertNoErr(t, err)
 }
 
+func TestDBViewSnapshot(t *testing.T) {
+	t.Run("concurrent writes", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redka.db")
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_ = db.Str().Set("name", "alice")
+		err = db.ViewSnapshot(func(tx *redka.Tx) error {
+			// The write does not wait for the snapshot.
+			err := db.Str().Set("name", "bob")
+			testx.AssertNoErr(t, err)
+
+			// The snapshot does not see the write.
+			name, err := tx.Str().Get("name")
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, name.String(), "alice")
+			return nil
+		})
+		testx.AssertNoErr(t, err)
+
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "bob")
+	})
+	t.Run("read-only", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redka.db")
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		err = db.ViewSnapshot(func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "alice")
+		})
+		testx.AssertErr(t, err, redka.ErrReadOnly)
+	})
+	t.Run("in-memory", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().Set("name", "alice")
+		err := db.ViewSnapshot(func(tx *redka.Tx) error {
+			name, err := tx.Str().Get("name")
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, name.String(), "alice")
+			return nil
+		})
+		testx.AssertNoErr(t, err)
+	})
+}
+
+func TestReadPool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "redka.db")
+	db, err := redka.Open(path, nil)
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	err = db.Update(func(tx *redka.Tx) error {
+		_ = tx.Str().Set("name", "bob")
+
+		// The read does not wait for the write transaction,
+		// and does not see its uncommitted changes.
+		name, err := db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "alice")
+		return nil
+	})
+	testx.AssertNoErr(t, err)
+
+	// The read sees the committed changes.
+	name, _ := db.Str().Get("name")
+	testx.AssertEqual(t, name.String(), "bob")
+}
+
 func TestDBUpdate(t *testing.T) {
 	db := getDB(t)
 	defer db.Close()
@@ -86,6 +240,233 @@ func TestDBUpdateRollback(t *testing.T) {
 	testx.AssertEqual(t, age.MustInt(), 25)
 }
 
+func TestDBUpdateIf(t *testing.T) {
+	t.Run("unchanged", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		_ = db.Str().Set("name", "alice")
+		err := db.UpdateIf([]string{"name", "age"}, func(tx *redka.Tx) error {
+			_ = tx.Str().Set("name", "bob")
+			return tx.Str().Set("age", 25)
+		})
+		testx.AssertNoErr(t, err)
+
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "bob")
+		age, _ := db.Str().Get("age")
+		testx.AssertEqual(t, age.MustInt(), 25)
+	})
+	t.Run("changed", func(t *testing.T) {
+		// The read pool lets UpdateIf read the version
+		// while the other transaction holds the write lock.
+		path := filepath.Join(t.TempDir(), "redka.db")
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		// Signal once UpdateIf has recorded the key version.
+		recorded := make(chan struct{})
+		db.AddHook(afterHook(func(op *redka.Op) {
+			if op.Name == "Key.Get" {
+				close(recorded)
+			}
+		}))
+
+		// Hold the write lock until the version is recorded,
+		// then change the key.
+		locked := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- db.Update(func(tx *redka.Tx) error {
+				close(locked)
+				<-recorded
+				return tx.Str().Set("name", "bob")
+			})
+		}()
+		<-locked
+
+		called := false
+		err = db.UpdateIf([]string{"name"}, func(tx *redka.Tx) error {
+			called = true
+			return tx.Str().Set("name", "cindy")
+		})
+		testx.AssertErr(t, err, redka.ErrVersion)
+		testx.AssertNoErr(t, <-done)
+		testx.AssertEqual(t, called, false)
+
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "bob")
+	})
+	t.Run("created", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redka.db")
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		recorded := make(chan struct{})
+		db.AddHook(afterHook(func(op *redka.Op) {
+			if op.Name == "Key.Get" {
+				close(recorded)
+			}
+		}))
+
+		locked := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- db.Update(func(tx *redka.Tx) error {
+				close(locked)
+				<-recorded
+				return tx.Str().Set("name", "bob")
+			})
+		}()
+		<-locked
+
+		err = db.UpdateIf([]string{"name"}, func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "cindy")
+		})
+		testx.AssertErr(t, err, redka.ErrVersion)
+		testx.AssertNoErr(t, <-done)
+	})
+	t.Run("recreated", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "redka.db")
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		recorded := make(chan struct{})
+		db.AddHook(afterHook(func(op *redka.Op) {
+			if op.Name == "Key.Get" {
+				close(recorded)
+			}
+		}))
+
+		// Delete and create the key again, so it has
+		// the same version but a different ID.
+		locked := make(chan struct{})
+		done := make(chan error)
+		go func() {
+			done <- db.Update(func(tx *redka.Tx) error {
+				close(locked)
+				<-recorded
+				if _, err := tx.Key().Delete("name"); err != nil {
+					return err
+				}
+				return tx.Str().Set("name", "alice")
+			})
+		}()
+		<-locked
+
+		err = db.UpdateIf([]string{"name"}, func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "cindy")
+		})
+		testx.AssertErr(t, err, redka.ErrVersion)
+		testx.AssertNoErr(t, <-done)
+	})
+}
+
+// afterHook calls the function after each operation.
+type afterHook func(op *redka.Op)
+
+func (h afterHook) Before(ctx context.Context, op *redka.Op) {}
+func (h afterHook) After(ctx context.Context, op *redka.Op)  { h(op) }
+
+func TestTxUpdate(t *testing.T) {
+	t.Run("nested error", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		errNested := errors.New("nested")
+		err := db.Update(func(tx *redka.Tx) error {
+			_ = tx.Str().Set("name", "alice")
+			err := tx.Update(func(tx *redka.Tx) error {
+				_ = tx.Str().Set("age", 25)
+				return errNested
+			})
+			testx.AssertErr(t, err, errNested)
+			return tx.Str().Set("city", "paris")
+		})
+		testx.AssertNoErr(t, err)
+
+		count, _ := db.Key().Count("name", "age", "city")
+		testx.AssertEqual(t, count, 2)
+		exists, _ := db.Key().Exists("age")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("nested commit", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		err := db.Update(func(tx *redka.Tx) error {
+			return tx.Update(func(tx *redka.Tx) error {
+				_ = tx.Str().Set("name", "alice")
+				return tx.Update(func(tx *redka.Tx) error {
+					return tx.Str().Set("age", 25)
+				})
+			})
+		})
+		testx.AssertNoErr(t, err)
+
+		count, _ := db.Key().Count("name", "age")
+		testx.AssertEqual(t, count, 2)
+	})
+	t.Run("outer rollback", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+
+		errOuter := errors.New("outer")
+		err := db.Update(func(tx *redka.Tx) error {
+			err := tx.Update(func(tx *redka.Tx) error {
+				return tx.Str().Set("name", "alice")
+			})
+			testx.AssertNoErr(t, err)
+			return errOuter
+		})
+		testx.AssertErr(t, err, errOuter)
+
+		exists, _ := db.Key().Exists("name")
+		testx.AssertEqual(t, exists, false)
+	})
+}
+
+func TestDBWithContext(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+	_ = db.Str().Set("name", "alice")
+
+	t.Run("active", func(t *testing.T) {
+		cdb := db.WithContext(context.Background())
+		name, err := cdb.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "alice")
+		err = cdb.Str().Set("age", 25)
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		cdb := db.WithContext(ctx)
+
+		_, err := cdb.Key().Keys("*")
+		testx.AssertEqual(t, errors.Is(err, context.Canceled), true)
+		_, err = cdb.Hash().Items("person")
+		testx.AssertEqual(t, errors.Is(err, context.Canceled), true)
+		err = cdb.Str().Set("name", "bob")
+		testx.AssertEqual(t, errors.Is(err, context.Canceled), true)
+		err = cdb.Update(func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "bob")
+		})
+		testx.AssertEqual(t, errors.Is(err, context.Canceled), true)
+
+		// The original database is not affected.
+		name, err := db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+}
+
 func getDB(tb testing.TB) *redka.DB {
 	tb.Helper()
 	db, err := redka.Open(":memory:", nil)
@@ -94,3 +475,63 @@ func getDB(tb testing.TB) *redka.DB {
 	}
 	return db
 }
+
+func TestOpenDB(t *testing.T) {
+	sdb, err := sql.Open("sqlite3", "file:/opendb.db?vfs=memdb&_foreign_keys=on")
+	testx.AssertNoErr(t, err)
+	defer sdb.Close()
+	sdb.SetMaxOpenConns(1)
+	_, err = sdb.Exec("create table users (id integer primary key, name text)")
+	testx.AssertNoErr(t, err)
+
+	db, err := redka.OpenDB(sdb, &redka.Options{TablePrefix: "redka_"})
+	testx.AssertNoErr(t, err)
+
+	var events int
+	db.OnChange(func(redka.ChangeEvent) { events++ })
+
+	t.Run("prefix", func(t *testing.T) {
+		_ = db.Str().Set("name", "alice")
+		_, _ = db.Hash().Set("person", "age", 25)
+		_, _ = db.SortedSet().Add("scores", "alice", 11)
+		keys, err := db.Key().Keys("*")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(keys), 3)
+		testx.AssertEqual(t, events, 3)
+
+		var count int
+		err = sdb.QueryRow("select count(*) from redka_rkey").Scan(&count)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 3)
+		err = sdb.QueryRow("select count(*) from sqlite_master where name = 'rkey'").Scan(&count)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 0)
+	})
+	t.Run("use tx", func(t *testing.T) {
+		tx, err := sdb.Begin()
+		testx.AssertNoErr(t, err)
+		_, err = tx.Exec("insert into users (name) values ('bob')")
+		testx.AssertNoErr(t, err)
+		err = db.UseTx(tx).Str().Set("user:bob", "active")
+		testx.AssertNoErr(t, err)
+		err = tx.Rollback()
+		testx.AssertNoErr(t, err)
+
+		exists, _ := db.Key().Exists("user:bob")
+		testx.AssertEqual(t, exists, false)
+	})
+	t.Run("close", func(t *testing.T) {
+		err := db.Close()
+		testx.AssertNoErr(t, err)
+		// The application's pool stays open.
+		err = sdb.Ping()
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("foreign keys", func(t *testing.T) {
+		sdb, err := sql.Open("sqlite3", ":memory:")
+		testx.AssertNoErr(t, err)
+		defer sdb.Close()
+		_, err = redka.OpenDB(sdb, nil)
+		testx.AssertErr(t, err, redka.ErrForeignKeys)
+	})
+}
@@ -0,0 +1,367 @@
+// Package redkatest provides helpers for testing the code
+// that uses Redka: in-memory databases, declarative fixtures,
+// and snapshots of the database state.
+//
+// A fixture is a JSON object that maps the keys to their values.
+// Strings, numbers and booleans are string values, while objects
+// with a "type" describe other types and the expiration:
+//
+//	{
+//	    "name": "alice",
+//	    "age": 25,
+//	    "session": {"type": "string", "value": "abc", "ttl": "10m"},
+//	    "person": {"type": "hash", "value": {"name": "alice", "age": 25}},
+//	    "scores": {"type": "zset", "value": {"alice": 11, "bob": 22}}
+//	}
+//
+// Use it to seed a database before a test with [Load],
+// and to check the database state after the test with [AssertState]:
+//
+//	func TestSignup(t *testing.T) {
+//	    db := redkatest.Open(t)
+//	    redkatest.Load(t, db, `{"users:count": 1}`)
+//	    signup(db, "alice")
+//	    redkatest.AssertState(t, db, `{
+//	        "users:count": 2,
+//	        "user:alice": {"type": "hash", "value": {"name": "alice"}}
+//	    }`)
+//	}
+package redkatest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+)
+
+// Open returns an empty in-memory database
+// that is closed when the test completes.
+func Open(tb testing.TB) *redka.DB {
+	tb.Helper()
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		tb.Fatalf("redkatest: open: %v", err)
+	}
+	tb.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// Entry is the state of a key.
+type Entry struct {
+	// Type is the key type ("string", "hash" or "zset").
+	Type string
+	// Value is a string for strings, a map[string]string for hashes,
+	// and a map[string]float64 for sorted sets.
+	Value any
+	// TTL is the expiration time relative to the moment the fixture
+	// is loaded. Zero means the key does not expire. Snapshots only
+	// tell whether the key expires, so they have TTL > 0 for such keys,
+	// and the state comparison ignores the actual TTL values.
+	TTL time.Duration
+}
+
+// State is the state of the database: entries by keys.
+type State map[string]Entry
+
+// fixtureEntry is an entry in the JSON fixture.
+type fixtureEntry struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+	TTL   string          `json:"ttl"`
+}
+
+// Parse parses the JSON fixture into a state.
+func Parse(fixture []byte) (State, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(fixture, &raw); err != nil {
+		return nil, fmt.Errorf("parse fixture: %w", err)
+	}
+	state := make(State, len(raw))
+	for key, data := range raw {
+		entry, err := parseEntry(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse fixture: key %q: %w", key, err)
+		}
+		state[key] = entry
+	}
+	return state, nil
+}
+
+// parseEntry parses a fixture entry.
+func parseEntry(data []byte) (Entry, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '{' {
+		s, err := scalarString(data)
+		return Entry{Type: "string", Value: s}, err
+	}
+
+	var fe fixtureEntry
+	if err := json.Unmarshal(data, &fe); err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{Type: fe.Type}
+	if fe.TTL != "" {
+		ttl, err := time.ParseDuration(fe.TTL)
+		if err != nil {
+			return Entry{}, fmt.Errorf("invalid ttl: %w", err)
+		}
+		entry.TTL = ttl
+	}
+
+	switch fe.Type {
+	case "string":
+		s, err := scalarString(fe.Value)
+		if err != nil {
+			return Entry{}, err
+		}
+		entry.Value = s
+	case "hash":
+		var items map[string]json.RawMessage
+		if err := json.Unmarshal(fe.Value, &items); err != nil {
+			return Entry{}, err
+		}
+		hash := make(map[string]string, len(items))
+		for field, val := range items {
+			s, err := scalarString(val)
+			if err != nil {
+				return Entry{}, fmt.Errorf("field %q: %w", field, err)
+			}
+			hash[field] = s
+		}
+		entry.Value = hash
+	case "zset":
+		var zset map[string]float64
+		if err := json.Unmarshal(fe.Value, &zset); err != nil {
+			return Entry{}, err
+		}
+		entry.Value = zset
+	default:
+		return Entry{}, fmt.Errorf("unsupported type %q", fe.Type)
+	}
+	return entry, nil
+}
+
+// scalarString converts a JSON string, number or boolean to a string.
+func scalarString(data []byte) (string, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		if v {
+			return "1", nil
+		}
+		return "0", nil
+	default:
+		return "", fmt.Errorf("expected a string, number or boolean, got %s", data)
+	}
+}
+
+// Load seeds the database with the JSON fixture (see the package
+// documentation for the format). Existing keys are overwritten.
+// Fails the test if the fixture is invalid.
+func Load(tb testing.TB, db *redka.DB, fixture string) {
+	tb.Helper()
+	state, err := Parse([]byte(fixture))
+	if err != nil {
+		tb.Fatalf("redkatest: %v", err)
+	}
+	if err := Apply(db, state); err != nil {
+		tb.Fatalf("redkatest: %v", err)
+	}
+}
+
+// LoadFile is like Load, but reads the fixture from a file.
+func LoadFile(tb testing.TB, db *redka.DB, path string) {
+	tb.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("redkatest: %v", err)
+	}
+	Load(tb, db, string(data))
+}
+
+// Apply writes the state to the database in a single transaction.
+// Existing keys are overwritten.
+func Apply(db *redka.DB, state State) error {
+	return db.Update(func(tx *redka.Tx) error {
+		for _, key := range slices.Sorted(maps.Keys(state)) {
+			if err := applyEntry(tx, key, state[key]); err != nil {
+				return fmt.Errorf("key %q: %w", key, err)
+			}
+		}
+		return nil
+	})
+}
+
+// applyEntry writes the entry to the database.
+func applyEntry(tx *redka.Tx, key string, entry Entry) error {
+	if _, err := tx.Key().Delete(key); err != nil {
+		return err
+	}
+	switch value := entry.Value.(type) {
+	case string:
+		if err := tx.Str().Set(key, value); err != nil {
+			return err
+		}
+	case map[string]string:
+		items := make(map[string]any, len(value))
+		for field, val := range value {
+			items[field] = val
+		}
+		if _, err := tx.Hash().SetMany(key, items); err != nil {
+			return err
+		}
+	case map[string]float64:
+		items := make(map[any]float64, len(value))
+		for elem, score := range value {
+			items[elem] = score
+		}
+		if _, err := tx.SortedSet().AddMany(key, items); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported value %T", entry.Value)
+	}
+	if entry.TTL > 0 {
+		if _, err := tx.Key().Expire(key, entry.TTL); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Snapshot returns the current state of the database.
+// Fails the test if the database can't be read.
+func Snapshot(tb testing.TB, db *redka.DB) State {
+	tb.Helper()
+	state := State{}
+	err := db.View(func(tx *redka.Tx) error {
+		keys, err := tx.Key().Keys("*")
+		if err != nil {
+			return err
+		}
+		for _, k := range keys {
+			entry := Entry{Type: k.TypeName()}
+			if k.ETime != nil {
+				entry.TTL = time.Until(time.UnixMilli(*k.ETime))
+			}
+			switch entry.Type {
+			case "string":
+				val, err := tx.Str().Get(k.Key)
+				if err != nil {
+					return err
+				}
+				entry.Value = val.String()
+			case "hash":
+				items, err := tx.Hash().Items(k.Key)
+				if err != nil {
+					return err
+				}
+				hash := make(map[string]string, len(items))
+				for field, val := range items {
+					hash[field] = val.String()
+				}
+				entry.Value = hash
+			case "zset":
+				items, err := tx.SortedSet().RangeWith(k.Key).
+					ByScore(math.Inf(-1), math.Inf(1)).Run()
+				if err != nil {
+					return err
+				}
+				zset := make(map[string]float64, len(items))
+				for _, item := range items {
+					zset[item.Elem.String()] = item.Score
+				}
+				entry.Value = zset
+			}
+			state[k.Key] = entry
+		}
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("redkatest: snapshot: %v", err)
+	}
+	return state
+}
+
+// Diff returns the differences between the want and got states,
+// one line per key, sorted by key. Returns nil if they are equal.
+// Only compares whether the keys expire, not the actual TTLs.
+func Diff(want, got State) []string {
+	keys := slices.Concat(slices.Collect(maps.Keys(want)), slices.Collect(maps.Keys(got)))
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	var diff []string
+	for _, key := range keys {
+		w, inWant := want[key]
+		g, inGot := got[key]
+		switch {
+		case !inGot:
+			diff = append(diff, fmt.Sprintf("- %s: missing (want %s)", key, formatEntry(w)))
+		case !inWant:
+			diff = append(diff, fmt.Sprintf("+ %s: unexpected %s", key, formatEntry(g)))
+		case formatEntry(w) != formatEntry(g):
+			diff = append(diff, fmt.Sprintf("~ %s: want %s, got %s", key, formatEntry(w), formatEntry(g)))
+		}
+	}
+	return diff
+}
+
+// formatEntry returns a canonical representation of the entry.
+func formatEntry(e Entry) string {
+	var b strings.Builder
+	b.WriteString(e.Type)
+	b.WriteByte(' ')
+	switch v := e.Value.(type) {
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case map[string]float64:
+		b.WriteByte('{')
+		for i, elem := range slices.Sorted(maps.Keys(v)) {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "%q: %v", elem, v[elem])
+		}
+		b.WriteByte('}')
+	default:
+		// Maps are printed with sorted keys.
+		fmt.Fprintf(&b, "%q", v)
+	}
+	if e.TTL > 0 {
+		b.WriteString(" (expires)")
+	}
+	return b.String()
+}
+
+// AssertState fails the test if the database state differs
+// from the JSON fixture, listing the differences.
+func AssertState(tb testing.TB, db *redka.DB, fixture string) {
+	tb.Helper()
+	want, err := Parse([]byte(fixture))
+	if err != nil {
+		tb.Fatalf("redkatest: %v", err)
+	}
+	if diff := Diff(want, Snapshot(tb, db)); len(diff) > 0 {
+		tb.Errorf("redkatest: database state mismatch:\n%s", strings.Join(diff, "\n"))
+	}
+}
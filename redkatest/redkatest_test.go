@@ -0,0 +1,111 @@
+package redkatest_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nalgeon/redka/internal/testx"
+	"github.com/nalgeon/redka/redkatest"
+)
+
+const fixture = `{
+	"name": "alice",
+	"age": 25,
+	"session": {"type": "string", "value": "abc", "ttl": "10m"},
+	"person": {"type": "hash", "value": {"name": "alice", "age": 25, "admin": true}},
+	"scores": {"type": "zset", "value": {"alice": 11, "bob": 22.5}}
+}`
+
+func TestLoad(t *testing.T) {
+	db := redkatest.Open(t)
+	redkatest.Load(t, db, fixture)
+
+	name, _ := db.Str().Get("name")
+	testx.AssertEqual(t, name.String(), "alice")
+	age, _ := db.Str().Get("age")
+	testx.AssertEqual(t, age.String(), "25")
+
+	key, _ := db.Key().Get("session")
+	testx.AssertEqual(t, key.ETime != nil, true)
+
+	person, _ := db.Hash().Items("person")
+	testx.AssertEqual(t, person["age"].String(), "25")
+	testx.AssertEqual(t, person["admin"].String(), "1")
+
+	score, _ := db.SortedSet().GetScore("scores", "bob")
+	testx.AssertEqual(t, score, 22.5)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	err := os.WriteFile(path, []byte(fixture), 0o644)
+	testx.AssertNoErr(t, err)
+
+	db := redkatest.Open(t)
+	redkatest.LoadFile(t, db, path)
+	redkatest.AssertState(t, db, fixture)
+}
+
+func TestParse(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		state, err := redkatest.Parse([]byte(fixture))
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(state), 5)
+		testx.AssertEqual(t, state["age"], redkatest.Entry{Type: "string", Value: "25"})
+		testx.AssertEqual(t, state["scores"].Value, map[string]float64{"alice": 11, "bob": 22.5})
+	})
+	t.Run("invalid", func(t *testing.T) {
+		tests := []string{
+			`[1, 2]`,
+			`{"name": [1]}`,
+			`{"name": {"type": "list", "value": [1]}}`,
+			`{"name": {"type": "string", "value": "alice", "ttl": "soon"}}`,
+			`{"person": {"type": "hash", "value": {"tags": ["a"]}}}`,
+		}
+		for _, test := range tests {
+			_, err := redkatest.Parse([]byte(test))
+			testx.AssertEqual(t, err != nil, true)
+		}
+	})
+}
+
+func TestDiff(t *testing.T) {
+	db := redkatest.Open(t)
+	redkatest.Load(t, db, fixture)
+
+	_ = db.Str().Set("name", "bob")
+	_, _ = db.Key().Delete("age")
+	_ = db.Str().Set("city", "paris")
+	_, _ = db.Key().Persist("session")
+
+	want, _ := redkatest.Parse([]byte(fixture))
+	diff := redkatest.Diff(want, redkatest.Snapshot(t, db))
+	testx.AssertEqual(t, diff, []string{
+		`- age: missing (want string "25")`,
+		`+ city: unexpected string "paris"`,
+		`~ name: want string "alice", got string "bob"`,
+		`~ session: want string "abc" (expires), got string "abc"`,
+	})
+
+	rec := &recorder{TB: t}
+	redkatest.AssertState(rec, db, fixture)
+	testx.AssertEqual(t, rec.failed, true)
+	testx.AssertEqual(t, strings.Contains(rec.msg, "~ name:"), true)
+}
+
+// recorder records the test failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failed = true
+	r.msg = format
+	if len(args) > 0 {
+		r.msg = args[0].(string)
+	}
+}
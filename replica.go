@@ -0,0 +1,135 @@
+package redka
+
+import (
+	"database/sql"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nalgeon/redka/internal/rhash"
+	"github.com/nalgeon/redka/internal/rhll"
+	"github.com/nalgeon/redka/internal/rkey"
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/rstring"
+	"github.com/nalgeon/redka/internal/rzset"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// openReplicas opens the read replicas set in the options.
+// Returns nil if there are none.
+func openReplicas(opts *Options, key *cipherKey, names *sqlx.Names) (*sqlx.Replicas, error) {
+	dbs := make([]*sql.DB, 0, len(opts.ReadReplicas))
+	for _, path := range opts.ReadReplicas {
+		db, err := openSQL(opts.DriverName, path, key, "")
+		if err == nil {
+			err = db.Ping()
+		}
+		if err != nil {
+			for _, db := range dbs {
+				_ = db.Close()
+			}
+			return nil, err
+		}
+		dbs = append(dbs, db)
+	}
+	return sqlx.NewReplicas(dbs, opts.MaxReplicaLag, opts.PinAfterWrite, names), nil
+}
+
+// setReplicas routes the read-only
+// repository methods to the replicas.
+func (db *DB) setReplicas(replicas *sqlx.Replicas) {
+	db.replicas = replicas
+	db.keyDB.Replicas = replicas
+	db.stringDB.Replicas = replicas
+	db.hashDB.Replicas = replicas
+	db.zsetDB.Replicas = replicas
+	db.streamDB.Replicas = replicas
+	db.hllDB.Replicas = replicas
+}
+
+// setReader sets the read pool of the database and the repositories.
+func (db *DB) setReader(reader *sql.DB) {
+	db.DB.Reader = reader
+	db.keyDB.DB.Reader = reader
+	db.stringDB.DB.Reader = reader
+	db.hashDB.DB.Reader = reader
+	db.zsetDB.DB.Reader = reader
+	db.streamDB.DB.Reader = reader
+	db.hllDB.DB.Reader = reader
+}
+
+// setWriter sets the writer of the database and the repositories.
+func (db *DB) setWriter(w *sqlx.Writer) {
+	db.DB.Writer = w
+	db.keyDB.Writer = w
+	db.stringDB.Writer = w
+	db.hashDB.Writer = w
+	db.zsetDB.Writer = w
+	db.streamDB.Writer = w
+	db.hllDB.Writer = w
+}
+
+// Primary returns a copy of the database that reads from the
+// primary database instead of the read replicas (see
+// [Options.ReadReplicas]), so it always sees the latest writes.
+// Use it to pin a caller to the primary after its own write:
+//
+//	err := db.Str().Set("name", "alice")
+//	// ...
+//	name, err := db.Primary().Str().Get("name") // always "alice"
+//
+// See [Options.PinAfterWrite] to pin all the callers instead.
+// The copy shares the connections and background workers with
+// the original database, so only close the original one.
+func (db *DB) Primary() *DB {
+	c := *db
+	c.keyDB = &rkey.DB{DB: db.keyDB.DB.Primary()}
+	c.stringDB = &rstring.DB{DB: db.stringDB.DB.Primary()}
+	c.hashDB = &rhash.DB{DB: db.hashDB.DB.Primary()}
+	c.zsetDB = &rzset.DB{DB: db.zsetDB.DB.Primary()}
+	c.streamDB = &rstream.DB{DB: db.streamDB.DB.Primary()}
+	c.hllDB = &rhll.DB{DB: db.hllDB.DB.Primary()}
+	return &c
+}
+
+// startReplicaCheck starts the goroutine that runs in the
+// background and measures the lag of the read replicas.
+// Returns nil if the lag is not checked.
+func (db *DB) startReplicaCheck() *time.Ticker {
+	interval := db.replicas.CheckInterval()
+	if interval == 0 {
+		return nil
+	}
+	db.checkReplicas()
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			db.checkReplicas()
+		}
+	}()
+	return ticker
+}
+
+// checkReplicas measures the lag of the read replicas.
+func (db *DB) checkReplicas() {
+	if err := db.replicas.Check(db.Conn()); err != nil {
+		db.log.Error("check replicas", "error", err)
+	}
+}
+
+// openReader opens the read pool of the database (see DB.ViewSnapshot).
+// The connections are read-only, so the pool can not be used for writes.
+func openReader(opts *Options, path string, key *cipherKey, pragmas []string) (*sql.DB, error) {
+	// Changing the journal mode is a write.
+	pragmas = slices.DeleteFunc(slices.Clone(pragmas), func(p string) bool {
+		return strings.HasPrefix(p, "journal_mode ")
+	})
+	settings := sqlx.Settings(sqlx.Options{ReadOnly: true, Pragmas: pragmas})
+	db, err := openSQL(opts.DriverName, path, key, settings)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(opts.ReadPoolSize)
+	db.SetMaxIdleConns(opts.ReadPoolSize)
+	return db, nil
+}
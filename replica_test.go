@@ -0,0 +1,144 @@
+package redka_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestReadReplicas(t *testing.T) {
+	dir := t.TempDir()
+	// The replica is a separate database that does not
+	// receive any changes from the primary.
+	replicaPath := filepath.Join(dir, "replica.db")
+	replica, err := redka.Open(replicaPath, nil)
+	testx.AssertNoErr(t, err)
+	_ = replica.Str().Set("name", "replica")
+	_ = replica.Close()
+
+	t.Run("routing", func(t *testing.T) {
+		db, err := redka.Open(filepath.Join(dir, "routing.db"), &redka.Options{
+			ReadReplicas: []string{"file:" + replicaPath + "?mode=ro"},
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		err = db.Str().Set("name", "primary")
+		testx.AssertNoErr(t, err)
+
+		// Reads go to the replica.
+		name, err := db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "replica")
+
+		// Transactions go to the primary.
+		err = db.View(func(tx *redka.Tx) error {
+			name, err = tx.Str().Get("name")
+			return err
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "primary")
+	})
+	t.Run("update if", func(t *testing.T) {
+		db, err := redka.Open(filepath.Join(dir, "updateif.db"), &redka.Options{
+			ReadReplicas: []string{"file:" + replicaPath + "?mode=ro"},
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		// The key has a different version on the primary.
+		_ = db.Str().Set("name", "primary")
+		err = db.Str().Set("name", "primary")
+		testx.AssertNoErr(t, err)
+
+		// The versions come from the primary,
+		// so the replica does not fail the check.
+		err = db.UpdateIf([]string{"name"}, func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "updated")
+		})
+		testx.AssertNoErr(t, err)
+		err = db.View(func(tx *redka.Tx) error {
+			name, err := tx.Str().Get("name")
+			testx.AssertEqual(t, name.String(), "updated")
+			return err
+		})
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("stale replica", func(t *testing.T) {
+		db, err := redka.Open(filepath.Join(dir, "stale.db"), &redka.Options{
+			ReadReplicas:  []string{"file:" + replicaPath + "?mode=ro"},
+			MaxReplicaLag: 100 * time.Millisecond,
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		err = db.Str().Set("name", "primary")
+		testx.AssertNoErr(t, err)
+
+		// The replica never receives heartbeats,
+		// so reads go to the primary.
+		name, err := db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "primary")
+	})
+	t.Run("fresh replica", func(t *testing.T) {
+		path := filepath.Join(dir, "fresh.db")
+		db, err := redka.Open(path, &redka.Options{
+			// The primary itself acts as a replica that is always in sync.
+			ReadReplicas:  []string{"file:" + path + "?mode=ro"},
+			MaxReplicaLag: 100 * time.Millisecond,
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		// Wait for the heartbeat to reach the replica.
+		time.Sleep(200 * time.Millisecond)
+		err = db.Str().Set("name", "primary")
+		testx.AssertNoErr(t, err)
+		name, err := db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "primary")
+	})
+	t.Run("pin after write", func(t *testing.T) {
+		db, err := redka.Open(filepath.Join(dir, "pin.db"), &redka.Options{
+			ReadReplicas:  []string{"file:" + replicaPath + "?mode=ro"},
+			PinAfterWrite: 100 * time.Millisecond,
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		err = db.Str().Set("name", "primary")
+		testx.AssertNoErr(t, err)
+
+		// Reads go to the primary right after the write.
+		name, err := db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "primary")
+
+		// And to the replica after the pin window.
+		time.Sleep(150 * time.Millisecond)
+		name, err = db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "replica")
+	})
+	t.Run("primary", func(t *testing.T) {
+		db, err := redka.Open(filepath.Join(dir, "primary.db"), &redka.Options{
+			ReadReplicas: []string{"file:" + replicaPath + "?mode=ro"},
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		err = db.Str().Set("name", "primary")
+		testx.AssertNoErr(t, err)
+
+		name, err := db.Primary().Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "primary")
+		name, err = db.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "replica")
+	})
+}
@@ -0,0 +1,95 @@
+package redka
+
+import (
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rhash"
+	"github.com/nalgeon/redka/internal/rkey"
+	"github.com/nalgeon/redka/internal/rstring"
+	"github.com/nalgeon/redka/internal/rzset"
+)
+
+// Keys is the common part of the key repositories
+// returned by [DB.Key] and [Tx.Key].
+//
+// The repository interfaces ([Keys], [Strings], [Hashes] and
+// [SortedSets]) let the application code depend on the behavior
+// rather than on the database. Pass the repositories of a real
+// database in production, and the in-memory fakes from the
+// fake package in unit tests.
+type Keys interface {
+	Exists(key string) (bool, error)
+	Count(keys ...string) (int, error)
+	Keys(pattern string) ([]core.Key, error)
+	Random() (core.Key, error)
+	Get(key string) (core.Key, error)
+	Expire(key string, ttl time.Duration) (bool, error)
+	ExpireAt(key string, at time.Time) (bool, error)
+	Persist(key string) (bool, error)
+	Rename(key, newKey string) error
+	RenameNotExists(key, newKey string) (bool, error)
+	Copy(key, newKey string, replace bool) (bool, error)
+	Delete(keys ...string) (int, error)
+	DeleteAll() error
+}
+
+// Strings is the common part of the string repositories
+// returned by [DB.Str] and [Tx.Str].
+type Strings interface {
+	Get(key string) (core.Value, error)
+	GetMany(keys ...string) (map[string]core.Value, error)
+	Set(key string, value any) error
+	SetExpires(key string, value any, ttl time.Duration) error
+	SetNotExists(key string, value any, ttl time.Duration) (bool, error)
+	SetExists(key string, value any, ttl time.Duration) (bool, error)
+	GetSet(key string, value any, ttl time.Duration) (core.Value, error)
+	SetMany(items map[string]any) error
+	SetManyNX(items map[string]any) (bool, error)
+	Incr(key string, delta int) (int, error)
+	IncrFloat(key string, delta float64) (float64, error)
+}
+
+// Hashes is the common part of the hash repositories
+// returned by [DB.Hash] and [Tx.Hash].
+type Hashes interface {
+	Delete(key string, fields ...string) (int, error)
+	Exists(key, field string) (bool, error)
+	Fields(key string) ([]string, error)
+	Get(key, field string) (core.Value, error)
+	GetMany(key string, fields ...string) (map[string]core.Value, error)
+	Incr(key, field string, delta int) (int, error)
+	IncrFloat(key, field string, delta float64) (float64, error)
+	Items(key string) (map[string]core.Value, error)
+	Len(key string) (int, error)
+	Set(key, field string, value any) (bool, error)
+	SetMany(key string, items map[string]any) (int, error)
+	SetNotExists(key, field string, value any) (bool, error)
+	Values(key string) ([]core.Value, error)
+}
+
+// SortedSets is the common part of the sorted set repositories
+// returned by [DB.SortedSet] and [Tx.SortedSet].
+type SortedSets interface {
+	Add(key string, elem any, score float64) (bool, error)
+	AddMany(key string, items map[any]float64) (int, error)
+	Count(key string, min, max float64) (int, error)
+	Delete(key string, elems ...any) (int, error)
+	GetRank(key string, elem any) (rank int, score float64, err error)
+	GetRankRev(key string, elem any) (rank int, score float64, err error)
+	GetScore(key string, elem any) (float64, error)
+	Incr(key string, elem any, delta float64) (float64, error)
+	Len(key string) (int, error)
+	Range(key string, start, stop int) ([]rzset.SetItem, error)
+}
+
+var (
+	_ Keys       = (*rkey.DB)(nil)
+	_ Keys       = (*rkey.Tx)(nil)
+	_ Strings    = (*rstring.DB)(nil)
+	_ Strings    = (*rstring.Tx)(nil)
+	_ Hashes     = (*rhash.DB)(nil)
+	_ Hashes     = (*rhash.Tx)(nil)
+	_ SortedSets = (*rzset.DB)(nil)
+	_ SortedSets = (*rzset.Tx)(nil)
+)
@@ -0,0 +1,63 @@
+package redka_test
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestBusyRetry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	openDB := func(t *testing.T, retry *redka.RetryPolicy) *redka.DB {
+		t.Helper()
+		db, err := redka.Open(path, &redka.Options{BusyRetry: retry})
+		testx.AssertNoErr(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		// Fail right away instead of waiting for the lock,
+		// so that the retry policy kicks in.
+		_, err = db.SQL.Exec("pragma busy_timeout = 0")
+		testx.AssertNoErr(t, err)
+		return db
+	}
+	// lock holds the write lock until the returned function is called.
+	lock := func(t *testing.T) func() {
+		t.Helper()
+		sdb, err := sql.Open("sqlite3", path)
+		testx.AssertNoErr(t, err)
+		tx, err := sdb.Begin()
+		testx.AssertNoErr(t, err)
+		_, err = tx.Exec("insert into rheartbeat (id, time) values (1, 0)")
+		testx.AssertNoErr(t, err)
+		return func() {
+			_ = tx.Rollback()
+			_ = sdb.Close()
+		}
+	}
+
+	t.Run("exhausted", func(t *testing.T) {
+		db := openDB(t, &redka.RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond})
+		unlock := lock(t)
+		defer unlock()
+
+		err := db.Str().Set("name", "alice")
+		testx.AssertEqual(t, errors.Is(err, redka.ErrBusy), true)
+	})
+	t.Run("retried", func(t *testing.T) {
+		db := openDB(t, &redka.RetryPolicy{Attempts: 20, BaseDelay: 10 * time.Millisecond})
+		unlock := lock(t)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			unlock()
+		}()
+
+		err := db.Str().Set("name", "alice")
+		testx.AssertNoErr(t, err)
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+}
@@ -0,0 +1,260 @@
+package redka
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// Scheduled operation types (see [ScheduledOp]).
+const (
+	ScheduleOpSet    = "set"    // set the string value
+	ScheduleOpDelete = "delete" // delete the key
+	ScheduleOpMove   = "move"   // move the sorted set element to another key
+)
+
+const sqlScheduleAdd = `
+insert into rschedule (time, op, key, value, target)
+values (?, ?, ?, ?, ?)`
+
+const sqlScheduleList = `
+select id, time, op, key, value, target from rschedule
+order by time, id`
+
+const sqlScheduleDue = `
+select id, time, op, key, value, target from rschedule
+where time <= ?
+order by time, id
+limit ?`
+
+const sqlScheduleDelete = `
+delete from rschedule where id = ?`
+
+// ScheduledOp is an operation on a key scheduled
+// to run at a future time (see [DB.ScheduleSet]).
+type ScheduledOp struct {
+	ID     int64     // operation ID
+	Time   time.Time // when the operation runs
+	Op     string    // set, delete or move
+	Key    string    // the key to change (the source key for move)
+	Value  Value     // the value for set, the element for move
+	Target string    // the destination key for move
+}
+
+// ScheduleSet schedules setting the string value of the key
+// at the given time. Overwrites the existing value (and removes
+// the TTL), like [rstring.DB.Set]. Returns the operation ID.
+//
+// The scheduled operations are persisted in the database and run
+// in the background (see [Options.ScheduleInterval]), so they survive
+// restarts. The operations that became due while the database was
+// closed run as soon as it is opened again.
+//
+// The operations change the database directly rather than through
+// the server, so they are not written to the server journal or sent
+// to the replicas, and the pending operations are not part of the
+// replication snapshot. Do not schedule operations on a database
+// served with the journal or replication enabled.
+func (db *DB) ScheduleSet(key string, value any, at time.Time) (int64, error) {
+	if !core.IsValueType(value) {
+		return 0, ErrValueType
+	}
+	return db.schedule(at, ScheduleOpSet, key, value, nil)
+}
+
+// ScheduleDelete schedules deleting the key at the given time.
+// Unlike the TTL, the deletion does not depend on the key's value,
+// so it still runs if the key is set again before the time.
+// Returns the operation ID.
+func (db *DB) ScheduleDelete(key string, at time.Time) (int64, error) {
+	return db.schedule(at, ScheduleOpDelete, key, nil, nil)
+}
+
+// ScheduleMove schedules moving the element from the src sorted set
+// to the dest sorted set at the given time, keeping its score (like
+// moving an item from the "pending" set to the "active" one). Does
+// nothing if src does not contain the element at the time.
+// Returns the operation ID.
+func (db *DB) ScheduleMove(src, dest string, elem any, at time.Time) (int64, error) {
+	if !core.IsValueType(elem) {
+		return 0, ErrValueType
+	}
+	return db.schedule(at, ScheduleOpMove, src, elem, dest)
+}
+
+// Scheduled returns the pending scheduled operations,
+// the earliest first.
+func (db *DB) Scheduled() ([]ScheduledOp, error) {
+	var ops []ScheduledOp
+	err := db.View(func(tx *Tx) error {
+		var err error
+		ops, err = sqlx.Select(tx.tx, sqlScheduleList, nil, scanScheduledOp)
+		return err
+	})
+	return ops, err
+}
+
+// CancelScheduled cancels the pending scheduled operation.
+// Returns false if there is no such operation (it has already
+// run or has been canceled).
+func (db *DB) CancelScheduled(id int64) (bool, error) {
+	var ok bool
+	err := db.Update(func(tx *Tx) error {
+		res, err := tx.tx.Exec(sqlScheduleDelete, id)
+		if err != nil {
+			return err
+		}
+		n, _ := res.RowsAffected()
+		ok = n > 0
+		return nil
+	})
+	return ok, err
+}
+
+// RunScheduled runs the scheduled operations that are due,
+// each in a separate transaction. An operation that fails (for
+// example, because the key has a different type) is logged and
+// discarded, so it does not block the others. Returns the number
+// of operations that have run successfully.
+//
+// The operations run in the background automatically, so there is
+// usually no need to call RunScheduled directly.
+func (db *DB) RunScheduled() (int, error) {
+	const batchSize = 100
+	var count int
+	for {
+		var ops []ScheduledOp
+		err := db.View(func(tx *Tx) error {
+			var err error
+			now := sqlx.Now(tx.tx).UnixMilli()
+			ops, err = sqlx.Select(tx.tx, sqlScheduleDue, []any{now, batchSize}, scanScheduledOp)
+			return err
+		})
+		if err != nil {
+			return count, err
+		}
+		for _, op := range ops {
+			ok, err := db.runScheduled(op)
+			if err != nil {
+				return count, err
+			}
+			if ok {
+				count++
+			}
+		}
+		if len(ops) < batchSize {
+			return count, nil
+		}
+	}
+}
+
+// schedule adds the operation to the schedule.
+func (db *DB) schedule(at time.Time, op, key string, value, target any) (int64, error) {
+	var id int64
+	err := db.Update(func(tx *Tx) error {
+		res, err := tx.tx.Exec(sqlScheduleAdd, at.UnixMilli(), op, key, value, target)
+		if err != nil {
+			return err
+		}
+		id, err = res.LastInsertId()
+		return err
+	})
+	return id, err
+}
+
+// runScheduled runs the operation and removes it from the schedule.
+// Returns false if the operation has been canceled or has failed.
+// The failed operation is discarded, while the one that could not be
+// committed (for example, because the database is busy) is kept
+// to run again later.
+func (db *DB) runScheduled(op ScheduledOp) (bool, error) {
+	var ran bool
+	var opErr error
+	err := db.Update(func(tx *Tx) error {
+		res, err := tx.tx.Exec(sqlScheduleDelete, op.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			// Canceled.
+			return nil
+		}
+		if opErr = op.run(tx); opErr != nil {
+			return opErr
+		}
+		ran = true
+		return nil
+	})
+	if opErr == nil {
+		return ran && err == nil, err
+	}
+
+	// Discard the failed operation.
+	db.log.Error("bg: run scheduled op", "id", op.ID, "op", op.Op, "key", op.Key, "error", opErr)
+	_, err = db.CancelScheduled(op.ID)
+	return false, err
+}
+
+// run runs the operation in the transaction.
+func (op ScheduledOp) run(tx *Tx) error {
+	switch op.Op {
+	case ScheduleOpSet:
+		return tx.Str().Set(op.Key, op.Value.Bytes())
+	case ScheduleOpDelete:
+		_, err := tx.Key().Delete(op.Key)
+		return err
+	case ScheduleOpMove:
+		zset := tx.SortedSet()
+		score, err := zset.GetScore(op.Key, op.Value.Bytes())
+		if errors.Is(err, core.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := zset.Delete(op.Key, op.Value.Bytes()); err != nil {
+			return err
+		}
+		_, err = zset.Add(op.Target, op.Value.Bytes(), score)
+		return err
+	default:
+		return fmt.Errorf("unknown scheduled op %q", op.Op)
+	}
+}
+
+// scanScheduledOp scans the scheduled operation.
+func scanScheduledOp(rows *sql.Rows) (ScheduledOp, error) {
+	var op ScheduledOp
+	var ms int64
+	var value []byte
+	var target sql.NullString
+	err := rows.Scan(&op.ID, &ms, &op.Op, &op.Key, &value, &target)
+	op.Time = time.UnixMilli(ms)
+	op.Value = Value(value)
+	op.Target = target.String
+	return op, err
+}
+
+// startScheduler starts the goroutine that runs
+// in the background and runs the scheduled operations.
+// Triggers every interval (see Options.ScheduleInterval).
+func (db *DB) startScheduler(interval time.Duration) *time.Ticker {
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			for _, db := range db.dbs.all() {
+				count, err := db.RunScheduled()
+				if err != nil {
+					db.log.Error("bg: run scheduled ops", "error", err)
+				} else if count > 0 {
+					db.log.Info("bg: run scheduled ops", "count", count)
+				}
+			}
+		}
+	}()
+	return ticker
+}
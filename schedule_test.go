@@ -0,0 +1,161 @@
+package redka_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// openDB opens a database with a clock that can be
+	// advanced by the test. The scheduler is effectively
+	// disabled, so the tests call RunScheduled directly.
+	openDB := func(t *testing.T) (*redka.DB, *atomic.Int64) {
+		var now atomic.Int64
+		now.Store(start.UnixMilli())
+		db, err := redka.Open(":memory:", &redka.Options{
+			Clock:            func() time.Time { return time.UnixMilli(now.Load()) },
+			ScheduleInterval: time.Hour,
+		})
+		testx.AssertNoErr(t, err)
+		t.Cleanup(func() { _ = db.Close() })
+		return db, &now
+	}
+
+	t.Run("set", func(t *testing.T) {
+		db, now := openDB(t)
+		_ = db.Str().Set("mode", "dev")
+
+		id, err := db.ScheduleSet("mode", "prod", start.Add(time.Minute))
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, id > 0, true)
+
+		count, err := db.RunScheduled()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 0)
+		val, _ := db.Str().Get("mode")
+		testx.AssertEqual(t, val.String(), "dev")
+
+		now.Add(time.Minute.Milliseconds())
+		count, err = db.RunScheduled()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+		val, _ = db.Str().Get("mode")
+		testx.AssertEqual(t, val.String(), "prod")
+
+		ops, _ := db.Scheduled()
+		testx.AssertEqual(t, len(ops), 0)
+	})
+	t.Run("delete", func(t *testing.T) {
+		db, now := openDB(t)
+		_ = db.Str().Set("promo", "on")
+		_, err := db.ScheduleDelete("promo", start.Add(time.Minute))
+		testx.AssertNoErr(t, err)
+
+		// The deletion does not depend on the value.
+		_ = db.Str().Set("promo", "still on")
+
+		now.Add(time.Minute.Milliseconds())
+		count, err := db.RunScheduled()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+		val, _ := db.Str().Get("promo")
+		testx.AssertEqual(t, val.Exists(), false)
+	})
+	t.Run("move", func(t *testing.T) {
+		db, now := openDB(t)
+		_, _ = db.SortedSet().Add("pending", "task", 42)
+		_, err := db.ScheduleMove("pending", "active", "task", start.Add(time.Minute))
+		testx.AssertNoErr(t, err)
+		_, err = db.ScheduleMove("pending", "active", "missing", start.Add(time.Minute))
+		testx.AssertNoErr(t, err)
+
+		now.Add(time.Minute.Milliseconds())
+		count, err := db.RunScheduled()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 2)
+
+		_, err = db.SortedSet().GetScore("pending", "task")
+		testx.AssertErr(t, err, redka.ErrNotFound)
+		score, err := db.SortedSet().GetScore("active", "task")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, score, 42.0)
+		_, err = db.SortedSet().GetScore("active", "missing")
+		testx.AssertErr(t, err, redka.ErrNotFound)
+	})
+	t.Run("list and cancel", func(t *testing.T) {
+		db, now := openDB(t)
+		id1, _ := db.ScheduleDelete("key", start.Add(2*time.Minute))
+		id2, _ := db.ScheduleSet("key", 42, start.Add(time.Minute))
+		id3, _ := db.ScheduleMove("src", "dest", "elem", start.Add(3*time.Minute))
+
+		ops, err := db.Scheduled()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(ops), 3)
+		testx.AssertEqual(t, ops[0].ID, id2)
+		testx.AssertEqual(t, ops[0].Op, redka.ScheduleOpSet)
+		testx.AssertEqual(t, ops[0].Value.String(), "42")
+		testx.AssertEqual(t, ops[0].Time.Equal(start.Add(time.Minute)), true)
+		testx.AssertEqual(t, ops[1].ID, id1)
+		testx.AssertEqual(t, ops[1].Op, redka.ScheduleOpDelete)
+		testx.AssertEqual(t, ops[2].ID, id3)
+		testx.AssertEqual(t, ops[2].Op, redka.ScheduleOpMove)
+		testx.AssertEqual(t, ops[2].Key, "src")
+		testx.AssertEqual(t, ops[2].Target, "dest")
+		testx.AssertEqual(t, ops[2].Value.String(), "elem")
+
+		ok, err := db.CancelScheduled(id2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+		ok, err = db.CancelScheduled(id2)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, false)
+
+		now.Add(time.Hour.Milliseconds())
+		count, _ := db.RunScheduled()
+		testx.AssertEqual(t, count, 2)
+		val, _ := db.Str().Get("key")
+		testx.AssertEqual(t, val.Exists(), false)
+	})
+	t.Run("failed", func(t *testing.T) {
+		db, _ := openDB(t)
+		_, _ = db.Hash().Set("key", "field", "value")
+		_, _ = db.ScheduleSet("key", "value", start)
+		_, _ = db.ScheduleSet("other", "value", start)
+
+		count, err := db.RunScheduled()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 1)
+
+		// The failed operation is discarded.
+		ops, _ := db.Scheduled()
+		testx.AssertEqual(t, len(ops), 0)
+		val, _ := db.Hash().Get("key", "field")
+		testx.AssertEqual(t, val.String(), "value")
+	})
+	t.Run("invalid value", func(t *testing.T) {
+		db, _ := openDB(t)
+		_, err := db.ScheduleSet("key", struct{}{}, start)
+		testx.AssertErr(t, err, redka.ErrValueType)
+		_, err = db.ScheduleMove("src", "dest", struct{}{}, start)
+		testx.AssertErr(t, err, redka.ErrValueType)
+	})
+	t.Run("background", func(t *testing.T) {
+		db, err := redka.Open(":memory:", &redka.Options{
+			ScheduleInterval: 10 * time.Millisecond,
+		})
+		testx.AssertNoErr(t, err)
+		defer db.Close()
+
+		_, _ = db.ScheduleSet("key", "value", time.Now())
+		time.Sleep(50 * time.Millisecond)
+		val, err := db.Str().Get("key")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val.String(), "value")
+	})
+}
@@ -0,0 +1,60 @@
+package redka
+
+import (
+	"context"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// ErrSchemaVersion is returned when the database schema version
+// is not supported (e.g. the database was created by a newer
+// version of Redka), or the migration is not possible.
+var ErrSchemaVersion = sqlx.ErrSchemaVersion
+
+// ErrIncompatible is returned by Open when the database file
+// cannot be used by this version of Redka (e.g. the data depends
+// on features it does not support, or the file has tables with
+// the Redka names but a different layout).
+var ErrIncompatible = sqlx.ErrIncompatible
+
+// Fingerprint describes the origin and the format of the database:
+// the schema version, the Redka version that created the database,
+// the features the data depends on and the collation (see
+// [Options.Collation]). Open checks the fingerprint
+// and refuses the databases it cannot use with [ErrSchemaVersion]
+// or [ErrIncompatible].
+type Fingerprint = sqlx.Fingerprint
+
+// SchemaVersion returns the version of the database schema
+// and the latest version supported by this version of Redka.
+func (db *DB) SchemaVersion() (current, latest int, err error) {
+	current, err = db.DB.SchemaVersion()
+	return current, sqlx.LatestVersion(), err
+}
+
+// Fingerprint returns the database fingerprint. The creator is
+// unknown for the databases created before the fingerprint was
+// introduced.
+func (db *DB) Fingerprint() (Fingerprint, error) {
+	return db.DB.Fingerprint()
+}
+
+// MigrateSchema upgrades or downgrades the database schema to the
+// given version (use 0 for the latest one) in a single transaction.
+// Open upgrades the schema automatically, so MigrateSchema is only
+// needed with the ManualMigrations option, or to downgrade before
+// switching to an older version of Redka. Fails with [ErrSchemaVersion]
+// if one of the reverted changes is irreversible.
+func (db *DB) MigrateSchema(ctx context.Context, version int) error {
+	if version == 0 {
+		version = sqlx.LatestVersion()
+	}
+	from, err := db.DB.Migrate(ctx, version)
+	if err != nil {
+		return err
+	}
+	if from != version {
+		db.log.Info("migrate schema", "from", from, "to", version)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package redka_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	current, latest, err := db.SchemaVersion()
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, current, latest)
+
+	err = db.MigrateSchema(context.Background(), 0)
+	testx.AssertNoErr(t, err)
+	err = db.MigrateSchema(context.Background(), latest+1)
+	testx.AssertEqual(t, errors.Is(err, redka.ErrSchemaVersion), true)
+	err = db.MigrateSchema(context.Background(), -1)
+	testx.AssertEqual(t, errors.Is(err, redka.ErrSchemaVersion), true)
+}
+
+func TestFingerprint(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	fp, err := db.Fingerprint()
+	testx.AssertNoErr(t, err)
+	_, latest, _ := db.SchemaVersion()
+	testx.AssertEqual(t, fp.Schema, latest)
+	testx.AssertEqual(t, fp.CreatedBy != "", true)
+	testx.AssertEqual(t, time.Since(fp.CreatedAt) < time.Minute, true)
+	testx.AssertEqual(t, len(fp.Features), 0)
+}
+
+func TestCompat(t *testing.T) {
+	t.Run("unknown feature", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.db")
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		_, err = db.SQL.Exec("insert into rmeta (name, value) values ('features', 'teleport')")
+		testx.AssertNoErr(t, err)
+		_ = db.Close()
+
+		_, err = redka.Open(path, nil)
+		testx.AssertEqual(t, errors.Is(err, redka.ErrIncompatible), true)
+		testx.AssertEqual(t, strings.Contains(err.Error(), "teleport"), true)
+		_, err = redka.Open(path, &redka.Options{ReadOnly: true})
+		testx.AssertEqual(t, errors.Is(err, redka.ErrIncompatible), true)
+	})
+	t.Run("foreign table", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.db")
+		sdb, err := sql.Open("sqlite3", path)
+		testx.AssertNoErr(t, err)
+		_, err = sdb.Exec("create table rkey (id integer primary key, name text)")
+		testx.AssertNoErr(t, err)
+		_ = sdb.Close()
+
+		_, err = redka.Open(path, nil)
+		testx.AssertEqual(t, errors.Is(err, redka.ErrIncompatible), true)
+
+		// The prefixed tables do not conflict with the foreign one.
+		db, err := redka.Open(path, &redka.Options{TablePrefix: "redka_"})
+		testx.AssertNoErr(t, err)
+		_ = db.Close()
+	})
+}
+
+func TestMigrateSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	t.Run("unversioned", func(t *testing.T) {
+		// A database created before the schema versioning.
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		_ = db.Str().Set("name", "alice")
+		_, err = db.SQL.Exec("drop table rschema")
+		testx.AssertNoErr(t, err)
+		_ = db.Close()
+
+		db, err = redka.Open(path, &redka.Options{ManualMigrations: true})
+		testx.AssertNoErr(t, err)
+		current, _, _ := db.SchemaVersion()
+		testx.AssertEqual(t, current, 0)
+		err = db.MigrateSchema(context.Background(), 0)
+		testx.AssertNoErr(t, err)
+		current, latest, _ := db.SchemaVersion()
+		testx.AssertEqual(t, current, latest)
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+		fp, err := db.Fingerprint()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, fp.Schema, latest)
+		_ = db.Close()
+	})
+	t.Run("newer", func(t *testing.T) {
+		sdb, err := sql.Open("sqlite3", path)
+		testx.AssertNoErr(t, err)
+		_, err = sdb.Exec("insert into rschema (version, time) values (1000, 0)")
+		testx.AssertNoErr(t, err)
+		_ = sdb.Close()
+
+		_, err = redka.Open(path, nil)
+		testx.AssertEqual(t, errors.Is(err, redka.ErrSchemaVersion), true)
+	})
+}
+
+func TestMigrateElements(t *testing.T) {
+	// Before version 4, the elements were stored as is,
+	// so 1, "1" and []byte("1") were different elements.
+	path := filepath.Join(t.TempDir(), "data.db")
+	db, err := redka.Open(path, nil)
+	testx.AssertNoErr(t, err)
+	_, _ = db.SortedSet().Add("race", "1", 1)
+	_, err = db.SQL.Exec(`
+	insert into rzset (key_id, elem, score)
+	select key_id, x'31', 2 from rzset union all
+	select key_id, 1, 3 from rzset`)
+	testx.AssertNoErr(t, err)
+	_, err = db.SQL.Exec("delete from rschema where version >= 4")
+	testx.AssertNoErr(t, err)
+	_ = db.Close()
+
+	db, err = redka.Open(path, nil)
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+	n, err := db.SortedSet().Len("race")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, n, 1)
+	score, err := db.SortedSet().GetScore("race", 1)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, score > 1, true)
+}
+
+func TestCollation(t *testing.T) {
+	t.Run("nocase", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.db")
+		db, err := redka.Open(path, &redka.Options{Collation: "NOCASE"})
+		testx.AssertNoErr(t, err)
+
+		_ = db.Str().Set("Name", "alice")
+		_ = db.Str().Set("NAME", "bob")
+		val, _ := db.Str().Get("name")
+		testx.AssertEqual(t, val.String(), "bob")
+		keys, err := db.Key().Keys("na*")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(keys), 1)
+		testx.AssertEqual(t, keys[0].Key, "Name")
+		keys, _ = db.Key().Keys("[m-o]A?e")
+		testx.AssertEqual(t, len(keys), 1)
+		keys, _ = db.Key().Keys("[^n]*")
+		testx.AssertEqual(t, len(keys), 0)
+
+		_, _ = db.Hash().Set("user", "Age", 25)
+		val, _ = db.Hash().Get("user", "AGE")
+		testx.AssertEqual(t, val.String(), "25")
+
+		_, _ = db.SortedSet().Add("race", "b", 1)
+		_, _ = db.SortedSet().Add("race", "A", 1)
+		_, _ = db.SortedSet().Add("race", "a", 2)
+		items, err := db.SortedSet().Range("race", 0, 10)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(items), 2)
+		testx.AssertEqual(t, items[0].Elem.String(), "b")
+		testx.AssertEqual(t, items[1].Elem.String(), "A")
+
+		fp, err := db.Fingerprint()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, fp.Collation, "nocase")
+		testx.AssertEqual(t, fp.Features, []string{"collation"})
+		_ = db.Close()
+
+		// The database keeps its collation.
+		db, err = redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		val, _ = db.Str().Get("nAmE")
+		testx.AssertEqual(t, val.String(), "bob")
+		_ = db.Close()
+
+		_, err = redka.Open(path, &redka.Options{Collation: "binary"})
+		testx.AssertEqual(t, errors.Is(err, redka.ErrIncompatible), true)
+	})
+	t.Run("binary", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.db")
+		db, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		_ = db.Str().Set("Name", "alice")
+		val, _ := db.Str().Get("name")
+		testx.AssertEqual(t, val.Exists(), false)
+		keys, _ := db.Key().Keys("na*")
+		testx.AssertEqual(t, len(keys), 0)
+		fp, _ := db.Fingerprint()
+		testx.AssertEqual(t, fp.Collation, "binary")
+		_ = db.Close()
+
+		_, err = redka.Open(path, &redka.Options{Collation: "nocase"})
+		testx.AssertEqual(t, errors.Is(err, redka.ErrIncompatible), true)
+	})
+	t.Run("not registered", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "data.db")
+		_, err := redka.Open(path, &redka.Options{Collation: "icu_und"})
+		testx.AssertEqual(t, errors.Is(err, redka.ErrIncompatible), true)
+		_, err = redka.Open(path, &redka.Options{Collation: "no case"})
+		testx.AssertEqual(t, errors.Is(err, redka.ErrIncompatible), true)
+	})
+}
@@ -0,0 +1,134 @@
+// Package sessions provides an HTTP session store backed by Redka.
+//
+// [Store] implements the scs.Store, scs.CtxStore and scs.IterableStore
+// interfaces of the github.com/alexedwards/scs/v2 session manager,
+// so it can be used as is:
+//
+//	sessionManager := scs.New()
+//	sessionManager.Store = sessions.New(db, nil)
+//
+// The package does not depend on gorilla/sessions, but the store
+// can back a gorilla sessions.Store that keeps the encoded session
+// values on the server side:
+//
+//	func (s *gorillaStore) load(session *sessions.Session) error {
+//	    data, found, err := s.store.Find(session.ID)
+//	    if err != nil || !found {
+//	        return err
+//	    }
+//	    return securecookie.DecodeMulti(session.Name(), string(data),
+//	        &session.Values, s.Codecs...)
+//	}
+//
+//	func (s *gorillaStore) save(session *sessions.Session) error {
+//	    data, err := securecookie.EncodeMulti(session.Name(),
+//	        session.Values, s.Codecs...)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    expiry := time.Now().Add(time.Duration(session.Options.MaxAge) * time.Second)
+//	    return s.store.Commit(session.ID, []byte(data), expiry)
+//	}
+//
+// Each session is a string key with the session token prefixed
+// (see [Options.Prefix]), which expires with the session.
+package sessions
+
+import (
+	"context"
+	"time"
+
+	"github.com/nalgeon/redka"
+)
+
+// Options configures the session store.
+type Options struct {
+	// Prefix is added to the session tokens to get the keys.
+	// Must not contain glob characters (*, ?, [).
+	// If empty, uses "session:".
+	Prefix string
+}
+
+// Store is a session store backed by Redka.
+// Safe for concurrent use.
+type Store struct {
+	db     *redka.DB
+	prefix string
+}
+
+// New creates a new session store.
+// The opts parameter is optional. If nil, uses default options.
+func New(db *redka.DB, opts *Options) *Store {
+	s := &Store{db: db, prefix: "session:"}
+	if opts != nil && opts.Prefix != "" {
+		s.prefix = opts.Prefix
+	}
+	return s
+}
+
+// Find returns the data of the session with the given token.
+// If the session does not exist or has expired, found is false.
+func (s *Store) Find(token string) (b []byte, found bool, err error) {
+	return s.FindCtx(context.Background(), token)
+}
+
+// FindCtx is like Find, but with a context.
+func (s *Store) FindCtx(ctx context.Context, token string) (b []byte, found bool, err error) {
+	val, err := s.db.WithContext(ctx).Str().Get(s.prefix + token)
+	if err != nil || !val.Exists() {
+		return nil, false, err
+	}
+	return val.Bytes(), true, nil
+}
+
+// Commit adds the session data with the given token and expiry time,
+// or replaces the data of an existing session. If the expiry time
+// has already passed, deletes the session.
+func (s *Store) Commit(token string, b []byte, expiry time.Time) error {
+	return s.CommitCtx(context.Background(), token, b, expiry)
+}
+
+// CommitCtx is like Commit, but with a context.
+func (s *Store) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	ttl := time.Until(expiry)
+	if ttl <= 0 {
+		return s.DeleteCtx(ctx, token)
+	}
+	return s.db.WithContext(ctx).Str().SetExpires(s.prefix+token, b, ttl)
+}
+
+// Delete deletes the session with the given token.
+// Does nothing if the session does not exist.
+func (s *Store) Delete(token string) error {
+	return s.DeleteCtx(context.Background(), token)
+}
+
+// DeleteCtx is like Delete, but with a context.
+func (s *Store) DeleteCtx(ctx context.Context, token string) error {
+	_, err := s.db.WithContext(ctx).Key().Delete(s.prefix + token)
+	return err
+}
+
+// All returns the data of all active sessions by their tokens.
+func (s *Store) All() (map[string][]byte, error) {
+	return s.AllCtx(context.Background())
+}
+
+// AllCtx is like All, but with a context.
+func (s *Store) AllCtx(ctx context.Context) (map[string][]byte, error) {
+	db := s.db.WithContext(ctx)
+	sessions := map[string][]byte{}
+	for key, err := range db.Key().Iter(s.prefix + "*") {
+		if err != nil {
+			return nil, err
+		}
+		val, err := db.Str().Get(key.Key)
+		if err != nil {
+			return nil, err
+		}
+		if val.Exists() {
+			sessions[key.Key[len(s.prefix):]] = val.Bytes()
+		}
+	}
+	return sessions, nil
+}
@@ -0,0 +1,81 @@
+package sessions_test
+
+import (
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+	"github.com/nalgeon/redka/sessions"
+)
+
+func getStore(tb testing.TB) (*redka.DB, *sessions.Store) {
+	tb.Helper()
+	db, err := redka.Open(":memory:", nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db, sessions.New(db, nil)
+}
+
+func TestStore(t *testing.T) {
+	t.Run("commit and find", func(t *testing.T) {
+		db, store := getStore(t)
+		defer db.Close()
+
+		err := store.Commit("abc", []byte("data"), time.Now().Add(time.Minute))
+		testx.AssertNoErr(t, err)
+
+		b, found, err := store.Find("abc")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, found, true)
+		testx.AssertEqual(t, string(b), "data")
+
+		key, _ := db.Key().Get("session:abc")
+		testx.AssertEqual(t, key.ETime != nil, true)
+	})
+	t.Run("not found", func(t *testing.T) {
+		db, store := getStore(t)
+		defer db.Close()
+
+		b, found, err := store.Find("abc")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, found, false)
+		testx.AssertEqual(t, b, []byte(nil))
+	})
+	t.Run("expired", func(t *testing.T) {
+		db, store := getStore(t)
+		defer db.Close()
+
+		_ = store.Commit("abc", []byte("data"), time.Now().Add(time.Minute))
+		err := store.Commit("abc", []byte("data"), time.Now().Add(-time.Second))
+		testx.AssertNoErr(t, err)
+		_, found, _ := store.Find("abc")
+		testx.AssertEqual(t, found, false)
+	})
+	t.Run("delete", func(t *testing.T) {
+		db, store := getStore(t)
+		defer db.Close()
+
+		_ = store.Commit("abc", []byte("data"), time.Now().Add(time.Minute))
+		err := store.Delete("abc")
+		testx.AssertNoErr(t, err)
+		_, found, _ := store.Find("abc")
+		testx.AssertEqual(t, found, false)
+	})
+	t.Run("all", func(t *testing.T) {
+		db, _ := getStore(t)
+		defer db.Close()
+		store := sessions.New(db, &sessions.Options{Prefix: "sess:"})
+
+		expiry := time.Now().Add(time.Minute)
+		_ = store.Commit("abc", []byte("one"), expiry)
+		_ = store.Commit("def", []byte("two"), expiry)
+		_ = db.Str().Set("name", "alice")
+
+		all, err := store.All()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, all, map[string][]byte{"abc": []byte("one"), "def": []byte("two")})
+	})
+}
@@ -0,0 +1,827 @@
+package redka
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rhash"
+	"github.com/nalgeon/redka/internal/rkey"
+	"github.com/nalgeon/redka/internal/rstream"
+	"github.com/nalgeon/redka/internal/rstring"
+	"github.com/nalgeon/redka/internal/rzset"
+)
+
+// SlotCount is the number of hash slots the keys are mapped to
+// (same as in Redis Cluster).
+const SlotCount = 16384
+
+// maxShards is the maximum number of shards. The shard index
+// is stored in the lower bits of the SCAN cursor.
+const maxShards = 1 << shardCursorBits
+
+// shardCursorBits is the number of the SCAN cursor bits
+// used for the shard index.
+const shardCursorBits = 8
+
+// KeySlot returns the hash slot of the key: CRC16 of the key modulo
+// [SlotCount], same as in Redis Cluster. If the key contains a hash
+// tag (a non-empty part between the first { and the following }),
+// only the tag is hashed, so "{user:1}:name" and "{user:1}:posts"
+// map to the same slot.
+func KeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key)) % SlotCount
+}
+
+// crc16 returns the CRC16-CCITT (XMODEM) checksum of s.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Shards is a database split into several SQLite files (shards),
+// possibly on different disks. Each key is stored in the shard that
+// owns its hash slot (see [KeySlot]), and the slots are divided between
+// the shards in equal contiguous ranges. Each shard has its own writer,
+// so writes to different shards run in parallel.
+//
+// Multi-key operations (like Count, Delete, Rename or Str().GetMany)
+// require all keys to be in the same slot and return [ErrCrossSlot]
+// otherwise. Use hash tags to keep the related keys together.
+// Operations on all keys (Keys, Scan, DeleteAll) visit every shard.
+//
+// The number of shards must not change after the data is written,
+// as it defines the key placement. Safe for concurrent use.
+type Shards struct {
+	dbs       []*DB
+	keyDB     *ShardKeys
+	stringDB  *ShardStrings
+	hashDB    *ShardHashes
+	zsetDB    *ShardSortedSets
+	streamDB  *ShardStreams
+	hllDB     *ShardHLLs
+	closeOnce sync.Once
+}
+
+// OpenShards opens (or creates) the shard databases at the given paths
+// with the same options (see [Open]). The order of the paths defines
+// the slot ranges, so it must stay the same between runs.
+func OpenShards(paths []string, options ...Option) (*Shards, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("open shards: no paths")
+	}
+	if len(paths) > maxShards {
+		return nil, fmt.Errorf("open shards: too many shards (max %d)", maxShards)
+	}
+	s := &Shards{dbs: make([]*DB, 0, len(paths))}
+	for _, path := range paths {
+		db, err := Open(path, options...)
+		if err != nil {
+			_ = s.Close()
+			return nil, fmt.Errorf("open shard %s: %w", path, err)
+		}
+		s.dbs = append(s.dbs, db)
+	}
+	s.keyDB = &ShardKeys{s}
+	s.stringDB = &ShardStrings{s}
+	s.hashDB = &ShardHashes{s}
+	s.zsetDB = &ShardSortedSets{s}
+	s.streamDB = &ShardStreams{s}
+	s.hllDB = &ShardHLLs{s}
+	return s, nil
+}
+
+// Len returns the number of shards.
+func (s *Shards) Len() int {
+	return len(s.dbs)
+}
+
+// DB returns the shard database by index (0 <= i < Len).
+func (s *Shards) DB(i int) *DB {
+	return s.dbs[i]
+}
+
+// Shard returns the database that stores the key.
+func (s *Shards) Shard(key string) *DB {
+	return s.dbs[s.index(KeySlot(key))]
+}
+
+// index returns the index of the shard that owns the slot.
+func (s *Shards) index(slot int) int {
+	return slot * len(s.dbs) / SlotCount
+}
+
+// shardOf returns the database that stores all the keys.
+// Returns ErrCrossSlot if the keys are in different slots.
+func (s *Shards) shardOf(keys ...string) (*DB, error) {
+	if len(keys) == 0 {
+		return s.dbs[0], nil
+	}
+	slot := KeySlot(keys[0])
+	for _, key := range keys[1:] {
+		if KeySlot(key) != slot {
+			return nil, core.ErrCrossSlot
+		}
+	}
+	return s.dbs[s.index(slot)], nil
+}
+
+// Key returns the key repository.
+func (s *Shards) Key() *ShardKeys {
+	return s.keyDB
+}
+
+// Str returns the string repository.
+func (s *Shards) Str() *ShardStrings {
+	return s.stringDB
+}
+
+// Hash returns the hash repository.
+func (s *Shards) Hash() *ShardHashes {
+	return s.hashDB
+}
+
+// SortedSet returns the sorted set repository.
+func (s *Shards) SortedSet() *ShardSortedSets {
+	return s.zsetDB
+}
+
+// Stream returns the stream repository.
+func (s *Shards) Stream() *ShardStreams {
+	return s.streamDB
+}
+
+// HLL returns the HyperLogLog repository.
+func (s *Shards) HLL() *ShardHLLs {
+	return s.hllDB
+}
+
+// Update executes a function within a writable transaction
+// on the shard that stores the key. See [DB.Update] for details.
+// All keys used in the transaction must be in the same shard.
+func (s *Shards) Update(key string, f func(tx *Tx) error) error {
+	return s.Shard(key).Update(f)
+}
+
+// View executes a function within a read-only transaction
+// on the shard that stores the key. See [DB.View] for details.
+func (s *Shards) View(key string, f func(tx *Tx) error) error {
+	return s.Shard(key).View(f)
+}
+
+// Close closes all shard databases.
+func (s *Shards) Close() error {
+	var errs []error
+	s.closeOnce.Do(func() {
+		for i, db := range s.dbs {
+			if err := db.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("close shard %d: %w", i, err))
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// ShardKeys is the key repository of the sharded database.
+type ShardKeys struct {
+	s *Shards
+}
+
+// Exists reports whether the key exists.
+func (r *ShardKeys) Exists(key string) (bool, error) {
+	return r.s.Shard(key).Key().Exists(key)
+}
+
+// Count returns the number of existing keys among specified.
+// The keys must be in the same slot.
+func (r *ShardKeys) Count(keys ...string) (int, error) {
+	db, err := r.s.shardOf(keys...)
+	if err != nil {
+		return 0, err
+	}
+	return db.Key().Count(keys...)
+}
+
+// Keys returns all keys matching pattern from all shards.
+func (r *ShardKeys) Keys(pattern string) ([]core.Key, error) {
+	var keys []core.Key
+	for _, db := range r.s.dbs {
+		part, err := db.Key().Keys(pattern)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, part...)
+	}
+	return keys, nil
+}
+
+// Scan iterates over keys matching pattern in all shards,
+// one shard after another. The cursor combines the shard
+// index and the cursor within the shard. Returns the next
+// cursor, or 0 when all shards are done.
+func (r *ShardKeys) Scan(cursor int, pattern string, pageSize int) (rkey.ScanResult, error) {
+	return r.ScanType(cursor, pattern, 0, pageSize)
+}
+
+// ScanType iterates over keys of the given type matching pattern
+// in all shards (see ShardKeys.Scan). Type 0 matches all types.
+func (r *ShardKeys) ScanType(cursor int, pattern string, typ core.TypeID, pageSize int) (rkey.ScanResult, error) {
+	idx := cursor & (maxShards - 1)
+	inner := cursor >> shardCursorBits
+	if idx >= len(r.s.dbs) {
+		return rkey.ScanResult{}, core.ErrSyntax
+	}
+	out, err := r.s.dbs[idx].Key().ScanType(inner, pattern, typ, pageSize)
+	if err != nil {
+		return out, err
+	}
+	switch {
+	case out.More:
+		out.Cursor = out.Cursor<<shardCursorBits | idx
+	case idx+1 < len(r.s.dbs):
+		// Continue with the next shard.
+		out.Cursor = idx + 1
+		out.More = true
+	default:
+		out.Cursor = 0
+	}
+	return out, nil
+}
+
+// Keyspace returns the number of existing keys, the number of keys
+// with an expiration time, and their average TTL across all shards.
+func (r *ShardKeys) Keyspace() (rkey.KeyspaceInfo, error) {
+	var total rkey.KeyspaceInfo
+	var ttlSum time.Duration
+	for _, db := range r.s.dbs {
+		info, err := db.Key().Keyspace()
+		if err != nil {
+			return rkey.KeyspaceInfo{}, err
+		}
+		total.Keys += info.Keys
+		total.Expires += info.Expires
+		ttlSum += info.AvgTTL * time.Duration(info.Expires)
+	}
+	if total.Expires > 0 {
+		total.AvgTTL = ttlSum / time.Duration(total.Expires)
+	}
+	return total, nil
+}
+
+// Expiry returns the number of keys that expire within
+// the next minute, hour and day across all shards.
+func (r *ShardKeys) Expiry() (rkey.ExpiryInfo, error) {
+	var total rkey.ExpiryInfo
+	for _, db := range r.s.dbs {
+		info, err := db.Key().Expiry()
+		if err != nil {
+			return rkey.ExpiryInfo{}, err
+		}
+		total.Minute += info.Minute
+		total.Hour += info.Hour
+		total.Day += info.Day
+		total.Total += info.Total
+		total.Expired += info.Expired
+	}
+	return total, nil
+}
+
+// Random returns a random key from a random non-empty shard.
+// Returns an empty key if there are no keys.
+func (r *ShardKeys) Random() (core.Key, error) {
+	for _, i := range rand.Perm(len(r.s.dbs)) {
+		key, err := r.s.dbs[i].Key().Random()
+		if err != nil || key.Exists() {
+			return key, err
+		}
+	}
+	return core.Key{}, nil
+}
+
+// Get returns a specific key with all associated details.
+func (r *ShardKeys) Get(key string) (core.Key, error) {
+	return r.s.Shard(key).Key().Get(key)
+}
+
+// Inspect returns the storage details of the key.
+func (r *ShardKeys) Inspect(key string) (rkey.KeyInfo, error) {
+	return r.s.Shard(key).Key().Inspect(key)
+}
+
+// Expire sets a time-to-live (ttl) for the key.
+func (r *ShardKeys) Expire(key string, ttl time.Duration) (bool, error) {
+	return r.s.Shard(key).Key().Expire(key, ttl)
+}
+
+// ExpireAt sets an expiration time for the key.
+func (r *ShardKeys) ExpireAt(key string, at time.Time) (bool, error) {
+	return r.s.Shard(key).Key().ExpireAt(key, at)
+}
+
+// ExpireWith sets the expiration time of the key
+// only if the specified conditions are met.
+func (r *ShardKeys) ExpireWith(key string) rkey.ExpireCmd {
+	return r.s.Shard(key).Key().ExpireWith(key)
+}
+
+// Persist removes the expiration time for the key.
+func (r *ShardKeys) Persist(key string) (bool, error) {
+	return r.s.Shard(key).Key().Persist(key)
+}
+
+// TTL returns the remaining time-to-live of the key.
+func (r *ShardKeys) TTL(key string) (time.Duration, error) {
+	return r.s.Shard(key).Key().TTL(key)
+}
+
+// ExpireTime returns the expiration time of the key.
+func (r *ShardKeys) ExpireTime(key string) (time.Time, error) {
+	return r.s.Shard(key).Key().ExpireTime(key)
+}
+
+// Rename changes the key name. Both keys must be in the same slot.
+func (r *ShardKeys) Rename(key, newKey string) error {
+	db, err := r.s.shardOf(key, newKey)
+	if err != nil {
+		return err
+	}
+	return db.Key().Rename(key, newKey)
+}
+
+// RenameNotExists changes the key name if the new name does not exist.
+// Both keys must be in the same slot.
+func (r *ShardKeys) RenameNotExists(key, newKey string) (bool, error) {
+	db, err := r.s.shardOf(key, newKey)
+	if err != nil {
+		return false, err
+	}
+	return db.Key().RenameNotExists(key, newKey)
+}
+
+// Copy copies the key with its value to the new key.
+// Both keys must be in the same slot.
+func (r *ShardKeys) Copy(key, newKey string, replace bool) (bool, error) {
+	db, err := r.s.shardOf(key, newKey)
+	if err != nil {
+		return false, err
+	}
+	return db.Key().Copy(key, newKey, replace)
+}
+
+// Delete deletes keys and their values. The keys must be in the same slot.
+func (r *ShardKeys) Delete(keys ...string) (int, error) {
+	db, err := r.s.shardOf(keys...)
+	if err != nil {
+		return 0, err
+	}
+	return db.Key().Delete(keys...)
+}
+
+// Unlink deletes keys and frees their values in the background.
+// The keys must be in the same slot.
+func (r *ShardKeys) Unlink(keys ...string) (int, error) {
+	db, err := r.s.shardOf(keys...)
+	if err != nil {
+		return 0, err
+	}
+	return db.Key().Unlink(keys...)
+}
+
+// Restore restores the key from the trash (see [rkey.DB.Restore]).
+func (r *ShardKeys) Restore(key string) (bool, error) {
+	return r.s.Shard(key).Key().Restore(key)
+}
+
+// DeleteAll deletes all keys and their values in all shards.
+func (r *ShardKeys) DeleteAll() error {
+	for _, db := range r.s.dbs {
+		if err := db.Key().DeleteAll(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ShardStrings is the string repository of the sharded database.
+type ShardStrings struct {
+	s *Shards
+}
+
+// Get returns the value of the key.
+func (r *ShardStrings) Get(key string) (core.Value, error) {
+	return r.s.Shard(key).Str().Get(key)
+}
+
+// GetMany returns a map of values for given keys.
+// The keys must be in the same slot.
+func (r *ShardStrings) GetMany(keys ...string) (map[string]core.Value, error) {
+	db, err := r.s.shardOf(keys...)
+	if err != nil {
+		return nil, err
+	}
+	return db.Str().GetMany(keys...)
+}
+
+// Set sets the key value that will not expire.
+func (r *ShardStrings) Set(key string, value any) error {
+	return r.s.Shard(key).Str().Set(key, value)
+}
+
+// SetExpires sets the key value with an optional expiration time (if ttl > 0).
+func (r *ShardStrings) SetExpires(key string, value any, ttl time.Duration) error {
+	return r.s.Shard(key).Str().SetExpires(key, value, ttl)
+}
+
+// SetNotExists sets the key value if the key does not exist.
+func (r *ShardStrings) SetNotExists(key string, value any, ttl time.Duration) (bool, error) {
+	return r.s.Shard(key).Str().SetNotExists(key, value, ttl)
+}
+
+// SetExists sets the key value if the key exists.
+func (r *ShardStrings) SetExists(key string, value any, ttl time.Duration) (bool, error) {
+	return r.s.Shard(key).Str().SetExists(key, value, ttl)
+}
+
+// GetSet returns the previous value of a key after setting it to a new value.
+func (r *ShardStrings) GetSet(key string, value any, ttl time.Duration) (core.Value, error) {
+	return r.s.Shard(key).Str().GetSet(key, value, ttl)
+}
+
+// GetEx returns the value of the key and sets or removes its expiration time.
+func (r *ShardStrings) GetEx(key string, ttl time.Duration) (core.Value, error) {
+	return r.s.Shard(key).Str().GetEx(key, ttl)
+}
+
+// GetDel returns the value of the key and deletes the key.
+func (r *ShardStrings) GetDel(key string) (core.Value, error) {
+	return r.s.Shard(key).Str().GetDel(key)
+}
+
+// SetWith sets the key value with additional options.
+func (r *ShardStrings) SetWith(key string, value any) rstring.SetCmd {
+	return r.s.Shard(key).Str().SetWith(key, value)
+}
+
+// SetMany sets the values of multiple keys.
+// The keys must be in the same slot.
+func (r *ShardStrings) SetMany(items map[string]any) error {
+	db, err := r.s.shardOf(mapKeys(items)...)
+	if err != nil {
+		return err
+	}
+	return db.Str().SetMany(items)
+}
+
+// SetManyNX sets the values of multiple keys, but only if none
+// of them exist yet. The keys must be in the same slot.
+func (r *ShardStrings) SetManyNX(items map[string]any) (bool, error) {
+	db, err := r.s.shardOf(mapKeys(items)...)
+	if err != nil {
+		return false, err
+	}
+	return db.Str().SetManyNX(items)
+}
+
+// Incr increments the integer key value by the specified amount.
+func (r *ShardStrings) Incr(key string, delta int) (int, error) {
+	return r.s.Shard(key).Str().Incr(key, delta)
+}
+
+// IncrFloat increments the float key value by the specified amount.
+func (r *ShardStrings) IncrFloat(key string, delta float64) (float64, error) {
+	return r.s.Shard(key).Str().IncrFloat(key, delta)
+}
+
+// Append appends the value to the end of the key value.
+func (r *ShardStrings) Append(key string, value any) (int, error) {
+	return r.s.Shard(key).Str().Append(key, value)
+}
+
+// SetRange overwrites part of the key value starting at the offset.
+func (r *ShardStrings) SetRange(key string, offset int, value any) (int, error) {
+	return r.s.Shard(key).Str().SetRange(key, offset, value)
+}
+
+// GetRange returns the part of the key value between start and end.
+func (r *ShardStrings) GetRange(key string, start, end int) (core.Value, error) {
+	return r.s.Shard(key).Str().GetRange(key, start, end)
+}
+
+// GetBit returns the bit value at the offset in the key value.
+func (r *ShardStrings) GetBit(key string, offset int) (bool, error) {
+	return r.s.Shard(key).Str().GetBit(key, offset)
+}
+
+// SetBit sets or clears the bit at the offset in the key value.
+func (r *ShardStrings) SetBit(key string, offset int, value bool) (bool, error) {
+	return r.s.Shard(key).Str().SetBit(key, offset, value)
+}
+
+// BitCount returns the number of set bits in the key value.
+func (r *ShardStrings) BitCount(key string, start, end int, unit rstring.BitUnit) (int, error) {
+	return r.s.Shard(key).Str().BitCount(key, start, end, unit)
+}
+
+// BitOp performs the bitwise operation between the key values
+// and stores the result in the dest key.
+// The keys must be in the same slot.
+func (r *ShardStrings) BitOp(op rstring.BitwiseOp, dest string, keys ...string) (int, error) {
+	db, err := r.s.shardOf(append([]string{dest}, keys...)...)
+	if err != nil {
+		return 0, err
+	}
+	return db.Str().BitOp(op, dest, keys...)
+}
+
+// mapKeys returns the keys of the map.
+func mapKeys(items map[string]any) []string {
+	keys := make([]string, 0, len(items))
+	for key := range items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// ShardHashes is the hash repository of the sharded database.
+type ShardHashes struct {
+	s *Shards
+}
+
+// Delete deletes one or more items from a hash.
+func (r *ShardHashes) Delete(key string, fields ...string) (int, error) {
+	return r.s.Shard(key).Hash().Delete(key, fields...)
+}
+
+// Exists checks if a field exists in a hash.
+func (r *ShardHashes) Exists(key, field string) (bool, error) {
+	return r.s.Shard(key).Hash().Exists(key, field)
+}
+
+// Fields returns all fields in a hash.
+func (r *ShardHashes) Fields(key string) ([]string, error) {
+	return r.s.Shard(key).Hash().Fields(key)
+}
+
+// FieldExpire sets the time-to-live of the fields in a hash.
+func (r *ShardHashes) FieldExpire(key string, ttl time.Duration, fields ...string) (map[string]bool, error) {
+	return r.s.Shard(key).Hash().FieldExpire(key, ttl, fields...)
+}
+
+// FieldExpireAt sets the expiration time of the fields in a hash.
+func (r *ShardHashes) FieldExpireAt(key string, at time.Time, fields ...string) (map[string]bool, error) {
+	return r.s.Shard(key).Hash().FieldExpireAt(key, at, fields...)
+}
+
+// FieldExpireTimes returns the expiration times of the fields in a hash.
+func (r *ShardHashes) FieldExpireTimes(key string) (map[string]time.Time, error) {
+	return r.s.Shard(key).Hash().FieldExpireTimes(key)
+}
+
+// FieldPersist removes the expiration time of the fields in a hash.
+func (r *ShardHashes) FieldPersist(key string, fields ...string) (map[string]bool, error) {
+	return r.s.Shard(key).Hash().FieldPersist(key, fields...)
+}
+
+// FieldTTL returns the time-to-live of the fields in a hash.
+func (r *ShardHashes) FieldTTL(key string, fields ...string) (map[string]time.Duration, error) {
+	return r.s.Shard(key).Hash().FieldTTL(key, fields...)
+}
+
+// Get returns the value of a field in a hash.
+func (r *ShardHashes) Get(key, field string) (core.Value, error) {
+	return r.s.Shard(key).Hash().Get(key, field)
+}
+
+// GetMany returns a map of values for given fields.
+func (r *ShardHashes) GetMany(key string, fields ...string) (map[string]core.Value, error) {
+	return r.s.Shard(key).Hash().GetMany(key, fields...)
+}
+
+// Incr increments the integer value of a field in a hash.
+func (r *ShardHashes) Incr(key, field string, delta int) (int, error) {
+	return r.s.Shard(key).Hash().Incr(key, field, delta)
+}
+
+// IncrFloat increments the float value of a field in a hash.
+func (r *ShardHashes) IncrFloat(key, field string, delta float64) (float64, error) {
+	return r.s.Shard(key).Hash().IncrFloat(key, field, delta)
+}
+
+// Items returns a map of all fields and values in a hash.
+func (r *ShardHashes) Items(key string) (map[string]core.Value, error) {
+	return r.s.Shard(key).Hash().Items(key)
+}
+
+// Len returns the number of fields in a hash.
+func (r *ShardHashes) Len(key string) (int, error) {
+	return r.s.Shard(key).Hash().Len(key)
+}
+
+// RandField returns random fields from a hash.
+func (r *ShardHashes) RandField(key string, count int, withValues bool) ([]rhash.HashItem, error) {
+	return r.s.Shard(key).Hash().RandField(key, count, withValues)
+}
+
+// Scan iterates over hash items with fields matching pattern.
+func (r *ShardHashes) Scan(key string, cursor int, pattern string, pageSize int) (rhash.ScanResult, error) {
+	return r.s.Shard(key).Hash().Scan(key, cursor, pattern, pageSize)
+}
+
+// Scanner returns an iterator for hash items with fields matching pattern.
+func (r *ShardHashes) Scanner(key, pattern string, pageSize int) *rhash.Scanner {
+	return r.s.Shard(key).Hash().Scanner(key, pattern, pageSize)
+}
+
+// Set creates or updates the value of a field in a hash.
+func (r *ShardHashes) Set(key, field string, value any) (bool, error) {
+	return r.s.Shard(key).Hash().Set(key, field, value)
+}
+
+// SetMany creates or updates the values of multiple fields in a hash.
+func (r *ShardHashes) SetMany(key string, items map[string]any) (int, error) {
+	return r.s.Shard(key).Hash().SetMany(key, items)
+}
+
+// SetNotExists creates the value of a field in a hash if it does not exist.
+func (r *ShardHashes) SetNotExists(key, field string, value any) (bool, error) {
+	return r.s.Shard(key).Hash().SetNotExists(key, field, value)
+}
+
+// Values returns all values in a hash.
+func (r *ShardHashes) Values(key string) ([]core.Value, error) {
+	return r.s.Shard(key).Hash().Values(key)
+}
+
+// ShardSortedSets is the sorted set repository of the sharded database.
+type ShardSortedSets struct {
+	s *Shards
+}
+
+// Add adds or updates an element in a set.
+func (r *ShardSortedSets) Add(key string, elem any, score float64) (bool, error) {
+	return r.s.Shard(key).SortedSet().Add(key, elem, score)
+}
+
+// AddMany adds or updates multiple elements in a set.
+func (r *ShardSortedSets) AddMany(key string, items map[any]float64) (int, error) {
+	return r.s.Shard(key).SortedSet().AddMany(key, items)
+}
+
+// Count returns the number of elements in a set with a score between min and max.
+func (r *ShardSortedSets) Count(key string, min, max float64) (int, error) {
+	return r.s.Shard(key).SortedSet().Count(key, min, max)
+}
+
+// Delete removes elements from a set.
+func (r *ShardSortedSets) Delete(key string, elems ...any) (int, error) {
+	return r.s.Shard(key).SortedSet().Delete(key, elems...)
+}
+
+// GeoAdd adds or updates an element in a geo set at the given position.
+func (r *ShardSortedSets) GeoAdd(key string, elem any, lon, lat float64) (bool, error) {
+	return r.s.Shard(key).SortedSet().GeoAdd(key, elem, lon, lat)
+}
+
+// GeoDist returns the distance in meters between two elements in a geo set.
+func (r *ShardSortedSets) GeoDist(key string, elem1, elem2 any) (float64, error) {
+	return r.s.Shard(key).SortedSet().GeoDist(key, elem1, elem2)
+}
+
+// GeoPos returns the positions of the elements in a geo set.
+func (r *ShardSortedSets) GeoPos(key string, elems ...any) (map[string]rzset.GeoPoint, error) {
+	return r.s.Shard(key).SortedSet().GeoPos(key, elems...)
+}
+
+// GeoSearch searches a geo set for the elements within an area.
+func (r *ShardSortedSets) GeoSearch(key string) rzset.GeoSearchCmd {
+	return r.s.Shard(key).SortedSet().GeoSearch(key)
+}
+
+// GetRank returns the rank and score of an element in a set.
+func (r *ShardSortedSets) GetRank(key string, elem any) (rank int, score float64, err error) {
+	return r.s.Shard(key).SortedSet().GetRank(key, elem)
+}
+
+// GetRankRev returns the rank and score of an element in a set,
+// with the scores ordered from high to low.
+func (r *ShardSortedSets) GetRankRev(key string, elem any) (rank int, score float64, err error) {
+	return r.s.Shard(key).SortedSet().GetRankRev(key, elem)
+}
+
+// GetScore returns the score of an element in a set.
+func (r *ShardSortedSets) GetScore(key string, elem any) (float64, error) {
+	return r.s.Shard(key).SortedSet().GetScore(key, elem)
+}
+
+// Incr increments the score of an element in a set.
+func (r *ShardSortedSets) Incr(key string, elem any, delta float64) (float64, error) {
+	return r.s.Shard(key).SortedSet().Incr(key, elem, delta)
+}
+
+// Len returns the number of elements in a set.
+func (r *ShardSortedSets) Len(key string) (int, error) {
+	return r.s.Shard(key).SortedSet().Len(key)
+}
+
+// Range returns a range of elements from a set with ranks between start and stop.
+func (r *ShardSortedSets) Range(key string, start, stop int) ([]rzset.SetItem, error) {
+	return r.s.Shard(key).SortedSet().Range(key, start, stop)
+}
+
+// ShardStreams is the stream repository of the sharded database.
+type ShardStreams struct {
+	s *Shards
+}
+
+// Add appends an entry to a stream and returns its ID.
+func (r *ShardStreams) Add(key string, fields ...any) (rstream.ID, error) {
+	return r.s.Shard(key).Stream().Add(key, fields...)
+}
+
+// AddID appends an entry with the given ID to a stream.
+func (r *ShardStreams) AddID(key string, id rstream.ID, fields ...any) error {
+	return r.s.Shard(key).Stream().AddID(key, id, fields...)
+}
+
+// Last returns the ID of the last entry in a stream.
+func (r *ShardStreams) Last(key string) (rstream.ID, error) {
+	return r.s.Shard(key).Stream().Last(key)
+}
+
+// Len returns the number of entries in a stream.
+func (r *ShardStreams) Len(key string) (int, error) {
+	return r.s.Shard(key).Stream().Len(key)
+}
+
+// Range returns the stream entries with IDs between start and end.
+func (r *ShardStreams) Range(key string, start, end rstream.ID, count int) ([]rstream.Entry, error) {
+	return r.s.Shard(key).Stream().Range(key, start, end, count)
+}
+
+// RevRange returns the stream entries with IDs between end and start,
+// in reverse order.
+func (r *ShardStreams) RevRange(key string, end, start rstream.ID, count int) ([]rstream.Entry, error) {
+	return r.s.Shard(key).Stream().RevRange(key, end, start, count)
+}
+
+// Read returns the stream entries with IDs greater than after.
+func (r *ShardStreams) Read(key string, after rstream.ID, count int) ([]rstream.Entry, error) {
+	return r.s.Shard(key).Stream().Read(key, after, count)
+}
+
+// ShardHLLs is the HyperLogLog repository of the sharded database.
+type ShardHLLs struct {
+	s *Shards
+}
+
+// Add adds the elements to a HyperLogLog.
+func (r *ShardHLLs) Add(key string, elems ...string) (bool, error) {
+	return r.s.Shard(key).HLL().Add(key, elems...)
+}
+
+// Count returns the estimated number of unique elements
+// in the union of the HyperLogLogs.
+// The keys must be in the same slot.
+func (r *ShardHLLs) Count(keys ...string) (int, error) {
+	db, err := r.s.shardOf(keys...)
+	if err != nil {
+		return 0, err
+	}
+	return db.HLL().Count(keys...)
+}
+
+// Merge merges the HyperLogLogs into the dest key.
+// The keys must be in the same slot.
+func (r *ShardHLLs) Merge(dest string, keys ...string) error {
+	db, err := r.s.shardOf(append([]string{dest}, keys...)...)
+	if err != nil {
+		return err
+	}
+	return db.HLL().Merge(dest, keys...)
+}
+
+var (
+	_ Keys       = (*ShardKeys)(nil)
+	_ Strings    = (*ShardStrings)(nil)
+	_ Hashes     = (*ShardHashes)(nil)
+	_ SortedSets = (*ShardSortedSets)(nil)
+)
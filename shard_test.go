@@ -0,0 +1,170 @@
+package redka_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestKeySlot(t *testing.T) {
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"123456789", 12739},
+		{"foo", 12182},
+		{"bar", 5061},
+		{"{user1000}.following", redka.KeySlot("user1000")},
+		{"{user1000}.followers", redka.KeySlot("user1000")},
+		{"foo{}{bar}", redka.KeySlot("foo{}{bar}")},
+		{"foo{{bar}}zap", redka.KeySlot("{bar")},
+		{"foo{bar}{zap}", redka.KeySlot("bar")},
+	}
+	for _, test := range tests {
+		got := redka.KeySlot(test.key)
+		testx.AssertEqual(t, got, test.want)
+	}
+}
+
+func TestShards(t *testing.T) {
+	t.Run("routing", func(t *testing.T) {
+		shards := getShards(t, 4)
+		for i := range 100 {
+			key := fmt.Sprintf("key:%d", i)
+			err := shards.Str().Set(key, i)
+			testx.AssertNoErr(t, err)
+		}
+		total := 0
+		for i := range shards.Len() {
+			keys, err := shards.DB(i).Key().Keys("*")
+			testx.AssertNoErr(t, err)
+			for _, key := range keys {
+				testx.AssertEqual(t, shards.Shard(key.Key), shards.DB(i))
+			}
+			total += len(keys)
+		}
+		testx.AssertEqual(t, total, 100)
+		info, err := shards.Key().Keyspace()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, info.Keys, 100)
+		testx.AssertEqual(t, info.Expires, 0)
+
+		val, err := shards.Str().Get("key:42")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val.String(), "42")
+	})
+	t.Run("cross slot", func(t *testing.T) {
+		shards := getShards(t, 4)
+		_ = shards.Str().Set("{user:1}:name", "alice")
+		_ = shards.Str().Set("{user:1}:age", 25)
+
+		count, err := shards.Key().Count("{user:1}:name", "{user:1}:age")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count, 2)
+		vals, err := shards.Str().GetMany("{user:1}:name", "{user:1}:age")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(vals), 2)
+
+		_, err = shards.Key().Count("foo", "bar")
+		testx.AssertErr(t, err, redka.ErrCrossSlot)
+		_, err = shards.Key().Delete("foo", "bar")
+		testx.AssertErr(t, err, redka.ErrCrossSlot)
+		err = shards.Key().Rename("{user:1}:name", "name")
+		testx.AssertErr(t, err, redka.ErrCrossSlot)
+		err = shards.Str().SetMany(map[string]any{"foo": 1, "bar": 2})
+		testx.AssertErr(t, err, redka.ErrCrossSlot)
+
+		err = shards.Key().Rename("{user:1}:name", "{user:1}:first")
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("all keys", func(t *testing.T) {
+		shards := getShards(t, 3)
+		var want []string
+		for i := range 50 {
+			key := fmt.Sprintf("key:%d", i)
+			want = append(want, key)
+			_, err := shards.Hash().Set(key, "field", i)
+			testx.AssertNoErr(t, err)
+		}
+		slices.Sort(want)
+
+		keys, err := shards.Key().Keys("key:*")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, keyNames(keys), want)
+
+		var scanned []core.Key
+		cursor := 0
+		for {
+			out, err := shards.Key().Scan(cursor, "*", 7)
+			testx.AssertNoErr(t, err)
+			scanned = append(scanned, out.Keys...)
+			if out.Cursor == 0 {
+				break
+			}
+			cursor = out.Cursor
+		}
+		testx.AssertEqual(t, keyNames(scanned), want)
+
+		key, err := shards.Key().Random()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, slices.Contains(want, key.Key), true)
+
+		err = shards.Key().DeleteAll()
+		testx.AssertNoErr(t, err)
+		keys, err = shards.Key().Keys("*")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(keys), 0)
+		key, err = shards.Key().Random()
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, key.Exists(), false)
+	})
+	t.Run("transaction", func(t *testing.T) {
+		shards := getShards(t, 2)
+		err := shards.Update("{cart:1}", func(tx *redka.Tx) error {
+			if _, err := tx.Hash().Set("{cart:1}:items", "apple", 3); err != nil {
+				return err
+			}
+			_, err := tx.Str().Incr("{cart:1}:count", 3)
+			return err
+		})
+		testx.AssertNoErr(t, err)
+		count, err := shards.Str().Get("{cart:1}:count")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, count.String(), "3")
+	})
+	t.Run("no paths", func(t *testing.T) {
+		_, err := redka.OpenShards(nil)
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
+
+// getShards opens the shards in a temporary directory.
+func getShards(tb testing.TB, n int) *redka.Shards {
+	tb.Helper()
+	dir := tb.TempDir()
+	paths := make([]string, n)
+	for i := range paths {
+		paths[i] = filepath.Join(dir, fmt.Sprintf("shard-%d.db", i))
+	}
+	shards, err := redka.OpenShards(paths, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = shards.Close() })
+	return shards
+}
+
+// keyNames returns the sorted key names.
+func keyNames(keys []core.Key) []string {
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.Key
+	}
+	slices.Sort(names)
+	return names
+}
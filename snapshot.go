@@ -0,0 +1,153 @@
+package redka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// snapshotPollInterval is how often the snapshot
+// scheduler checks the snapshot rules.
+const snapshotPollInterval = time.Second
+
+// ErrSnapshotBusy is returned when the WAL could not be fully
+// checkpointed before a snapshot (e.g. because of long-running
+// readers), so the database file alone is not consistent.
+var ErrSnapshotBusy = errors.New("snapshot: wal checkpoint is busy")
+
+// SnapshotFunc captures a snapshot of the database file at path
+// (e.g. copies it to a backup directory or uploads it to S3-compatible
+// storage). While the function is running, the file is consistent
+// and no writes are made to the database.
+type SnapshotFunc func(ctx context.Context, path string) error
+
+// SnapshotRule triggers a snapshot after the given time
+// if at least the given number of changes were made since
+// the last snapshot (like the "save" rule in Redis).
+type SnapshotRule struct {
+	After   time.Duration // time since the last snapshot
+	Changes int           // number of changes (at least 1)
+}
+
+// defaultSnapshotRules are the default snapshot
+// rules (the same as Redis defaults).
+var defaultSnapshotRules = []SnapshotRule{
+	{After: time.Hour, Changes: 1},
+	{After: 5 * time.Minute, Changes: 100},
+	{After: time.Minute, Changes: 10000},
+}
+
+// SnapshotOptions configures the scheduled snapshots.
+type SnapshotOptions struct {
+	// Rules trigger the snapshots. A snapshot is made
+	// when any of the rules matches. If empty, uses
+	// 1h/1 change, 5m/100 changes and 1m/10000 changes.
+	Rules []SnapshotRule
+	// Func captures the snapshot (required).
+	Func SnapshotFunc
+}
+
+// Snapshot captures a crash-consistent snapshot of the database file.
+// Briefly quiesces the writes, checkpoints the WAL into the database
+// file and calls fn with the path of the file. The writes resume
+// after fn returns, so it should complete quickly (e.g. copy the file
+// and upload the copy later). Fails with [ErrSnapshotBusy] if the
+// WAL could not be checkpointed.
+//
+// Works with file databases only. Tools like Litestream may keep
+// their own read transactions open, which can make the checkpoint
+// busy. In this case, retry later.
+func (db *DB) Snapshot(ctx context.Context, fn SnapshotFunc) error {
+	if fn == nil {
+		return errors.New("snapshot: nil function")
+	}
+	// Take over a connection from the pool. With the default
+	// single-connection pool, this alone stops the writes.
+	conn, err := db.SQL.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer conn.Close()
+
+	var path string
+	if err := conn.QueryRowContext(ctx, sqlDatabaseFile).Scan(&path); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if path == "" {
+		return errors.New("snapshot: not a file database")
+	}
+
+	res, err := db.checkpoint(ctx, conn, CheckpointTruncate)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	if res.Busy {
+		return ErrSnapshotBusy
+	}
+
+	// Hold the write lock, so that other connections
+	// (and processes) do not write while fn is running.
+	if _, err := conn.ExecContext(ctx, "begin immediate"); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer func() { _, _ = conn.ExecContext(context.Background(), "rollback") }()
+
+	start := time.Now()
+	if err := fn(ctx, path); err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	db.log.Info("snapshot", "path", path, "took", time.Since(start))
+	return nil
+}
+
+// startSnapshots starts the goroutine that runs in the background
+// and makes snapshots according to the rules. Returns nil if the
+// scheduled snapshots are disabled.
+func (db *DB) startSnapshots(opts *SnapshotOptions) *time.Ticker {
+	if opts == nil || opts.Func == nil {
+		return nil
+	}
+	rules := opts.Rules
+	if len(rules) == 0 {
+		rules = defaultSnapshotRules
+	}
+
+	// The pool has a single connection (see sqlx.DB.init),
+	// so the total number of changes made through this connection
+	// tells how many writes there were since the last snapshot.
+	var lastChanges int64
+	if err := db.SQL.QueryRow(sqlTotalChanges).Scan(&lastChanges); err != nil {
+		db.log.Error("snapshot", "error", err)
+	}
+	last := time.Now()
+	ticker := time.NewTicker(snapshotPollInterval)
+	go func() {
+		for range ticker.C {
+			var changes int64
+			if err := db.SQL.QueryRow(sqlTotalChanges).Scan(&changes); err != nil {
+				db.log.Error("snapshot", "error", err)
+				continue
+			}
+			if !snapshotDue(rules, time.Since(last), changes-lastChanges) {
+				continue
+			}
+			if err := db.Snapshot(context.Background(), opts.Func); err != nil {
+				db.log.Error("snapshot", "error", err)
+				continue
+			}
+			lastChanges, last = changes, time.Now()
+		}
+	}()
+	return ticker
+}
+
+// snapshotDue reports whether any of the rules matches.
+func snapshotDue(rules []SnapshotRule, elapsed time.Duration, changes int64) bool {
+	for _, rule := range rules {
+		if elapsed >= rule.After && changes >= int64(max(rule.Changes, 1)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package redka_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	db, err := redka.Open(filepath.Join(dir, "data.db"), nil)
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	t.Run("copy", func(t *testing.T) {
+		_ = db.Str().Set("name", "alice")
+		dst := filepath.Join(dir, "snapshot.db")
+		err := db.Snapshot(context.Background(), func(ctx context.Context, path string) error {
+			// The WAL is checkpointed, so copying
+			// the database file is enough.
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(dst, data, 0644)
+		})
+		testx.AssertNoErr(t, err)
+
+		snap, err := redka.Open(dst, nil)
+		testx.AssertNoErr(t, err)
+		defer snap.Close()
+		name, err := snap.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+	t.Run("func error", func(t *testing.T) {
+		err := db.Snapshot(context.Background(), func(ctx context.Context, path string) error {
+			return os.ErrPermission
+		})
+		testx.AssertEqual(t, err != nil, true)
+
+		// The writes resume after the snapshot.
+		err = db.Str().Set("name", "bob")
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("in-memory", func(t *testing.T) {
+		mem, err := redka.Open("", &redka.Options{InMemory: true})
+		testx.AssertNoErr(t, err)
+		defer mem.Close()
+		err = mem.Snapshot(context.Background(), func(ctx context.Context, path string) error {
+			return nil
+		})
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
+
+func TestAutoSnapshot(t *testing.T) {
+	var count atomic.Int32
+	db, err := redka.Open(filepath.Join(t.TempDir(), "data.db"), &redka.Options{
+		AutoSnapshot: &redka.SnapshotOptions{
+			Rules: []redka.SnapshotRule{{After: 0, Changes: 2}},
+			Func: func(ctx context.Context, path string) error {
+				count.Add(1)
+				return nil
+			},
+		},
+	})
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	_ = db.Str().Set("age", 25)
+	waitFor(t, func() bool { return count.Load() == 1 })
+}
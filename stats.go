@@ -0,0 +1,144 @@
+package redka
+
+import (
+	"database/sql"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// Stats describes the database usage.
+type Stats struct {
+	// SQL is the connection pool statistics.
+	SQL sql.DBStats
+	// Ops is the number of operations made through
+	// the repositories since the database was opened.
+	Ops int64
+	// OpsPerSec is the average number of operations per second
+	// since the previous call to Stats (or since the database
+	// was opened, if this is the first call).
+	OpsPerSec float64
+	// OpsByType is the number of operations per key type
+	// (string, hash, zset, stream, hll), with the generic
+	// key operations (like Key().Expire) under "key".
+	OpsByType map[string]int64
+	// Hits and Misses are the numbers of value lookups (like
+	// Str().Get or Hash().Get) that found and did not find the value.
+	Hits   int64
+	Misses int64
+	// ExpiredKeys is the number of expired keys
+	// deleted by the background sweeps.
+	ExpiredKeys int64
+	// ExpireSweeps is the number of the expired keys sweeps,
+	// and ExpireSweepTime is their total duration
+	// (see [Options.ExpireStrategy]).
+	ExpireSweeps    int64
+	ExpireSweepTime time.Duration
+	// FreedRows is the number of unlinked keys and values
+	// deleted in the background (see [rkey.DB.Unlink]).
+	FreedRows int64
+	// Retries is the number of write transactions retried
+	// because the database was busy (see [Options.BusyRetry]).
+	Retries int64
+	// Commits and Rollbacks are the numbers of committed
+	// and rolled back write transactions (including the
+	// attempts rolled back before a retry).
+	Commits   int64
+	Rollbacks int64
+	// OpenScanners is the number of scanners (and iterators)
+	// created with the repositories that are not exhausted yet.
+	// Scanners abandoned before the end stay open.
+	OpenScanners int64
+	// Size is the size of the database in bytes
+	// (Pages * PageSize, without the WAL file).
+	Size int64
+	// Pages is the number of pages in the database, and FreePages
+	// is the number of unused ones (reused by the future writes
+	// or released with [DB.Compact]).
+	Pages     int64
+	FreePages int64
+	PageSize  int64
+}
+
+// dbStats are the database counters. Safe for concurrent use.
+type dbStats struct {
+	sqlx.Counters
+	expired   atomic.Int64 // keys deleted by the expiry sweeps
+	sweeps    atomic.Int64 // number of the expiry sweeps
+	sweepTime atomic.Int64 // total duration of the expiry sweeps
+	freed     atomic.Int64 // rows deleted by the lazy free
+
+	mu       sync.Mutex
+	lastTime time.Time // time of the previous Stats call
+	lastOps  int64     // operations at the previous Stats call
+}
+
+// newDBStats creates the database counters.
+func newDBStats() *dbStats {
+	return &dbStats{lastTime: time.Now()}
+}
+
+// opsPerSec returns the average number of operations per second
+// since the previous call, given the current number of operations.
+func (s *dbStats) opsPerSec(ops int64) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	elapsed := now.Sub(s.lastTime).Seconds()
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(ops-s.lastOps) / elapsed
+	}
+	s.lastTime, s.lastOps = now, ops
+	return rate
+}
+
+// Stats returns the database usage statistics.
+// The page stats are left at zero if the database
+// can not be queried (for example, when it is closed).
+func (db *DB) Stats() Stats {
+	s := db.stats
+	ops := s.Ops.Load()
+	byType := make(map[string]int64, len(sqlx.Repos))
+	for i, repo := range sqlx.Repos {
+		byType[opFamilies[repo]] = s.RepoOps[i].Load()
+	}
+	stats := Stats{
+		SQL:             db.SQL.Stats(),
+		Ops:             ops,
+		OpsPerSec:       s.opsPerSec(ops),
+		OpsByType:       byType,
+		Hits:            s.Hits.Load(),
+		Misses:          s.Misses.Load(),
+		ExpiredKeys:     s.expired.Load(),
+		ExpireSweeps:    s.sweeps.Load(),
+		ExpireSweepTime: time.Duration(s.sweepTime.Load()),
+		FreedRows:       s.freed.Load(),
+		Retries:         s.Retries.Load(),
+		Commits:         s.Commits.Load(),
+		Rollbacks:       s.Rollbacks.Load(),
+		OpenScanners:    s.Scanners.Load(),
+	}
+	var cacheSize int64
+	err := db.SQL.QueryRow(sqlPageStats).Scan(
+		&stats.Pages, &stats.PageSize, &stats.FreePages, &cacheSize,
+	)
+	if err != nil {
+		stats.Pages, stats.PageSize, stats.FreePages = 0, 0, 0
+	}
+	stats.Size = stats.Pages * stats.PageSize
+	return stats
+}
+
+// PublishExpvar publishes the database statistics (see [DB.Stats])
+// as an expvar variable with the given name, so that they are served
+// at /debug/vars along with the runtime stats. Like [expvar.Publish],
+// panics if the name is already registered.
+func (db *DB) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return db.Stats()
+	}))
+}
@@ -0,0 +1,66 @@
+package redka_test
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestStats(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	_, _ = db.Str().Get("name")
+	_, _ = db.Str().Get("city")
+	_, _ = db.Hash().Get("person", "age")
+
+	sc := db.Key().Scanner("*", 0)
+	stats := db.Stats()
+	testx.AssertEqual(t, stats.Ops, int64(4))
+	testx.AssertEqual(t, stats.OpsPerSec > 0, true)
+	testx.AssertEqual(t, stats.Hits, int64(1))
+	testx.AssertEqual(t, stats.Misses, int64(2))
+	testx.AssertEqual(t, stats.Retries, int64(0))
+	testx.AssertEqual(t, stats.OpenScanners, int64(1))
+	testx.AssertEqual(t, stats.SQL.MaxOpenConnections, 1)
+	testx.AssertEqual(t, stats.OpsByType["string"], int64(3))
+	testx.AssertEqual(t, stats.OpsByType["hash"], int64(1))
+	testx.AssertEqual(t, stats.OpsByType["key"], int64(0))
+	testx.AssertEqual(t, stats.Commits, int64(1))
+	testx.AssertEqual(t, stats.Rollbacks, int64(0))
+	testx.AssertEqual(t, stats.Pages > 0, true)
+	testx.AssertEqual(t, stats.Size, stats.Pages*stats.PageSize)
+
+	for sc.Scan() {
+	}
+	testx.AssertNoErr(t, sc.Err())
+	stats = db.Stats()
+	testx.AssertEqual(t, stats.OpenScanners, int64(0))
+
+	_ = db.Update(func(tx *redka.Tx) error {
+		_ = tx.Str().Set("city", "paris")
+		return errors.New("rollback")
+	})
+	stats = db.Stats()
+	testx.AssertEqual(t, stats.Commits, int64(1))
+	testx.AssertEqual(t, stats.Rollbacks, int64(1))
+}
+
+func TestPublishExpvar(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	_ = db.Str().Set("name", "alice")
+	db.PublishExpvar("redka_test")
+
+	v := expvar.Get("redka_test")
+	var stats redka.Stats
+	err := json.Unmarshal([]byte(v.String()), &stats)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, stats.Ops, int64(1))
+}
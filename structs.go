@@ -0,0 +1,94 @@
+package redka
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SetStruct stores the exported fields of the struct as the fields
+// of the hash. The hash field names are taken from the "redka" tag
+// (like `redka:"name"`) or the struct field names. Fields tagged
+// with "-" are skipped. The values are converted like in [Set],
+// so the nested structs, slices and maps are encoded with the codec
+// set in [Options.Codec]. Existing hash fields that are not in the
+// struct are kept as is.
+//
+// Returns [ErrValueType] if v is not a struct or a pointer to a struct.
+func SetStruct(db *DB, key string, v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("%w: %T is not a struct", ErrValueType, v)
+	}
+	items := map[string]any{}
+	for name, field := range structFields(rv) {
+		data, err := encodeValue(db.codec, field.Interface())
+		if err != nil {
+			return err
+		}
+		items[name] = data
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	_, err := db.Hash().SetMany(key, items)
+	return err
+}
+
+// GetStruct returns the hash as a struct of type T, converting
+// the hash fields like [SetStruct] does. The hash fields missing
+// in the struct are ignored, and the struct fields missing in the
+// hash are left as zero values. Returns [ErrNotFound] if the key
+// does not exist.
+func GetStruct[T any](db *DB, key string) (T, error) {
+	var v T
+	rv := reflect.ValueOf(&v).Elem()
+	if rv.Kind() != reflect.Struct {
+		return v, fmt.Errorf("%w: %T is not a struct", ErrValueType, v)
+	}
+	items, err := db.Hash().Items(key)
+	if err != nil {
+		return v, err
+	}
+	if len(items) == 0 {
+		return v, ErrNotFound
+	}
+	for name, field := range structFields(rv) {
+		data, ok := items[name]
+		if !ok {
+			continue
+		}
+		if err := decodeInto(db.codec, data, field); err != nil {
+			var zero T
+			return zero, fmt.Errorf("field %s: %w", name, err)
+		}
+	}
+	return v, nil
+}
+
+// structFields iterates over the exported fields
+// of the struct value by their hash field names.
+func structFields(rv reflect.Value) func(yield func(string, reflect.Value) bool) {
+	return func(yield func(string, reflect.Value) bool) {
+		rt := rv.Type()
+		for i := range rt.NumField() {
+			sf := rt.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name := sf.Name
+			if tag, ok := sf.Tag.Lookup("redka"); ok {
+				tag, _, _ = strings.Cut(tag, ",")
+				if tag == "-" {
+					continue
+				}
+				if tag != "" {
+					name = tag
+				}
+			}
+			if !yield(name, rv.Field(i)) {
+				return
+			}
+		}
+	}
+}
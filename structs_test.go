@@ -0,0 +1,74 @@
+package redka_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+type account struct {
+	Name    string    `redka:"name"`
+	Age     int       `redka:"age"`
+	Admin   bool      `redka:"admin"`
+	Created time.Time `redka:"created"`
+	Tags    []string  `redka:"tags"`
+	Secret  string    `redka:"-"`
+	Country string
+	note    string
+}
+
+func TestStruct(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	created := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	acc := account{
+		Name: "alice", Age: 25, Admin: true, Created: created,
+		Tags: []string{"a", "b"}, Secret: "xyz", Country: "fr", note: "hi",
+	}
+
+	t.Run("set", func(t *testing.T) {
+		err := redka.SetStruct(db, "user:1", &acc)
+		testx.AssertNoErr(t, err)
+		items, _ := db.Hash().Items("user:1")
+		testx.AssertEqual(t, len(items), 6)
+		testx.AssertEqual(t, items["name"].String(), "alice")
+		testx.AssertEqual(t, items["age"].String(), "25")
+		testx.AssertEqual(t, items["admin"].String(), "1")
+		testx.AssertEqual(t, items["created"].String(), "2024-05-01T12:30:00Z")
+		testx.AssertEqual(t, items["tags"].String(), `["a","b"]`)
+		testx.AssertEqual(t, items["Country"].String(), "fr")
+
+		n, err := db.Hash().Incr("user:1", "age", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 26)
+	})
+	t.Run("get", func(t *testing.T) {
+		got, err := redka.GetStruct[account](db, "user:1")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, got.Name, "alice")
+		testx.AssertEqual(t, got.Age, 26)
+		testx.AssertEqual(t, got.Admin, true)
+		testx.AssertEqual(t, got.Created.Equal(created), true)
+		testx.AssertEqual(t, got.Tags, []string{"a", "b"})
+		testx.AssertEqual(t, got.Secret, "")
+		testx.AssertEqual(t, got.Country, "fr")
+	})
+	t.Run("not found", func(t *testing.T) {
+		_, err := redka.GetStruct[account](db, "user:2")
+		testx.AssertErr(t, err, redka.ErrNotFound)
+	})
+	t.Run("not a struct", func(t *testing.T) {
+		err := redka.SetStruct(db, "user:2", 42)
+		testx.AssertErr(t, err, redka.ErrValueType)
+		_, err = redka.GetStruct[int](db, "user:1")
+		testx.AssertErr(t, err, redka.ErrValueType)
+	})
+	t.Run("invalid field", func(t *testing.T) {
+		_, _ = db.Hash().Set("user:3", "age", "many")
+		_, err := redka.GetStruct[account](db, "user:3")
+		testx.AssertErr(t, err, redka.ErrValueType)
+	})
+}
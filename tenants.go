@@ -0,0 +1,134 @@
+package redka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ErrTenantNotFound is returned when accessing
+// a tenant that is not attached.
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// Tenants is a set of isolated databases (tenants) served by
+// a single process. Each tenant is stored in its own SQLite file,
+// so tenants can be backed up, flushed or removed independently.
+// Safe for concurrent use.
+type Tenants struct {
+	opts *Options
+	mu   sync.RWMutex
+	dbs  map[string]*DB
+}
+
+// NewTenants creates an empty set of tenants. The options are used
+// to open the tenant databases. The opts parameter is optional.
+// If nil, uses default options.
+func NewTenants(opts *Options) *Tenants {
+	return &Tenants{opts: opts, dbs: map[string]*DB{}}
+}
+
+// Attach opens the database at path and adds it as a tenant
+// with the given name. Fails if the tenant already exists.
+func (t *Tenants) Attach(name, path string) (*DB, error) {
+	if name == "" {
+		return nil, errors.New("attach tenant: empty name")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.dbs[name]; ok {
+		return nil, fmt.Errorf("attach tenant %s: already exists", name)
+	}
+	db, err := Open(path, t.opts)
+	if err != nil {
+		return nil, fmt.Errorf("attach tenant %s: %w", name, err)
+	}
+	t.dbs[name] = db
+	return db, nil
+}
+
+// Detach removes the tenant and closes its database.
+// The database file remains intact.
+func (t *Tenants) Detach(name string) error {
+	t.mu.Lock()
+	db, ok := t.dbs[name]
+	delete(t.dbs, name)
+	t.mu.Unlock()
+	if !ok {
+		return ErrTenantNotFound
+	}
+	return db.Close()
+}
+
+// Get returns the database of the tenant.
+func (t *Tenants) Get(name string) (*DB, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	db, ok := t.dbs[name]
+	if !ok {
+		return nil, ErrTenantNotFound
+	}
+	return db, nil
+}
+
+// Route returns the tenant database for a key prefixed
+// with the tenant name and a separator (e.g. "acme:user:1"
+// with sep = ":"), along with the key without the prefix.
+func (t *Tenants) Route(key, sep string) (*DB, string, error) {
+	name, rest, found := strings.Cut(key, sep)
+	if !found {
+		return nil, "", ErrTenantNotFound
+	}
+	db, err := t.Get(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return db, rest, nil
+}
+
+// Names returns the names of the tenants in alphabetical order.
+func (t *Tenants) Names() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	names := make([]string, 0, len(t.dbs))
+	for name := range t.dbs {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Backup creates a snapshot of the tenant database
+// at the given path. See [DB.Backup] for details.
+func (t *Tenants) Backup(ctx context.Context, name, path string) error {
+	db, err := t.Get(name)
+	if err != nil {
+		return err
+	}
+	return db.Backup(ctx, path)
+}
+
+// Flush deletes all the keys of the tenant.
+func (t *Tenants) Flush(name string) error {
+	db, err := t.Get(name)
+	if err != nil {
+		return err
+	}
+	return db.Key().DeleteAll()
+}
+
+// Close closes the databases of all the tenants.
+func (t *Tenants) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var errs []error
+	for name, db := range t.dbs {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("close tenant %s: %w", name, err))
+		}
+		delete(t.dbs, name)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,82 @@
+package redka_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestTenants(t *testing.T) {
+	dir := t.TempDir()
+	tenants := redka.NewTenants(nil)
+	defer tenants.Close()
+
+	acme, err := tenants.Attach("acme", filepath.Join(dir, "acme.db"))
+	testx.AssertNoErr(t, err)
+	_, err = tenants.Attach("globex", filepath.Join(dir, "globex.db"))
+	testx.AssertNoErr(t, err)
+
+	t.Run("attach existing", func(t *testing.T) {
+		_, err := tenants.Attach("acme", filepath.Join(dir, "other.db"))
+		testx.AssertEqual(t, err != nil, true)
+	})
+	t.Run("names", func(t *testing.T) {
+		testx.AssertEqual(t, tenants.Names(), []string{"acme", "globex"})
+	})
+	t.Run("get", func(t *testing.T) {
+		db, err := tenants.Get("acme")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, db, acme)
+
+		_, err = tenants.Get("initech")
+		testx.AssertErr(t, err, redka.ErrTenantNotFound)
+	})
+	t.Run("route", func(t *testing.T) {
+		db, key, err := tenants.Route("globex:user:1", ":")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, key, "user:1")
+		err = db.Str().Set(key, "alice")
+		testx.AssertNoErr(t, err)
+
+		// The key is isolated within the tenant.
+		exists, err := acme.Key().Exists(key)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, exists, false)
+
+		_, _, err = tenants.Route("initech:user:1", ":")
+		testx.AssertErr(t, err, redka.ErrTenantNotFound)
+		_, _, err = tenants.Route("user", ":")
+		testx.AssertErr(t, err, redka.ErrTenantNotFound)
+	})
+	t.Run("backup and flush", func(t *testing.T) {
+		err := acme.Str().Set("name", "acme")
+		testx.AssertNoErr(t, err)
+
+		path := filepath.Join(dir, "acme-backup.db")
+		err = tenants.Backup(context.Background(), "acme", path)
+		testx.AssertNoErr(t, err)
+		err = tenants.Flush("acme")
+		testx.AssertNoErr(t, err)
+
+		exists, err := acme.Key().Exists("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, exists, false)
+
+		backup, err := redka.Open(path, nil)
+		testx.AssertNoErr(t, err)
+		defer backup.Close()
+		name, err := backup.Str().Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name.String(), "acme")
+	})
+	t.Run("detach", func(t *testing.T) {
+		err := tenants.Detach("globex")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, tenants.Names(), []string{"acme"})
+		err = tenants.Detach("globex")
+		testx.AssertErr(t, err, redka.ErrTenantNotFound)
+	})
+}
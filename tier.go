@@ -0,0 +1,340 @@
+package redka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/glob"
+	"github.com/nalgeon/redka/internal/resp"
+)
+
+// tierDialTimeout is the timeout for connecting to the Redis tier.
+const tierDialTimeout = 5 * time.Second
+
+// TierMode defines how the tier reads and writes the keys.
+type TierMode string
+
+const (
+	// TierWriteThrough writes to Redka and then to Redis,
+	// and reads from Redis, falling back to Redka on a miss.
+	TierWriteThrough = TierMode("write-through")
+	// TierWriteBehind writes to Redis and queues the write to Redka
+	// (see [WriteBehind]), and reads like TierWriteThrough.
+	TierWriteBehind = TierMode("write-behind")
+	// TierRedkaOnly keeps the keys in Redka only
+	// (e.g. for the long-tail data that is rarely read).
+	TierRedkaOnly = TierMode("redka-only")
+)
+
+// TierPolicy defines how the tier handles the keys matching the pattern.
+type TierPolicy struct {
+	// Pattern is a Redis glob pattern (e.g. "session:*").
+	Pattern string
+	// Mode is the tiering mode. If empty, uses TierWriteThrough.
+	Mode TierMode
+	// RedisTTL is the maximum time the key is kept in Redis
+	// (the key's own TTL applies if it's shorter).
+	// If zero, the key is kept in Redis for as long as it exists.
+	RedisTTL time.Duration
+}
+
+// TierOptions configures the tier.
+type TierOptions struct {
+	// User and Password are used to authenticate with the Redis server.
+	// If User is empty, authenticates as the default user.
+	User     string
+	Password string
+	// DB is the number of the Redis database.
+	DB int
+	// Policies are matched against the key in order,
+	// and the first matching policy applies.
+	Policies []TierPolicy
+	// Default is the policy for the keys that match none of the Policies.
+	// If its Mode is empty, uses TierWriteThrough.
+	Default TierPolicy
+	// WriteBehind configures the write-behind queue
+	// used by the TierWriteBehind policies.
+	WriteBehind *WriteBehindOptions
+	// OnError is called when a Redis operation fails, but the call
+	// succeeds anyway (e.g. a read falls back to Redka).
+	// If nil, the errors are logged.
+	OnError func(key string, err error)
+}
+
+// Tier uses Redka as a durable second tier behind a Redis server.
+// The hot keys are served from Redis, while the full dataset is kept
+// in Redka, so Redis only needs memory for the working set. The keys
+// read from Redka on a Redis miss are copied back to Redis.
+//
+// The tier works with the string keys. Each key is handled according
+// to the first matching policy (see [TierOptions]). If Redis is not
+// available, the reads fall back to Redka. Safe for concurrent use.
+type Tier struct {
+	db   *DB
+	addr string
+	opts TierOptions
+	wb   *WriteBehind
+
+	mu   sync.Mutex   // protects conn
+	conn *resp.Client // nil if not connected
+}
+
+// Tier returns a tier that uses the database behind the Redis server
+// at addr (host:port). Connects to Redis on first use.
+// Call [Tier.Close] to apply the pending writes before
+// closing the database.
+//
+// The opts parameter is optional. If nil, uses default options.
+func (db *DB) Tier(addr string, opts *TierOptions) *Tier {
+	t := &Tier{db: db, addr: addr}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.Default.Mode == "" {
+		t.opts.Default.Mode = TierWriteThrough
+	}
+	t.opts.Policies = append([]TierPolicy(nil), t.opts.Policies...)
+	for i := range t.opts.Policies {
+		if t.opts.Policies[i].Mode == "" {
+			t.opts.Policies[i].Mode = TierWriteThrough
+		}
+	}
+	if t.opts.OnError == nil {
+		t.opts.OnError = func(key string, err error) {
+			db.log.Warn("tier", "key", key, "error", err)
+		}
+	}
+	t.wb = db.WriteBehind(t.opts.WriteBehind)
+	return t
+}
+
+// Get returns the value of the key. Reads from Redis, and on a miss
+// reads from Redka and copies the value to Redis.
+// Returns nil if the key does not exist.
+func (t *Tier) Get(key string) (core.Value, error) {
+	policy := t.policy(key)
+	if policy.Mode != TierRedkaOnly {
+		reply, err := t.do("get", key)
+		if err != nil {
+			t.opts.OnError(key, err)
+		} else if val, ok := reply.([]byte); ok {
+			return core.Value(val), nil
+		}
+	}
+
+	val, err := t.db.Str().Get(key)
+	if err != nil || !val.Exists() || policy.Mode == TierRedkaOnly {
+		return val, err
+	}
+
+	// Copy the value to Redis, along with its TTL.
+	k, err := t.db.Key().Get(key)
+	if err != nil {
+		return val, err
+	}
+	var ttl time.Duration
+	if k.ETime != nil {
+		ttl = time.Until(time.UnixMilli(*k.ETime))
+		if ttl <= 0 {
+			return val, nil
+		}
+	}
+	if err := t.redisSet(key, val, redisTTL(ttl, policy.RedisTTL)); err != nil {
+		t.opts.OnError(key, err)
+	}
+	return val, nil
+}
+
+// Set sets the key value that does not expire.
+// Overwrites the value if the key already exists.
+func (t *Tier) Set(key string, value any) error {
+	return t.SetExpires(key, value, 0)
+}
+
+// SetExpires sets the key value with an optional expiration time
+// (if ttl > 0). Overwrites the value and ttl if the key already exists.
+func (t *Tier) SetExpires(key string, value any, ttl time.Duration) error {
+	b, ok := tierBytes(value)
+	if !ok {
+		return core.ErrValueType
+	}
+	policy := t.policy(key)
+	switch policy.Mode {
+	case TierRedkaOnly:
+		return t.db.Str().SetExpires(key, value, ttl)
+	case TierWriteBehind:
+		if err := t.redisSet(key, b, redisTTL(ttl, policy.RedisTTL)); err != nil {
+			return err
+		}
+		return t.wb.Update(func(tx *Tx) error {
+			return tx.Str().SetExpires(key, value, ttl)
+		})
+	default:
+		if err := t.db.Str().SetExpires(key, value, ttl); err != nil {
+			return err
+		}
+		return t.redisSet(key, b, redisTTL(ttl, policy.RedisTTL))
+	}
+}
+
+// Delete deletes the keys from both tiers.
+// Non-existing keys are ignored.
+func (t *Tier) Delete(keys ...string) error {
+	var redis, now, later []string
+	for _, key := range keys {
+		switch t.policy(key).Mode {
+		case TierRedkaOnly:
+			now = append(now, key)
+		case TierWriteBehind:
+			redis = append(redis, key)
+			later = append(later, key)
+		default:
+			redis = append(redis, key)
+			now = append(now, key)
+		}
+	}
+	if len(now) > 0 {
+		if _, err := t.db.Key().Delete(now...); err != nil {
+			return err
+		}
+	}
+	if len(redis) > 0 {
+		if _, err := t.do(append([]string{"del"}, redis...)...); err != nil {
+			return err
+		}
+	}
+	if len(later) > 0 {
+		return t.wb.Update(func(tx *Tx) error {
+			_, err := tx.Key().Delete(later...)
+			return err
+		})
+	}
+	return nil
+}
+
+// Flush waits until the queued writes are applied to Redka.
+// Returns the first write error since the previous flush, if any.
+func (t *Tier) Flush() error {
+	return t.wb.Flush()
+}
+
+// Close applies the queued writes to Redka
+// and closes the Redis connection.
+func (t *Tier) Close() error {
+	err := t.wb.Drain()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn != nil {
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+	return err
+}
+
+// policy returns the policy for the key.
+func (t *Tier) policy(key string) TierPolicy {
+	for _, p := range t.opts.Policies {
+		if glob.Match(p.Pattern, key) {
+			return p
+		}
+	}
+	return t.opts.Default
+}
+
+// redisSet sets the key value in Redis with an optional ttl.
+func (t *Tier) redisSet(key string, val []byte, ttl time.Duration) error {
+	args := []string{"set", key, string(val)}
+	if ttl > 0 {
+		args = append(args, "px", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	}
+	_, err := t.do(args...)
+	return err
+}
+
+// do sends the command to Redis and returns the reply.
+// Reconnects on the next call if the connection fails.
+func (t *Tier) do(args ...string) (any, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn == nil {
+		conn, err := t.connect()
+		if err != nil {
+			return nil, fmt.Errorf("tier: connect to %s: %w", t.addr, err)
+		}
+		t.conn = conn
+	}
+	reply, err := t.conn.Do(args...)
+	var respErr resp.Error
+	if err != nil && !errors.As(err, &respErr) {
+		_ = t.conn.Close()
+		t.conn = nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tier: %s: %w", args[0], err)
+	}
+	return reply, nil
+}
+
+// connect connects to the Redis server,
+// authenticates and selects the database.
+func (t *Tier) connect() (*resp.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tierDialTimeout)
+	defer cancel()
+	c, err := resp.Dial(ctx, t.addr)
+	if err != nil {
+		return nil, err
+	}
+	if t.opts.Password != "" {
+		args := []string{"auth", t.opts.Password}
+		if t.opts.User != "" {
+			args = []string{"auth", t.opts.User, t.opts.Password}
+		}
+		if _, err := c.Do(args...); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	if t.opts.DB != 0 {
+		if _, err := c.Do("select", strconv.Itoa(t.opts.DB)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// redisTTL returns the shortest positive ttl (zero if both are zero).
+func redisTTL(ttl, limit time.Duration) time.Duration {
+	if ttl <= 0 {
+		return limit
+	}
+	if limit <= 0 {
+		return ttl
+	}
+	return min(ttl, limit)
+}
+
+// tierBytes returns the value as stored in Redis.
+func tierBytes(v any) ([]byte, bool) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), true
+	case []byte:
+		return v, true
+	case int:
+		return strconv.AppendInt(nil, int64(v), 10), true
+	case float64:
+		return strconv.AppendFloat(nil, v, 'f', -1, 64), true
+	case bool:
+		if v {
+			return []byte("1"), true
+		}
+		return []byte("0"), true
+	}
+	return nil, false
+}
@@ -0,0 +1,137 @@
+package redka_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/rdb"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestTier(t *testing.T) {
+	t.Run("read-through", func(t *testing.T) {
+		redis := newFakeRedis(t)
+		redis.set("hot", rdb.Entry{Type: core.TypeString, Str: []byte("from redis")}, -1)
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("hot", "from redka")
+		_ = db.Str().SetExpires("cold", "from redka", time.Minute)
+
+		tier := db.Tier(redis.addr(), &redka.TierOptions{
+			Default: redka.TierPolicy{RedisTTL: time.Hour},
+		})
+		defer tier.Close()
+
+		val, err := tier.Get("hot")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val.String(), "from redis")
+
+		val, err = tier.Get("cold")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val.String(), "from redka")
+		cached, ttl, ok := redis.get("cold")
+		testx.AssertEqual(t, ok, true)
+		testx.AssertEqual(t, cached, "from redka")
+		testx.AssertEqual(t, ttl > 50_000 && ttl <= 60_000, true)
+
+		val, err = tier.Get("nope")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val.Exists(), false)
+	})
+	t.Run("write-through", func(t *testing.T) {
+		redis := newFakeRedis(t)
+		db := getDB(t)
+		defer db.Close()
+		tier := db.Tier(redis.addr(), &redka.TierOptions{
+			Default: redka.TierPolicy{RedisTTL: time.Hour},
+		})
+		defer tier.Close()
+
+		err := tier.Set("name", "alice")
+		testx.AssertNoErr(t, err)
+		val, _ := db.Str().Get("name")
+		testx.AssertEqual(t, val.String(), "alice")
+		cached, ttl, _ := redis.get("name")
+		testx.AssertEqual(t, cached, "alice")
+		testx.AssertEqual(t, ttl, int64(3600_000))
+
+		err = tier.Set("age", 25)
+		testx.AssertNoErr(t, err)
+		cached, _, _ = redis.get("age")
+		testx.AssertEqual(t, cached, "25")
+
+		err = tier.Delete("name")
+		testx.AssertNoErr(t, err)
+		_, _, ok := redis.get("name")
+		testx.AssertEqual(t, ok, false)
+		val, _ = db.Str().Get("name")
+		testx.AssertEqual(t, val.Exists(), false)
+
+		err = tier.Set("struct", struct{}{})
+		testx.AssertErr(t, err, redka.ErrValueType)
+	})
+	t.Run("policies", func(t *testing.T) {
+		redis := newFakeRedis(t)
+		db := getDB(t)
+		defer db.Close()
+		tier := db.Tier(redis.addr(), &redka.TierOptions{
+			Policies: []redka.TierPolicy{
+				{Pattern: "session:*", Mode: redka.TierWriteBehind, RedisTTL: time.Minute},
+				{Pattern: "archive:*", Mode: redka.TierRedkaOnly},
+			},
+		})
+		defer tier.Close()
+
+		err := tier.Set("session:1", "token")
+		testx.AssertNoErr(t, err)
+		cached, ttl, _ := redis.get("session:1")
+		testx.AssertEqual(t, cached, "token")
+		testx.AssertEqual(t, ttl, int64(60_000))
+		err = tier.Flush()
+		testx.AssertNoErr(t, err)
+		val, _ := db.Str().Get("session:1")
+		testx.AssertEqual(t, val.String(), "token")
+
+		err = tier.Set("archive:2020", "old")
+		testx.AssertNoErr(t, err)
+		_, _, ok := redis.get("archive:2020")
+		testx.AssertEqual(t, ok, false)
+		val, err = tier.Get("archive:2020")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val.String(), "old")
+		_, _, ok = redis.get("archive:2020")
+		testx.AssertEqual(t, ok, false)
+
+		err = tier.Set("user:1", "alice")
+		testx.AssertNoErr(t, err)
+		_, ttl, _ = redis.get("user:1")
+		testx.AssertEqual(t, ttl, int64(-1))
+	})
+	t.Run("redis down", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		testx.AssertNoErr(t, err)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+
+		var errs int
+		tier := db.Tier(addr, &redka.TierOptions{
+			OnError: func(key string, err error) { errs++ },
+		})
+		defer tier.Close()
+
+		val, err := tier.Get("name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, val.String(), "alice")
+		testx.AssertEqual(t, errs, 2)
+
+		err = tier.Set("name", "bob")
+		testx.AssertEqual(t, err != nil, true)
+	})
+}
@@ -0,0 +1,149 @@
+package redka
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// tracerName is the instrumentation scope name
+// passed to the TracerProvider.
+const tracerName = "github.com/nalgeon/redka"
+
+// TracerProvider provides the tracer for the traced operations
+// (like the OpenTelemetry TracerProvider). Redka does not depend
+// on OpenTelemetry, so wrap an OpenTelemetry provider to use it
+// with Redka:
+//
+//	type otelProvider struct{ trace.TracerProvider }
+//
+//	func (p otelProvider) Tracer(name string) redka.Tracer {
+//	    return otelTracer{p.TracerProvider.Tracer(name)}
+//	}
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, redka.Span) {
+//	    ctx, span := t.Tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+//	    return ctx, otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) SetAttribute(key string, value any) {
+//	    switch v := value.(type) {
+//	    case int:
+//	        s.SetAttributes(attribute.Int(key, v))
+//	    case int64:
+//	        s.SetAttributes(attribute.Int64(key, v))
+//	    case float64:
+//	        s.SetAttributes(attribute.Float64(key, v))
+//	    default:
+//	        s.SetAttributes(attribute.String(key, fmt.Sprint(v)))
+//	    }
+//	}
+//
+//	func (s otelSpan) RecordError(err error) {
+//	    s.Span.RecordError(err)
+//	    s.SetStatus(codes.Error, err.Error())
+//	}
+//
+//	func (s otelSpan) End() { s.Span.End() }
+//
+// and pass it to [Open] with Options.TracerProvider:
+//
+//	db, err := redka.Open("data.db", &redka.Options{
+//	    TracerProvider: otelProvider{otel.GetTracerProvider()},
+//	})
+type TracerProvider interface {
+	// Tracer returns the tracer with the given
+	// instrumentation scope name.
+	Tracer(name string) Tracer
+}
+
+// Tracer starts the spans of the traced operations.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx (if any).
+	// Returns the context with the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a traced operation.
+type Span interface {
+	// SetAttribute sets the span attribute.
+	SetAttribute(key string, value any)
+	// RecordError marks the span as failed with the error.
+	RecordError(err error)
+	// End completes the span.
+	End()
+}
+
+// Span attributes set by Redka.
+const (
+	attrSystem       = "db.system"
+	attrOperation    = "db.operation.name"
+	attrKeyCount     = "redka.key_count"
+	attrRowsAffected = "redka.rows_affected"
+	attrSQLDuration  = "redka.sql_duration_ms"
+)
+
+// traceHook traces the repository operations.
+// The span name is the operation name prefixed with "redka."
+// (like "redka.Str.Set"). Use [DB.WithContext] to make the spans
+// children of the application's spans.
+type traceHook struct {
+	tracer Tracer
+	spans  sync.Map // *Op -> Span
+}
+
+// Before starts the operation span.
+func (h *traceHook) Before(ctx context.Context, op *Op) {
+	_, span := startSpan(ctx, h.tracer, "redka."+op.Name)
+	span.SetAttribute(attrKeyCount, len(op.Keys))
+	h.spans.Store(op, span)
+}
+
+// After ends the operation span.
+func (h *traceHook) After(ctx context.Context, op *Op) {
+	val, ok := h.spans.LoadAndDelete(op)
+	if !ok {
+		return
+	}
+	endSpan(val.(Span), op.RowsAffected, op.SQLDuration.Seconds(), op.Err)
+}
+
+// traceTx runs the transaction function f within a span with the
+// given name (like "redka.Update"), if the database has a tracer.
+// The span is a child of the span in ctx (if any).
+func (db *DB) traceTx(ctx context.Context, name string, f func(ctx context.Context) error) error {
+	if db.tracer == nil {
+		return f(ctx)
+	}
+	var stats sqlx.QueryStats
+	ctx, span := startSpan(ctx, db.tracer, name)
+	err := f(sqlx.WithQueryStats(ctx, &stats))
+	endSpan(span, stats.RowsAffected(), stats.Duration().Seconds(), err)
+	return err
+}
+
+// startSpan starts a span with the common attributes.
+func startSpan(ctx context.Context, tracer Tracer, name string) (context.Context, Span) {
+	ctx, span := tracer.Start(ctx, name)
+	span.SetAttribute(attrSystem, "redka")
+	span.SetAttribute(attrOperation, strings.TrimPrefix(name, "redka."))
+	return ctx, span
+}
+
+// endSpan sets the SQL statistics attributes and ends the span.
+// ErrNotFound is not recorded as an error.
+func endSpan(span Span, rows int64, sqlSeconds float64, err error) {
+	span.SetAttribute(attrRowsAffected, rows)
+	span.SetAttribute(attrSQLDuration, sqlSeconds*1000)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		span.RecordError(err)
+	}
+	span.End()
+}
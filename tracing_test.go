@@ -0,0 +1,176 @@
+package redka_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestTracer(t *testing.T) {
+	tracer := &fakeTracer{}
+	db, err := redka.Open(":memory:", &redka.Options{
+		TracerProvider: fakeProvider{tracer},
+	})
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	t.Run("operation", func(t *testing.T) {
+		tracer.reset()
+		_ = db.Str().Set("name", "alice")
+		testx.AssertEqual(t, len(tracer.spans), 1)
+		span := tracer.spans[0]
+		testx.AssertEqual(t, span.name, "redka.Str.Set")
+		testx.AssertEqual(t, span.attrs["db.system"], "redka")
+		testx.AssertEqual(t, span.attrs["db.operation.name"], "Str.Set")
+		testx.AssertEqual(t, span.attrs["redka.key_count"], 1)
+		testx.AssertEqual(t, span.attrs["redka.rows_affected"].(int64) > 0, true)
+		testx.AssertEqual(t, span.attrs["redka.sql_duration_ms"].(float64) > 0, true)
+		testx.AssertEqual(t, span.err, nil)
+		testx.AssertEqual(t, span.ended, true)
+	})
+	t.Run("read", func(t *testing.T) {
+		tracer.reset()
+		_, _ = db.Str().Get("name")
+		span := tracer.spans[0]
+		testx.AssertEqual(t, span.attrs["redka.rows_affected"], int64(0))
+		testx.AssertEqual(t, span.attrs["redka.sql_duration_ms"].(float64) > 0, true)
+	})
+	t.Run("error", func(t *testing.T) {
+		tracer.reset()
+		_, err := db.Hash().Set("name", "age", 25)
+		testx.AssertErr(t, err, redka.ErrKeyType)
+		testx.AssertEqual(t, len(tracer.spans), 1)
+		testx.AssertErr(t, tracer.spans[0].err, redka.ErrKeyType)
+	})
+	t.Run("not found", func(t *testing.T) {
+		tracer.reset()
+		_, err := db.Hash().Get("person", "age")
+		testx.AssertErr(t, err, redka.ErrNotFound)
+		testx.AssertEqual(t, tracer.spans[0].err, nil)
+	})
+	t.Run("parent", func(t *testing.T) {
+		tracer.reset()
+		ctx, parent := tracer.Start(context.Background(), "app")
+		_, _ = db.WithContext(ctx).Str().Get("name")
+		parent.End()
+		testx.AssertEqual(t, len(tracer.spans), 2)
+		testx.AssertEqual(t, tracer.spans[1].name, "redka.Str.Get")
+		testx.AssertEqual(t, tracer.spans[1].parent, "app")
+	})
+	t.Run("update", func(t *testing.T) {
+		tracer.reset()
+		err := db.Update(func(tx *redka.Tx) error {
+			if err := tx.Str().Set("name", "bob"); err != nil {
+				return err
+			}
+			return tx.Str().Set("city", "paris")
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, len(tracer.spans), 1)
+		span := tracer.spans[0]
+		testx.AssertEqual(t, span.name, "redka.Update")
+		testx.AssertEqual(t, span.attrs["db.operation.name"], "Update")
+		testx.AssertEqual(t, span.attrs["redka.rows_affected"].(int64) >= 2, true)
+		testx.AssertEqual(t, span.attrs["redka.sql_duration_ms"].(float64) > 0, true)
+		testx.AssertEqual(t, span.ended, true)
+	})
+	t.Run("update error", func(t *testing.T) {
+		tracer.reset()
+		err := db.Update(func(tx *redka.Tx) error {
+			_, err := tx.Hash().Set("name", "age", 25)
+			return err
+		})
+		testx.AssertErr(t, err, redka.ErrKeyType)
+		testx.AssertErr(t, tracer.spans[0].err, redka.ErrKeyType)
+	})
+	t.Run("view", func(t *testing.T) {
+		for _, test := range []struct {
+			name string
+			view func(f func(tx *redka.Tx) error) error
+		}{
+			{"redka.View", db.View},
+			{"redka.ViewSnapshot", db.ViewSnapshot},
+		} {
+			tracer.reset()
+			err := test.view(func(tx *redka.Tx) error {
+				_, err := tx.Str().Get("name")
+				return err
+			})
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, len(tracer.spans), 1)
+			span := tracer.spans[0]
+			testx.AssertEqual(t, span.name, test.name)
+			testx.AssertEqual(t, span.attrs["redka.rows_affected"], int64(0))
+			testx.AssertEqual(t, span.attrs["redka.sql_duration_ms"].(float64) > 0, true)
+		}
+	})
+	t.Run("update parent", func(t *testing.T) {
+		tracer.reset()
+		ctx, parent := tracer.Start(context.Background(), "app")
+		_ = db.UpdateContext(ctx, func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "alice")
+		})
+		parent.End()
+		testx.AssertEqual(t, len(tracer.spans), 2)
+		testx.AssertEqual(t, tracer.spans[1].name, "redka.Update")
+		testx.AssertEqual(t, tracer.spans[1].parent, "app")
+	})
+	t.Run("accessor", func(t *testing.T) {
+		testx.AssertEqual(t, db.Tracer() == tracer, true)
+		testx.AssertEqual(t, tracer.scope, "github.com/nalgeon/redka")
+		plain := getDB(t)
+		defer plain.Close()
+		testx.AssertEqual(t, plain.Tracer(), nil)
+	})
+}
+
+// fakeProvider provides the fake tracer.
+type fakeProvider struct {
+	tracer *fakeTracer
+}
+
+func (p fakeProvider) Tracer(name string) redka.Tracer {
+	p.tracer.scope = name
+	return p.tracer
+}
+
+// fakeTracer records the spans.
+type fakeTracer struct {
+	mu    sync.Mutex
+	scope string
+	spans []*fakeSpan
+}
+
+type spanKey struct{}
+
+func (t *fakeTracer) Start(ctx context.Context, name string) (context.Context, redka.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := &fakeSpan{name: name, attrs: map[string]any{}}
+	if parent, ok := ctx.Value(spanKey{}).(*fakeSpan); ok {
+		span.parent = parent.name
+	}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *fakeTracer) reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.spans = nil
+}
+
+type fakeSpan struct {
+	name   string
+	parent string
+	attrs  map[string]any
+	err    error
+	ended  bool
+}
+
+func (s *fakeSpan) SetAttribute(key string, value any) { s.attrs[key] = value }
+func (s *fakeSpan) RecordError(err error)              { s.err = err }
+func (s *fakeSpan) End()                               { s.ended = true }
@@ -0,0 +1,179 @@
+package redka
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// Codec encodes and decodes the values of types that have no
+// natural string representation (like structs, slices and maps)
+// for the typed accessors (see [Get] and [Set]).
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec encodes values as JSON. It's the default codec.
+var JSONCodec Codec = jsonCodec{}
+
+// jsonCodec encodes values as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// BinaryCodec encodes values that implement [encoding.BinaryMarshaler]
+// and decodes them with [encoding.BinaryUnmarshaler]. Other values
+// fail with [ErrValueType]. Use it with the types generated by
+// serialization libraries (like protobuf or msgpack wrappers)
+// that provide these methods.
+var BinaryCodec Codec = binaryCodec{}
+
+// binaryCodec encodes values with their binary marshaling methods.
+type binaryCodec struct{}
+
+func (binaryCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T is not a BinaryMarshaler", ErrValueType, v)
+	}
+	return m.MarshalBinary()
+}
+
+func (binaryCodec) Unmarshal(data []byte, v any) error {
+	u, ok := v.(encoding.BinaryUnmarshaler)
+	if !ok {
+		return fmt.Errorf("%w: %T is not a BinaryUnmarshaler", ErrValueType, v)
+	}
+	return u.UnmarshalBinary(data)
+}
+
+// Get returns the string value of the key converted to type T.
+// Returns [ErrNotFound] if the key does not exist. See [Set]
+// for the supported types and their representation.
+func Get[T any](db *DB, key string) (T, error) {
+	var zero T
+	val, err := db.Str().Get(key)
+	if err != nil {
+		return zero, err
+	}
+	if !val.Exists() {
+		return zero, ErrNotFound
+	}
+	return decodeValue[T](db.codec, val)
+}
+
+// Set converts the value to a string and sets it as the key value
+// that will not expire. Overwrites the value if the key already exists.
+//
+// Strings and byte slices are stored as is. Numbers and booleans
+// are stored in their text form (so that [rstring.DB.Incr] works
+// with them). Types implementing [encoding.TextMarshaler] (like
+// time.Time) are stored as text. Other types (like structs) are
+// encoded with the codec set in [Options.Codec] (JSON by default).
+func Set[T any](db *DB, key string, val T) error {
+	return SetExpires(db, key, val, 0)
+}
+
+// SetExpires is like [Set], but also sets the expiration time
+// (if ttl > 0).
+func SetExpires[T any](db *DB, key string, val T, ttl time.Duration) error {
+	data, err := encodeValue(db.codec, val)
+	if err != nil {
+		return err
+	}
+	return db.Str().SetExpires(key, data, ttl)
+}
+
+// encodeValue converts the value to bytes.
+func encodeValue(codec Codec, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case encoding.TextMarshaler:
+		return v.MarshalText()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return []byte(rv.String()), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return []byte("1"), nil
+		}
+		return []byte("0"), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(nil, rv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.AppendUint(nil, rv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.AppendFloat(nil, rv.Float(), 'f', -1, rv.Type().Bits()), nil
+	}
+	return codec.Marshal(v)
+}
+
+// decodeValue converts the bytes to a value of type T.
+func decodeValue[T any](codec Codec, data []byte) (T, error) {
+	var v T
+	if err := decodeInto(codec, data, reflect.ValueOf(&v).Elem()); err != nil {
+		var zero T
+		return zero, err
+	}
+	return v, nil
+}
+
+// decodeInto converts the bytes to a value and stores it in rv,
+// which must be settable.
+func decodeInto(codec Codec, data []byte, rv reflect.Value) error {
+	if rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeInto(codec, data, rv.Elem())
+	}
+	switch p := rv.Addr().Interface().(type) {
+	case *string:
+		*p = string(data)
+		return nil
+	case *[]byte:
+		*p = data
+		return nil
+	case encoding.TextUnmarshaler:
+		return p.UnmarshalText(data)
+	}
+
+	s := string(data)
+	var err error
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(s)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(s)
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(s, 10, rv.Type().Bits())
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(s, 10, rv.Type().Bits())
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(s, rv.Type().Bits())
+		rv.SetFloat(f)
+	default:
+		err = codec.Unmarshal(data, rv.Addr().Interface())
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrValueType, err)
+	}
+	return nil
+}
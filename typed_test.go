@@ -0,0 +1,150 @@
+package redka_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+type person struct {
+	Name string
+	Age  int
+}
+
+func TestTyped(t *testing.T) {
+	db := getDB(t)
+	defer db.Close()
+
+	t.Run("string", func(t *testing.T) {
+		err := redka.Set(db, "name", "alice")
+		testx.AssertNoErr(t, err)
+		name, err := redka.Get[string](db, "name")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, name, "alice")
+	})
+	t.Run("int", func(t *testing.T) {
+		err := redka.Set(db, "age", 25)
+		testx.AssertNoErr(t, err)
+		n, err := db.Str().Incr("age", 1)
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, n, 26)
+		age, err := redka.Get[int](db, "age")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, age, 26)
+	})
+	t.Run("float", func(t *testing.T) {
+		err := redka.Set(db, "score", 4.5)
+		testx.AssertNoErr(t, err)
+		score, err := redka.Get[float64](db, "score")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, score, 4.5)
+	})
+	t.Run("bool", func(t *testing.T) {
+		err := redka.Set(db, "ok", true)
+		testx.AssertNoErr(t, err)
+		val, _ := db.Str().Get("ok")
+		testx.AssertEqual(t, val.String(), "1")
+		ok, err := redka.Get[bool](db, "ok")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, ok, true)
+	})
+	t.Run("time", func(t *testing.T) {
+		now := time.Date(2024, 5, 1, 12, 30, 0, 500, time.UTC)
+		err := redka.Set(db, "now", now)
+		testx.AssertNoErr(t, err)
+		val, _ := db.Str().Get("now")
+		testx.AssertEqual(t, val.String(), "2024-05-01T12:30:00.0000005Z")
+		got, err := redka.Get[time.Time](db, "now")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, got.Equal(now), true)
+	})
+	t.Run("struct", func(t *testing.T) {
+		err := redka.Set(db, "person", person{Name: "alice", Age: 25})
+		testx.AssertNoErr(t, err)
+		val, _ := db.Str().Get("person")
+		testx.AssertEqual(t, val.String(), `{"Name":"alice","Age":25}`)
+		got, err := redka.Get[person](db, "person")
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, got, person{Name: "alice", Age: 25})
+	})
+	t.Run("expires", func(t *testing.T) {
+		err := redka.SetExpires(db, "temp", 42, time.Millisecond)
+		testx.AssertNoErr(t, err)
+		time.Sleep(5 * time.Millisecond)
+		_, err = redka.Get[int](db, "temp")
+		testx.AssertErr(t, err, redka.ErrNotFound)
+	})
+	t.Run("not found", func(t *testing.T) {
+		_, err := redka.Get[int](db, "missing")
+		testx.AssertErr(t, err, redka.ErrNotFound)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		_ = redka.Set(db, "name", "alice")
+		_, err := redka.Get[int](db, "name")
+		testx.AssertEqual(t, errors.Is(err, redka.ErrValueType), true)
+	})
+}
+
+func TestTypedCodec(t *testing.T) {
+	db, err := redka.Open(":memory:", &redka.Options{Codec: gobCodec{}})
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	err = redka.Set(db, "person", person{Name: "alice", Age: 25})
+	testx.AssertNoErr(t, err)
+	got, err := redka.Get[person](db, "person")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, got, person{Name: "alice", Age: 25})
+}
+
+func TestBinaryCodec(t *testing.T) {
+	db, err := redka.Open(":memory:", redka.WithCodec(redka.BinaryCodec))
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	err = redka.Set(db, "point", point{X: 1, Y: 2})
+	testx.AssertNoErr(t, err)
+	val, _ := db.Str().Get("point")
+	testx.AssertEqual(t, val.Bytes(), []byte{1, 2})
+	got, err := redka.Get[point](db, "point")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, got, point{X: 1, Y: 2})
+	ptr, err := redka.Get[*point](db, "point")
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, *ptr, point{X: 1, Y: 2})
+
+	err = redka.Set(db, "person", person{Name: "alice", Age: 25})
+	testx.AssertErr(t, err, redka.ErrValueType)
+}
+
+// point is encoded as two bytes.
+type point struct{ X, Y byte }
+
+func (p point) MarshalBinary() ([]byte, error) {
+	return []byte{p.X, p.Y}, nil
+}
+
+func (p *point) UnmarshalBinary(data []byte) error {
+	if len(data) != 2 {
+		return errors.New("invalid point")
+	}
+	p.X, p.Y = data[0], data[1]
+	return nil
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
@@ -0,0 +1,174 @@
+package redka
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	sqlAutoVacuum     = `pragma auto_vacuum`
+	sqlFreelistCount  = `pragma freelist_count`
+	sqlPageCount      = `pragma page_count`
+	sqlTotalChanges   = `select total_changes()`
+	sqlSetIncremental = `pragma auto_vacuum = incremental`
+	sqlVacuum         = `vacuum`
+)
+
+// autoVacuumIncremental is the auto_vacuum
+// value for the incremental mode.
+const autoVacuumIncremental = 2
+
+// Default incremental vacuum settings.
+const (
+	defaultVacuumInterval = time.Minute
+	defaultVacuumPages    = 1000
+)
+
+// VacuumOptions configures the incremental vacuum.
+type VacuumOptions struct {
+	// Interval is the time between the vacuum steps.
+	// A step only runs if there were no writes since
+	// the previous one. If zero, uses 1 minute.
+	Interval time.Duration
+	// Pages is the maximum number of free pages reclaimed
+	// in a single step. If zero, uses 1000.
+	Pages int
+}
+
+// Compact reclaims the free pages left by the deleted data,
+// shrinking the database file. With the incremental vacuum enabled
+// (see [Options.IncrementalVacuum]), reclaims the pages in small
+// steps, allowing other queries to run in between, and stops
+// early if ctx is canceled. Otherwise, runs a full VACUUM, which
+// rebuilds the whole database and blocks other queries until done.
+// A full VACUUM may reassign the row IDs, so the SCAN, HSCAN
+// and ZSCAN cursors created before it start over.
+func (db *DB) Compact(ctx context.Context) error {
+	var mode int
+	if err := db.SQL.QueryRowContext(ctx, sqlAutoVacuum).Scan(&mode); err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+	if mode != autoVacuumIncremental {
+		if _, err := db.SQL.ExecContext(ctx, sqlVacuum); err != nil {
+			return fmt.Errorf("compact: %w", err)
+		}
+		if err := db.DB.NextGeneration(); err != nil {
+			return fmt.Errorf("compact: %w", err)
+		}
+		db.log.Info("compact", "mode", "full")
+		return nil
+	}
+	var total int
+	for {
+		n, err := db.vacuumStep(ctx, defaultVacuumPages)
+		if err != nil {
+			return fmt.Errorf("compact: %w", err)
+		}
+		total += n
+		if n == 0 {
+			break
+		}
+	}
+	db.log.Info("compact", "mode", "incremental", "pages", total)
+	return nil
+}
+
+// enableIncrementalVacuum switches the database to the incremental
+// auto_vacuum mode. Existing databases are rebuilt with a full VACUUM,
+// because SQLite only changes the mode of an empty database otherwise.
+// Reports whether the database was rebuilt.
+func enableIncrementalVacuum(db *sql.DB) (bool, error) {
+	var mode, pages int
+	if err := db.QueryRow(sqlAutoVacuum).Scan(&mode); err != nil {
+		return false, err
+	}
+	if mode == autoVacuumIncremental {
+		return false, nil
+	}
+	if _, err := db.Exec(sqlSetIncremental); err != nil {
+		return false, err
+	}
+	if err := db.QueryRow(sqlPageCount).Scan(&pages); err != nil {
+		return false, err
+	}
+	if pages == 0 {
+		return false, nil
+	}
+	if _, err := db.Exec(sqlVacuum); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// vacuumStep reclaims up to n free pages.
+// Returns the number of reclaimed pages.
+func (db *DB) vacuumStep(ctx context.Context, n int) (int, error) {
+	var free int
+	if err := db.SQL.QueryRowContext(ctx, sqlFreelistCount).Scan(&free); err != nil {
+		return 0, err
+	}
+	n = min(n, free)
+	if n == 0 {
+		return 0, nil
+	}
+	// The pragma reclaims one page per step, so it should
+	// be run as a query (not Exec) to reclaim all n pages.
+	query := fmt.Sprintf("pragma incremental_vacuum(%d)", n)
+	rows, err := db.SQL.QueryContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		// The pragma returns no rows, keep stepping.
+	}
+	return n, rows.Err()
+}
+
+// startVacuum starts the goroutine that runs in the background
+// and reclaims the free pages while the database is idle.
+// Returns nil if the incremental vacuum is disabled.
+func (db *DB) startVacuum(opts *VacuumOptions) *time.Ticker {
+	if opts == nil {
+		return nil
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultVacuumInterval
+	}
+	pages := opts.Pages
+	if pages <= 0 {
+		pages = defaultVacuumPages
+	}
+
+	// The pool has a single connection (see sqlx.DB.init),
+	// so the total number of changes made through this connection
+	// tells whether there were any writes since the last check.
+	var lastChanges int64 = -1
+	ticker := time.NewTicker(interval)
+	go func() {
+		for range ticker.C {
+			var changes int64
+			if err := db.SQL.QueryRow(sqlTotalChanges).Scan(&changes); err != nil {
+				db.log.Error("vacuum", "error", err)
+				continue
+			}
+			idle := changes == lastChanges
+			lastChanges = changes
+			if !idle {
+				continue
+			}
+			n, err := db.vacuumStep(context.Background(), pages)
+			if err != nil {
+				db.log.Error("vacuum", "error", err)
+				continue
+			}
+			if n > 0 {
+				db.log.Debug("vacuum", "pages", n)
+			}
+		}
+	}()
+	return ticker
+}
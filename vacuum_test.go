@@ -0,0 +1,128 @@
+package redka_test
+
+import (
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *redka.Options
+	}{
+		{"full", nil},
+		{"incremental", &redka.Options{
+			IncrementalVacuum: &redka.VacuumOptions{Interval: time.Hour},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.db")
+			db, err := redka.Open(path, test.opts)
+			testx.AssertNoErr(t, err)
+			defer db.Close()
+
+			fillAndDelete(t, db)
+			testx.AssertEqual(t, freePages(t, db) > 0, true)
+
+			err = db.Compact(context.Background())
+			testx.AssertNoErr(t, err)
+			testx.AssertEqual(t, freePages(t, db), 0)
+		})
+	}
+}
+
+func TestCompactScan(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	db, err := redka.Open(path, nil)
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	for i := range 10 {
+		_, _ = db.Hash().Set("person", "f"+strconv.Itoa(i), i)
+	}
+	_, _ = db.Hash().Delete("person", "f0", "f1", "f2", "f3", "f4")
+
+	first, err := db.Hash().Scan("person", 0, "*", 2)
+	testx.AssertNoErr(t, err)
+	next, err := db.Hash().Scan("person", first.Cursor, "*", 10)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(next.Items), 3)
+
+	// The full vacuum may renumber the hash fields,
+	// so the scan starts over instead of skipping them.
+	err = db.Compact(context.Background())
+	testx.AssertNoErr(t, err)
+	next, err = db.Hash().Scan("person", first.Cursor, "*", 10)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(next.Items), 5)
+
+	// The cursors of the new generation continue the scan.
+	first, err = db.Hash().Scan("person", 0, "*", 2)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, first.Cursor > 1<<48, true)
+	next, err = db.Hash().Scan("person", first.Cursor, "*", 10)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, len(next.Items), 3)
+}
+
+func TestIncrementalVacuum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+
+	// Existing databases are switched to the incremental mode.
+	db, err := redka.Open(path, nil)
+	testx.AssertNoErr(t, err)
+	_ = db.Str().Set("name", "alice")
+	_ = db.Close()
+
+	db, err = redka.Open(path, &redka.Options{
+		IncrementalVacuum: &redka.VacuumOptions{Interval: 10 * time.Millisecond, Pages: 10},
+	})
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	var mode int
+	err = db.SQL.QueryRow("pragma auto_vacuum").Scan(&mode)
+	testx.AssertNoErr(t, err)
+	testx.AssertEqual(t, mode, 2)
+
+	// The free pages are reclaimed in the background.
+	fillAndDelete(t, db)
+	waitFor(t, func() bool { return freePages(t, db) == 0 })
+}
+
+// fillAndDelete writes some data and deletes it,
+// leaving free pages in the database file.
+func fillAndDelete(t *testing.T, db *redka.DB) {
+	value := strings.Repeat("x", 1000)
+	err := db.Update(func(tx *redka.Tx) error {
+		for i := range 500 {
+			if err := tx.Str().Set("key"+strconv.Itoa(i), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	testx.AssertNoErr(t, err)
+	keys, _ := db.Key().Keys("*")
+	for _, key := range keys {
+		_, err := db.Key().Delete(key.Key)
+		testx.AssertNoErr(t, err)
+	}
+	_, err = db.Checkpoint(redka.CheckpointTruncate)
+	testx.AssertNoErr(t, err)
+}
+
+func freePages(t *testing.T, db *redka.DB) int {
+	var n int
+	err := db.SQL.QueryRow("pragma freelist_count").Scan(&n)
+	testx.AssertNoErr(t, err)
+	return n
+}
@@ -0,0 +1,184 @@
+package redka
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/glob"
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// watchBufferSize is the number of events
+// buffered for each watcher (see DB.Watch).
+const watchBufferSize = 1024
+
+// EventOp is the kind of keyspace event.
+type EventOp = core.EventOp
+
+// Kinds of keyspace events.
+const (
+	EventCreated  = core.EventCreated  // key created
+	EventModified = core.EventModified // key value or expiration time changed
+	EventDeleted  = core.EventDeleted  // key deleted
+	EventExpired  = core.EventExpired  // key deleted after its expiration time
+)
+
+// Event is a keyspace event: a key created, modified,
+// deleted or expired by a committed transaction.
+type Event = core.Event
+
+// Watch returns a channel that receives the events for the keys
+// matching the pattern (like in [rkey.DB.Keys]), similar to
+// the Redis keyspace notifications:
+//   - EventCreated when a new key is created;
+//   - EventModified when the value or the expiration time
+//     of an existing key changes;
+//   - EventDeleted when a key is deleted;
+//   - EventExpired when the background job deletes
+//     an expired key (see [Options.ExpireInterval]).
+//
+// A transaction reports at most one event of each kind per key.
+// A renamed key is reported as deleted under the old name and
+// created under the new one. Streams, HyperLogLogs, keys deleted
+// with Key().DeleteAll (FLUSHDB) and keys overwritten by a rename
+// are not reported.
+//
+// The channel is closed when ctx is done. It is also closed if the
+// receiver falls behind and the channel buffer fills up, so the
+// events are never lost silently (check ctx.Err() to tell the cases
+// apart, and call Watch again to resume).
+func (db *DB) Watch(ctx context.Context, pattern string) <-chan Event {
+	nocase := db.keyDB.Collation == sqlx.CollationNocase
+	w := &watcher{
+		pattern: glob.Compile(pattern, nocase),
+		ch:      make(chan Event, watchBufferSize),
+	}
+	db.watchers.add(db, w)
+	go func() {
+		<-ctx.Done()
+		db.watchers.remove(w)
+	}()
+	return w.ch
+}
+
+// watcher is a subscriber to the keyspace events.
+type watcher struct {
+	pattern glob.Pattern
+	ch      chan Event
+}
+
+// watchers delivers the keyspace events to the watchers.
+// Subscribes to the database changes on the first watcher,
+// so the databases without watchers do not capture them.
+type watchers struct {
+	mu   sync.Mutex
+	subs map[*watcher]struct{}
+	once sync.Once
+}
+
+// add registers the watcher.
+func (ws *watchers) add(db *DB, w *watcher) {
+	ws.once.Do(func() { db.OnChange(ws.notify) })
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.subs == nil {
+		ws.subs = map[*watcher]struct{}{}
+	}
+	ws.subs[w] = struct{}{}
+}
+
+// remove unregisters the watcher and closes its channel
+// (unless it has already been removed).
+func (ws *watchers) remove(w *watcher) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if _, ok := ws.subs[w]; ok {
+		delete(ws.subs, w)
+		close(w.ch)
+	}
+}
+
+// notify sends the events for the changes to the matching
+// watchers. Removes the watchers with a full buffer.
+func (ws *watchers) notify(event ChangeEvent) {
+	events := keyEvents(event)
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	for w := range ws.subs {
+		for _, ev := range events {
+			if w.pattern.Match(ev.Key) && !ws.send(w, ev) {
+				break
+			}
+		}
+	}
+}
+
+// send queues the event for the watcher, or removes the watcher
+// if its buffer is full. Must be called with the lock held.
+func (ws *watchers) send(w *watcher, ev Event) bool {
+	select {
+	case w.ch <- ev:
+		return true
+	default:
+		delete(ws.subs, w)
+		close(w.ch)
+		return false
+	}
+}
+
+// keyEvents returns the keyspace events for the changes
+// made by a transaction, in the order they were made.
+func keyEvents(event ChangeEvent) []Event {
+	created := make(map[string]bool, len(event.Created))
+	for _, key := range event.Created {
+		created[key] = true
+	}
+	var events []Event
+	seen := map[Event]bool{}
+	// deleted tracks the deleted keys, so that the deletions
+	// of their values are not reported as modifications.
+	deleted := map[string]bool{}
+	add := func(op EventOp, key string, typ core.TypeID) {
+		ev := Event{Op: op, Key: key, Type: typ}
+		if !seen[ev] {
+			seen[ev] = true
+			events = append(events, ev)
+		}
+	}
+	// changed reports the change to the key value.
+	changed := func(key string, typ core.TypeID) {
+		deleted[key] = false
+		if created[key] {
+			add(EventCreated, key, typ)
+		} else {
+			add(EventModified, key, typ)
+		}
+	}
+
+	for _, c := range event.Changes {
+		switch c.Op {
+		case ChangeSet, ChangeExpire:
+			changed(c.Key, c.Type)
+		case ChangeDelete, ChangeExpired:
+			isKey := c.Field == "" && c.Before == nil
+			if !isKey {
+				// A hash field or a sorted set member.
+				if !deleted[c.Key] {
+					changed(c.Key, c.Type)
+				}
+				continue
+			}
+			deleted[c.Key] = true
+			if c.Op == ChangeExpired {
+				add(EventExpired, c.Key, c.Type)
+			} else {
+				add(EventDeleted, c.Key, c.Type)
+			}
+		case ChangeRename:
+			add(EventDeleted, c.Before.String(), c.Type)
+			add(EventCreated, c.After.String(), c.Type)
+		}
+	}
+	return events
+}
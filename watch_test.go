@@ -0,0 +1,142 @@
+package redka_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/core"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestWatch(t *testing.T) {
+	// receive returns the events received so far.
+	receive := func(ch <-chan redka.Event) []redka.Event {
+		var events []redka.Event
+		for {
+			select {
+			case ev := <-ch:
+				events = append(events, ev)
+			default:
+				return events
+			}
+		}
+	}
+
+	t.Run("create and modify", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		ch := db.Watch(context.Background(), "*")
+
+		_ = db.Str().Set("name", "alice")
+		_ = db.Str().Set("name", "bob")
+		_, _ = db.Hash().Set("person", "name", "alice")
+		_, _ = db.Hash().Set("person", "age", 25)
+		_, _ = db.Key().Expire("name", time.Minute)
+		_, _ = db.Hash().Delete("person", "age")
+
+		testx.AssertEqual(t, receive(ch), []redka.Event{
+			{Op: redka.EventCreated, Key: "name", Type: core.TypeString},
+			{Op: redka.EventModified, Key: "name", Type: core.TypeString},
+			{Op: redka.EventCreated, Key: "person", Type: core.TypeHash},
+			{Op: redka.EventModified, Key: "person", Type: core.TypeHash},
+			{Op: redka.EventModified, Key: "name", Type: core.TypeString},
+			{Op: redka.EventModified, Key: "person", Type: core.TypeHash},
+		})
+	})
+	t.Run("delete", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_, _ = db.SortedSet().AddMany("scores", map[any]float64{"one": 1, "two": 2})
+		ch := db.Watch(context.Background(), "*")
+
+		_, _ = db.Key().Delete("scores")
+		testx.AssertEqual(t, receive(ch), []redka.Event{
+			{Op: redka.EventDeleted, Key: "scores", Type: core.TypeSortedSet},
+		})
+	})
+	t.Run("expire", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_, _ = db.Hash().Set("person", "name", "alice")
+		_, _ = db.Key().Expire("person", time.Millisecond)
+		ch := db.Watch(context.Background(), "*")
+
+		time.Sleep(5 * time.Millisecond)
+		_, _ = db.Key().DeleteExpired(0)
+		testx.AssertEqual(t, receive(ch), []redka.Event{
+			{Op: redka.EventExpired, Key: "person", Type: core.TypeHash},
+		})
+	})
+	t.Run("rename", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		_ = db.Str().Set("name", "alice")
+		ch := db.Watch(context.Background(), "*")
+
+		_ = db.Key().Rename("name", "title")
+		testx.AssertEqual(t, receive(ch), []redka.Event{
+			{Op: redka.EventDeleted, Key: "name", Type: core.TypeString},
+			{Op: redka.EventCreated, Key: "title", Type: core.TypeString},
+		})
+	})
+	t.Run("transaction", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		ch := db.Watch(context.Background(), "*")
+
+		err := db.Update(func(tx *redka.Tx) error {
+			_ = tx.Str().Set("name", "alice")
+			_ = tx.Str().Set("name", "bob")
+			_, _ = tx.Key().Expire("name", time.Minute)
+			return nil
+		})
+		testx.AssertNoErr(t, err)
+		testx.AssertEqual(t, receive(ch), []redka.Event{
+			{Op: redka.EventCreated, Key: "name", Type: core.TypeString},
+		})
+	})
+	t.Run("pattern", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		ch := db.Watch(context.Background(), "user:*")
+
+		_ = db.Str().Set("user:1", "alice")
+		_ = db.Str().Set("order:1", "pizza")
+		testx.AssertEqual(t, receive(ch), []redka.Event{
+			{Op: redka.EventCreated, Key: "user:1", Type: core.TypeString},
+		})
+	})
+	t.Run("cancel", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := db.Watch(ctx, "*")
+
+		cancel()
+		_, ok := <-ch
+		testx.AssertEqual(t, ok, false)
+		_ = db.Str().Set("name", "alice")
+	})
+	t.Run("overflow", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		ch := db.Watch(context.Background(), "*")
+
+		err := db.Update(func(tx *redka.Tx) error {
+			for i := 0; i < 2000; i++ {
+				_ = tx.Str().Set(strconv.Itoa(i), i)
+			}
+			return nil
+		})
+		testx.AssertNoErr(t, err)
+
+		var n int
+		for range ch {
+			n++
+		}
+		testx.AssertEqual(t, n, 1024)
+	})
+}
@@ -0,0 +1,217 @@
+package redka
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/nalgeon/redka/internal/sqlx"
+)
+
+// Default write-behind settings.
+const (
+	defaultWriteQueueSize = 10000
+	defaultWriteBatchSize = 1000
+)
+
+// ErrWriteBehindClosed is returned when writing to
+// a write-behind queue after it has been drained.
+var ErrWriteBehindClosed = errors.New("write-behind queue is closed")
+
+// WriteBehindOptions configures the write-behind queue.
+type WriteBehindOptions struct {
+	// QueueSize is the maximum number of pending writes.
+	// When the queue is full, [WriteBehind.Update] blocks
+	// until there is room. If zero, uses 10000.
+	QueueSize int
+	// BatchSize is the maximum number of writes applied
+	// in a single transaction. If zero, uses 1000.
+	BatchSize int
+	// OnError is called by the writer when a write fails.
+	// If nil, the errors are logged.
+	OnError func(error)
+}
+
+// WriteBehind applies the writes asynchronously. Writes are
+// acknowledged as soon as they are queued, and a single writer
+// goroutine applies them in batched transactions. This increases
+// the write throughput at the cost of durability: the queued writes
+// are lost if the process crashes, and the reads do not see the
+// writes until they are applied. Use [WriteBehind.Flush] to wait
+// for the queued writes. Safe for concurrent use.
+//
+// Each write is applied atomically: if it fails, its changes
+// are rolled back, but the rest of the batch is committed.
+type WriteBehind struct {
+	db    *DB
+	opts  WriteBehindOptions
+	queue chan writeItem
+	done  chan struct{}
+
+	mu     sync.RWMutex // protects closed
+	closed bool
+
+	errMu sync.Mutex
+	err   error // first error since the last flush
+}
+
+// writeItem is either a write or a flush request.
+type writeItem struct {
+	f     func(tx *Tx) error
+	flush chan struct{} // closed when the preceding writes are applied
+}
+
+// WriteBehind starts the write-behind queue.
+// Call [WriteBehind.Drain] to apply the pending writes
+// and stop the queue before closing the database.
+//
+// The opts parameter is optional. If nil, uses default options.
+func (db *DB) WriteBehind(opts *WriteBehindOptions) *WriteBehind {
+	w := &WriteBehind{db: db, done: make(chan struct{})}
+	if opts != nil {
+		w.opts = *opts
+	}
+	if w.opts.QueueSize <= 0 {
+		w.opts.QueueSize = defaultWriteQueueSize
+	}
+	if w.opts.BatchSize <= 0 {
+		w.opts.BatchSize = defaultWriteBatchSize
+	}
+	w.queue = make(chan writeItem, w.opts.QueueSize)
+	go w.run()
+	return w
+}
+
+// Update queues a function to be executed within
+// a writable transaction. Returns without waiting for
+// the function to execute. The errors returned by the
+// function are reported by [WriteBehind.Flush].
+func (w *WriteBehind) Update(f func(tx *Tx) error) error {
+	return w.send(writeItem{f: f})
+}
+
+// Flush waits until the writes queued before the call are applied.
+// Returns the first write error since the previous flush, if any.
+func (w *WriteBehind) Flush() error {
+	ch := make(chan struct{})
+	if err := w.send(writeItem{flush: ch}); err != nil {
+		return err
+	}
+	<-ch
+	return w.takeErr()
+}
+
+// Drain applies the pending writes and stops the queue.
+// Further writes fail with [ErrWriteBehindClosed].
+// Returns the first write error since the previous flush, if any.
+func (w *WriteBehind) Drain() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+	w.mu.Unlock()
+	<-w.done
+	return w.takeErr()
+}
+
+// send puts the item in the queue.
+func (w *WriteBehind) send(item writeItem) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return ErrWriteBehindClosed
+	}
+	w.queue <- item
+	return nil
+}
+
+// run applies the queued writes until the queue is closed.
+func (w *WriteBehind) run() {
+	defer close(w.done)
+	var batch []func(tx *Tx) error
+	var flushes []chan struct{}
+	for item := range w.queue {
+		batch, flushes = w.add(batch, flushes, item)
+		// Collect the writes that are already queued.
+	collect:
+		for len(batch) < w.opts.BatchSize {
+			select {
+			case item, ok := <-w.queue:
+				if !ok {
+					break collect
+				}
+				batch, flushes = w.add(batch, flushes, item)
+			default:
+				break collect
+			}
+		}
+		w.apply(batch)
+		for _, ch := range flushes {
+			close(ch)
+		}
+		batch, flushes = batch[:0], flushes[:0]
+	}
+}
+
+// add adds the item to the batch.
+func (w *WriteBehind) add(batch []func(tx *Tx) error, flushes []chan struct{},
+	item writeItem) ([]func(tx *Tx) error, []chan struct{}) {
+	if item.flush != nil {
+		return batch, append(flushes, item.flush)
+	}
+	return append(batch, item.f), flushes
+}
+
+// apply executes the writes in a single transaction.
+// Each write runs within a savepoint, so that a failed write
+// does not affect the others.
+func (w *WriteBehind) apply(batch []func(tx *Tx) error) {
+	if len(batch) == 0 {
+		return
+	}
+	var errs []error
+	err := w.db.Update(func(tx *Tx) error {
+		// The function is retried if the database is busy,
+		// so the errors are collected anew on each run.
+		errs = errs[:0]
+		for _, f := range batch {
+			err := sqlx.Savepoint(tx.tx, "writebehind", func() error {
+				return f(tx)
+			})
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		w.report(err)
+		return
+	}
+	for _, err := range errs {
+		w.report(err)
+	}
+}
+
+// report records the write error.
+func (w *WriteBehind) report(err error) {
+	w.errMu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.errMu.Unlock()
+	if w.opts.OnError != nil {
+		w.opts.OnError(err)
+	} else {
+		w.db.log.Error("write behind", "error", err)
+	}
+}
+
+// takeErr returns and clears the recorded write error.
+func (w *WriteBehind) takeErr() error {
+	w.errMu.Lock()
+	defer w.errMu.Unlock()
+	err := w.err
+	w.err = nil
+	return err
+}
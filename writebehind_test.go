@@ -0,0 +1,84 @@
+package redka_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestWriteBehind(t *testing.T) {
+	t.Run("flush", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		w := db.WriteBehind(&redka.WriteBehindOptions{BatchSize: 10})
+		defer w.Drain()
+
+		for i := range 100 {
+			err := w.Update(func(tx *redka.Tx) error {
+				return tx.Str().Set("key"+strconv.Itoa(i), i)
+			})
+			testx.AssertNoErr(t, err)
+		}
+		err := w.Flush()
+		testx.AssertNoErr(t, err)
+
+		keys, _ := db.Key().Keys("*")
+		testx.AssertEqual(t, len(keys), 100)
+	})
+	t.Run("failed write", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		var reported []error
+		w := db.WriteBehind(&redka.WriteBehindOptions{
+			OnError: func(err error) { reported = append(reported, err) },
+		})
+		defer w.Drain()
+
+		errFailed := errors.New("failed")
+		_ = w.Update(func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "alice")
+		})
+		_ = w.Update(func(tx *redka.Tx) error {
+			_ = tx.Str().Set("age", 25)
+			return errFailed
+		})
+		_ = w.Update(func(tx *redka.Tx) error {
+			return tx.Str().Set("city", "paris")
+		})
+		err := w.Flush()
+		testx.AssertErr(t, err, errFailed)
+		testx.AssertEqual(t, len(reported), 1)
+
+		// The failed write is rolled back, the others are applied.
+		count, _ := db.Key().Count("name", "age", "city")
+		testx.AssertEqual(t, count, 2)
+
+		// The error is reported once.
+		err = w.Flush()
+		testx.AssertNoErr(t, err)
+	})
+	t.Run("drain", func(t *testing.T) {
+		db := getDB(t)
+		defer db.Close()
+		w := db.WriteBehind(nil)
+
+		_ = w.Update(func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "alice")
+		})
+		err := w.Drain()
+		testx.AssertNoErr(t, err)
+
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+
+		err = w.Update(func(tx *redka.Tx) error { return nil })
+		testx.AssertErr(t, err, redka.ErrWriteBehindClosed)
+		err = w.Flush()
+		testx.AssertErr(t, err, redka.ErrWriteBehindClosed)
+		err = w.Drain()
+		testx.AssertNoErr(t, err)
+	})
+}
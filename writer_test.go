@@ -0,0 +1,62 @@
+package redka_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nalgeon/redka"
+	"github.com/nalgeon/redka/internal/testx"
+)
+
+func TestSingleWriter(t *testing.T) {
+	db, err := redka.Open("", &redka.Options{InMemory: true, WriterQueue: 10})
+	testx.AssertNoErr(t, err)
+	defer db.Close()
+
+	t.Run("concurrent writes", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := db.Str().Incr("counter", 1)
+				testx.AssertNoErr(t, err)
+				err = db.Str().Set(fmt.Sprintf("key:%d", i), i)
+				testx.AssertNoErr(t, err)
+			}()
+		}
+		wg.Wait()
+		counter, _ := db.Str().Get("counter")
+		testx.AssertEqual(t, counter.String(), "50")
+	})
+	t.Run("cancel queued", func(t *testing.T) {
+		started := make(chan struct{})
+		release := make(chan struct{})
+		go func() {
+			_ = db.Update(func(tx *redka.Tx) error {
+				close(started)
+				<-release
+				return tx.Str().Set("name", "alice")
+			})
+		}()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err := db.UpdateContext(ctx, func(tx *redka.Tx) error {
+			return tx.Str().Set("name", "bob")
+		})
+		testx.AssertEqual(t, errors.Is(err, context.DeadlineExceeded), true)
+		close(release)
+
+		// The canceled write is never executed.
+		err = db.Str().Set("age", 25)
+		testx.AssertNoErr(t, err)
+		name, _ := db.Str().Get("name")
+		testx.AssertEqual(t, name.String(), "alice")
+	})
+}